@@ -1,20 +1,27 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
+	"time"
 	db "zust/db/sqlc"
+	"zust/service/file"
+	"zust/service/security"
 
 	"github.com/google/uuid"
 )
 
+// HandleGetProfile returns the profile of an active account. The response has a weak ETag, 304 is returned when it
+// matches If-None-Match.
+// endpoint: GET /accounts/{id}
+// Success: 200, 304
+// Fail: 400, 403, 404, 500
 func (server *Server) HandleGetProfile(w http.ResponseWriter, r *http.Request) {
 	// Get the account ID from path parameter
 	id := r.PathValue("id")
@@ -27,11 +34,11 @@ func (server *Server) HandleGetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get account profile from database
-	account, err := server.query.GetProfile(r.Context(), accUuid)
+	account, err := server.getProfile(r.Context(), accUuid)
 	if err != nil {
 		// If account ID not match any record
 		if errors.Is(err, sql.ErrNoRows) {
-			server.WriteError(w, http.StatusNotFound, "Account not found")
+			server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
 			return
 		}
 
@@ -43,14 +50,20 @@ func (server *Server) HandleGetProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Check if account status is active
 	if account.Status != db.AccountStatusActive {
-		server.WriteError(w, http.StatusForbidden, "Account is not active")
+		server.WriteErrorCode(w, http.StatusForbidden, CodeAccountNotActive, "Account is not active", nil)
 		return
 	}
 
 	// Return account profile
-	server.WriteJSON(w, http.StatusOK, account)
+	server.WriteJSONWithETag(w, r, http.StatusOK, account)
 }
 
+// HandleEditProfile edits the profile of the requester (username, description, avatar and cover). The edit is made
+// from the version of the profile sent in the form, if the profile has been edited since then, 409 is returned with
+// the latest profile in details. Without version, the edit applies on the current profile.
+// endpoint: PUT /accounts/{id}
+// Success: 201
+// Fail: 400, 403, 409, 413, 415, 500, 507
 func (server *Server) HandleEditProfile(w http.ResponseWriter, r *http.Request) {
 	// Check if the account ID in path parameter match with the ID extract from access token
 	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
@@ -66,30 +79,45 @@ func (server *Server) HandleEditProfile(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Parse request multipart form data
-	r.Body = http.MaxBytesReader(w, r.Body, server.config.ImageSize)
-	base := filepath.Join(server.config.ResourcePath, accID.String())
+	// Get the version the edit is made from, default to the current profile. The edit is rejected before the
+	// images are replaced if the profile has been edited since then
+	version := oldProfile.Version
+	if value := r.FormValue("version"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || parsed < 1 {
+			server.WriteError(w, http.StatusBadRequest, "Invalid version")
+			return
+		}
+		version = int32(parsed)
+	}
+	if version != oldProfile.Version {
+		server.WriteErrorCode(w, http.StatusConflict, CodeEditConflict, "Profile was edited since this version", oldProfile)
+		return
+	}
 
 	// Get new avatar image if provided
 	avatar, _, err := r.FormFile("avatar")
 	if err != nil {
-		server.WriteError(w, http.StatusBadRequest, "Invalid avatar file")
+		server.writeFormError(w, err, "Invalid avatar file")
 		return
 	}
 	if avatar != nil {
 		defer avatar.Close()
-		// Copy new file to storage
-		oldAvatar, err := os.OpenFile(filepath.Join(base, "avatar.png"), os.O_RDWR, os.ModePerm)
-		if err != nil {
-			server.logger.Error("PUT /accounts/{id}: failed to open the current avatar file in storage", "id", accID.String(),
-				"error", err)
-			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		if !server.checkStorageSpace(w) {
+			return
+		}
+		if !server.checkUploadType(w, avatar, file.IsImage, "Avatar must be a PNG or JPEG image") {
+			return
+		}
+		// Re-encode the image, which strips its metadata (EXIF, GPS location, ...)
+		var image bytes.Buffer
+		if err := file.NormalizeImage(avatar, &image); err != nil {
+			server.WriteError(w, http.StatusBadRequest, "Invalid avatar file")
 			return
 		}
-		defer oldAvatar.Close()
 
-		_, err = io.Copy(oldAvatar, avatar)
-		if err != nil {
+		// Replace the current avatar in storage
+		if err := server.storage.Put(file.AvatarKey(accID.String()), &image); err != nil {
 			server.logger.Error("PUT /accounts/{id}: failed to overwrite avatar", "error", err)
 			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 			return
@@ -99,23 +127,26 @@ func (server *Server) HandleEditProfile(w http.ResponseWriter, r *http.Request)
 	// Get new cover image file if provided
 	cover, _, err := r.FormFile("cover")
 	if err != nil {
-		server.WriteError(w, http.StatusBadRequest, "Invalid cover file")
+		server.writeFormError(w, err, "Invalid cover file")
 		return
 	}
 	if cover != nil {
 		defer cover.Close()
-		// Copy new file to storage
-		oldCover, err := os.OpenFile(filepath.Join(base, "cover.png"), os.O_RDWR, os.ModePerm)
-		if err != nil {
-			server.logger.Error("PUT /accounts/{id}: failed to open the current cover file in storage", "id", accID.String(),
-				"error", err)
-			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		if !server.checkStorageSpace(w) {
+			return
+		}
+		if !server.checkUploadType(w, cover, file.IsImage, "Cover must be a PNG or JPEG image") {
+			return
+		}
+		// Re-encode the image, which strips its metadata (EXIF, GPS location, ...)
+		var image bytes.Buffer
+		if err := file.NormalizeImage(cover, &image); err != nil {
+			server.WriteError(w, http.StatusBadRequest, "Invalid cover file")
 			return
 		}
-		defer oldCover.Close()
 
-		_, err = io.Copy(oldCover, cover)
-		if err != nil {
+		// Replace the current cover in storage
+		if err := server.storage.Put(file.CoverKey(accID.String()), &image); err != nil {
 			server.logger.Error("PUT /accounts/{id}: failed to overwrite cover image", "error", err)
 			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 			return
@@ -135,18 +166,34 @@ func (server *Server) HandleEditProfile(w http.ResponseWriter, r *http.Request)
 		description = oldProfile.Description.String
 	}
 
-	// Update profile
+	// Update profile, only if it hasn't been edited since the version
 	account, err := server.query.EditProfile(r.Context(), db.EditProfileParams{
 		AccountID:   accID,
 		Username:    username,
 		Description: sql.NullString{String: description, Valid: true},
+		Version:     version,
 	})
 
+	if errors.Is(err, sql.ErrNoRows) {
+		latest, err := server.query.GetProfile(r.Context(), accID)
+		if err != nil {
+			server.logger.Error("PUT /accounts/{id}: failed to get profile", "error", err)
+			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		server.WriteErrorCode(w, http.StatusConflict, CodeEditConflict, "Profile was edited since this version", latest)
+		return
+	}
+	if errors.Is(db.MapError(err), db.ErrDuplicateUsername) {
+		server.WriteErrorCode(w, http.StatusConflict, CodeUsernameTaken, "Username is already taken", nil)
+		return
+	}
 	if err != nil {
 		server.logger.Error("PUT /accounts/{id}: failed to edit profile", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
+	server.invalidateProfile(r.Context(), accID)
 
 	// Return the newly updated profile back to client
 	server.WriteJSON(w, http.StatusCreated, account)
@@ -173,6 +220,7 @@ func (server *Server) HandleLockAccount(w http.ResponseWriter, r *http.Request)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
+	server.invalidateProfile(r.Context(), accID)
 
 	server.WriteJSON(w, http.StatusCreated, fmt.Sprintf("Account with ID %s locked successfully", accID.String()))
 }
@@ -207,10 +255,149 @@ func (server *Server) HandleUnlockAccount(w http.ResponseWriter, r *http.Request
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
+	server.invalidateProfile(r.Context(), accountID)
 
 	server.WriteJSON(w, http.StatusCreated, fmt.Sprintf("Account with ID %s unlocked successfully", accountID.String()))
 }
 
+// HandleDeleteAccount deletes the account of the requester. The account and its videos are hidden and all its
+// tokens are revoked, but they are kept so an admin can restore them. The email and username stay taken. An account
+// with a verified phone number needs a step-up code (see requireStepUp)
+// endpoint: DELETE /accounts/{id}
+// Success: 200
+// Fail: 400, 401, 403, 404, 500
+func (server *Server) HandleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+
+	// Deleting the account is a sensitive action, it needs a code sent to the verified phone number if there is one
+	credentials, err := server.query.GetAccountCredentials(r.Context(), accID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
+			return
+		}
+		server.logger.Error("DELETE /accounts/{id}: failed to get account credentials", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "DELETE /accounts/{id}"))
+	if !server.requireStepUp(w, r, accID, db.StepUpPurposeAccountDeletion, credentials) {
+		return
+	}
+
+	affected, err := server.query.DeleteAccount(r.Context(), accID)
+	if err != nil {
+		server.logger.Error("DELETE /accounts/{id}: failed to delete account", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if affected == 0 {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
+		return
+	}
+	server.invalidateProfile(r.Context(), accID)
+	server.invalidateTokenVersion(r.Context(), accID)
+	server.invalidateAccountVideos(r.Context(), accID)
+
+	server.WriteJSON(w, http.StatusOK, fmt.Sprintf("Account with ID %s deleted successfully", accID.String()))
+}
+
+// Request body to change the password
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// HandleChangePassword changes the password of the requester, all its sessions are revoked so every device must login
+// again with the new password. An account with a verified phone number needs a step-up code (see requireStepUp)
+// endpoint: PUT /accounts/{id}/password
+// Success: 200
+// Fail: 400, 401, 403, 500
+func (server *Server) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Get request body
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "PUT /accounts/{id}/password"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	credentials, err := server.query.GetAccountCredentials(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/password: failed to get account credentials", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// If the account does not have a password (OAuth account)
+	if !credentials.Password.Valid {
+		server.WriteError(w, http.StatusBadRequest, "Account does not have a password, please login with OAuth provider")
+		return
+	}
+
+	// Check the current password before the step-up code, so a wrong password doesn't use the code
+	if !security.BcryptCompare(credentials.Password.String, req.CurrentPassword) {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeAuthInvalidCredentials, "Invalid password", nil)
+		return
+	}
+	if !server.requireStepUp(w, r, accountID, db.StepUpPurposePasswordChange, credentials) {
+		return
+	}
+
+	hashedPassword, err := security.BcryptHash(req.NewPassword)
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/password: failed to hash password", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Change the password and revoke all the sessions, the tokens issued before them too
+	err = server.query.ExecTx(r.Context(), func(q *db.Queries) error {
+		err := q.SetPassword(r.Context(), db.SetPasswordParams{
+			AccountID: accountID,
+			Password:  sql.NullString{String: hashedPassword, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.RevokeAccountSessions(r.Context(), accountID); err != nil {
+			return err
+		}
+		return q.IncrementTokenVersion(r.Context(), accountID)
+	})
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/password: failed to change password", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	server.invalidateTokenVersion(r.Context(), accountID)
+
+	server.WriteJSON(w, http.StatusOK, "Password changed successfully, please login again")
+}
+
 type subscribeRequest struct {
 	SubscriberID   uuid.UUID `json:"subscriber_id" validate:"required"`
 	SubscriberToID uuid.UUID `json:"subscribe_to_id" validate:"required"`
@@ -220,13 +407,13 @@ func (server *Server) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 	// Get request body
 	var req subscribeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		server.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
 		return
 	}
 
 	// Validate request body
 	if err := server.validate.Struct(req); err != nil {
-		server.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		server.writeValidationError(w, err)
 		return
 	}
 
@@ -237,12 +424,27 @@ func (server *Server) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 
 	// Check if subscriber status is active or not before processing with the request
 	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /subscribe"))
-	if _, isActive := server.checkAccountStatus(w, r, req.SubscriberID); !isActive {
+	subscriber, isActive := server.checkAccountStatus(w, r, req.SubscriberID)
+	if !isActive {
+		return
+	}
+
+	// The channel must be on the same site as the subscriber
+	if inTenant, err := server.inTenant(r.Context(), req.SubscriberToID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
+			return
+		}
+		server.logger.Error("POST /subscribe: failed to get account tenant", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	} else if !inTenant {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
 		return
 	}
 
 	// Create subscription
-	result, err := server.query.Subscribe(r.Context(), db.SubscribeParams{
+	result, err := server.subscribe(r.Context(), db.SubscribeParams{
 		SubscriberID:  req.SubscriberID,
 		SubscribeToID: req.SubscriberToID,
 	})
@@ -252,6 +454,18 @@ func (server *Server) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
+	server.notify(r.Context(), db.CreateNotificationParams{
+		RecipientID: req.SubscriberToID,
+		Type:        db.NotificationTypeNewSubscriber,
+		ActorID:     uuid.NullUUID{UUID: req.SubscriberID, Valid: true},
+	})
+	server.emitWebhookEvent(r.Context(), req.SubscriberToID, webhookSubscriberNew, func() (any, error) {
+		return subscriberEventData{
+			SubscriberID: req.SubscriberID,
+			Username:     subscriber.Username,
+			SubscribedAt: result.SubscribeAt,
+		}, nil
+	})
 
 	// Return result back to client
 	server.WriteJSON(w, http.StatusCreated, result)
@@ -261,13 +475,13 @@ func (server *Server) HandleUnsubscribe(w http.ResponseWriter, r *http.Request)
 	// Get request body
 	var req subscribeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		server.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
 		return
 	}
 
 	// Validate request body
 	if err := server.validate.Struct(req); err != nil {
-		server.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		server.writeValidationError(w, err)
 		return
 	}
 
@@ -283,7 +497,7 @@ func (server *Server) HandleUnsubscribe(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Delete subscription
-	err := server.query.Unsubscribe(r.Context(), db.UnsubscribeParams{
+	err := server.unsubscribe(r.Context(), db.UnsubscribeParams{
 		SubscriberID:  req.SubscriberID,
 		SubscribeToID: req.SubscriberToID,
 	})
@@ -297,3 +511,139 @@ func (server *Server) HandleUnsubscribe(w http.ResponseWriter, r *http.Request)
 	// Return result back to client
 	server.WriteJSON(w, http.StatusOK, "Unsubscription successfully")
 }
+
+// Helper method: subscribe an account to a channel, and count the new subscriber of the channel
+func (server *Server) subscribe(ctx context.Context, arg db.SubscribeParams) (db.Subscribe, error) {
+	var result db.Subscribe
+	err := server.query.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		if result, err = q.Subscribe(ctx, arg); err != nil {
+			return err
+		}
+		return q.AddSubscribers(ctx, db.AddSubscribersParams{Delta: 1, AccountID: arg.SubscribeToID})
+	})
+	return result, err
+}
+
+// Helper method: unsubscribe an account from a channel, the subscriber is uncounted only if it was subscribed
+func (server *Server) unsubscribe(ctx context.Context, arg db.UnsubscribeParams) error {
+	return server.query.ExecTx(ctx, func(q *db.Queries) error {
+		deleted, err := q.Unsubscribe(ctx, arg)
+		if err != nil || deleted == 0 {
+			return err
+		}
+		return q.AddSubscribers(ctx, db.AddSubscribersParams{Delta: -1, AccountID: arg.SubscribeToID})
+	})
+}
+
+// A subscriber in the list of subscribers of a channel
+type subscriberResult struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	Description  string    `json:"description"`
+	Avatar       string    `json:"avatar"`
+	SubscribedAt time.Time `json:"subscribed_at"`
+}
+
+// HandleListSubscribers lists the active subscribers of the requester's channel, latest first. The next page is
+// requested with the cursor returned in X-Next-Cursor.
+// endpoint: GET /accounts/{id}/subscribers?cursor=...&size=...
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleListSubscribers(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Get pagination
+	cursor, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	// List subscribers
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	afterCreatedAt, afterID := cursor.params()
+	subscribers, err := server.query.ListSubscribers(r.Context(), db.ListSubscribersParams{
+		SubscribeToID:  accID,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		PageSize:       int32(size),
+	})
+	if err != nil {
+		server.logger.Error("GET /accounts/{id}/subscribers: failed to list subscribers", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Return the result back to client
+	data := make([]subscriberResult, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		data = append(data, subscriberResult{
+			ID:           subscriber.AccountID.String(),
+			Username:     subscriber.Username,
+			Description:  subscriber.Description.String,
+			Avatar:       server.mediaService.GenerateMediaLink(subscriber.AccountID.String(), "", file.Avatar),
+			SubscribedAt: subscriber.SubscribeAt,
+		})
+	}
+	if len(subscribers) > 0 {
+		last := subscribers[len(subscribers)-1]
+		setNextCursor(w, len(subscribers), size, last.SubscribeAt, last.AccountID)
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Request body for SetProcessingWebhook, empty URL removes the webhook
+type processingWebhookRequest struct {
+	URL string `json:"url" validate:"omitempty,url,max=255"`
+}
+
+// HandleSetProcessingWebhook sets the URL which is called when the processing of an uploaded video
+// completes or fails.
+// endpoint: PUT /accounts/{id}/webhook
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleSetProcessingWebhook(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Get request body
+	var req processingWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Check account status if it's active or not before processing with the request
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "PUT /accounts/{id}/webhook"))
+	if _, isActive := server.checkAccountStatus(w, r, accID); !isActive {
+		return
+	}
+
+	// Update webhook
+	err := server.query.SetProcessingWebhook(r.Context(), db.SetProcessingWebhookParams{
+		AccountID:            accID,
+		ProcessingWebhookUrl: sql.NullString{String: req.URL, Valid: req.URL != ""},
+	})
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/webhook: failed to set processing webhook", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Processing webhook updated successfully")
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/job"
+
+	"github.com/google/uuid"
+)
+
+// Default and maximum number of failed jobs returned
+const (
+	defaultFailedJobs = 50
+	maxFailedJobs     = 200
+)
+
+// HandleListFailedJobs returns the most recent jobs that ran out of attempts (dead-letter), only available to admin.
+// The payloads of the emails are redacted, since their body carries the links and codes sent to the users.
+// endpoint: GET /admin/jobs/failed?limit=...
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleListFailedJobs(w http.ResponseWriter, r *http.Request) {
+	limit := defaultFailedJobs
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxFailedJobs {
+			server.WriteError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	jobs, err := server.jobs.Failed(r.Context(), limit)
+	if err != nil {
+		server.logger.Error("GET /admin/jobs/failed: failed to list failed jobs", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	for i := range jobs {
+		jobs[i].Payload = redactJobPayload(jobs[i].Type, jobs[i].Payload)
+	}
+
+	server.WriteJSON(w, http.StatusOK, jobs)
+}
+
+// HandleRequeueJob puts a failed job back into the queue with its attempts reset, only available to admin.
+// endpoint: POST /admin/jobs/{id}/requeue
+// Success: 200
+// Fail: 403, 404, 500
+func (server *Server) HandleRequeueJob(w http.ResponseWriter, r *http.Request) {
+	err := server.jobs.Requeue(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, job.ErrJobNotFound) {
+			server.WriteError(w, http.StatusNotFound, "Cannot found any failed job with this ID")
+			return
+		}
+
+		server.logger.Error("POST /admin/jobs/{id}/requeue: failed to requeue job", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Job requeued successfully")
+}
+
+// A deleted video in the list of deleted videos
+type deletedVideoResult struct {
+	ID                string    `json:"id"`
+	Title             string    `json:"title"`
+	Status            string    `json:"status"`
+	PublisherID       string    `json:"publisher_id"`
+	PublisherUsername string    `json:"username"`
+	DeletedAt         time.Time `json:"deleted_at"`
+}
+
+// HandleListDeletedVideos lists the deleted videos of all tenants, latest deleted first, only available to admin.
+// The next page is requested with the cursor returned in X-Next-Cursor.
+// endpoint: GET /admin/videos/deleted?cursor=...&size=...
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleListDeletedVideos(w http.ResponseWriter, r *http.Request) {
+	cursor, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	afterDeletedAt, afterID := cursor.params()
+	videos, err := server.query.ListDeletedVideos(r.Context(), db.ListDeletedVideosParams{
+		AfterDeletedAt: afterDeletedAt,
+		AfterID:        afterID,
+		PageSize:       int32(size),
+	})
+	if err != nil {
+		server.logger.Error("GET /admin/videos/deleted: failed to list deleted videos", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]deletedVideoResult, 0, len(videos))
+	for _, video := range videos {
+		data = append(data, deletedVideoResult{
+			ID:                video.VideoID.String(),
+			Title:             video.Title,
+			Status:            string(video.Status),
+			PublisherID:       video.AccountID.String(),
+			PublisherUsername: video.Username,
+			DeletedAt:         video.DeletedAt.Time,
+		})
+	}
+	if len(videos) > 0 {
+		last := videos[len(videos)-1]
+		setNextCursor(w, len(videos), size, last.DeletedAt.Time, last.VideoID)
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// HandleRestoreVideo restores a deleted video, only available to admin. The video is visible again if its publisher
+// isn't deleted.
+// endpoint: POST /admin/videos/{id}/restore
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleRestoreVideo(w http.ResponseWriter, r *http.Request) {
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	affected, err := server.query.RestoreVideo(r.Context(), videoID)
+	if err != nil {
+		server.logger.Error("POST /admin/videos/{id}/restore: failed to restore video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if affected == 0 {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "No deleted video with this ID", nil)
+		return
+	}
+	server.invalidateVideos(r.Context(), videoID)
+
+	server.WriteJSON(w, http.StatusOK, "Video restored successfully")
+}
+
+// A deleted account in the list of deleted accounts
+type deletedAccountResult struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// HandleListDeletedAccounts lists the deleted accounts of all tenants, latest deleted first, only available to admin.
+// The next page is requested with the cursor returned in X-Next-Cursor.
+// endpoint: GET /admin/accounts/deleted?cursor=...&size=...
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleListDeletedAccounts(w http.ResponseWriter, r *http.Request) {
+	cursor, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	afterDeletedAt, afterID := cursor.params()
+	accounts, err := server.query.ListDeletedAccounts(r.Context(), db.ListDeletedAccountsParams{
+		AfterDeletedAt: afterDeletedAt,
+		AfterID:        afterID,
+		PageSize:       int32(size),
+	})
+	if err != nil {
+		server.logger.Error("GET /admin/accounts/deleted: failed to list deleted accounts", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]deletedAccountResult, 0, len(accounts))
+	for _, account := range accounts {
+		data = append(data, deletedAccountResult{
+			ID:        account.AccountID.String(),
+			Tenant:    account.TenantID,
+			Email:     account.Email,
+			Username:  account.Username,
+			DeletedAt: account.DeletedAt.Time,
+		})
+	}
+	if len(accounts) > 0 {
+		last := accounts[len(accounts)-1]
+		setNextCursor(w, len(accounts), size, last.DeletedAt.Time, last.AccountID)
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// HandleRestoreAccount restores a deleted account with its videos, only available to admin. The tokens issued
+// before the deletion stay revoked, the owner has to log in again.
+// endpoint: POST /admin/accounts/{id}/restore
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleRestoreAccount(w http.ResponseWriter, r *http.Request) {
+	var accountID uuid.UUID
+	if err := accountID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	affected, err := server.query.RestoreAccount(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("POST /admin/accounts/{id}/restore: failed to restore account", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if affected == 0 {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "No deleted account with this ID", nil)
+		return
+	}
+	server.invalidateProfile(r.Context(), accountID)
+
+	server.WriteJSON(w, http.StatusOK, "Account restored successfully")
+}
+
+// Helper function: get the payload of a job as listed to the admins. Only the recipient and the subject of an email
+// are kept: its body, headers and attachments can hold tokens (password reset, verification, unsubscribe links)
+func redactJobPayload(jobType string, payload json.RawMessage) json.RawMessage {
+	if jobType != job.TypeSendEmail {
+		return payload
+	}
+
+	var email sendEmailPayload
+	if err := json.Unmarshal(payload, &email); err != nil {
+		return json.RawMessage(`{"redacted":true}`)
+	}
+	redacted, err := json.Marshal(map[string]any{
+		"to":       email.To,
+		"subject":  email.Subject,
+		"redacted": true,
+	})
+	if err != nil {
+		return json.RawMessage(`{"redacted":true}`)
+	}
+	return redacted
+}
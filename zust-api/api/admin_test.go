@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"zust/service/job"
+)
+
+func TestRedactJobPayload(t *testing.T) {
+	email, _ := json.Marshal(sendEmailPayload{
+		To:      "user@example.com",
+		Subject: "Reset your password",
+		Body:    `<a href="https://zust.example/reset?token=secret-token">Reset</a>`,
+		Text:    "https://zust.example/reset?token=secret-token",
+		Headers: map[string]string{"List-Unsubscribe": "<https://zust.example/unsubscribe?token=secret-token>"},
+	})
+
+	tests := []struct {
+		name    string
+		jobType string
+		payload string
+		want    string
+	}{
+		{name: "email", jobType: job.TypeSendEmail, payload: string(email),
+			want: `{"redacted":true,"subject":"Reset your password","to":"user@example.com"}`},
+		{name: "invalid email", jobType: job.TypeSendEmail, payload: `"secret-token"`, want: `{"redacted":true}`},
+		{name: "transcode", jobType: job.TypeTranscode, payload: `{"video_id":"x","publisher_id":"y"}`,
+			want: `{"video_id":"x","publisher_id":"y"}`},
+	}
+
+	for _, test := range tests {
+		got := string(redactJobPayload(test.jobType, json.RawMessage(test.payload)))
+		if got != test.want {
+			t.Errorf("%s: redactJobPayload() = %s, want %s", test.name, got, test.want)
+		}
+		if strings.Contains(got, "secret-token") {
+			t.Errorf("%s: redactJobPayload() leaks the token: %s", test.name, got)
+		}
+	}
+}
@@ -7,12 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 	db "zust/db/sqlc"
 	"zust/service/file"
+	"zust/service/job"
 	"zust/service/mail"
 	"zust/service/security"
 
@@ -21,6 +21,14 @@ import (
 
 /*=== PASSWORD AUTH HANDLERS ===*/
 
+// A verification link or code is valid for verificationTTL, and a code is refused after verificationCodeMaxAttempts
+// wrong tries
+const (
+	verificationTTL             = 24 * time.Hour
+	verificationCodeDigits      = 6
+	verificationCodeMaxAttempts = 5
+)
+
 // Request body for login
 type loginRequest struct {
 	Username string `json:"username" validate:"required"`
@@ -40,29 +48,40 @@ type loginResponse struct {
 // HandleLogin handles the login with username and password.
 // Endpoint: POST /auth/login
 // Success: 200
-// Fail: 400, 403, 500
+// Fail: 400, 403, 429, 500
 func (server *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	// Extract the request body
 	var req loginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		server.logger.Error("POST /login: failed to decode request body", "error", err)
-		server.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
 		return
 	}
 
 	// Validate the request body
 	if err := server.validate.Struct(&req); err != nil {
 		server.logger.Error("POST /login: invalid request body", "error", err)
-		server.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Reject the login if there were too many wrong passwords for the username
+	attemptsKey := loginAttemptsKey(tenantOf(r.Context()), req.Username)
+	if !server.checkLoginLockout(w, r, attemptsKey) {
 		return
 	}
 
 	// Get account by username
-	account, err := server.query.GetAccountByUsername(r.Context(), req.Username)
+	account, err := server.query.GetAccountByUsername(r.Context(), db.GetAccountByUsernameParams{
+		TenantID: tenantOf(r.Context()),
+		Username: req.Username,
+	})
 	if err != nil {
 		// If no account found with the username
 		if errors.Is(err, sql.ErrNoRows) {
-			server.WriteError(w, http.StatusBadRequest, "Invalid username or password")
+			server.failLogin(r.Context(), attemptsKey)
+			server.WriteErrorCode(w, http.StatusBadRequest, CodeAuthInvalidCredentials,
+				"Invalid username or password", nil)
 			return
 		}
 
@@ -74,7 +93,7 @@ func (server *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// If the account status is not active
 	if account.Status != db.AccountStatusActive {
-		server.WriteError(w, http.StatusForbidden, "Account is not active")
+		server.WriteErrorCode(w, http.StatusForbidden, CodeAccountNotActive, "Account is not active", nil)
 		return
 	}
 
@@ -86,22 +105,20 @@ func (server *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 	// Check if the password is correct
 	if !security.BcryptCompare(account.Password.String, req.Password) {
-		server.WriteError(w, http.StatusBadRequest, "Invalid username or password")
+		server.failLogin(r.Context(), attemptsKey)
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeAuthInvalidCredentials, "Invalid username or password", nil)
 		return
 	}
-
-	// If success, create JWT tokens (access token and refresh token)
-	accessToken, err := server.jwtService.CreateToken(account.AccountID.String(), "access-token",
-		int(account.TokenVersion), server.jwtService.TokenExpirationTime)
-	if err != nil {
-		server.logger.Error("POST /login: failed to create JWT access token", "error", err)
-		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
-		return
+	if server.attempts != nil {
+		if err := server.attempts.Reset(r.Context(), attemptsKey); err != nil {
+			server.logger.Error("POST /login: failed to reset failed login attempts", "error", err)
+		}
 	}
-	refreshToken, err := server.jwtService.CreateToken(account.AccountID.String(), "refresh-token",
-		int(account.TokenVersion), server.jwtService.RefreshTokenExpirationTime)
+
+	// If success, open a session with its JWT tokens (access token and refresh token)
+	accessToken, refreshToken, err := server.createSession(r, account.AccountID, account.TokenVersion)
 	if err != nil {
-		server.logger.Error("POST /login: failed to create JWT refresh token", "error", err)
+		server.logger.Error("POST /login: failed to open session", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
@@ -128,20 +145,20 @@ type registerRequest struct {
 // HandleRegister handles the register with email, username and password.
 // endoint: POST /auth/register
 // Success: 200
-// Fail: 400, 500
+// Fail: 400, 409, 500
 func (server *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	// Extract the request body
 	var req registerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		server.logger.Error("POST /register: failed to decode request body", "error", err)
-		server.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
 		return
 	}
 
 	// Validate the request body
 	if err := server.validate.Struct(&req); err != nil {
 		server.logger.Error("POST /register: invalid request body", "error", err)
-		server.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		server.writeValidationError(w, err)
 		return
 	}
 
@@ -155,20 +172,23 @@ func (server *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 
 	// Create account
 	account, err := server.query.CreateAccountWithPassword(r.Context(), db.CreateAccountWithPasswordParams{
+		TenantID: tenantOf(r.Context()),
 		Email:    req.Email,
 		Username: req.Username,
 		Password: sql.NullString{String: hashedPassword, Valid: true},
 	})
 	if err != nil {
+		err = db.MapError(err)
+
 		// If the email is already taken
-		if strings.Contains(err.Error(), "account_email_key") {
-			server.WriteError(w, http.StatusBadRequest, "Email is already taken")
+		if errors.Is(err, db.ErrDuplicateEmail) {
+			server.WriteErrorCode(w, http.StatusConflict, CodeEmailTaken, "Email is already taken", nil)
 			return
 		}
 
 		// If the username is already taken
-		if strings.Contains(err.Error(), "account_username_key") {
-			server.WriteError(w, http.StatusBadRequest, "Username is already taken")
+		if errors.Is(err, db.ErrDuplicateUsername) {
+			server.WriteErrorCode(w, http.StatusConflict, CodeUsernameTaken, "Username is already taken", nil)
 			return
 		}
 
@@ -179,7 +199,7 @@ func (server *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create user repository with default avatar and cover
-	err = server.storage.CreateUserRepo(account.AccountID.String())
+	err = server.storage.EnsureUserRepo(account.AccountID.String())
 	if err != nil {
 		server.logger.Error("POST /auth/register: failed to create user repository", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
@@ -187,7 +207,8 @@ func (server *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send verification email
-	if err := server.sendVerificationEmail(account.AccountID.String(), account.Username, account.Email); err != nil {
+	if err := server.sendVerificationEmail(r.Context(), account.TenantID, account.AccountID.String(),
+		account.Username, account.Email); err != nil {
 		server.logger.Error("POST /register: failed to send verification email", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Account created successfully, but failed to send verification email")
 		return
@@ -196,22 +217,45 @@ func (server *Server) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	server.WriteJSON(w, http.StatusOK, "Account created successfully")
 }
 
-// Helper method: send verification email
-func (server *Server) sendVerificationEmail(id, username, email string) error {
+// Helper method: send verification email, with a link to the site of the tenant of the account and a numeric code
+// (for the mail clients breaking the link). A new code replaces the previous one
+func (server *Server) sendVerificationEmail(ctx context.Context, tenant, id, username, email string) error {
 	// Generate token: userID|timestamp and encode it with base64
 	token := security.Encode(fmt.Sprintf("%s|%d", id, time.Now().UnixNano()))
 
+	// Generate the code, only its hash is stored
+	code, err := security.GenerateCode(verificationCodeDigits)
+	if err != nil {
+		return err
+	}
+	var accountID uuid.UUID
+	accountID.Scan(id)
+	err = server.query.SetEmailVerificationCode(ctx, db.SetEmailVerificationCodeParams{
+		AccountID: accountID,
+		CodeHash:  security.HashCode(server.config.SecretKey, id, code),
+		ExpiresAt: time.Now().Add(verificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
 	// Prepare email body
-	body, err := server.mailService.PrepareEmail("template/verification.html", mail.VerificationEmailPayload{
+	body, text, err := server.mailService.PrepareBodies("verification", mail.VerificationEmailPayload{
 		Username: username,
-		Link:     fmt.Sprintf("http://%s:%s/auth/verification?token=%s", server.config.Domain, server.config.Port, token),
+		Link:     fmt.Sprintf("http://%s:%s/auth/verification?token=%s", server.config.ForTenant(tenant).Domain, server.config.Port, token),
+		Code:     code,
 	})
 	if err != nil {
 		return err
 	}
 
-	// Send email
-	return server.mailService.SendEmail(email, "Zust - Verify your email", body)
+	// Send email in background
+	return server.jobs.Enqueue(ctx, job.TypeSendEmail, sendEmailPayload{
+		To:      email,
+		Subject: "Zust - Verify your email",
+		Body:    body,
+		Text:    text,
+	})
 }
 
 // HandleVerify handles the verification of the account and activate it.
@@ -222,7 +266,7 @@ func (server *Server) HandleVerify(w http.ResponseWriter, r *http.Request) {
 	// Get the token from query params
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		server.WriteError(w, http.StatusBadRequest, "Missing token")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationInvalid, "Missing token", nil)
 		return
 	}
 
@@ -232,7 +276,7 @@ func (server *Server) HandleVerify(w http.ResponseWriter, r *http.Request) {
 	// Split the decoded string to get the account ID and timestamp
 	parts := strings.Split(decodeToken, "|")
 	if len(parts) != 2 {
-		server.WriteError(w, http.StatusBadRequest, "Invalid token")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationInvalid, "Invalid token", nil)
 		return
 	}
 	accountID := parts[0]
@@ -240,12 +284,12 @@ func (server *Server) HandleVerify(w http.ResponseWriter, r *http.Request) {
 	// Check if the token is expired (valid for 24 hours)
 	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
-		server.WriteError(w, http.StatusBadRequest, "Invalid token")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationInvalid, "Invalid token", nil)
 		return
 	}
 	// Since the timestamp is generated by UnixNano(), the sec parameter should be in 0 to get the correct time
-	if time.Since(time.Unix(0, timestamp)) > 24*time.Hour {
-		server.WriteError(w, http.StatusBadRequest, "Token has expired")
+	if time.Since(time.Unix(0, timestamp)) > verificationTTL {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationExpired, "Token has expired", nil)
 		return
 	}
 
@@ -260,7 +304,7 @@ func (server *Server) HandleVerify(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// If no account found with the account ID
 		if errors.Is(err, sql.ErrNoRows) {
-			server.WriteError(w, http.StatusBadRequest, "Account does not exist")
+			server.WriteErrorCode(w, http.StatusBadRequest, CodeAccountNotFound, "Account does not exist", nil)
 			return
 		}
 
@@ -269,6 +313,111 @@ func (server *Server) HandleVerify(w http.ResponseWriter, r *http.Request) {
 		server.WriteError(w, http.StatusInternalServerError, "Failed to verify account")
 		return
 	}
+	server.invalidateProfile(r.Context(), uuid)
+
+	// The code sent along the link can't be used anymore
+	if err := server.query.DeleteEmailVerificationCode(r.Context(), uuid); err != nil {
+		server.logger.Error("GET /verification: failed to delete verification code", "error", err)
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Account verified successfully")
+}
+
+// Request body to confirm an email with the code of the verification email
+type confirmVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Code  string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// HandleConfirmVerification handles the verification of the account with the code of the verification email, an
+// alternative to the link, and activate it. A code is refused after a few wrong tries, a new email must be sent.
+// endpoint: POST /auth/verification/confirm
+// Success: 200
+// Fail: 400, 500
+func (server *Server) HandleConfirmVerification(w http.ResponseWriter, r *http.Request) {
+	// Extract the request body
+	var req confirmVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate the request body
+	if err := server.validate.Struct(&req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Get account by email, an unknown email gets the same error as a wrong code
+	account, err := server.query.GetAccountByEmail(r.Context(), db.GetAccountByEmailParams{
+		TenantID: tenantOf(r.Context()),
+		Email:    req.Email,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationInvalid, "Invalid code", nil)
+			return
+		}
+
+		// Other database error
+		server.logger.Error("POST /auth/verification/confirm: failed to get account by email", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Check for account status
+	if account.Status != db.AccountStatusInactive {
+		server.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Account is %s", account.Status))
+		return
+	}
+
+	// Count the try before checking the code, so concurrent tries can't exceed the limit
+	verification, err := server.query.GetEmailVerificationCode(r.Context(), account.AccountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationInvalid, "Invalid code", nil)
+			return
+		}
+		server.logger.Error("POST /auth/verification/confirm: failed to get verification code", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if time.Now().After(verification.ExpiresAt) {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationExpired, "Code has expired", nil)
+		return
+	}
+	tried, err := server.query.IncrementEmailVerificationAttempts(r.Context(), db.IncrementEmailVerificationAttemptsParams{
+		AccountID:   account.AccountID,
+		MaxAttempts: verificationCodeMaxAttempts,
+	})
+	if err != nil {
+		server.logger.Error("POST /auth/verification/confirm: failed to count verification attempt", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if tried == 0 {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationExpired,
+			"Too many wrong codes, please request a new verification email", nil)
+		return
+	}
+	if !security.CompareCode(server.config.SecretKey, account.AccountID.String(), req.Code, verification.CodeHash) {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVerificationInvalid, "Invalid code", nil)
+		return
+	}
+
+	// Activate the account, the code can't be used again
+	err = server.query.ExecTx(r.Context(), func(q *db.Queries) error {
+		if err := q.ActivateAccount(r.Context(), account.AccountID); err != nil {
+			return err
+		}
+		return q.DeleteEmailVerificationCode(r.Context(), account.AccountID)
+	})
+	if err != nil {
+		server.logger.Error("POST /auth/verification/confirm: failed to activate account", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Failed to verify account")
+		return
+	}
+	server.invalidateProfile(r.Context(), account.AccountID)
 
 	server.WriteJSON(w, http.StatusOK, "Account verified successfully")
 }
@@ -286,7 +435,10 @@ func (server *Server) HandleResendVerification(w http.ResponseWriter, r *http.Re
 	}
 
 	// Get account by email
-	account, err := server.query.GetAccountByEmail(r.Context(), email)
+	account, err := server.query.GetAccountByEmail(r.Context(), db.GetAccountByEmailParams{
+		TenantID: tenantOf(r.Context()),
+		Email:    email,
+	})
 	if err != nil {
 		// If no account found with the email
 		if errors.Is(err, sql.ErrNoRows) {
@@ -307,7 +459,8 @@ func (server *Server) HandleResendVerification(w http.ResponseWriter, r *http.Re
 	}
 
 	// Send verification email
-	if err := server.sendVerificationEmail(account.AccountID.String(), account.Username, account.Email); err != nil {
+	if err := server.sendVerificationEmail(r.Context(), tenantOf(r.Context()), account.AccountID.String(),
+		account.Username, account.Email); err != nil {
 		server.logger.Error("POST /verification/resend: failed to send verification email", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Failed to send verification email")
 		return
@@ -343,7 +496,7 @@ type OAuthProvider interface {
 // HandleCallback handles the OAuth callback from provider
 // endpoint: GET /oauth2/callback?code=...&state=...
 // Success: 200
-// Fail: 400, 500
+// Fail: 400, 409, 500
 func (server *Server) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	// Get the OAuth provider
 	providerName := r.URL.Query().Get("state")
@@ -360,7 +513,7 @@ func (server *Server) HandleCallback(w http.ResponseWriter, r *http.Request) {
 		provider = &GoogleProvider{
 			ClientID:     server.config.GoogleClientID,
 			ClientSecret: server.config.GoogleClientSecret,
-			Domain:       server.config.Domain,
+			Domain:       server.config.ForTenant(tenantOf(r.Context())).Domain,
 			Port:         server.config.Port,
 		}
 	default:
@@ -399,6 +552,7 @@ func (server *Server) HandleCallback(w http.ResponseWriter, r *http.Request) {
 func (server *Server) handleOAuth(w http.ResponseWriter, r *http.Request, userData userData, provider string) {
 	// Check if account is already registered with the email
 	isRegistered, err := server.query.IsAccountRegistered(r.Context(), db.IsAccountRegisteredParams{
+		TenantID:        tenantOf(r.Context()),
 		OauthProvider:   sql.NullString{String: provider, Valid: true},
 		OauthProviderID: sql.NullString{String: userData.ID, Valid: true},
 	})
@@ -411,6 +565,7 @@ func (server *Server) handleOAuth(w http.ResponseWriter, r *http.Request, userDa
 	// If account is registered, login the user
 	if isRegistered {
 		account, err := server.query.LoginWithOAuth(r.Context(), db.LoginWithOAuthParams{
+			TenantID:        tenantOf(r.Context()),
 			OauthProvider:   sql.NullString{String: provider, Valid: true},
 			OauthProviderID: sql.NullString{String: userData.ID, Valid: true},
 		})
@@ -422,22 +577,14 @@ func (server *Server) handleOAuth(w http.ResponseWriter, r *http.Request, userDa
 
 		// If the account status is not active
 		if account.Status != db.AccountStatusActive {
-			server.WriteError(w, http.StatusForbidden, "Account is not active")
+			server.WriteErrorCode(w, http.StatusForbidden, CodeAccountNotActive, "Account is not active", nil)
 			return
 		}
 
-		// If success, create JWT tokens (access token and refresh token)
-		accessToken, err := server.jwtService.CreateToken(account.AccountID.String(), "access-token",
-			int(account.TokenVersion), server.jwtService.TokenExpirationTime)
-		if err != nil {
-			server.logger.Error("GET oauth2/callback: failed to create JWT access token", "error", err)
-			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
-			return
-		}
-		refreshToken, err := server.jwtService.CreateToken(account.AccountID.String(), "refresh-token",
-			int(account.TokenVersion), server.jwtService.RefreshTokenExpirationTime)
+		// If success, open a session with its JWT tokens (access token and refresh token)
+		accessToken, refreshToken, err := server.createSession(r, account.AccountID, account.TokenVersion)
 		if err != nil {
-			server.logger.Error("GET oauth2/callback: failed to create JWT refresh token", "error", err)
+			server.logger.Error("GET oauth2/callback: failed to open session", "error", err)
 			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 			return
 		}
@@ -457,21 +604,24 @@ func (server *Server) handleOAuth(w http.ResponseWriter, r *http.Request, userDa
 
 	// If account is not registered, create a new account
 	account, err := server.query.CreateAccountWithOAuth(r.Context(), db.CreateAccountWithOAuthParams{
+		TenantID:        tenantOf(r.Context()),
 		Email:           userData.Email,
 		Username:        userData.Username,
 		OauthProvider:   sql.NullString{String: provider, Valid: true},
 		OauthProviderID: sql.NullString{String: userData.ID, Valid: true},
 	})
 	if err != nil {
+		err = db.MapError(err)
+
 		// If the email is already taken
-		if strings.Contains(err.Error(), "account_email_key") {
-			server.WriteError(w, http.StatusBadRequest, "Email is already taken")
+		if errors.Is(err, db.ErrDuplicateEmail) {
+			server.WriteErrorCode(w, http.StatusConflict, CodeEmailTaken, "Email is already taken", nil)
 			return
 		}
 
 		// If the username is already taken
-		if strings.Contains(err.Error(), "account_username_key") {
-			server.WriteError(w, http.StatusBadRequest, "Username is already taken")
+		if errors.Is(err, db.ErrDuplicateUsername) {
+			server.WriteErrorCode(w, http.StatusConflict, CodeUsernameTaken, "Username is already taken", nil)
 			return
 		}
 
@@ -481,35 +631,30 @@ func (server *Server) handleOAuth(w http.ResponseWriter, r *http.Request, userDa
 		return
 	}
 
-	// If success, create JWT tokens (access token and refresh token)
-	accessToken, err := server.jwtService.CreateToken(account.AccountID.String(), "access-token",
-		int(account.TokenVersion), server.jwtService.TokenExpirationTime)
+	// If success, open a session with its JWT tokens (access token and refresh token)
+	accessToken, refreshToken, err := server.createSession(r, account.AccountID, account.TokenVersion)
 	if err != nil {
-		server.logger.Error("GET oauth2/callback: failed to create JWT access token", "error", err)
-		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
-		return
-	}
-	refreshToken, err := server.jwtService.CreateToken(account.AccountID.String(), "refresh-token",
-		int(account.TokenVersion), server.jwtService.RefreshTokenExpirationTime)
-	if err != nil {
-		server.logger.Error("GET oauth2/callback: failed to create JWT refresh token", "error", err)
+		server.logger.Error("GET oauth2/callback: failed to open session", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
 	// Create user repositoty with default avatar and cover
-	err = server.storage.CreateUserRepo(account.AccountID.String())
+	err = server.storage.EnsureUserRepo(account.AccountID.String())
 	if err != nil {
 		server.logger.Error("POST /oauth2/callback: failed to create user repo", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
-	// Download the image and rewrite the default avatar
-	server.storage.DownloadURL(
-		userData.Avatar,
-		filepath.Join(server.config.ResourcePath, account.AccountID.String(), "avatar.png"),
-	)
+	// Download the image and rewrite the default avatar in background
+	err = server.jobs.Enqueue(r.Context(), job.TypeDownloadAvatar, downloadAvatarPayload{
+		AccountID: account.AccountID,
+		URL:       userData.Avatar,
+	})
+	if err != nil {
+		server.logger.Error("GET oauth2/callback: failed to enqueue avatar download", "error", err)
+	}
 
 	// Return user info and tokens
 	var resp = loginResponse{
@@ -525,30 +670,37 @@ func (server *Server) handleOAuth(w http.ResponseWriter, r *http.Request, userDa
 
 /*=== Auth shared logic ===*/
 
-// HandleLogout handles the logout by invalidating the current tokens version.
+// HandleLogout handles the logout by revoking the session of the current tokens. The tokens issued before the
+// sessions are logged out by invalidating the current tokens version (logout from all devices).
 // endpoint: POST /auth/logout
 // Success: 200
 // Fail: 400, 500
 func (server *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	// Extract account ID from claims
-	claims := r.Context().Value(clKey)
-	var uuid uuid.UUID
-	uuid.Scan(claims.(*security.CustomClaims).ID)
+	claims := r.Context().Value(clKey).(*security.CustomClaims)
+	var accountID uuid.UUID
+	accountID.Scan(claims.ID)
 
 	// Check if account status is active or not before continuing with the request
 	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /auth/logout"))
-	if _, isActive := server.checkAccountStatus(w, r, uuid); isActive {
-		// Increase token version to logout (logout from all account)
-		err := server.query.IncrementTokenVersion(r.Context(), uuid)
+	if _, isActive := server.checkAccountStatus(w, r, accountID); isActive {
+		var err error
+		if claims.SessionID != "" {
+			// Revoke the session, the other devices stay logged in
+			var sessionID uuid.UUID
+			sessionID.Scan(claims.SessionID)
+			_, err = server.query.RevokeSession(r.Context(), db.RevokeSessionParams{
+				SessionID: sessionID,
+				AccountID: accountID,
+			})
+			server.invalidateSession(r.Context(), sessionID)
+		} else {
+			// Increase token version to logout (logout from all account)
+			err = server.query.IncrementTokenVersion(r.Context(), accountID)
+			server.invalidateTokenVersion(r.Context(), accountID)
+		}
 		if err != nil {
-			// If no account found with the account ID
-			if errors.Is(err, sql.ErrNoRows) {
-				server.WriteError(w, http.StatusBadRequest, "Account does not exist")
-				return
-			}
-
-			// Other database error
-			server.logger.Error("POST /logout: failed to increment token version", "error", err)
+			server.logger.Error("POST /logout: failed to revoke session", "error", err)
 			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 			return
 		}
@@ -557,45 +709,100 @@ func (server *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleRefreshToken handles the refresh token mechanism by create new access token using the provided refresh token.
+// Response body for token refresh
+type refreshResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// HandleRefreshToken handles the refresh token mechanism by rotating the session of the provided refresh token: it
+// returns a new access token and a new refresh token, the provided refresh token can't be used again. Reusing a
+// refresh token means it was stolen (the thief or the owner used it first), so its session is revoked.
 // endpoint: POST /auth/token/refresh
 // Success: 200
-// Fail: 400, 500
+// Fail: 400, 401, 403, 500
 func (server *Server) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
-	// Extract account ID from claims
-	claims := r.Context().Value(clKey)
-	var uuid uuid.UUID
-	uuid.Scan(claims.(*security.CustomClaims).ID)
+	// Extract account ID and session ID from claims
+	claims := r.Context().Value(clKey).(*security.CustomClaims)
+	var accountID, sessionID uuid.UUID
+	accountID.Scan(claims.ID)
+	if claims.SessionID == "" {
+		server.WriteErrorCode(w, http.StatusUnauthorized, CodeAuthTokenInvalid,
+			"Refresh token has no session, please login again", nil)
+		return
+	}
+	sessionID.Scan(claims.SessionID)
 
 	// Check if account status is active or not before continuing with the request
 	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /auth/token/refresh"))
-	if _, isActive := server.checkAccountStatus(w, r, uuid); isActive {
-		// Increase token version to logout (logout from all account)
-		err := server.query.IncrementTokenVersion(r.Context(), uuid)
-		if err != nil {
-			// If no account found with the account ID
-			if errors.Is(err, sql.ErrNoRows) {
-				server.WriteError(w, http.StatusBadRequest, "Account does not exist")
-				return
-			}
-
-			// Other database error
-			server.logger.Error("POST /auth/token/refresh: failed to increment token version", "error", err)
-			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
-			return
-		}
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
 
-		// Create new access token using the refresh token
-		newAccessToken, err := server.jwtService.CreateToken(claims.(*security.CustomClaims).ID, "access-token",
-			claims.(*security.CustomClaims).Version+1, server.jwtService.TokenExpirationTime)
+	// Only the current refresh token of the session can be used
+	session, err := server.query.GetSession(r.Context(), sessionID)
+	if err != nil {
+		server.logger.Error("POST /auth/token/refresh: failed to get session", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	tokenHash := security.HashToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if session.TokenHash != tokenHash {
+		_, err := server.query.RevokeSession(r.Context(), db.RevokeSessionParams{
+			SessionID: sessionID,
+			AccountID: accountID,
+		})
 		if err != nil {
-			server.logger.Error("POST /auth/token/refresh: failed to create new access token", "error", err)
+			server.logger.Error("POST /auth/token/refresh: failed to revoke session", "error", err)
 			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 			return
 		}
+		server.invalidateSession(r.Context(), sessionID)
 
-		server.WriteJSON(w, http.StatusOK, map[string]string{
-			"access_token": newAccessToken,
-		})
+		server.logger.Warn("POST /auth/token/refresh: refresh token reused, session revoked",
+			"session_id", sessionID, "ip", server.clientIP(r))
+		server.WriteErrorCode(w, http.StatusUnauthorized, CodeAuthTokenReused,
+			"Refresh token was already used, please login again", nil)
+		return
+	}
+
+	// Create new tokens for the session
+	newAccessToken, err := server.jwtService.CreateToken(claims.ID, "access-token", claims.SessionID,
+		claims.Version, server.jwtService.TokenExpirationTime)
+	if err != nil {
+		server.logger.Error("POST /auth/token/refresh: failed to create new access token", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	newRefreshToken, err := server.jwtService.CreateToken(claims.ID, "refresh-token", claims.SessionID,
+		claims.Version, server.jwtService.RefreshTokenExpirationTime)
+	if err != nil {
+		server.logger.Error("POST /auth/token/refresh: failed to create new refresh token", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Rotate the session, unless a concurrent request with the same refresh token did it first
+	rotated, err := server.query.RotateSession(r.Context(), db.RotateSessionParams{
+		NewTokenHash: security.HashToken(newRefreshToken),
+		Ip:           truncate(server.clientIP(r), maxIPLength),
+		ExpiresAt:    time.Now().Add(server.jwtService.RefreshTokenExpirationTime),
+		SessionID:    sessionID,
+		TokenHash:    tokenHash,
+	})
+	if err != nil {
+		server.logger.Error("POST /auth/token/refresh: failed to rotate session", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
 	}
+	if rotated == 0 {
+		server.WriteErrorCode(w, http.StatusUnauthorized, CodeAuthTokenReused, "Refresh token was already used", nil)
+		return
+	}
+	server.invalidateSession(r.Context(), sessionID)
+
+	server.WriteJSON(w, http.StatusOK, refreshResponse{
+		AccessToken:  newAccessToken,
+		RefreshToken: newRefreshToken,
+	})
 }
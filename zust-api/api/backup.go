@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"zust/service/file"
+	"zust/service/job"
+)
+
+// Helper function: get the file name of a backup archive created at 't'
+func backupName(t time.Time) string {
+	return fmt.Sprintf("zust-%s.tar.gz", t.UTC().Format("20060102T150405Z"))
+}
+
+// Helper function: check if the name in path parameter is the name of a backup archive, so it can never point
+// outside of the backup directory
+func isValidBackupName(name string) bool {
+	return filepath.Base(name) == name && strings.HasPrefix(name, "zust-") && strings.HasSuffix(name, ".tar.gz")
+}
+
+// Method to handle the backup job: every stored file referenced by the database (files of existing accounts and
+// videos) is written into a new archive of the backup directory. Orphans, caches and temporary files are skipped
+func (server *Server) handleBackupJob(ctx context.Context, j *job.Job) error {
+	owners, err := server.loadFileOwners(ctx)
+	if err != nil {
+		return err
+	}
+
+	files, err := server.storage.List("")
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for _, info := range files {
+		if owners.owns(info.Key) {
+			keys = append(keys, info.Key)
+		}
+	}
+
+	if err := os.MkdirAll(server.config.BackupPath, 0755); err != nil {
+		return err
+	}
+
+	// Write atomically, so a listed archive is always complete
+	path := filepath.Join(server.config.BackupPath, backupName(time.Now()))
+	archive, err := file.CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if err := file.WriteBackup(server.storage, keys, archive); err != nil {
+		return err
+	}
+	if err := archive.Commit(); err != nil {
+		return err
+	}
+
+	server.logger.Info("backup: archive created", "archive", filepath.Base(path), "files", len(keys))
+	return nil
+}
+
+// Payload of the restore job
+type restorePayload struct {
+	Name string `json:"name"`
+}
+
+// Method to handle the restore job: the files of the archive missing from the storage are restored, then the
+// repository of every account is rebuilt, so the directories and default images lost with the storage are back
+func (server *Server) handleRestoreJob(ctx context.Context, j *job.Job) error {
+	var payload restorePayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+	if !isValidBackupName(payload.Name) {
+		return fmt.Errorf("invalid backup name %q", payload.Name)
+	}
+
+	archive, err := os.Open(filepath.Join(server.config.BackupPath, payload.Name))
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	restored, err := file.RestoreBackup(server.storage, archive)
+	if err != nil {
+		return err
+	}
+
+	accountIDs, err := server.query.ListAccountIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range accountIDs {
+		if err := server.storage.EnsureUserRepo(id.String()); err != nil {
+			return err
+		}
+	}
+
+	server.logger.Info("backup: archive restored", "archive", payload.Name, "restored", restored)
+	return nil
+}
+
+// A backup archive in the backup directory
+type backupArchive struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HandleListBackups lists the backup archives, newest first, only available to admin.
+// endpoint: GET /admin/backups
+// Success: 200
+// Fail: 403, 500
+func (server *Server) HandleListBackups(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(server.config.BackupPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		server.logger.Error("GET /admin/backups: failed to read backup directory", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	archives := []backupArchive{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isValidBackupName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		archives = append(archives, backupArchive{Name: entry.Name(), Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+	sort.Slice(archives, func(i, j int) bool { return archives[i].CreatedAt.After(archives[j].CreatedAt) })
+
+	server.WriteJSON(w, http.StatusOK, archives)
+}
+
+// HandleCreateBackup starts a backup of the user repositories in background, only available to admin.
+// endpoint: POST /admin/backups
+// Success: 202
+// Fail: 403, 500
+func (server *Server) HandleCreateBackup(w http.ResponseWriter, r *http.Request) {
+	if err := server.jobs.Enqueue(r.Context(), job.TypeBackup, struct{}{}); err != nil {
+		server.logger.Error("POST /admin/backups: failed to enqueue backup job", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusAccepted, "Backup started")
+}
+
+// HandleRestoreBackup starts restoring a backup archive in background, only available to admin. Files still in the
+// storage are kept as is, only the missing ones are restored.
+// endpoint: POST /admin/backups/{name}/restore
+// Success: 202
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleRestoreBackup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !isValidBackupName(name) {
+		server.WriteError(w, http.StatusBadRequest, "Invalid backup name")
+		return
+	}
+
+	if _, err := os.Stat(filepath.Join(server.config.BackupPath, name)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			server.WriteError(w, http.StatusNotFound, "Cannot found any backup with this name")
+			return
+		}
+
+		server.logger.Error("POST /admin/backups/{name}/restore: failed to stat backup archive", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if err := server.jobs.Enqueue(r.Context(), job.TypeRestore, restorePayload{Name: name}); err != nil {
+		server.logger.Error("POST /admin/backups/{name}/restore: failed to enqueue restore job", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusAccepted, "Restore started")
+}
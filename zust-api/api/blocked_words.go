@@ -0,0 +1,346 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/security"
+	"zust/service/wordfilter"
+
+	"github.com/google/uuid"
+)
+
+// Maximum number of blocked words of a list
+const maxBlockedWords = 500
+
+// Lists of the blocked words: the site list maintained by the admins, and the list of a channel
+const (
+	blockedWordScopeSite    = "site"
+	blockedWordScopeChannel = "channel"
+)
+
+// Request body to add a blocked word to a list
+type blockedWordRequest struct {
+	Pattern string `json:"pattern" validate:"required,max=200"`
+	Mode    string `json:"mode" validate:"required,oneof=exact substring regex"`
+}
+
+// A blocked word of a list
+type blockedWordResult struct {
+	ID        string    `json:"id"`
+	Pattern   string    `json:"pattern"`
+	Mode      string    `json:"mode"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// A blocked word matching a text, the pattern of the site list is only shown to admins
+type blockedWordMatch struct {
+	Field   string `json:"field,omitempty"` // field of the video, for example: title
+	Scope   string `json:"scope"`           // site or channel
+	Pattern string `json:"pattern,omitempty"`
+	Mode    string `json:"mode"`
+	Text    string `json:"text"` // part of the text matched
+}
+
+// HandleListChannelBlockedWords lists the blocked words of the channel of the requester, applied to the titles and
+// descriptions of its videos on top of the site list.
+// endpoint: GET /accounts/{id}/blocked-words
+// Success: 200
+// Fail: 400, 401, 500
+func (server *Server) HandleListChannelBlockedWords(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "GET /accounts/{id}/blocked-words"))
+	server.listBlockedWords(w, r, uuid.NullUUID{UUID: accountID, Valid: true})
+}
+
+// HandleCreateChannelBlockedWord adds a blocked word to the list of the channel of the requester. 'mode' is exact
+// (whole words), substring or regex (RE2 syntax), the matching ignores the case. The videos already published are
+// not checked again.
+// endpoint: POST /accounts/{id}/blocked-words
+// Success: 201
+// Fail: 400, 401, 403, 409, 500
+func (server *Server) HandleCreateChannelBlockedWord(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /accounts/{id}/blocked-words"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+	server.createBlockedWord(w, r, uuid.NullUUID{UUID: accountID, Valid: true})
+}
+
+// HandleDeleteChannelBlockedWord removes a blocked word from the list of the channel of the requester.
+// endpoint: DELETE /accounts/{id}/blocked-words/{word_id}
+// Success: 200
+// Fail: 400, 401, 404, 500
+func (server *Server) HandleDeleteChannelBlockedWord(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "DELETE /accounts/{id}/blocked-words/{word_id}"))
+	server.deleteBlockedWord(w, r, uuid.NullUUID{UUID: accountID, Valid: true}, r.PathValue("word_id"))
+}
+
+// HandleListSiteBlockedWords lists the blocked words of the site list, applied to the titles and descriptions of
+// the videos of all channels, only available to admin.
+// endpoint: GET /admin/blocked-words
+// Success: 200
+// Fail: 403, 500
+func (server *Server) HandleListSiteBlockedWords(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "GET /admin/blocked-words"))
+	server.listBlockedWords(w, r, uuid.NullUUID{})
+}
+
+// HandleCreateSiteBlockedWord adds a blocked word to the site list, only available to admin. The modes are the ones
+// of the channel lists.
+// endpoint: POST /admin/blocked-words
+// Success: 201
+// Fail: 400, 403, 409, 500
+func (server *Server) HandleCreateSiteBlockedWord(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /admin/blocked-words"))
+	server.createBlockedWord(w, r, uuid.NullUUID{})
+}
+
+// HandleDeleteSiteBlockedWord removes a blocked word from the site list, only available to admin.
+// endpoint: DELETE /admin/blocked-words/{id}
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleDeleteSiteBlockedWord(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "DELETE /admin/blocked-words/{id}"))
+	server.deleteBlockedWord(w, r, uuid.NullUUID{}, r.PathValue("id"))
+}
+
+// Request body to test a phrase against the blocked words
+type testBlockedWordsRequest struct {
+	Text string `json:"text" validate:"required,max=5000"`
+}
+
+// Response body for TestBlockedWords
+type testBlockedWordsResponse struct {
+	Blocked bool               `json:"blocked"`
+	Matches []blockedWordMatch `json:"matches"`
+}
+
+// HandleTestBlockedWords tests a phrase against the lists applied to the videos of the requester: the site list and
+// the list of its channel. The patterns of the site list are only shown to admins.
+// endpoint: POST /blocked-words/test
+// Success: 200
+// Fail: 400, 401, 500
+func (server *Server) HandleTestBlockedWords(w http.ResponseWriter, r *http.Request) {
+	// Get request body
+	var req testBlockedWordsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	filter, err := server.blockedWordFilter(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("POST /blocked-words/test: failed to load blocked words", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	role, err := server.query.GetAccountRole(r.Context(), accountID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		server.logger.Error("POST /blocked-words/test: failed to get account role", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := testBlockedWordsResponse{Matches: toBlockedWordMatches("", filter.Match(req.Text), role == db.AccountRoleAdmin)}
+	data.Blocked = len(data.Matches) > 0
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Helper method: write the blocked words of a list, the site list if 'owner' is not set
+func (server *Server) listBlockedWords(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	words, err := server.query.ListBlockedWords(r.Context(), owner)
+	if err != nil {
+		server.logger.Error(fmt.Sprintf("%s: failed to list blocked words", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]blockedWordResult, 0, len(words))
+	for _, word := range words {
+		data = append(data, toBlockedWordResult(word))
+	}
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Helper method: add the blocked word of the request body to a list, the site list if 'owner' is not set
+func (server *Server) createBlockedWord(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	// Get request body
+	var req blockedWordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body, the pattern must compile
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+	if _, err := wordfilter.Compile(wordfilter.Mode(req.Mode), req.Pattern); err != nil {
+		server.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Invalid pattern: %s", err))
+		return
+	}
+
+	// Check the number of blocked words of the list
+	count, err := server.query.CountBlockedWords(r.Context(), owner)
+	if err != nil {
+		server.logger.Error(fmt.Sprintf("%s: failed to count blocked words", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if count >= maxBlockedWords {
+		server.WriteErrorCode(w, http.StatusConflict, CodeBlockedWordLimitReached, "Too many blocked words", nil)
+		return
+	}
+
+	word, err := server.query.CreateBlockedWord(r.Context(), db.CreateBlockedWordParams{
+		AccountID: owner,
+		Pattern:   req.Pattern,
+		Mode:      db.BlockedWordMode(req.Mode),
+	})
+	if err != nil {
+		// Nothing is inserted when the list already has the word
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteError(w, http.StatusConflict, "Blocked word is already in the list")
+			return
+		}
+		server.logger.Error(fmt.Sprintf("%s: failed to create blocked word", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusCreated, toBlockedWordResult(word))
+}
+
+// Helper method: remove a blocked word from a list, the site list if 'owner' is not set
+func (server *Server) deleteBlockedWord(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID, id string) {
+	var wordID uuid.UUID
+	if err := wordID.Scan(id); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid blocked word ID")
+		return
+	}
+
+	deleted, err := server.query.DeleteBlockedWord(r.Context(), db.DeleteBlockedWordParams{
+		BlockedWordID: wordID,
+		AccountID:     owner,
+	})
+	if err != nil {
+		server.logger.Error(fmt.Sprintf("%s: failed to delete blocked word", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeBlockedWordNotFound, "Blocked word not found", nil)
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Blocked word deleted successfully")
+}
+
+// Method to get the filter of the blocked words applied to the videos of a channel: the site list and the list of
+// the channel
+func (server *Server) blockedWordFilter(ctx context.Context, accountID uuid.UUID) (*wordfilter.Filter, error) {
+	words, err := server.query.ListActiveBlockedWords(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]wordfilter.Rule, 0, len(words))
+	for _, word := range words {
+		scope := blockedWordScopeSite
+		if word.AccountID.Valid {
+			scope = blockedWordScopeChannel
+		}
+		rules = append(rules, wordfilter.Rule{
+			ID:      word.BlockedWordID.String(),
+			Pattern: word.Pattern,
+			Mode:    wordfilter.Mode(word.Mode),
+			Scope:   scope,
+		})
+	}
+	return wordfilter.New(rules)
+}
+
+// Helper method: check the title and the description of a video of a channel against the blocked words. If any of
+// them matches, 422 is written with the matches in details and false is returned
+func (server *Server) checkBlockedWords(w http.ResponseWriter, r *http.Request, publisherID uuid.UUID, title,
+	description string) bool {
+	filter, err := server.blockedWordFilter(r.Context(), publisherID)
+	if err != nil {
+		server.logger.Error(fmt.Sprintf("%s: failed to load blocked words", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return false
+	}
+
+	matches := toBlockedWordMatches("title", filter.Match(title), false)
+	matches = append(matches, toBlockedWordMatches("description", filter.Match(description), false)...)
+	if len(matches) > 0 {
+		server.WriteErrorCode(w, http.StatusUnprocessableEntity, CodeBlockedWords,
+			"Title or description contains blocked words", matches)
+		return false
+	}
+	return true
+}
+
+// Helper function: convert a blocked word into its response
+func toBlockedWordResult(word db.BlockedWord) blockedWordResult {
+	return blockedWordResult{
+		ID:        word.BlockedWordID.String(),
+		Pattern:   word.Pattern,
+		Mode:      string(word.Mode),
+		CreatedAt: word.CreatedAt,
+	}
+}
+
+// Helper function: convert the matches of a field into their response, the patterns of the site list are hidden
+// unless 'showSite' is set
+func toBlockedWordMatches(field string, matches []wordfilter.Match, showSite bool) []blockedWordMatch {
+	results := make([]blockedWordMatch, 0, len(matches))
+	for _, match := range matches {
+		result := blockedWordMatch{
+			Field: field,
+			Scope: match.Rule.Scope,
+			Mode:  string(match.Rule.Mode),
+			Text:  match.Text,
+		}
+		if showSite || match.Rule.Scope != blockedWordScopeSite {
+			result.Pattern = match.Rule.Pattern
+		}
+		results = append(results, result)
+	}
+	return results
+}
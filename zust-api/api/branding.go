@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"zust/service/file"
+
+	"github.com/google/uuid"
+)
+
+// Helper function: check if the branding kind in path parameter is supported
+func isValidBranding(kind string) bool {
+	return kind == file.BrandingIntro || kind == file.BrandingOutro
+}
+
+// HandleSetBranding uploads the intro or outro clip of a channel (multipart form with the 'video' file), which is
+// stitched onto the videos uploaded with branding enabled. A clip cannot be longer than 30 seconds.
+// endpoint: PUT /accounts/{id}/branding/{kind}, kind is 'intro' or 'outro'
+// Success: 200
+// Fail: 400, 403, 413, 415, 422, 500, 507
+func (server *Server) HandleSetBranding(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	kind := r.PathValue("kind")
+	if !isValidBranding(kind) {
+		server.WriteError(w, http.StatusBadRequest, "Branding must be intro or outro")
+		return
+	}
+
+	// Check account status if it's active or not before processing with the request
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "PUT /accounts/{id}/branding/{kind}"))
+	if _, isActive := server.checkAccountStatus(w, r, accID); !isActive {
+		return
+	}
+
+	// Reject the upload early if the storage is almost full
+	if !server.checkStorageSpace(w) {
+		return
+	}
+
+	// Parse request multipart form data
+	clip, _, err := r.FormFile("video")
+	if err != nil {
+		server.writeFormError(w, err, "Failed to read uploaded video")
+		return
+	}
+	defer clip.Close()
+	if !server.checkUploadType(w, clip, file.IsVideo, "Uploaded file is not a video") {
+		return
+	}
+
+	// Save into a temporary file first, so the current clip is kept if the new one is rejected
+	path := server.localPath(file.BrandingKey(accID.String(), kind))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		server.logger.Error("PUT /accounts/{id}/branding/{kind}: failed to create branding directory", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	tmp := file.TempPath(path)
+	defer os.Remove(tmp)
+
+	dest, err := os.Create(tmp)
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/branding/{kind}: failed to create branding file", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	_, err = io.Copy(dest, clip)
+	dest.Close()
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Failed to read uploaded video")
+		return
+	}
+
+	// The clip follows the same upload limits as videos, and must be short
+	if err := server.checkUploadedVideo(tmp); err != nil {
+		var mediaErrs file.MediaErrors
+		if errors.As(err, &mediaErrs) {
+			server.WriteErrorCode(w, http.StatusUnprocessableEntity, CodeVideoNotSupported,
+				"Uploaded video is not supported",
+				mediaErrs)
+			return
+		}
+		if errors.Is(err, file.ErrUnsupportedMedia) {
+			server.WriteError(w, http.StatusUnprocessableEntity, "Uploaded file is not a valid video")
+			return
+		}
+
+		server.logger.Error("PUT /accounts/{id}/branding/{kind}: failed to probe uploaded video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	duration, err := server.mediaService.GetVideoDuration(tmp)
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/branding/{kind}: failed to get video duration", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if duration > file.MaxBrandingDuration {
+		server.WriteError(w, http.StatusUnprocessableEntity, "Branding clip cannot be longer than 30 seconds")
+		return
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		server.logger.Error("PUT /accounts/{id}/branding/{kind}: failed to save branding file", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Branding updated successfully")
+}
+
+// HandleDeleteBranding removes the intro or outro clip of a channel.
+// endpoint: DELETE /accounts/{id}/branding/{kind}, kind is 'intro' or 'outro'
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleDeleteBranding(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	kind := r.PathValue("kind")
+	if !isValidBranding(kind) {
+		server.WriteError(w, http.StatusBadRequest, "Branding must be intro or outro")
+		return
+	}
+
+	// Check account status if it's active or not before processing with the request
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "DELETE /accounts/{id}/branding/{kind}"))
+	if _, isActive := server.checkAccountStatus(w, r, accID); !isActive {
+		return
+	}
+
+	if err := server.storage.Delete(file.BrandingKey(accID.String(), kind)); err != nil {
+		server.logger.Error("DELETE /accounts/{id}/branding/{kind}: failed to remove branding file", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Branding removed successfully")
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/cache"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Method to get the storage key of a media ID (see ExtractFileKey), cached since every media request decodes it
+func (server *Server) mediaFileKey(ctx context.Context, opaqueID string) string {
+	key, _ := cache.Fetch(ctx, server.cache, "media:"+opaqueID, func() (string, error) {
+		return server.mediaService.ExtractFileKey(opaqueID), nil
+	})
+	return key
+}
+
+// Method to get a video with its publisher and counters, cached until the video is written or the entry expires.
+// The videos of other tenants are not found
+func (server *Server) getVideo(ctx context.Context, videoID uuid.UUID) (db.GetVideoRow, error) {
+	video, err := cache.Fetch(ctx, server.cache, "video:"+videoID.String(), func() (db.GetVideoRow, error) {
+		return server.query.GetVideo(ctx, videoID)
+	})
+	if err != nil {
+		return video, err
+	}
+
+	if ok, err := server.inTenant(ctx, video.AccountID); err != nil {
+		return db.GetVideoRow{}, err
+	} else if !ok {
+		return db.GetVideoRow{}, sql.ErrNoRows
+	}
+	return video, nil
+}
+
+// Method to get a video like getVideo, but read from the database for the requests which must see the latest write
+// (for example: the version of an edit). The videos of other tenants are not found
+func (server *Server) getLatestVideo(ctx context.Context, videoID uuid.UUID) (db.GetVideoRow, error) {
+	video, err := server.query.GetVideo(ctx, videoID)
+	if err != nil {
+		return video, err
+	}
+
+	if ok, err := server.inTenant(ctx, video.AccountID); err != nil {
+		return db.GetVideoRow{}, err
+	} else if !ok {
+		return db.GetVideoRow{}, sql.ErrNoRows
+	}
+	return video, nil
+}
+
+// Method to get the fields of a video deciding who can access its files, cached like getVideo. The videos of other
+// tenants are not found
+func (server *Server) getVideoAccess(ctx context.Context, videoID uuid.UUID) (db.GetVideoAccessRow, error) {
+	access, err := cache.Fetch(ctx, server.cache, "video_access:"+videoID.String(),
+		func() (db.GetVideoAccessRow, error) {
+			return server.query.GetVideoAccess(ctx, videoID)
+		})
+	if err != nil {
+		return access, err
+	}
+
+	if ok, err := server.inTenant(ctx, access.PublisherID); err != nil {
+		return db.GetVideoAccessRow{}, err
+	} else if !ok {
+		return db.GetVideoAccessRow{}, sql.ErrNoRows
+	}
+	return access, nil
+}
+
+// Method to get the profile of an account, cached until the account is written or the entry expires. The accounts
+// of other tenants are not found
+func (server *Server) getProfile(ctx context.Context, accountID uuid.UUID) (db.GetProfileRow, error) {
+	profile, err := cache.Fetch(ctx, server.cache, "profile:"+accountID.String(), func() (db.GetProfileRow, error) {
+		return server.query.GetProfile(ctx, accountID)
+	})
+	if err != nil {
+		return profile, err
+	}
+
+	if ok, err := server.inTenant(ctx, accountID); err != nil {
+		return db.GetProfileRow{}, err
+	} else if !ok {
+		return db.GetProfileRow{}, sql.ErrNoRows
+	}
+	return profile, nil
+}
+
+// Method to remove the cached rows of videos, called after the videos are written
+func (server *Server) invalidateVideos(ctx context.Context, videoIDs ...uuid.UUID) {
+	keys := make([]string, 0, 2*len(videoIDs))
+	for _, id := range videoIDs {
+		keys = append(keys, "video:"+id.String(), "video_access:"+id.String())
+	}
+	server.cache.Delete(ctx, keys...)
+}
+
+// Method to remove the cached rows of all the videos of an account, called after the account is deleted so its videos
+// are no longer served. Failure is only logged
+func (server *Server) invalidateAccountVideos(ctx context.Context, accountID uuid.UUID) {
+	videoIDs, err := server.query.ListPublisherVideoIDs(ctx, accountID)
+	if err != nil {
+		server.logger.Error("cache: failed to list videos of account", "account_id", accountID, "error", err)
+		return
+	}
+	server.invalidateVideos(ctx, videoIDs...)
+}
+
+// Method to remove the cached profile of an account, called after the account is written
+func (server *Server) invalidateProfile(ctx context.Context, accountID uuid.UUID) {
+	server.cache.Delete(ctx, "profile:"+accountID.String())
+}
+
+// Store of the token versions checked by VerifyToken, read through the cache of the server. The token version of an
+// account and the active sessions are cached separately, so bumping the token version of an account invalidates the
+// tokens of all its sessions at once
+type tokenVersionCache struct {
+	server *Server
+}
+
+// Method to get the store of the token versions to verify the tokens with
+func (server *Server) tokenVersions() security.TokenVersionStore {
+	return tokenVersionCache{server: server}
+}
+
+// Method to get the token version of an account, cached until the version is bumped or the entry expires
+func (store tokenVersionCache) GetTokenVersion(ctx context.Context, accountID uuid.UUID) (int32, error) {
+	server := store.server
+	return cache.FetchTTL(ctx, server.cache, "token_version:"+accountID.String(), server.config.TokenCacheTTL,
+		func() (int32, error) {
+			return server.query.GetTokenVersion(ctx, accountID)
+		})
+}
+
+// Method to get the token version of the account of an active session, cached until the session is revoked or
+// rotated, or the entry expires. A revoked session is never cached
+func (store tokenVersionCache) GetSessionTokenVersion(ctx context.Context,
+	arg db.GetSessionTokenVersionParams) (int32, error) {
+	server := store.server
+	session, err := cache.FetchTTL(ctx, server.cache, "session:"+arg.SessionID.String(), server.config.TokenCacheTTL,
+		func() (db.GetActiveSessionRow, error) {
+			return server.query.GetActiveSession(ctx, arg.SessionID)
+		})
+	if err != nil {
+		return 0, err
+	}
+	if session.AccountID != arg.AccountID || !session.ExpiresAt.After(time.Now()) {
+		return 0, sql.ErrNoRows
+	}
+	return store.GetTokenVersion(ctx, arg.AccountID)
+}
+
+// Method to remove the cached token version of an account, called after the version is bumped
+func (server *Server) invalidateTokenVersion(ctx context.Context, accountID uuid.UUID) {
+	server.cache.Delete(ctx, "token_version:"+accountID.String())
+}
+
+// Method to remove the cached session, called after the session is revoked or rotated
+func (server *Server) invalidateSession(ctx context.Context, sessionID uuid.UUID) {
+	server.cache.Delete(ctx, "session:"+sessionID.String())
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	db "zust/db/sqlc"
+	"zust/service/file"
+	"zust/service/job"
+	"zust/service/transcription"
+
+	"github.com/google/uuid"
+)
+
+// Payload of the caption job
+type captionPayload struct {
+	VideoID     uuid.UUID `json:"video_id"`
+	PublisherID uuid.UUID `json:"publisher_id"`
+}
+
+// Method to put a video into the caption queue, run after the video is transcoded
+func (server *Server) enqueueCaption(ctx context.Context, videoID, publisherID uuid.UUID) error {
+	payload := captionPayload{VideoID: videoID, PublisherID: publisherID}
+	return server.jobs.Enqueue(ctx, job.TypeCaption, payload, job.WithMaxAttempts(server.config.TranscodeRetries+1))
+}
+
+// Method to handle the caption job: the audio of the video is transcribed into WebVTT captions, stored in the
+// subtitles directory and marked as auto-generated. Subtitles uploaded by the publisher in the same language
+// are never replaced
+func (server *Server) handleCaptionJob(ctx context.Context, j *job.Job) error {
+	var payload captionPayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+	if server.transcriber == nil {
+		return fmt.Errorf("transcription is disabled")
+	}
+
+	// Work on a local copy, so the job runs on any machine which can reach the storage
+	work, err := os.MkdirTemp("", "zust-caption-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+
+	// Transcribe the original, or the best rendition if the original is removed by retention policy
+	accID, vidID := payload.PublisherID.String(), payload.VideoID.String()
+	input := filepath.Join(work, vidID+".mp4")
+	err = file.Fetch(server.storage, file.OriginalKey(accID, vidID), input)
+	if errors.Is(err, fs.ErrNotExist) {
+		if best := file.BestRendition(server.storage, accID, vidID); best != "" {
+			err = file.Fetch(server.storage, path.Join(path.Dir(file.OriginalKey(accID, vidID)), best), input)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	audio := filepath.Join(work, vidID+".wav")
+	if err := server.mediaService.ExtractAudio(ctx, input, audio); err != nil {
+		return err
+	}
+
+	transcript, err := server.transcriber.Transcribe(ctx, audio)
+	if err != nil {
+		return err
+	}
+
+	if !transcription.IsValidLanguage(transcript.Language) {
+		return fmt.Errorf("invalid transcript language %q", transcript.Language)
+	}
+
+	subtitles, err := server.query.ListSubtitles(ctx, payload.VideoID)
+	if err != nil {
+		return err
+	}
+	for _, subtitle := range subtitles {
+		if subtitle.Language == transcript.Language && !subtitle.AutoGenerated {
+			return nil
+		}
+	}
+
+	key := file.SubtitleKey(payload.PublisherID.String(), payload.VideoID.String(), transcript.Language)
+	if err := server.storage.Put(key, strings.NewReader(transcript.VTT)); err != nil {
+		return err
+	}
+
+	return server.query.UpsertAutoSubtitle(ctx, db.UpsertAutoSubtitleParams{
+		VideoID:  payload.VideoID,
+		Language: transcript.Language,
+	})
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	db "zust/db/sqlc"
+	"zust/service/cache"
+	"zust/service/file"
+	"zust/service/mail"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// NewControl creates the server used by zustctl, which runs the operational tasks against the database, the
+// storage and the job queue directly. Unlike the API server and the workers, it doesn't need ffmpeg and doesn't run
+// any job: the jobs it enqueues (emails, transcodes) are run by the API server or the workers
+func NewControl(conn *sql.DB, config *security.Config, logger *slog.Logger) (*Server, error) {
+	mailService, err := mail.NewEmailService(config)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		query:        newStore(conn, config, logger),
+		jwtService:   security.NewJWTService(config),
+		mailService:  mailService,
+		localStorage: file.NewLocalStorage(config),
+		cache:        cache.NewCache(config),
+		logger:       logger,
+		config:       config,
+	}
+	server.storage = server.localStorage
+	server.setupTenants()
+	server.jobs = newJobQueue(server.query, config, logger)
+	return server, nil
+}
+
+// Method to create an active admin account with a password, with its repository. Admins manage the whole
+// deployment, so they belong to the default tenant
+func (server *Server) CreateAdmin(ctx context.Context, email, username, password string) (uuid.UUID, error) {
+	hashedPassword, err := security.BcryptHash(password)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var accountID uuid.UUID
+	err = server.query.ExecTx(ctx, func(q *db.Queries) error {
+		account, err := q.CreateAccountWithPassword(ctx, db.CreateAccountWithPasswordParams{
+			TenantID: security.DefaultTenant,
+			Email:    email,
+			Username: username,
+			Password: sql.NullString{String: hashedPassword, Valid: true},
+		})
+		if err != nil {
+			err = db.MapError(err)
+			if errors.Is(err, db.ErrDuplicateEmail) {
+				return fmt.Errorf("email %s is already taken", email)
+			}
+			if errors.Is(err, db.ErrDuplicateUsername) {
+				return fmt.Errorf("username %s is already taken", username)
+			}
+			return err
+		}
+		accountID = account.AccountID
+
+		if err := q.SetAccountStatus(ctx, db.SetAccountStatusParams{
+			AccountID: accountID,
+			Status:    db.AccountStatusActive,
+		}); err != nil {
+			return err
+		}
+		return q.SetAccountRole(ctx, db.SetAccountRoleParams{AccountID: accountID, Role: db.AccountRoleAdmin})
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return accountID, server.storage.EnsureUserRepo(accountID.String())
+}
+
+// Method to send the verification email again to an inactive account of a tenant
+func (server *Server) ResendVerification(ctx context.Context, tenant, email string) error {
+	account, err := server.query.GetAccountByEmail(ctx, db.GetAccountByEmailParams{TenantID: tenant, Email: email})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("account with email %s does not exist", email)
+		}
+		return err
+	}
+
+	if account.Status != db.AccountStatusInactive {
+		return fmt.Errorf("account is %s", account.Status)
+	}
+	return server.sendVerificationEmail(ctx, tenant, account.AccountID.String(), account.Username, account.Email)
+}
+
+// Method to put a video back into the transcode queue, for example after a failed transcoding. The original upload
+// must still be in the storage
+func (server *Server) RequeueTranscode(ctx context.Context, videoID uuid.UUID, branding bool) error {
+	video, err := server.query.GetVideo(ctx, videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("video %s does not exist", videoID)
+		}
+		return err
+	}
+
+	if video.Status != db.VideoStatusPending && video.Status != db.VideoStatusFailed {
+		return fmt.Errorf("video is %s, only pending or failed videos can be transcoded", video.Status)
+	}
+	return server.enqueueTranscode(ctx, videoID, video.AccountID, branding)
+}
+
+// Method to suspend (ban) an account, or reinstate it when 'suspend' is false. Suspending an account revokes its
+// tokens, so it's logged out of all its sessions
+func (server *Server) SuspendAccount(ctx context.Context, accountID uuid.UUID, suspend bool) error {
+	profile, err := server.query.GetProfile(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("account %s does not exist", accountID)
+		}
+		return err
+	}
+
+	status := db.AccountStatusBanned
+	if !suspend {
+		if profile.Status != db.AccountStatusBanned {
+			return fmt.Errorf("account is %s, only suspended accounts can be reinstated", profile.Status)
+		}
+		status = db.AccountStatusActive
+	}
+
+	err = server.query.ExecTx(ctx, func(q *db.Queries) error {
+		if err := q.SetAccountStatus(ctx, db.SetAccountStatusParams{AccountID: accountID, Status: status}); err != nil {
+			return err
+		}
+		if suspend {
+			return q.IncrementTokenVersion(ctx, accountID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	server.invalidateProfile(ctx, accountID)
+	server.invalidateTokenVersion(ctx, accountID)
+	return nil
+}
+
+// Storage used by an account
+type AccountUsage struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Files     int       `json:"files"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// Space of the storage and the accounts using most of it
+type StorageUsage struct {
+	Health   *file.StorageHealth `json:"health"`
+	Files    int                 `json:"files"`
+	Bytes    int64               `json:"bytes"`
+	Accounts []AccountUsage      `json:"accounts"`
+}
+
+// Method to compute the storage usage, with the 'top' accounts using most of the storage (all accounts if top <= 0)
+func (server *Server) StorageUsage(ctx context.Context, top int) (*StorageUsage, error) {
+	health, err := server.storage.Health()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := server.query.ListAccountIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &StorageUsage{Health: health}
+	for _, id := range ids {
+		files, err := server.storage.List(id.String() + "/")
+		if err != nil {
+			return nil, err
+		}
+
+		account := AccountUsage{AccountID: id, Files: len(files)}
+		for _, info := range files {
+			account.Bytes += info.Size
+		}
+		usage.Files += account.Files
+		usage.Bytes += account.Bytes
+		usage.Accounts = append(usage.Accounts, account)
+	}
+
+	slices.SortFunc(usage.Accounts, func(a, b AccountUsage) int {
+		return cmp.Compare(b.Bytes, a.Bytes)
+	})
+	if top > 0 && len(usage.Accounts) > top {
+		usage.Accounts = usage.Accounts[:top]
+	}
+	return usage, nil
+}
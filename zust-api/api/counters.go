@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Method to recompute the like, view and subscriber counters from their source tables. The counters are maintained
+// along with the source tables, so a counter drifting means an update was missed (or made out of the application):
+// each drift is logged before it's fixed
+func (server *Server) runCounterReconcileJob(ctx context.Context) {
+	videos, err := server.query.ReconcileVideoCounters(ctx)
+	if err != nil {
+		server.logger.Error("counters: failed to reconcile video counters", "error", err)
+		return
+	}
+
+	videoIDs := make([]uuid.UUID, 0, len(videos))
+	for _, video := range videos {
+		server.logger.Warn("counters: video counters drifted", "video_id", video.VideoID,
+			"likes", video.OldLikes, "actual_likes", video.Likes, "views", video.OldViews, "actual_views", video.Views)
+		videoIDs = append(videoIDs, video.VideoID)
+	}
+	server.invalidateVideos(ctx, videoIDs...)
+
+	accounts, err := server.query.ReconcileSubscriberCounters(ctx)
+	if err != nil {
+		server.logger.Error("counters: failed to reconcile subscriber counters", "error", err)
+		return
+	}
+	for _, account := range accounts {
+		server.logger.Warn("counters: subscriber counter drifted", "account_id", account.AccountID,
+			"subscribers", account.OldSubscribers, "actual_subscribers", account.Subscribers)
+		server.invalidateProfile(ctx, account.AccountID)
+	}
+
+	server.logger.Info("counters: counters reconciled", "drifted_videos", len(videos),
+		"drifted_accounts", len(accounts))
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/job"
+	"zust/service/mail"
+	"zust/service/security"
+)
+
+// Number of accounts claimed at once by the digest job, and maximum number of videos in a digest (the latest ones)
+const (
+	digestBatchSize = 100
+	digestMaxVideos = 20
+)
+
+// Period covered by a digest of each frequency, the first digest of an account covers one period
+var digestPeriods = map[db.DigestFrequency]time.Duration{
+	db.DigestFrequencyDaily:  24 * time.Hour,
+	db.DigestFrequencyWeekly: 7 * 24 * time.Hour,
+}
+
+// Method to send the digests which are due: the accounts are due once DIGEST_HOUR is passed in their timezone (on
+// Mondays for the weekly digest), and no digest was sent to them this day. The accounts are claimed before their
+// digest is sent, so several instances never send the same digest twice. An account without new video gets no email
+func (server *Server) runDigestJob(ctx context.Context) {
+	for {
+		recipients, err := server.query.ClaimDigestRecipients(ctx, db.ClaimDigestRecipientsParams{
+			SendHour: int32(server.config.DigestHour),
+			PageSize: digestBatchSize,
+		})
+		if err != nil {
+			server.logger.Error("digest: failed to claim digest recipients", "error", err)
+			return
+		}
+
+		for _, recipient := range recipients {
+			if err := server.sendDigest(ctx, recipient); err != nil {
+				server.logger.Error("digest: failed to send digest", "account_id", recipient.AccountID, "error", err)
+			}
+		}
+
+		if len(recipients) < digestBatchSize || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Helper method: send the digest of the videos published since the previous digest of an account, at most one
+// period back
+func (server *Server) sendDigest(ctx context.Context, recipient db.ClaimDigestRecipientsRow) error {
+	since := time.Now().Add(-digestPeriods[recipient.DigestFrequency])
+	if recipient.PreviousSentAt.Valid && recipient.PreviousSentAt.Time.After(since) {
+		since = recipient.PreviousSentAt.Time
+	}
+
+	videos, err := server.query.ListDigestVideos(ctx, db.ListDigestVideosParams{
+		SubscriberID: recipient.AccountID,
+		Since:        since,
+		PageSize:     digestMaxVideos,
+	})
+	if err != nil {
+		return err
+	}
+	if len(videos) == 0 {
+		return nil
+	}
+
+	// Prepare email body, with links to the site of the tenant of the account
+	domain := server.config.ForTenant(recipient.TenantID).Domain
+	unsubscribeLink := fmt.Sprintf("http://%s:%s/unsubscribe?token=%s", domain, server.config.Port,
+		security.SignUnsubscribeToken(server.config.SecretKey, recipient.AccountID.String(), security.UnsubscribeDigest))
+	payload := mail.DigestEmailPayload{
+		Username:        recipient.Username,
+		Frequency:       string(recipient.DigestFrequency),
+		Videos:          make([]mail.DigestVideo, len(videos)),
+		UnsubscribeLink: unsubscribeLink,
+	}
+	for i, video := range videos {
+		payload.Videos[i] = mail.DigestVideo{
+			Title:    video.Title,
+			Channel:  video.Username,
+			Duration: (time.Duration(video.Duration) * time.Second).String(),
+			Link:     fmt.Sprintf("http://%s:%s/videos/%s", domain, server.config.Port, video.VideoID),
+		}
+	}
+	body, text, err := server.mailService.PrepareBodies("digest", payload)
+	if err != nil {
+		return err
+	}
+
+	// Send email in background, the mail clients show their own unsubscribe button from the List-Unsubscribe
+	// headers, and unsubscribe in one click with a POST (RFC 8058)
+	return server.jobs.Enqueue(ctx, job.TypeSendEmail, sendEmailPayload{
+		To:      recipient.Email,
+		Subject: fmt.Sprintf("Zust - Your %s digest of new videos", recipient.DigestFrequency),
+		Body:    body,
+		Text:    text,
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + unsubscribeLink + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorCode is the machine-readable code of an error response. Clients should match the code instead of the
+// message, which is meant for humans and can change
+type ErrorCode string
+
+// Generic codes, used by WriteError from the status when no specific code applies
+const (
+	CodeBadRequest           ErrorCode = "BAD_REQUEST"            // 400
+	CodeUnauthorized         ErrorCode = "UNAUTHORIZED"           // 401
+	CodeForbidden            ErrorCode = "FORBIDDEN"              // 403
+	CodeNotFound             ErrorCode = "NOT_FOUND"              // 404
+	CodeConflict             ErrorCode = "CONFLICT"               // 409
+	CodeBodyTooLarge         ErrorCode = "REQUEST_BODY_TOO_LARGE" // 413
+	CodeUnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE" // 415
+	CodeUnprocessable        ErrorCode = "UNPROCESSABLE_ENTITY"   // 422
+	CodeRateLimited          ErrorCode = "RATE_LIMITED"           // 429
+	CodeInternal             ErrorCode = "INTERNAL_ERROR"         // 500
+	CodeUnavailable          ErrorCode = "SERVICE_UNAVAILABLE"    // 503
+	CodeGatewayTimeout       ErrorCode = "GATEWAY_TIMEOUT"        // 504
+	CodeStorageFull          ErrorCode = "STORAGE_FULL"           // 507
+)
+
+// Specific codes
+const (
+	// Authentication
+	CodeAuthMissingToken       ErrorCode = "AUTH_MISSING_TOKEN"       // no access token in the Authorization header
+	CodeAuthTokenExpired       ErrorCode = "AUTH_TOKEN_EXPIRED"       // the access token must be refreshed
+	CodeAuthTokenInvalid       ErrorCode = "AUTH_TOKEN_INVALID"       // malformed, revoked or wrong type of token
+	CodeAuthInvalidCredentials ErrorCode = "AUTH_INVALID_CREDENTIALS" // wrong username or password
+	CodeAuthTokenReused        ErrorCode = "AUTH_TOKEN_REUSED"        // a rotated refresh token is replayed
+	CodeSessionNotFound        ErrorCode = "SESSION_NOT_FOUND"
+	CodeVerificationInvalid    ErrorCode = "VERIFICATION_TOKEN_INVALID"
+	CodeVerificationExpired    ErrorCode = "VERIFICATION_TOKEN_EXPIRED"
+	CodeUnsubscribeInvalid     ErrorCode = "UNSUBSCRIBE_TOKEN_INVALID"
+
+	// Accounts
+	CodeAccountNotFound  ErrorCode = "ACCOUNT_NOT_FOUND"
+	CodeAccountNotActive ErrorCode = "ACCOUNT_NOT_ACTIVE" // not verified yet, or locked
+	CodeUsernameTaken    ErrorCode = "USERNAME_TAKEN"
+	CodeEmailTaken       ErrorCode = "EMAIL_TAKEN"
+
+	// Step-up verification
+	CodeSMSNotEnabled    ErrorCode = "SMS_NOT_ENABLED" // no SMS provider configured
+	CodePhoneNotVerified ErrorCode = "PHONE_NOT_VERIFIED"
+	CodeStepUpRequired   ErrorCode = "STEP_UP_REQUIRED"     // the action needs a code sent to the phone number
+	CodeStepUpInvalid    ErrorCode = "STEP_UP_CODE_INVALID" // wrong, expired or already used code
+
+	// Notifications
+	CodePushNotEnabled           ErrorCode = "PUSH_NOT_ENABLED" // no VAPID key configured
+	CodePushSubscriptionNotFound ErrorCode = "PUSH_SUBSCRIPTION_NOT_FOUND"
+
+	// Webhooks
+	CodeWebhookNotFound     ErrorCode = "WEBHOOK_NOT_FOUND"
+	CodeWebhookLimitReached ErrorCode = "WEBHOOK_LIMIT_REACHED"
+
+	// Moderation
+	CodeAlreadyReported         ErrorCode = "ALREADY_REPORTED" // the reporter already has an open report of the target
+	CodeReportNotFound          ErrorCode = "REPORT_NOT_FOUND" // no open report of the target
+	CodeBlockedWords            ErrorCode = "BLOCKED_WORDS"    // the text contains words of a blocked-word list
+	CodeBlockedWordNotFound     ErrorCode = "BLOCKED_WORD_NOT_FOUND"
+	CodeBlockedWordLimitReached ErrorCode = "BLOCKED_WORD_LIMIT_REACHED"
+
+	// Requests
+	CodeInvalidRequestBody ErrorCode = "INVALID_REQUEST_BODY" // the body can't be decoded or fails validation
+	CodeEditConflict       ErrorCode = "EDIT_CONFLICT"        // edited since the version it was made from
+
+	// Videos
+	CodeVideoNotFound        ErrorCode = "VIDEO_NOT_FOUND"
+	CodeVideoNotReady        ErrorCode = "VIDEO_NOT_READY"      // still being processed
+	CodeVideoHeld            ErrorCode = "VIDEO_HELD"           // held for moderation review
+	CodeVideoAgeRestricted   ErrorCode = "VIDEO_AGE_RESTRICTED" // only shown to signed-in viewers
+	CodeVideoFailed          ErrorCode = "VIDEO_FAILED"
+	CodeVideoNotSupported    ErrorCode = "VIDEO_NOT_SUPPORTED" // container, codecs or duration not accepted
+	CodeTranscodeBacklogFull ErrorCode = "TRANSCODE_BACKLOG_FULL"
+)
+
+// Generic code of each status
+var statusCodes = map[int]ErrorCode{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodeBodyTooLarge,
+	http.StatusUnsupportedMediaType:  CodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:   CodeUnprocessable,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+	http.StatusGatewayTimeout:        CodeGatewayTimeout,
+	http.StatusInsufficientStorage:   CodeStorageFull,
+}
+
+// Helper function: get the generic code of a status
+func statusCode(status int) ErrorCode {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+// Body of an error response
+type errorResponse struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Details any       `json:"details,omitempty"`
+}
+
+// WriteError writes an error response in JSON format, with the generic code of the status
+func (server *Server) WriteError(w http.ResponseWriter, status int, message string) {
+	server.WriteErrorCode(w, status, statusCode(status), message, nil)
+}
+
+// WriteErrorWithDetails writes an error response in JSON format, with details explaining the error
+func (server *Server) WriteErrorWithDetails(w http.ResponseWriter, status int, message string, details any) {
+	server.WriteErrorCode(w, status, statusCode(status), message, details)
+}
+
+// WriteErrorCode writes an error response in JSON format with a specific code, details are omitted if nil
+func (server *Server) WriteErrorCode(w http.ResponseWriter, status int, code ErrorCode, message string, details any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{
+		Code:    code,
+		Message: message,
+		Details: details,
+	})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// WriteJSONWithETag writes a JSON response like WriteJSON, with a weak ETag computed from the body. If the request
+// has an If-None-Match matching the ETag, 304 is written without the body, so clients polling a resource only
+// download it when it changes
+func (server *Server) WriteJSONWithETag(w http.ResponseWriter, r *http.Request, status int, data any) {
+	body, err := json.Marshal(map[string]any{
+		"data": data,
+	})
+	if err != nil {
+		server.logger.Error(r.Method+" "+r.URL.Path+": failed to encode response", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `W/"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// Helper function: check if an If-None-Match header matches an ETag. The comparison is weak (as required for
+// If-None-Match), so the W/ prefix is ignored on both sides
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for candidate := range strings.SplitSeq(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
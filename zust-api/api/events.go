@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+	db "zust/db/sqlc"
+
+	"github.com/google/uuid"
+)
+
+// Number of batches buffered by an event writer, events are dropped once the buffer is full (when the database
+// can't keep up), and time given to the database to insert a batch
+const (
+	eventBufferBatches = 10
+	eventFlushTimeout  = 30 * time.Second
+)
+
+// Batching writer of events: the events (playback pings, player events) are buffered and inserted in bulk, instead
+// of one INSERT per event. Events buffered when the server stops are flushed before it exits
+type eventWriter[T any] struct {
+	name          string // kind of events, for the logs
+	insert        func(ctx context.Context, batch []T) error
+	batchSize     int
+	flushInterval time.Duration
+	logger        *slog.Logger
+
+	events  chan T
+	dropped atomic.Uint64
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Constructor method for an event writer, a batch is inserted once batchSize events are buffered or every
+// flushInterval
+func newEventWriter[T any](name string, batchSize int, flushInterval time.Duration, logger *slog.Logger,
+	insert func(ctx context.Context, batch []T) error) *eventWriter[T] {
+	return &eventWriter[T]{
+		name:          name,
+		insert:        insert,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		logger:        logger,
+		events:        make(chan T, batchSize*eventBufferBatches),
+	}
+}
+
+// Helper method: create the event writers and register their lifecycle hooks
+func (server *Server) registerEventWriter() {
+	server.events = newEventWriter("view events", server.config.EventBatchSize, server.config.EventFlushInterval,
+		server.logger, server.insertViewEvents)
+	server.playbackEvents = newEventWriter("playback events", server.config.EventBatchSize,
+		server.config.EventFlushInterval, server.logger, server.insertPlaybackEvents)
+
+	server.OnLifecycle(Hook{Name: "events", Start: server.events.start, Stop: server.events.stop})
+	server.OnLifecycle(Hook{Name: "playback_events", Start: server.playbackEvents.start,
+		Stop: server.playbackEvents.stop})
+}
+
+// Method to start the event writer
+func (writer *eventWriter[T]) start(ctx context.Context) error {
+	ctx, writer.cancel = context.WithCancel(ctx)
+	writer.done = make(chan struct{})
+	go writer.run(ctx)
+	return nil
+}
+
+// Method to stop the event writer, waiting until the buffered events are flushed or ctx is done
+func (writer *eventWriter[T]) stop(ctx context.Context) error {
+	if writer.cancel == nil {
+		return nil
+	}
+	writer.cancel()
+
+	select {
+	case <-writer.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Method to buffer an event, it returns false if the event is dropped because the buffer is full
+func (writer *eventWriter[T]) record(event T) bool {
+	select {
+	case writer.events <- event:
+		return true
+	default:
+		if writer.dropped.Add(1)%uint64(writer.batchSize) == 1 {
+			writer.logger.Warn("events: buffer is full, "+writer.name+" are dropped", "dropped", writer.dropped.Load())
+		}
+		return false
+	}
+}
+
+// Method to get the number of free slots in the buffer, a batch of events larger than this would be partially
+// dropped
+func (writer *eventWriter[T]) available() int {
+	return cap(writer.events) - len(writer.events)
+}
+
+// Helper method: insert the buffered events once a batch is full or every flush interval, until ctx is cancelled.
+// The events still buffered then are flushed before returning
+func (writer *eventWriter[T]) run(ctx context.Context) {
+	defer close(writer.done)
+	ticker := time.NewTicker(writer.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, writer.batchSize)
+	add := func(event T) {
+		batch = append(batch, event)
+		if len(batch) >= writer.batchSize {
+			batch = writer.flush(batch)
+		}
+	}
+
+	for {
+		select {
+		case event := <-writer.events:
+			add(event)
+		case <-ticker.C:
+			batch = writer.flush(batch)
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-writer.events:
+					add(event)
+				default:
+					writer.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Helper method: insert a batch of events, and return the batch emptied for reuse. A batch failing to insert is
+// dropped, so a database outage doesn't make the buffer grow without limit
+func (writer *eventWriter[T]) flush(batch []T) []T {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), eventFlushTimeout)
+	defer cancel()
+	if err := writer.insert(ctx, batch); err != nil {
+		writer.dropped.Add(uint64(len(batch)))
+		writer.logger.Error("events: failed to insert "+writer.name, "count", len(batch), "error", err)
+	}
+	return batch[:0]
+}
+
+// Helper method: insert a batch of view events, and count the views on their videos
+func (server *Server) insertViewEvents(ctx context.Context, batch []db.CreateViewEventsParams) error {
+	if _, err := server.query.CreateViewEvents(ctx, batch); err != nil {
+		return err
+	}
+
+	// A count failing here is fixed by the counter reconciliation
+	views := make(map[uuid.UUID]int64)
+	for _, event := range batch {
+		views[event.VideoID]++
+	}
+	for videoID, count := range views {
+		if err := server.query.AddVideoViews(ctx, db.AddVideoViewsParams{Delta: count, VideoID: videoID}); err != nil {
+			server.logger.Error("events: failed to count video views", "video_id", videoID, "error", err)
+		}
+	}
+	return nil
+}
+
+// Helper method: insert a batch of playback events
+func (server *Server) insertPlaybackEvents(ctx context.Context, batch []db.CreatePlaybackEventsParams) error {
+	_, err := server.query.CreatePlaybackEvents(ctx, batch)
+	return err
+}
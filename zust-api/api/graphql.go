@@ -0,0 +1,384 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/file"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+// Schema of the GraphQL endpoint, so clients can fetch a page (for example: the home feed with the publishers and
+// the counters of the videos) in one request instead of one REST call per video
+const graphqlSchema = `
+schema {
+	query: Query
+}
+
+scalar Time
+
+type Query {
+	# Latest public videos, the next page starts after the cursor of the last video of a page
+	feed(first: Int = 20, offset: Int = 0, after: String): [Video!]!
+	# Latest public videos of the channels the requester subscribes to, requires an access token
+	subscriptionFeed(first: Int = 20, offset: Int = 0, after: String): [Video!]!
+	video(id: ID!): Video
+	channel(id: ID!): Channel
+	# Channels the requester subscribes to, requires an access token
+	subscriptions: [Channel!]!
+}
+
+type Video {
+	id: ID!
+	title: String!
+	description: String!
+	duration: Int!
+	createdAt: Time!
+	license: String!
+	thumbnail: String!
+	views: Int!
+	likes: Int!
+	publisher: Channel!
+	# Position of the video in the list, to request the next page
+	cursor: String!
+}
+
+type Channel {
+	id: ID!
+	username: String!
+	description: String!
+	avatar: String!
+	cover: String!
+	subscribers: Int!
+	videos(first: Int = 20, offset: Int = 0, after: String): [Video!]!
+}
+`
+
+// Max number of videos of a page, max depth of a query (Query > Channel > Video > Channel > ...) and timeout of a
+// request, so a single query can't keep the database busy
+const (
+	graphqlMaxPageSize = 50
+	graphqlMaxDepth    = 6
+	graphqlTimeout     = 10 * time.Second
+)
+
+// Error returned to the client when a resolver fails, the cause is only logged
+var errGraphQLInternal = errors.New("internal server error")
+
+// Helper method: create the handler of the GraphQL endpoint. The access token is optional, it's only required by
+// the fields of the requester (subscriptions)
+func (server *Server) graphqlHandler() http.Handler {
+	schema := graphql.MustParseSchema(graphqlSchema, &queryResolver{server: server},
+		graphql.MaxDepth(graphqlMaxDepth))
+	handler := &relay.Handler{Schema: schema}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), graphqlTimeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+
+		// The tokens of the accounts of other tenants are ignored
+		if claims := server.mediaClaims(r); claims != nil {
+			if ok, _ := server.claimsInTenant(r.Context(), claims); ok {
+				r = r.WithContext(context.WithValue(r.Context(), clKey, claims))
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// Pagination arguments of the lists of videos
+type pageArgs struct {
+	First  int32
+	Offset int32
+	After  *string
+}
+
+// Helper function: clamp the pagination arguments of a list
+func (args pageArgs) clamp() (int32, int32) {
+	return min(max(args.First, 0), graphqlMaxPageSize), max(args.Offset, 0)
+}
+
+// Helper function: get the cursor of the page, nil for the first page
+func (args pageArgs) cursor() (*pageCursor, error) {
+	if args.After == nil || *args.After == "" {
+		return nil, nil
+	}
+	return decodeCursor(*args.After)
+}
+
+// Resolver of the root query
+type queryResolver struct {
+	server *Server
+}
+
+// Method to resolve the latest public videos
+func (resolver *queryResolver) Feed(ctx context.Context, args pageArgs) ([]*videoResolver, error) {
+	cursor, err := args.cursor()
+	if err != nil {
+		return nil, err
+	}
+	afterCreatedAt, afterID := cursor.params()
+	pageSize, pageOffset := args.clamp()
+	rows, err := resolver.server.query.ListFeedVideos(ctx, db.ListFeedVideosParams{
+		TenantID:       tenantOf(ctx),
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		PageSize:       pageSize,
+		PageOffset:     pageOffset,
+	})
+	if err != nil {
+		resolver.server.logger.Error("POST /graphql: failed to list feed videos", "error", err)
+		return nil, errGraphQLInternal
+	}
+
+	videos := make([]*videoResolver, len(rows))
+	for i, row := range rows {
+		videos[i] = resolver.server.newVideoResolver(db.ListChannelVideosRow(row))
+	}
+	return videos, nil
+}
+
+// Method to resolve the latest public videos of the channels the requester subscribes to
+func (resolver *queryResolver) SubscriptionFeed(ctx context.Context, args pageArgs) ([]*videoResolver, error) {
+	accountID, err := graphqlAccountID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := args.cursor()
+	if err != nil {
+		return nil, err
+	}
+	afterCreatedAt, afterID := cursor.params()
+	pageSize, pageOffset := args.clamp()
+	rows, err := resolver.server.query.ListSubscriptionVideos(ctx, db.ListSubscriptionVideosParams{
+		SubscriberID:   accountID,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		PageSize:       pageSize,
+		PageOffset:     pageOffset,
+	})
+	if err != nil {
+		resolver.server.logger.Error("POST /graphql: failed to list subscription videos", "error", err)
+		return nil, errGraphQLInternal
+	}
+
+	videos := make([]*videoResolver, len(rows))
+	for i, row := range rows {
+		videos[i] = resolver.server.newVideoResolver(db.ListChannelVideosRow(row))
+	}
+	return videos, nil
+}
+
+// Method to resolve a video, only published public videos are returned (null otherwise)
+func (resolver *queryResolver) Video(ctx context.Context, args struct{ ID graphql.ID }) (*videoResolver, error) {
+	var videoID uuid.UUID
+	if err := videoID.Scan(string(args.ID)); err != nil {
+		return nil, fmt.Errorf("invalid video ID")
+	}
+
+	video, err := resolver.server.getVideo(ctx, videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		resolver.server.logger.Error("POST /graphql: failed to get video", "error", err)
+		return nil, errGraphQLInternal
+	}
+	if video.Status != db.VideoStatusPublished || video.Visibility != db.VideoVisibilityPublic {
+		return nil, nil
+	}
+
+	return resolver.server.newVideoResolver(db.ListChannelVideosRow{
+		VideoID:     video.VideoID,
+		Title:       video.Title,
+		Duration:    video.Duration,
+		Description: video.Description,
+		CreatedAt:   video.CreatedAt,
+		License:     video.License,
+		AccountID:   video.AccountID,
+		Username:    video.Username,
+		TotalView:   video.TotalView,
+		TotalLike:   video.TotalLike,
+	}), nil
+}
+
+// Method to resolve a channel, only active accounts are returned (null otherwise)
+func (resolver *queryResolver) Channel(ctx context.Context, args struct{ ID graphql.ID }) (*channelResolver, error) {
+	var accountID uuid.UUID
+	if err := accountID.Scan(string(args.ID)); err != nil {
+		return nil, fmt.Errorf("invalid channel ID")
+	}
+
+	profile, err := resolver.server.getProfile(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		resolver.server.logger.Error("POST /graphql: failed to get profile", "error", err)
+		return nil, errGraphQLInternal
+	}
+	if profile.Status != db.AccountStatusActive {
+		return nil, nil
+	}
+
+	return &channelResolver{
+		server:      resolver.server,
+		id:          profile.AccountID,
+		username:    profile.Username,
+		description: &profile.Description,
+	}, nil
+}
+
+// Method to resolve the channels the requester subscribes to
+func (resolver *queryResolver) Subscriptions(ctx context.Context) ([]*channelResolver, error) {
+	accountID, err := graphqlAccountID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := resolver.server.query.ListSubscriptions(ctx, accountID)
+	if err != nil {
+		resolver.server.logger.Error("POST /graphql: failed to list subscriptions", "error", err)
+		return nil, errGraphQLInternal
+	}
+
+	channels := make([]*channelResolver, len(rows))
+	for i, row := range rows {
+		channels[i] = &channelResolver{
+			server:      resolver.server,
+			id:          row.AccountID,
+			username:    row.Username,
+			description: &row.Description,
+		}
+	}
+	return channels, nil
+}
+
+// Helper function: get the ID of the requester from its access token
+func graphqlAccountID(ctx context.Context) (uuid.UUID, error) {
+	var accountID uuid.UUID
+	claims, ok := ctx.Value(clKey).(*security.CustomClaims)
+	if !ok || accountID.Scan(claims.ID) != nil {
+		return accountID, fmt.Errorf("access token is required")
+	}
+	return accountID, nil
+}
+
+// Resolver of a video
+type videoResolver struct {
+	server *Server
+	video  db.ListChannelVideosRow
+}
+
+// Constructor method for video resolver
+func (server *Server) newVideoResolver(video db.ListChannelVideosRow) *videoResolver {
+	return &videoResolver{server: server, video: video}
+}
+
+func (resolver *videoResolver) ID() graphql.ID { return graphql.ID(resolver.video.VideoID.String()) }
+
+func (resolver *videoResolver) Title() string { return resolver.video.Title }
+
+func (resolver *videoResolver) Description() string { return resolver.video.Description.String }
+
+func (resolver *videoResolver) Duration() int32 { return resolver.video.Duration }
+
+func (resolver *videoResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: resolver.video.CreatedAt}
+}
+
+func (resolver *videoResolver) License() string { return string(resolver.video.License) }
+
+func (resolver *videoResolver) Views() int32 { return int32(resolver.video.TotalView) }
+
+func (resolver *videoResolver) Likes() int32 { return int32(resolver.video.TotalLike) }
+
+func (resolver *videoResolver) Thumbnail() string {
+	return resolver.server.mediaService.GenerateMediaLink(resolver.video.AccountID.String(),
+		fmt.Sprintf("%s.png", resolver.video.VideoID.String()), file.Thumbnail)
+}
+
+func (resolver *videoResolver) Cursor() string {
+	return encodeCursor(resolver.video.CreatedAt, resolver.video.VideoID)
+}
+
+func (resolver *videoResolver) Publisher() *channelResolver {
+	return &channelResolver{server: resolver.server, id: resolver.video.AccountID, username: resolver.video.Username}
+}
+
+// Resolver of a channel. The description is loaded from the profile only when it's requested
+type channelResolver struct {
+	server      *Server
+	id          uuid.UUID
+	username    string
+	description *sql.NullString
+}
+
+func (resolver *channelResolver) ID() graphql.ID { return graphql.ID(resolver.id.String()) }
+
+func (resolver *channelResolver) Username() string { return resolver.username }
+
+func (resolver *channelResolver) Description(ctx context.Context) (string, error) {
+	if resolver.description == nil {
+		profile, err := resolver.server.getProfile(ctx, resolver.id)
+		if err != nil {
+			resolver.server.logger.Error("POST /graphql: failed to get profile", "error", err)
+			return "", errGraphQLInternal
+		}
+		resolver.description = &profile.Description
+	}
+	return resolver.description.String, nil
+}
+
+func (resolver *channelResolver) Avatar() string {
+	return resolver.server.mediaService.GenerateMediaLink(resolver.id.String(), "", file.Avatar)
+}
+
+func (resolver *channelResolver) Cover() string {
+	return resolver.server.mediaService.GenerateMediaLink(resolver.id.String(), "", file.Cover)
+}
+
+func (resolver *channelResolver) Subscribers(ctx context.Context) (int32, error) {
+	count, err := resolver.server.query.CountSubscribers(ctx, resolver.id)
+	if err != nil {
+		resolver.server.logger.Error("POST /graphql: failed to count subscribers", "error", err)
+		return 0, errGraphQLInternal
+	}
+	return int32(count), nil
+}
+
+func (resolver *channelResolver) Videos(ctx context.Context, args pageArgs) ([]*videoResolver, error) {
+	cursor, err := args.cursor()
+	if err != nil {
+		return nil, err
+	}
+	afterCreatedAt, afterID := cursor.params()
+	pageSize, pageOffset := args.clamp()
+	rows, err := resolver.server.query.ListChannelVideos(ctx, db.ListChannelVideosParams{
+		PublisherID:    resolver.id,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		PageSize:       pageSize,
+		PageOffset:     pageOffset,
+	})
+	if err != nil {
+		resolver.server.logger.Error("POST /graphql: failed to list channel videos", "error", err)
+		return nil, errGraphQLInternal
+	}
+
+	videos := make([]*videoResolver, len(rows))
+	for i, row := range rows {
+		videos[i] = resolver.server.newVideoResolver(row)
+	}
+	return videos, nil
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+	"zust/service/file"
+)
+
+// Last health check of the storage
+type storageStatus struct {
+	mu        sync.Mutex
+	Available bool                `json:"available"`
+	Low       bool                `json:"low"` // free space or inodes below the thresholds
+	Usage     *file.StorageHealth `json:"usage,omitempty"`
+	CheckedAt time.Time           `json:"checked_at"`
+	Error     string              `json:"error,omitempty"`
+}
+
+// Method to check the space left in the storage, run periodically so uploads can be rejected before the storage is
+// full instead of failing in the middle of a write
+func (server *Server) runStorageCheck(ctx context.Context) {
+	usage, err := server.storage.Health()
+	low := err == nil && (usage.FreeBytes < server.config.StorageMinFree ||
+		usage.TotalInodes > 0 && usage.FreeInodes < server.config.StorageMinFreeInodes)
+
+	server.storageStatus.mu.Lock()
+	wasLow := server.storageStatus.Low
+	server.storageStatus.Available = err == nil
+	server.storageStatus.Low = low
+	server.storageStatus.Usage = usage
+	server.storageStatus.CheckedAt = time.Now()
+	server.storageStatus.Error = ""
+	if err != nil {
+		server.storageStatus.Error = err.Error()
+	}
+	server.storageStatus.mu.Unlock()
+
+	switch {
+	case err != nil:
+		server.logger.Error("storage: failed to check storage health", "error", err)
+	case low && !wasLow:
+		server.logger.Warn("storage: free space is low, uploads are rejected", "free_bytes", usage.FreeBytes,
+			"free_inodes", usage.FreeInodes)
+	case !low && wasLow:
+		server.logger.Info("storage: free space is back, uploads are accepted", "free_bytes", usage.FreeBytes,
+			"free_inodes", usage.FreeInodes)
+	}
+}
+
+// Method to reject uploads with 507 while the storage is unreachable or its free space is below the thresholds
+func (server *Server) checkStorageSpace(w http.ResponseWriter) bool {
+	server.storageStatus.mu.Lock()
+	defer server.storageStatus.mu.Unlock()
+
+	if !server.storageStatus.Available || server.storageStatus.Low {
+		server.WriteError(w, http.StatusInsufficientStorage, "Not enough storage space, please try again later")
+		return false
+	}
+	return true
+}
+
+// HandleHealth returns the health of the server and its storage. The status is 'degraded' while the free space of
+// the storage is low (uploads are rejected), and 'unavailable' if the storage can't be reached.
+// endpoint: GET /health
+// Success: 200
+// Fail: 503
+func (server *Server) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	server.storageStatus.mu.Lock()
+	defer server.storageStatus.mu.Unlock()
+
+	status, code := "ok", http.StatusOK
+	switch {
+	case !server.storageStatus.Available:
+		status, code = "unavailable", http.StatusServiceUnavailable
+	case server.storageStatus.Low:
+		status = "degraded"
+	}
+
+	server.WriteJSON(w, code, map[string]any{
+		"status":  status,
+		"storage": server.storageStatus,
+	})
+}
+
+// HandleLiveness reports that the process is up, without checking its dependencies
+// endpoint: GET /healthz
+// Success: 200
+func (server *Server) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// Max duration of a readiness check
+const readinessTimeout = 5 * time.Second
+
+// HandleReadiness reports if the server can handle requests: the database can be reached, the storage is writable
+// and ffmpeg is present. The result of each check is returned, with 503 if any of them failed
+// endpoint: GET /readyz
+// Success: 200
+// Fail: 503
+func (server *Server) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{}
+	ready := true
+	for name, check := range server.dependencyChecks(ctx) {
+		checks[name] = "ok"
+		if err := check(); err != nil {
+			server.logger.Error("GET /readyz: readiness check failed", "check", name, "error", err)
+			checks[name] = err.Error()
+			ready = false
+		}
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	server.WriteJSON(w, code, map[string]any{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// Helper method: get the checks of the dependencies of the server, by name: the database can be reached, the
+// storage is writable and ffmpeg is present
+func (server *Server) dependencyChecks(ctx context.Context) map[string]func() error {
+	return map[string]func() error{
+		"database": func() error { return server.query.Ping(ctx) },
+		"storage":  server.checkStorageWritable,
+		"ffmpeg": func() error {
+			_, err := exec.LookPath(server.mediaService.FFmpegPath)
+			return err
+		},
+	}
+}
+
+// Helper method: check that a file can be written into the storage. The probe file is never committed, so nothing
+// is left in the storage
+func (server *Server) checkStorageWritable() error {
+	probe, err := server.storage.Create(".readyz")
+	if err != nil {
+		return err
+	}
+	if _, err := probe.Write([]byte("ok")); err != nil {
+		probe.Close()
+		return err
+	}
+	return probe.Close()
+}
+
+// HandleMetrics exposes the storage metrics and the latency of the database queries in the Prometheus text format.
+// endpoint: GET /metrics
+// Success: 200
+func (server *Server) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	server.storageStatus.mu.Lock()
+	defer server.storageStatus.mu.Unlock()
+
+	usage := server.storageStatus.Usage
+	if usage == nil {
+		usage = &file.StorageHealth{}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	for _, metric := range []struct {
+		name, help string
+		value      uint64
+	}{
+		{"zust_storage_up", "Whether the storage can be reached", boolMetric(server.storageStatus.Available)},
+		{"zust_storage_low", "Whether the free space of the storage is below the thresholds", boolMetric(server.storageStatus.Low)},
+		{"zust_storage_total_bytes", "Size of the storage in bytes", usage.TotalBytes},
+		{"zust_storage_free_bytes", "Free space of the storage in bytes", usage.FreeBytes},
+		{"zust_storage_total_inodes", "Number of inodes of the storage", usage.TotalInodes},
+		{"zust_storage_free_inodes", "Number of free inodes of the storage", usage.FreeInodes},
+		{"zust_view_events_dropped", "Number of view events dropped, not inserted", server.events.dropped.Load()},
+		{"zust_playback_events_dropped", "Number of playback events dropped, not inserted", server.playbackEvents.dropped.Load()},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", metric.name, metric.help, metric.name, metric.name,
+			metric.value)
+	}
+	server.query.WriteMetrics(w)
+}
+
+// Helper function: get the value of a boolean metric
+func boolMetric(value bool) uint64 {
+	if value {
+		return 1
+	}
+	return 0
+}
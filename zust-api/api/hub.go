@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Number of events buffered for a stream, a slower stream drops the next events: the client catches up with the
+// changes since its sync cursor
+const hubStreamBuffer = 16
+
+// Redis channel the events are published on, so every instance gets the events of its streams
+const hubChannel = "zust:notifications"
+
+// An event sent to the notification streams of an account
+type hubEvent struct {
+	Name string // SSE event name
+	Data any
+}
+
+// An event published on the Redis channel, with the account it's sent to
+type sharedHubEvent struct {
+	AccountID uuid.UUID       `json:"account_id"`
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Hub of the notification streams opened on this instance, by account. When the cache is in Redis, the events
+// are published on a Redis channel and delivered by each instance to its own streams, so the clients of an account
+// get its events whichever instance they are connected to
+type notificationHub struct {
+	mu      sync.Mutex
+	streams map[uuid.UUID]map[chan hubEvent]struct{}
+
+	client *redis.Client // nil if the events are not shared
+	pubsub *redis.PubSub
+	done   chan struct{} // closed when the delivery of the published events stops
+}
+
+// Constructor method for the notification hub
+func newNotificationHub(config *security.Config) *notificationHub {
+	hub := &notificationHub{streams: make(map[uuid.UUID]map[chan hubEvent]struct{})}
+	if config.CacheDriver == "redis" {
+		hub.client = redis.NewClient(&redis.Options{
+			Addr:     config.RedisAddr,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+		})
+	}
+	return hub
+}
+
+// Helper method: register the lifecycle hook of the notification hub, which receives the events published by
+// all the instances
+func (server *Server) registerNotificationHub() {
+	server.OnLifecycle(Hook{
+		Name:  "notifications",
+		Start: server.hub.open,
+		Stop:  server.hub.close,
+	})
+}
+
+// Method to subscribe to the Redis channel, the published events are delivered until the hub is closed
+func (hub *notificationHub) open(ctx context.Context) error {
+	if hub.client == nil {
+		return nil
+	}
+
+	hub.pubsub = hub.client.Subscribe(ctx, hubChannel)
+	if _, err := hub.pubsub.Receive(ctx); err != nil {
+		hub.pubsub.Close()
+		return err
+	}
+
+	hub.done = make(chan struct{})
+	go func() {
+		defer close(hub.done)
+		for message := range hub.pubsub.Channel() {
+			var event sharedHubEvent
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				continue
+			}
+			hub.deliver(event.AccountID, hubEvent{Name: event.Name, Data: event.Data})
+		}
+	}()
+	return nil
+}
+
+// Method to unsubscribe from the Redis channel, waiting for the delivery to stop until ctx is done
+func (hub *notificationHub) close(ctx context.Context) error {
+	if hub.pubsub == nil {
+		return nil
+	}
+	if err := hub.pubsub.Close(); err != nil {
+		return err
+	}
+
+	select {
+	case <-hub.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Method to open a stream of the events of an account, it must be closed with unsubscribe
+func (hub *notificationHub) subscribe(accountID uuid.UUID) chan hubEvent {
+	stream := make(chan hubEvent, hubStreamBuffer)
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.streams[accountID] == nil {
+		hub.streams[accountID] = make(map[chan hubEvent]struct{})
+	}
+	hub.streams[accountID][stream] = struct{}{}
+	return stream
+}
+
+// Method to close a stream of an account
+func (hub *notificationHub) unsubscribe(accountID uuid.UUID, stream chan hubEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	delete(hub.streams[accountID], stream)
+	if len(hub.streams[accountID]) == 0 {
+		delete(hub.streams, accountID)
+	}
+}
+
+// Method to send an event to all the streams of an account, through the Redis channel when the events are shared.
+// If it can't be published, the event is only delivered to the streams of this instance
+func (hub *notificationHub) publish(accountID uuid.UUID, event hubEvent) {
+	if hub.client != nil {
+		data, err := json.Marshal(event.Data)
+		if err == nil {
+			payload, _ := json.Marshal(sharedHubEvent{AccountID: accountID, Name: event.Name, Data: data})
+			if hub.client.Publish(context.Background(), hubChannel, payload).Err() == nil {
+				return
+			}
+		}
+	}
+	hub.deliver(accountID, event)
+}
+
+// Helper method: send an event to the streams of an account opened on this instance, without blocking: a full
+// stream misses the event
+func (hub *notificationHub) deliver(accountID uuid.UUID, event hubEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for stream := range hub.streams[accountID] {
+		select {
+		case stream <- event:
+		default:
+		}
+	}
+}
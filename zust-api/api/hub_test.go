@@ -0,0 +1,52 @@
+package api
+
+import (
+	"testing"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+func TestNotificationHub(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *security.Config
+	}{
+		{name: "memory", config: &security.Config{CacheDriver: "memory"}},
+		// Redis is down: the events are still delivered to the streams of this instance
+		{name: "redis unreachable", config: &security.Config{CacheDriver: "redis", RedisAddr: "127.0.0.1:1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hub := newNotificationHub(test.config)
+			accountID, otherID := uuid.New(), uuid.New()
+			stream := hub.subscribe(accountID)
+			other := hub.subscribe(otherID)
+
+			hub.publish(accountID, hubEvent{Name: "read", Data: map[string]int{"updated": 2}})
+			select {
+			case event := <-stream:
+				if event.Name != "read" {
+					t.Errorf("event = %q, want %q", event.Name, "read")
+				}
+			default:
+				t.Fatal("the stream of the account got no event")
+			}
+			select {
+			case event := <-other:
+				t.Errorf("the stream of another account got %q", event.Name)
+			default:
+			}
+
+			// A closed stream gets nothing, and a full stream drops the events instead of blocking
+			hub.unsubscribe(accountID, stream)
+			for range hubStreamBuffer + 1 {
+				hub.publish(otherID, hubEvent{Name: "notification"})
+			}
+			if len(stream) != 0 || len(other) != hubStreamBuffer {
+				t.Errorf("buffered events = %d and %d, want 0 and %d", len(stream), len(other), hubStreamBuffer)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"zust/service/file"
+)
+
+// Storage key of the directory caching the transformed images
+const imageCacheDir = ".cache/images"
+
+// Helper method: serve a resized and/or converted variant of a stored image (avatar, cover or thumbnail). Variants
+// are generated once, then cached in the storage under a key derived from the source file and the transformation,
+// so a replaced image gets new variants
+func (server *Server) serveTransformedImage(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" || !file.IsTransformableImage(key) {
+		server.WriteError(w, http.StatusBadRequest, "Only images can be transformed")
+		return
+	}
+
+	transform, ok := server.parseImageTransform(w, r)
+	if !ok {
+		return
+	}
+
+	source, err := server.storage.Stat(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+
+		server.logger.Error("GET /media/{id}: failed to get image info", "key", key, "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	hash := sha256.Sum256(fmt.Appendf(nil, "%s:%d:%d:%d:%s", key, source.ModTime.UnixNano(),
+		transform.Width, transform.Height, transform.Format))
+	name := hex.EncodeToString(hash[:])
+	variant := path.Join(imageCacheDir, name[:2], fmt.Sprintf("%s.%s", name, transform.Format))
+
+	if !file.Exists(server.storage, variant) {
+		err := server.mediaService.TransformImage(r.Context(), server.localPath(key), server.localPath(variant),
+			transform)
+		if err != nil {
+			server.logger.Error("GET /media/{id}: failed to transform image", "key", key, "error", err)
+			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/"+transform.Format)
+	server.serveStoredFile(w, r, variant)
+}
+
+// Helper method: parse the transformation of an image from the query parameters: 'w' and 'h' (pixels, up to
+// file.MaxImageDimension) and 'format' (png, jpeg or webp, default to png)
+func (server *Server) parseImageTransform(w http.ResponseWriter, r *http.Request) (file.ImageTransform, bool) {
+	query := r.URL.Query()
+	transform := file.ImageTransform{Format: query.Get("format")}
+
+	for param, value := range map[string]*int{"w": &transform.Width, "h": &transform.Height} {
+		if !query.Has(param) {
+			continue
+		}
+		n, err := strconv.Atoi(query.Get(param))
+		if err != nil || n <= 0 || n > file.MaxImageDimension {
+			server.WriteError(w, http.StatusBadRequest,
+				fmt.Sprintf("Invalid image %s, must be between 1 and %d", param, file.MaxImageDimension))
+			return transform, false
+		}
+		*value = n
+	}
+
+	if transform.Format == "" {
+		transform.Format = "png"
+	}
+	if !server.mediaService.IsSupportedImageFormat(transform.Format) {
+		server.WriteError(w, http.StatusBadRequest, "Unsupported image format")
+		return transform, false
+	}
+	return transform, true
+}
@@ -0,0 +1,415 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/file"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Import status
+const (
+	importDownloading = "downloading"
+	importProcessing  = "processing"
+	importCompleted   = "completed"
+	importFailed      = "failed"
+)
+
+// Progress of a video import
+type importProgress struct {
+	AccountID  uuid.UUID `json:"-"`
+	Status     string    `json:"status"`
+	Downloaded int64     `json:"downloaded_bytes"`
+	Total      int64     `json:"total_bytes"`
+	Percentage float64   `json:"percentage"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Time the progress of a finished import is kept, so client can still poll the result
+const importRetention = time.Hour
+
+// Minimum interval between two writes of the progress of an import to Redis, status changes are always written
+const importShareInterval = time.Second
+
+// Prefix of the import progress keys in Redis, so they never collide with the keys of the cache and the job queue
+const importKeyPrefix = "zust:import:"
+
+// Progress of an import as shared in Redis, with the importer which is not in the response
+type sharedImport struct {
+	AccountID uuid.UUID `json:"account_id"`
+	importProgress
+}
+
+// Tracker of all running (and recently finished) video imports. The imports run on the instance which received
+// them, their progress is kept in memory and copied to Redis (when the cache is in Redis) so any instance behind
+// the load balancer can report it
+type importTracker struct {
+	mu      sync.RWMutex
+	imports map[uuid.UUID]*importProgress
+	shared  map[uuid.UUID]time.Time // last write of the progress to Redis
+	client  *redis.Client           // nil if the progress is not shared
+
+	// The downloads are cancelled when the imports are stopped
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// Constructor method for import tracker
+func newImportTracker(config *security.Config) *importTracker {
+	tracker := &importTracker{
+		imports: make(map[uuid.UUID]*importProgress),
+		shared:  make(map[uuid.UUID]time.Time),
+		ctx:     context.Background(),
+	}
+	if config.CacheDriver == "redis" {
+		tracker.client = redis.NewClient(&redis.Options{
+			Addr:     config.RedisAddr,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+		})
+	}
+	return tracker
+}
+
+// Method to get a copy of the import progress of a video, from Redis if the import runs on another instance
+func (tracker *importTracker) get(ctx context.Context, videoID uuid.UUID) (importProgress, bool) {
+	tracker.mu.RLock()
+	var local importProgress
+	progress, ok := tracker.imports[videoID]
+	if ok {
+		local = *progress
+	}
+	tracker.mu.RUnlock()
+
+	if ok {
+		return local, true
+	}
+	if tracker.client == nil {
+		return importProgress{}, false
+	}
+	data, err := tracker.client.Get(ctx, importKeyPrefix+videoID.String()).Bytes()
+	if err != nil {
+		return importProgress{}, false
+	}
+	var shared sharedImport
+	if err := json.Unmarshal(data, &shared); err != nil {
+		return importProgress{}, false
+	}
+	shared.importProgress.AccountID = shared.AccountID
+	return shared.importProgress, true
+}
+
+// Helper method: register the lifecycle hook of the video imports
+func (server *Server) registerImports() {
+	server.OnLifecycle(Hook{
+		Name:  "imports",
+		Start: server.imports.open,
+		Stop:  server.imports.close,
+	})
+}
+
+// Method to start accepting the imports, they run until the tracker is closed
+func (tracker *importTracker) open(ctx context.Context) error {
+	tracker.ctx, tracker.cancel = context.WithCancel(ctx)
+	return nil
+}
+
+// Method to cancel the running imports, waiting for them to return until ctx is done. The cancelled imports fail
+// and their video is deleted
+func (tracker *importTracker) close(ctx context.Context) error {
+	if tracker.cancel == nil {
+		return nil
+	}
+	tracker.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		tracker.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Method to run an import in background until it returns or the imports are stopped
+func (tracker *importTracker) run(fn func(ctx context.Context)) {
+	tracker.running.Add(1)
+	go func() {
+		defer tracker.running.Done()
+		fn(tracker.ctx)
+	}()
+}
+
+// Method to start tracking the import of a video
+func (tracker *importTracker) start(videoID, accountID uuid.UUID) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.imports[videoID] = &importProgress{AccountID: accountID, Status: importDownloading, Total: -1}
+	tracker.share(videoID, true)
+}
+
+// Method to update the import progress of a video
+func (tracker *importTracker) update(videoID uuid.UUID, fn func(progress *importProgress)) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if progress, ok := tracker.imports[videoID]; ok {
+		status := progress.Status
+		fn(progress)
+		tracker.share(videoID, progress.Status != status)
+	}
+}
+
+// Helper method: copy the progress of an import to Redis, at most once per share interval unless 'force'. The
+// tracker must be locked
+func (tracker *importTracker) share(videoID uuid.UUID, force bool) {
+	if tracker.client == nil {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(tracker.shared[videoID]) < importShareInterval {
+		return
+	}
+	tracker.shared[videoID] = now
+
+	progress := tracker.imports[videoID]
+	data, err := json.Marshal(sharedImport{AccountID: progress.AccountID, importProgress: *progress})
+	if err != nil {
+		return
+	}
+	tracker.client.Set(context.Background(), importKeyPrefix+videoID.String(), data, importRetention)
+}
+
+// Method to mark an import as finished, the progress is kept for a while so client can still poll the result
+func (tracker *importTracker) finish(videoID uuid.UUID, err error) {
+	tracker.update(videoID, func(progress *importProgress) {
+		progress.Status = importCompleted
+		if err != nil {
+			progress.Status = importFailed
+			progress.Error = err.Error()
+		}
+	})
+
+	time.AfterFunc(importRetention, func() {
+		tracker.mu.Lock()
+		delete(tracker.imports, videoID)
+		delete(tracker.shared, videoID)
+		tracker.mu.Unlock()
+	})
+}
+
+// Request body for ImportVideo
+type importVideoRequest struct {
+	URL         string `json:"url" validate:"required,url"`
+	Title       string `json:"title" validate:"required,max=50"`
+	Description string `json:"description" validate:"max=500"`
+	License     string `json:"license" validate:"omitempty,license"`
+	Attribution string `json:"attribution" validate:"max=255"`
+	Branding    bool   `json:"branding"` // stitch the intro/outro of the channel onto the video
+}
+
+// HandleImportVideo creates a video from a remote URL. The download runs in background, its progress can be
+// polled with GET /videos/import/{id}
+// endpoint: POST /videos/import
+// Success: 202
+// Fail: 400, 403, 500, 503, 507
+func (server *Server) HandleImportVideo(w http.ResponseWriter, r *http.Request) {
+	// Get request body
+	var req importVideoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Only accept HTTP(S) URL
+	remote, err := url.Parse(req.URL)
+	if err != nil || (remote.Scheme != "http" && remote.Scheme != "https") {
+		server.WriteError(w, http.StatusBadRequest, "Only HTTP and HTTPS URL are supported")
+		return
+	}
+
+	// Check if requester account status is active or not
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /videos/import"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	// The title and the description can't contain blocked words
+	if !server.checkBlockedWords(w, r, accountID, req.Title, req.Description) {
+		return
+	}
+
+	// Reject the import early if the transcode queue is full
+	if ok := server.checkTranscodeBacklog(w, r); !ok {
+		return
+	}
+
+	// Reject the import early if the storage is almost full
+	if !server.checkStorageSpace(w) {
+		return
+	}
+
+	// Insert video metadata into database with status 'pending'
+	desc := strings.TrimSpace(req.Description)
+	attr := strings.TrimSpace(req.Attribution)
+	license := db.VideoLicenseStandard
+	if req.License != "" {
+		license = db.VideoLicense(req.License)
+	}
+	video, err := server.query.CreateVideo(r.Context(), db.CreateVideoParams{
+		Title:       strings.TrimSpace(req.Title),
+		Description: sql.NullString{String: desc, Valid: desc != ""},
+		PublisherID: accountID,
+		License:     license,
+		Attribution: sql.NullString{String: attr, Valid: attr != ""},
+	})
+	if err != nil {
+		server.logger.Error("POST /videos/import: failed to create video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Start tracking and download the video in background
+	server.imports.start(video.VideoID, accountID)
+
+	server.imports.run(func(ctx context.Context) {
+		server.importVideo(ctx, accountID, video.VideoID, req.URL, req.Branding)
+	})
+
+	server.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"video_id": video.VideoID.String(),
+		"message":  "Video import started",
+	})
+}
+
+// Method to download the remote video, then feed it into the normal processing pipeline. Run in background
+func (server *Server) importVideo(ctx context.Context, accountID, videoID uuid.UUID, remoteURL string, branding bool) {
+	err := server.runImport(ctx, accountID, videoID, remoteURL, branding)
+	if err != nil {
+		server.logger.Error("import: failed to import video", "video_id", videoID, "url", remoteURL, "error", err)
+
+		// Remove the failed video so it won't stay pending forever
+		if _, delErr := server.query.DeleteVideo(context.Background(), db.DeleteVideoParams{
+			VideoID:     videoID,
+			PublisherID: accountID,
+		}); delErr != nil {
+			server.logger.Error("import: failed to delete failed video", "video_id", videoID, "error", delErr)
+		}
+		server.invalidateVideos(context.Background(), videoID)
+	}
+	server.imports.finish(videoID, err)
+}
+
+// Helper method: the import steps, any error will make the whole import fail
+func (server *Server) runImport(ctx context.Context, accountID, videoID uuid.UUID, remoteURL string,
+	branding bool) error {
+	resource := server.localPath(file.OriginalKey(accountID.String(), videoID.String()))
+	thumbnail := server.localPath(file.ThumbnailKey(accountID.String(), videoID.String()))
+
+	// Download the video with progress tracking, up to the upload limit of the tenant of the publisher
+	tenant, err := server.accountTenant(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	err = file.DownloadURLWithProgress(ctx, remoteURL, resource, server.config.ForTenant(tenant).VideoSize,
+		func(written, total int64) {
+			server.imports.update(videoID, func(progress *importProgress) {
+				progress.Downloaded = written
+				progress.Total = total
+				if total > 0 {
+					progress.Percentage = float64(written) * 100 / float64(total)
+				}
+			})
+		})
+	if err != nil {
+		os.Remove(resource)
+		if errors.Is(err, file.ErrFileTooLarge) {
+			return fmt.Errorf("remote video exceeds the upload size limit")
+		}
+		return err
+	}
+
+	server.imports.update(videoID, func(progress *importProgress) {
+		progress.Status = importProcessing
+		progress.Percentage = 100
+	})
+
+	// Reject unsupported containers/codecs before going further
+	if err := server.checkUploadedVideo(resource); err != nil {
+		os.Remove(resource)
+		return err
+	}
+
+	// Get video duration and update to database
+	duration, err := server.mediaService.GetVideoDuration(resource)
+	if err != nil {
+		return err
+	}
+	err = server.query.UpdateVideoDuration(ctx, db.UpdateVideoDurationParams{
+		VideoID:  videoID,
+		Duration: duration,
+	})
+	if err != nil {
+		return err
+	}
+	server.invalidateVideos(ctx, videoID)
+
+	// Imported video has no thumbnail, so we generate the poster from the video
+	if err := server.mediaService.GeneratePoster(ctx, resource, thumbnail, float64(duration)); err != nil {
+		return err
+	}
+
+	// Run content moderation hook on the imported video
+	server.moderateVideo(videoID, resource, thumbnail, duration)
+
+	// Transcode the imported video
+	return server.enqueueTranscode(ctx, videoID, accountID, branding)
+}
+
+// HandleGetImportProgress returns the progress of a video import, only available to the importer.
+// endpoint: GET /videos/import/{id}
+// Success: 200
+// Fail: 400, 404
+func (server *Server) HandleGetImportProgress(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Get progress and check if the requester is the importer
+	progress, ok := server.imports.get(r.Context(), videoID)
+	claims := r.Context().Value(clKey).(*security.CustomClaims)
+	if !ok || progress.AccountID.String() != claims.ID {
+		server.WriteError(w, http.StatusNotFound, "Cannot found any import with this video ID")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, progress)
+}
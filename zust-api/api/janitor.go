@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+	"zust/service/file"
+)
+
+// Statistics of the temporary file janitor since the server started
+type janitorStats struct {
+	mu             sync.Mutex
+	Runs           int       `json:"runs"`
+	RemovedEntries int       `json:"removed_entries"`
+	ReclaimedBytes int64     `json:"reclaimed_bytes"`
+	LastRunAt      time.Time `json:"last_run_at"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// Method to remove the partial files left behind by crashed uploads and transcodes (temporary files which are
+// not modified for TEMP_MAX_AGE), and record the space reclaimed
+func (server *Server) runJanitorJob(ctx context.Context) {
+	removed, reclaimed, err := file.CleanTemp(server.config.ResourcePath, server.config.TempMaxAge)
+
+	server.janitor.mu.Lock()
+	server.janitor.Runs++
+	server.janitor.RemovedEntries += removed
+	server.janitor.ReclaimedBytes += reclaimed
+	server.janitor.LastRunAt = time.Now()
+	server.janitor.LastError = ""
+	if err != nil {
+		server.janitor.LastError = err.Error()
+	}
+	server.janitor.mu.Unlock()
+
+	if err != nil {
+		server.logger.Error("janitor: failed to clean temporary files", "error", err)
+	}
+	server.logger.Info("janitor: temporary files processed", "removed", removed, "reclaimed_bytes", reclaimed)
+}
+
+// HandleGetJanitorStats returns the statistics of the temporary file janitor since the server started: number of
+// runs, entries removed and bytes reclaimed, only available to admin.
+// endpoint: GET /admin/janitor
+// Success: 200
+// Fail: 403
+func (server *Server) HandleGetJanitorStats(w http.ResponseWriter, r *http.Request) {
+	server.janitor.mu.Lock()
+	defer server.janitor.mu.Unlock()
+	server.WriteJSON(w, http.StatusOK, server.janitor)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"os"
+	"zust/service/file"
+	"zust/service/job"
+	"zust/service/mail"
+
+	"github.com/google/uuid"
+)
+
+// Method to register the handlers of all background jobs. Media jobs are left to the transcoding workers when
+// they run remotely
+func (server *Server) registerJobs() {
+	if server.config.TranscodeWorkers != "remote" {
+		server.registerMediaJobs()
+	}
+	server.jobs.Register(job.TypeDownloadAvatar, server.handleDownloadAvatarJob)
+	server.jobs.Register(job.TypeSendEmail, server.handleSendEmailJob)
+	server.jobs.Register(job.TypeCleanup, server.handleCleanupJob)
+	server.jobs.Register(job.TypeBackup, server.handleBackupJob)
+	server.jobs.Register(job.TypeRestore, server.handleRestoreJob)
+	server.jobs.Register(job.TypeWebhook, server.handleWebhookJob)
+	if server.coldStorage != nil {
+		server.jobs.Register(job.TypeThaw, server.handleThawJob)
+	}
+	if server.push != nil {
+		server.jobs.Register(job.TypePush, server.handlePushJob)
+	}
+}
+
+// Method to register the handlers of the media jobs (transcoding and captions), the heavy encoding work
+func (server *Server) registerMediaJobs() {
+	server.jobs.Register(job.TypeTranscode, server.handleTranscodeJob)
+	server.jobs.Register(job.TypeCaption, server.handleCaptionJob)
+}
+
+// Payload of the avatar download job
+type downloadAvatarPayload struct {
+	AccountID uuid.UUID `json:"account_id"`
+	URL       string    `json:"url"`
+}
+
+// Method to handle the avatar download job: download the avatar from OAuth provider and rewrite the default avatar
+func (server *Server) handleDownloadAvatarJob(ctx context.Context, j *job.Job) error {
+	var payload downloadAvatarPayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+
+	return file.DownloadURL(ctx, payload.URL, server.localPath(file.AvatarKey(payload.AccountID.String())))
+}
+
+// Payload of the email sending job: the body is the HTML, sent along its plain-text alternative. The headers are
+// added to the default ones
+type sendEmailPayload struct {
+	To          string            `json:"to"`
+	Subject     string            `json:"subject"`
+	Body        string            `json:"body"`
+	Text        string            `json:"text,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attachments []mail.Attachment `json:"attachments,omitempty"`
+}
+
+// Method to handle the email sending job
+func (server *Server) handleSendEmailJob(ctx context.Context, j *job.Job) error {
+	var payload sendEmailPayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+
+	return server.mailService.SendEmail(&mail.Message{
+		To:          payload.To,
+		Subject:     payload.Subject,
+		Text:        payload.Text,
+		HTML:        payload.Body,
+		Headers:     payload.Headers,
+		Attachments: payload.Attachments,
+	})
+}
+
+// Payload of the file cleanup job
+type cleanupPayload struct {
+	Files []string `json:"files"`
+}
+
+// Method to handle the file cleanup job: remove all files, files that are already removed are ignored
+func (server *Server) handleCleanupJob(ctx context.Context, j *job.Job) error {
+	var payload cleanupPayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+
+	for _, filename := range payload.Files {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
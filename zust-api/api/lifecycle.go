@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Hook of a subsystem of the server (job workers, schedulers, cache, HTTP listener, ...). Hooks are started in the
+// order they are registered and stopped in the reverse order, so a subsystem is stopped before the ones registered
+// earlier, which it may depend on
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error // nil if the subsystem has nothing to start
+	Stop  func(ctx context.Context) error // nil if the subsystem has nothing to stop
+}
+
+// Hooks of the subsystems of the server
+type lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	started int // number of hooks started, only them are stopped
+}
+
+// OnLifecycle registers the hook of a subsystem, it must be called before the server is started
+func (server *Server) OnLifecycle(hook Hook) {
+	server.lifecycle.mu.Lock()
+	defer server.lifecycle.mu.Unlock()
+	server.lifecycle.hooks = append(server.lifecycle.hooks, hook)
+}
+
+// Helper method: start the hooks in order. If a hook fails, the hooks already started are stopped
+func (server *Server) startHooks(ctx context.Context) error {
+	server.lifecycle.mu.Lock()
+	hooks := server.lifecycle.hooks[server.lifecycle.started:]
+	server.lifecycle.mu.Unlock()
+
+	for _, hook := range hooks {
+		if hook.Start != nil {
+			if err := hook.Start(ctx); err != nil {
+				server.Shutdown(ctx)
+				return fmt.Errorf("start %s: %w", hook.Name, err)
+			}
+			server.logger.Debug("lifecycle: started", "hook", hook.Name)
+		}
+
+		server.lifecycle.mu.Lock()
+		server.lifecycle.started++
+		server.lifecycle.mu.Unlock()
+	}
+	return nil
+}
+
+// Shutdown stops the started subsystems in the reverse order of their registration. Each hook is stopped even if
+// a previous one failed, the errors are returned together. ctx bounds the time given to the whole shutdown
+func (server *Server) Shutdown(ctx context.Context) error {
+	server.lifecycle.mu.Lock()
+	hooks := server.lifecycle.hooks[:server.lifecycle.started]
+	server.lifecycle.started = 0
+	server.lifecycle.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.Stop == nil {
+			continue
+		}
+		if err := hook.Stop(ctx); err != nil {
+			server.logger.Error("lifecycle: failed to stop", "hook", hook.Name, "error", err)
+			errs = append(errs, fmt.Errorf("stop %s: %w", hook.Name, err))
+			continue
+		}
+		server.logger.Debug("lifecycle: stopped", "hook", hook.Name)
+	}
+	return errors.Join(errs...)
+}
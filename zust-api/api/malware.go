@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+	"zust/service/file"
+	"zust/service/job"
+	"zust/service/mail"
+
+	"github.com/google/uuid"
+)
+
+// Method to scan the uploaded files of a video (the video and the thumbnail supplied by the user, if any) for
+// malware before anything derived from them is published. If a file is infected, all of them are moved into the
+// quarantine, the video is failed and the admins are notified. It returns true if the video is infected
+func (server *Server) scanUpload(ctx context.Context, videoID, publisherID uuid.UUID, paths ...string) (bool, error) {
+	// Malware scanning is disabled
+	if server.malwareScanner == nil {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	var uploads []string
+	signature := ""
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		uploads = append(uploads, path)
+
+		result, err := server.malwareScanner.Scan(ctx, path)
+		if err != nil {
+			return false, err
+		}
+		if result.Infected && signature == "" {
+			signature = result.Signature
+		}
+	}
+	if signature == "" {
+		return false, nil
+	}
+
+	// Quarantine the uploaded files, so they are never served nor transcoded
+	quarantine := filepath.Join(server.config.QuarantinePath, publisherID.String(), videoID.String())
+	if err := os.MkdirAll(quarantine, 0755); err != nil {
+		return true, err
+	}
+	for _, path := range uploads {
+		if err := file.MoveFile(path, filepath.Join(quarantine, filepath.Base(path))); err != nil {
+			return true, err
+		}
+	}
+
+	if err := server.query.FailVideo(ctx, videoID); err != nil {
+		server.logger.Error("malware: failed to mark video as failed", "video_id", videoID, "error", err)
+	}
+	server.invalidateVideos(ctx, videoID)
+	server.logger.Warn("malware: infected upload quarantined", "video_id", videoID, "publisher_id", publisherID,
+		"signature", signature, "quarantine", quarantine)
+
+	server.callProcessingWebhook(ctx, videoID, publisherID, "video.processing_failed")
+	server.notifyMalware(ctx, mail.MalwareEmailPayload{
+		VideoID:     videoID.String(),
+		PublisherID: publisherID.String(),
+		Signature:   signature,
+		Quarantine:  quarantine,
+	})
+	return true, nil
+}
+
+// Helper method: send the malware detection email to all admins, failure is only logged
+func (server *Server) notifyMalware(ctx context.Context, payload mail.MalwareEmailPayload) {
+	emails, err := server.query.ListAdminEmails(ctx)
+	if err != nil {
+		server.logger.Error("malware: failed to list admin emails", "error", err)
+		return
+	}
+
+	body, text, err := server.mailService.PrepareBodies("malware", payload)
+	if err != nil {
+		server.logger.Error("malware: failed to prepare email", "error", err)
+		return
+	}
+
+	for _, email := range emails {
+		err := server.jobs.Enqueue(ctx, job.TypeSendEmail, sendEmailPayload{
+			To:      email,
+			Subject: "Zust - Malware detected in an upload",
+			Body:    body,
+			Text:    text,
+		})
+		if err != nil {
+			server.logger.Error("malware: failed to enqueue email", "email", email, "error", err)
+		}
+	}
+}
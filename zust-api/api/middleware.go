@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	db "zust/db/sqlc"
+	"zust/service/security"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // AuthMiddleware is a middleware that checks for a valid JWT token in the Authorization header
@@ -16,26 +19,28 @@ func (server *Server) AuthMiddleware(next http.Handler) http.Handler {
 		// Get the request header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			server.WriteError(w, http.StatusUnauthorized, "Missing request header")
+			server.WriteErrorCode(w, http.StatusUnauthorized, CodeAuthMissingToken, "Missing request header", nil)
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		// Verify token
-		claims, err := server.jwtService.VerifyToken(tokenString, server.query)
+		claims, err := server.jwtService.VerifyToken(r.Context(), tokenString, server.tokenVersions())
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
-				server.WriteError(w, http.StatusUnauthorized, "Access token expired")
+				server.WriteErrorCode(w, http.StatusUnauthorized, CodeAuthTokenExpired, "Access token expired", nil)
 				return
 			}
 
 			if errors.Is(err, jwt.ErrTokenMalformed) {
-				server.WriteError(w, http.StatusBadRequest, "Invalid access token: token is malformed")
+				server.WriteErrorCode(w, http.StatusBadRequest, CodeAuthTokenInvalid,
+					"Invalid access token: token is malformed", nil)
 				return
 			}
 
-			server.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Invalid access token: %s", err.Error()))
+			server.WriteErrorCode(w, http.StatusBadRequest, CodeAuthTokenInvalid,
+				fmt.Sprintf("Invalid access token: %s", err.Error()), nil)
 			return
 		}
 
@@ -43,13 +48,94 @@ func (server *Server) AuthMiddleware(next http.Handler) http.Handler {
 		path := r.URL.Path
 		if claims.TokenType == "refresh-token" && path == "/auth/token/refresh" ||
 			claims.TokenType == "access-token" && path != "/auth/token/refresh" {
+			// A token is only valid on the site of its account
+			if ok, err := server.claimsInTenant(r.Context(), claims); err != nil {
+				server.logger.Error(fmt.Sprintf("%s %s: failed to get account tenant", r.Method, r.URL.Path),
+					"error", err)
+				server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			} else if !ok {
+				server.WriteErrorCode(w, http.StatusUnauthorized, CodeAuthTokenInvalid,
+					"Invalid access token: token is not issued by this site", nil)
+				return
+			}
+
 			// Extract the claims and put them in the request context
 			r = r.WithContext(context.WithValue(r.Context(), clKey, claims))
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		server.WriteError(w, http.StatusBadRequest, "Invalid access token: unsuitable token type for this request")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeAuthTokenInvalid,
+			"Invalid access token: unsuitable token type for this request", nil)
+
+	})
+}
+
+// AdminMiddleware is a middleware that only allows admin accounts, it must be wrapped inside AuthMiddleware
+func (server *Server) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var accountID uuid.UUID
+		accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+
+		role, err := server.query.GetAccountRole(r.Context(), accountID)
+		if err != nil {
+			server.logger.Error(fmt.Sprintf("%s %s: failed to get account role", r.Method, r.URL.Path), "error", err)
+			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		// Admins manage the whole deployment, from the site of the default tenant only
+		if role != db.AccountRoleAdmin || tenantOf(r.Context()) != security.DefaultTenant {
+			server.WriteError(w, http.StatusForbidden, "Only admin can access this resource")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Memory used to parse a multipart form, the files over it are kept in temporary files
+const multipartMemory = 32 << 20
+
+// Helper method: get the body size limit of a route, from the pattern it's registered with and the upload limits
+// of the tenant
+func (server *Server) bodyLimit(tenant, pattern string) int64 {
+	config := server.config.ForTenant(tenant)
+	switch pattern {
+	case "PUT /accounts/{id}", "PUT /accounts/{id}/watermark":
+		return config.ImageSize
+	case "POST /videos", "PUT /accounts/{id}/branding/{kind}":
+		return config.VideoSize
+	default:
+		return config.JSONBodySize
+	}
+}
 
+// BodyLimitMiddleware is a middleware that limits the size of the request body with the limit of the matched
+// route. A request whose Content-Length is over the limit is rejected with 413 before its body is read, a longer
+// body without Content-Length fails when the handler reads past the limit
+func (server *Server) BodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := server.mux.Handler(r)
+		limit := server.bodyLimit(tenantOf(r.Context()), pattern)
+		if r.ContentLength > limit {
+			server.WriteError(w, http.StatusRequestEntityTooLarge, "Request body is too large")
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
 	})
 }
+
+// Helper method: write the error of a multipart form which can't be read, 413 if the body is over the limit of
+// the route, otherwise 400 with 'message'
+func (server *Server) writeFormError(w http.ResponseWriter, err error, message string) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		server.WriteError(w, http.StatusRequestEntityTooLarge, "Request body is too large")
+		return
+	}
+	server.WriteError(w, http.StatusBadRequest, message)
+}
@@ -0,0 +1,539 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Error of a decision on a target without open report, nothing to decide on
+var errNoOpenReports = errors.New("no open report for the target")
+
+// Method to run the content moderation hook on an uploaded video. The video is sampled into frames, which (together
+// with the thumbnail) are sent to the moderation scanner. If any category score reaches its configured threshold,
+// the video is held for review. This method is expected to run in background, so it only logs errors
+func (server *Server) moderateVideo(videoID uuid.UUID, resource, thumbnail string, duration int32) {
+	// Moderation is disabled
+	if server.moderationScanner == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	// Extract frames into a temporary directory, which is removed after scanning
+	dir, err := os.MkdirTemp("", "zust-moderation-*")
+	if err != nil {
+		server.logger.Error("moderation: failed to create temporary directory", "video_id", videoID, "error", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	frames, err := server.mediaService.ExtractFrames(ctx, resource, dir, server.config.ModerationFrames, duration)
+	if err != nil {
+		server.logger.Error("moderation: failed to extract frames", "video_id", videoID, "error", err)
+		return
+	}
+	if thumbnail != "" {
+		frames = append(frames, thumbnail)
+	}
+
+	// Scan frames and check against the thresholds
+	result, err := server.moderationScanner.Scan(ctx, frames)
+	if err != nil {
+		server.logger.Error("moderation: failed to scan video", "video_id", videoID, "error", err)
+		return
+	}
+
+	category, exceeded := result.Exceed(server.config.ModerationThresholds)
+	if !exceeded {
+		return
+	}
+
+	// Hold the video for review
+	if err := server.query.HoldVideo(ctx, videoID); err != nil {
+		server.logger.Error("moderation: failed to hold video for review", "video_id", videoID, "error", err)
+		return
+	}
+	server.invalidateVideos(ctx, videoID)
+	server.logger.Info("moderation: video held for review", "video_id", videoID, "category", category,
+		"score", result.Scores[category])
+}
+
+// Request body to report a video or an account
+type reportRequest struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam harassment hate violence sexual copyright other"`
+	Details string `json:"details" validate:"max=500"`
+}
+
+// HandleReportVideo reports a video to the moderators, a user can't report the same video again until a decision is
+// made on it.
+// endpoint: POST /videos/{id}/reports
+// Success: 201
+// Fail: 400, 401, 403, 404, 409, 500
+func (server *Server) HandleReportVideo(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Get request body
+	var req reportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	var reporterID uuid.UUID
+	reporterID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /videos/{id}/reports"))
+	if _, isActive := server.checkAccountStatus(w, r, reporterID); !isActive {
+		return
+	}
+
+	// Only the videos the reporter can watch can be reported
+	video, err := server.getVideo(r.Context(), videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+			return
+		}
+
+		server.logger.Error("POST /videos/{id}/reports: failed to get video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if video.AccountID == reporterID {
+		server.WriteError(w, http.StatusBadRequest, "Cannot report your own video")
+		return
+	}
+	if video.Status != db.VideoStatusPublished || video.Visibility == db.VideoVisibilityPrivate {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+		return
+	}
+
+	server.createReport(w, r, reporterID, db.ReportTargetVideo, videoID, req)
+}
+
+// HandleReportAccount reports an account to the moderators, a user can't report the same account again until a
+// decision is made on it.
+// endpoint: POST /accounts/{id}/reports
+// Success: 201
+// Fail: 400, 401, 403, 404, 409, 500
+func (server *Server) HandleReportAccount(w http.ResponseWriter, r *http.Request) {
+	// Get the ID of the reported account
+	var accountID uuid.UUID
+	if err := accountID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	// Get request body
+	var req reportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	var reporterID uuid.UUID
+	reporterID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	if reporterID == accountID {
+		server.WriteError(w, http.StatusBadRequest, "Cannot report your own account")
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /accounts/{id}/reports"))
+	if _, isActive := server.checkAccountStatus(w, r, reporterID); !isActive {
+		return
+	}
+
+	// The reported account must be on the same site as the reporter
+	if _, err := server.getProfile(r.Context(), accountID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
+			return
+		}
+
+		server.logger.Error("POST /accounts/{id}/reports: failed to get account", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.createReport(w, r, reporterID, db.ReportTargetAccount, accountID, req)
+}
+
+// Helper method: create the report of a target and write the response, 409 is returned if the reporter already has
+// an open report of the target
+func (server *Server) createReport(w http.ResponseWriter, r *http.Request, reporterID uuid.UUID,
+	targetType db.ReportTarget, targetID uuid.UUID, req reportRequest) {
+	created, err := server.query.CreateReport(r.Context(), db.CreateReportParams{
+		ReporterID: reporterID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     db.ReportReason(req.Reason),
+		Details:    sql.NullString{String: req.Details, Valid: req.Details != ""},
+	})
+	if err != nil {
+		server.logger.Error(fmt.Sprintf("%s: failed to create report", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if created == 0 {
+		server.WriteErrorCode(w, http.StatusConflict, CodeAlreadyReported, "You already reported this "+string(targetType),
+			nil)
+		return
+	}
+
+	server.WriteJSON(w, http.StatusCreated, "Report submitted successfully")
+}
+
+// A target with open reports in the moderation queue
+type moderationQueueResult struct {
+	TargetType      string    `json:"target_type"`
+	TargetID        string    `json:"target_id"`
+	TargetName      string    `json:"target_name"` // title of the video or username of the account
+	ReportCount     int       `json:"report_count"`
+	Reasons         []string  `json:"reasons"`
+	FirstReportedAt time.Time `json:"first_reported_at"`
+	LastReportedAt  time.Time `json:"last_reported_at"`
+}
+
+// HandleListModerationQueue lists the targets with open reports, their reports grouped together, only available to
+// admin. The targets reported first come first; 'type' keeps only the videos or the accounts. The next page is
+// requested with the cursor returned in X-Next-Cursor.
+// endpoint: GET /admin/moderation?type=...&cursor=...&size=...
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleListModerationQueue(w http.ResponseWriter, r *http.Request) {
+	targetType := r.URL.Query().Get("type")
+	if targetType != "" && !isReportTarget(db.ReportTarget(targetType)) {
+		server.WriteError(w, http.StatusBadRequest, "Invalid target type")
+		return
+	}
+
+	cursor, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	afterReportedAt, afterID := cursor.params()
+	targets, err := server.query.ListModerationQueue(r.Context(), db.ListModerationQueueParams{
+		TargetType:      targetType,
+		AfterReportedAt: afterReportedAt,
+		AfterID:         afterID,
+		PageSize:        int32(size),
+	})
+	if err != nil {
+		server.logger.Error("GET /admin/moderation: failed to list moderation queue", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]moderationQueueResult, 0, len(targets))
+	for _, target := range targets {
+		data = append(data, moderationQueueResult{
+			TargetType:      string(target.TargetType),
+			TargetID:        target.TargetID.String(),
+			TargetName:      target.TargetName,
+			ReportCount:     int(target.ReportCount),
+			Reasons:         target.Reasons,
+			FirstReportedAt: target.FirstReportedAt,
+			LastReportedAt:  target.LastReportedAt,
+		})
+	}
+	if len(targets) > 0 {
+		last := targets[len(targets)-1]
+		setNextCursor(w, len(targets), size, last.FirstReportedAt, last.TargetID)
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// An open report of a target
+type reportResult struct {
+	ID               string    `json:"id"`
+	Reason           string    `json:"reason"`
+	Details          string    `json:"details"`
+	ReporterID       string    `json:"reporter_id"`
+	ReporterUsername string    `json:"reporter_username"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// A decision made on a target
+type moderationDecisionResult struct {
+	ID                string    `json:"id"`
+	Action            string    `json:"action"`
+	Note              string    `json:"note"`
+	ModeratorID       string    `json:"moderator_id"`
+	ModeratorUsername string    `json:"moderator_username,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+
+	Strike *decisionStrikeResult `json:"strike,omitempty"` // only in the response of a strike
+}
+
+// The strike given by a decision and its enforcement
+type decisionStrikeResult struct {
+	AccountID      string     `json:"account_id"`
+	ActiveStrikes  int        `json:"active_strikes"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	Suspended      bool       `json:"suspended"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"` // not set if suspended until an admin reinstates it
+}
+
+// Response body for GetModerationTarget
+type moderationTargetResponse struct {
+	Reports   []reportResult             `json:"reports"`
+	Decisions []moderationDecisionResult `json:"decisions"` // latest first
+}
+
+// HandleGetModerationTarget returns the open reports of a target and the decisions made on it before, only available
+// to admin. 'type' is video or account.
+// endpoint: GET /admin/moderation/{type}/{id}
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleGetModerationTarget(w http.ResponseWriter, r *http.Request) {
+	targetType, targetID, ok := server.parseModerationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	reports, err := server.query.ListOpenReports(r.Context(), db.ListOpenReportsParams{
+		TargetType: targetType,
+		TargetID:   targetID,
+	})
+	if err != nil {
+		server.logger.Error("GET /admin/moderation/{type}/{id}: failed to list open reports", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	decisions, err := server.query.ListModerationDecisions(r.Context(), db.ListModerationDecisionsParams{
+		TargetType: targetType,
+		TargetID:   targetID,
+	})
+	if err != nil {
+		server.logger.Error("GET /admin/moderation/{type}/{id}: failed to list decisions", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := moderationTargetResponse{
+		Reports:   make([]reportResult, 0, len(reports)),
+		Decisions: make([]moderationDecisionResult, 0, len(decisions)),
+	}
+	for _, report := range reports {
+		data.Reports = append(data.Reports, reportResult{
+			ID:               report.ReportID.String(),
+			Reason:           string(report.Reason),
+			Details:          report.Details.String,
+			ReporterID:       report.AccountID.String(),
+			ReporterUsername: report.Username,
+			CreatedAt:        report.CreatedAt,
+		})
+	}
+	for _, decision := range decisions {
+		data.Decisions = append(data.Decisions, moderationDecisionResult{
+			ID:                decision.DecisionID.String(),
+			Action:            string(decision.Action),
+			Note:              decision.Note.String,
+			ModeratorID:       decision.AccountID.String(),
+			ModeratorUsername: decision.Username,
+			CreatedAt:         decision.CreatedAt,
+		})
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Request body to make a decision on a reported target
+type moderationDecisionRequest struct {
+	Action string `json:"action" validate:"required,oneof=dismiss age_restrict remove strike"`
+	Note   string `json:"note" validate:"max=500"`
+}
+
+// HandleCreateModerationDecision acts on a reported target and resolves all its open reports, only available to
+// admin. The decision is recorded with the requester as moderator. The actions are:
+//   - dismiss: nothing is done, the reports are closed
+//   - age_restrict: the video is only shown to signed-in viewers (videos only)
+//   - remove: the video or the account is deleted, an admin can restore it later
+//   - strike: a strike against the publisher of the video (which is removed too) or against the account, the
+//     account is suspended if its active strikes reach a rule of the strike policy (see giveStrike) and is told by
+//     email
+//
+// endpoint: POST /admin/moderation/{type}/{id}/decisions
+// Success: 201
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleCreateModerationDecision(w http.ResponseWriter, r *http.Request) {
+	targetType, targetID, ok := server.parseModerationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	// Get request body
+	var req moderationDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+	action := db.ModerationAction(req.Action)
+	if action == db.ModerationActionAgeRestrict && targetType != db.ReportTargetVideo {
+		server.WriteError(w, http.StatusBadRequest, "Only a video can be age restricted")
+		return
+	}
+
+	var moderatorID uuid.UUID
+	moderatorID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+
+	// Record the decision, resolve the reports and apply the action together
+	var decision db.ModerationDecision
+	var strike *strikeOutcome
+	err := server.query.ExecTx(r.Context(), func(q *db.Queries) error {
+		var err error
+		decision, err = q.CreateModerationDecision(r.Context(), db.CreateModerationDecisionParams{
+			TargetType:  targetType,
+			TargetID:    targetID,
+			Action:      action,
+			ModeratorID: moderatorID,
+			Note:        sql.NullString{String: req.Note, Valid: req.Note != ""},
+		})
+		if err != nil {
+			return err
+		}
+
+		resolved, err := q.ResolveReports(r.Context(), db.ResolveReportsParams{
+			TargetType: targetType,
+			TargetID:   targetID,
+			DecisionID: uuid.NullUUID{UUID: decision.DecisionID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if resolved == 0 {
+			return errNoOpenReports
+		}
+
+		// The target may be deleted since it was reported, the decision is recorded anyway
+		switch {
+		case action == db.ModerationActionAgeRestrict:
+			_, err = q.SetVideoAgeRestricted(r.Context(), targetID)
+		case action == db.ModerationActionDismiss:
+		case targetType == db.ReportTargetVideo:
+			_, err = q.RemoveVideo(r.Context(), targetID)
+		case action == db.ModerationActionRemove:
+			_, err = q.DeleteAccount(r.Context(), targetID)
+		}
+		if err != nil || action != db.ModerationActionStrike {
+			return err
+		}
+
+		// The strike is against the publisher of a video, or the account itself
+		accountID, target := targetID, "your account"
+		if targetType == db.ReportTargetVideo {
+			video, err := q.GetVideoPublisher(r.Context(), targetID)
+			if err != nil {
+				return err
+			}
+			accountID, target = video.PublisherID, fmt.Sprintf("your video %q", video.Title)
+		}
+		strike, err = server.giveStrike(r.Context(), q, accountID, decision.DecisionID, target)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errNoOpenReports) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeReportNotFound, "No open report for this target", nil)
+			return
+		}
+
+		server.logger.Error("POST /admin/moderation/{type}/{id}/decisions: failed to create decision", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if targetType == db.ReportTargetVideo {
+		server.invalidateVideos(r.Context(), targetID)
+	} else if action == db.ModerationActionRemove {
+		server.invalidateProfile(r.Context(), targetID)
+		server.invalidateTokenVersion(r.Context(), targetID)
+		server.invalidateAccountVideos(r.Context(), targetID)
+	}
+	server.logger.Info("moderation: decision made", "target_type", targetType, "target_id", targetID,
+		"action", action, "moderator_id", moderatorID)
+
+	data := moderationDecisionResult{
+		ID:          decision.DecisionID.String(),
+		Action:      string(decision.Action),
+		Note:        decision.Note.String,
+		ModeratorID: decision.ModeratorID.String(),
+		CreatedAt:   decision.CreatedAt,
+	}
+	if strike != nil {
+		if strike.Suspended {
+			server.invalidateProfile(r.Context(), strike.AccountID)
+			server.invalidateTokenVersion(r.Context(), strike.AccountID)
+			server.logger.Info("moderation: account suspended", "account_id", strike.AccountID,
+				"active_strikes", strike.ActiveStrikes)
+		}
+		server.notifyStrike(r.Context(), strike)
+
+		data.Strike = &decisionStrikeResult{
+			AccountID:     strike.AccountID.String(),
+			ActiveStrikes: int(strike.ActiveStrikes),
+			ExpiresAt:     strike.Strike.ExpiresAt,
+			Suspended:     strike.Suspended,
+		}
+		if strike.Suspended && strike.SuspendedUntil.Valid {
+			data.Strike.SuspendedUntil = &strike.SuspendedUntil.Time
+		}
+	}
+
+	server.WriteJSON(w, http.StatusCreated, data)
+}
+
+// Helper method: get the type and the ID of the target in the path of a moderation endpoint. If they are invalid,
+// 400 is written and false is returned
+func (server *Server) parseModerationTarget(w http.ResponseWriter, r *http.Request) (db.ReportTarget, uuid.UUID,
+	bool) {
+	targetType := db.ReportTarget(r.PathValue("type"))
+	if !isReportTarget(targetType) {
+		server.WriteError(w, http.StatusBadRequest, "Invalid target type")
+		return "", uuid.UUID{}, false
+	}
+
+	var targetID uuid.UUID
+	if err := targetID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid target ID")
+		return "", uuid.UUID{}, false
+	}
+	return targetType, targetID, true
+}
+
+// Helper function: check if a target type is one of the reportable targets
+func isReportTarget(targetType db.ReportTarget) bool {
+	return targetType == db.ReportTargetVideo || targetType == db.ReportTargetAccount
+}
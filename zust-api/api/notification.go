@@ -0,0 +1,644 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+
+	// The timezones of the accounts are validated against the IANA database, embedded so the validation doesn't
+	// depend on the zoneinfo of the host
+	_ "time/tzdata"
+)
+
+// Request body for UpdateNotificationPreferences, the missing fields are left unchanged
+type notificationPreferencesRequest struct {
+	DigestFrequency *string `json:"digest_frequency" validate:"omitempty,oneof=off daily weekly"`
+	Timezone        *string `json:"timezone" validate:"omitempty,timezone,max=64"`
+}
+
+// Method to get the parameters of the update of the preferences of an account, the missing fields are not updated
+func (req notificationPreferencesRequest) params(accountID uuid.UUID) db.UpdateNotificationPreferencesParams {
+	params := db.UpdateNotificationPreferencesParams{AccountID: accountID}
+	if req.DigestFrequency != nil {
+		params.DigestFrequency = db.NullDigestFrequency{DigestFrequency: db.DigestFrequency(*req.DigestFrequency), Valid: true}
+	}
+	if req.Timezone != nil {
+		params.Timezone = sql.NullString{String: *req.Timezone, Valid: true}
+	}
+	return params
+}
+
+// Response body for GetNotificationPreferences and UpdateNotificationPreferences
+type notificationPreferencesResponse struct {
+	DigestFrequency string `json:"digest_frequency"`
+	Timezone        string `json:"timezone"`
+}
+
+// Response body for GetEmailPreferences
+type emailPreferencesResponse struct {
+	Kind            string `json:"kind"` // kind of email the token unsubscribes from
+	DigestFrequency string `json:"digest_frequency"`
+	Timezone        string `json:"timezone"`
+}
+
+// Request body for MarkNotificationsRead: the listed notifications are marked as read, or all the notifications
+// (of a type) without IDs
+type markNotificationsReadRequest struct {
+	IDs  []uuid.UUID `json:"ids" validate:"max=100"`
+	Type string      `json:"type" validate:"omitempty,oneof=new_video new_subscriber video_ready video_failed"`
+}
+
+// A notification in the list of notifications, and the data of the notification event of the streams (without the
+// names of the actor and the video)
+type notificationResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Type          string     `json:"type"`
+	ActorID       *uuid.UUID `json:"actor_id,omitempty"`
+	ActorUsername string     `json:"actor_username,omitempty"`
+	VideoID       *uuid.UUID `json:"video_id,omitempty"`
+	VideoTitle    string     `json:"video_title,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReadAt        *time.Time `json:"read_at,omitempty"`
+}
+
+// Response body for GetUnreadCounts, the count of each type is 0 if there is no unread notification of this type
+type unreadCountsResponse struct {
+	Total  int64            `json:"total"`
+	ByType map[string]int64 `json:"by_type"`
+}
+
+// Response body for MarkNotificationsRead and MarkNotificationRead, and the data of the read event of the streams
+type notificationsReadResponse struct {
+	IDs    []uuid.UUID          `json:"ids"` // notifications marked as read, the ones already read are left out
+	Unread unreadCountsResponse `json:"unread"`
+}
+
+// Helper function: build a notification of the list of notifications
+func newNotificationResponse(row db.ListNotificationsRow) notificationResponse {
+	notification := notificationResponse{
+		ID:            row.NotificationID,
+		Type:          string(row.Type),
+		ActorUsername: row.ActorUsername.String,
+		VideoTitle:    row.VideoTitle.String,
+		CreatedAt:     row.CreatedAt,
+	}
+	if row.ActorID.Valid {
+		notification.ActorID = &row.ActorID.UUID
+	}
+	if row.VideoID.Valid {
+		notification.VideoID = &row.VideoID.UUID
+	}
+	if row.ReadAt.Valid {
+		notification.ReadAt = &row.ReadAt.Time
+	}
+	return notification
+}
+
+// Helper function: build the notification event of the streams from a new notification
+func newNotificationEvent(notification db.Notification) notificationResponse {
+	return newNotificationResponse(db.ListNotificationsRow{
+		NotificationID: notification.NotificationID,
+		Type:           notification.Type,
+		ActorID:        notification.ActorID,
+		VideoID:        notification.VideoID,
+		CreatedAt:      notification.CreatedAt,
+	})
+}
+
+// Preference turned off by the unsubscribe token of each kind of email
+var unsubscribePreferences = map[string]db.UpdateNotificationPreferencesParams{
+	security.UnsubscribeDigest: {
+		DigestFrequency: db.NullDigestFrequency{DigestFrequency: db.DigestFrequencyOff, Valid: true},
+	},
+}
+
+// HandleGetNotificationPreferences returns the notification preferences of the account: how often the digest of the
+// new videos of its subscriptions is sent, and the timezone it is sent in.
+// endpoint: GET /accounts/{id}/notification-preferences
+// Success: 200
+// Fail: 400, 500
+func (server *Server) HandleGetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	preferences, err := server.query.GetNotificationPreferences(r.Context(), accID)
+	if err != nil {
+		server.logger.Error("GET /accounts/{id}/notification-preferences: failed to get notification preferences",
+			"error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, notificationPreferencesResponse{
+		DigestFrequency: string(preferences.DigestFrequency),
+		Timezone:        preferences.Timezone,
+	})
+}
+
+// HandleUpdateNotificationPreferences updates the notification preferences of the account. The digest frequency is
+// off, daily or weekly (sent on Mondays), the timezone is an IANA name (Europe/Paris).
+// endpoint: PUT /accounts/{id}/notification-preferences
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Get request body
+	var req notificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Check account status if it's active or not before processing with the request
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "PUT /accounts/{id}/notification-preferences"))
+	if _, isActive := server.checkAccountStatus(w, r, accID); !isActive {
+		return
+	}
+
+	// Update preferences
+	preferences, err := server.query.UpdateNotificationPreferences(r.Context(), req.params(accID))
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/notification-preferences: failed to update notification preferences",
+			"error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, notificationPreferencesResponse{
+		DigestFrequency: string(preferences.DigestFrequency),
+		Timezone:        preferences.Timezone,
+	})
+}
+
+// HandleGetEmailPreferences returns the notification preferences of the account of an unsubscribe token, without
+// login: it's the preference center opened from the unsubscribe link of an email.
+// endpoint: GET /unsubscribe?token=TOKEN
+// Success: 200
+// Fail: 400, 404, 500
+func (server *Server) HandleGetEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	accID, kind, ok := server.unsubscribeToken(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := server.query.GetNotificationPreferences(r.Context(), accID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
+			return
+		}
+
+		server.logger.Error("GET /unsubscribe: failed to get notification preferences", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, emailPreferencesResponse{
+		Kind:            kind,
+		DigestFrequency: string(preferences.DigestFrequency),
+		Timezone:        preferences.Timezone,
+	})
+}
+
+// HandleUnsubscribeEmail turns off the kind of email of an unsubscribe token, without login. It's the one-click
+// unsubscribe of the List-Unsubscribe-Post header (RFC 8058): the body sent by the mail clients is ignored.
+// endpoint: POST /unsubscribe?token=TOKEN
+// Success: 200
+// Fail: 400, 404, 500
+func (server *Server) HandleUnsubscribeEmail(w http.ResponseWriter, r *http.Request) {
+	accID, kind, ok := server.unsubscribeToken(w, r)
+	if !ok {
+		return
+	}
+
+	params := unsubscribePreferences[kind]
+	params.AccountID = accID
+	if _, err := server.query.UpdateNotificationPreferences(r.Context(), params); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
+			return
+		}
+
+		server.logger.Error("POST /unsubscribe: failed to update notification preferences", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Unsubscribed successfully")
+}
+
+// HandleUpdateEmailPreferences updates the notification preferences of the account of an unsubscribe token, without
+// login, from the preference center. The body is the same as UpdateNotificationPreferences.
+// endpoint: PUT /unsubscribe?token=TOKEN
+// Success: 200
+// Fail: 400, 404, 500
+func (server *Server) HandleUpdateEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	accID, _, ok := server.unsubscribeToken(w, r)
+	if !ok {
+		return
+	}
+
+	// Get request body
+	var req notificationPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Update preferences
+	preferences, err := server.query.UpdateNotificationPreferences(r.Context(), req.params(accID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeAccountNotFound, "Account not found", nil)
+			return
+		}
+
+		server.logger.Error("PUT /unsubscribe: failed to update notification preferences", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, notificationPreferencesResponse{
+		DigestFrequency: string(preferences.DigestFrequency),
+		Timezone:        preferences.Timezone,
+	})
+}
+
+// Helper method: get the account and the kind of email of the unsubscribe token in the query parameters. It writes
+// 400 and returns false if the token is missing, invalid, or of an unknown kind of email
+func (server *Server) unsubscribeToken(w http.ResponseWriter, r *http.Request) (uuid.UUID, string, bool) {
+	accountID, kind, err := security.VerifyUnsubscribeToken(server.config.SecretKey, r.URL.Query().Get("token"))
+	if err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeUnsubscribeInvalid, "Invalid unsubscribe token", nil)
+		return uuid.Nil, "", false
+	}
+
+	var accID uuid.UUID
+	if _, known := unsubscribePreferences[kind]; !known || accID.Scan(accountID) != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeUnsubscribeInvalid, "Invalid unsubscribe token", nil)
+		return uuid.Nil, "", false
+	}
+	return accID, kind, true
+}
+
+// HandleListNotifications lists the notifications of the requester, latest first (only the unread ones with
+// unread=true). The next page is requested with the cursor returned in X-Next-Cursor. The first page also returns
+// X-Sync-Cursor: with since=SYNC_CURSOR, the notifications created or read since then are listed instead, oldest
+// change first, and X-Sync-Cursor is the cursor of the next sync. This way the clients of an account catch up with
+// the changes made by the others.
+// endpoint: GET /notifications?cursor=...&size=...&unread=true or GET /notifications?since=...&size=...
+// Success: 200
+// Fail: 400, 401, 500
+func (server *Server) HandleListNotifications(w http.ResponseWriter, r *http.Request) {
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+
+	// Get pagination
+	cursor, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+	if since := r.URL.Query().Get("since"); since != "" {
+		server.listNotificationChanges(w, r, accountID, since, size)
+		return
+	}
+
+	// The sync cursor is taken before the list, so a change made meanwhile is synced again rather than missed
+	if cursor == nil {
+		syncCursor, err := server.notificationSyncCursor(r.Context(), accountID)
+		if err != nil {
+			server.logger.Error("GET /notifications: failed to get sync cursor", "error", err)
+			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		w.Header().Set("X-Sync-Cursor", syncCursor)
+	}
+
+	// List notifications
+	afterCreatedAt, afterID := cursor.params()
+	notifications, err := server.query.ListNotifications(r.Context(), db.ListNotificationsParams{
+		RecipientID:    accountID,
+		UnreadOnly:     r.URL.Query().Get("unread") == "true",
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		PageSize:       int32(size),
+	})
+	if err != nil {
+		server.logger.Error("GET /notifications: failed to list notifications", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Return the result back to client
+	data := make([]notificationResponse, len(notifications))
+	for i, notification := range notifications {
+		data[i] = newNotificationResponse(notification)
+	}
+	if len(notifications) > 0 {
+		last := notifications[len(notifications)-1]
+		setNextCursor(w, len(notifications), size, last.CreatedAt, last.NotificationID)
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Helper method: list the notifications changed since a sync cursor, for ListNotifications. A page as long as
+// requested may be followed by more changes
+func (server *Server) listNotificationChanges(w http.ResponseWriter, r *http.Request, accountID uuid.UUID,
+	since string, size int) {
+	syncCursor, err := decodeCursor(since)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid sync cursor")
+		return
+	}
+
+	changes, err := server.query.ListNotificationChanges(r.Context(), db.ListNotificationChangesParams{
+		RecipientID:    accountID,
+		SinceUpdatedAt: syncCursor.CreatedAt,
+		SinceID:        syncCursor.ID,
+		PageSize:       int32(size),
+	})
+	if err != nil {
+		server.logger.Error("GET /notifications: failed to list notification changes", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Return the result back to client, the next sync starts after the last change
+	data := make([]notificationResponse, len(changes))
+	for i, change := range changes {
+		data[i] = newNotificationResponse(db.ListNotificationsRow{
+			NotificationID: change.NotificationID,
+			Type:           change.Type,
+			ActorID:        change.ActorID,
+			ActorUsername:  change.ActorUsername,
+			VideoID:        change.VideoID,
+			VideoTitle:     change.VideoTitle,
+			CreatedAt:      change.CreatedAt,
+			ReadAt:         change.ReadAt,
+		})
+	}
+	if len(changes) > 0 {
+		last := changes[len(changes)-1]
+		since = encodeCursor(last.UpdatedAt, last.NotificationID)
+	}
+	w.Header().Set("X-Sync-Cursor", since)
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// HandleGetUnreadCounts returns the number of unread notifications of the requester, in total and by type.
+// endpoint: GET /notifications/unread
+// Success: 200
+// Fail: 401, 500
+func (server *Server) HandleGetUnreadCounts(w http.ResponseWriter, r *http.Request) {
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+
+	counts, err := server.unreadCounts(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("GET /notifications/unread: failed to count unread notifications", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, counts)
+}
+
+// HandleMarkNotificationsRead marks notifications of the requester as read: the listed ones, or all of them (of a
+// type) without IDs. The other clients of the account get the read event on their notification stream.
+// endpoint: POST /notifications/read
+// Success: 200
+// Fail: 400, 401, 500
+func (server *Server) HandleMarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+
+	// Get request body
+	var req markNotificationsReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Mark notifications as read
+	params := db.MarkNotificationsReadParams{RecipientID: accountID}
+	if len(req.IDs) > 0 {
+		params.Ids = req.IDs
+	}
+	if req.Type != "" {
+		params.Type = db.NullNotificationType{NotificationType: db.NotificationType(req.Type), Valid: true}
+	}
+	marked, err := server.query.MarkNotificationsRead(r.Context(), params)
+	if err != nil {
+		server.logger.Error("POST /notifications/read: failed to mark notifications as read", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.writeNotificationsRead(w, r, "POST /notifications/read", accountID, marked)
+}
+
+// HandleMarkNotificationRead marks a notification of the requester as read, like MarkNotificationsRead.
+// endpoint: POST /notifications/{id}/read
+// Success: 200
+// Fail: 400, 401, 404, 500
+func (server *Server) HandleMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	var accountID, notificationID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	if err := notificationID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid notification ID")
+		return
+	}
+
+	// Mark notification as read, a notification already read is left as is
+	marked, err := server.query.MarkNotificationsRead(r.Context(), db.MarkNotificationsReadParams{
+		RecipientID: accountID,
+		Ids:         []uuid.UUID{notificationID},
+	})
+	if err != nil {
+		server.logger.Error("POST /notifications/{id}/read: failed to mark notification as read", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.writeNotificationsRead(w, r, "POST /notifications/{id}/read", accountID, marked)
+}
+
+// Helper method: send the read event to the notification streams of the account and write the response of the
+// notifications marked as read
+func (server *Server) writeNotificationsRead(w http.ResponseWriter, r *http.Request, endpoint string,
+	accountID uuid.UUID, marked []uuid.UUID) {
+	counts, err := server.unreadCounts(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error(endpoint+": failed to count unread notifications", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	result := notificationsReadResponse{IDs: marked, Unread: counts}
+	if len(marked) > 0 {
+		server.hub.publish(accountID, hubEvent{Name: "read", Data: result})
+	}
+	server.WriteJSON(w, http.StatusOK, result)
+}
+
+// HandleStreamNotifications streams the changes of the notifications of the requester with Server-Sent Events:
+// 'notification' for a new notification, and 'read' when notifications are marked as read (by any client of the
+// account). A client reconnecting syncs the changes it missed with the since cursor of ListNotifications.
+// endpoint: GET /notifications/stream
+// Success: 200 (event stream)
+// Fail: 401, 500
+func (server *Server) HandleStreamNotifications(w http.ResponseWriter, r *http.Request) {
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+
+	// The writer is wrapped by the middlewares, the controller finds the flusher of the underlying writer
+	rc := http.NewResponseController(w)
+
+	stream := server.hub.subscribe(accountID)
+	defer server.hub.unsubscribe(accountID, stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		server.logger.Error("GET /notifications/stream: streaming is not supported", "error", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			rc.Flush()
+		case event := <-stream:
+			payload, err := json.Marshal(event.Data)
+			if err != nil {
+				server.logger.Error("GET /notifications/stream: failed to marshal event", "error", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, payload)
+			rc.Flush()
+		}
+	}
+}
+
+// Helper method: count the unread notifications of an account, by type
+func (server *Server) unreadCounts(ctx context.Context, accountID uuid.UUID) (unreadCountsResponse, error) {
+	rows, err := server.query.CountUnreadNotificationsByType(ctx, accountID)
+	if err != nil {
+		return unreadCountsResponse{}, err
+	}
+
+	counts := unreadCountsResponse{ByType: map[string]int64{
+		string(db.NotificationTypeNewVideo):      0,
+		string(db.NotificationTypeNewSubscriber): 0,
+		string(db.NotificationTypeVideoReady):    0,
+		string(db.NotificationTypeVideoFailed):   0,
+	}}
+	for _, row := range rows {
+		counts.ByType[string(row.Type)] = row.Count
+		counts.Total += row.Count
+	}
+	return counts, nil
+}
+
+// Helper method: get the sync cursor of the notifications of an account, the position of its last change
+func (server *Server) notificationSyncCursor(ctx context.Context, accountID uuid.UUID) (string, error) {
+	last, err := server.query.GetNotificationSyncPoint(ctx, accountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return encodeCursor(time.Unix(0, 0), uuid.Nil), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return encodeCursor(last.UpdatedAt, last.NotificationID), nil
+}
+
+// Method to notify an account of an event: the notification is stored and sent to the notification streams of the
+// account, then pushed to the browsers of the account in background when Web Push is enabled. Failure is only
+// logged, the event itself is already done
+func (server *Server) notify(ctx context.Context, arg db.CreateNotificationParams) {
+	notification, err := server.query.CreateNotification(ctx, arg)
+	if err != nil {
+		server.logger.Error("notification: failed to create notification", "account_id", arg.RecipientID,
+			"type", arg.Type, "error", err)
+		return
+	}
+
+	server.hub.publish(arg.RecipientID, hubEvent{Name: "notification", Data: newNotificationEvent(notification)})
+	server.enqueuePush(ctx, pushPayload{
+		RecipientID: uuid.NullUUID{UUID: arg.RecipientID, Valid: true},
+		Type:        arg.Type,
+		ActorID:     arg.ActorID,
+		VideoID:     arg.VideoID,
+		CreatedAt:   notification.CreatedAt,
+	})
+}
+
+// Method to notify all the subscribers of a channel of an event of the channel, like notify
+func (server *Server) notifySubscribers(ctx context.Context, arg db.CreateSubscriberNotificationsParams) {
+	created, err := server.query.CreateSubscriberNotifications(ctx, arg)
+	if err != nil {
+		server.logger.Error("notification: failed to create subscriber notifications", "account_id", arg.ActorID,
+			"type", arg.Type, "error", err)
+		return
+	}
+	if len(created) == 0 {
+		return
+	}
+
+	for _, notification := range created {
+		server.hub.publish(notification.RecipientID, hubEvent{Name: "notification", Data: newNotificationEvent(notification)})
+	}
+
+	server.enqueuePush(ctx, pushPayload{
+		Type:      arg.Type,
+		ActorID:   uuid.NullUUID{UUID: arg.ActorID, Valid: true},
+		VideoID:   arg.VideoID,
+		CreatedAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Default and max number of items of a page
+const (
+	defaultPageSize = 20
+	maxPageSize     = 50
+)
+
+// Cursor of a page of a list sorted by time (creation, deletion, ...), newest first. It's the position of the last
+// item of the previous page: the next page starts right after it, so the items inserted meanwhile don't shift the
+// pages like an offset does. Clients get it as an opaque token
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Helper function: encode the position of an item into a cursor token. The database stores microseconds, so the
+// time is encoded with this precision
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	buf := binary.BigEndian.AppendUint64(make([]byte, 0, 24), uint64(createdAt.UnixMicro()))
+	buf = append(buf, id[:]...)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// Helper function: decode a cursor token
+func decodeCursor(token string) (*pageCursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(buf) != 24 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	cursor := &pageCursor{CreatedAt: time.UnixMicro(int64(binary.BigEndian.Uint64(buf[:8])))}
+	copy(cursor.ID[:], buf[8:])
+	return cursor, nil
+}
+
+// Method to get the query params of the cursor, both are null for the first page
+func (cursor *pageCursor) params() (sql.NullTime, uuid.NullUUID) {
+	if cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: cursor.ID, Valid: true}
+}
+
+// Helper method: get the cursor and the size of the requested page from the query (cursor=...&size=...), the first
+// page is requested without cursor. If they are invalid, 400 is written and false is returned
+func (server *Server) parsePage(w http.ResponseWriter, r *http.Request) (*pageCursor, int, bool) {
+	query := r.URL.Query()
+
+	var cursor *pageCursor
+	if token := query.Get("cursor"); token != "" {
+		var err error
+		if cursor, err = decodeCursor(token); err != nil {
+			server.WriteError(w, http.StatusBadRequest, "Invalid cursor")
+			return nil, 0, false
+		}
+	}
+
+	size := defaultPageSize
+	if value := query.Get("size"); value != "" {
+		var err error
+		if size, err = strconv.Atoi(value); err != nil || size < 1 || size > maxPageSize {
+			server.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Page size must be between 1 and %d", maxPageSize))
+			return nil, 0, false
+		}
+	}
+	return cursor, size, true
+}
+
+// Helper method: get the page number (starting at 1) of a list paged by offset, for the lists which can't be paged
+// with a cursor, like the search results sorted by relevance
+func (server *Server) parsePageNumber(w http.ResponseWriter, r *http.Request) (int, bool) {
+	page := 1
+	if value := r.URL.Query().Get("page"); value != "" {
+		var err error
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			server.WriteError(w, http.StatusBadRequest, "Invalid page")
+			return 0, false
+		}
+	}
+	return page, true
+}
+
+// Helper function: return the cursor of the next page in the X-Next-Cursor header. A page shorter than requested is
+// the last one, so there is no next page
+func setNextCursor(w http.ResponseWriter, count, size int, createdAt time.Time, id uuid.UUID) {
+	if count < size {
+		return
+	}
+	w.Header().Set("X-Next-Cursor", encodeCursor(createdAt, id))
+}
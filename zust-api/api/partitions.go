@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// The partitions of the view and playback events are checked daily, and created this many months ahead of the current month so
+// an insert never misses its partition, even if the job doesn't run for a while
+const (
+	partitionCheckInterval = 24 * time.Hour
+	partitionsAhead        = 3
+)
+
+// Method to maintain the monthly partitions of the view and playback events: create the partitions of the current
+// and the next months, then drop the partitions older than the retention (EVENT_RETENTION). The views of the dropped
+// partitions are kept in the view counters of the videos, only the analytics of those months are lost
+func (server *Server) runPartitionJob(ctx context.Context) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i <= partitionsAhead; i++ {
+		if err := server.query.CreateViewEventPartition(ctx, start.AddDate(0, i, 0)); err != nil {
+			server.logger.Error("partitions: failed to create view event partition", "error", err)
+			return
+		}
+		if err := server.query.CreatePlaybackEventPartition(ctx, start.AddDate(0, i, 0)); err != nil {
+			server.logger.Error("partitions: failed to create playback event partition", "error", err)
+			return
+		}
+	}
+
+	if server.config.EventRetention == 0 {
+		return
+	}
+
+	before := start.AddDate(0, -server.config.EventRetention, 0)
+	dropped, err := server.query.DropViewEventPartitions(ctx, before)
+	if err != nil {
+		server.logger.Error("partitions: failed to drop expired view event partitions", "error", err)
+		return
+	}
+	if len(dropped) > 0 {
+		server.logger.Info("partitions: expired view event partitions dropped", "partitions", dropped)
+	}
+
+	dropped, err = server.query.DropPlaybackEventPartitions(ctx, before)
+	if err != nil {
+		server.logger.Error("partitions: failed to drop expired playback event partitions", "error", err)
+		return
+	}
+	if len(dropped) > 0 {
+		server.logger.Info("partitions: expired playback event partitions dropped", "partitions", dropped)
+	}
+}
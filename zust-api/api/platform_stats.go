@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/job"
+)
+
+// Interval of the measure of the space used by the stored files, the measure walks all the files of the storages
+const storageUsageInterval = 6 * time.Hour
+
+// Number of something in a day (in UTC), for example the signups
+type dailyCount struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+// Daily numbers of the platform, and their total over the range
+type countStats struct {
+	Total int64        `json:"total"`
+	Daily []dailyCount `json:"daily"`
+}
+
+// Transcode jobs finished in a day (in UTC)
+type dailyTranscodes struct {
+	Day         string  `json:"day"`
+	Completed   int64   `json:"completed"`
+	Failed      int64   `json:"failed"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+// Current transcode queue, and the transcode jobs finished over the range, read from the job queue. With the DB
+// queue a job only fails once it's out of attempts, with asynq each failed attempt is counted (see job.Stats)
+type transcodeStats struct {
+	Pending          int64             `json:"pending"`
+	Running          int64             `json:"running"`
+	OldestPendingAge float64           `json:"oldest_pending_age"` // seconds the oldest due job has been waiting
+	Completed        int64             `json:"completed"`
+	Failed           int64             `json:"failed"`
+	FailureRate      float64           `json:"failure_rate"`
+	Daily            []dailyTranscodes `json:"daily"`
+}
+
+// Space used by the stored files at the end of a day (in UTC), and its growth from the day before
+type dailyStorage struct {
+	Day         string `json:"day"`
+	UsedBytes   int64  `json:"used_bytes"`
+	ColdBytes   int64  `json:"cold_bytes"`
+	Files       int64  `json:"files"`
+	GrowthBytes int64  `json:"growth_bytes"`
+}
+
+// Space used by the stored files at the last measure, and its growth over the range
+type storageStats struct {
+	UsedBytes   int64          `json:"used_bytes"`
+	ColdBytes   int64          `json:"cold_bytes"`
+	Files       int64          `json:"files"`
+	GrowthBytes int64          `json:"growth_bytes"`
+	MeasuredAt  *time.Time     `json:"measured_at"` // null until the storage is measured
+	Daily       []dailyStorage `json:"daily"`
+}
+
+// Response body for GetPlatformStats
+type platformStatsResponse struct {
+	Range       string         `json:"range"`
+	Since       time.Time      `json:"since"`
+	Signups     countStats     `json:"signups"`
+	Uploads     countStats     `json:"uploads"`
+	Transcode   transcodeStats `json:"transcode"`
+	Storage     storageStats   `json:"storage"`
+	ActiveUsers countStats     `json:"active_users"`
+}
+
+// HandleGetPlatformStats returns the statistics of the platform over a time range (default to 28 days) for the ops
+// dashboard, only available to admin: signups and uploads per day, transcode queue depth and failure rate, growth
+// of the storage, and active users. An active user viewed a video in the day, the total also counts the users who
+// used their session in the range. The days without any activity are not listed.
+// endpoint: GET /admin/stats?range=7d|28d|90d|365d
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleGetPlatformStats(w http.ResponseWriter, r *http.Request) {
+	// Get time range
+	rangeParam := r.URL.Query().Get("range")
+	if rangeParam == "" {
+		rangeParam = "28d"
+	}
+	duration, ok := statsRanges[rangeParam]
+	if !ok {
+		server.WriteError(w, http.StatusBadRequest, "Unsupported range, only accept 7d, 28d, 90d or 365d")
+		return
+	}
+	since := time.Now().Add(-duration)
+	data := platformStatsResponse{Range: rangeParam, Since: since}
+
+	signups, err := server.query.ListDailySignups(r.Context(), since)
+	if err != nil {
+		server.logger.Error("GET /admin/stats: failed to get daily signups", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	data.Signups.Daily = make([]dailyCount, 0, len(signups))
+	for _, day := range signups {
+		data.Signups.Total += day.Signups
+		data.Signups.Daily = append(data.Signups.Daily, dailyCount{Day: day.Day.Format(time.DateOnly), Count: day.Signups})
+	}
+
+	uploads, err := server.query.ListDailyUploads(r.Context(), since)
+	if err != nil {
+		server.logger.Error("GET /admin/stats: failed to get daily uploads", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	data.Uploads.Daily = make([]dailyCount, 0, len(uploads))
+	for _, day := range uploads {
+		data.Uploads.Total += day.Uploads
+		data.Uploads.Daily = append(data.Uploads.Daily, dailyCount{Day: day.Day.Format(time.DateOnly), Count: day.Uploads})
+	}
+
+	if data.Transcode, err = server.transcodeStats(r.Context(), since); err != nil {
+		server.logger.Error("GET /admin/stats: failed to get transcode statistics", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if data.Storage, err = server.storageStats(r.Context(), since); err != nil {
+		server.logger.Error("GET /admin/stats: failed to get storage usage", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	activeUsers, err := server.query.ListDailyActiveUsers(r.Context(), since)
+	if err != nil {
+		server.logger.Error("GET /admin/stats: failed to get daily active users", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	data.ActiveUsers.Daily = make([]dailyCount, 0, len(activeUsers))
+	for _, day := range activeUsers {
+		data.ActiveUsers.Daily = append(data.ActiveUsers.Daily, dailyCount{
+			Day:   day.Day.Format(time.DateOnly),
+			Count: day.ActiveUsers,
+		})
+	}
+	if data.ActiveUsers.Total, err = server.query.CountActiveUsers(r.Context(), since); err != nil {
+		server.logger.Error("GET /admin/stats: failed to count active users", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Method to get the current transcode queue, and the transcode jobs finished since a time
+func (server *Server) transcodeStats(ctx context.Context, since time.Time) (transcodeStats, error) {
+	var stats transcodeStats
+	queue, err := server.jobs.Stats(ctx, job.TypeTranscode, since)
+	if err != nil {
+		return stats, err
+	}
+	stats.Pending, stats.Running = queue.Pending, queue.Running
+	stats.OldestPendingAge = queue.OldestPendingAge.Seconds()
+
+	stats.Daily = make([]dailyTranscodes, 0, len(queue.Daily))
+	for _, day := range queue.Daily {
+		stats.Completed += day.Completed
+		stats.Failed += day.Failed
+		stats.Daily = append(stats.Daily, dailyTranscodes{
+			Day:         day.Day.Format(time.DateOnly),
+			Completed:   day.Completed,
+			Failed:      day.Failed,
+			FailureRate: failureRate(day.Completed, day.Failed),
+		})
+	}
+	stats.FailureRate = failureRate(stats.Completed, stats.Failed)
+	return stats, nil
+}
+
+// Method to get the space used by the stored files at the end of each day since a time. The growth of the first day
+// is computed from the measure of the day before, if any
+func (server *Server) storageStats(ctx context.Context, since time.Time) (storageStats, error) {
+	var stats storageStats
+	usages, err := server.query.ListStorageUsage(ctx, since.AddDate(0, 0, -1))
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Daily = make([]dailyStorage, 0, len(usages))
+	var previous *db.StorageUsage
+	for i, usage := range usages {
+		day := dailyStorage{
+			Day:       usage.Day.Format(time.DateOnly),
+			UsedBytes: usage.UsedBytes,
+			ColdBytes: usage.ColdBytes,
+			Files:     usage.Files,
+		}
+		if previous != nil {
+			day.GrowthBytes = usage.UsedBytes + usage.ColdBytes - previous.UsedBytes - previous.ColdBytes
+		}
+		previous = &usages[i]
+
+		// The measure of the day before the range is only the baseline of the growth
+		if usage.Day.Before(since.UTC().Truncate(24 * time.Hour)) {
+			continue
+		}
+		stats.GrowthBytes += day.GrowthBytes
+		stats.Daily = append(stats.Daily, day)
+	}
+
+	if previous != nil {
+		stats.UsedBytes, stats.ColdBytes, stats.Files = previous.UsedBytes, previous.ColdBytes, previous.Files
+		stats.MeasuredAt = &previous.MeasuredAt
+	}
+	return stats, nil
+}
+
+// Helper function: get the ratio of the failed jobs over the finished ones, 0 if none is finished
+func failureRate(completed, failed int64) float64 {
+	if completed+failed == 0 {
+		return 0
+	}
+	return float64(failed) / float64(completed+failed)
+}
+
+// Method to measure the space used by the files of the storages, recorded as the usage of the current day
+func (server *Server) runStorageUsageJob(ctx context.Context) {
+	files, err := server.storage.List("")
+	if err != nil {
+		server.logger.Error("storage_usage: failed to list files", "error", err)
+		return
+	}
+	params := db.RecordStorageUsageParams{MeasuredAt: time.Now(), Files: int64(len(files))}
+	for _, info := range files {
+		params.UsedBytes += info.Size
+	}
+
+	if server.coldStorage != nil {
+		coldFiles, err := server.coldStorage.List("")
+		if err != nil {
+			server.logger.Error("storage_usage: failed to list cold files", "error", err)
+			return
+		}
+		params.Files += int64(len(coldFiles))
+		for _, info := range coldFiles {
+			params.ColdBytes += info.Size
+		}
+	}
+
+	if err := server.query.RecordStorageUsage(ctx, params); err != nil {
+		server.logger.Error("storage_usage: failed to record storage usage", "error", err)
+		return
+	}
+	server.logger.Info("storage_usage: storage measured", "used_bytes", params.UsedBytes, "cold_bytes",
+		params.ColdBytes, "files", params.Files)
+}
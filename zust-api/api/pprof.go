@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// Helper method: mount the pprof endpoints under /debug/pprof/, to profile the memory and CPU of the server during
+// large uploads and transcodes. They are only reachable by the admins, or from the loopback interface, as set by
+// PPROF_MODE
+func (server *Server) registerPprof() {
+	var guard func(http.Handler) http.Handler
+	switch server.config.PprofMode {
+	case "admin":
+		guard = func(next http.Handler) http.Handler { return server.AuthMiddleware(server.AdminMiddleware(next)) }
+	case "local":
+		guard = server.LocalMiddleware
+	default:
+		return
+	}
+
+	server.mux.Handle("GET /debug/pprof/", guard(http.HandlerFunc(pprof.Index)))
+	server.mux.Handle("GET /debug/pprof/cmdline", guard(http.HandlerFunc(pprof.Cmdline)))
+	server.mux.Handle("GET /debug/pprof/profile", guard(http.HandlerFunc(pprof.Profile)))
+	server.mux.Handle("GET /debug/pprof/symbol", guard(http.HandlerFunc(pprof.Symbol)))
+	server.mux.Handle("POST /debug/pprof/symbol", guard(http.HandlerFunc(pprof.Symbol)))
+	server.mux.Handle("GET /debug/pprof/trace", guard(http.HandlerFunc(pprof.Trace)))
+}
+
+// LocalMiddleware is a middleware that only allows requests from the loopback interface. The address of the
+// connection is used, forwarded headers are not trusted
+func (server *Server) LocalMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if ip := net.ParseIP(host); err != nil || ip == nil || !ip.IsLoopback() {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/file"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Maximum time a blocking playlist reload waits, 3 times the target duration as recommended by LL-HLS
+	premiereBlockTimeout = 12 * time.Second
+	// Maximum time a request for a part advertised by the preload hint waits for the part to be written
+	premierePartTimeout = 3 * time.Second
+	// Time a finished premiere is kept, so late viewers can still watch it
+	premiereRetention = time.Hour
+)
+
+// A premiere being streamed (or recently finished) in LL-HLS
+type premiere struct {
+	playlist    *file.LLHLSPlaylist
+	dir         string
+	publisherID uuid.UUID
+}
+
+// In-memory registry of the premieres, packaging runs on this instance so it is not shared
+type premiereTracker struct {
+	mu        sync.RWMutex
+	premieres map[uuid.UUID]*premiere
+}
+
+// Constructor method for premiere tracker
+func newPremiereTracker() *premiereTracker {
+	return &premiereTracker{premieres: make(map[uuid.UUID]*premiere)}
+}
+
+// Method to get the premiere of a video
+func (tracker *premiereTracker) get(videoID uuid.UUID) (*premiere, bool) {
+	tracker.mu.RLock()
+	defer tracker.mu.RUnlock()
+	p, ok := tracker.premieres[videoID]
+	return p, ok
+}
+
+// Method to register a premiere, return false if the video already has one
+func (tracker *premiereTracker) add(videoID uuid.UUID, p *premiere) bool {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if _, ok := tracker.premieres[videoID]; ok {
+		return false
+	}
+	tracker.premieres[videoID] = p
+	return true
+}
+
+// Method to mark a premiere as finished, it is removed with its files after a while
+func (tracker *premiereTracker) finish(videoID uuid.UUID) {
+	time.AfterFunc(premiereRetention, func() {
+		tracker.mu.Lock()
+		p, ok := tracker.premieres[videoID]
+		delete(tracker.premieres, videoID)
+		tracker.mu.Unlock()
+
+		if ok {
+			os.RemoveAll(p.dir)
+		}
+	})
+}
+
+// Response body for StartPremiere
+type premiereResponse struct {
+	VideoID  string `json:"video_id"`
+	Playlist string `json:"playlist"` // LL-HLS media playlist
+}
+
+// HandleStartPremiere starts streaming a published video in low-latency HLS, so viewers watch it together with a
+// few seconds of latency. Only the publisher can start a premiere, and only of a public video.
+// endpoint: POST /videos/{id}/premiere
+// Success: 202
+// Fail: 400, 403, 404, 409, 500
+func (server *Server) HandleStartPremiere(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Get video to check if the requester is the publisher
+	video, err := server.getVideo(r.Context(), videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+			return
+		}
+
+		server.logger.Error("POST /videos/{id}/premiere: failed to get video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	claims := r.Context().Value(clKey).(*security.CustomClaims)
+	if claims.ID != video.AccountID.String() {
+		server.WriteError(w, http.StatusForbidden, "Only the publisher can start a premiere of this video")
+		return
+	}
+
+	if video.Status != db.VideoStatusPublished {
+		server.WriteError(w, http.StatusConflict, "Only published video can be premiered")
+		return
+	}
+
+	// The live files are served to everyone watching the premiere
+	if video.Visibility != db.VideoVisibilityPublic {
+		server.WriteError(w, http.StatusConflict, "Only public video can be premiered")
+		return
+	}
+
+	// Stream the best rendition, which is already scaled down
+	accountID := video.AccountID.String()
+	filename := file.BestRendition(server.storage, accountID, videoID.String())
+	if filename == "" {
+		server.WriteError(w, http.StatusConflict, "Video has no rendition to premiere")
+		return
+	}
+
+	p := &premiere{
+		playlist:    file.NewLLHLSPlaylist(),
+		dir:         server.localPath(file.LiveKey(accountID, videoID.String())),
+		publisherID: video.AccountID,
+	}
+	if !server.premieres.add(videoID, p) {
+		server.WriteError(w, http.StatusConflict, "Video is already premiered")
+		return
+	}
+
+	// Package in background, in real time
+	input := filepath.Join(filepath.Dir(server.localPath(file.OriginalKey(accountID, videoID.String()))), filename)
+	go func() {
+		defer server.premieres.finish(videoID)
+		if err := os.RemoveAll(p.dir); err != nil {
+			server.logger.Error("POST /videos/{id}/premiere: failed to clean live directory", "error", err)
+		}
+		if err := server.mediaService.PackageLLHLS(context.Background(), input, p.dir, p.playlist); err != nil {
+			server.logger.Error("POST /videos/{id}/premiere: failed to package LL-HLS", "error", err)
+		}
+	}()
+
+	server.WriteJSON(w, http.StatusAccepted, premiereResponse{
+		VideoID:  videoID.String(),
+		Playlist: server.mediaService.GenerateLiveLink(videoID.String()),
+	})
+}
+
+// HandleLiveFile serves the LL-HLS playlist, parts and segments of a premiere. The playlist supports blocking
+// reload with the _HLS_msn and _HLS_part query parameters, the part advertised by the preload hint is held
+// until it is written. The files are checked like the media files of the video, so a premiere stops being served
+// when the video is made private, held or removed.
+// endpoint: GET /live/{id}/{file}
+// Success: 200
+// Fail: 400, 401, 403, 404, 500, 503
+func (server *Server) HandleLiveFile(w http.ResponseWriter, r *http.Request) {
+	if !server.checkOriginAuth(w, r) {
+		return
+	}
+
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	p, ok := server.premieres.get(videoID)
+	if !ok {
+		server.WriteError(w, http.StatusNotFound, "Video is not premiered")
+		return
+	}
+
+	name := r.PathValue("file")
+	key := path.Join(file.LiveKey(p.publisherID.String(), videoID.String()), path.Clean("/"+name))
+	if !server.checkMediaAccess(w, r, key) {
+		return
+	}
+
+	if name == file.HLSPlaylist {
+		server.serveLivePlaylist(w, r, p.playlist)
+		return
+	}
+
+	// Clean the name as an absolute path first, so it can never escape the live directory
+	filename := filepath.Join(p.dir, filepath.Clean("/"+name))
+	deadline := time.Now().Add(premierePartTimeout)
+	for {
+		if _, err := os.Stat(filename); err == nil || time.Now().After(deadline) {
+			break
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if contentType, ok := streamingContentTypes[filepath.Ext(filename)]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
+	http.ServeFile(w, r, filename)
+}
+
+// Helper method: serve the LL-HLS playlist, blocking until the requested media sequence number (and part) exists
+func (server *Server) serveLivePlaylist(w http.ResponseWriter, r *http.Request, playlist *file.LLHLSPlaylist) {
+	query := r.URL.Query()
+	if value := query.Get("_HLS_msn"); value != "" {
+		msn, err := strconv.Atoi(value)
+		if err != nil || msn < 0 {
+			server.WriteError(w, http.StatusBadRequest, "Invalid _HLS_msn")
+			return
+		}
+
+		part := -1
+		if value := query.Get("_HLS_part"); value != "" {
+			if part, err = strconv.Atoi(value); err != nil || part < 0 {
+				server.WriteError(w, http.StatusBadRequest, "Invalid _HLS_part")
+				return
+			}
+		}
+
+		// A request too far in the future will never be satisfied in time
+		if msn > playlist.NextSequence()+2 {
+			server.WriteError(w, http.StatusBadRequest, "_HLS_msn is too far in the future")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), premiereBlockTimeout)
+		defer cancel()
+		if err := playlist.Wait(ctx, msn, part); err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			server.WriteError(w, http.StatusServiceUnavailable, "Playlist is not updated in time")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", streamingContentTypes[".m3u8"])
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(playlist.Render()))
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"zust/service/file"
+)
+
+// Requests of a file tracked since the last pre-warm
+type rangeHits struct {
+	count int
+	end   int64 // end of the furthest range requested at the start of the file
+}
+
+// Tracker of the byte ranges requested for the renditions, used to pick the files to pre-warm
+type rangeTracker struct {
+	mu   sync.Mutex
+	hits map[string]*rangeHits
+}
+
+// Constructor method for range tracker
+func newRangeTracker() *rangeTracker {
+	return &rangeTracker{hits: make(map[string]*rangeHits)}
+}
+
+// Method to record a request of the bytes before 'end' of a file
+func (tracker *rangeTracker) record(key string, end int64) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	hits, ok := tracker.hits[key]
+	if !ok {
+		hits = &rangeHits{}
+		tracker.hits[key] = hits
+	}
+	hits.count++
+	hits.end = max(hits.end, end)
+}
+
+// Method to get the requests tracked so far and start tracking again from scratch
+func (tracker *rangeTracker) reset() map[string]*rangeHits {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	hits := tracker.hits
+	tracker.hits = make(map[string]*rangeHits)
+	return hits
+}
+
+// Helper method: record the range requested for a stored file. Only the renditions and streaming segments are
+// tracked, they are never rewritten so a copy of their first bytes stays valid. Ranges starting past the
+// pre-warm max size can't be served from the cache, so they are ignored
+func (server *Server) trackRange(r *http.Request, key string) {
+	if server.warmCache == nil {
+		return
+	}
+	if ext := path.Ext(key); ext != ".mp4" && ext != ".m4s" {
+		return
+	}
+	if _, _, ok := file.ParseVideoKey(key); !ok {
+		return
+	}
+
+	start, end, ok := parseRange(r.Header.Get("Range"))
+	if !ok || start >= server.config.PrewarmMaxSize {
+		return
+	}
+	if end < 0 || end > server.config.PrewarmMaxSize {
+		end = server.config.PrewarmMaxSize
+	}
+	server.ranges.record(key, end)
+}
+
+// Helper function: parse the first range of a Range header, 'end' is exclusive and -1 if the range is open.
+// A request without Range reads the whole file. Suffix ranges (bytes=-N) read the end of the file, so they are
+// reported as not ok
+func parseRange(header string) (start, end int64, ok bool) {
+	if header == "" {
+		return 0, -1, true
+	}
+
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found {
+		return 0, 0, false
+	}
+	spec, _, _ = strings.Cut(spec, ",")
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found || first == "" {
+		return 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	if last == "" {
+		return start, -1, true
+	}
+	end, err = strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, false
+	}
+	return start, end + 1, true
+}
+
+// Helper method: open a stored file, through the warm cache when its first bytes are pre-warmed
+func (server *Server) openStoredFile(key string) (io.ReadSeekCloser, *file.FileInfo, error) {
+	if server.warmCache != nil {
+		if reader, info, err := server.warmCache.Open(server.storage, key); err == nil {
+			return reader, info, nil
+		}
+	}
+	return server.storage.Get(key)
+}
+
+// Method to pre-warm the hot files: the files requested at least PREWARM_MIN_HITS times since the last run are
+// copied into the warm cache up to the end of their furthest range, the files no longer hot are removed from it
+func (server *Server) runPrewarmJob(ctx context.Context) {
+	keep := make(map[string]bool)
+	for key, hits := range server.ranges.reset() {
+		if hits.count < server.config.PrewarmMinHits {
+			continue
+		}
+		if err := server.warmCache.Warm(server.storage, key, hits.end); err != nil {
+			server.logger.Error("prewarm: failed to pre-warm file", "key", key, "error", err)
+			continue
+		}
+		keep[key] = true
+	}
+
+	removed, err := server.warmCache.Prune(keep)
+	if err != nil {
+		server.logger.Error("prewarm: failed to prune warm cache", "error", err)
+	}
+	server.logger.Info("prewarm: hot files processed", "warm", len(keep), "removed", removed)
+}
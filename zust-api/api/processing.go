@@ -0,0 +1,304 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Processing status of a single rendition
+type renditionStatus struct {
+	Resolution string `json:"resolution"`
+	Status     string `json:"status"`
+	Progress   int    `json:"progress"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Response body for GetProcessingStatus
+type processingResponse struct {
+	VideoID    string            `json:"video_id"`
+	Status     string            `json:"status"`
+	Renditions []renditionStatus `json:"renditions"`
+}
+
+// HandleGetProcessingStatus returns the processing status and progress of each rendition of a video,
+// which is only available to its publisher.
+// endpoint: GET /videos/{id}/processing
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleGetProcessingStatus(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Get video to check if the requester is the publisher
+	video, err := server.getLatestVideo(r.Context(), videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+			return
+		}
+
+		server.logger.Error("GET /videos/{id}/processing: failed to get video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	claims := r.Context().Value(clKey).(*security.CustomClaims)
+	if claims.ID != video.AccountID.String() {
+		server.WriteError(w, http.StatusForbidden, "Only the publisher can view the processing status of this video")
+		return
+	}
+
+	// Get the status of each rendition
+	data, err := server.buildProcessingResponse(r.Context(), videoID, video.Status)
+	if err != nil {
+		server.logger.Error("GET /videos/{id}/processing: failed to list renditions", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Interval to check the processing status for the stream, and to send keep-alive comments
+const (
+	streamPollInterval      = time.Second
+	streamKeepAliveInterval = 15 * time.Second
+)
+
+// HandleStreamProcessingStatus streams the processing status of a video as Server-Sent Events, which is only
+// available to its publisher. A 'progress' event is sent each time the status changes, then a 'done' event
+// once the processing is finished and the stream is closed.
+// endpoint: GET /videos/{id}/processing/stream
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleStreamProcessingStatus(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Get video to check if the requester is the publisher
+	video, err := server.getLatestVideo(r.Context(), videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+			return
+		}
+
+		server.logger.Error("GET /videos/{id}/processing/stream: failed to get video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	claims := r.Context().Value(clKey).(*security.CustomClaims)
+	if claims.ID != video.AccountID.String() {
+		server.WriteError(w, http.StatusForbidden, "Only the publisher can view the processing status of this video")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		server.WriteError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	poll := time.NewTicker(streamPollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	var last []byte
+	for {
+		// Send the status if it changed
+		status := video.Status
+		if current, err := server.query.GetVideo(r.Context(), videoID); err == nil {
+			status = current.Status
+		}
+		data, err := server.buildProcessingResponse(r.Context(), videoID, status)
+		if err != nil {
+			server.logger.Error("GET /videos/{id}/processing/stream: failed to list renditions", "error", err)
+			return
+		}
+
+		payload, _ := json.Marshal(data)
+		if !bytes.Equal(payload, last) {
+			fmt.Fprintf(w, "event: progress\ndata: %s\n\n", payload)
+			flusher.Flush()
+			last = payload
+		}
+
+		if data.finished() {
+			fmt.Fprintf(w, "event: done\ndata: %s\n\n", payload)
+			flusher.Flush()
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-poll.C:
+		}
+	}
+}
+
+// Method to check if the processing is finished: the video left the pending status, or all renditions are
+// completed or failed
+func (data *processingResponse) finished() bool {
+	if data.Status != string(db.VideoStatusPending) {
+		return true
+	}
+	if len(data.Renditions) == 0 {
+		return false
+	}
+	for _, rendition := range data.Renditions {
+		if rendition.Status == string(db.RenditionStatusQueued) || rendition.Status == string(db.RenditionStatusProcessing) {
+			return false
+		}
+	}
+	return true
+}
+
+// Helper method: build the processing status of a video from its renditions
+func (server *Server) buildProcessingResponse(ctx context.Context, videoID uuid.UUID,
+	status db.VideoStatus) (*processingResponse, error) {
+	renditions, err := server.query.ListRenditions(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &processingResponse{
+		VideoID:    videoID.String(),
+		Status:     string(status),
+		Renditions: make([]renditionStatus, 0, len(renditions)),
+	}
+	for _, rendition := range renditions {
+		data.Renditions = append(data.Renditions, renditionStatus{
+			Resolution: rendition.Resolution,
+			Status:     string(rendition.Status),
+			Progress:   int(rendition.Progress),
+			Error:      rendition.Error.String,
+		})
+	}
+	return data, nil
+}
+
+// Method to check if all renditions of a video are finished, and if so, call the publisher's processing webhook
+// with event 'video.processing_completed' (or 'video.processing_failed' if any rendition failed), send the event to
+// the publisher's webhooks, and notify the publisher. It returns whether the processing is finished
+func (server *Server) completeProcessing(ctx context.Context, videoID, publisherID uuid.UUID) bool {
+	renditions, err := server.query.ListRenditions(ctx, videoID)
+	if err != nil {
+		server.logger.Error("processing: failed to list renditions", "video_id", videoID, "error", err)
+		return false
+	}
+
+	event, notification := webhookProcessingCompleted, db.NotificationTypeVideoReady
+	for _, rendition := range renditions {
+		switch rendition.Status {
+		case db.RenditionStatusQueued, db.RenditionStatusProcessing:
+			return false
+		case db.RenditionStatusFailed:
+			event, notification = webhookProcessingFailed, db.NotificationTypeVideoFailed
+		}
+	}
+
+	server.callProcessingWebhook(ctx, videoID, publisherID, event)
+	server.emitWebhookEvent(ctx, publisherID, event, server.videoEventData(ctx, publisherID, videoID))
+	server.notify(ctx, db.CreateNotificationParams{
+		RecipientID: publisherID,
+		Type:        notification,
+		VideoID:     uuid.NullUUID{UUID: videoID, Valid: true},
+	})
+	return true
+}
+
+// Client of the processing webhooks. The URL is set by the publisher, so the client only connects to public
+// addresses and doesn't follow the redirects
+var processingWebhookClient = &http.Client{
+	Transport: &http.Transport{DialContext: (&net.Dialer{Control: security.PublicOnly}).DialContext},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Method to call the processing webhook of the publisher (if configured). Failure is only logged
+func (server *Server) callProcessingWebhook(ctx context.Context, videoID, publisherID uuid.UUID, event string) {
+	webhook, err := server.query.GetProcessingWebhook(ctx, publisherID)
+	if err != nil {
+		server.logger.Error("processing: failed to get processing webhook", "account_id", publisherID, "error", err)
+		return
+	}
+	if !webhook.Valid || webhook.String == "" {
+		return
+	}
+
+	// Build the payload with the final status of each rendition
+	video, err := server.query.GetVideo(ctx, videoID)
+	if err != nil {
+		server.logger.Error("processing: failed to get video", "video_id", videoID, "error", err)
+		return
+	}
+	data, err := server.buildProcessingResponse(ctx, videoID, video.Status)
+	if err != nil {
+		server.logger.Error("processing: failed to list renditions", "video_id", videoID, "error", err)
+		return
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"event":     event,
+		"timestamp": time.Now().UTC(),
+		"data":      data,
+	})
+	if err != nil {
+		server.logger.Error("processing: failed to marshal webhook payload", "error", err)
+		return
+	}
+
+	// Send the webhook request
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", webhook.String, bytes.NewReader(payload))
+	if err != nil {
+		server.logger.Error("processing: failed to create webhook request", "url", webhook.String, "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := processingWebhookClient.Do(req)
+	if err != nil {
+		server.logger.Error("processing: failed to call webhook", "url", webhook.String, "error", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		server.logger.Error("processing: webhook responded with error",
+			"url", webhook.String, "error", fmt.Sprintf("status code %d", resp.StatusCode))
+	}
+}
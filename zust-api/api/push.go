@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/job"
+	"zust/service/push"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Maximum number of browsers a push job sends to at once, and how long the push services keep a notification for
+// the browsers which are offline
+const (
+	pushConcurrency = 8
+	pushTTL         = 24 * time.Hour
+)
+
+// Request body for CreatePushSubscription, as given by PushSubscription.toJSON() of the browser
+type pushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" validate:"required,url,startswith=https://,max=2048"`
+	Keys     struct {
+		P256dh string `json:"p256dh" validate:"required,max=100"`
+		Auth   string `json:"auth" validate:"required,max=50"`
+	} `json:"keys"`
+}
+
+// Response body of a push subscription, the keys are not sent back
+type pushSubscriptionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Endpoint  string    `json:"endpoint"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Payload of the push notification job. It's sent to the browsers of the recipient, or to the browsers of the
+// subscribers of the actor when there is no recipient (the events of a channel)
+type pushPayload struct {
+	RecipientID uuid.NullUUID       `json:"recipient_id"`
+	Type        db.NotificationType `json:"type"`
+	ActorID     uuid.NullUUID       `json:"actor_id"`
+	VideoID     uuid.NullUUID       `json:"video_id"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// Message pushed to the browsers, shown by the service worker of the site
+type pushMessage struct {
+	Type          db.NotificationType `json:"type"`
+	ActorID       *uuid.UUID          `json:"actor_id,omitempty"`
+	ActorUsername string              `json:"actor_username,omitempty"`
+	VideoID       *uuid.UUID          `json:"video_id,omitempty"`
+	VideoTitle    string              `json:"video_title,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+}
+
+// HandleGetVAPIDPublicKey returns the public VAPID key of the server, the applicationServerKey the browsers
+// subscribe with.
+// endpoint: GET /push/vapid-public-key
+// Success: 200
+// Fail: 404
+func (server *Server) HandleGetVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	if server.push == nil {
+		server.WriteErrorCode(w, http.StatusNotFound, CodePushNotEnabled, "Web Push is not enabled", nil)
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, map[string]string{"public_key": server.push.PublicKey()})
+}
+
+// HandleListPushSubscriptions returns the browsers subscribed to the push notifications of the requester.
+// endpoint: GET /push/subscriptions
+// Success: 200
+// Fail: 401, 500
+func (server *Server) HandleListPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+
+	subscriptions, err := server.query.ListPushSubscriptions(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("GET /push/subscriptions: failed to list push subscriptions", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	result := make([]pushSubscriptionResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		result[i] = pushSubscriptionResponse{
+			ID:        subscription.PushSubscriptionID,
+			Endpoint:  subscription.Endpoint,
+			CreatedAt: subscription.CreatedAt,
+		}
+	}
+	server.WriteJSON(w, http.StatusOK, result)
+}
+
+// HandleCreatePushSubscription subscribes a browser to the push notifications of the requester. The body is the
+// PushSubscription of the browser, subscribed with the public VAPID key. A browser subscribed again (or by another
+// account) is updated.
+// endpoint: POST /push/subscriptions
+// Success: 201
+// Fail: 400, 401, 403, 404, 500
+func (server *Server) HandleCreatePushSubscription(w http.ResponseWriter, r *http.Request) {
+	if server.push == nil {
+		server.WriteErrorCode(w, http.StatusNotFound, CodePushNotEnabled, "Web Push is not enabled", nil)
+		return
+	}
+
+	// Get request body
+	var req pushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body, the keys must be usable to encrypt the notifications
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+	subscription := push.Subscription{Endpoint: req.Endpoint, P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	if err := subscription.Validate(); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid push subscription keys", nil)
+		return
+	}
+
+	// Check account status if it's active or not before processing with the request
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /push/subscriptions"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	// Create subscription
+	created, err := server.query.CreatePushSubscription(r.Context(), db.CreatePushSubscriptionParams{
+		AccountID: accountID,
+		Endpoint:  subscription.Endpoint,
+		P256dh:    subscription.P256dh,
+		Auth:      subscription.Auth,
+	})
+	if err != nil {
+		server.logger.Error("POST /push/subscriptions: failed to create push subscription", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusCreated, pushSubscriptionResponse{
+		ID:        created.PushSubscriptionID,
+		Endpoint:  created.Endpoint,
+		CreatedAt: created.CreatedAt,
+	})
+}
+
+// HandleDeletePushSubscription unsubscribes a browser of the requester from the push notifications.
+// endpoint: DELETE /push/subscriptions/{id}
+// Success: 200
+// Fail: 400, 401, 404, 500
+func (server *Server) HandleDeletePushSubscription(w http.ResponseWriter, r *http.Request) {
+	var accountID, subscriptionID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	if err := subscriptionID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid push subscription ID")
+		return
+	}
+
+	deleted, err := server.query.DeletePushSubscription(r.Context(), db.DeletePushSubscriptionParams{
+		PushSubscriptionID: subscriptionID,
+		AccountID:          accountID,
+	})
+	if err != nil {
+		server.logger.Error("DELETE /push/subscriptions/{id}: failed to delete push subscription", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		server.WriteErrorCode(w, http.StatusNotFound, CodePushSubscriptionNotFound, "Push subscription not found", nil)
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Push subscription deleted successfully")
+}
+
+// Helper method: enqueue the push of a notification to the browsers, when Web Push is enabled. Failure is only logged
+func (server *Server) enqueuePush(ctx context.Context, payload pushPayload) {
+	if server.push == nil {
+		return
+	}
+	if err := server.jobs.Enqueue(ctx, job.TypePush, payload); err != nil {
+		server.logger.Error("notification: failed to enqueue push job", "type", payload.Type, "error", err)
+	}
+}
+
+// Method to handle the push notification job: the message is sent to each browser, at most pushConcurrency at
+// once. The subscriptions which are gone are deleted. The job isn't retried when some browsers fail, it would push
+// the notification twice to the others, so the failures are only logged
+func (server *Server) handlePushJob(ctx context.Context, j *job.Job) error {
+	var payload pushPayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+
+	var subscriptions []db.PushSubscription
+	var err error
+	if payload.RecipientID.Valid {
+		subscriptions, err = server.query.ListPushSubscriptions(ctx, payload.RecipientID.UUID)
+	} else {
+		subscriptions, err = server.query.ListSubscriberPushSubscriptions(ctx, payload.ActorID.UUID)
+	}
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	// Build the message once, with the names of the actor and the video at the time it's sent
+	content, err := server.query.GetPushMessageContent(ctx, db.GetPushMessageContentParams{
+		ActorID: payload.ActorID,
+		VideoID: payload.VideoID,
+	})
+	if err != nil {
+		return err
+	}
+	message := pushMessage{
+		Type:          payload.Type,
+		ActorUsername: content.ActorUsername,
+		VideoTitle:    content.VideoTitle,
+		CreatedAt:     payload.CreatedAt,
+	}
+	if payload.ActorID.Valid {
+		message.ActorID = &payload.ActorID.UUID
+	}
+	if payload.VideoID.Valid {
+		message.VideoID = &payload.VideoID.UUID
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	slots := make(chan struct{}, pushConcurrency)
+	var wg sync.WaitGroup
+	for _, subscription := range subscriptions {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			server.sendPush(ctx, subscription, data)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Helper method: send a message to a browser, and delete its subscription if it's gone
+func (server *Server) sendPush(ctx context.Context, subscription db.PushSubscription, data []byte) {
+	err := server.push.Send(ctx, push.Subscription{
+		Endpoint: subscription.Endpoint,
+		P256dh:   subscription.P256dh,
+		Auth:     subscription.Auth,
+	}, data, pushTTL)
+	switch {
+	case errors.Is(err, push.ErrSubscriptionGone):
+		if err := server.query.DeletePushSubscriptionByEndpoint(ctx, subscription.Endpoint); err != nil {
+			server.logger.Error("push: failed to delete push subscription", "subscription_id",
+				subscription.PushSubscriptionID, "error", err)
+		}
+	case err != nil:
+		server.logger.Warn("push: failed to send push notification", "subscription_id",
+			subscription.PushSubscriptionID, "error", err)
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"zust/service/ratelimit"
+)
+
+// Helper method: get the rate limit policy of a request and the key of its bucket. Auth and media routes are
+// limited per IP, with a strict and a generous limit. Other routes are limited per account when the request
+// carries an access token, so the clients behind the same IP don't share their limit
+func (server *Server) rateLimitPolicy(r *http.Request) (string, ratelimit.Policy) {
+	perMinute := func(limit int) ratelimit.Policy {
+		return ratelimit.Policy{Rate: float64(limit) / 60, Burst: limit}
+	}
+
+	ip := server.clientIP(r)
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/auth/"):
+		return "auth:" + ip, perMinute(server.config.RateLimitAuth)
+	case strings.HasPrefix(r.URL.Path, "/media/"), strings.HasPrefix(r.URL.Path, "/live/"):
+		return "media:" + ip, perMinute(server.config.RateLimitMedia)
+	}
+
+	// The token is only parsed to pick the bucket, the account is authenticated later by AuthMiddleware
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		claims, err := server.jwtService.ParseToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err == nil && claims.TokenType == "access-token" {
+			return "api:account:" + claims.ID, perMinute(server.config.RateLimitAPI)
+		}
+	}
+	return "api:" + ip, perMinute(server.config.RateLimitAPI)
+}
+
+// Helper method: get the IP of the client. When the request comes from a trusted proxy, X-Forwarded-For is read
+// from the right: each proxy appends the address it received the request from, so the first address which isn't a
+// trusted proxy is the client. The addresses on its left are set by the client and can't be trusted
+func (server *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !server.isTrustedProxy(host) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			continue
+		}
+		if !server.isTrustedProxy(ip) {
+			return ip
+		}
+		host = ip
+	}
+	return host
+}
+
+// Helper method: check if an address is one of the trusted proxies
+func (server *Server) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, proxy := range server.config.TrustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Routes never rate limited, so the orchestrator and the monitoring can always reach the server
+var rateLimitExempt = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/health":  true,
+	"/metrics": true,
+}
+
+// RateLimitMiddleware is a middleware that limits the requests with a token bucket per client. The limit and the
+// remaining requests are returned in the X-RateLimit-* headers, a request over the limit is rejected with 429 and
+// the number of seconds to wait in Retry-After. If the limiter fails, requests are let through
+func (server *Server) RateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if server.limiter == nil || rateLimitExempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, policy := server.rateLimitPolicy(r)
+		result, err := server.limiter.Allow(r.Context(), key, policy)
+		if err != nil {
+			server.logger.Error("rate limit: failed to check rate limit", "key", key, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(policy.Burst))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			server.WriteError(w, http.StatusTooManyRequests, "Too many requests, please try again later")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Helper function: get the key of the failed logins of a username on a tenant
+func loginAttemptsKey(tenant, username string) string {
+	return "login:" + tenant + ":" + username
+}
+
+// Helper method: check if the logins of a key are locked out after too many wrong passwords, the request is
+// rejected with 429 and the time until the lockout ends in Retry-After. If the store fails, logins are let through
+func (server *Server) checkLoginLockout(w http.ResponseWriter, r *http.Request, key string) bool {
+	if server.attempts == nil || server.config.LoginMaxAttempts == 0 {
+		return true
+	}
+
+	count, left, err := server.attempts.Get(r.Context(), key)
+	if err != nil {
+		server.logger.Error("POST /login: failed to get failed login attempts", "key", key, "error", err)
+		return true
+	}
+	if count < server.config.LoginMaxAttempts {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(left.Seconds()))))
+	server.WriteErrorCode(w, http.StatusTooManyRequests, CodeRateLimited,
+		"Too many failed login attempts, please try again later", nil)
+	return false
+}
+
+// Helper method: record a failed login of a key
+func (server *Server) failLogin(ctx context.Context, key string) {
+	if server.attempts == nil || server.config.LoginMaxAttempts == 0 {
+		return
+	}
+	if _, err := server.attempts.Fail(ctx, key, server.config.LoginLockout); err != nil {
+		server.logger.Error("POST /login: failed to record failed login attempt", "key", key, "error", err)
+	}
+}
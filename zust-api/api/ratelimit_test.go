@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"zust/service/security"
+)
+
+func TestClientIP(t *testing.T) {
+	server := &Server{config: &security.Config{TrustedProxies: []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.10/32"),
+	}}}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		ip        string
+	}{
+		{name: "direct", remote: "203.0.113.7:5000", ip: "203.0.113.7"},
+		{name: "untrusted forwarded", remote: "203.0.113.7:5000", forwarded: []string{"198.51.100.1"},
+			ip: "203.0.113.7"},
+		{name: "trusted proxy", remote: "10.0.0.2:5000", forwarded: []string{"198.51.100.1"}, ip: "198.51.100.1"},
+		{name: "spoofed leftmost", remote: "10.0.0.2:5000", forwarded: []string{"1.2.3.4, 198.51.100.1"},
+			ip: "198.51.100.1"},
+		{name: "chain of proxies", remote: "10.0.0.2:5000", forwarded: []string{"198.51.100.1, 192.168.1.10"},
+			ip: "198.51.100.1"},
+		{name: "multiple headers", remote: "10.0.0.2:5000", forwarded: []string{"1.2.3.4", "198.51.100.1, 10.1.1.1"},
+			ip: "198.51.100.1"},
+		{name: "only proxies", remote: "10.0.0.2:5000", forwarded: []string{"10.0.0.3, 10.0.0.4"}, ip: "10.0.0.3"},
+		{name: "no header", remote: "10.0.0.2:5000", ip: "10.0.0.2"},
+		{name: "ipv4 mapped proxy", remote: "[::ffff:10.0.0.2]:5000", forwarded: []string{"198.51.100.1"},
+			ip: "198.51.100.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/videos", nil)
+			r.RemoteAddr = test.remote
+			for _, value := range test.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := server.clientIP(r); got != test.ip {
+				t.Errorf("clientIP() = %q, want %q", got, test.ip)
+			}
+		})
+	}
+}
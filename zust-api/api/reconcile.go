@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/file"
+
+	"github.com/google/uuid"
+)
+
+// Discrepancies between the storage and the database
+type reconcileReport struct {
+	OrphanFiles  []string       `json:"orphan_files"` // files without owning account or video
+	OrphanBytes  int64          `json:"orphan_bytes"`
+	MissingFiles []missingFiles `json:"missing_files"` // rows whose files are missing
+	Removed      int            `json:"removed"`       // orphan files deleted, always 0 on dry-run
+}
+
+// Row of the database whose files are missing in the storage
+type missingFiles struct {
+	Kind   string    `json:"kind"` // account or video
+	ID     uuid.UUID `json:"id"`
+	Reason string    `json:"reason"`
+}
+
+// Method to compare the storage against the database: files which belong to no account or video are orphans,
+// deleted unless 'dryRun' is true, and published videos or accounts whose files are missing are reported.
+// Only files not modified for ORPHAN_MIN_AGE are orphans, so a file written right before its row is never removed
+func (server *Server) reconcileStorage(ctx context.Context, dryRun bool) (*reconcileReport, error) {
+	owners, err := server.loadFileOwners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := server.storage.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &reconcileReport{OrphanFiles: []string{}, MissingFiles: []missingFiles{}}
+	cutoff := time.Now().Add(-server.config.OrphanMinAge)
+	for _, info := range files {
+		if info.ModTime.After(cutoff) || !owners.isOrphan(info.Key) {
+			continue
+		}
+
+		report.OrphanFiles = append(report.OrphanFiles, info.Key)
+		report.OrphanBytes += info.Size
+		if dryRun {
+			continue
+		}
+		if err := server.storage.Delete(info.Key); err != nil {
+			server.logger.Error("reconcile: failed to delete orphan file", "key", info.Key, "error", err)
+			continue
+		}
+		report.Removed++
+	}
+
+	for _, id := range owners.accountIDs {
+		if !file.Exists(server.storage, file.AvatarKey(id.String())) {
+			report.MissingFiles = append(report.MissingFiles, missingFiles{Kind: "account", ID: id, Reason: "avatar is missing"})
+		}
+	}
+	for _, video := range owners.videos {
+		// The renditions of cold videos are in the cold storage
+		if video.Status != db.VideoStatusPublished && video.Status != db.VideoStatusHeld || video.ColdAt.Valid {
+			continue
+		}
+
+		accID, videoID := video.PublisherID.String(), video.VideoID.String()
+		hasOriginal := !video.OriginalRemovedAt.Valid && file.Exists(server.storage, file.OriginalKey(accID, videoID))
+		if !hasOriginal && file.BestRendition(server.storage, accID, videoID) == "" {
+			report.MissingFiles = append(report.MissingFiles, missingFiles{
+				Kind:   "video",
+				ID:     video.VideoID,
+				Reason: "neither the original nor any rendition is available",
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// Accounts and videos of the database, which own the stored files
+type fileOwners struct {
+	accountIDs []uuid.UUID
+	videos     []db.ListVideoFilesRow
+	accounts   map[uuid.UUID]bool
+	publishers map[uuid.UUID]uuid.UUID // publisher of each video
+}
+
+// Helper method: load the accounts and videos owning the stored files
+func (server *Server) loadFileOwners(ctx context.Context) (*fileOwners, error) {
+	accountIDs, err := server.query.ListAccountIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	videos, err := server.query.ListVideoFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := &fileOwners{
+		accountIDs: accountIDs,
+		videos:     videos,
+		accounts:   make(map[uuid.UUID]bool, len(accountIDs)),
+		publishers: make(map[uuid.UUID]uuid.UUID, len(videos)),
+	}
+	for _, id := range accountIDs {
+		owners.accounts[id] = true
+	}
+	for _, video := range videos {
+		owners.publishers[video.VideoID] = video.PublisherID
+	}
+	return owners, nil
+}
+
+// Method to check if a stored file belongs to an account of the database, and to one of its videos if it's a file
+// of a video. Temporary files and files outside the user repositories (caches, ...) are not owned
+func (owners *fileOwners) owns(key string) bool {
+	if strings.Contains(key, file.TempMarker) {
+		return false
+	}
+	accID, err := uuid.Parse(strings.Split(key, "/")[0])
+	if err != nil || !owners.accounts[accID] {
+		return false
+	}
+	return !owners.isOrphan(key)
+}
+
+// Method to check if a stored file belongs to no account or video. Files outside the layout of the user
+// repositories (caches, temporary files, unknown directories) are never orphans
+func (owners *fileOwners) isOrphan(key string) bool {
+	if strings.Contains(key, file.TempMarker) {
+		return false
+	}
+
+	accID, err := uuid.Parse(strings.Split(key, "/")[0])
+	if err != nil {
+		return false
+	}
+	if !owners.accounts[accID] {
+		return true
+	}
+
+	// Files of the account (avatar, cover, watermark, ...) are kept as long as the account exists
+	_, videoID, ok := file.ParseVideoKey(key)
+	if !ok {
+		return false
+	}
+
+	publisher, exists := owners.publishers[videoID]
+	return !exists || publisher != accID
+}
+
+// Method to run the reconciliation periodically, deleting the orphan files
+func (server *Server) runReconcileJob(ctx context.Context) {
+	report, err := server.reconcileStorage(ctx, false)
+	if err != nil {
+		server.logger.Error("reconcile: failed to reconcile storage", "error", err)
+		return
+	}
+
+	for _, missing := range report.MissingFiles {
+		server.logger.Warn("reconcile: files are missing", "kind", missing.Kind, "id", missing.ID,
+			"reason", missing.Reason)
+	}
+	server.logger.Info("reconcile: storage reconciled", "orphans", len(report.OrphanFiles),
+		"removed", report.Removed, "orphan_bytes", report.OrphanBytes, "missing", len(report.MissingFiles))
+}
+
+// HandleReconcileStorage reports the discrepancies between the storage and the database without changing anything
+// (dry-run): orphan files which the reconciliation job would delete, and rows whose files are missing. Only
+// available to admin.
+// endpoint: GET /admin/storage/reconcile
+// Success: 200
+// Fail: 403, 500
+func (server *Server) HandleReconcileStorage(w http.ResponseWriter, r *http.Request) {
+	report, err := server.reconcileStorage(r.Context(), true)
+	if err != nil {
+		server.logger.Error("GET /admin/storage/reconcile: failed to reconcile storage", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, report)
+}
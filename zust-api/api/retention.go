@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"zust/service/file"
+	"zust/service/job"
+
+	"github.com/google/uuid"
+)
+
+// Method to apply the original file retention policy. For each publisher, the originals of the newest videos are
+// kept as long as their total size is under the per-user quota, the rest is deleted or archived (based on config).
+// Only originals of published videos whose renditions are all completed, and without a media job (transcode or
+// caption) waiting or running, are touched: these jobs read the original
+func (server *Server) runRetentionJob(ctx context.Context) {
+	// Get all videos (grouped by publisher, newest first) which still have their original file
+	videos, err := server.query.ListRetainedOriginals(ctx)
+	if err != nil {
+		server.logger.Error("retention: failed to list retained originals", "error", err)
+		return
+	}
+
+	inUse, err := server.videosWithMediaJobs(ctx)
+	if err != nil {
+		server.logger.Error("retention: failed to list media jobs", "error", err)
+		return
+	}
+
+	archivePath := ""
+	if server.config.OriginalPolicy == "archive" {
+		archivePath = server.config.OriginalArchivePath
+	}
+
+	var (
+		reclaimed int64
+		removed   int
+		usage     = make(map[uuid.UUID]int64) // Total size of kept originals of each publisher
+	)
+	for _, video := range videos {
+		accID, videoID := video.PublisherID.String(), video.VideoID.String()
+
+		// Never remove the original while a job still needs it
+		if inUse[video.VideoID] {
+			continue
+		}
+
+		original, err := server.storage.Stat(file.OriginalKey(accID, videoID))
+		if err != nil {
+			server.logger.Error("retention: failed to get original file size", "video_id", videoID, "error", err)
+			continue
+		}
+
+		// Keep the original while the publisher is still under quota
+		if usage[video.PublisherID]+original.Size <= server.config.OriginalQuota {
+			usage[video.PublisherID] += original.Size
+			continue
+		}
+
+		// Remove (or archive) the original and record it in database
+		n, err := file.RemoveOriginal(server.storage, accID, videoID, archivePath)
+		if err != nil {
+			server.logger.Error("retention: failed to remove original file", "video_id", videoID, "error", err)
+			continue
+		}
+		if err := server.query.MarkOriginalRemoved(ctx, video.VideoID); err != nil {
+			server.logger.Error("retention: failed to mark original as removed", "video_id", videoID, "error", err)
+			continue
+		}
+		server.invalidateVideos(ctx, video.VideoID)
+
+		reclaimed += n
+		removed++
+	}
+
+	server.logger.Info("retention: original files processed", "policy", server.config.OriginalPolicy,
+		"removed", removed, "reclaimed_bytes", reclaimed)
+}
+
+// Helper method: get the videos with a transcode or caption job waiting or running. A payload which can't be
+// decoded fails the whole listing, since its video is unknown
+func (server *Server) videosWithMediaJobs(ctx context.Context) (map[uuid.UUID]bool, error) {
+	videos := make(map[uuid.UUID]bool)
+	for _, jobType := range []string{job.TypeTranscode, job.TypeCaption} {
+		payloads, err := server.jobs.Active(ctx, jobType)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, payload := range payloads {
+			var target struct {
+				VideoID uuid.UUID `json:"video_id"`
+			}
+			if err := json.Unmarshal(payload, &target); err != nil {
+				return nil, err
+			}
+			videos[target.VideoID] = true
+		}
+	}
+	return videos, nil
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Periodic jobs of the server, running until the scheduler is stopped
+type scheduler struct {
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// Helper method: register the lifecycle hook of the periodic jobs
+func (server *Server) registerScheduler() {
+	server.OnLifecycle(Hook{
+		Name:  "scheduler",
+		Start: server.startScheduler,
+		Stop:  server.stopScheduler,
+	})
+}
+
+// Method to start the periodic jobs of the server
+func (server *Server) startScheduler(ctx context.Context) error {
+	ctx, server.scheduler.cancel = context.WithCancel(ctx)
+
+	if server.config.OriginalPolicy != "keep" {
+		server.schedule(ctx, "retention", server.config.RetentionInterval, server.runRetentionJob)
+	}
+
+	server.schedule(ctx, "janitor", server.config.TempCleanupInterval, server.runJanitorJob)
+	server.schedule(ctx, "sessions", server.config.SessionCleanupInterval, server.runSessionCleanupJob)
+	server.schedule(ctx, "trending", server.config.TrendingRefreshInterval, server.runTrendingJob)
+	server.schedule(ctx, "watch_time", server.config.WatchTimeRollupInterval, server.runWatchTimeJob)
+	server.schedule(ctx, "digest", server.config.DigestCheckInterval, server.runDigestJob)
+	server.schedule(ctx, "webhooks", webhookCleanupInterval, server.runWebhookCleanupJob)
+	server.schedule(ctx, "step_up", stepUpCleanupInterval, server.runStepUpCleanupJob)
+	server.schedule(ctx, "strikes", strikeCheckInterval, server.runStrikeJob)
+	server.schedule(ctx, "counters", server.config.CounterReconcileInterval, server.runCounterReconcileJob)
+
+	// Create the partitions of the view events once before serving, so the first events always have their partition
+	server.runPartitionJob(ctx)
+	server.schedule(ctx, "partitions", partitionCheckInterval, server.runPartitionJob)
+
+	// Check the storage once before serving, so uploads are never accepted on a full storage
+	server.runStorageCheck(ctx)
+	server.schedule(ctx, "storage", server.config.StorageCheckInterval, server.runStorageCheck)
+	server.schedule(ctx, "storage_usage", storageUsageInterval, server.runStorageUsageJob)
+
+	if server.coldStorage != nil {
+		server.schedule(ctx, "tiering", server.config.ColdCheckInterval, server.runTieringJob)
+	}
+
+	if server.warmCache != nil {
+		server.schedule(ctx, "prewarm", server.config.PrewarmInterval, server.runPrewarmJob)
+	}
+
+	if server.config.OrphanGCInterval > 0 {
+		server.schedule(ctx, "reconcile", server.config.OrphanGCInterval, server.runReconcileJob)
+	}
+	return nil
+}
+
+// Method to stop the periodic jobs, waiting for the running ones to return until ctx is done
+func (server *Server) stopScheduler(ctx context.Context) error {
+	if server.scheduler.cancel == nil {
+		return nil
+	}
+	server.scheduler.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		server.scheduler.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Method to run a job periodically in background until ctx is cancelled
+func (server *Server) schedule(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context)) {
+	server.scheduler.running.Add(1)
+	go func() {
+		defer server.scheduler.running.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				server.logger.Debug("scheduler: running job", "job", name)
+				job(ctx)
+			}
+		}
+	}()
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+	db "zust/db/sqlc"
+	"zust/service/file"
+)
+
+// Max number of words of a search keyword, the following words are ignored
+const maxSearchWords = 8
+
+// Helper function: build the full-text query of a search keyword. Each word must match, as a prefix so the results
+// show up while the keyword is being typed. Only the letters and digits of the words are kept, so the keyword can't
+// inject tsquery operators. It returns an empty query if the keyword has no word
+func searchQuery(keyword string) string {
+	words := strings.FieldsFunc(keyword, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+	if len(words) > maxSearchWords {
+		words = words[:maxSearchWords]
+	}
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// A channel in the search result
+type searchChannelResult struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	Description string `json:"description"`
+	Avatar      string `json:"avatar"`
+}
+
+// HandleSearchChannels searches the active channels by username and description, most relevant first.
+// endpoint: GET /accounts?q=...&page=...&size=...
+// Success: 200
+// Fail: 400, 500
+func (server *Server) HandleSearchChannels(w http.ResponseWriter, r *http.Request) {
+	query := searchQuery(r.URL.Query().Get("q"))
+	if query == "" {
+		server.WriteError(w, http.StatusBadRequest, "Missing search keyword")
+		return
+	}
+
+	// Get pagination, the results sorted by relevance are paged by page number
+	_, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+	page, ok := server.parsePageNumber(w, r)
+	if !ok {
+		return
+	}
+
+	channels, err := server.query.SearchChannels(r.Context(), db.SearchChannelsParams{
+		Query:      query,
+		TenantID:   tenantOf(r.Context()),
+		PageSize:   int32(size),
+		PageOffset: int32((page - 1) * size),
+	})
+	if err != nil {
+		server.logger.Error("GET /accounts: failed to search channels", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]searchChannelResult, 0, len(channels))
+	for _, channel := range channels {
+		data = append(data, searchChannelResult{
+			ID:          channel.AccountID.String(),
+			Username:    channel.Username,
+			Description: channel.Description.String,
+			Avatar:      server.mediaService.GenerateMediaLink(channel.AccountID.String(), "", file.Avatar),
+		})
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
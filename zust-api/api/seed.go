@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"zust/asset"
+	db "zust/db/sqlc"
+	"zust/service/file"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Demo channel created by the seed command
+type seedChannel struct {
+	username    string
+	description string
+	videos      []seedVideo
+}
+
+// Demo video created by the seed command
+type seedVideo struct {
+	title       string
+	description string
+	duration    int32
+	license     db.VideoLicense
+}
+
+// Demo channels and their videos. The last account has no video, it's a viewer subscribed to all channels
+var seedChannels = []seedChannel{
+	{
+		username:    "demo_cooking",
+		description: "Quick recipes for busy weeknights",
+		videos: []seedVideo{
+			{"Five minute fried rice", "Leftover rice, eggs and whatever is in the fridge", 312, db.VideoLicenseStandard},
+			{"Homemade pho broth", "A slow Sunday broth, step by step", 1245, db.VideoLicenseStandard},
+			{"Knife skills for beginners", "Dice, julienne and chiffonade without fear", 547, db.VideoLicenseCcBy},
+		},
+	},
+	{
+		username:    "demo_travel",
+		description: "Slow travel across Southeast Asia",
+		videos: []seedVideo{
+			{"Hanoi old quarter at dawn", "Walking the streets before the city wakes up", 689, db.VideoLicenseStandard},
+			{"Ha Long Bay by kayak", "Two days paddling between the islands", 1532, db.VideoLicenseCcBySa},
+			{"Night train to Hue", "What a sleeper cabin is really like", 903, db.VideoLicenseStandard},
+		},
+	},
+	{
+		username:    "demo_music",
+		description: "Acoustic covers and original songs",
+		videos: []seedVideo{
+			{"Rainy day acoustic session", "Three songs recorded in one take", 1104, db.VideoLicenseCcByNc},
+			{"Fingerstyle guitar basics", "Patterns to practice every day", 765, db.VideoLicenseCc0},
+		},
+	},
+	{
+		username:    "demo_viewer",
+		description: "Just here to watch",
+	},
+}
+
+// Resolutions of the stubbed renditions of the demo videos
+var seedResolutions = []string{"720p", "480p"}
+
+// Accounts and videos created by the seed command
+type SeedResult struct {
+	Accounts int `json:"accounts"`
+	Videos   int `json:"videos"`
+}
+
+// Method to create the demo data for local development: active accounts (all with the same password), the
+// subscriptions of the viewer and published videos. Transcoding is stubbed: the renditions are marked completed
+// and the thumbnail is a placeholder, but no HLS file is generated so the demo videos can't be played.
+// Accounts which already exist are skipped with their videos, so the command can run again
+func (server *Server) Seed(ctx context.Context, password string) (*SeedResult, error) {
+	hashedPassword, err := security.BcryptHash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SeedResult{}
+	var channelIDs []uuid.UUID
+	for _, channel := range seedChannels {
+		accountID, created, err := server.seedAccount(ctx, channel, hashedPassword)
+		if err != nil {
+			return nil, fmt.Errorf("seed account %s: %w", channel.username, err)
+		}
+		if len(channel.videos) > 0 {
+			channelIDs = append(channelIDs, accountID)
+		}
+		if !created {
+			continue
+		}
+		result.Accounts++
+
+		for _, video := range channel.videos {
+			if err := server.seedVideo(ctx, accountID, video); err != nil {
+				return nil, fmt.Errorf("seed video %q: %w", video.title, err)
+			}
+			result.Videos++
+		}
+
+		// The viewer subscribes to all channels
+		if len(channel.videos) == 0 {
+			for _, channelID := range channelIDs {
+				_, err := server.subscribe(ctx, db.SubscribeParams{
+					SubscriberID:  accountID,
+					SubscribeToID: channelID,
+				})
+				if err != nil {
+					return nil, fmt.Errorf("seed subscription of %s: %w", channel.username, err)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Helper method: create an active demo account with its repository, or get the existing one. 'created' is false if
+// the account already exists
+func (server *Server) seedAccount(ctx context.Context, channel seedChannel, hashedPassword string) (uuid.UUID,
+	bool, error) {
+	existing, err := server.query.GetAccountByUsername(ctx, db.GetAccountByUsernameParams{
+		TenantID: security.DefaultTenant,
+		Username: channel.username,
+	})
+	if err == nil {
+		return existing.AccountID, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, false, err
+	}
+
+	var accountID uuid.UUID
+	err = server.query.ExecTx(ctx, func(q *db.Queries) error {
+		account, err := q.CreateAccountWithPassword(ctx, db.CreateAccountWithPasswordParams{
+			TenantID: security.DefaultTenant,
+			Email:    channel.username + "@example.com",
+			Username: channel.username,
+			Password: sql.NullString{String: hashedPassword, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		accountID = account.AccountID
+
+		if err := q.SetAccountStatus(ctx, db.SetAccountStatusParams{
+			AccountID: accountID,
+			Status:    db.AccountStatusActive,
+		}); err != nil {
+			return err
+		}
+		_, err = q.EditProfile(ctx, db.EditProfileParams{
+			AccountID:   accountID,
+			Username:    channel.username,
+			Description: sql.NullString{String: channel.description, Valid: true},
+		})
+		return err
+	})
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	return accountID, true, server.storage.EnsureUserRepo(accountID.String())
+}
+
+// Helper method: create a published demo video, with its renditions marked completed and a placeholder thumbnail
+func (server *Server) seedVideo(ctx context.Context, accountID uuid.UUID, video seedVideo) error {
+	created, err := server.query.CreateVideo(ctx, db.CreateVideoParams{
+		Title:       video.title,
+		Description: sql.NullString{String: video.description, Valid: true},
+		PublisherID: accountID,
+		License:     video.license,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Use the default cover as the thumbnail
+	thumbnail, err := asset.Open(server.config.AssetPath, "cover.png")
+	if err != nil {
+		return err
+	}
+	defer thumbnail.Close()
+	if err := server.storage.Put(file.ThumbnailKey(accountID.String(), created.VideoID.String()), thumbnail); err != nil {
+		return err
+	}
+
+	// Stub the transcoding
+	if err := server.query.UpdateVideoDuration(ctx, db.UpdateVideoDurationParams{
+		VideoID:  created.VideoID,
+		Duration: video.duration,
+	}); err != nil {
+		return err
+	}
+	for _, resolution := range seedResolutions {
+		if err := server.query.CreateRendition(ctx, db.CreateRenditionParams{
+			VideoID:    created.VideoID,
+			Resolution: resolution,
+		}); err != nil {
+			return err
+		}
+		if err := server.query.UpdateRenditionStatus(ctx, db.UpdateRenditionStatusParams{
+			VideoID:    created.VideoID,
+			Resolution: resolution,
+			Status:     db.RenditionStatusCompleted,
+			Progress:   100,
+		}); err != nil {
+			return err
+		}
+	}
+
+	_, err = server.query.PublishVideo(ctx, created.VideoID)
+	return err
+}
@@ -1,15 +1,28 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	db "zust/db/sqlc"
+	"zust/service/cache"
 	"zust/service/file"
+	"zust/service/job"
 	"zust/service/mail"
+	"zust/service/malware"
+	"zust/service/moderation"
+	"zust/service/push"
+	"zust/service/ratelimit"
 	"zust/service/security"
+	"zust/service/sms"
+	"zust/service/transcription"
+	"zust/service/webhook"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -26,77 +39,396 @@ var (
 
 // Server struct
 type Server struct {
-	query        *db.Queries
-	jwtService   *security.JWTService
-	mailService  *mail.EmailService
-	mediaService *file.MediaService
-	storage      *file.LocalStorage
-	mux          *http.ServeMux
-	logger       *slog.Logger
-	validate     *validator.Validate
-	config       *security.Config
+	query             *db.Store
+	jwtService        *security.JWTService
+	mailService       *mail.EmailService
+	mediaService      *file.MediaService
+	storage           file.Storage
+	localStorage      *file.LocalStorage
+	coldStorage       file.Storage    // nil if cold storage tiering is disabled
+	warmCache         *file.WarmCache // nil if pre-warm is disabled
+	ranges            *rangeTracker
+	moderationScanner moderation.ModerationScanner
+	transcriber       transcription.Transcriber
+	malwareScanner    malware.Scanner
+	push              *push.Service // nil if Web Push is disabled
+	sms               sms.Sender    // nil if SMS is disabled
+	webhooks          *webhook.Client
+	hub               *notificationHub
+	imports           *importTracker
+	premieres         *premiereTracker
+	janitor           *janitorStats
+	storageStatus     *storageStatus
+	cache             cache.Cache
+	limiter           ratelimit.Limiter  // nil if rate limiting is disabled
+	attempts          ratelimit.Attempts // failed logins, nil if rate limiting is disabled
+	tenants           *tenantRegistry
+	jobs              job.Queue
+	scheduler         scheduler
+	events            *eventWriter[db.CreateViewEventsParams]
+	playbackEvents    *eventWriter[db.CreatePlaybackEventsParams]
+	lifecycle         lifecycle
+	mux               *http.ServeMux
+	logger            *slog.Logger
+	validate          *validator.Validate
+	config            *security.Config
 }
 
-// NewServer creates a new HTTP server and setup routing
-func NewServer(conn *sql.DB, config *security.Config, logger *slog.Logger) *Server {
-	server := &Server{
-		query:        db.New(conn),
-		jwtService:   security.NewJWTService(config),
-		mailService:  mail.NewEmailService(config),
-		mediaService: file.NewMediaService(config),
-		storage:      file.NewLocalStorage(config),
-		mux:          http.NewServeMux(),
-		logger:       logger,
-		validate:     validator.New(validator.WithRequiredStructEnabled()),
-		config:       config,
+// NewServer creates a new HTTP server and setup routing.
+// It returns an error if ffmpeg or ffprobe is not usable
+func NewServer(conn *sql.DB, config *security.Config, logger *slog.Logger) (*Server, error) {
+	server, err := newServer(conn, config, logger)
+	if err != nil {
+		return nil, err
 	}
+	server.registerJobs()
+	server.registerScheduler()
+	server.registerEventWriter()
+	server.registerImports()
+	server.registerNotificationHub()
+
+	// Validation errors refer to the JSON names of the fields
+	server.validate.RegisterTagNameFunc(jsonFieldName)
+
+	// Custom validation tag for video license
+	server.validate.RegisterValidation("license", func(fl validator.FieldLevel) bool {
+		return isValidLicense(fl.Field().String())
+	})
 
 	server.RegisterHandler()
 
-	return server
+	return server, nil
+}
+
+// NewWorker creates a transcoding worker, which only runs the media jobs (transcoding and captions) pulled from
+// the job queue shared with the API server. Progress is written into the shared database, where the API server
+// reads it for the processing status endpoints
+func NewWorker(conn *sql.DB, config *security.Config, logger *slog.Logger) (*Server, error) {
+	server, err := newServer(conn, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	server.registerMediaJobs()
+	return server, nil
+}
+
+// Helper function: create the server with its services, shared by the API server and the transcoding workers
+func newServer(conn *sql.DB, config *security.Config, logger *slog.Logger) (*Server, error) {
+	server := &Server{
+		query:         newStore(conn, config, logger),
+		jwtService:    security.NewJWTService(config),
+		mediaService:  file.NewMediaService(config),
+		localStorage:  file.NewLocalStorage(config),
+		imports:       newImportTracker(config),
+		premieres:     newPremiereTracker(),
+		janitor:       &janitorStats{},
+		storageStatus: &storageStatus{},
+		ranges:        newRangeTracker(),
+		cache:         cache.NewCache(config),
+		limiter:       ratelimit.NewLimiter(config),
+		attempts:      ratelimit.NewAttempts(config),
+		webhooks:      webhook.NewClient(),
+		hub:           newNotificationHub(config),
+		mux:           http.NewServeMux(),
+		logger:        logger,
+		validate:      validator.New(validator.WithRequiredStructEnabled()),
+		config:        config,
+	}
+	server.storage = server.localStorage
+
+	// Stale renditions are only moved to the cold storage when it's configured
+	switch {
+	case config.ColdStorageDriver == "azure":
+		azure, err := file.NewAzureStorage(config)
+		if err != nil {
+			return nil, err
+		}
+		server.coldStorage = azure
+	case config.ColdStoragePath != "":
+		server.coldStorage = &file.LocalStorage{ResourcePath: config.ColdStoragePath, Sharded: server.localStorage.Sharded}
+	}
+
+	// Repositories of the tenants other than the default one are stored under their tenant directory
+	server.setupTenants()
+
+	// Hot renditions are only pre-warmed when a warm cache is configured
+	if config.PrewarmPath != "" {
+		server.warmCache = &file.WarmCache{Path: config.PrewarmPath}
+	}
+
+	// Fail fast if ffmpeg cannot run the configured transcoding
+	if err := server.mediaService.DetectFFmpeg(); err != nil {
+		return nil, err
+	}
+	logger.Info("ffmpeg detected", "ffmpeg", server.mediaService.FFmpegPath, "version", server.mediaService.FFmpegVersion,
+		"ffprobe", server.mediaService.FFprobePath, "ffprobe_version", server.mediaService.FFprobeVersion)
+
+	// Hardware encoder is detected once, H.264 is encoded with libx264 if it's not usable
+	if config.HWAccel != file.HWAccelNone {
+		if err := server.mediaService.DetectHWAccel(); err != nil {
+			logger.Warn("hardware encoder is not available, fallback to libx264", "hwaccel", config.HWAccel, "error", err)
+		}
+	}
+
+	// Content moderation is only enabled when the detection service is configured
+	if config.ModerationURL != "" {
+		server.moderationScanner = moderation.NewHTTPScanner(config)
+	}
+
+	// Uploads are only scanned for malware when a scanner is configured
+	server.malwareScanner = malware.NewScanner(config)
+
+	// Automatic captions are only generated when a transcription provider is configured
+	server.transcriber = transcription.NewTranscriber(config)
+
+	// Emails are signed with DKIM when a key is configured
+	mailService, err := mail.NewEmailService(config)
+	if err != nil {
+		return nil, err
+	}
+	server.mailService = mailService
+
+	// Notifications are only pushed to the browsers when a VAPID key is configured
+	pushService, err := push.NewService(config)
+	if err != nil {
+		return nil, err
+	}
+	server.push = pushService
+
+	// Sensitive actions only need a code sent by SMS when an SMS provider is configured
+	server.sms = sms.NewSender(config)
+
+	server.jobs = newJobQueue(server.query, config, logger)
+
+	// The cache is closed once the job workers are stopped, as they may still use it
+	server.OnLifecycle(Hook{Name: "cache", Stop: func(ctx context.Context) error { return server.cache.Close() }})
+	server.OnLifecycle(Hook{Name: "jobs", Start: server.jobs.Start, Stop: server.jobs.Stop})
+
+	return server, nil
+}
+
+// Helper function: create the store of the database, recording the latency of the queries and retrying the
+// transient errors
+func newStore(conn *sql.DB, config *security.Config, logger *slog.Logger) *db.Store {
+	return db.NewStore(conn, db.NewQueryMetrics(logger, config.DbSlowQueryThreshold), config.DbMaxRetries)
+}
+
+// Helper function: create the job queue. Background jobs are stored in database, or in Redis for multi-instance
+// deployments
+func newJobQueue(query *db.Store, config *security.Config, logger *slog.Logger) job.Queue {
+	switch config.JobDriver {
+	case "asynq":
+		return job.NewAsynqQueue(config, logger)
+	default:
+		return job.NewDBQueue(query.Queries, config, logger)
+	}
 }
 
 // RegisterHandler register all route
 func (server *Server) RegisterHandler() {
+	// Liveness and readiness probes, registered first so orchestrators can manage the server
+	server.mux.HandleFunc("GET /healthz", server.HandleLiveness)
+	server.mux.HandleFunc("GET /readyz", server.HandleReadiness)
+
+	// Health and metrics
+	server.mux.HandleFunc("GET /health", server.HandleHealth)
+	server.mux.HandleFunc("GET /metrics", server.HandleMetrics)
+
+	// Profiling, disabled by default
+	server.registerPprof()
+
+	// GraphQL endpoint, disabled by default
+	if server.config.GraphQLEnabled {
+		server.mux.Handle("POST /graphql", server.graphqlHandler())
+	}
+
 	// Media serving
 	server.mux.HandleFunc("GET /media/{id}", server.HandleMedia)
+	server.mux.HandleFunc("GET /media/{id}/{path...}", server.HandleMediaFile)
+	server.mux.HandleFunc("GET /live/{id}/{file}", server.HandleLiveFile)
 
 	// Auth routes
 	server.mux.HandleFunc("POST /auth/login", server.HandleLogin)
 	server.mux.HandleFunc("POST /auth/register", server.HandleRegister)
 	server.mux.HandleFunc("POST /auth/verification/resend", server.HandleResendVerification)
 	server.mux.HandleFunc("GET /auth/verification", server.HandleVerify)
+	server.mux.HandleFunc("POST /auth/verification/confirm", server.HandleConfirmVerification)
 	server.mux.HandleFunc("GET /oauth2/callback", server.HandleCallback)
 	server.mux.Handle("POST /auth/token/refresh", server.AuthMiddleware(http.HandlerFunc(server.HandleRefreshToken)))
+	server.mux.Handle("GET /auth/sessions", server.AuthMiddleware(http.HandlerFunc(server.HandleListSessions)))
+	server.mux.Handle("DELETE /auth/sessions", server.AuthMiddleware(http.HandlerFunc(server.HandleRevokeSessions)))
+	server.mux.Handle("DELETE /auth/sessions/{id}", server.AuthMiddleware(http.HandlerFunc(server.HandleRevokeSession)))
 	server.mux.Handle("POST /auth/logout", server.AuthMiddleware(http.HandlerFunc(server.HandleLogout)))
 
 	// Account routes
+	server.mux.HandleFunc("GET /accounts", server.HandleSearchChannels)
 	server.mux.HandleFunc("GET /accounts/{id}", server.HandleGetProfile)
 	server.mux.Handle("PUT /accounts/{id}", server.AuthMiddleware(http.HandlerFunc(server.HandleEditProfile)))
+	server.mux.Handle("DELETE /accounts/{id}", server.AuthMiddleware(http.HandlerFunc(server.HandleDeleteAccount)))
+	server.mux.Handle("PUT /accounts/{id}/password", server.AuthMiddleware(http.HandlerFunc(server.HandleChangePassword)))
+	server.mux.Handle("PUT /accounts/{id}/phone", server.AuthMiddleware(http.HandlerFunc(server.HandleSetPhoneNumber)))
+	server.mux.Handle("POST /accounts/{id}/phone/verify", server.AuthMiddleware(http.HandlerFunc(server.HandleVerifyPhoneNumber)))
+	server.mux.Handle("DELETE /accounts/{id}/phone", server.AuthMiddleware(http.HandlerFunc(server.HandleRemovePhoneNumber)))
+	server.mux.Handle("POST /accounts/{id}/step-up", server.AuthMiddleware(http.HandlerFunc(server.HandleRequestStepUp)))
+	server.mux.Handle("PUT /accounts/{id}/webhook", server.AuthMiddleware(http.HandlerFunc(server.HandleSetProcessingWebhook)))
+	server.mux.Handle("GET /accounts/{id}/webhooks", server.AuthMiddleware(http.HandlerFunc(server.HandleListWebhooks)))
+	server.mux.Handle("POST /accounts/{id}/webhooks", server.AuthMiddleware(http.HandlerFunc(server.HandleCreateWebhook)))
+	server.mux.Handle("DELETE /accounts/{id}/webhooks/{webhook_id}", server.AuthMiddleware(http.HandlerFunc(server.HandleDeleteWebhook)))
+	server.mux.Handle("GET /accounts/{id}/webhooks/{webhook_id}/deliveries", server.AuthMiddleware(http.HandlerFunc(server.HandleListWebhookDeliveries)))
+	server.mux.Handle("PUT /accounts/{id}/watermark", server.AuthMiddleware(http.HandlerFunc(server.HandleSetWatermark)))
+	server.mux.Handle("DELETE /accounts/{id}/watermark", server.AuthMiddleware(http.HandlerFunc(server.HandleDeleteWatermark)))
+	server.mux.Handle("PUT /accounts/{id}/branding/{kind}", server.AuthMiddleware(http.HandlerFunc(server.HandleSetBranding)))
+	server.mux.Handle("DELETE /accounts/{id}/branding/{kind}", server.AuthMiddleware(http.HandlerFunc(server.HandleDeleteBranding)))
 	server.mux.Handle("POST /accounts/{id}/lock", server.AuthMiddleware(http.HandlerFunc(server.HandleLockAccount)))
 	server.mux.Handle("POST /accounts/{id}/unlock", server.AuthMiddleware(http.HandlerFunc(server.HandleUnlockAccount)))
+	server.mux.Handle("POST /accounts/{id}/reports", server.AuthMiddleware(http.HandlerFunc(server.HandleReportAccount)))
+	server.mux.Handle("GET /accounts/{id}/strikes", server.AuthMiddleware(http.HandlerFunc(server.HandleListStrikes)))
+	server.mux.Handle("GET /accounts/{id}/blocked-words", server.AuthMiddleware(http.HandlerFunc(server.HandleListChannelBlockedWords)))
+	server.mux.Handle("POST /accounts/{id}/blocked-words", server.AuthMiddleware(http.HandlerFunc(server.HandleCreateChannelBlockedWord)))
+	server.mux.Handle("DELETE /accounts/{id}/blocked-words/{word_id}", server.AuthMiddleware(http.HandlerFunc(server.HandleDeleteChannelBlockedWord)))
+	server.mux.Handle("POST /blocked-words/test", server.AuthMiddleware(http.HandlerFunc(server.HandleTestBlockedWords)))
+	server.mux.Handle("GET /accounts/{id}/subscribers", server.AuthMiddleware(http.HandlerFunc(server.HandleListSubscribers)))
+	server.mux.Handle("GET /accounts/{id}/notification-preferences", server.AuthMiddleware(http.HandlerFunc(server.HandleGetNotificationPreferences)))
+	server.mux.Handle("PUT /accounts/{id}/notification-preferences", server.AuthMiddleware(http.HandlerFunc(server.HandleUpdateNotificationPreferences)))
+	server.mux.HandleFunc("GET /unsubscribe", server.HandleGetEmailPreferences)
+	server.mux.HandleFunc("POST /unsubscribe", server.HandleUnsubscribeEmail)
+	server.mux.HandleFunc("PUT /unsubscribe", server.HandleUpdateEmailPreferences)
+
+	// Notification routes
+	server.mux.Handle("GET /notifications", server.AuthMiddleware(http.HandlerFunc(server.HandleListNotifications)))
+	server.mux.Handle("GET /notifications/unread", server.AuthMiddleware(http.HandlerFunc(server.HandleGetUnreadCounts)))
+	server.mux.Handle("GET /notifications/stream", server.AuthMiddleware(http.HandlerFunc(server.HandleStreamNotifications)))
+	server.mux.Handle("POST /notifications/read", server.AuthMiddleware(http.HandlerFunc(server.HandleMarkNotificationsRead)))
+	server.mux.Handle("POST /notifications/{id}/read", server.AuthMiddleware(http.HandlerFunc(server.HandleMarkNotificationRead)))
+
+	// Web Push routes
+	server.mux.HandleFunc("GET /push/vapid-public-key", server.HandleGetVAPIDPublicKey)
+	server.mux.Handle("GET /push/subscriptions", server.AuthMiddleware(http.HandlerFunc(server.HandleListPushSubscriptions)))
+	server.mux.Handle("POST /push/subscriptions", server.AuthMiddleware(http.HandlerFunc(server.HandleCreatePushSubscription)))
+	server.mux.Handle("DELETE /push/subscriptions/{id}", server.AuthMiddleware(http.HandlerFunc(server.HandleDeletePushSubscription)))
 	server.mux.Handle("POST /subscribe", server.AuthMiddleware(http.HandlerFunc(server.HandleSubscribe)))
 	server.mux.Handle("DELETE /subscribe", server.AuthMiddleware(http.HandlerFunc(server.HandleUnsubscribe)))
 
 	// Video routes
+	server.mux.HandleFunc("GET /videos", server.HandleSearchVideos)
+	server.mux.HandleFunc("GET /videos/trending", server.HandleListTrendingVideos)
 	server.mux.Handle("POST /videos", server.AuthMiddleware(http.HandlerFunc(server.HandleCreateVideo)))
+	server.mux.Handle("POST /videos/bulk", server.AuthMiddleware(http.HandlerFunc(server.HandleBulkVideos)))
+	server.mux.Handle("POST /videos/import", server.AuthMiddleware(http.HandlerFunc(server.HandleImportVideo)))
+	server.mux.Handle("GET /videos/import/{id}", server.AuthMiddleware(http.HandlerFunc(server.HandleGetImportProgress)))
 	server.mux.HandleFunc("GET /videos/{id}", server.HandleGetVideo)
+	server.mux.Handle("PATCH /videos/{id}", server.AuthMiddleware(http.HandlerFunc(server.HandleEditVideo)))
+	server.mux.HandleFunc("GET /videos/{id}/waveform", server.HandleGetWaveform)
+	server.mux.Handle("GET /videos/{id}/processing", server.AuthMiddleware(http.HandlerFunc(server.HandleGetProcessingStatus)))
+	server.mux.Handle("GET /videos/{id}/processing/stream", server.AuthMiddleware(http.HandlerFunc(server.HandleStreamProcessingStatus)))
+	server.mux.Handle("POST /videos/{id}/premiere", server.AuthMiddleware(http.HandlerFunc(server.HandleStartPremiere)))
+	server.mux.HandleFunc("POST /videos/{id}/views", server.HandleRecordView)
+	server.mux.Handle("POST /videos/{id}/reports", server.AuthMiddleware(http.HandlerFunc(server.HandleReportVideo)))
+	server.mux.Handle("GET /videos/{id}/stats", server.AuthMiddleware(http.HandlerFunc(server.HandleGetVideoStats)))
+	server.mux.HandleFunc("POST /events", server.HandleRecordEvents)
 
-}
+	// Admin routes
+	server.mux.Handle("GET /admin/storage/reconcile", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleReconcileStorage))))
+	server.mux.Handle("GET /admin/backups", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleListBackups))))
+	server.mux.Handle("POST /admin/backups", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleCreateBackup))))
+	server.mux.Handle("POST /admin/backups/{name}/restore", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleRestoreBackup))))
+	server.mux.Handle("GET /admin/janitor", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleGetJanitorStats))))
+	server.mux.Handle("GET /admin/stats", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleGetPlatformStats))))
+	server.mux.Handle("GET /admin/videos/deleted", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleListDeletedVideos))))
+	server.mux.Handle("POST /admin/videos/{id}/restore", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleRestoreVideo))))
+	server.mux.Handle("GET /admin/accounts/deleted", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleListDeletedAccounts))))
+	server.mux.Handle("POST /admin/accounts/{id}/restore", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleRestoreAccount))))
+	server.mux.Handle("GET /admin/jobs/failed", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleListFailedJobs))))
+	server.mux.Handle("POST /admin/jobs/{id}/requeue", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleRequeueJob))))
+	server.mux.Handle("GET /admin/moderation", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleListModerationQueue))))
+	server.mux.Handle("GET /admin/moderation/{type}/{id}", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleGetModerationTarget))))
+	server.mux.Handle("POST /admin/moderation/{type}/{id}/decisions", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleCreateModerationDecision))))
+	server.mux.Handle("GET /admin/blocked-words", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleListSiteBlockedWords))))
+	server.mux.Handle("POST /admin/blocked-words", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleCreateSiteBlockedWord))))
+	server.mux.Handle("DELETE /admin/blocked-words/{id}", server.AuthMiddleware(server.AdminMiddleware(http.HandlerFunc(server.HandleDeleteSiteBlockedWord))))
 
-// Start runs the HTTP server on a specific address
-func (server *Server) Start() error {
-	server.logger.Info(fmt.Sprintf("Server start at %s:%s", server.config.Domain, server.config.Port))
-	return http.ListenAndServe(fmt.Sprintf(":%s", server.config.Port), server.mux)
 }
 
-// WriteError writes an error response in JSON format
-func (server *Server) WriteError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": message,
+// Start runs the HTTP server on a specific address until ctx is cancelled (for example: on SIGTERM), then shuts it
+// down gracefully. HTTP/2 is negotiated on the TLS listener, and accepted without TLS (h2c) when enabled. The port
+// is only bound once the dependencies passed their self-check and the other subsystems are started
+func (server *Server) Start(ctx context.Context) error {
+	if err := server.selfCheck(ctx); err != nil {
+		return err
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(server.config.H2CEnabled)
+
+	httpServer := &http.Server{
+		Addr:      fmt.Sprintf(":%s", server.config.Port),
+		Handler:   server.TenantMiddleware(server.RateLimitMiddleware(server.BodyLimitMiddleware(server.TimeoutMiddleware(server.mux)))),
+		Protocols: protocols,
+	}
+
+	// The HTTP listener is registered last, so it's the first subsystem stopped: requests in flight are finished
+	// while the job workers and the cache are still running
+	serveErr := make(chan error, 1)
+	server.OnLifecycle(Hook{
+		Name: "http",
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", httpServer.Addr)
+			if err != nil {
+				return err
+			}
+
+			if server.config.TLSCertFile != "" {
+				server.logger.Info(fmt.Sprintf("Server start at %s:%s (TLS, HTTP/2)", server.config.Domain, server.config.Port))
+				go func() {
+					serveErr <- httpServer.ServeTLS(listener, server.config.TLSCertFile, server.config.TLSKeyFile)
+				}()
+				return nil
+			}
+			server.logger.Info(fmt.Sprintf("Server start at %s:%s", server.config.Domain, server.config.Port), "h2c",
+				server.config.H2CEnabled)
+			go func() { serveErr <- httpServer.Serve(listener) }()
+			return nil
+		},
+		Stop: httpServer.Shutdown,
 	})
+
+	// The subsystems outlive ctx, they are only stopped by the shutdown
+	if err := server.startHooks(context.WithoutCancel(ctx)); err != nil {
+		return err
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		server.logger.Info("Server shutting down", "timeout", server.config.ShutdownTimeout)
+	case err = <-serveErr:
+	}
+	return errors.Join(err, server.shutdown())
+}
+
+// StartWorker runs the media jobs until ctx is cancelled, once the dependencies passed their self-check. The job
+// workers are then stopped gracefully
+func (server *Server) StartWorker(ctx context.Context) error {
+	if err := server.selfCheck(ctx); err != nil {
+		return err
+	}
+	if err := server.startHooks(context.WithoutCancel(ctx)); err != nil {
+		return err
+	}
+	server.logger.Info("Transcoding worker started", "job_driver", server.config.JobDriver)
+
+	<-ctx.Done()
+	server.logger.Info("Worker shutting down", "timeout", server.config.ShutdownTimeout)
+	return server.shutdown()
+}
+
+// Helper method: stop the subsystems within the shutdown timeout
+func (server *Server) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), server.config.ShutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
 }
 
 // WriteJSON writes a JSON response with the given status code and data in any data type
@@ -124,12 +456,19 @@ func (server *Server) checkAccountStatus(w http.ResponseWriter, r *http.Request,
 
 	// Check if account status is active before processing request
 	if oldProfile.Status != db.AccountStatusActive {
+		server.WriteErrorCode(w, http.StatusForbidden, CodeAccountNotActive, "Account is not active", nil)
 		return nil, false
 	}
 
 	return &oldProfile, true
 }
 
+// Method to get the local path of a stored file. ffmpeg reads and writes the media files in the resource path,
+// which is the root of the local storage
+func (server *Server) localPath(key string) string {
+	return server.localStorage.LocalPath(key)
+}
+
 // Method to check if the account ID provided in the request data match with the ID extract from the access token
 func (server *Server) checkIDMatch(w http.ResponseWriter, r *http.Request, accountID string) bool {
 	// Get the account ID from the claims and check if they match with the account ID given in request data
@@ -141,3 +480,19 @@ func (server *Server) checkIDMatch(w http.ResponseWriter, r *http.Request, accou
 
 	return true
 }
+
+// Method to check the type of an uploaded file from its magic bytes ('isType' is one of file.IsImage or
+// file.IsVideo) instead of trusting its name. It writes 415 with 'message' if the file is not of the expected type
+func (server *Server) checkUploadType(w http.ResponseWriter, upload io.ReadSeeker,
+	isType func(io.ReadSeeker) (bool, error), message string) bool {
+	ok, err := isType(upload)
+	if err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Failed to read uploaded file")
+		return false
+	}
+	if !ok {
+		server.WriteError(w, http.StatusUnsupportedMediaType, message)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Max length of the device details kept in a session, as in the session table
+const (
+	maxUserAgentLength = 255
+	maxIPLength        = 45
+)
+
+// Helper method: open a session for a login on the device of the request, and create its access token and refresh
+// token. Only the hash of the refresh token is stored
+func (server *Server) createSession(r *http.Request, accountID uuid.UUID, version int32) (string, string, error) {
+	sessionID := uuid.New()
+	accessToken, err := server.jwtService.CreateToken(accountID.String(), "access-token", sessionID.String(),
+		int(version), server.jwtService.TokenExpirationTime)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create JWT access token: %w", err)
+	}
+	refreshToken, err := server.jwtService.CreateToken(accountID.String(), "refresh-token", sessionID.String(),
+		int(version), server.jwtService.RefreshTokenExpirationTime)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create JWT refresh token: %w", err)
+	}
+
+	err = server.query.CreateSession(r.Context(), db.CreateSessionParams{
+		SessionID: sessionID,
+		AccountID: accountID,
+		TokenHash: security.HashToken(refreshToken),
+		UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+		Ip:        truncate(server.clientIP(r), maxIPLength),
+		ExpiresAt: time.Now().Add(server.jwtService.RefreshTokenExpirationTime),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// Helper function: cut a string to its first n characters
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// A session (logged in device) in the list of sessions
+type sessionResult struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // the session of the access token of the request
+}
+
+// HandleListSessions lists the active sessions (logged in devices) of the requester, most recently used first.
+// endpoint: GET /auth/sessions
+// Success: 200
+// Fail: 401, 500
+func (server *Server) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(clKey).(*security.CustomClaims)
+	var accountID uuid.UUID
+	accountID.Scan(claims.ID)
+
+	sessions, err := server.query.ListSessions(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("GET /auth/sessions: failed to list sessions", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]sessionResult, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, sessionResult{
+			ID:         session.SessionID.String(),
+			UserAgent:  session.UserAgent,
+			IP:         session.Ip,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.SessionID.String() == claims.SessionID,
+		})
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// HandleRevokeSession revokes a session of the requester, which logs out its device: its access token and refresh
+// token are rejected from now on.
+// endpoint: DELETE /auth/sessions/{id}
+// Success: 200
+// Fail: 400, 401, 404, 500
+func (server *Server) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	var accountID, sessionID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	if err := sessionID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	affected, err := server.query.RevokeSession(r.Context(), db.RevokeSessionParams{
+		SessionID: sessionID,
+		AccountID: accountID,
+	})
+	if err != nil {
+		server.logger.Error("DELETE /auth/sessions/{id}: failed to revoke session", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if affected == 0 {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeSessionNotFound, "Session not found", nil)
+		return
+	}
+	server.invalidateSession(r.Context(), sessionID)
+
+	server.WriteJSON(w, http.StatusOK, "Session revoked successfully")
+}
+
+// HandleRevokeSessions revokes all the sessions of the requester, including the current one, which logs out all
+// its devices. The tokens issued before the sessions are revoked too.
+// endpoint: DELETE /auth/sessions
+// Success: 200
+// Fail: 401, 500
+func (server *Server) HandleRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+
+	err := server.query.ExecTx(r.Context(), func(q *db.Queries) error {
+		if err := q.RevokeAccountSessions(r.Context(), accountID); err != nil {
+			return err
+		}
+		return q.IncrementTokenVersion(r.Context(), accountID)
+	})
+	if err != nil {
+		server.logger.Error("DELETE /auth/sessions: failed to revoke sessions", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	server.invalidateTokenVersion(r.Context(), accountID)
+
+	server.WriteJSON(w, http.StatusOK, "Logged out of all sessions successfully")
+}
+
+// Method to delete the sessions which are expired or revoked, they can't be used anymore
+func (server *Server) runSessionCleanupJob(ctx context.Context) {
+	deleted, err := server.query.DeleteStaleSessions(ctx, time.Now())
+	if err != nil {
+		server.logger.Error("sessions: failed to delete stale sessions", "error", err)
+		return
+	}
+	server.logger.Info("sessions: stale sessions deleted", "deleted", deleted)
+}
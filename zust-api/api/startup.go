@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+	"zust/service/security"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Delay between two connection attempts to the database on boot, doubled after each failure up to the max delay
+const (
+	dbRetryDelay    = 500 * time.Millisecond
+	dbRetryMaxDelay = 10 * time.Second
+)
+
+// ConnectDatabase opens the connection pool of the database and waits until it can be reached, retrying with
+// backoff for DbConnectTimeout, so the server doesn't start while the database is down and fail its first requests.
+// The queries run through database/sql on top of the pool. The returned function closes the pool
+func ConnectDatabase(ctx context.Context, config *security.Config, logger *slog.Logger) (*sql.DB, func(), error) {
+	poolConfig, err := pgxpool.ParseConfig(config.DbSource)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.DbMaxConns > 0 {
+		poolConfig.MaxConns = int32(config.DbMaxConns)
+	}
+	poolConfig.MinConns = int32(config.DbMinConns)
+	poolConfig.MaxConnLifetime = config.DbMaxConnLifetime
+	poolConfig.MaxConnIdleTime = config.DbMaxConnIdleTime
+	poolConfig.HealthCheckPeriod = config.DbHealthCheckPeriod
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn := stdlib.OpenDBFromPool(pool)
+	closeDB := func() {
+		conn.Close()
+		pool.Close()
+	}
+
+	deadline := time.Now().Add(config.DbConnectTimeout)
+	delay := dbRetryDelay
+	for {
+		err = pool.Ping(ctx)
+		if err == nil {
+			return conn, closeDB, nil
+		}
+		if time.Now().Add(delay).After(deadline) {
+			closeDB()
+			return nil, nil, fmt.Errorf("database is not reachable after %s: %w", config.DbConnectTimeout, err)
+		}
+
+		logger.Warn("database is not reachable yet, retrying", "retry_in", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			closeDB()
+			return nil, nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, dbRetryMaxDelay)
+	}
+}
+
+// Helper method: check the dependencies of the server before it binds its port or pulls jobs, so a broken
+// deployment fails on boot instead of on its first requests
+func (server *Server) selfCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	for name, check := range server.dependencyChecks(ctx) {
+		if err := check(); err != nil {
+			return fmt.Errorf("self-check %s failed: %w", name, err)
+		}
+	}
+	server.logger.Info("Self-check passed")
+	return nil
+}
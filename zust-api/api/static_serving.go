@@ -1,19 +1,271 @@
 package api
 
 import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net/http"
+	"path"
+	"strings"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/file"
+	"zust/service/security"
+
+	"github.com/google/uuid"
 )
 
-// HandleMedia handle static serving media file
-// endpoint: GET /media/{id}
-// Fail: 404
+// HandleMedia handle static serving media file. Images (avatars, covers and thumbnails) can be resized and converted
+// on the fly with the 'w', 'h' and 'format' query parameters, for example: ?w=320&h=180&format=webp.
+// Files of a private (or not yet published) video require the access token of the publisher, in the Authorization
+// header or the 'access_token' query parameter
+// endpoint: GET /media/{id}?w=...&h=...&format=...&access_token=...
+// Fail: 400, 401, 403, 404, 500, 503
 func (server *Server) HandleMedia(w http.ResponseWriter, r *http.Request) {
-	// Get the ID from path parameter
-	id := r.PathValue("id")
+	if !server.checkOriginAuth(w, r) {
+		return
+	}
+
+	// Get the storage key of the file from the ID in path parameter
+	key := server.mediaFileKey(r.Context(), r.PathValue("id"))
+	if !server.checkMediaAccess(w, r, key) {
+		return
+	}
+
+	// Serve a variant of the image if a transformation is requested
+	if query := r.URL.Query(); query.Has("w") || query.Has("h") || query.Has("format") {
+		server.serveTransformedImage(w, r, key)
+		return
+	}
+
+	// Serve file
+	server.serveStoredFile(w, r, key)
+}
+
+// Content types of streaming files, which are not registered in the mime package by default
+var streamingContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".m4s":  "video/iso.segment",
+	".mpd":  "application/dash+xml",
+}
+
+// HandleMediaFile handle static serving a file inside a media directory, for example: HLS playlists and segments.
+// Like HandleMedia, the files of a private video require the access token of the publisher
+// endpoint: GET /media/{id}/{path...}?access_token=...
+// Fail: 401, 403, 404, 500, 503
+func (server *Server) HandleMediaFile(w http.ResponseWriter, r *http.Request) {
+	if !server.checkOriginAuth(w, r) {
+		return
+	}
+
+	// Get the media directory
+	dir := server.mediaFileKey(r.Context(), r.PathValue("id"))
+	if dir == "" {
+		http.NotFound(w, r)
+		return
+	}
 
-	// Get file path
-	path := server.mediaService.ExtractFilePath(id)
+	// Clean the path as an absolute path first, so it can never escape the media directory
+	key := path.Join(dir, path.Clean("/"+r.PathValue("path")))
+	if !server.checkMediaAccess(w, r, key) {
+		return
+	}
+
+	if contentType, ok := streamingContentTypes[path.Ext(key)]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
 
 	// Serve file
-	http.ServeFile(w, r, path)
+	server.serveStoredFile(w, r, key)
+}
+
+// Header carrying the shared secret of the CDN when it fetches media from the origin
+const originAuthHeader = "X-Origin-Auth"
+
+// Helper method: when the media is served through a CDN with an origin secret, only the CDN can fetch media from
+// the server, so the signed links can't be bypassed by requesting the origin directly
+func (server *Server) checkOriginAuth(w http.ResponseWriter, r *http.Request) bool {
+	if server.config.CDNOriginSecret == "" {
+		return true
+	}
+
+	secret := r.Header.Get(originAuthHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(server.config.CDNOriginSecret)) != 1 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Helper method: check if the requester can access a stored file. Files of a video are served to everyone only
+// when the video is published and not private, otherwise only its publisher and the admins can access them. The
+// files of an age restricted video are only served to signed-in viewers.
+// Files of an account (avatar and cover) are always public, any other key is not found, so a forged key (for
+// example with the shard or the tenant directory) can't reach the files of a video without the checks
+func (server *Server) checkMediaAccess(w http.ResponseWriter, r *http.Request, key string) bool {
+	accID, videoID, ok := file.ParseVideoKey(key)
+	if !ok {
+		if !file.IsAccountKey(key) {
+			http.NotFound(w, r)
+			return false
+		}
+		return true
+	}
+
+	access, err := server.getVideoAccess(r.Context(), videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return false
+		}
+
+		server.logger.Error("GET /media/{id}: failed to get video access", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+
+	// Files which don't belong to the publisher of the video are never served (deleted videos are not found)
+	if access.PublisherID != accID {
+		http.NotFound(w, r)
+		return false
+	}
+	if access.Status != db.VideoStatusPublished || access.Visibility == db.VideoVisibilityPrivate {
+		if !server.checkRestrictedAccess(w, r, access.PublisherID) {
+			return false
+		}
+	} else if access.AgeRestricted {
+		// Age restricted files are only served to signed-in viewers
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if server.mediaClaims(r) == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return false
+		}
+	}
+
+	// The renditions of a cold video are restored from the cold storage on demand
+	if access.ColdAt.Valid && server.coldStorage != nil && !file.Exists(server.storage, key) {
+		if err := server.thawVideo(w, r, videoID, access.PublisherID); err != nil {
+			server.logger.Error("GET /media/{id}: failed to enqueue thaw job", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return false
+		}
+
+		// Meanwhile, the renditions are served straight from a cold storage which can sign links. The HLS files
+		// are not, the links of their segments are relative to the playlist and can't carry the signature
+		if linker, ok := server.coldStorage.(file.Linker); ok && path.Ext(key) == ".mp4" &&
+			file.Exists(server.coldStorage, key) {
+			w.Header().Set("Cache-Control", "private, no-store")
+			http.Redirect(w, r, linker.SignedURL(key, server.config.AzureSASTTL), http.StatusTemporaryRedirect)
+			return false
+		}
+		http.Error(w, "Video is being restored, please try again later", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// Helper method: check if the requester is the publisher of a restricted video (private, or not published) or an
+// admin
+func (server *Server) checkRestrictedAccess(w http.ResponseWriter, r *http.Request, publisherID uuid.UUID) bool {
+	// Restricted files must not be kept by shared caches (CDN, proxies)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	claims := server.mediaClaims(r)
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if claims.ID == publisherID.String() {
+		return true
+	}
+
+	var requesterID uuid.UUID
+	requesterID.Scan(claims.ID)
+	role, err := server.query.GetAccountRole(r.Context(), requesterID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		server.logger.Error("GET /media/{id}: failed to get account role", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if role != db.AccountRoleAdmin {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Helper method: get the claims of the access token of a media request, from the Authorization header or the
+// 'access_token' query parameter (for the players which can't set headers). It returns nil if the token is
+// missing or invalid
+func (server *Server) mediaClaims(r *http.Request) *security.CustomClaims {
+	token := r.URL.Query().Get("access_token")
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		token = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if token == "" {
+		return nil
+	}
+
+	claims, err := server.jwtService.VerifyToken(r.Context(), token, server.tokenVersions())
+	if err != nil || claims.TokenType != "access-token" {
+		return nil
+	}
+	return claims
+}
+
+// Helper method: stream a stored file from the storage, Range and conditional requests are handled by
+// http.ServeContent on top of the seekable reader, so it doesn't depend on the file being on the local disk
+func (server *Server) serveStoredFile(w http.ResponseWriter, r *http.Request, key string) {
+	if key == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, info, err := server.openStoredFile(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+
+		server.logger.Error("GET /media/{id}: failed to open media file", "key", key, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	server.trackRange(r, key)
+
+	// The ETag changes whenever the file is rewritten, If-None-Match and If-Modified-Since are answered with 304
+	// by http.ServeContent
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime.UnixNano(), info.Size))
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", server.cacheControl(key))
+	}
+
+	// Throttle the response so a few clients can't saturate the uplink of the origin
+	if limit := server.mediaRateLimit(key); limit > 0 {
+		w = newThrottledWriter(w, r, limit)
+	}
+
+	http.ServeContent(w, r, info.Name, info.ModTime, file)
+}
+
+// Max age of the media files which never change under the same URL (one year, the maximum honored by browsers)
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// Helper method: get the Cache-Control of a stored file. Video files and streaming segments are written once by
+// the transcoder, so they can be cached as immutable; other files (avatars, covers, thumbnails, playlists,
+// subtitles) can be replaced under the same URL, so they are revalidated after the configured max age
+func (server *Server) cacheControl(key string) string {
+	ext := path.Ext(key)
+	immutable := ext == ".m4s" || ext == ".mp4"
+	if server.config.MediaCacheImmutable && immutable {
+		return fmt.Sprintf("public, max-age=%d, immutable", int(immutableMaxAge.Seconds()))
+	}
+	if server.config.MediaCacheMaxAge <= 0 {
+		return "no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(server.config.MediaCacheMaxAge.Seconds()))
 }
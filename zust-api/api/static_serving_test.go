@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"zust/service/file"
+	"zust/service/security"
+)
+
+func TestCheckMediaAccessDeniesUnknownKeys(t *testing.T) {
+	const (
+		accID   = "0b8f5a4e-7d3c-4a8e-9a43-2f1d6c5e8b71"
+		videoID = "5c2e7f10-3b9d-4e61-8f2a-9d4b1c7e6a05"
+	)
+	shard := strings.ReplaceAll(file.ShardDir(accID), "/", ":") // for example: 3f:a2
+	media := &file.MediaService{}
+
+	tests := []struct {
+		name    string
+		id      string // decoded media ID
+		allowed bool
+	}{
+		{name: "avatar", id: accID + ":avatar:avatar.png", allowed: true},
+		{name: "cover", id: accID + ":cover:cover.png", allowed: true},
+		{name: "sharded video", id: fmt.Sprintf("%s:%s/resource/%s.mp4", shard, accID, videoID)},
+		{name: "sharded thumbnail", id: fmt.Sprintf("%s:%s/thumbnail/%s.png", shard, accID, videoID)},
+		{name: "tenant video", id: fmt.Sprintf("tenants:club:%s/resource/%s.mp4", accID, videoID)},
+		{name: "tenant hls", id: fmt.Sprintf("tenants:club:%s/hls/%s/master.m3u8", accID, videoID)},
+		{name: "other account file", id: accID + ":watermark.png:x"},
+		{name: "account file in sub directory", id: accID + ":branding:intro.mp4"},
+		{name: "not an account", id: "assets:avatar:avatar.png"},
+		{name: "malformed", id: "avatar.png"},
+	}
+
+	server := &Server{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := media.ExtractFileKey(security.Encode(test.id))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/media/x", nil)
+
+			allowed := server.checkMediaAccess(w, r, key)
+			if allowed != test.allowed {
+				t.Fatalf("checkMediaAccess(%q) = %v, want %v", key, allowed, test.allowed)
+			}
+			if !allowed && w.Code != http.StatusNotFound {
+				t.Fatalf("checkMediaAccess(%q) status = %d, want %d", key, w.Code, http.StatusNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Supported time range for statistics, default to 28 days
+var statsRanges = map[string]time.Duration{
+	"7d":   7 * 24 * time.Hour,
+	"28d":  28 * 24 * time.Hour,
+	"90d":  90 * 24 * time.Hour,
+	"365d": 365 * 24 * time.Hour,
+}
+
+// Daily views of a video
+type dailyViews struct {
+	Day   string `json:"day"`
+	Views int    `json:"views"`
+}
+
+// Number of views coming from a traffic source
+type trafficSource struct {
+	Source string `json:"source"`
+	Views  int    `json:"views"`
+}
+
+// Watch time (in seconds) of a video in a day, rolled up from the playback events, and its average per playback
+type dailyWatchTime struct {
+	Day                 string  `json:"day"`
+	WatchTime           float64 `json:"watch_time"`
+	AverageViewDuration float64 `json:"average_view_duration"`
+}
+
+// Number of player events of a type, and number of playbacks with at least one of them
+type playbackEvents struct {
+	Type      string `json:"type"`
+	Events    int    `json:"events"`
+	Playbacks int    `json:"playbacks"`
+}
+
+// Response body for GetVideoStats
+type videoStatsResponse struct {
+	VideoID              string           `json:"video_id"`
+	Range                string           `json:"range"`
+	TotalViews           int              `json:"total_views"`
+	AverageWatchDuration float64          `json:"average_watch_duration"`
+	DailyViews           []dailyViews     `json:"daily_views"`
+	TrafficSources       []trafficSource  `json:"traffic_sources"`
+	PlaybackEvents       []playbackEvents `json:"playback_events"`
+	WatchTime            float64          `json:"watch_time"`
+	AverageViewDuration  float64          `json:"average_view_duration"`
+	DailyWatchTime       []dailyWatchTime `json:"daily_watch_time"`
+}
+
+// Request body for RecordView, the source defaults to direct
+type recordViewRequest struct {
+	WatchDuration int    `json:"watch_duration" validate:"min=0"`
+	Source        string `json:"source" validate:"omitempty,max=20"`
+}
+
+// How far the time of an event sent by the player can be from the time of the server: events older than the window
+// (sent late from a buffer) or in the future (wrong clock) are recorded at the time they are received
+const (
+	eventPastWindow   = 24 * time.Hour
+	eventFutureWindow = time.Minute
+)
+
+// A player event: the positions are in seconds, the seek events have the position before the seek and the quality
+// switch events the new quality
+type playbackEventRequest struct {
+	Type       string    `json:"type" validate:"required,oneof=play pause seek quality_switch"`
+	VideoID    uuid.UUID `json:"video_id" validate:"required"`
+	PlaybackID uuid.UUID `json:"playback_id" validate:"required"`
+	Position   float64   `json:"position" validate:"min=0"`
+	SeekFrom   *float64  `json:"seek_from" validate:"required_if=Type seek,omitempty,min=0"`
+	Quality    string    `json:"quality" validate:"required_if=Type quality_switch,omitempty,max=10"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Request body for RecordEvents, at most 100 events per request
+type recordEventsRequest struct {
+	Events []playbackEventRequest `json:"events" validate:"required,min=1,max=100,dive"`
+}
+
+// Response body for RecordEvents
+type recordEventsResponse struct {
+	Accepted int `json:"accepted"`
+}
+
+// HandleRecordView records a view of a published video, sent by the player (playback ping). The access token is
+// optional, guests are recorded without account. Views are buffered and inserted in bulk, so a recorded view
+// appears in the statistics after a few seconds.
+// endpoint: POST /videos/{id}/views
+// Success: 202
+// Fail: 400, 404, 500, 503
+func (server *Server) HandleRecordView(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Get request body
+	var req recordViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+	if req.Source == "" {
+		req.Source = "direct"
+	}
+
+	// Only the views of published videos are recorded
+	video, err := server.getVideo(r.Context(), videoID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		server.logger.Error("POST /videos/{id}/views: failed to get video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if err != nil || video.Status != db.VideoStatusPublished {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+		return
+	}
+
+	// The watch duration can't be longer than the video
+	event := db.CreateViewEventsParams{
+		VideoID:       videoID,
+		WatchDuration: min(int32(req.WatchDuration), video.Duration),
+		Source:        req.Source,
+		CreatedAt:     time.Now(),
+	}
+	if claims := server.mediaClaims(r); claims != nil {
+		if ok, _ := server.claimsInTenant(r.Context(), claims); ok {
+			event.AccountID.Valid = event.AccountID.UUID.Scan(claims.ID) == nil
+		}
+	}
+
+	if !server.events.record(event) {
+		server.WriteErrorCode(w, http.StatusServiceUnavailable, CodeUnavailable, "Too many views to record, try again later",
+			nil)
+		return
+	}
+	server.WriteJSON(w, http.StatusAccepted, "View recorded")
+}
+
+// HandleRecordEvents records a batch of player events (play, pause, seek, quality switch), sent by the player
+// every few seconds. The access token is optional, guests are recorded without account. The events of unpublished
+// or unknown videos are skipped, and the number of accepted events is returned. Like the views, the events are
+// buffered and inserted in bulk.
+// endpoint: POST /events
+// Success: 202
+// Fail: 400, 500, 503
+func (server *Server) HandleRecordEvents(w http.ResponseWriter, r *http.Request) {
+	// Get request body
+	var req recordEventsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Drop the whole batch when it doesn't fit in the buffer, so the player can send it again instead of losing a
+	// part of it
+	if server.playbackEvents.available() < len(req.Events) {
+		server.playbackEvents.dropped.Add(uint64(len(req.Events)))
+		server.WriteErrorCode(w, http.StatusServiceUnavailable, CodeUnavailable, "Too many events to record, try again later",
+			nil)
+		return
+	}
+
+	var accountID uuid.NullUUID
+	if claims := server.mediaClaims(r); claims != nil {
+		if ok, _ := server.claimsInTenant(r.Context(), claims); ok {
+			accountID.Valid = accountID.UUID.Scan(claims.ID) == nil
+		}
+	}
+
+	// Only the events of published videos are recorded, each video is fetched once
+	videos := make(map[uuid.UUID]*db.GetVideoRow)
+	now := time.Now()
+	accepted := 0
+	for _, event := range req.Events {
+		video, ok := videos[event.VideoID]
+		if !ok {
+			found, err := server.getVideo(r.Context(), event.VideoID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				server.logger.Error("POST /events: failed to get video", "error", err)
+				server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			if err == nil && found.Status == db.VideoStatusPublished {
+				video = &found
+			}
+			videos[event.VideoID] = video
+		}
+		if video == nil {
+			continue
+		}
+
+		// The positions can't be past the end of the video
+		params := db.CreatePlaybackEventsParams{
+			VideoID:    event.VideoID,
+			AccountID:  accountID,
+			PlaybackID: event.PlaybackID,
+			Type:       event.Type,
+			Position:   min(event.Position, float64(video.Duration)),
+			OccurredAt: event.Timestamp,
+			CreatedAt:  now,
+		}
+		if event.SeekFrom != nil {
+			params.SeekFrom = sql.NullFloat64{Float64: min(*event.SeekFrom, float64(video.Duration)), Valid: true}
+		}
+		if event.Quality != "" {
+			params.Quality = sql.NullString{String: event.Quality, Valid: true}
+		}
+		if params.OccurredAt.Before(now.Add(-eventPastWindow)) || params.OccurredAt.After(now.Add(eventFutureWindow)) {
+			params.OccurredAt = now
+		}
+
+		if !server.playbackEvents.record(params) {
+			break
+		}
+		accepted++
+	}
+
+	server.WriteJSON(w, http.StatusAccepted, recordEventsResponse{Accepted: accepted})
+}
+
+// HandleGetVideoStats returns the statistics of a video, which is only available to its publisher.
+// endpoint: GET /videos/{id}/stats?range=7d|28d|90d|365d
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleGetVideoStats(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Get time range
+	rangeParam := r.URL.Query().Get("range")
+	if rangeParam == "" {
+		rangeParam = "28d"
+	}
+	duration, ok := statsRanges[rangeParam]
+	if !ok {
+		server.WriteError(w, http.StatusBadRequest, "Unsupported range, only accept 7d, 28d, 90d or 365d")
+		return
+	}
+	since := time.Now().Add(-duration)
+
+	// Get video to check if the requester is the publisher
+	video, err := server.getVideo(r.Context(), videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+			return
+		}
+
+		server.logger.Error("GET /videos/{id}/stats: failed to get video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	claims := r.Context().Value(clKey).(*security.CustomClaims)
+	if claims.ID != video.AccountID.String() {
+		server.WriteError(w, http.StatusForbidden, "Only the publisher can view the statistics of this video")
+		return
+	}
+
+	// Aggregate statistics from view events
+	summary, err := server.query.GetVideoWatchSummary(r.Context(), db.GetVideoWatchSummaryParams{
+		VideoID: videoID,
+		Since:   since,
+	})
+	if err != nil {
+		server.logger.Error("GET /videos/{id}/stats: failed to get watch summary", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	daily, err := server.query.GetVideoDailyViews(r.Context(), db.GetVideoDailyViewsParams{
+		VideoID: videoID,
+		Since:   since,
+	})
+	if err != nil {
+		server.logger.Error("GET /videos/{id}/stats: failed to get daily views", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	sources, err := server.query.GetVideoTrafficSources(r.Context(), db.GetVideoTrafficSourcesParams{
+		VideoID: videoID,
+		Since:   since,
+	})
+	if err != nil {
+		server.logger.Error("GET /videos/{id}/stats: failed to get traffic sources", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	events, err := server.query.GetVideoPlaybackEvents(r.Context(), db.GetVideoPlaybackEventsParams{
+		VideoID: videoID,
+		Since:   since,
+	})
+	if err != nil {
+		server.logger.Error("GET /videos/{id}/stats: failed to get playback events", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	watchTime, err := server.query.GetVideoDailyWatchTime(r.Context(), db.GetVideoDailyWatchTimeParams{
+		VideoID: videoID,
+		Since:   since,
+	})
+	if err != nil {
+		server.logger.Error("GET /videos/{id}/stats: failed to get daily watch time", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Return the result back to client
+	data := videoStatsResponse{
+		VideoID:              videoID.String(),
+		Range:                rangeParam,
+		TotalViews:           int(summary.TotalViews),
+		AverageWatchDuration: summary.AverageWatchDuration,
+		DailyViews:           make([]dailyViews, 0, len(daily)),
+		TrafficSources:       make([]trafficSource, 0, len(sources)),
+		PlaybackEvents:       make([]playbackEvents, 0, len(events)),
+		DailyWatchTime:       make([]dailyWatchTime, 0, len(watchTime)),
+	}
+	for _, day := range daily {
+		data.DailyViews = append(data.DailyViews, dailyViews{Day: day.Day.Format(time.DateOnly), Views: int(day.Views)})
+	}
+	for _, source := range sources {
+		data.TrafficSources = append(data.TrafficSources, trafficSource{Source: source.Source, Views: int(source.Views)})
+	}
+	for _, event := range events {
+		data.PlaybackEvents = append(data.PlaybackEvents, playbackEvents{
+			Type:      event.Type,
+			Events:    int(event.Events),
+			Playbacks: int(event.Playbacks),
+		})
+	}
+	var playbacks int64
+	for _, day := range watchTime {
+		data.DailyWatchTime = append(data.DailyWatchTime, dailyWatchTime{
+			Day:                 day.Day.Format(time.DateOnly),
+			WatchTime:           day.WatchTime,
+			AverageViewDuration: averageViewDuration(day.WatchTime, day.Playbacks),
+		})
+		data.WatchTime += day.WatchTime
+		playbacks += day.Playbacks
+	}
+	data.AverageViewDuration = averageViewDuration(data.WatchTime, playbacks)
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Helper function: get the average watch time of the playbacks, 0 without playback
+func averageViewDuration(watchTime float64, playbacks int64) float64 {
+	if playbacks == 0 {
+		return 0
+	}
+	return watchTime / float64(playbacks)
+}
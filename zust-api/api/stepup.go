@@ -0,0 +1,426 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Step-up codes: a code expires after stepUpCodeTTL or stepUpMaxAttempts wrong tries, and at most stepUpMaxSends
+// codes are sent to an account per stepUpSendWindow (each SMS has a cost)
+const (
+	stepUpCodeDigits      = 6
+	stepUpCodeTTL         = 10 * time.Minute
+	stepUpMaxAttempts     = 5
+	stepUpMaxSends        = 5
+	stepUpSendWindow      = time.Hour
+	stepUpCleanupInterval = time.Hour
+)
+
+// Headers of a sensitive action carrying its step-up code
+const (
+	headerStepUpChallenge = "X-Step-Up-Challenge"
+	headerStepUpCode      = "X-Step-Up-Code"
+)
+
+var (
+	// Error when too many codes were sent to an account recently
+	errStepUpRateLimited = errors.New("too many step-up codes sent")
+
+	// Error of a code which is wrong, expired, already used or for another action
+	errStepUpInvalid = errors.New("invalid step-up code")
+)
+
+// What a code allows, written in the SMS
+var stepUpActions = map[db.StepUpPurpose]string{
+	db.StepUpPurposePhoneVerification: "verify your phone number",
+	db.StepUpPurposePhoneChange:       "change your phone number",
+	db.StepUpPurposePasswordChange:    "change your password",
+	db.StepUpPurposeAccountDeletion:   "delete your account",
+}
+
+// Request body to ask for a step-up code
+type stepUpRequest struct {
+	Purpose db.StepUpPurpose `json:"purpose" validate:"required,oneof=phone_change password_change account_deletion"`
+}
+
+// Response body of a code sent by SMS, the phone number is masked
+type stepUpResponse struct {
+	ChallengeID uuid.UUID        `json:"challenge_id"`
+	Purpose     db.StepUpPurpose `json:"purpose"`
+	PhoneNumber string           `json:"phone_number"`
+	ExpiresAt   time.Time        `json:"expires_at"`
+}
+
+// Request body to set the phone number of an account
+type setPhoneNumberRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164"`
+}
+
+// Request body to confirm a phone number with the code sent to it
+type verifyPhoneNumberRequest struct {
+	ChallengeID uuid.UUID `json:"challenge_id" validate:"required"`
+	Code        string    `json:"code" validate:"required,len=6,numeric"`
+}
+
+// HandleRequestStepUp sends a code to the verified phone number of the requester, the code is then sent along the
+// sensitive action (in the X-Step-Up-Challenge and X-Step-Up-Code headers).
+// endpoint: POST /accounts/{id}/step-up
+// Success: 202
+// Fail: 400, 401, 403, 404, 409, 429, 500
+func (server *Server) HandleRequestStepUp(w http.ResponseWriter, r *http.Request) {
+	if server.sms == nil {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeSMSNotEnabled, "SMS is not enabled", nil)
+		return
+	}
+
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Get request body
+	var req stepUpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /accounts/{id}/step-up"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	credentials, err := server.query.GetAccountCredentials(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("POST /accounts/{id}/step-up: failed to get account credentials", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !credentials.PhoneVerifiedAt.Valid {
+		server.WriteErrorCode(w, http.StatusConflict, CodePhoneNotVerified, "Account has no verified phone number", nil)
+		return
+	}
+
+	server.sendStepUpCode(w, r, accountID, req.Purpose, credentials.PhoneNumber.String)
+}
+
+// HandleSetPhoneNumber sends a code to a new phone number of the requester, it's set once the code is confirmed.
+// Replacing a verified phone number is a sensitive action.
+// endpoint: PUT /accounts/{id}/phone
+// Success: 202
+// Fail: 400, 401, 403, 404, 429, 500
+func (server *Server) HandleSetPhoneNumber(w http.ResponseWriter, r *http.Request) {
+	if server.sms == nil {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeSMSNotEnabled, "SMS is not enabled", nil)
+		return
+	}
+
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Get request body
+	var req setPhoneNumberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "PUT /accounts/{id}/phone"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	credentials, err := server.query.GetAccountCredentials(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/phone: failed to get account credentials", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !server.requireStepUp(w, r, accountID, db.StepUpPurposePhoneChange, credentials) {
+		return
+	}
+
+	server.sendStepUpCode(w, r, accountID, db.StepUpPurposePhoneVerification, req.PhoneNumber)
+}
+
+// HandleVerifyPhoneNumber confirms the new phone number of the requester with the code sent to it, the phone
+// number is then set as verified.
+// endpoint: POST /accounts/{id}/phone/verify
+// Success: 200
+// Fail: 400, 401, 403, 500
+func (server *Server) HandleVerifyPhoneNumber(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Get request body
+	var req verifyPhoneNumberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /accounts/{id}/phone/verify"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	challenge, err := server.checkStepUpCode(r.Context(), accountID, db.StepUpPurposePhoneVerification,
+		req.ChallengeID, req.Code)
+	if err != nil {
+		if errors.Is(err, errStepUpInvalid) {
+			server.WriteErrorCode(w, http.StatusForbidden, CodeStepUpInvalid, "Invalid or expired verification code", nil)
+			return
+		}
+		server.logger.Error("POST /accounts/{id}/phone/verify: failed to check code", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	err = server.query.SetPhoneNumber(r.Context(), db.SetPhoneNumberParams{
+		AccountID:   accountID,
+		PhoneNumber: sql.NullString{String: challenge.PhoneNumber, Valid: true},
+	})
+	if err != nil {
+		server.logger.Error("POST /accounts/{id}/phone/verify: failed to set phone number", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Phone number verified successfully")
+}
+
+// HandleRemovePhoneNumber removes the phone number of the requester, the sensitive actions then don't need a code
+// anymore. Removing a verified phone number is a sensitive action.
+// endpoint: DELETE /accounts/{id}/phone
+// Success: 200
+// Fail: 400, 401, 403, 404, 500
+func (server *Server) HandleRemovePhoneNumber(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "DELETE /accounts/{id}/phone"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	credentials, err := server.query.GetAccountCredentials(r.Context(), accountID)
+	if err != nil {
+		server.logger.Error("DELETE /accounts/{id}/phone: failed to get account credentials", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if !credentials.PhoneNumber.Valid {
+		server.WriteErrorCode(w, http.StatusNotFound, CodePhoneNotVerified, "Account has no phone number", nil)
+		return
+	}
+	if !server.requireStepUp(w, r, accountID, db.StepUpPurposePhoneChange, credentials) {
+		return
+	}
+
+	if err := server.query.RemovePhoneNumber(r.Context(), accountID); err != nil {
+		server.logger.Error("DELETE /accounts/{id}/phone: failed to remove phone number", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Phone number removed successfully")
+}
+
+// Method to check the step-up code of a sensitive action, sent in the X-Step-Up-Challenge and X-Step-Up-Code
+// headers. Only the accounts with a verified phone number need one, and only while SMS is enabled: the others pass.
+// The code must have been sent for this action to the current phone number. The response is written if it fails
+func (server *Server) requireStepUp(w http.ResponseWriter, r *http.Request, accountID uuid.UUID,
+	purpose db.StepUpPurpose, credentials db.GetAccountCredentialsRow) bool {
+	if server.sms == nil || !credentials.PhoneVerifiedAt.Valid {
+		return true
+	}
+
+	challengeID, err := uuid.Parse(r.Header.Get(headerStepUpChallenge))
+	code := r.Header.Get(headerStepUpCode)
+	if err != nil || code == "" {
+		server.WriteErrorCode(w, http.StatusForbidden, CodeStepUpRequired,
+			"This action needs a verification code sent to your phone number", map[string]string{
+				"purpose":      string(purpose),
+				"phone_number": maskPhoneNumber(credentials.PhoneNumber.String),
+			})
+		return false
+	}
+
+	challenge, err := server.checkStepUpCode(r.Context(), accountID, purpose, challengeID, code)
+	if err == nil && challenge.PhoneNumber != credentials.PhoneNumber.String {
+		err = errStepUpInvalid
+	}
+	if err != nil {
+		if errors.Is(err, errStepUpInvalid) {
+			server.WriteErrorCode(w, http.StatusForbidden, CodeStepUpInvalid, "Invalid or expired verification code", nil)
+			return false
+		}
+		server.logger.Error(fmt.Sprintf("%s: failed to check step-up code", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return false
+	}
+	return true
+}
+
+// Method to check a code against its challenge and use it. Each try counts (before the comparison, so concurrent
+// tries can't exceed the limit), the challenge is consumed by the first right one. It returns errStepUpInvalid if
+// the code can't be used
+func (server *Server) checkStepUpCode(ctx context.Context, accountID uuid.UUID, purpose db.StepUpPurpose,
+	challengeID uuid.UUID, code string) (*db.StepUpChallenge, error) {
+	challenge, err := server.query.GetStepUpChallenge(ctx, db.GetStepUpChallengeParams{
+		ChallengeID: challengeID,
+		AccountID:   accountID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errStepUpInvalid
+		}
+		return nil, err
+	}
+	if challenge.Purpose != purpose || challenge.ConsumedAt.Valid || time.Now().After(challenge.ExpiresAt) {
+		return nil, errStepUpInvalid
+	}
+
+	tried, err := server.query.IncrementStepUpAttempts(ctx, db.IncrementStepUpAttemptsParams{
+		ChallengeID: challengeID,
+		MaxAttempts: stepUpMaxAttempts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tried == 0 || !security.CompareCode(server.config.SecretKey, challengeID.String(), code, challenge.CodeHash) {
+		return nil, errStepUpInvalid
+	}
+
+	consumed, err := server.query.ConsumeStepUpChallenge(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	if consumed == 0 {
+		return nil, errStepUpInvalid
+	}
+	return &challenge, nil
+}
+
+// Method to send a new code for an action to a phone number, and write the challenge to confirm it with. The
+// response is written if it fails
+func (server *Server) sendStepUpCode(w http.ResponseWriter, r *http.Request, accountID uuid.UUID,
+	purpose db.StepUpPurpose, phoneNumber string) {
+	challenge, err := server.createStepUpChallenge(r.Context(), accountID, purpose, phoneNumber)
+	if err != nil {
+		if errors.Is(err, errStepUpRateLimited) {
+			server.WriteError(w, http.StatusTooManyRequests, "Too many verification codes sent, please try again later")
+			return
+		}
+		server.logger.Error(fmt.Sprintf("%s: failed to send step-up code", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Failed to send verification code")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusAccepted, stepUpResponse{
+		ChallengeID: challenge.ChallengeID,
+		Purpose:     challenge.Purpose,
+		PhoneNumber: maskPhoneNumber(challenge.PhoneNumber),
+		ExpiresAt:   challenge.ExpiresAt,
+	})
+}
+
+// Helper method: create a challenge with a new code and send the code by SMS
+func (server *Server) createStepUpChallenge(ctx context.Context, accountID uuid.UUID, purpose db.StepUpPurpose,
+	phoneNumber string) (*db.StepUpChallenge, error) {
+	sent, err := server.query.CountRecentStepUpChallenges(ctx, db.CountRecentStepUpChallengesParams{
+		AccountID: accountID,
+		Since:     time.Now().Add(-stepUpSendWindow),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sent >= stepUpMaxSends {
+		return nil, errStepUpRateLimited
+	}
+
+	code, err := security.GenerateCode(stepUpCodeDigits)
+	if err != nil {
+		return nil, err
+	}
+	challengeID := uuid.New()
+	challenge, err := server.query.CreateStepUpChallenge(ctx, db.CreateStepUpChallengeParams{
+		ChallengeID: challengeID,
+		AccountID:   accountID,
+		Purpose:     purpose,
+		PhoneNumber: phoneNumber,
+		CodeHash:    security.HashCode(server.config.SecretKey, challengeID.String(), code),
+		ExpiresAt:   time.Now().Add(stepUpCodeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf("%s is your Zust code to %s. It expires in %d minutes, don't share it with anyone.",
+		code, stepUpActions[purpose], int(stepUpCodeTTL.Minutes()))
+	if err := server.sms.Send(ctx, phoneNumber, body); err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// Helper function: mask a phone number but its last 2 digits, to show where a code was sent
+func maskPhoneNumber(phoneNumber string) string {
+	if len(phoneNumber) <= 3 {
+		return phoneNumber
+	}
+	return phoneNumber[:1] + strings.Repeat("*", len(phoneNumber)-3) + phoneNumber[len(phoneNumber)-2:]
+}
+
+// Method to delete the step-up challenges which expired, they are kept for the send window since they count in the
+// codes sent to their account
+func (server *Server) runStepUpCleanupJob(ctx context.Context) {
+	deleted, err := server.query.DeleteExpiredStepUpChallenges(ctx, time.Now().Add(-stepUpSendWindow))
+	if err != nil {
+		server.logger.Error("step_up: failed to delete expired challenges", "error", err)
+		return
+	}
+	server.logger.Info("step_up: expired challenges deleted", "deleted", deleted)
+}
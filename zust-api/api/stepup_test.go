@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	db "zust/db/sqlc"
+
+	"github.com/google/uuid"
+)
+
+// SMS sender of the tests, nothing is sent
+type fakeSMS struct{}
+
+func (fakeSMS) Send(ctx context.Context, to, body string) error {
+	return nil
+}
+
+func TestRequireStepUp(t *testing.T) {
+	verified := db.GetAccountCredentialsRow{
+		PhoneNumber:     sql.NullString{String: "+84901234567", Valid: true},
+		PhoneVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+	unverified := db.GetAccountCredentialsRow{
+		PhoneNumber: sql.NullString{String: "+84901234567", Valid: true},
+	}
+
+	// None of the cases reaches the database: they pass or fail before the challenge is read
+	tests := []struct {
+		name        string
+		smsEnabled  bool
+		credentials db.GetAccountCredentialsRow
+		challenge   string
+		code        string
+		pass        bool
+	}{
+		{name: "sms disabled", credentials: verified, pass: true},
+		{name: "no phone number", smsEnabled: true, pass: true},
+		{name: "unverified phone number", smsEnabled: true, credentials: unverified, pass: true},
+		{name: "no headers", smsEnabled: true, credentials: verified},
+		{name: "no code", smsEnabled: true, credentials: verified, challenge: uuid.NewString()},
+		{name: "no challenge", smsEnabled: true, credentials: verified, code: "123456"},
+		{name: "invalid challenge", smsEnabled: true, credentials: verified, challenge: "not-a-uuid", code: "123456"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := &Server{}
+			if test.smsEnabled {
+				server.sms = fakeSMS{}
+			}
+
+			r := httptest.NewRequest("DELETE", "/accounts/x", nil)
+			if test.challenge != "" {
+				r.Header.Set(headerStepUpChallenge, test.challenge)
+			}
+			if test.code != "" {
+				r.Header.Set(headerStepUpCode, test.code)
+			}
+			w := httptest.NewRecorder()
+
+			pass := server.requireStepUp(w, r, uuid.New(), db.StepUpPurposeAccountDeletion, test.credentials)
+			if pass != test.pass {
+				t.Fatalf("requireStepUp() = %v, want %v", pass, test.pass)
+			}
+			if pass {
+				return
+			}
+
+			var resp struct {
+				Code    ErrorCode         `json:"code"`
+				Details map[string]string `json:"details"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if w.Code != http.StatusForbidden || resp.Code != CodeStepUpRequired {
+				t.Errorf("response = %d %s, want %d %s", w.Code, resp.Code, http.StatusForbidden, CodeStepUpRequired)
+			}
+			if resp.Details["purpose"] != string(db.StepUpPurposeAccountDeletion) ||
+				resp.Details["phone_number"] != "+*********67" {
+				t.Errorf("details = %v, want the purpose and the masked phone number", resp.Details)
+			}
+		})
+	}
+}
+
+func TestMaskPhoneNumber(t *testing.T) {
+	tests := []struct {
+		phoneNumber string
+		masked      string
+	}{
+		{phoneNumber: "+84901234567", masked: "+*********67"},
+		{phoneNumber: "+1234", masked: "+**34"},
+		{phoneNumber: "+12", masked: "+12"},
+		{phoneNumber: "", masked: ""},
+	}
+
+	for _, test := range tests {
+		if got := maskPhoneNumber(test.phoneNumber); got != test.masked {
+			t.Errorf("maskPhoneNumber(%q) = %q, want %q", test.phoneNumber, got, test.masked)
+		}
+	}
+}
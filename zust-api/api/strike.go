@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/job"
+	"zust/service/mail"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Interval of the strike job, which also ends the temporary suspensions
+const strikeCheckInterval = 15 * time.Minute
+
+// Outcome of a strike, sent to the account by email once the decision is committed
+type strikeOutcome struct {
+	AccountID      uuid.UUID
+	Target         string // what the strike is for, for example: your video "Title"
+	Strike         db.Strike
+	ActiveStrikes  int64
+	Suspended      bool
+	SuspendedUntil sql.NullTime // not set if the account is suspended until an admin reinstates it
+}
+
+// Method to give a strike to an account for a decision, then apply the strike policy: the account is suspended if
+// its active strikes reach a rule. It runs in the transaction of the decision.
+// A suspension never shortens the current one of the account
+func (server *Server) giveStrike(ctx context.Context, q *db.Queries, accountID, decisionID uuid.UUID,
+	target string) (*strikeOutcome, error) {
+	now := time.Now()
+	strike, err := q.CreateStrike(ctx, db.CreateStrikeParams{
+		AccountID:  accountID,
+		DecisionID: decisionID,
+		ExpiresAt:  now.Add(server.config.StrikeExpiry),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := q.CountActiveStrikes(ctx, db.CountActiveStrikesParams{AccountID: accountID, Now: now})
+	if err != nil {
+		return nil, err
+	}
+	outcome := &strikeOutcome{AccountID: accountID, Target: target, Strike: strike, ActiveStrikes: count}
+
+	rule, ok := server.strikeRule(int(count))
+	if !ok {
+		return outcome, nil
+	}
+	if rule.Suspension > 0 {
+		outcome.SuspendedUntil = sql.NullTime{Time: now.Add(rule.Suspension), Valid: true}
+	}
+	suspended, err := q.SuspendAccount(ctx, db.SuspendAccountParams{
+		SuspendedUntil: outcome.SuspendedUntil,
+		AccountID:      accountID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	outcome.Suspended = suspended > 0
+	return outcome, nil
+}
+
+// Helper method: get the rule of the strike policy applying to a number of active strikes, which is the rule with
+// the most strikes reached
+func (server *Server) strikeRule(count int) (security.StrikeRule, bool) {
+	var rule security.StrikeRule
+	found := false
+	for _, candidate := range server.config.StrikePolicy {
+		if candidate.Strikes <= count {
+			rule, found = candidate, true
+		}
+	}
+	return rule, found
+}
+
+// Helper method: send the strike email to the account given the strike, failure is only logged
+func (server *Server) notifyStrike(ctx context.Context, outcome *strikeOutcome) {
+	profile, err := server.query.GetProfile(ctx, outcome.AccountID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			server.logger.Error("strike: failed to get profile", "account_id", outcome.AccountID, "error", err)
+		}
+		return
+	}
+
+	payload := mail.StrikeEmailPayload{
+		Username:      profile.Username,
+		Target:        outcome.Target,
+		ActiveStrikes: int(outcome.ActiveStrikes),
+		ExpiresAt:     outcome.Strike.ExpiresAt.UTC().Format("January 2, 2006"),
+		Suspended:     outcome.Suspended,
+	}
+	subject := "Zust - Your account received a strike"
+	if outcome.Suspended {
+		subject = "Zust - Your account has been suspended"
+		if outcome.SuspendedUntil.Valid {
+			payload.SuspendedUntil = outcome.SuspendedUntil.Time.UTC().Format("January 2, 2006 15:04 MST")
+		}
+	}
+
+	body, text, err := server.mailService.PrepareBodies("strike", payload)
+	if err != nil {
+		server.logger.Error("strike: failed to prepare email", "error", err)
+		return
+	}
+	err = server.jobs.Enqueue(ctx, job.TypeSendEmail, sendEmailPayload{
+		To:      profile.Email,
+		Subject: subject,
+		Body:    body,
+		Text:    text,
+	})
+	if err != nil {
+		server.logger.Error("strike: failed to enqueue email", "account_id", outcome.AccountID, "error", err)
+	}
+}
+
+// An active strike of an account
+type strikeResult struct {
+	ID         string    `json:"id"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// HandleListStrikes lists the active strikes of the requester, latest first. The expired strikes don't count towards
+// the strike policy anymore, so they are not listed.
+// endpoint: GET /accounts/{id}/strikes
+// Success: 200
+// Fail: 400, 401, 500
+func (server *Server) HandleListStrikes(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accountID uuid.UUID
+	accountID.Scan(r.PathValue("id"))
+	strikes, err := server.query.ListActiveStrikes(r.Context(), db.ListActiveStrikesParams{
+		AccountID: accountID,
+		Now:       time.Now(),
+	})
+	if err != nil {
+		server.logger.Error("GET /accounts/{id}/strikes: failed to list strikes", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]strikeResult, 0, len(strikes))
+	for _, strike := range strikes {
+		data = append(data, strikeResult{
+			ID:         strike.StrikeID.String(),
+			TargetType: string(strike.TargetType),
+			TargetID:   strike.TargetID.String(),
+			CreatedAt:  strike.CreatedAt,
+			ExpiresAt:  strike.ExpiresAt,
+		})
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Method to run the strike job: reinstate the accounts whose suspension is over, and delete the expired strikes
+func (server *Server) runStrikeJob(ctx context.Context) {
+	now := time.Now()
+	reinstated, err := server.query.ReinstateSuspendedAccounts(ctx, now)
+	if err != nil {
+		server.logger.Error("strikes: failed to reinstate suspended accounts", "error", err)
+	}
+	for _, accountID := range reinstated {
+		server.invalidateProfile(ctx, accountID)
+	}
+
+	deleted, err := server.query.DeleteExpiredStrikes(ctx, now)
+	if err != nil {
+		server.logger.Error("strikes: failed to delete expired strikes", "error", err)
+		return
+	}
+	server.logger.Info("strikes: expired strikes deleted", "deleted", deleted, "reinstated", len(reinstated))
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"zust/service/file"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Custom type to avoid context key collisions
+type tenantKey string
+
+var tnKey tenantKey = "tenant"
+
+// Tenants (sites) served by the deployment: the tenant of each host, and the tenant of the accounts already looked
+// up. The tenant of an account never changes, so it's kept in memory without expiration
+type tenantRegistry struct {
+	hosts    map[string]string
+	accounts sync.Map // account ID (uuid.UUID) -> tenant ID (string)
+}
+
+// Constructor method for tenant registry
+func newTenantRegistry(config *security.Config) *tenantRegistry {
+	registry := &tenantRegistry{hosts: make(map[string]string)}
+	for _, tenant := range config.Tenants {
+		for _, host := range tenant.Hosts {
+			registry.hosts[host] = tenant.ID
+		}
+	}
+	return registry
+}
+
+// Helper method: set up the tenants of the server. The repositories of the accounts are only looked up by tenant
+// when the deployment serves several sites
+func (server *Server) setupTenants() {
+	server.tenants = newTenantRegistry(server.config)
+	if len(server.config.Tenants) == 0 {
+		return
+	}
+
+	server.localStorage.TenantDir = server.tenantDir
+	if cold, ok := server.coldStorage.(*file.LocalStorage); ok {
+		cold.TenantDir = server.tenantDir
+	}
+}
+
+// TenantMiddleware is a middleware that takes the tenant of the request from its host, and puts it into the request
+// context. Hosts of no tenant are served by the default tenant
+func (server *Server) TenantMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if name, _, err := net.SplitHostPort(host); err == nil {
+			host = name
+		}
+
+		tenant, ok := server.tenants.hosts[strings.ToLower(host)]
+		if !ok {
+			tenant = security.DefaultTenant
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tnKey, tenant)))
+	})
+}
+
+// Helper function: get the tenant of a request context, the default tenant outside of a request (for example: in
+// a background job)
+func tenantOf(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tnKey).(string); ok {
+		return tenant
+	}
+	return security.DefaultTenant
+}
+
+// Method to get the tenant of an account
+func (server *Server) accountTenant(ctx context.Context, accountID uuid.UUID) (string, error) {
+	if len(server.config.Tenants) == 0 {
+		return security.DefaultTenant, nil
+	}
+	if tenant, ok := server.tenants.accounts.Load(accountID); ok {
+		return tenant.(string), nil
+	}
+
+	tenant, err := server.query.GetAccountTenant(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	server.tenants.accounts.Store(accountID, tenant)
+	return tenant, nil
+}
+
+// Helper method: check if an account belongs to the tenant of the request. The accounts of the other tenants are
+// handled as if they don't exist
+func (server *Server) inTenant(ctx context.Context, accountID uuid.UUID) (bool, error) {
+	tenant, err := server.accountTenant(ctx, accountID)
+	if err != nil {
+		return false, err
+	}
+	return tenant == tenantOf(ctx), nil
+}
+
+// Helper method: get the directory of the repository of an account in the storage
+func (server *Server) tenantDir(accID string) (string, error) {
+	var accountID uuid.UUID
+	if err := accountID.Scan(accID); err != nil {
+		return "", err
+	}
+
+	tenant, err := server.accountTenant(context.Background(), accountID)
+	if err != nil {
+		return "", err
+	}
+	return file.TenantDir(tenant), nil
+}
+
+// Helper method: check if the account of the claims of a token belongs to the tenant of the request
+func (server *Server) claimsInTenant(ctx context.Context, claims *security.CustomClaims) (bool, error) {
+	var accountID uuid.UUID
+	if err := accountID.Scan(claims.ID); err != nil {
+		return false, nil
+	}
+	return server.inTenant(ctx, accountID)
+}
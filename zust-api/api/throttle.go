@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"zust/service/file"
+
+	"golang.org/x/time/rate"
+)
+
+// Size of the chunks written to a throttled connection, which is also the burst of its token bucket
+const throttleChunkSize = 32 << 10
+
+// Response writer throttled by a token bucket, so one connection can't take more than its share of the uplink
+type throttledWriter struct {
+	http.ResponseWriter
+	request *http.Request
+	limiter *rate.Limiter
+}
+
+// Constructor method for throttled writer, the limit is in bytes per second
+func newThrottledWriter(w http.ResponseWriter, r *http.Request, bytesPerSecond int) *throttledWriter {
+	return &throttledWriter{
+		ResponseWriter: w,
+		request:        r,
+		limiter:        rate.NewLimiter(rate.Limit(bytesPerSecond), throttleChunkSize),
+	}
+}
+
+// Method to write the body in chunks, each waiting for enough tokens. It stops when the client goes away
+func (writer *throttledWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), throttleChunkSize)]
+		if err := writer.limiter.WaitN(writer.request.Context(), len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := writer.ResponseWriter.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Method to expose the underlying writer to http.ResponseController
+func (writer *throttledWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// Helper method: get the rate limit (bytes per second) of a stored file, 0 means unlimited. Files of a rendition
+// (for example: {video}_720p.mp4 or the HLS segments under 720p/) use the cap of their resolution if configured
+func (server *Server) mediaRateLimit(key string) int {
+	if resolution := keyResolution(key); resolution != "" {
+		if limit, ok := server.config.MediaRateLimits[resolution]; ok {
+			return limit
+		}
+	}
+	return server.config.MediaRateLimit
+}
+
+// Helper function: get the resolution of the rendition a stored file belongs to, or an empty string if none
+func keyResolution(key string) string {
+	key = strings.TrimSuffix(key, path.Ext(key))
+	for _, element := range strings.Split(key, "/") {
+		for _, part := range strings.Split(element, "_") {
+			if file.ParseResolution(part) > 0 {
+				return part
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"zust/service/file"
+	"zust/service/job"
+
+	"github.com/google/uuid"
+)
+
+// Seconds a client is asked to wait before requesting again a video being restored from the cold storage
+const thawRetryAfter = 30
+
+// Method to apply the cold storage tiering policy: the renditions of the published videos not watched for
+// COLD_AFTER are moved into the cold storage. The video is marked as cold before its files are moved, so a request
+// in the middle of the move restores it instead of failing
+func (server *Server) runTieringJob(ctx context.Context) {
+	videos, err := server.query.ListStaleVideos(ctx, time.Now().Add(-server.config.ColdAfter))
+	if err != nil {
+		server.logger.Error("tiering: failed to list stale videos", "error", err)
+		return
+	}
+
+	moved := 0
+	for _, video := range videos {
+		accID, videoID := video.PublisherID.String(), video.VideoID.String()
+
+		keys, err := file.ColdKeys(server.storage, accID, videoID)
+		if err != nil {
+			server.logger.Error("tiering: failed to list video files", "video_id", videoID, "error", err)
+			continue
+		}
+		if len(keys) == 0 {
+			continue
+		}
+
+		if err := server.query.MarkVideoCold(ctx, video.VideoID); err != nil {
+			server.logger.Error("tiering: failed to mark video as cold", "video_id", videoID, "error", err)
+			continue
+		}
+		server.invalidateVideos(ctx, video.VideoID)
+		if err := file.MoveFiles(server.storage, server.coldStorage, keys); err != nil {
+			server.logger.Error("tiering: failed to move video files", "video_id", videoID, "error", err)
+			continue
+		}
+		moved++
+	}
+
+	server.logger.Info("tiering: stale videos processed", "stale", len(videos), "moved", moved)
+}
+
+// Payload of the thaw job
+type thawPayload struct {
+	VideoID     uuid.UUID `json:"video_id"`
+	PublisherID uuid.UUID `json:"publisher_id"`
+}
+
+// Method to handle the thaw job: the files of a cold video are moved back into the main storage. A video already
+// restored by another job is skipped
+func (server *Server) handleThawJob(ctx context.Context, j *job.Job) error {
+	var payload thawPayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+
+	access, err := server.query.GetVideoAccess(ctx, payload.VideoID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The video has been deleted since, its files stay in the cold storage
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !access.ColdAt.Valid {
+		return nil
+	}
+
+	keys, err := file.ColdKeys(server.coldStorage, payload.PublisherID.String(), payload.VideoID.String())
+	if err != nil {
+		return err
+	}
+	if err := file.MoveFiles(server.coldStorage, server.storage, keys); err != nil {
+		return err
+	}
+	if err := server.query.MarkVideoWarm(ctx, payload.VideoID); err != nil {
+		return err
+	}
+	server.invalidateVideos(ctx, payload.VideoID)
+	return nil
+}
+
+// Method to start restoring a cold video, its files are served again once the thaw job is done. The client is
+// asked to retry later with the Retry-After header
+func (server *Server) thawVideo(w http.ResponseWriter, r *http.Request, videoID, publisherID uuid.UUID) error {
+	payload := thawPayload{VideoID: videoID, PublisherID: publisherID}
+	if err := server.jobs.Enqueue(r.Context(), job.TypeThaw, payload); err != nil {
+		return err
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(thawRetryAfter))
+	return nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Routes without deadline: uploads, media streaming and the routes which run as long as their work (or the client)
+// needs
+var timeoutExempt = map[string]bool{
+	"GET /media/{id}":                    true,
+	"GET /media/{id}/{path...}":          true,
+	"GET /live/{id}/{file}":              true,
+	"POST /videos":                       true,
+	"PUT /accounts/{id}":                 true,
+	"PUT /accounts/{id}/watermark":       true,
+	"PUT /accounts/{id}/branding/{kind}": true,
+	"GET /videos/{id}/processing/stream": true,
+	"GET /notifications/stream":          true,
+	"GET /admin/storage/reconcile":       true,
+	"GET /debug/pprof/profile":           true,
+	"GET /debug/pprof/trace":             true,
+}
+
+// Response writer of a request with a deadline. The handler has its own headers, copied to the response when the
+// status is written, so they are not shared with the 504 response. Once the deadline is over, the handler can't
+// write anymore: its writes fail with http.ErrHandlerTimeout
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+// Method to get the headers of the response
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Method to write the status of the response, unless the deadline is over
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(status)
+}
+
+// Helper method: copy the headers and write the status of the response, the writer must be locked
+func (tw *timeoutWriter) writeHeader(status int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	for key, values := range tw.header {
+		tw.w.Header()[key] = values
+	}
+	tw.w.WriteHeader(status)
+}
+
+// Method to write the body of the response, unless the deadline is over
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(data)
+}
+
+// Method to get the underlying response writer, for http.ResponseController
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
+
+// TimeoutMiddleware is a middleware that puts a deadline on the request context, so the database queries of the
+// handler are cancelled once it's over. If the handler hasn't answered by then, the request is answered with 504
+// and whatever the handler writes afterwards is dropped
+func (server *Server) TimeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if server.config.RequestTimeout == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, pattern := server.mux.Handler(r); timeoutExempt[pattern] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), server.config.RequestTimeout)
+		defer cancel()
+
+		tw := &timeoutWriter{w: w, header: w.Header().Clone()}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			next.ServeHTTP(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+		case <-ctx.Done():
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			tw.timedOut = true
+
+			// Nothing is written if the client is gone, or if the handler already started its response
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && !tw.wroteHeader {
+				server.logger.Warn(fmt.Sprintf("%s %s: request timed out", r.Method, r.URL.Path),
+					"timeout", server.config.RequestTimeout)
+				server.WriteError(w, http.StatusGatewayTimeout, "Request timed out")
+			}
+		}
+	})
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/file"
+	"zust/service/job"
+
+	"github.com/google/uuid"
+)
+
+// Minimum interval between two progress updates of the same video persisted into database
+const progressSaveInterval = 2 * time.Second
+
+// Payload of the transcode job
+type transcodePayload struct {
+	VideoID     uuid.UUID `json:"video_id"`
+	PublisherID uuid.UUID `json:"publisher_id"`
+	Branding    bool      `json:"branding,omitempty"`
+}
+
+// Method to put a video into the transcode queue
+// If 'branding' is true, the intro/outro of the channel are stitched onto the video
+func (server *Server) enqueueTranscode(ctx context.Context, videoID, publisherID uuid.UUID, branding bool) error {
+	payload := transcodePayload{VideoID: videoID, PublisherID: publisherID, Branding: branding}
+	return server.jobs.Enqueue(ctx, job.TypeTranscode, payload, job.WithMaxAttempts(server.config.TranscodeRetries+1))
+}
+
+// Seconds a client is asked to wait before retrying an upload rejected by the transcode backpressure
+const transcodeRetryAfter = 60
+
+// Method to apply backpressure on uploads: when the transcode queue holds more jobs than the configured limit,
+// the request is rejected with 503 so one burst of uploads can't pile up work the workers never catch up with
+func (server *Server) checkTranscodeBacklog(w http.ResponseWriter, r *http.Request) bool {
+	if server.config.TranscodeQueueLimit <= 0 {
+		return true
+	}
+
+	backlog, err := server.jobs.Backlog(r.Context(), job.TypeTranscode)
+	if err != nil {
+		server.logger.Error(fmt.Sprintf("%s: failed to get transcode backlog", r.Context().Value(epKey)), "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return false
+	}
+	if backlog >= server.config.TranscodeQueueLimit {
+		w.Header().Set("Retry-After", strconv.Itoa(transcodeRetryAfter))
+		server.WriteErrorCode(w, http.StatusServiceUnavailable, CodeTranscodeBacklogFull,
+			"Server is busy processing other videos, please try again later", nil)
+		return false
+	}
+	return true
+}
+
+// Method to handle the transcode job. When the last attempt fails, all unfinished renditions are marked
+// as failed and the publisher is notified
+func (server *Server) handleTranscodeJob(ctx context.Context, j *job.Job) error {
+	var payload transcodePayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+
+	err := server.transcodeVideo(ctx, payload.VideoID, payload.PublisherID, payload.Branding)
+	if err == nil || !j.LastAttempt() {
+		return err
+	}
+
+	// Out of retries, the video is failed until an admin requeues the job
+	if failErr := server.query.FailVideo(ctx, payload.VideoID); failErr != nil {
+		server.logger.Error("transcode: failed to mark video as failed", "video_id", payload.VideoID, "error", failErr)
+	}
+	server.invalidateVideos(ctx, payload.VideoID)
+
+	renditions, listErr := server.query.ListRenditions(ctx, payload.VideoID)
+	if listErr != nil {
+		server.logger.Error("transcode: failed to list renditions", "video_id", payload.VideoID, "error", listErr)
+	}
+	for _, rendition := range renditions {
+		if rendition.Status == db.RenditionStatusCompleted {
+			continue
+		}
+		server.updateRendition(ctx, payload.VideoID, rendition.Resolution, db.RenditionStatusFailed, 0, err)
+	}
+	server.completeProcessing(ctx, payload.VideoID, payload.PublisherID)
+	return err
+}
+
+// Method to transcode the uploaded video into the per-title ladder, then publish it
+func (server *Server) transcodeVideo(ctx context.Context, videoID, publisherID uuid.UUID, branding bool) error {
+	// A failed video being requeued is pending again
+	if err := server.query.ResetFailedVideo(ctx, videoID); err != nil {
+		return err
+	}
+	server.invalidateVideos(ctx, videoID)
+
+	accID, vidID := publisherID.String(), videoID.String()
+
+	// Work on local copies of the stored files, so the job runs on any machine which can reach the storage
+	work, err := os.MkdirTemp("", "zust-transcode-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+
+	input := filepath.Join(work, vidID+".mp4")
+	if err := file.Fetch(server.storage, file.OriginalKey(accID, vidID), input); err != nil {
+		return err
+	}
+	poster := filepath.Join(work, vidID+".png")
+	err = file.Fetch(server.storage, file.ThumbnailKey(accID, vidID), poster)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// Scan the uploaded files before anything is derived from them, an infected video is never retried
+	infected, err := server.scanUpload(ctx, videoID, publisherID, input, poster)
+	if infected && err == nil {
+		// The local copies are quarantined, the stored ones are removed so they are never served
+		for _, key := range []string{file.OriginalKey(accID, vidID), file.ThumbnailKey(accID, vidID)} {
+			if err := server.storage.Delete(key); err != nil {
+				server.logger.Error("transcode: failed to remove infected upload", "key", key, "error", err)
+			}
+		}
+	}
+	if infected || err != nil {
+		return err
+	}
+
+	// Choose the rungs of the ladder based on the source video
+	info, err := server.mediaService.Probe(input)
+	if err != nil {
+		return err
+	}
+
+	// Standardize the poster, from the thumbnail supplied by the user or from the video itself
+	if err := server.mediaService.GeneratePoster(ctx, input, poster, info.Duration); err != nil {
+		return err
+	}
+	if err := file.Store(server.storage, file.ThumbnailKey(accID, vidID), poster); err != nil {
+		return err
+	}
+
+	// Stitch the intro/outro of the channel, the stitched video replaces the original as the transcode input
+	if branding {
+		stitched, err := server.stitchBranding(ctx, publisherID, videoID, work, input, info)
+		if err != nil {
+			return err
+		}
+		if stitched != "" {
+			input = stitched
+			if info, err = server.mediaService.Probe(input); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Generate the peak waveform of what players will play, videos without audio have none
+	if info.HasAudio() {
+		waveform := filepath.Join(work, vidID+".json")
+		if err := server.mediaService.GenerateWaveform(ctx, input, waveform, info); err != nil {
+			return err
+		}
+		if err := file.Store(server.storage, file.WaveformKey(accID, vidID), waveform); err != nil {
+			return err
+		}
+	}
+
+	rungs := server.mediaService.PerTitleLadder(info, server.mediaService.Ladder)
+	rungs = file.WithCodecs(rungs, server.mediaService.Codecs)
+
+	outputs := make(map[file.ResolutionConfig]string, len(rungs))
+	for _, res := range rungs {
+		if err := server.query.CreateRendition(ctx, db.CreateRenditionParams{
+			VideoID:    videoID,
+			Resolution: res.Name(),
+		}); err != nil {
+			return err
+		}
+		server.updateRendition(ctx, videoID, res.Name(), db.RenditionStatusProcessing, 0, nil)
+		outputs[res] = filepath.Join(work, fmt.Sprintf("%s_%s.mp4", vidID, res.Name()))
+	}
+
+	// Overlay the watermark of the channel, or the default one of the deployment
+	watermark, err := server.watermarkFor(ctx, publisherID, work)
+	if err != nil {
+		return err
+	}
+
+	// Transcode all resolutions in a single ffmpeg run, the progress is persisted so uploader can follow it
+	var lastSaved time.Time
+	progress := func(percent int) {
+		if time.Since(lastSaved) < progressSaveInterval {
+			return
+		}
+		lastSaved = time.Now()
+		for _, res := range rungs {
+			server.updateRendition(ctx, videoID, res.Name(), db.RenditionStatusProcessing, int32(percent), nil)
+		}
+	}
+	rungs, err = server.mediaService.MultiResolution(ctx, input, outputs, watermark, info, progress)
+	if err != nil {
+		return err
+	}
+
+	// Package the renditions into HLS for adaptive streaming, then store them with their HLS directory
+	if err := server.packageHLS(ctx, videoID, publisherID, work, rungs, outputs); err != nil {
+		return err
+	}
+	for _, res := range rungs {
+		if err := file.Store(server.storage, file.RenditionKey(accID, vidID, res.Name()), outputs[res]); err != nil {
+			return err
+		}
+	}
+
+	for _, res := range rungs {
+		server.updateRendition(ctx, videoID, res.Name(), db.RenditionStatusCompleted, 100, nil)
+	}
+
+	// Publish the video. A video held by moderation (or deleted) in the meantime is not pending anymore,
+	// so it stays as is
+	published, err := server.query.PublishVideo(ctx, videoID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	server.invalidateVideos(ctx, videoID)
+
+	server.completeProcessing(ctx, videoID, publisherID)
+
+	// The publisher's webhooks get the published videos, the subscribers are notified of the public ones
+	if err == nil {
+		server.emitWebhookEvent(ctx, publisherID, webhookVideoPublished, server.videoEventData(ctx, publisherID, videoID))
+	}
+	if err == nil && published.Visibility == db.VideoVisibilityPublic {
+		server.notifySubscribers(ctx, db.CreateSubscriberNotificationsParams{
+			Type:    db.NotificationTypeNewVideo,
+			ActorID: publisherID,
+			VideoID: uuid.NullUUID{UUID: videoID, Valid: true},
+		})
+	}
+
+	// Captions are generated afterward, the video doesn't wait for them
+	if server.transcriber != nil && info.HasAudio() {
+		if err := server.enqueueCaption(ctx, videoID, publisherID); err != nil {
+			server.logger.Error("transcode: failed to enqueue caption job", "video_id", videoID, "error", err)
+		}
+	}
+	return nil
+}
+
+// Method to package each rendition into HLS, then write the master playlist listing all of them (and the DASH
+// manifest if enabled). The package is built in the work directory, then replaces the stored HLS directory
+func (server *Server) packageHLS(ctx context.Context, videoID, publisherID uuid.UUID, work string,
+	rungs []file.ResolutionConfig, outputs map[file.ResolutionConfig]string) error {
+	dir := filepath.Join(work, "hls")
+
+	variants := make([]file.StreamVariant, 0, len(rungs))
+	for _, res := range rungs {
+		if err := server.mediaService.PackageHLS(ctx, outputs[res], filepath.Join(dir, res.Name())); err != nil {
+			return err
+		}
+
+		// The actual size and bitrate of the rendition are advertised in the master playlist
+		info, err := server.mediaService.Probe(outputs[res])
+		if err != nil {
+			return err
+		}
+		variants = append(variants, file.StreamVariant{
+			Name:      res.Name(),
+			Width:     info.Width,
+			Height:    info.Height,
+			Bandwidth: info.Bitrate,
+			Codecs:    info.Codecs(),
+			Codec:     res.Codec,
+		})
+	}
+
+	if err := server.mediaService.WriteMasterPlaylist(dir, variants); err != nil {
+		return err
+	}
+
+	// DASH manifest shares the same CMAF segments
+	if server.config.DASHEnabled {
+		if err := server.mediaService.WriteDASHManifest(dir, variants); err != nil {
+			return err
+		}
+	}
+	return file.StoreDir(server.storage, file.HLSKey(publisherID.String(), videoID.String()), dir)
+}
+
+// Helper method: stitch the intro/outro of the channel onto the video, the clips are fetched into the work
+// directory. It returns the path of the stitched video, or an empty string if the channel has neither intro nor outro
+func (server *Server) stitchBranding(ctx context.Context, publisherID, videoID uuid.UUID, work, input string,
+	info *file.MediaInfo) (string, error) {
+	clips := make(map[string]string, 2)
+	for _, kind := range []string{file.BrandingIntro, file.BrandingOutro} {
+		clip := filepath.Join(work, kind+".mp4")
+		err := file.Fetch(server.storage, file.BrandingKey(publisherID.String(), kind), clip)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		clips[kind] = clip
+	}
+	if len(clips) == 0 {
+		return "", nil
+	}
+
+	output := filepath.Join(work, fmt.Sprintf("%s_branded.mp4", videoID.String()))
+	err := server.mediaService.StitchBranding(ctx, input, clips[file.BrandingIntro], clips[file.BrandingOutro], output,
+		info)
+	if err != nil {
+		return "", err
+	}
+	return output, nil
+}
+
+// Helper method: update the status of a rendition, failure is only logged
+func (server *Server) updateRendition(ctx context.Context, videoID uuid.UUID, resolution string,
+	status db.RenditionStatus, progress int32, cause error) {
+	var errMsg sql.NullString
+	if cause != nil {
+		errMsg = sql.NullString{String: cause.Error(), Valid: true}
+	}
+
+	err := server.query.UpdateRenditionStatus(ctx, db.UpdateRenditionStatusParams{
+		VideoID:    videoID,
+		Resolution: resolution,
+		Status:     status,
+		Progress:   progress,
+		Error:      errMsg,
+	})
+	if err != nil {
+		server.logger.Error("transcode: failed to update rendition status", "video_id", videoID,
+			"resolution", resolution, "error", err)
+	}
+}
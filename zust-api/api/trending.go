@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/file"
+)
+
+// Method to refresh the trending videos from the latest view events. The refresh doesn't block the reads of the
+// trending videos, and the refreshes of several instances run one after another
+func (server *Server) runTrendingJob(ctx context.Context) {
+	start := time.Now()
+	if err := server.query.RefreshTrendingVideos(ctx); err != nil {
+		server.logger.Error("trending: failed to refresh trending videos", "error", err)
+		return
+	}
+	server.logger.Debug("trending: trending videos refreshed", "duration", time.Since(start))
+}
+
+// A video in the trending videos
+type trendingVideoResult struct {
+	ID                string    `json:"id"`
+	Title             string    `json:"title"`
+	Thumbnail         string    `json:"thumbnail"`
+	Duration          int       `json:"duration"`
+	CreatedAt         time.Time `json:"created_at"`
+	PublisherID       string    `json:"publisher_id"`
+	PublisherUsername string    `json:"username"`
+	RecentViews       int64     `json:"recent_views"` // views in the last 48 hours
+}
+
+// HandleListTrendingVideos lists the public videos gaining the most views recently, the most trending first. The
+// list is refreshed every few minutes (TRENDING_REFRESH_INTERVAL), not on each request.
+// endpoint: GET /videos/trending?page=...&size=...
+// Success: 200
+// Fail: 400, 500
+func (server *Server) HandleListTrendingVideos(w http.ResponseWriter, r *http.Request) {
+	_, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+	page, ok := server.parsePageNumber(w, r)
+	if !ok {
+		return
+	}
+
+	videos, err := server.query.ListTrendingVideos(r.Context(), db.ListTrendingVideosParams{
+		TenantID:   tenantOf(r.Context()),
+		PageSize:   int32(size),
+		PageOffset: int32((page - 1) * size),
+	})
+	if err != nil {
+		server.logger.Error("GET /videos/trending: failed to list trending videos", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]trendingVideoResult, 0, len(videos))
+	for _, video := range videos {
+		data = append(data, trendingVideoResult{
+			ID:    video.VideoID.String(),
+			Title: video.Title,
+			Thumbnail: server.mediaService.GenerateMediaLink(
+				video.AccountID.String(), fmt.Sprintf("%s.png", video.VideoID.String()), file.Thumbnail,
+			),
+			Duration:          int(video.Duration),
+			CreatedAt:         video.CreatedAt,
+			PublisherID:       video.AccountID.String(),
+			PublisherUsername: video.Username,
+			RecentViews:       video.RecentViews,
+		})
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Error of a field failing validation
+type fieldError struct {
+	Field   string `json:"field"`           // JSON name of the field
+	Rule    string `json:"rule"`            // validation tag, for example: max
+	Param   string `json:"param,omitempty"` // parameter of the rule, for example: 50 for max=50
+	Message string `json:"message"`
+}
+
+// Helper function: get the JSON name of a struct field, so validation errors refer to the fields of the request
+// body instead of the Go fields
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// Helper function: describe a failed rule in English. The values provided by the client are never echoed, they
+// can be passwords
+func fieldErrorMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required", "required_if":
+		return "is required"
+	case "max":
+		if err.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", err.Param())
+		}
+		return fmt.Sprintf("must be at most %s", err.Param())
+	case "min":
+		if err.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", err.Param())
+		}
+		return fmt.Sprintf("must be at least %s", err.Param())
+	case "len":
+		return fmt.Sprintf("must be %s characters", err.Param())
+	case "numeric":
+		return "must only contain digits"
+	case "email":
+		return "must be a valid email address"
+	case "e164":
+		return "must be a phone number in E.164 format, for example +14155550100"
+	case "url":
+		return "must be a valid URL"
+	case "startswith":
+		return fmt.Sprintf("must start with %s", err.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(err.Param()), ", "))
+	case "timezone":
+		return "must be a valid IANA timezone"
+	case "license":
+		return "must be a supported license"
+	default:
+		return fmt.Sprintf("failed the '%s' rule", err.Tag())
+	}
+}
+
+// Helper function: get the path of a failed field in the request body, with the index of the element for the fields
+// of a list ("events[2].type")
+func fieldPath(err validator.FieldError) string {
+	if _, path, ok := strings.Cut(err.Namespace(), "."); ok {
+		return path
+	}
+	return err.Field()
+}
+
+// Method to write the error of a request body failing validation, with the errors of each field in details
+func (server *Server) writeValidationError(w http.ResponseWriter, err error) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	details := make([]fieldError, len(validationErrs))
+	for i, fieldErr := range validationErrs {
+		details[i] = fieldError{
+			Field:   fieldPath(fieldErr),
+			Rule:    fieldErr.Tag(),
+			Param:   fieldErr.Param(),
+			Message: fieldErrorMessage(fieldErr),
+		}
+	}
+	server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", details)
+}
@@ -1,17 +1,23 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	db "zust/db/sqlc"
 	"zust/service/file"
+	"zust/service/job"
+	"zust/service/security"
 
 	"github.com/google/uuid"
 )
@@ -19,18 +25,29 @@ import (
 // HandleCreateVideo handle the video uploading.
 // endpoint: POST /videos
 // Success: 201
-// Fail: 400, 403
+// Fail: 400, 403, 413, 415, 422, 503, 507
 func (server *Server) HandleCreateVideo(w http.ResponseWriter, r *http.Request) {
 	// Check if requester account status is active or not
 	var accountID uuid.UUID
-	accountID.Scan(r.Context().Value(clKey))
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /videos"))
 	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
 		return
 	}
 
+	// Reject the upload early if the transcode queue is full
+	if ok := server.checkTranscodeBacklog(w, r); !ok {
+		return
+	}
+
+	// Reject the upload early if the storage is almost full
+	if !server.checkStorageSpace(w) {
+		return
+	}
+
 	// Get video metadata and insert into database with status 'pending'
-	if err := r.ParseMultipartForm(server.config.VideoSize); err != nil {
-		server.WriteError(w, http.StatusBadRequest, "Failed to parse multipart form")
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		server.writeFormError(w, err, "Failed to parse multipart form")
 		return
 	}
 
@@ -44,16 +61,39 @@ func (server *Server) HandleCreateVideo(w http.ResponseWriter, r *http.Request)
 	var description sql.NullString
 	description.Scan(desc)
 
+	// Get video license, default to standard license (all rights reserved)
+	license := db.VideoLicenseStandard
+	if value := strings.TrimSpace(r.FormValue("license")); value != "" {
+		if !isValidLicense(value) {
+			server.WriteError(w, http.StatusBadRequest, "Unsupported license")
+			return
+		}
+		license = db.VideoLicense(value)
+	}
+
+	attr := strings.TrimSpace(r.FormValue("attribution"))
+	if len(attr) > 255 {
+		server.WriteError(w, http.StatusBadRequest, "Attribution cannot be longer than 255 characters")
+		return
+	}
+
 	publisherID := r.FormValue("publisher_id")
 	if publisherID != accountID.String() {
 		server.WriteError(w, http.StatusBadRequest, "Publisher ID must be the ID of the requester")
 		return
 	}
 
+	// The title and the description can't contain blocked words
+	if !server.checkBlockedWords(w, r, accountID, title, desc) {
+		return
+	}
+
 	video, err := server.query.CreateVideo(r.Context(), db.CreateVideoParams{
 		Title:       title,
 		Description: description,
 		PublisherID: accountID,
+		License:     license,
+		Attribution: sql.NullString{String: attr, Valid: attr != ""},
 	})
 
 	if err != nil {
@@ -69,10 +109,16 @@ func (server *Server) HandleCreateVideo(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	defer resource.Close()
+	if !server.checkUploadType(w, resource, file.IsVideo, "Uploaded file is not a video") {
+		server.discardVideo(r.Context(), accountID, video.VideoID)
+		return
+	}
 
-	base := filepath.Join(server.config.ResourcePath, accountID.String())
-	filename := filepath.Join(base, "resource", fmt.Sprintf("%s.mp4", video.VideoID.String()))
-	dest, err := os.Create(filename)
+	key := file.OriginalKey(accountID.String(), video.VideoID.String())
+	filename := server.localPath(key)
+
+	// Write atomically, so an interrupted upload never looks like a complete video
+	dest, err := server.storage.Create(key)
 	if err != nil {
 		server.logger.Error("POST /videos: failed to create resource video file in local storage", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
@@ -81,12 +127,36 @@ func (server *Server) HandleCreateVideo(w http.ResponseWriter, r *http.Request)
 	defer dest.Close()
 
 	_, err = io.Copy(dest, resource)
+	if err == nil {
+		err = dest.Commit()
+	}
 	if err != nil {
 		server.logger.Error("POST /videos: failed to copy the user uploaded video to local storage", "error", err)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
+	// Probe the uploaded video and reject it if it violates the upload limits before accepting it
+	if err := server.checkUploadedVideo(filename); err != nil {
+		server.discardVideo(r.Context(), accountID, video.VideoID, filename)
+
+		var mediaErrs file.MediaErrors
+		if errors.As(err, &mediaErrs) {
+			server.WriteErrorCode(w, http.StatusUnprocessableEntity, CodeVideoNotSupported,
+				"Uploaded video is not supported",
+				mediaErrs)
+			return
+		}
+		if errors.Is(err, file.ErrUnsupportedMedia) {
+			server.WriteError(w, http.StatusUnprocessableEntity, "Uploaded file is not a valid video")
+			return
+		}
+
+		server.logger.Error("POST /videos: failed to probe uploaded video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
 	// Get video duration and update to database
 	duration, err := server.mediaService.GetVideoDuration(filename)
 	if err != nil {
@@ -103,56 +173,97 @@ func (server *Server) HandleCreateVideo(w http.ResponseWriter, r *http.Request)
 		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
 		return
 	}
+	server.invalidateVideos(r.Context(), video.VideoID)
 
-	// Get and download thumbnail
+	// Get and download thumbnail, it's optional: the poster is generated from the video during transcoding
+	// if the user doesn't supply one
+	resourceFile := filename
+	filename = ""
 	thumbnail, _, err := r.FormFile("thumbnail")
-	if err != nil || thumbnail == nil {
-		server.WriteError(w, http.StatusBadRequest, "Failed to read uploaded video")
+	if err != nil && !errors.Is(err, http.ErrMissingFile) {
+		server.WriteError(w, http.StatusBadRequest, "Failed to read uploaded thumbnail")
 		return
 	}
+	if err == nil {
+		defer thumbnail.Close()
+		if !server.checkUploadType(w, thumbnail, file.IsImage, "Thumbnail must be a PNG or JPEG image") {
+			server.discardVideo(r.Context(), accountID, video.VideoID, resourceFile)
+			return
+		}
 
-	filename = filepath.Join(base, "thumbnail", fmt.Sprintf("%s.png", video.VideoID.String()))
-	dest, err = os.Create(filename)
-	if err != nil {
-		server.logger.Error("POST /videos: failed to create thumbnail file in local storage", "error", err)
-		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
-		return
-	}
+		// Re-encode the image, which strips its metadata (EXIF, GPS location, ...)
+		var image bytes.Buffer
+		if err := file.NormalizeImage(thumbnail, &image); err != nil {
+			server.discardVideo(r.Context(), accountID, video.VideoID, resourceFile)
+			server.WriteError(w, http.StatusBadRequest, "Failed to read uploaded thumbnail")
+			return
+		}
 
-	_, err = io.Copy(dest, thumbnail)
-	if err != nil {
-		server.logger.Error("POST /videos: failed to copy the user uploaded thumbnail to local storage", "error", err)
-		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
-		return
+		key := file.ThumbnailKey(accountID.String(), video.VideoID.String())
+		filename = server.localPath(key)
+		if err := server.storage.Put(key, &image); err != nil {
+			server.logger.Error("POST /videos: failed to save the user uploaded thumbnail", "error", err)
+			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
 	}
 
 	// Return the result back to client
 	server.WriteJSON(w, http.StatusCreated, "Video uploaded successfully! The video may not available right away")
 
+	// Run content moderation hook on the uploaded video (background services)
+	go server.moderateVideo(video.VideoID, resourceFile, filename, duration)
+
 	// Transcode video (background services)
+	// The intro/outro of the channel are stitched onto the video if requested
+	branding := r.FormValue("branding") == "true"
+	if err := server.enqueueTranscode(r.Context(), video.VideoID, accountID, branding); err != nil {
+		server.logger.Error("POST /videos: failed to enqueue transcode job", "video_id", video.VideoID, "error", err)
+	}
 }
 
 // request body for GetVideo
 type getVideoResponse struct {
-	ID                string    `json:"id"`
-	Title             string    `json:"title"`
-	Resource          string    `json:"resource"`
-	Thumbnail         string    `json:"thumbnail"`
-	Duration          int       `json:"duration"`
-	Description       string    `json:"description"`
-	CreatedAt         time.Time `json:"created_at"`
-	PublisherID       string    `json:"publisher_id"`
-	PublisherUsername string    `json:"username"`
-	PublisherAvatar   string    `json:"avatar"`
-	TotalSubscriber   int       `json:"total_subscribers"`
-	TotakLike         int       `json:"total_like"`
-	TotalView         int       `json:"total_view"`
+	ID                string             `json:"id"`
+	Title             string             `json:"title"`
+	Resource          string             `json:"resource"`
+	Codec             string             `json:"codec,omitempty"` // codec of the rendition served
+	HLS               string             `json:"hls,omitempty"`   // HLS master playlist for adaptive streaming
+	DASH              string             `json:"dash,omitempty"`  // DASH manifest for adaptive streaming
+	Thumbnail         string             `json:"thumbnail"`
+	Duration          int                `json:"duration"`
+	Description       string             `json:"description"`
+	CreatedAt         time.Time          `json:"created_at"`
+	PublisherID       string             `json:"publisher_id"`
+	PublisherUsername string             `json:"username"`
+	PublisherAvatar   string             `json:"avatar"`
+	Visibility        string             `json:"visibility"`
+	Category          string             `json:"category"`
+	License           string             `json:"license"`
+	Attribution       string             `json:"attribution"`
+	TotalSubscriber   int                `json:"total_subscribers"`
+	TotakLike         int                `json:"total_like"`
+	TotalView         int                `json:"total_view"`
+	Subtitles         []subtitleResponse `json:"subtitles"`
+	Version           int                `json:"version"` // version of the title and description, for EditVideo
+	AgeRestricted     bool               `json:"age_restricted"`
+}
+
+// Subtitles of a video in a language
+type subtitleResponse struct {
+	Language      string `json:"language"`
+	URL           string `json:"url"`
+	AutoGenerated bool   `json:"auto_generated"`
 }
 
 // HandleGetVideo handles the GET request for video.
-// endpoint: GET /videos/{id}?resolution=...
-// Success: 200
-// Fail: 400, 403, 404, 500
+// 'codecs' is the comma separated list of codecs the client can decode (h264, vp9, av1), default to h264.
+// A video moved to the cold storage is restored in background, the client is asked to retry with 202.
+// An age restricted video needs the access token of a signed-in viewer in the Authorization header.
+// The response has a weak ETag, 304 is returned when it matches If-None-Match.
+// endpoint: GET /videos/{id}?resolution=...&codecs=...
+// Success: 200, 202, 304
+// Fail: 400, 401, 403, 404, 500
 func (server *Server) HandleGetVideo(w http.ResponseWriter, r *http.Request) {
 	// Get video ID
 	id := r.PathValue("id")
@@ -165,11 +276,11 @@ func (server *Server) HandleGetVideo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get video
-	video, err := server.query.GetVideo(r.Context(), videoUuid)
+	video, err := server.getVideo(r.Context(), videoUuid)
 	if err != nil {
 		// If video ID didn't match any record
 		if errors.Is(err, sql.ErrNoRows) {
-			server.WriteError(w, http.StatusNotFound, "Cannot found any video with this ID")
+			server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
 			return
 		}
 
@@ -181,26 +292,63 @@ func (server *Server) HandleGetVideo(w http.ResponseWriter, r *http.Request) {
 
 	// Check video status
 	switch video.Status {
-	case db.VideoStatusDeleted:
-		server.WriteError(w, http.StatusForbidden, "Video is deleted")
 	case db.VideoStatusPending:
-		server.WriteError(w, http.StatusBadRequest, "Video is not available for now")
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVideoNotReady, "Video is not available for now", nil)
+		return
+	case db.VideoStatusHeld:
+		server.WriteErrorCode(w, http.StatusForbidden, CodeVideoHeld, "Video is held for review", nil)
+		return
+	case db.VideoStatusFailed:
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVideoFailed, "Video processing failed", nil)
+		return
+	}
+
+	// An age restricted video is only shown to signed-in viewers, and must not be kept by shared caches
+	if video.AgeRestricted {
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if server.mediaClaims(r) == nil {
+			server.WriteErrorCode(w, http.StatusUnauthorized, CodeVideoAgeRestricted,
+				"Video is age restricted, please sign in to watch it", nil)
+			return
+		}
+	}
+
+	// The renditions of a stale video are in the cold storage, restore them before serving the video
+	if video.ColdAt.Valid && server.coldStorage != nil {
+		if err := server.thawVideo(w, r, video.VideoID, video.AccountID); err != nil {
+			server.logger.Error("GET /videos/{id}: failed to enqueue thaw job", "error", err)
+			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		server.WriteJSON(w, http.StatusAccepted, "Video is processing, please try again later")
+		return
 	}
 
 	// Get video based on request parameter
 	resourceName := video.VideoID.String()
+	var codec file.VideoCodec
 	switch r.URL.Query().Get("resolution") {
 	case "":
 		resourceName += ".mp4"
-	case "1080p":
-		resourceName += "_1080p.mp4"
-	case "720p":
-		resourceName += "_720p.mp4"
-	case "480p":
-		resourceName += "_480p.mp4"
+		// The original file is removed by retention policy, fallback to the best rendition available
+		if video.OriginalRemovedAt.Valid {
+			resourceName = file.BestRendition(server.storage, video.AccountID.String(), video.VideoID.String())
+		}
 	default:
-		server.WriteError(w, http.StatusBadRequest, "Unsupport resolution")
-		return
+		// Any rung of the ladder, for example: 1080p
+		resolution := r.URL.Query().Get("resolution")
+		if file.ParseResolution(resolution) == 0 {
+			server.WriteError(w, http.StatusBadRequest, "Unsupport resolution")
+			return
+		}
+
+		// Serve the best codec the client accepts among the ones the rendition is available in
+		available := file.RenditionCodecs(server.storage, video.AccountID.String(), video.VideoID.String(), resolution)
+		codec = file.BestCodec(parseAcceptedCodecs(r.URL.Query().Get("codecs")), available)
+		if codec == "" {
+			codec = file.CodecH264
+		}
+		resourceName += fmt.Sprintf("_%s.mp4", file.RenditionName(resolution, codec))
 	}
 
 	// Send data back to client
@@ -209,10 +357,36 @@ func (server *Server) HandleGetVideo(w http.ResponseWriter, r *http.Request) {
 		video.AccountID.String(), fmt.Sprintf("%s.png", video.VideoID.String()), file.Thumbnail,
 	)
 	avatar := server.mediaService.GenerateMediaLink(video.AccountID.String(), "avatar.png", file.Avatar)
+	var hls, dash string
+	if file.HasHLS(server.storage, video.AccountID.String(), video.VideoID.String()) {
+		hls = server.mediaService.GenerateHLSLink(video.AccountID.String(), video.VideoID.String())
+	}
+	if file.HasDASH(server.storage, video.AccountID.String(), video.VideoID.String()) {
+		dash = server.mediaService.GenerateDASHLink(video.AccountID.String(), video.VideoID.String())
+	}
+	subtitles, err := server.query.ListSubtitles(r.Context(), video.VideoID)
+	if err != nil {
+		server.logger.Error("GET /videos/{id}: failed to list subtitles", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	subtitleList := make([]subtitleResponse, 0, len(subtitles))
+	for _, subtitle := range subtitles {
+		subtitleList = append(subtitleList, subtitleResponse{
+			Language: subtitle.Language,
+			URL: server.mediaService.GenerateMediaLink(video.AccountID.String(),
+				fmt.Sprintf("%s_%s.vtt", video.VideoID.String(), subtitle.Language), file.Subtitles),
+			AutoGenerated: subtitle.AutoGenerated,
+		})
+	}
+
 	data := getVideoResponse{
 		ID:                video.VideoID.String(),
 		Title:             video.Title,
 		Resource:          resource,
+		Codec:             string(codec),
+		HLS:               hls,
+		DASH:              dash,
 		Thumbnail:         thumbnail,
 		Duration:          int(video.Duration),
 		Description:       video.Description.String,
@@ -220,9 +394,482 @@ func (server *Server) HandleGetVideo(w http.ResponseWriter, r *http.Request) {
 		PublisherID:       video.AccountID.String(),
 		PublisherUsername: video.Username,
 		PublisherAvatar:   avatar,
+		Visibility:        string(video.Visibility),
+		Category:          video.Category.String,
+		License:           string(video.License),
+		Attribution:       video.Attribution.String,
 		TotalSubscriber:   int(video.TotalSubscriber),
 		TotakLike:         int(video.TotalLike),
 		TotalView:         int(video.TotalView),
+		Subtitles:         subtitleList,
+		Version:           int(video.Version),
+		AgeRestricted:     video.AgeRestricted,
+	}
+
+	server.WriteJSONWithETag(w, r, http.StatusOK, data)
+}
+
+// Request body for EditVideo, the fields not sent are kept
+type editVideoRequest struct {
+	Title       *string `json:"title" validate:"omitempty,min=1,max=50"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+	Version     int32   `json:"version" validate:"required,min=1"`
+}
+
+// Response body for EditVideo, also the details of a conflict
+type editVideoResponse struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     int    `json:"version"`
+}
+
+// HandleEditVideo edits the title and the description of a video of the requester. The edit is made from the version
+// of the video returned by GetVideo, if the video has been edited since then, 409 is returned with the latest title
+// and description in details.
+// endpoint: PATCH /videos/{id}
+// Success: 200
+// Fail: 400, 403, 404, 409, 500
+func (server *Server) HandleEditVideo(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Check if requester account status is active or not
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "PATCH /videos/{id}"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	// Get request body
+	var req editVideoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Get the current video, the fields not sent are kept
+	video, err := server.getLatestVideo(r.Context(), videoID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		server.logger.Error("PATCH /videos/{id}: failed to get video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if err != nil {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+		return
+	}
+	if video.AccountID != accountID {
+		server.WriteError(w, http.StatusForbidden, "Only the publisher can edit this video")
+		return
+	}
+
+	latest := editVideoResponse{
+		ID:          video.VideoID.String(),
+		Title:       video.Title,
+		Description: video.Description.String,
+		Version:     int(video.Version),
+	}
+	if req.Version != video.Version {
+		server.WriteErrorCode(w, http.StatusConflict, CodeEditConflict, "Video was edited since this version", latest)
+		return
+	}
+
+	title := video.Title
+	if req.Title != nil {
+		title = strings.TrimSpace(*req.Title)
+		if title == "" {
+			server.WriteError(w, http.StatusBadRequest, "Title cannot be empty")
+			return
+		}
+	}
+	description := video.Description
+	if req.Description != nil {
+		desc := strings.TrimSpace(*req.Description)
+		description = sql.NullString{String: desc, Valid: desc != ""}
+	}
+	if !server.checkBlockedWords(w, r, accountID, title, description.String) {
+		return
+	}
+
+	// Update video, only if it hasn't been edited since the version
+	version, err := server.query.EditVideo(r.Context(), db.EditVideoParams{
+		VideoID:     videoID,
+		PublisherID: accountID,
+		Version:     req.Version,
+		Title:       title,
+		Description: description,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Edited (or deleted) between the read and the update, return the latest state
+		video, err := server.getLatestVideo(r.Context(), videoID)
+		if err != nil {
+			server.logger.Error("PATCH /videos/{id}: failed to get video", "error", err)
+			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		latest.Title, latest.Description, latest.Version = video.Title, video.Description.String, int(video.Version)
+		server.WriteErrorCode(w, http.StatusConflict, CodeEditConflict, "Video was edited since this version", latest)
+		return
+	}
+	if err != nil {
+		if errors.Is(db.MapError(err), db.ErrDuplicateTitle) {
+			server.WriteError(w, http.StatusConflict, "Title is already used by another video")
+			return
+		}
+		server.logger.Error("PATCH /videos/{id}: failed to edit video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	server.invalidateVideos(r.Context(), videoID)
+
+	// Return the edited video back to client
+	server.WriteJSON(w, http.StatusOK, editVideoResponse{
+		ID:          videoID.String(),
+		Title:       title,
+		Description: description.String,
+		Version:     int(version),
+	})
+}
+
+// Maximum number of videos that can be managed in a single bulk request
+const maxBulkVideos = 50
+
+// Request body for BulkVideos
+type bulkVideoRequest struct {
+	VideoIDs    []uuid.UUID `json:"video_ids" validate:"required,min=1"`
+	Action      string      `json:"action" validate:"required,oneof=visibility category license delete"`
+	Visibility  string      `json:"visibility" validate:"required_if=Action visibility,omitempty,oneof=public unlisted private"`
+	Category    string      `json:"category" validate:"max=30"`
+	License     string      `json:"license" validate:"required_if=Action license,omitempty,license"`
+	Attribution string      `json:"attribution" validate:"max=255"`
+}
+
+// Result of the bulk action on a single video
+type bulkVideoResult struct {
+	VideoID string `json:"video_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// HandleBulkVideos applies the same action (change visibility, change category, change license or delete) on multiple videos
+// of the requester in a single database transaction. Videos that don't exist or don't belong to the requester
+// are reported as failed without affecting the others.
+// endpoint: POST /videos/bulk
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleBulkVideos(w http.ResponseWriter, r *http.Request) {
+	// Get request body
+	var req bulkVideoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	if len(req.VideoIDs) > maxBulkVideos {
+		server.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Cannot manage more than %d videos at once", maxBulkVideos))
+		return
+	}
+
+	// Check if requester account status is active or not
+	var accountID uuid.UUID
+	accountID.Scan(r.Context().Value(clKey).(*security.CustomClaims).ID)
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /videos/bulk"))
+	if _, isActive := server.checkAccountStatus(w, r, accountID); !isActive {
+		return
+	}
+
+	// Apply the action on each video inside a transaction
+	results := make([]bulkVideoResult, 0, len(req.VideoIDs))
+	seen := make(map[uuid.UUID]bool)
+	err := server.query.ExecTx(r.Context(), func(q *db.Queries) error {
+		// Start over if the transaction is retried
+		results = results[:0]
+		clear(seen)
+
+		for _, videoID := range req.VideoIDs {
+			// Skip duplicated ID
+			if seen[videoID] {
+				continue
+			}
+			seen[videoID] = true
+
+			var (
+				affected int64
+				err      error
+			)
+			switch req.Action {
+			case "visibility":
+				affected, err = q.UpdateVideoVisibility(r.Context(), db.UpdateVideoVisibilityParams{
+					VideoID:     videoID,
+					PublisherID: accountID,
+					Visibility:  db.VideoVisibility(req.Visibility),
+				})
+			case "category":
+				affected, err = q.UpdateVideoCategory(r.Context(), db.UpdateVideoCategoryParams{
+					VideoID:     videoID,
+					PublisherID: accountID,
+					Category:    sql.NullString{String: req.Category, Valid: req.Category != ""},
+				})
+			case "license":
+				affected, err = q.UpdateVideoLicense(r.Context(), db.UpdateVideoLicenseParams{
+					VideoID:     videoID,
+					PublisherID: accountID,
+					License:     db.VideoLicense(req.License),
+					Attribution: sql.NullString{String: req.Attribution, Valid: req.Attribution != ""},
+				})
+			case "delete":
+				affected, err = q.DeleteVideo(r.Context(), db.DeleteVideoParams{
+					VideoID:     videoID,
+					PublisherID: accountID,
+				})
+			}
+
+			// Database error abort the whole transaction
+			if err != nil {
+				return err
+			}
+
+			// Nothing get updated means the video does not exist, is already deleted or not owned by requester
+			result := bulkVideoResult{VideoID: videoID.String(), Success: affected > 0}
+			if !result.Success {
+				result.Error = "Video not found or not owned by requester"
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+
+	if err != nil {
+		server.logger.Error("POST /videos/bulk: failed to execute bulk action", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	for videoID := range seen {
+		server.invalidateVideos(r.Context(), videoID)
+	}
+
+	// Return the per-video result back to client
+	server.WriteJSON(w, http.StatusOK, results)
+}
+
+// Licenses that a video can be published under
+var videoLicenses = []db.VideoLicense{
+	db.VideoLicenseStandard,
+	db.VideoLicenseCcBy,
+	db.VideoLicenseCcBySa,
+	db.VideoLicenseCcByNd,
+	db.VideoLicenseCcByNc,
+	db.VideoLicenseCcByNcSa,
+	db.VideoLicenseCcByNcNd,
+	db.VideoLicenseCc0,
+}
+
+// Helper function: check if the license is supported
+func isValidLicense(license string) bool {
+	return slices.Contains(videoLicenses, db.VideoLicense(license))
+}
+
+// Helper function: parse the comma separated list of codecs accepted by the client, unknown codecs are ignored
+func parseAcceptedCodecs(value string) []file.VideoCodec {
+	var codecs []file.VideoCodec
+	for _, name := range strings.Split(value, ",") {
+		if codec, err := file.ParseVideoCodec(strings.TrimSpace(name)); err == nil {
+			codecs = append(codecs, codec)
+		}
+	}
+	return codecs
+}
+
+// Helper method: probe the uploaded video and validate it against the upload limits.
+// It returns file.MediaErrors if the video violates the limits, an error wrapping file.ErrUnsupportedMedia if
+// the file is not a valid video, or other error if the probing itself failed
+func (server *Server) checkUploadedVideo(filename string) error {
+	info, err := server.mediaService.Probe(filename)
+	if err != nil {
+		// ffprobe is not available, this is our fault
+		if errors.Is(err, exec.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", file.ErrUnsupportedMedia, err)
+	}
+
+	return server.mediaService.ValidateMedia(info)
+}
+
+// Helper method: discard a rejected video by marking it as deleted and removing its files in background
+func (server *Server) discardVideo(ctx context.Context, accountID, videoID uuid.UUID, files ...string) {
+	if err := server.jobs.Enqueue(ctx, job.TypeCleanup, cleanupPayload{Files: files}); err != nil {
+		server.logger.Error("failed to enqueue cleanup of discarded video", "video_id", videoID, "error", err)
+	}
+
+	_, err := server.query.DeleteVideo(ctx, db.DeleteVideoParams{VideoID: videoID, PublisherID: accountID})
+	if err != nil {
+		server.logger.Error("failed to delete discarded video", "video_id", videoID, "error", err)
+	}
+	server.invalidateVideos(ctx, videoID)
+}
+
+// A video in the search result
+type searchVideoResult struct {
+	ID                string    `json:"id"`
+	Title             string    `json:"title"`
+	Thumbnail         string    `json:"thumbnail"`
+	Duration          int       `json:"duration"`
+	CreatedAt         time.Time `json:"created_at"`
+	License           string    `json:"license"`
+	Attribution       string    `json:"attribution"`
+	PublisherID       string    `json:"publisher_id"`
+	PublisherUsername string    `json:"username"`
+}
+
+// HandleSearchVideos searches public videos by title, description and category, and can be filtered by license to
+// discover reusable content. With a keyword the videos are sorted by relevance (sort=relevance, default) or newest
+// first (sort=recent), without a keyword they are sorted newest first.
+// The newest first pages are requested with the cursor returned in X-Next-Cursor, page numbers are kept for older
+// clients; the relevance pages are requested with page numbers.
+// endpoint: GET /videos?q=...&sort=...&license=...&reusable=true&cursor=...&size=... (or page=... instead of cursor)
+// Success: 200
+// Fail: 400, 500
+func (server *Server) HandleSearchVideos(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Get license filter
+	license := query.Get("license")
+	if license != "" && !isValidLicense(license) {
+		server.WriteError(w, http.StatusBadRequest, "Unsupported license")
+		return
+	}
+
+	reusable := false
+	if value := query.Get("reusable"); value != "" {
+		var err error
+		if reusable, err = strconv.ParseBool(value); err != nil {
+			server.WriteError(w, http.StatusBadRequest, "Invalid reusable value")
+			return
+		}
+	}
+
+	// Get the keyword and the sort order
+	keyword := strings.TrimSpace(query.Get("q"))
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "recent"
+		if keyword != "" {
+			sort = "relevance"
+		}
+	}
+	if sort != "relevance" && sort != "recent" {
+		server.WriteError(w, http.StatusBadRequest, "Invalid sort, only accept relevance or recent")
+		return
+	}
+	if sort == "relevance" && keyword == "" {
+		server.WriteError(w, http.StatusBadRequest, "Sorting by relevance requires a search keyword")
+		return
+	}
+
+	// Get pagination
+	cursor, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+	page, ok := server.parsePageNumber(w, r)
+	if !ok {
+		return
+	}
+	if cursor != nil && (page > 1 || sort == "relevance") {
+		server.WriteError(w, http.StatusBadRequest, "Cursor is only supported when sorting by recent, without page")
+		return
+	}
+
+	// A keyword without any word can't match anything
+	tsquery := searchQuery(keyword)
+	if keyword != "" && tsquery == "" {
+		server.WriteJSON(w, http.StatusOK, []searchVideoResult{})
+		return
+	}
+
+	// Search videos
+	var videos []db.SearchVideosRow
+	var err error
+	if sort == "relevance" {
+		var ranked []db.SearchVideosRankedRow
+		ranked, err = server.query.SearchVideosRanked(r.Context(), db.SearchVideosRankedParams{
+			Query:      tsquery,
+			License:    license,
+			Reusable:   reusable,
+			TenantID:   tenantOf(r.Context()),
+			PageSize:   int32(size),
+			PageOffset: int32((page - 1) * size),
+		})
+		for _, video := range ranked {
+			videos = append(videos, db.SearchVideosRow{
+				VideoID:     video.VideoID,
+				Title:       video.Title,
+				Duration:    video.Duration,
+				CreatedAt:   video.CreatedAt,
+				License:     video.License,
+				Attribution: video.Attribution,
+				AccountID:   video.AccountID,
+				Username:    video.Username,
+			})
+		}
+	} else {
+		afterCreatedAt, afterID := cursor.params()
+		videos, err = server.query.SearchVideos(r.Context(), db.SearchVideosParams{
+			Query:          tsquery,
+			License:        license,
+			Reusable:       reusable,
+			TenantID:       tenantOf(r.Context()),
+			AfterCreatedAt: afterCreatedAt,
+			AfterID:        afterID,
+			PageSize:       int32(size),
+			PageOffset:     int32((page - 1) * size),
+		})
+	}
+	if err != nil {
+		server.logger.Error("GET /videos: failed to search videos", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Return the result back to client
+	data := make([]searchVideoResult, 0, len(videos))
+	for _, video := range videos {
+		data = append(data, searchVideoResult{
+			ID:    video.VideoID.String(),
+			Title: video.Title,
+			Thumbnail: server.mediaService.GenerateMediaLink(
+				video.AccountID.String(), fmt.Sprintf("%s.png", video.VideoID.String()), file.Thumbnail,
+			),
+			Duration:          int(video.Duration),
+			CreatedAt:         video.CreatedAt,
+			License:           string(video.License),
+			Attribution:       video.Attribution.String,
+			PublisherID:       video.AccountID.String(),
+			PublisherUsername: video.Username,
+		})
+	}
+	if sort == "recent" && len(videos) > 0 {
+		last := videos[len(videos)-1]
+		setNextCursor(w, len(videos), size, last.CreatedAt, last.VideoID)
 	}
 
 	server.WriteJSON(w, http.StatusOK, data)
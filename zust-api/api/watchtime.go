@@ -0,0 +1,32 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Method to roll up the daily watch time of the videos from the playback events. The days since the latest rolled up
+// day are recomputed, and at least the previous day: an event can be sent up to a day after it happened. A day is
+// recomputed as a whole, so running the job again (or on several instances) gives the same rows
+func (server *Server) runWatchTimeJob(ctx context.Context) {
+	start := time.Now()
+	latest, err := server.query.GetLatestWatchTimeDay(ctx)
+	if err != nil {
+		server.logger.Error("watch_time: failed to get latest rolled up day", "error", err)
+		return
+	}
+
+	now := start.UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.UTC)
+	if latest.Before(since) {
+		since = latest
+	}
+
+	rows, err := server.query.RollupVideoWatchTime(ctx, since)
+	if err != nil {
+		server.logger.Error("watch_time: failed to roll up watch time", "error", err)
+		return
+	}
+	server.logger.Debug("watch_time: watch time rolled up", "since", since, "rows", rows,
+		"duration", time.Since(start))
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	db "zust/db/sqlc"
+	"zust/service/file"
+
+	"github.com/google/uuid"
+)
+
+// HandleSetWatermark sets the watermark overlaid on the videos of a channel, it replaces the default watermark of
+// the deployment. The request is a multipart form with the PNG 'image' (optional when only updating the position
+// or opacity), 'position' (top-left, top-right, bottom-left, bottom-right) and 'opacity' ([0, 1]).
+// Only videos transcoded after the update are affected.
+// endpoint: PUT /accounts/{id}/watermark
+// Success: 200
+// Fail: 400, 403, 413, 500, 507
+func (server *Server) HandleSetWatermark(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Check account status if it's active or not before processing with the request
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "PUT /accounts/{id}/watermark"))
+	if _, isActive := server.checkAccountStatus(w, r, accID); !isActive {
+		return
+	}
+
+	// Reject the upload early if the storage is almost full
+	if !server.checkStorageSpace(w) {
+		return
+	}
+
+	position := r.FormValue("position")
+	if position == "" {
+		position = string(db.WatermarkPositionBottomRight)
+	}
+	if !file.IsValidWatermarkPosition(position) {
+		server.WriteError(w, http.StatusBadRequest, "Invalid watermark position")
+		return
+	}
+
+	opacity := 0.5
+	if value := r.FormValue("opacity"); value != "" {
+		var err error
+		if opacity, err = strconv.ParseFloat(value, 32); err != nil || opacity < 0 || opacity > 1 {
+			server.WriteError(w, http.StatusBadRequest, "Invalid watermark opacity")
+			return
+		}
+	}
+
+	// Get the watermark image if provided, it must be a PNG so it can have transparency
+	key := file.WatermarkKey(accID.String())
+	image, _, err := r.FormFile("image")
+	switch {
+	case err == nil:
+		defer image.Close()
+		data, err := io.ReadAll(image)
+		if err != nil {
+			server.WriteError(w, http.StatusBadRequest, "Invalid watermark image")
+			return
+		}
+		if http.DetectContentType(data) != "image/png" {
+			server.WriteError(w, http.StatusBadRequest, "Watermark image must be a PNG")
+			return
+		}
+
+		if err := server.storage.Put(key, bytes.NewReader(data)); err != nil {
+			server.logger.Error("PUT /accounts/{id}/watermark: failed to save watermark image", "error", err)
+			server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+	case errors.Is(err, http.ErrMissingFile):
+		// Keep the current image, there must be one
+		if !file.Exists(server.storage, key) {
+			server.WriteError(w, http.StatusBadRequest, "Watermark image is required")
+			return
+		}
+	default:
+		server.writeFormError(w, err, "Invalid watermark image")
+		return
+	}
+
+	err = server.query.UpsertWatermark(r.Context(), db.UpsertWatermarkParams{
+		AccountID: accID,
+		Position:  db.WatermarkPosition(position),
+		Opacity:   float32(opacity),
+	})
+	if err != nil {
+		server.logger.Error("PUT /accounts/{id}/watermark: failed to set watermark", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Watermark updated successfully")
+}
+
+// HandleDeleteWatermark removes the watermark of a channel, its videos fall back to the default watermark of the
+// deployment (if any).
+// endpoint: DELETE /accounts/{id}/watermark
+// Success: 200
+// Fail: 400, 403, 500
+func (server *Server) HandleDeleteWatermark(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Check account status if it's active or not before processing with the request
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "DELETE /accounts/{id}/watermark"))
+	if _, isActive := server.checkAccountStatus(w, r, accID); !isActive {
+		return
+	}
+
+	if err := server.query.DeleteWatermark(r.Context(), accID); err != nil {
+		server.logger.Error("DELETE /accounts/{id}/watermark: failed to delete watermark", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if err := server.storage.Delete(file.WatermarkKey(accID.String())); err != nil {
+		server.logger.Error("DELETE /accounts/{id}/watermark: failed to remove watermark image", "error", err)
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Watermark removed successfully")
+}
+
+// Helper method: get the watermark overlaid on the videos of a channel: its own watermark, whose image is fetched
+// into the work directory 'work', or the default one of the deployment. It returns nil if there is none
+func (server *Server) watermarkFor(ctx context.Context, accountID uuid.UUID, work string) (*file.Watermark, error) {
+	watermark, err := server.query.GetWatermark(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return server.mediaService.Watermark, nil
+		}
+		return nil, err
+	}
+
+	image := filepath.Join(work, "watermark.png")
+	if err := file.Fetch(server.storage, file.WatermarkKey(accountID.String()), image); err != nil {
+		return nil, err
+	}
+
+	return &file.Watermark{
+		Image:    image,
+		Position: string(watermark.Position),
+		Opacity:  float64(watermark.Opacity),
+	}, nil
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	db "zust/db/sqlc"
+	"zust/service/file"
+
+	"github.com/google/uuid"
+)
+
+// HandleGetWaveform returns the peak waveform of the audio of a video, for players to render a scrubber.
+// The waveform has a fixed number of peaks in [0, 1], each covering the same part of the duration.
+// endpoint: GET /videos/{id}/waveform
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleGetWaveform(w http.ResponseWriter, r *http.Request) {
+	// Get video ID
+	var videoID uuid.UUID
+	if err := videoID.Scan(r.PathValue("id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid video ID")
+		return
+	}
+
+	// Get video
+	video, err := server.getVideo(r.Context(), videoID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			server.WriteErrorCode(w, http.StatusNotFound, CodeVideoNotFound, "Cannot found any video with this ID", nil)
+			return
+		}
+
+		server.logger.Error("GET /videos/{id}/waveform: failed to get video", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// The waveform is generated during processing, only published videos have it
+	switch video.Status {
+	case db.VideoStatusHeld:
+		server.WriteErrorCode(w, http.StatusForbidden, CodeVideoHeld, "Video is held for review", nil)
+		return
+	case db.VideoStatusPending, db.VideoStatusFailed:
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeVideoNotReady, "Video is not available for now", nil)
+		return
+	}
+
+	data, err := file.ReadFile(server.storage, file.WaveformKey(video.AccountID.String(), video.VideoID.String()))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			server.WriteError(w, http.StatusNotFound, "Video has no audio waveform")
+			return
+		}
+
+		server.logger.Error("GET /videos/{id}/waveform: failed to read waveform", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, json.RawMessage(data))
+}
@@ -0,0 +1,406 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/job"
+	"zust/service/webhook"
+
+	"github.com/google/uuid"
+)
+
+// Events sent to the webhooks of the creators
+const (
+	webhookVideoPublished      = "video.published"
+	webhookProcessingCompleted = "video.processing_completed"
+	webhookProcessingFailed    = "video.processing_failed"
+	webhookSubscriberNew       = "subscriber.new"
+)
+
+// Maximum number of webhooks of an account, and how often the deliveries older than the retention are deleted
+const (
+	maxWebhooks            = 10
+	webhookCleanupInterval = 24 * time.Hour
+)
+
+// Request body for CreateWebhook
+type webhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=255"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=video.published video.processing_completed video.processing_failed subscriber.new"`
+}
+
+// Response body of a webhook, the secret is only returned when the webhook is created
+type webhookResponse struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Response body of a delivery in the delivery log of a webhook, with the result of its last attempt
+type webhookDeliveryResponse struct {
+	ID            uuid.UUID       `json:"id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	StatusCode    int32           `json:"status_code,omitempty"` // none if the webhook didn't respond
+	Error         string          `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	LastAttemptAt *time.Time      `json:"last_attempt_at,omitempty"`
+}
+
+// Payload of the webhook delivery job, the request is loaded from the delivery log so the retries send the same one
+type webhookPayload struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+// Data of the subscriber.new event
+type subscriberEventData struct {
+	SubscriberID uuid.UUID `json:"subscriber_id"`
+	Username     string    `json:"username"`
+	SubscribedAt time.Time `json:"subscribed_at"`
+}
+
+// Helper function: build the response of a webhook
+func toWebhookResponse(hook db.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:        hook.WebhookID,
+		URL:       hook.Url,
+		Events:    hook.Events,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+// HandleListWebhooks lists the webhooks of the account, without their secret.
+// endpoint: GET /accounts/{id}/webhooks
+// Success: 200
+// Fail: 403, 500
+func (server *Server) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	hooks, err := server.query.ListWebhooks(r.Context(), accID)
+	if err != nil {
+		server.logger.Error("GET /accounts/{id}/webhooks: failed to list webhooks", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	data := make([]webhookResponse, len(hooks))
+	for i, hook := range hooks {
+		data[i] = toWebhookResponse(hook)
+	}
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// HandleCreateWebhook registers a webhook receiving the events of the account it's subscribed to: video.published,
+// video.processing_completed, video.processing_failed and subscriber.new. The requests are signed with the secret of
+// the webhook in the X-Zust-Signature header, the secret is only returned here.
+// endpoint: POST /accounts/{id}/webhooks
+// Success: 201
+// Fail: 400, 403, 409, 500
+func (server *Server) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	// Get request body
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		server.WriteErrorCode(w, http.StatusBadRequest, CodeInvalidRequestBody, "Invalid request body", nil)
+		return
+	}
+
+	// Validate request body
+	if err := server.validate.Struct(req); err != nil {
+		server.writeValidationError(w, err)
+		return
+	}
+
+	// Check account status if it's active or not before processing with the request
+	var accID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	r = r.WithContext(context.WithValue(r.Context(), epKey, "POST /accounts/{id}/webhooks"))
+	if _, isActive := server.checkAccountStatus(w, r, accID); !isActive {
+		return
+	}
+
+	// Check the number of webhooks of the account
+	count, err := server.query.CountWebhooks(r.Context(), accID)
+	if err != nil {
+		server.logger.Error("POST /accounts/{id}/webhooks: failed to count webhooks", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if count >= maxWebhooks {
+		server.WriteErrorCode(w, http.StatusConflict, CodeWebhookLimitReached, "Too many webhooks", nil)
+		return
+	}
+
+	// Create webhook with a new secret
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		server.logger.Error("POST /accounts/{id}/webhooks: failed to generate webhook secret", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	slices.Sort(req.Events)
+	hook, err := server.query.CreateWebhook(r.Context(), db.CreateWebhookParams{
+		AccountID: accID,
+		Url:       req.URL,
+		Secret:    secret,
+		Events:    slices.Compact(req.Events),
+	})
+	if err != nil {
+		server.logger.Error("POST /accounts/{id}/webhooks: failed to create webhook", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	result := toWebhookResponse(hook)
+	result.Secret = hook.Secret
+	server.WriteJSON(w, http.StatusCreated, result)
+}
+
+// HandleDeleteWebhook deletes a webhook of the account along with its delivery log, the pending deliveries are
+// dropped.
+// endpoint: DELETE /accounts/{id}/webhooks/{webhook_id}
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accID, webhookID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	if err := webhookID.Scan(r.PathValue("webhook_id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	deleted, err := server.query.DeleteWebhook(r.Context(), db.DeleteWebhookParams{
+		WebhookID: webhookID,
+		AccountID: accID,
+	})
+	if err != nil {
+		server.logger.Error("DELETE /accounts/{id}/webhooks/{webhook_id}: failed to delete webhook", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeWebhookNotFound, "Webhook not found", nil)
+		return
+	}
+
+	server.WriteJSON(w, http.StatusOK, "Webhook deleted successfully")
+}
+
+// HandleListWebhookDeliveries lists the deliveries of a webhook of the account, latest first, with the result of
+// their last attempt. The next page is requested with the cursor returned in X-Next-Cursor.
+// endpoint: GET /accounts/{id}/webhooks/{webhook_id}/deliveries?cursor=...&size=...
+// Success: 200
+// Fail: 400, 403, 404, 500
+func (server *Server) HandleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	// Check if the account ID in path parameter match with the ID extract from access token
+	if isIDMatched := server.checkIDMatch(w, r, r.PathValue("id")); !isIDMatched {
+		return
+	}
+
+	var accID, webhookID uuid.UUID
+	accID.Scan(r.PathValue("id"))
+	if err := webhookID.Scan(r.PathValue("webhook_id")); err != nil {
+		server.WriteError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+
+	// Get pagination
+	cursor, size, ok := server.parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	// The webhook must be one of the account
+	hook, err := server.query.GetWebhook(r.Context(), webhookID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		server.logger.Error("GET /accounts/{id}/webhooks/{webhook_id}/deliveries: failed to get webhook", "error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if err != nil || hook.AccountID != accID {
+		server.WriteErrorCode(w, http.StatusNotFound, CodeWebhookNotFound, "Webhook not found", nil)
+		return
+	}
+
+	// List deliveries
+	afterCreatedAt, afterID := cursor.params()
+	deliveries, err := server.query.ListWebhookDeliveries(r.Context(), db.ListWebhookDeliveriesParams{
+		WebhookID:      webhookID,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		PageSize:       int32(size),
+	})
+	if err != nil {
+		server.logger.Error("GET /accounts/{id}/webhooks/{webhook_id}/deliveries: failed to list deliveries",
+			"error", err)
+		server.WriteError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Return the result back to client
+	data := make([]webhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		data[i] = webhookDeliveryResponse{
+			ID:         delivery.DeliveryID,
+			Event:      delivery.Event,
+			Payload:    delivery.Payload,
+			Status:     string(delivery.Status),
+			Attempts:   delivery.Attempts,
+			StatusCode: delivery.StatusCode.Int32,
+			Error:      delivery.Error.String,
+			CreatedAt:  delivery.CreatedAt,
+		}
+		if delivery.LastAttemptAt.Valid {
+			data[i].LastAttemptAt = &delivery.LastAttemptAt.Time
+		}
+	}
+	if len(deliveries) > 0 {
+		last := deliveries[len(deliveries)-1]
+		setNextCursor(w, len(deliveries), size, last.CreatedAt, last.DeliveryID)
+	}
+
+	server.WriteJSON(w, http.StatusOK, data)
+}
+
+// Method to send an event of an account to its webhooks subscribed to it: a delivery is logged for each webhook, and
+// sent in background. The data is only built when a webhook is subscribed to the event. Failure is only logged
+func (server *Server) emitWebhookEvent(ctx context.Context, accountID uuid.UUID, event string,
+	data func() (any, error)) {
+	hooks, err := server.query.ListEventWebhooks(ctx, db.ListEventWebhooksParams{AccountID: accountID, Event: event})
+	if err != nil {
+		server.logger.Error("webhook: failed to list webhooks", "account_id", accountID, "event", event, "error", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	// The payload is the same as the processing webhook: the event, when it happened, and its data
+	eventData, err := data()
+	if err != nil {
+		server.logger.Error("webhook: failed to build event data", "account_id", accountID, "event", event, "error", err)
+		return
+	}
+	payload, err := json.Marshal(map[string]any{
+		"event":     event,
+		"timestamp": time.Now().UTC(),
+		"data":      eventData,
+	})
+	if err != nil {
+		server.logger.Error("webhook: failed to marshal webhook payload", "error", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		delivery, err := server.query.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			WebhookID: hook.WebhookID,
+			Event:     event,
+			Payload:   payload,
+		})
+		if err != nil {
+			server.logger.Error("webhook: failed to create delivery", "webhook_id", hook.WebhookID, "error", err)
+			continue
+		}
+
+		err = server.jobs.Enqueue(ctx, job.TypeWebhook, webhookPayload{DeliveryID: delivery.DeliveryID},
+			job.WithMaxAttempts(server.config.WebhookMaxAttempts))
+		if err != nil {
+			server.logger.Error("webhook: failed to enqueue delivery", "delivery_id", delivery.DeliveryID, "error", err)
+		}
+	}
+}
+
+// Helper method: get the builder of the data of a video event, the processing status of the video. The events run
+// outside of a request, so the video is checked against the account of the webhooks instead of the tenant
+func (server *Server) videoEventData(ctx context.Context, accountID, videoID uuid.UUID) func() (any, error) {
+	return func() (any, error) {
+		video, err := server.query.GetVideo(ctx, videoID)
+		if err != nil {
+			return nil, err
+		}
+		if video.AccountID != accountID {
+			return nil, fmt.Errorf("video %s is not published by account %s", videoID, accountID)
+		}
+		return server.buildProcessingResponse(ctx, videoID, video.Status)
+	}
+}
+
+// Method to handle the webhook delivery job: the logged request is sent, and the result of the attempt is logged.
+// A failed attempt is retried by the job queue, until WEBHOOK_MAX_ATTEMPTS. The deliveries of a deleted webhook are
+// dropped
+func (server *Server) handleWebhookJob(ctx context.Context, j *job.Job) error {
+	var payload webhookPayload
+	if err := j.Decode(&payload); err != nil {
+		return err
+	}
+
+	delivery, err := server.query.GetWebhookDelivery(ctx, payload.DeliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	statusCode, deliverErr := server.webhooks.Deliver(ctx, webhook.Delivery{
+		ID:     delivery.DeliveryID.String(),
+		URL:    delivery.Url,
+		Secret: delivery.Secret,
+		Event:  delivery.Event,
+		Body:   delivery.Payload,
+	})
+
+	// Log the result of the attempt
+	result := db.UpdateWebhookDeliveryParams{
+		DeliveryID: delivery.DeliveryID,
+		Status:     db.WebhookDeliveryStatusSucceeded,
+		Attempts:   int32(j.Attempts),
+		StatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0},
+	}
+	if deliverErr != nil {
+		result.Status = db.WebhookDeliveryStatusPending
+		if j.LastAttempt() {
+			result.Status = db.WebhookDeliveryStatusFailed
+		}
+		result.Error = sql.NullString{String: deliverErr.Error(), Valid: true}
+	}
+	if err := server.query.UpdateWebhookDelivery(ctx, result); err != nil {
+		server.logger.Error("webhook: failed to update delivery", "delivery_id", delivery.DeliveryID, "error", err)
+	}
+	return deliverErr
+}
+
+// Method to delete the webhook deliveries older than the retention
+func (server *Server) runWebhookCleanupJob(ctx context.Context) {
+	deleted, err := server.query.DeleteOldWebhookDeliveries(ctx, time.Now().Add(-server.config.WebhookDeliveryRetention))
+	if err != nil {
+		server.logger.Error("webhooks: failed to delete old deliveries", "error", err)
+		return
+	}
+	server.logger.Info("webhooks: old deliveries deleted", "deleted", deleted)
+}
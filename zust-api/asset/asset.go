@@ -0,0 +1,26 @@
+// Package asset holds the default files of a user repository (avatar and cover), embedded into the binary so the
+// server doesn't depend on its working directory
+package asset
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed avatar.png cover.png
+var files embed.FS
+
+// Function to open an asset, from the 'override' directory if it's set and has the file, otherwise from the
+// embedded assets
+func Open(override, name string) (fs.File, error) {
+	if override != "" {
+		file, err := os.Open(filepath.Join(override, name))
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return file, err
+		}
+	}
+	return files.Open(name)
+}
@@ -1,37 +1,150 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"zust/api"
+	"zust/db/migration"
 	"zust/service/security"
-
-	_ "github.com/lib/pq"
 )
 
+// API server. With the 'migrate' subcommand, it only runs the database migrations instead:
+//
+//	migrate up           apply all pending migrations
+//	migrate down [N]     revert the last N migrations (all of them without N)
+//	migrate version      print the version of the database
+//	migrate force V      set the version of the database without running any migration
+//
+// With the 'seed' subcommand, it creates demo accounts and videos for local development instead (-password sets the
+// password of the demo accounts)
 func main() {
 	// Initialize logger
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	// Load config from .env
-	err := security.LoadConfig("./.env")
+	// Load config from the flags, the environment, and the .env file if any
+	envPath := flag.String("env", "./.env", "path of the .env file, the environment is used alone if it doesn't exist")
+	applyFlags := security.EnvFlags(flag.CommandLine)
+	flag.Parse()
+	err := applyFlags()
+	if err == nil {
+		err = security.LoadConfig(*envPath)
+	}
 	if err != nil {
-		logger.Error("Failed to load configurations from .env", "error", err)
+		logger.Error("Failed to load configurations", "error", err)
 		return
 	}
 	config := security.GetConfig()
 
-	// Connect to database
-	conn, err := sql.Open(config.DbDriver, config.DbSource)
+	// Connect to database, waiting for it to be up
+	conn, closeDB, err := api.ConnectDatabase(context.Background(), &config, logger)
 	if err != nil {
 		logger.Error("Error ebstablish database connection", "error", err)
 		return
 	}
+	defer closeDB()
 
-	// Create and start server
-	svr := api.NewServer(conn, &config, logger)
-	if err := svr.Start(); err != nil {
+	// Run the migrations only
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(context.Background(), conn, flag.Args()[1:]); err != nil {
+			logger.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Create the demo data only
+	if flag.Arg(0) == "seed" {
+		if err := runSeed(context.Background(), conn, &config, logger, flag.Args()[1:]); err != nil {
+			logger.Error("Seed failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Apply the pending migrations before the server uses the database
+	if config.AutoMigrate {
+		if err := migration.Up(context.Background(), conn); err != nil {
+			logger.Error("Failed to apply database migrations", "error", err)
+			return
+		}
+		logger.Info("Database migrations applied")
+	}
+
+	// Create and start server, it runs until interrupted then stops its subsystems before the database is closed
+	svr, err := api.NewServer(conn, &config, logger)
+	if err != nil {
+		logger.Error("Failed to create server", "error", err)
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := svr.Start(ctx); err != nil {
 		logger.Error("Error: server unexpectedly shutdown", "error", err)
+		return
+	}
+	logger.Info("Server stopped")
+}
+
+// Helper function: run a migrate subcommand
+func runMigrate(ctx context.Context, conn *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command, only accept up, down, version or force")
+	}
+
+	switch args[0] {
+	case "up":
+		return migration.Up(ctx, conn)
+	case "down":
+		steps := 0
+		if len(args) > 1 {
+			var err error
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		return migration.Down(ctx, conn, steps)
+	case "version":
+		version, dirty, err := migration.Version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version: %d, dirty: %t\n", version, dirty)
+		return nil
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("missing version to force")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migration.Force(ctx, conn, version)
+	default:
+		return fmt.Errorf("invalid migrate command %q, only accept up, down, version or force", args[0])
+	}
+}
+
+// Helper function: run the seed subcommand
+func runSeed(ctx context.Context, conn *sql.DB, config *security.Config, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	password := flags.String("password", "zust-demo", "password of the demo accounts")
+	flags.Parse(args)
+
+	server, err := api.NewControl(conn, config, logger)
+	if err != nil {
+		return err
+	}
+	result, err := server.Seed(ctx, *password)
+	if err != nil {
+		return err
 	}
+	logger.Info("Demo data created", "accounts", result.Accounts, "videos", result.Videos)
+	return nil
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"zust/service/file"
+)
+
+// Interval between two progress logs
+const progressInterval = 10 * time.Second
+
+// Storage migration: copies all user repositories from one storage to another, for example when moving the
+// resource path to another disk. Every copy is verified with its checksum and recorded in a journal, so running the
+// command again after an interruption resumes the migration.
+// With -shard, the user repositories of -from are moved in place from the flat layout to the sharded layout instead
+func main() {
+	// Initialize logger
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	from := flag.String("from", "", "resource path of the source storage")
+	to := flag.String("to", "", "resource path of the destination storage")
+	journalPath := flag.String("journal", "migrate-storage.journal", "journal of the copied files, to resume the migration")
+	layout := flag.String("layout", "flat", "layout of the destination storage: flat or sharded")
+	shard := flag.Bool("shard", false, "move the repositories of -from into the sharded layout in place")
+	flag.Parse()
+
+	if *shard {
+		if *from == "" {
+			logger.Error("-from is required")
+			os.Exit(2)
+		}
+		moved, err := file.ShardRepositories(*from)
+		if err != nil {
+			logger.Error("Storage sharding stopped", "moved", moved, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Storage sharding completed", "moved", moved)
+		return
+	}
+
+	if *layout != "flat" && *layout != "sharded" {
+		logger.Error("-layout only accepts flat or sharded")
+		os.Exit(2)
+	}
+	if *from == "" || *to == "" {
+		logger.Error("Both -from and -to are required")
+		os.Exit(2)
+	}
+
+	journal, err := file.OpenMigrationJournal(*journalPath)
+	if err != nil {
+		logger.Error("Failed to open migration journal", "error", err)
+		os.Exit(1)
+	}
+	defer journal.Close()
+
+	// Stop between two files when interrupted, the journal keeps what is already copied
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	src := &file.LocalStorage{ResourcePath: *from}
+	dst := &file.LocalStorage{ResourcePath: *to, Sharded: *layout == "sharded"}
+
+	var lastLog time.Time
+	result, err := file.MigrateStorage(ctx, src, dst, journal, func(progress file.MigrationProgress) {
+		if time.Since(lastLog) < progressInterval {
+			return
+		}
+		lastLog = time.Now()
+		logger.Info("Migrating storage", "done", progress.Copied+progress.Skipped, "total", progress.Total,
+			"copied_bytes", progress.Bytes)
+	})
+	if err != nil {
+		logger.Error("Storage migration stopped", "copied", result.Copied, "skipped", result.Skipped,
+			"total", result.Total, "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Storage migration completed", "copied", result.Copied, "skipped", result.Skipped,
+		"total", result.Total, "copied_bytes", result.Bytes)
+}
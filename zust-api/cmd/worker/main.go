@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"zust/api"
+	"zust/service/security"
+)
+
+// Transcoding worker: runs the media jobs (transcoding and captions) on a different machine than the API server.
+// It shares the database, the job queue and the storage with the API server, which should run with
+// TRANSCODE_WORKERS=remote. The source video is fetched from the storage into a local temporary directory, and the
+// outputs are stored back, so only the storage needs to be reachable from the worker
+func main() {
+	// Initialize logger
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	// Load config from the flags, the environment, and the .env file if any
+	envPath := flag.String("env", "./.env", "path of the .env file, the environment is used alone if it doesn't exist")
+	applyFlags := security.EnvFlags(flag.CommandLine)
+	flag.Parse()
+	err := applyFlags()
+	if err == nil {
+		err = security.LoadConfig(*envPath)
+	}
+	if err != nil {
+		logger.Error("Failed to load configurations", "error", err)
+		return
+	}
+	config := security.GetConfig()
+
+	// Connect to database, waiting for it to be up
+	conn, closeDB, err := api.ConnectDatabase(context.Background(), &config, logger)
+	if err != nil {
+		logger.Error("Error ebstablish database connection", "error", err)
+		return
+	}
+	defer closeDB()
+
+	// Run until interrupted. The jobs in progress stay claimed, they are requeued for another worker once their
+	// heartbeat has stopped for a few minutes
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	worker, err := api.NewWorker(conn, &config, logger)
+	if err != nil {
+		logger.Error("Failed to create worker", "error", err)
+		return
+	}
+	if err := worker.StartWorker(ctx); err != nil {
+		logger.Error("Error: worker unexpectedly shutdown", "error", err)
+		return
+	}
+	logger.Info("Worker stopped")
+}
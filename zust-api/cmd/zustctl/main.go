@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"zust/api"
+	"zust/service/security"
+
+	"github.com/google/uuid"
+)
+
+// Usage of zustctl, printed with -h or an unknown command
+const usage = `Usage: zustctl [-env path] <command> [flags]
+
+Commands:
+  create-admin         create an active admin account (-email, -username, -password)
+  resend-verification  send the verification email again to an inactive account (-email, -tenant)
+  requeue-transcode    put a pending or failed video back into the transcode queue (-video, -branding)
+  suspend              suspend an account and revoke its tokens (-account)
+  unsuspend            reinstate a suspended account (-account)
+  storage              show the storage usage and the accounts using most of it (-top)
+`
+
+// Admin CLI: runs the operational tasks against the database, the storage and the job queue of a deployment. It
+// loads the same configuration as the API server. The jobs it enqueues (emails, transcodes) are run by the API
+// server or the transcoding workers
+func main() {
+	// Initialize logger
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	// Load config from the environment, and the .env file if any
+	envPath := flag.String("env", "./.env", "path of the .env file, the environment is used alone if it doesn't exist")
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	err := security.LoadConfig(*envPath)
+	if err != nil {
+		logger.Error("Failed to load configurations", "error", err)
+		os.Exit(1)
+	}
+	config := security.GetConfig()
+
+	// Connect to database, waiting for it to be up
+	conn, closeDB, err := api.ConnectDatabase(context.Background(), &config, logger)
+	if err != nil {
+		logger.Error("Error ebstablish database connection", "error", err)
+		os.Exit(1)
+	}
+	defer closeDB()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server, err := api.NewControl(conn, &config, logger)
+	if err != nil {
+		logger.Error("Failed to create control server", "error", err)
+		os.Exit(1)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+	if err := run(ctx, server, command, args); err != nil {
+		logger.Error("Command failed", "command", command, "error", err)
+		os.Exit(1)
+	}
+}
+
+// Helper function: parse the flags of a command and run it
+func run(ctx context.Context, server *api.Server, command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+
+	switch command {
+	case "create-admin":
+		email := flags.String("email", "", "email of the admin")
+		username := flags.String("username", "", "username of the admin")
+		password := flags.String("password", "", "password of the admin")
+		flags.Parse(args)
+		if *email == "" || *username == "" || *password == "" {
+			return fmt.Errorf("-email, -username and -password are required")
+		}
+
+		accountID, err := server.CreateAdmin(ctx, *email, *username, *password)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Admin %s created with ID %s\n", *username, accountID)
+
+	case "resend-verification":
+		email := flags.String("email", "", "email of the account")
+		tenant := flags.String("tenant", security.DefaultTenant, "tenant of the account")
+		flags.Parse(args)
+		if *email == "" {
+			return fmt.Errorf("-email is required")
+		}
+
+		if err := server.ResendVerification(ctx, *tenant, *email); err != nil {
+			return err
+		}
+		fmt.Printf("Verification email queued for %s\n", *email)
+
+	case "requeue-transcode":
+		video := flags.String("video", "", "ID of the video")
+		branding := flags.Bool("branding", false, "stitch the intro/outro of the channel onto the video")
+		flags.Parse(args)
+		videoID, err := parseID("-video", *video)
+		if err != nil {
+			return err
+		}
+
+		if err := server.RequeueTranscode(ctx, videoID, *branding); err != nil {
+			return err
+		}
+		fmt.Printf("Video %s queued for transcoding\n", videoID)
+
+	case "suspend", "unsuspend":
+		account := flags.String("account", "", "ID of the account")
+		flags.Parse(args)
+		accountID, err := parseID("-account", *account)
+		if err != nil {
+			return err
+		}
+
+		if err := server.SuspendAccount(ctx, accountID, command == "suspend"); err != nil {
+			return err
+		}
+		fmt.Printf("Account %s %sed\n", accountID, command)
+
+	case "storage":
+		top := flags.Int("top", 10, "number of accounts listed, 0 to list all accounts")
+		flags.Parse(args)
+
+		usage, err := server.StorageUsage(ctx, *top)
+		if err != nil {
+			return err
+		}
+		printStorageUsage(usage)
+
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	return nil
+}
+
+// Helper function: parse the ID given to a flag
+func parseID(name, value string) (uuid.UUID, error) {
+	if value == "" {
+		return uuid.Nil, fmt.Errorf("%s is required", name)
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return id, nil
+}
+
+// Helper function: print the storage usage as a table
+func printStorageUsage(usage *api.StorageUsage) {
+	const mb = 1 << 20
+	health := usage.Health
+	fmt.Printf("Disk: %d MB free of %d MB, %d inodes free of %d\n", health.FreeBytes/mb, health.TotalBytes/mb,
+		health.FreeInodes, health.TotalInodes)
+	fmt.Printf("User repositories: %d files, %d MB\n\n", usage.Files, usage.Bytes/mb)
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ACCOUNT\tFILES\tSIZE (MB)")
+	for _, account := range usage.Accounts {
+		fmt.Fprintf(table, "%s\t%d\t%.1f\n", account.AccountID, account.Files, float64(account.Bytes)/mb)
+	}
+	table.Flush()
+}
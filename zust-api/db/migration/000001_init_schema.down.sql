@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS subtitle;
+DROP TABLE IF EXISTS watermark;
+DROP TABLE IF EXISTS job;
+DROP TABLE IF EXISTS video_rendition;
+DROP TABLE IF EXISTS view_event;
+DROP TABLE IF EXISTS favorite;
+DROP TABLE IF EXISTS watch_video;
+DROP TABLE IF EXISTS like_video;
+DROP TABLE IF EXISTS video;
+DROP TABLE IF EXISTS subscribe;
+DROP TABLE IF EXISTS account;
+DROP TYPE IF EXISTS account_status;
+DROP TYPE IF EXISTS account_role;
+DROP TYPE IF EXISTS video_status;
+DROP TYPE IF EXISTS video_visibility;
+DROP TYPE IF EXISTS video_license;
+DROP TYPE IF EXISTS rendition_status;
+DROP TYPE IF EXISTS job_status;
+DROP TYPE IF EXISTS watermark_position;
\ No newline at end of file
@@ -0,0 +1,139 @@
+-- Create enum
+CREATE TYPE account_status AS ENUM ('inactive', 'active', 'banned', 'locked');
+CREATE TYPE account_role AS ENUM ('user', 'admin');
+CREATE TYPE video_status AS ENUM ('pending', 'published', 'held', 'deleted', 'failed');
+CREATE TYPE video_visibility AS ENUM ('public', 'unlisted', 'private');
+CREATE TYPE video_license AS ENUM ('standard', 'cc-by', 'cc-by-sa', 'cc-by-nd', 'cc-by-nc', 'cc-by-nc-sa', 'cc-by-nc-nd', 'cc0');
+CREATE TYPE job_status AS ENUM ('pending', 'running', 'completed', 'failed');
+CREATE TYPE rendition_status AS ENUM ('queued', 'processing', 'completed', 'failed');
+CREATE TYPE watermark_position AS ENUM ('top-left', 'top-right', 'bottom-left', 'bottom-right');
+
+-- Create table account
+CREATE TABLE IF NOT EXISTS account (
+    account_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    email VARCHAR(40) NOT NULL UNIQUE,
+    username VARCHAR(20) NOT NULL UNIQUE,
+    password VARCHAR(60), -- BCrypt hashing generates 60 characters
+    description VARCHAR(100),
+    status account_status NOT NULL DEFAULT account_status('inactive'),
+    -- OAuth2-specific fields
+    oauth_provider VARCHAR(10), -- 'google', 'github'
+    oauth_provider_id VARCHAR(25), -- the user ID from provider
+    -- JWT token version: used for ban/logout everywhere
+    token_version INT NOT NULL DEFAULT 1,
+    -- Called when the processing of an uploaded video completes or fails
+    processing_webhook_url VARCHAR(255),
+    role account_role NOT NULL DEFAULT account_role('user')
+);
+
+CREATE UNIQUE INDEX idx_unique_email ON account (email);
+CREATE UNIQUE INDEX idx_unique_username ON account (username);
+
+-- Create table subscribe
+CREATE TABLE IF NOT EXISTS subscribe (
+    subscriber_id UUID NOT NULL REFERENCES account(account_id),
+    subscribe_to_id UUID NOT NULL REFERENCES account(account_id),
+    PRIMARY KEY(subscriber_id, subscribe_to_id),
+    subscribe_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create table video
+CREATE TABLE IF NOT EXISTS video (
+    video_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    title VARCHAR(50) NOT NULL UNIQUE,
+    duration INT NOT NULL DEFAULT 0,
+    description VARCHAR(500),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    publisher_id UUID NOT NULL REFERENCES account(account_id),
+    status video_status NOT NULL DEFAULT video_status('pending'),
+    visibility video_visibility NOT NULL DEFAULT video_visibility('public'),
+    category VARCHAR(30),
+    original_removed_at TIMESTAMPTZ, -- set when the raw uploaded file is deleted/archived by retention policy
+    license video_license NOT NULL DEFAULT video_license('standard'),
+    attribution VARCHAR(255), -- credit to the original author, for reused content
+    cold_at TIMESTAMPTZ -- set while the renditions are moved to the cold storage by tiering policy
+);
+
+CREATE INDEX idx_video_license ON video (license);
+
+-- Create table like_video
+CREATE TABLE IF NOT EXISTS like_video (
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    account_id UUID NOT NULL REFERENCES account(account_id),
+    PRIMARY KEY(video_id, account_id),
+    like_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create table watch_video
+CREATE TABLE IF NOT EXISTS watch_video (
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    account_id UUID NOT NULL REFERENCES account(account_id),
+    PRIMARY KEY(video_id, account_id),
+    watch_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create table favorite
+CREATE TABLE IF NOT EXISTS favorite (
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    account_id UUID NOT NULL REFERENCES account(account_id),
+    PRIMARY KEY(video_id, account_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create table view_event
+CREATE TABLE IF NOT EXISTS view_event (
+    event_id BIGSERIAL PRIMARY KEY,
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    account_id UUID REFERENCES account(account_id), -- NULL for guest viewer
+    watch_duration INT NOT NULL DEFAULT 0, -- seconds watched in this view
+    source VARCHAR(20) NOT NULL DEFAULT 'direct', -- traffic source: 'direct', 'search', 'feed', 'external', ...
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_view_event_video ON view_event (video_id, created_at);
+
+
+-- Create table video_rendition
+CREATE TABLE IF NOT EXISTS video_rendition (
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    resolution VARCHAR(10) NOT NULL, -- '1080p', '720p', '480p'
+    PRIMARY KEY(video_id, resolution),
+    status rendition_status NOT NULL DEFAULT rendition_status('queued'),
+    progress INT NOT NULL DEFAULT 0, -- percentage
+    error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create table job: background jobs (transcoding, avatar downloads, email sends, cleanups, ...)
+CREATE TABLE IF NOT EXISTS job (
+    job_id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL, -- 'video.transcode', 'account.download_avatar', 'mail.send', ...
+    payload JSONB NOT NULL DEFAULT '{}',
+    status job_status NOT NULL DEFAULT job_status('pending'),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 3,
+    last_error TEXT,
+    run_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- the job is not picked up before this time
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_job_pending ON job (run_at) WHERE status = 'pending';
+
+-- Create table watermark: watermark overlaid on the videos of a channel, the image is stored in the user repository
+CREATE TABLE IF NOT EXISTS watermark (
+    account_id UUID PRIMARY KEY REFERENCES account(account_id),
+    position watermark_position NOT NULL DEFAULT watermark_position('bottom-right'),
+    opacity REAL NOT NULL DEFAULT 0.5, -- [0, 1]
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Create table subtitle: WebVTT subtitles of a video, the files are stored in the user repository
+CREATE TABLE IF NOT EXISTS subtitle (
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    language VARCHAR(10) NOT NULL, -- BCP 47 tag, for example: 'en', 'vi', 'pt-BR'
+    PRIMARY KEY(video_id, language),
+    auto_generated BOOLEAN NOT NULL DEFAULT FALSE, -- produced by the transcription step
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
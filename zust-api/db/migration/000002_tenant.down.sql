@@ -0,0 +1,7 @@
+ALTER TABLE account DROP CONSTRAINT IF EXISTS account_email_key;
+ALTER TABLE account DROP CONSTRAINT IF EXISTS account_username_key;
+ALTER TABLE account ADD CONSTRAINT account_email_key UNIQUE (email);
+ALTER TABLE account ADD CONSTRAINT account_username_key UNIQUE (username);
+CREATE UNIQUE INDEX idx_unique_email ON account (email);
+CREATE UNIQUE INDEX idx_unique_username ON account (username);
+ALTER TABLE account DROP COLUMN tenant_id;
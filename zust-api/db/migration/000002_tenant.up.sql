@@ -0,0 +1,10 @@
+-- Tenant (site) of the accounts, the accounts created before multi-tenancy belong to the default tenant
+ALTER TABLE account ADD COLUMN tenant_id VARCHAR(30) NOT NULL DEFAULT 'default';
+
+-- Emails and usernames are unique per tenant instead of globally
+DROP INDEX IF EXISTS idx_unique_email;
+DROP INDEX IF EXISTS idx_unique_username;
+ALTER TABLE account DROP CONSTRAINT IF EXISTS account_email_key;
+ALTER TABLE account DROP CONSTRAINT IF EXISTS account_username_key;
+ALTER TABLE account ADD CONSTRAINT account_email_key UNIQUE (tenant_id, email);
+ALTER TABLE account ADD CONSTRAINT account_username_key UNIQUE (tenant_id, username);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_subscribe_to;
+DROP INDEX IF EXISTS idx_video_created;
@@ -0,0 +1,4 @@
+-- Lists are paginated by (creation time, ID), newest first, so the next page is read from the index after the
+-- cursor instead of skipping the previous pages
+CREATE INDEX idx_video_created ON video (created_at DESC, video_id DESC);
+CREATE INDEX idx_subscribe_to ON subscribe (subscribe_to_id, subscribe_at DESC, subscriber_id DESC);
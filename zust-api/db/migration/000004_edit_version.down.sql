@@ -0,0 +1,2 @@
+ALTER TABLE video DROP COLUMN version;
+ALTER TABLE account DROP COLUMN version;
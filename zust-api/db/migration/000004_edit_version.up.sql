@@ -0,0 +1,5 @@
+-- Version of the editable fields of the accounts (profile) and the videos (title, description), incremented by
+-- each edit: an edit is only applied on the version it was made from, so concurrent editors don't overwrite
+-- each other
+ALTER TABLE account ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE video ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,6 @@
+UPDATE video SET status = 'deleted' WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_video_deleted;
+DROP INDEX IF EXISTS idx_account_deleted;
+ALTER TABLE video DROP COLUMN deleted_at;
+ALTER TABLE account DROP COLUMN deleted_at;
@@ -0,0 +1,10 @@
+-- Deleted accounts and videos are kept with the time they were deleted, so they can be restored. The queries
+-- exclude them unless they are meant for the admins
+ALTER TABLE account ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE video ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- The status of the videos deleted before is unknown, they are restored as failed so they can be transcoded again
+UPDATE video SET deleted_at = updated_at, status = 'failed' WHERE status = 'deleted';
+
+CREATE INDEX idx_account_deleted ON account (deleted_at DESC, account_id DESC) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_video_deleted ON video (deleted_at DESC, video_id DESC) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Create table session: a login on a device. The refresh token of the session is rotated on each refresh, only the
+-- hash of its current token is stored, so a refresh token used twice (stolen and replayed) is detected
+CREATE TABLE IF NOT EXISTS session (
+    session_id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES account(account_id),
+    token_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 (hex) of the current refresh token
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '', -- IPv4 or IPv6 of the last refresh
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_session_account ON session (account_id) WHERE revoked_at IS NULL;
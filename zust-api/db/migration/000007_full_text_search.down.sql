@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_account_search;
+DROP INDEX IF EXISTS idx_video_search;
+ALTER TABLE account DROP COLUMN search_vector;
+ALTER TABLE video DROP COLUMN search_vector;
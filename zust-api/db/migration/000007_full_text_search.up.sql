@@ -0,0 +1,15 @@
+-- Full-text search of the videos (title, description, category) and the channels (username, description). The
+-- 'simple' configuration doesn't stem, so it works the same for the titles in any language
+ALTER TABLE video ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', title), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(category, '')), 'C')
+) STORED;
+
+ALTER TABLE account ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', username), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_video_search ON video USING GIN (search_vector);
+CREATE INDEX idx_account_search ON account USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_view_event_created;
+DROP MATERIALIZED VIEW IF EXISTS video_trending;
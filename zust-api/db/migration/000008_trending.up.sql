@@ -0,0 +1,14 @@
+-- Trending videos: view velocity of the videos over the last 48 hours, each view weighs less as it ages (its weight
+-- is divided by e every 6 hours), so the videos gaining views now rank above the videos which were popular yesterday.
+-- It's refreshed periodically by the server, the unique index allows refreshing it without blocking the reads
+CREATE MATERIALIZED VIEW video_trending AS
+SELECT video_id,
+    COUNT(*) AS recent_views,
+    SUM(exp(-extract(epoch FROM now() - created_at) / 21600))::float8 AS score
+FROM view_event
+WHERE created_at >= now() - interval '48 hours'
+GROUP BY video_id;
+
+CREATE UNIQUE INDEX idx_video_trending_video ON video_trending (video_id);
+CREATE INDEX idx_video_trending_score ON video_trending (score DESC);
+CREATE INDEX idx_view_event_created ON view_event (created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE account DROP COLUMN subscriber_count;
+ALTER TABLE video DROP COLUMN view_count;
+ALTER TABLE video DROP COLUMN like_count;
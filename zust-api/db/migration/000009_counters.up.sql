@@ -0,0 +1,11 @@
+-- Counters of the likes and views of the videos and of the subscribers of the accounts, maintained along with the
+-- source tables so the videos are read without counting. They are recomputed from the source tables periodically
+ALTER TABLE video ADD COLUMN like_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE video ADD COLUMN view_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE account ADD COLUMN subscriber_count BIGINT NOT NULL DEFAULT 0;
+
+UPDATE video v SET
+    like_count = (SELECT COUNT(*) FROM like_video lv WHERE lv.video_id = v.video_id),
+    view_count = (SELECT COUNT(*) FROM view_event ve WHERE ve.video_id = v.video_id);
+UPDATE account a SET
+    subscriber_count = (SELECT COUNT(*) FROM subscribe s WHERE s.subscribe_to_id = a.account_id);
@@ -0,0 +1,39 @@
+DROP MATERIALIZED VIEW IF EXISTS video_trending;
+
+ALTER TABLE view_event RENAME TO view_event_partitioned;
+ALTER TABLE view_event_partitioned RENAME CONSTRAINT view_event_pkey TO view_event_partitioned_pkey;
+ALTER SEQUENCE view_event_event_id_seq RENAME TO view_event_partitioned_event_id_seq;
+DROP INDEX IF EXISTS idx_view_event_video;
+DROP INDEX IF EXISTS idx_view_event_created;
+
+CREATE TABLE view_event (
+    event_id BIGSERIAL PRIMARY KEY,
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    account_id UUID REFERENCES account(account_id), -- NULL for guest viewer
+    watch_duration INT NOT NULL DEFAULT 0, -- seconds watched in this view
+    source VARCHAR(20) NOT NULL DEFAULT 'direct', -- traffic source: 'direct', 'search', 'feed', 'external', ...
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_view_event_video ON view_event (video_id, created_at);
+CREATE INDEX idx_view_event_created ON view_event (created_at);
+
+INSERT INTO view_event (event_id, video_id, account_id, watch_duration, source, created_at)
+SELECT event_id, video_id, account_id, watch_duration, source, created_at FROM view_event_partitioned;
+SELECT setval('view_event_event_id_seq', COALESCE((SELECT MAX(event_id) FROM view_event), 0) + 1, false);
+
+DROP TABLE view_event_partitioned;
+DROP FUNCTION IF EXISTS drop_view_event_partitions(TIMESTAMPTZ);
+DROP FUNCTION IF EXISTS create_view_event_partition(DATE);
+ALTER TABLE video DROP COLUMN pruned_view_count;
+
+CREATE MATERIALIZED VIEW video_trending AS
+SELECT video_id,
+    COUNT(*) AS recent_views,
+    SUM(exp(-extract(epoch FROM now() - created_at) / 21600))::float8 AS score
+FROM view_event
+WHERE created_at >= now() - interval '48 hours'
+GROUP BY video_id;
+
+CREATE UNIQUE INDEX idx_video_trending_video ON video_trending (video_id);
+CREATE INDEX idx_video_trending_score ON video_trending (score DESC);
@@ -0,0 +1,91 @@
+-- Partition the view events by month (in UTC), named view_event_YYYY_MM: the analytics queries only scan the months
+-- they cover, and the expired months are dropped as a whole instead of deleted row by row. The partitions are
+-- created ahead by the server, an event of a month without partition is rejected
+
+-- The trending videos depend on the view events, they are recreated below
+DROP MATERIALIZED VIEW IF EXISTS video_trending;
+
+ALTER TABLE view_event RENAME TO view_event_old;
+ALTER TABLE view_event_old RENAME CONSTRAINT view_event_pkey TO view_event_old_pkey;
+ALTER SEQUENCE view_event_event_id_seq RENAME TO view_event_old_event_id_seq;
+DROP INDEX IF EXISTS idx_view_event_video;
+DROP INDEX IF EXISTS idx_view_event_created;
+
+CREATE TABLE view_event (
+    event_id BIGSERIAL,
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    account_id UUID REFERENCES account(account_id), -- NULL for guest viewer
+    watch_duration INT NOT NULL DEFAULT 0, -- seconds watched in this view
+    source VARCHAR(20) NOT NULL DEFAULT 'direct', -- traffic source: 'direct', 'search', 'feed', 'external', ...
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (event_id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX idx_view_event_video ON view_event (video_id, created_at);
+CREATE INDEX idx_view_event_created ON view_event (created_at);
+
+-- Views of the dropped partitions, so the view counters still count them when they are reconciled
+ALTER TABLE video ADD COLUMN pruned_view_count BIGINT NOT NULL DEFAULT 0;
+
+-- Create the partition of the month of a date, if it doesn't exist
+CREATE OR REPLACE FUNCTION create_view_event_partition(month DATE) RETURNS VOID AS $$
+DECLARE
+    start_at DATE := date_trunc('month', month)::date;
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS %I PARTITION OF view_event FOR VALUES FROM (%L) TO (%L)',
+        'view_event_' || to_char(start_at, 'YYYY_MM'),
+        start_at::timestamp AT TIME ZONE 'UTC',
+        (start_at + interval '1 month')::timestamp AT TIME ZONE 'UTC'
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Drop the partitions of the months ended before a time, their views are added to the pruned views of the videos.
+-- It returns the names of the dropped partitions
+CREATE OR REPLACE FUNCTION drop_view_event_partitions(before TIMESTAMPTZ) RETURNS SETOF TEXT AS $$
+DECLARE
+    part TEXT;
+BEGIN
+    FOR part IN
+        SELECT c.relname FROM pg_inherits i
+        JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent = 'view_event'::regclass AND c.relname ~ '^view_event_\d{4}_\d{2}$'
+            AND (to_date(substr(c.relname, 12), 'YYYY_MM') + interval '1 month')::timestamp AT TIME ZONE 'UTC' <= before
+        ORDER BY c.relname
+    LOOP
+        EXECUTE format(
+            'UPDATE video v SET pruned_view_count = v.pruned_view_count + c.views '
+            'FROM (SELECT video_id, COUNT(*) AS views FROM %I GROUP BY video_id) c WHERE v.video_id = c.video_id',
+            part
+        );
+        EXECUTE format('DROP TABLE %I', part);
+        RETURN NEXT part;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Move the existing events, with the partitions of their months up to two months ahead
+SELECT create_view_event_partition(month::date)
+FROM generate_series(
+    date_trunc('month', COALESCE((SELECT MIN(created_at) FROM view_event_old), now()) AT TIME ZONE 'UTC'),
+    date_trunc('month', now() AT TIME ZONE 'UTC') + interval '2 months',
+    interval '1 month'
+) AS month;
+
+INSERT INTO view_event (event_id, video_id, account_id, watch_duration, source, created_at)
+SELECT event_id, video_id, account_id, watch_duration, source, created_at FROM view_event_old;
+SELECT setval('view_event_event_id_seq', COALESCE((SELECT MAX(event_id) FROM view_event), 0) + 1, false);
+
+DROP TABLE view_event_old;
+
+CREATE MATERIALIZED VIEW video_trending AS
+SELECT video_id,
+    COUNT(*) AS recent_views,
+    SUM(exp(-extract(epoch FROM now() - created_at) / 21600))::float8 AS score
+FROM view_event
+WHERE created_at >= now() - interval '48 hours'
+GROUP BY video_id;
+
+CREATE UNIQUE INDEX idx_video_trending_video ON video_trending (video_id);
+CREATE INDEX idx_video_trending_score ON video_trending (score DESC);
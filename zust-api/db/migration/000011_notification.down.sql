@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification;
+DROP TYPE IF EXISTS notification_type;
@@ -0,0 +1,18 @@
+-- Kind of event a notification is about: a channel the recipient subscribes to published a video, an account
+-- subscribed to the recipient, or a video of the recipient finished processing (ready or failed)
+CREATE TYPE notification_type AS ENUM ('new_video', 'new_subscriber', 'video_ready', 'video_failed');
+
+-- Create table notification: an event shown to an account. The actor is the account which caused it (NULL for the
+-- events of the system), the video is its subject when it's about a video
+CREATE TABLE IF NOT EXISTS notification (
+    notification_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    recipient_id UUID NOT NULL REFERENCES account(account_id),
+    type notification_type NOT NULL,
+    actor_id UUID REFERENCES account(account_id),
+    video_id UUID REFERENCES video(video_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    read_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_notification_recipient ON notification (recipient_id, created_at DESC, notification_id DESC);
+CREATE INDEX idx_notification_unread ON notification (recipient_id) WHERE read_at IS NULL;
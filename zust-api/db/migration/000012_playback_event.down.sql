@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS playback_event;
+DROP FUNCTION IF EXISTS drop_playback_event_partitions(TIMESTAMPTZ);
+DROP FUNCTION IF EXISTS create_playback_event_partition(DATE);
@@ -0,0 +1,59 @@
+-- Create table playback_event: the events sent by the players (play, pause, seek, quality switch), grouped by
+-- playback (a playback ID is generated by the player for each time a video is opened). Like the view events, they
+-- are partitioned by month (in UTC) and inserted in bulk with COPY: the type is checked by a constraint instead of
+-- an enum, COPY only supports the types registered in the driver
+CREATE TABLE IF NOT EXISTS playback_event (
+    event_id BIGSERIAL,
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    account_id UUID REFERENCES account(account_id), -- NULL for guest viewer
+    playback_id UUID NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('play', 'pause', 'seek', 'quality_switch')),
+    position DOUBLE PRECISION NOT NULL, -- position in the video (seconds) when the event happened
+    seek_from DOUBLE PRECISION, -- position before the seek, for the seek events
+    quality VARCHAR(10), -- resolution switched to (720p, ...), for the quality switch events
+    occurred_at TIMESTAMPTZ NOT NULL, -- time of the event on the player
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(), -- time the event is received
+    PRIMARY KEY (event_id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX idx_playback_event_video ON playback_event (video_id, created_at);
+
+-- Create the partition of the month of a date, if it doesn't exist
+CREATE OR REPLACE FUNCTION create_playback_event_partition(month DATE) RETURNS VOID AS $$
+DECLARE
+    start_at DATE := date_trunc('month', month)::date;
+BEGIN
+    EXECUTE format(
+        'CREATE TABLE IF NOT EXISTS %I PARTITION OF playback_event FOR VALUES FROM (%L) TO (%L)',
+        'playback_event_' || to_char(start_at, 'YYYY_MM'),
+        start_at::timestamp AT TIME ZONE 'UTC',
+        (start_at + interval '1 month')::timestamp AT TIME ZONE 'UTC'
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Drop the partitions of the months ended before a time, and return their names
+CREATE OR REPLACE FUNCTION drop_playback_event_partitions(before TIMESTAMPTZ) RETURNS SETOF TEXT AS $$
+DECLARE
+    part TEXT;
+BEGIN
+    FOR part IN
+        SELECT c.relname FROM pg_inherits i
+        JOIN pg_class c ON c.oid = i.inhrelid
+        WHERE i.inhparent = 'playback_event'::regclass AND c.relname ~ '^playback_event_\d{4}_\d{2}$'
+            AND (to_date(substr(c.relname, 16), 'YYYY_MM') + interval '1 month')::timestamp AT TIME ZONE 'UTC' <= before
+        ORDER BY c.relname
+    LOOP
+        EXECUTE format('DROP TABLE %I', part);
+        RETURN NEXT part;
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Partitions of the current month and the next two, the next ones are created by the server
+SELECT create_playback_event_partition(month::date)
+FROM generate_series(
+    date_trunc('month', now() AT TIME ZONE 'UTC'),
+    date_trunc('month', now() AT TIME ZONE 'UTC') + interval '2 months',
+    interval '1 month'
+) AS month;
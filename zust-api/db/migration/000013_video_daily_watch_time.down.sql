@@ -0,0 +1 @@
+DROP TABLE IF EXISTS video_daily_watch_time;
@@ -0,0 +1,11 @@
+-- Create table video_daily_watch_time: the watch time of a video per day (in UTC), rolled up from the playback events
+-- by the server. The rows are kept when the partitions of their events are dropped, so the watch time of a video
+-- outlives the retention of the raw events
+CREATE TABLE IF NOT EXISTS video_daily_watch_time (
+    video_id UUID NOT NULL REFERENCES video(video_id),
+    day DATE NOT NULL,
+    watch_time DOUBLE PRECISION NOT NULL DEFAULT 0, -- seconds watched in all playbacks of the day
+    playbacks BIGINT NOT NULL DEFAULT 0, -- playbacks with at least one event in the day
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (video_id, day)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE account DROP COLUMN IF EXISTS digest_sent_at;
+ALTER TABLE account DROP COLUMN IF EXISTS timezone;
+ALTER TABLE account DROP COLUMN IF EXISTS digest_frequency;
+DROP TYPE IF EXISTS digest_frequency;
@@ -0,0 +1,8 @@
+-- How often an account receives the digest of the new videos of its subscriptions
+CREATE TYPE digest_frequency AS ENUM ('off', 'daily', 'weekly');
+
+-- Notification preferences of the accounts: the digest is sent in the morning of the timezone of the account (an
+-- IANA name), digest_sent_at is when the last digest was sent (or skipped, without new video)
+ALTER TABLE account ADD COLUMN digest_frequency digest_frequency NOT NULL DEFAULT 'weekly';
+ALTER TABLE account ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE account ADD COLUMN digest_sent_at TIMESTAMPTZ;
@@ -0,0 +1,12 @@
+-- Create table push_subscription: a browser subscribed to the Web Push notifications of an account. The endpoint is
+-- the URL of the push service of the browser, p256dh and auth are the keys the payloads are encrypted with
+CREATE TABLE IF NOT EXISTS push_subscription (
+    push_subscription_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    account_id UUID NOT NULL REFERENCES account(account_id),
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh VARCHAR(100) NOT NULL,
+    auth VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_push_subscription_account ON push_subscription (account_id);
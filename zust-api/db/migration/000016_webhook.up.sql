@@ -0,0 +1,32 @@
+-- Create table webhook: a URL of a creator receiving the events it's subscribed to, signed with its secret
+CREATE TABLE IF NOT EXISTS webhook (
+    webhook_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    account_id UUID NOT NULL REFERENCES account(account_id),
+    url VARCHAR(255) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT[] NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_account ON webhook (account_id);
+
+-- Status of a delivery: pending until it succeeds, or fails after its last attempt
+CREATE TYPE webhook_delivery_status AS ENUM ('pending', 'succeeded', 'failed');
+
+-- Create table webhook_delivery: the log of the events sent to a webhook, with the result of the last attempt. The
+-- deliveries are deleted along with their webhook, and after the retention period
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    delivery_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    webhook_id UUID NOT NULL REFERENCES webhook(webhook_id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    status webhook_delivery_status NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    status_code INT,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_attempt_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_delivery_webhook ON webhook_delivery (webhook_id, created_at DESC, delivery_id DESC);
+CREATE INDEX idx_webhook_delivery_created ON webhook_delivery (created_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_notification_updated;
+ALTER TABLE notification DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,6 @@
+-- Last change of a notification (created or read), the clients of an account sync the changes since the last one
+-- they got
+ALTER TABLE notification ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+UPDATE notification SET updated_at = COALESCE(read_at, created_at);
+
+CREATE INDEX idx_notification_updated ON notification (recipient_id, updated_at, notification_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS step_up_challenge;
+DROP TYPE IF EXISTS step_up_purpose;
+ALTER TABLE account DROP COLUMN IF EXISTS phone_verified_at;
+ALTER TABLE account DROP COLUMN IF EXISTS phone_number;
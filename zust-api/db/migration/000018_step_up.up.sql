@@ -0,0 +1,24 @@
+-- Phone number of an account (E.164), set once a code sent to it is confirmed. The sensitive actions of an account
+-- with a verified phone number need a code sent to it by SMS
+ALTER TABLE account ADD COLUMN phone_number VARCHAR(16);
+ALTER TABLE account ADD COLUMN phone_verified_at TIMESTAMPTZ;
+
+-- Action a code is sent for: verifying a new phone number, or a sensitive action of the account
+CREATE TYPE step_up_purpose AS ENUM ('phone_verification', 'phone_change', 'password_change', 'account_deletion');
+
+-- Create table step_up_challenge: a code sent by SMS, only its hash is kept. A challenge is used once, before it
+-- expires and within a few attempts
+CREATE TABLE IF NOT EXISTS step_up_challenge (
+    challenge_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    account_id UUID NOT NULL REFERENCES account(account_id) ON DELETE CASCADE,
+    purpose step_up_purpose NOT NULL,
+    phone_number VARCHAR(16) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_step_up_challenge_account ON step_up_challenge (account_id, created_at);
+CREATE INDEX idx_step_up_challenge_expires ON step_up_challenge (expires_at);
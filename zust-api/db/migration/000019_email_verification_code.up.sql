@@ -0,0 +1,9 @@
+-- Create table email_verification_code: the numeric code sent along the verification link, only its hash is kept.
+-- An account has one code at a time, sending the verification email again replaces it
+CREATE TABLE IF NOT EXISTS email_verification_code (
+    account_id UUID PRIMARY KEY REFERENCES account(account_id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS report;
+DROP TABLE IF EXISTS moderation_decision;
+DROP TYPE IF EXISTS moderation_action;
+DROP TYPE IF EXISTS report_reason;
+DROP TYPE IF EXISTS report_target;
+ALTER TABLE video DROP COLUMN IF EXISTS age_restricted;
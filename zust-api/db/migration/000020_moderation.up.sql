@@ -0,0 +1,38 @@
+-- Age restricted videos are only shown to signed-in viewers
+ALTER TABLE video ADD COLUMN age_restricted BOOLEAN NOT NULL DEFAULT false;
+
+-- Content a user can report, and why
+CREATE TYPE report_target AS ENUM ('video', 'account');
+CREATE TYPE report_reason AS ENUM ('spam', 'harassment', 'hate', 'violence', 'sexual', 'copyright', 'other');
+
+-- Action a moderator takes on reported content
+CREATE TYPE moderation_action AS ENUM ('dismiss', 'age_restrict', 'remove', 'strike');
+
+-- Create table moderation_decision: the action taken by a moderator on a target, it resolves the open reports of it
+CREATE TABLE IF NOT EXISTS moderation_decision (
+    decision_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    target_type report_target NOT NULL,
+    target_id UUID NOT NULL,
+    action moderation_action NOT NULL,
+    moderator_id UUID NOT NULL REFERENCES account(account_id),
+    note VARCHAR(500),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_moderation_decision_target ON moderation_decision (target_type, target_id, created_at);
+
+-- Create table report: a report of a video or an account by a user. A report is open until a decision is made on its
+-- target, a user has at most one open report of a target
+CREATE TABLE IF NOT EXISTS report (
+    report_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    reporter_id UUID NOT NULL REFERENCES account(account_id) ON DELETE CASCADE,
+    target_type report_target NOT NULL,
+    target_id UUID NOT NULL,
+    reason report_reason NOT NULL,
+    details VARCHAR(500),
+    decision_id UUID REFERENCES moderation_decision(decision_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_report_open_reporter ON report (reporter_id, target_type, target_id) WHERE decision_id IS NULL;
+CREATE INDEX idx_report_open_target ON report (target_type, target_id, created_at) WHERE decision_id IS NULL;
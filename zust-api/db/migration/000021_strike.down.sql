@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS strike;
+ALTER TABLE account DROP COLUMN IF EXISTS suspended_until;
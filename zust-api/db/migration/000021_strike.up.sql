@@ -0,0 +1,16 @@
+-- End of the suspension of a banned account, which is reinstated once it's passed. NULL means the account stays
+-- banned until an admin reinstates it
+ALTER TABLE account ADD COLUMN suspended_until TIMESTAMPTZ;
+
+-- Create table strike: a strike against an account, given by a moderation decision. A strike counts towards the
+-- enforcement policy until it expires
+CREATE TABLE IF NOT EXISTS strike (
+    strike_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    account_id UUID NOT NULL REFERENCES account(account_id) ON DELETE CASCADE,
+    decision_id UUID NOT NULL REFERENCES moderation_decision(decision_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_strike_account ON strike (account_id, expires_at);
+CREATE INDEX idx_strike_expires ON strike (expires_at);
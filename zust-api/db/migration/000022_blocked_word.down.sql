@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS blocked_word;
+DROP TYPE IF EXISTS blocked_word_mode;
@@ -0,0 +1,15 @@
+-- How a blocked word is matched: whole words, anywhere in the text, or as a regular expression
+CREATE TYPE blocked_word_mode AS ENUM ('exact', 'substring', 'regex');
+
+-- Create table blocked_word: a word (or phrase, or pattern) the titles and descriptions of the videos can't contain.
+-- The words of the site list, maintained by the admins, have no account; the others are in the list of a channel
+CREATE TABLE IF NOT EXISTS blocked_word (
+    blocked_word_id UUID PRIMARY KEY DEFAULT gen_random_UUID(),
+    account_id UUID REFERENCES account(account_id) ON DELETE CASCADE,
+    pattern VARCHAR(200) NOT NULL,
+    mode blocked_word_mode NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_blocked_word_channel ON blocked_word (account_id, mode, pattern) WHERE account_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_blocked_word_site ON blocked_word (mode, pattern) WHERE account_id IS NULL;
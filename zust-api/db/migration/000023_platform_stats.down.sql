@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS storage_usage;
+DROP INDEX IF EXISTS idx_job_type_updated;
+DROP INDEX IF EXISTS idx_account_created;
+ALTER TABLE account DROP COLUMN IF EXISTS created_at;
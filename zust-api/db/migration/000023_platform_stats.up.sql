@@ -0,0 +1,15 @@
+-- Time of the signup of an account. The accounts created before are counted as signed up when the column is added
+ALTER TABLE account ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX idx_account_created ON account (created_at);
+CREATE INDEX idx_job_type_updated ON job (type, updated_at);
+
+-- Create table storage_usage: the space used by the stored files per day (in UTC), measured by the server. The row of
+-- a day is overwritten by each measure of the day, so it holds the latest one
+CREATE TABLE IF NOT EXISTS storage_usage (
+    day DATE PRIMARY KEY,
+    used_bytes BIGINT NOT NULL DEFAULT 0, -- bytes of the files of the storage
+    cold_bytes BIGINT NOT NULL DEFAULT 0, -- bytes of the files of the cold storage, 0 if it's disabled
+    files BIGINT NOT NULL DEFAULT 0, -- files of both storages
+    measured_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
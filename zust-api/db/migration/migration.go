@@ -0,0 +1,105 @@
+// Package migration holds the database migrations, embedded into the binary so a deployment doesn't need to apply
+// the schema out-of-band. Migrations are named {version}_{title}.up.sql and {version}_{title}.down.sql.
+// A database created from the schema before the migrations existed is already at version 1: run 'migrate force 1'
+// once so the migrations start from there
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Helper function: create the migrator on a dedicated connection of the pool, so closing the migrator doesn't close
+// the pool shared with the server
+func newMigrate(ctx context.Context, db *sql.DB) (*migrate.Migrate, func(), error) {
+	source, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return nil, nil, err
+	}
+	return m, func() { m.Close() }, nil
+}
+
+// Function to apply all the migrations not applied yet
+func Up(ctx context.Context, db *sql.DB) error {
+	m, closeFn, err := newMigrate(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Function to revert the last 'steps' migrations, or all of them if steps <= 0
+func Down(ctx context.Context, db *sql.DB, steps int) error {
+	m, closeFn, err := newMigrate(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	if steps <= 0 {
+		err = m.Down()
+	} else {
+		err = m.Steps(-steps)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Function to get the version of the database, 0 if no migration is applied. 'dirty' is true when a migration
+// failed in the middle, the database must then be fixed by hand and its version set with Force
+func Version(ctx context.Context, db *sql.DB) (version uint, dirty bool, err error) {
+	m, closeFn, err := newMigrate(ctx, db)
+	if err != nil {
+		return 0, false, err
+	}
+	defer closeFn()
+
+	version, dirty, err = m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Function to set the version of the database without running any migration, and clear the dirty flag
+func Force(ctx context.Context, db *sql.DB, version int) error {
+	m, closeFn, err := newMigrate(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	return m.Force(version)
+}
@@ -1,20 +1,20 @@
 -- name: CreateAccountWithPassword :one
-INSERT INTO account (email, username, password)
-VALUES ($1, $2, $3)
+INSERT INTO account (tenant_id, email, username, password)
+VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: CreateAccountWithOAuth :one
-INSERT INTO account (email, username, status, oauth_provider, oauth_provider_id)
-VALUES ($1, $2, 'active', $3, $4)
+INSERT INTO account (tenant_id, email, username, status, oauth_provider, oauth_provider_id)
+VALUES ($1, $2, $3, 'active', $4, $5)
 RETURNING *;
 
 -- name: GetAccountByUsername :one
 SELECT account_id, email, username, password, description, status, token_version FROM account
-WHERE username = $1;
+WHERE tenant_id = $1 AND username = $2 AND deleted_at IS NULL;
 
 -- name: GetAccountByEmail :one
 SELECT account_id, email, username, password, description, status, token_version FROM account
-WHERE email = $1;
+WHERE tenant_id = $1 AND email = $2 AND deleted_at IS NULL;
 
 -- name: ActivateAccount :exec
 UPDATE account
@@ -23,7 +23,7 @@ WHERE account_id = $1;
 
 -- name: LoginWithOAuth :one
 SELECT account_id, email, username, description, status, token_version FROM account
-WHERE oauth_provider = $1 AND oauth_provider_id = $2;
+WHERE tenant_id = $1 AND oauth_provider = $2 AND oauth_provider_id = $3 AND deleted_at IS NULL;
 
 -- name: GetTokenVersion :one
 SELECT token_version FROM account
@@ -31,7 +31,7 @@ WHERE account_id = $1;
 
 -- name: IsAccountRegistered :one
 SELECT EXISTS (
-    SELECT 1 FROM account WHERE oauth_provider = $1 AND oauth_provider_id = $2
+    SELECT 1 FROM account WHERE tenant_id = $1 AND oauth_provider = $2 AND oauth_provider_id = $3
 );
 
 -- name: IncrementTokenVersion :exec
@@ -40,14 +40,14 @@ SET token_version = token_version + 1
 WHERE account_id = $1;
 
 -- name: GetProfile :one
-SELECT account_id, email, username, description, status FROM account
-WHERE account_id = $1;
+SELECT account_id, email, username, description, status, version FROM account
+WHERE account_id = $1 AND deleted_at IS NULL;
 
 -- name: EditProfile :one
 UPDATE account
-SET username = $2, description = $3
-WHERE account_id = $1
-RETURNING account_id, email, username, description, status;
+SET username = $2, description = $3, version = version + 1
+WHERE account_id = $1 AND version = $4
+RETURNING account_id, email, username, description, status, version;
 
 -- name: LockAccount :exec
 UPDATE account
@@ -64,6 +64,134 @@ INSERT INTO subscribe (subscriber_id, subscribe_to_id)
 VALUES ($1, $2)
 RETURNING *;
 
--- name: Unsubscribe :exec
+-- name: Unsubscribe :execrows
 DELETE FROM subscribe
-WHERE subscriber_id = $1 AND subscribe_to_id = $2;
\ No newline at end of file
+WHERE subscriber_id = $1 AND subscribe_to_id = $2;
+
+-- name: SetProcessingWebhook :exec
+UPDATE account
+SET processing_webhook_url = $2
+WHERE account_id = $1;
+
+-- name: GetProcessingWebhook :one
+SELECT processing_webhook_url FROM account
+WHERE account_id = $1;
+
+-- name: GetAccountRole :one
+SELECT role FROM account
+WHERE account_id = $1;
+
+-- name: ListAccountIDs :many
+SELECT account_id FROM account;
+
+-- name: ListAdminEmails :many
+SELECT email FROM account
+WHERE role = 'admin' AND status = 'active' AND deleted_at IS NULL;
+
+-- name: ListSubscriptions :many
+SELECT a.account_id, a.username, a.description FROM subscribe s
+JOIN account a ON a.account_id = s.subscribe_to_id
+WHERE s.subscriber_id = $1 AND a.status = 'active' AND a.deleted_at IS NULL
+ORDER BY s.subscribe_at DESC;
+
+-- name: CountSubscribers :one
+SELECT subscriber_count FROM account
+WHERE account_id = $1;
+
+-- name: SetAccountRole :exec
+UPDATE account
+SET role = $2
+WHERE account_id = $1;
+
+-- name: SetAccountStatus :exec
+UPDATE account
+SET status = $2, suspended_until = NULL
+WHERE account_id = $1;
+
+-- name: GetAccountTenant :one
+SELECT tenant_id FROM account
+WHERE account_id = $1;
+
+-- name: ListSubscribers :many
+SELECT a.account_id, a.username, a.description, s.subscribe_at FROM subscribe s
+JOIN account a ON a.account_id = s.subscriber_id
+WHERE s.subscribe_to_id = sqlc.arg(subscribe_to_id) AND a.status = 'active' AND a.deleted_at IS NULL
+    AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+        OR (s.subscribe_at, s.subscriber_id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY s.subscribe_at DESC, s.subscriber_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: DeleteAccount :execrows
+UPDATE account
+SET deleted_at = now(), token_version = token_version + 1
+WHERE account_id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreAccount :execrows
+UPDATE account
+SET deleted_at = NULL
+WHERE account_id = $1 AND deleted_at IS NOT NULL;
+
+-- name: ListDeletedAccounts :many
+SELECT account_id, tenant_id, email, username, deleted_at FROM account
+WHERE deleted_at IS NOT NULL
+    AND (sqlc.narg(after_deleted_at)::timestamptz IS NULL
+        OR (deleted_at, account_id) < (sqlc.narg(after_deleted_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY deleted_at DESC, account_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: SearchChannels :many
+SELECT account_id, username, description,
+    ts_rank(search_vector, to_tsquery('simple', sqlc.arg(query)::text)) AS rank
+FROM account
+WHERE tenant_id = sqlc.arg(tenant_id) AND status = 'active' AND deleted_at IS NULL
+    AND search_vector @@ to_tsquery('simple', sqlc.arg(query)::text)
+ORDER BY rank DESC, account_id
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: AddSubscribers :exec
+UPDATE account
+SET subscriber_count = subscriber_count + sqlc.arg(delta)
+WHERE account_id = sqlc.arg(account_id);
+
+-- name: ReconcileSubscriberCounters :many
+UPDATE account a
+SET subscriber_count = c.subscribers
+FROM (
+    SELECT x.account_id, x.subscriber_count AS old_subscribers,
+        (SELECT COUNT(*) FROM subscribe s WHERE s.subscribe_to_id = x.account_id) AS subscribers
+    FROM account x
+) c
+WHERE a.account_id = c.account_id AND a.subscriber_count <> c.subscribers
+RETURNING a.account_id, c.old_subscribers, c.subscribers;
+
+-- name: GetAccountCredentials :one
+SELECT password, phone_number, phone_verified_at FROM account
+WHERE account_id = $1 AND deleted_at IS NULL;
+
+-- name: SetPassword :exec
+UPDATE account
+SET password = $2
+WHERE account_id = $1;
+
+-- name: SetPhoneNumber :exec
+UPDATE account
+SET phone_number = $2, phone_verified_at = now()
+WHERE account_id = $1;
+
+-- name: RemovePhoneNumber :exec
+UPDATE account
+SET phone_number = NULL, phone_verified_at = NULL
+WHERE account_id = $1;
+
+-- name: SuspendAccount :execrows
+UPDATE account
+SET status = 'banned', suspended_until = sqlc.narg(suspended_until), token_version = token_version + 1
+WHERE account_id = sqlc.arg(account_id) AND deleted_at IS NULL
+    AND (status <> 'banned' OR (suspended_until IS NOT NULL AND (sqlc.narg(suspended_until)::timestamptz IS NULL
+        OR suspended_until < sqlc.narg(suspended_until)::timestamptz)));
+
+-- name: ReinstateSuspendedAccounts :many
+UPDATE account
+SET status = 'active', suspended_until = NULL
+WHERE status = 'banned' AND suspended_until <= sqlc.arg(now)::timestamptz
+RETURNING account_id;
\ No newline at end of file
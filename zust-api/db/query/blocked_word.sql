@@ -0,0 +1,23 @@
+-- name: CreateBlockedWord :one
+INSERT INTO blocked_word (account_id, pattern, mode)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: ListBlockedWords :many
+SELECT * FROM blocked_word
+WHERE account_id IS NOT DISTINCT FROM sqlc.narg(account_id)
+ORDER BY created_at, blocked_word_id;
+
+-- name: ListActiveBlockedWords :many
+SELECT * FROM blocked_word
+WHERE account_id IS NULL OR account_id = sqlc.arg(account_id)::uuid
+ORDER BY created_at, blocked_word_id;
+
+-- name: CountBlockedWords :one
+SELECT COUNT(*) FROM blocked_word
+WHERE account_id IS NOT DISTINCT FROM sqlc.narg(account_id);
+
+-- name: DeleteBlockedWord :execrows
+DELETE FROM blocked_word
+WHERE blocked_word_id = sqlc.arg(blocked_word_id) AND account_id IS NOT DISTINCT FROM sqlc.narg(account_id);
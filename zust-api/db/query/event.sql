@@ -0,0 +1,92 @@
+-- name: GetVideoDailyViews :many
+SELECT date_trunc('day', created_at)::date AS day, COUNT(*) AS views
+FROM view_event
+WHERE video_id = sqlc.arg(video_id) AND created_at >= sqlc.arg(since)
+GROUP BY day
+ORDER BY day;
+
+-- name: GetVideoWatchSummary :one
+SELECT
+    COUNT(*) AS total_views,
+    COALESCE(AVG(watch_duration), 0)::float8 AS average_watch_duration
+FROM view_event
+WHERE video_id = sqlc.arg(video_id) AND created_at >= sqlc.arg(since);
+
+-- name: GetVideoTrafficSources :many
+SELECT source, COUNT(*) AS views
+FROM view_event
+WHERE video_id = sqlc.arg(video_id) AND created_at >= sqlc.arg(since)
+GROUP BY source
+ORDER BY views DESC;
+
+-- name: RefreshTrendingVideos :exec
+REFRESH MATERIALIZED VIEW CONCURRENTLY video_trending;
+
+-- name: ListTrendingVideos :many
+SELECT v.video_id, v.title, v.duration, v.created_at, a.account_id, a.username, t.recent_views
+FROM video_trending t
+JOIN video v ON v.video_id = t.video_id
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND a.tenant_id = sqlc.arg(tenant_id)
+ORDER BY t.score DESC, t.video_id
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: AddVideoViews :exec
+UPDATE video
+SET view_count = view_count + sqlc.arg(delta)
+WHERE video_id = sqlc.arg(video_id);
+
+-- name: CreateViewEventPartition :exec
+SELECT create_view_event_partition(sqlc.arg(month)::date);
+
+-- name: DropViewEventPartitions :many
+SELECT part::text FROM drop_view_event_partitions(sqlc.arg(before)::timestamptz) AS part;
+
+-- name: CreatePlaybackEventPartition :exec
+SELECT create_playback_event_partition(sqlc.arg(month)::date);
+
+-- name: DropPlaybackEventPartitions :many
+SELECT part::text FROM drop_playback_event_partitions(sqlc.arg(before)::timestamptz) AS part;
+
+-- name: GetVideoPlaybackEvents :many
+SELECT type, COUNT(*) AS events, COUNT(DISTINCT playback_id) AS playbacks
+FROM playback_event
+WHERE video_id = sqlc.arg(video_id) AND created_at >= sqlc.arg(since)
+GROUP BY type
+ORDER BY type;
+
+-- name: RollupVideoWatchTime :execrows
+WITH ordered AS (
+    SELECT video_id, playback_id, occurred_at,
+        CASE WHEN type = 'seek' THEN seek_from ELSE position END AS stop_position,
+        LAG(type) OVER playback AS previous_type,
+        LAG(position) OVER playback AS previous_position,
+        LAG(occurred_at) OVER playback AS previous_at
+    FROM playback_event
+    WHERE created_at >= sqlc.arg(since)::timestamptz - interval '1 day'
+    WINDOW playback AS (PARTITION BY video_id, playback_id ORDER BY occurred_at, event_id)
+), watched AS (
+    SELECT video_id, playback_id, (occurred_at AT TIME ZONE 'UTC')::date AS day,
+        CASE WHEN previous_type IS NULL OR previous_type = 'pause' THEN 0
+        ELSE GREATEST(LEAST(stop_position - previous_position, extract(epoch FROM occurred_at - previous_at)::float8), 0)
+        END AS watch_time
+    FROM ordered
+    WHERE occurred_at >= sqlc.arg(since)::timestamptz
+)
+INSERT INTO video_daily_watch_time (video_id, day, watch_time, playbacks)
+SELECT video_id, day, SUM(watch_time), COUNT(DISTINCT playback_id)
+FROM watched
+GROUP BY video_id, day
+ON CONFLICT (video_id, day) DO UPDATE
+SET watch_time = EXCLUDED.watch_time, playbacks = EXCLUDED.playbacks, updated_at = now();
+
+-- name: GetLatestWatchTimeDay :one
+SELECT COALESCE(MAX(day)::timestamp AT TIME ZONE 'UTC', 'epoch')::timestamptz AS day
+FROM video_daily_watch_time;
+
+-- name: GetVideoDailyWatchTime :many
+SELECT day::date AS day, watch_time, playbacks
+FROM video_daily_watch_time
+WHERE video_id = sqlc.arg(video_id) AND day >= sqlc.arg(since)::date
+ORDER BY day;
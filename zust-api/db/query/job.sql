@@ -0,0 +1,60 @@
+-- name: EnqueueJob :one
+INSERT INTO job (type, payload, max_attempts, run_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ClaimJob :one
+UPDATE job
+SET status = 'running', attempts = attempts + 1, updated_at = now()
+WHERE job_id = (
+    SELECT j.job_id FROM job j
+    WHERE j.status = 'pending' AND j.run_at <= now() AND j.type = ANY(sqlc.arg(types)::text[])
+    ORDER BY j.run_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteJob :exec
+UPDATE job
+SET status = 'completed', last_error = NULL, updated_at = now()
+WHERE job_id = $1;
+
+-- name: RetryJob :exec
+UPDATE job
+SET status = 'pending', run_at = $2, last_error = $3, updated_at = now()
+WHERE job_id = $1;
+
+-- name: FailJob :exec
+UPDATE job
+SET status = 'failed', last_error = $2, updated_at = now()
+WHERE job_id = $1;
+
+-- name: TouchJob :exec
+UPDATE job
+SET updated_at = now()
+WHERE job_id = $1 AND status = 'running';
+
+-- name: RequeueStaleJobs :execrows
+UPDATE job
+SET status = 'pending', updated_at = now()
+WHERE status = 'running' AND updated_at < sqlc.arg(stale_before);
+
+-- name: ListFailedJobs :many
+SELECT * FROM job
+WHERE status = 'failed'
+ORDER BY updated_at DESC
+LIMIT $1;
+
+-- name: RequeueFailedJob :execrows
+UPDATE job
+SET status = 'pending', attempts = 0, run_at = now(), updated_at = now()
+WHERE job_id = $1 AND status = 'failed';
+
+-- name: CountActiveJobs :one
+SELECT COUNT(*) FROM job
+WHERE type = $1 AND status IN ('pending', 'running');
+
+-- name: ListActiveJobPayloads :many
+SELECT payload FROM job
+WHERE type = $1 AND status IN ('pending', 'running');
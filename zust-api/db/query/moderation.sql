@@ -0,0 +1,43 @@
+-- name: CreateReport :execrows
+INSERT INTO report (reporter_id, target_type, target_id, reason, details)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (reporter_id, target_type, target_id) WHERE decision_id IS NULL DO NOTHING;
+
+-- name: ListModerationQueue :many
+SELECT q.target_type, q.target_id, q.report_count, q.reasons, q.first_reported_at, q.last_reported_at,
+    COALESCE(v.title, a.username, '')::text AS target_name
+FROM (
+    SELECT target_type, target_id, COUNT(*) AS report_count, array_agg(DISTINCT reason)::text[] AS reasons,
+        MIN(created_at)::timestamptz AS first_reported_at, MAX(created_at)::timestamptz AS last_reported_at
+    FROM report
+    WHERE decision_id IS NULL AND (sqlc.arg(target_type)::text = '' OR target_type::text = sqlc.arg(target_type)::text)
+    GROUP BY target_type, target_id
+) q
+LEFT JOIN video v ON q.target_type = 'video' AND v.video_id = q.target_id
+LEFT JOIN account a ON q.target_type = 'account' AND a.account_id = q.target_id
+WHERE sqlc.narg(after_reported_at)::timestamptz IS NULL
+    OR (q.first_reported_at, q.target_id) > (sqlc.narg(after_reported_at)::timestamptz, sqlc.narg(after_id)::uuid)
+ORDER BY q.first_reported_at, q.target_id
+LIMIT sqlc.arg(page_size);
+
+-- name: ListOpenReports :many
+SELECT r.report_id, r.reason, r.details, r.created_at, a.account_id, a.username FROM report r
+JOIN account a ON a.account_id = r.reporter_id
+WHERE r.target_type = $1 AND r.target_id = $2 AND r.decision_id IS NULL
+ORDER BY r.created_at, r.report_id;
+
+-- name: ListModerationDecisions :many
+SELECT d.decision_id, d.action, d.note, d.created_at, a.account_id, a.username FROM moderation_decision d
+JOIN account a ON a.account_id = d.moderator_id
+WHERE d.target_type = $1 AND d.target_id = $2
+ORDER BY d.created_at DESC, d.decision_id DESC;
+
+-- name: CreateModerationDecision :one
+INSERT INTO moderation_decision (target_type, target_id, action, moderator_id, note)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ResolveReports :execrows
+UPDATE report
+SET decision_id = $3
+WHERE target_type = $1 AND target_id = $2 AND decision_id IS NULL;
@@ -0,0 +1,99 @@
+-- name: CreateNotification :one
+INSERT INTO notification (recipient_id, type, actor_id, video_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: CreateSubscriberNotifications :many
+INSERT INTO notification (recipient_id, type, actor_id, video_id)
+SELECT s.subscriber_id, sqlc.arg(type)::notification_type, sqlc.arg(actor_id)::uuid, sqlc.narg(video_id)::uuid
+FROM subscribe s
+JOIN account a ON a.account_id = s.subscriber_id
+WHERE s.subscribe_to_id = sqlc.arg(actor_id)::uuid AND a.status = 'active' AND a.deleted_at IS NULL
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT n.notification_id, n.type, n.actor_id, a.username AS actor_username, n.video_id, v.title AS video_title,
+    n.created_at, n.read_at
+FROM notification n
+LEFT JOIN account a ON a.account_id = n.actor_id
+LEFT JOIN video v ON v.video_id = n.video_id
+WHERE n.recipient_id = sqlc.arg(recipient_id) AND (n.video_id IS NULL OR v.deleted_at IS NULL)
+    AND (NOT sqlc.arg(unread_only)::bool OR n.read_at IS NULL)
+    AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+        OR (n.created_at, n.notification_id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY n.created_at DESC, n.notification_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: MarkNotificationRead :execrows
+UPDATE notification
+SET read_at = now(), updated_at = now()
+WHERE notification_id = $1 AND recipient_id = $2 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :one
+SELECT digest_frequency, timezone FROM account
+WHERE account_id = $1 AND deleted_at IS NULL;
+
+-- name: UpdateNotificationPreferences :one
+UPDATE account
+SET digest_frequency = COALESCE(sqlc.narg(digest_frequency), digest_frequency),
+    timezone = COALESCE(sqlc.narg(timezone), timezone)
+WHERE account_id = sqlc.arg(account_id) AND deleted_at IS NULL
+RETURNING digest_frequency, timezone;
+
+-- name: ClaimDigestRecipients :many
+WITH due AS (
+    SELECT account_id, digest_sent_at FROM account
+    WHERE digest_frequency <> 'off' AND status = 'active' AND deleted_at IS NULL
+        AND extract(hour FROM now() AT TIME ZONE timezone) >= sqlc.arg(send_hour)::int
+        AND (digest_frequency = 'daily' OR extract(isodow FROM now() AT TIME ZONE timezone) = 1)
+        AND (digest_sent_at IS NULL
+            OR digest_sent_at < date_trunc('day', now() AT TIME ZONE timezone) AT TIME ZONE timezone)
+    ORDER BY account_id
+    LIMIT sqlc.arg(page_size)
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE account a
+SET digest_sent_at = now()
+FROM due
+WHERE a.account_id = due.account_id
+RETURNING a.account_id, a.tenant_id, a.email, a.username, a.digest_frequency, due.digest_sent_at AS previous_sent_at;
+
+-- name: ListDigestVideos :many
+SELECT v.video_id, v.title, v.duration, v.created_at, a.username
+FROM subscribe s
+JOIN video v ON v.publisher_id = s.subscribe_to_id
+JOIN account a ON a.account_id = v.publisher_id
+WHERE s.subscriber_id = sqlc.arg(subscriber_id) AND v.created_at >= sqlc.arg(since)
+    AND v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: ListNotificationChanges :many
+SELECT n.notification_id, n.type, n.actor_id, a.username AS actor_username, n.video_id, v.title AS video_title,
+    n.created_at, n.read_at, n.updated_at
+FROM notification n
+LEFT JOIN account a ON a.account_id = n.actor_id
+LEFT JOIN video v ON v.video_id = n.video_id
+WHERE n.recipient_id = sqlc.arg(recipient_id) AND (n.video_id IS NULL OR v.deleted_at IS NULL)
+    AND (n.updated_at, n.notification_id) > (sqlc.arg(since_updated_at)::timestamptz, sqlc.arg(since_id)::uuid)
+ORDER BY n.updated_at, n.notification_id
+LIMIT sqlc.arg(page_size);
+
+-- name: GetNotificationSyncPoint :one
+SELECT updated_at, notification_id FROM notification
+WHERE recipient_id = $1
+ORDER BY updated_at DESC, notification_id DESC
+LIMIT 1;
+
+-- name: CountUnreadNotificationsByType :many
+SELECT type, COUNT(*) AS count FROM notification
+WHERE recipient_id = $1 AND read_at IS NULL
+GROUP BY type;
+
+-- name: MarkNotificationsRead :many
+UPDATE notification
+SET read_at = now(), updated_at = now()
+WHERE recipient_id = sqlc.arg(recipient_id) AND read_at IS NULL
+    AND (sqlc.narg(ids)::uuid[] IS NULL OR notification_id = ANY(sqlc.narg(ids)::uuid[]))
+    AND (sqlc.narg(type)::notification_type IS NULL OR type = sqlc.narg(type)::notification_type)
+RETURNING notification_id;
@@ -0,0 +1,32 @@
+-- name: CreatePushSubscription :one
+INSERT INTO push_subscription (account_id, endpoint, p256dh, auth)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (endpoint) DO UPDATE
+SET account_id = EXCLUDED.account_id, p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth
+RETURNING *;
+
+-- name: DeletePushSubscription :execrows
+DELETE FROM push_subscription
+WHERE push_subscription_id = $1 AND account_id = $2;
+
+-- name: DeletePushSubscriptionByEndpoint :exec
+DELETE FROM push_subscription
+WHERE endpoint = $1;
+
+-- name: ListPushSubscriptions :many
+SELECT * FROM push_subscription
+WHERE account_id = $1
+ORDER BY created_at DESC;
+
+-- name: ListSubscriberPushSubscriptions :many
+SELECT p.push_subscription_id, p.account_id, p.endpoint, p.p256dh, p.auth, p.created_at
+FROM push_subscription p
+JOIN subscribe s ON s.subscriber_id = p.account_id
+JOIN account a ON a.account_id = p.account_id
+WHERE s.subscribe_to_id = $1 AND a.status = 'active' AND a.deleted_at IS NULL;
+
+-- name: GetPushMessageContent :one
+SELECT COALESCE(a.username, '')::text AS actor_username, COALESCE(v.title, '')::text AS video_title
+FROM (SELECT 1) AS one
+LEFT JOIN account a ON a.account_id = sqlc.narg(actor_id)::uuid
+LEFT JOIN video v ON v.video_id = sqlc.narg(video_id)::uuid;
@@ -0,0 +1,15 @@
+-- name: CreateRendition :exec
+INSERT INTO video_rendition (video_id, resolution)
+VALUES ($1, $2)
+ON CONFLICT (video_id, resolution) DO UPDATE
+SET status = 'queued', progress = 0, error = NULL, updated_at = now();
+
+-- name: UpdateRenditionStatus :exec
+UPDATE video_rendition
+SET status = $3, progress = $4, error = $5, updated_at = now()
+WHERE video_id = $1 AND resolution = $2;
+
+-- name: ListRenditions :many
+SELECT * FROM video_rendition
+WHERE video_id = $1
+ORDER BY resolution;
@@ -0,0 +1,41 @@
+-- name: CreateSession :exec
+INSERT INTO session (session_id, account_id, token_hash, user_agent, ip, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetSession :one
+SELECT * FROM session
+WHERE session_id = $1;
+
+-- name: GetActiveSession :one
+SELECT account_id, expires_at FROM session
+WHERE session_id = $1 AND revoked_at IS NULL;
+
+-- name: GetSessionTokenVersion :one
+SELECT a.token_version FROM session s
+JOIN account a ON a.account_id = s.account_id
+WHERE s.session_id = $1 AND s.account_id = $2 AND s.revoked_at IS NULL AND s.expires_at > now();
+
+-- name: RotateSession :execrows
+UPDATE session
+SET token_hash = sqlc.arg(new_token_hash), ip = sqlc.arg(ip), expires_at = sqlc.arg(expires_at), last_used_at = now()
+WHERE session_id = sqlc.arg(session_id) AND token_hash = sqlc.arg(token_hash)
+    AND revoked_at IS NULL AND expires_at > now();
+
+-- name: ListSessions :many
+SELECT session_id, user_agent, ip, created_at, last_used_at, expires_at FROM session
+WHERE account_id = $1 AND revoked_at IS NULL AND expires_at > now()
+ORDER BY last_used_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE session
+SET revoked_at = now()
+WHERE session_id = $1 AND account_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeAccountSessions :exec
+UPDATE session
+SET revoked_at = now()
+WHERE account_id = $1 AND revoked_at IS NULL;
+
+-- name: DeleteStaleSessions :execrows
+DELETE FROM session
+WHERE expires_at < sqlc.arg(before) OR revoked_at < sqlc.arg(before);
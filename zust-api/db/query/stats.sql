@@ -0,0 +1,59 @@
+-- name: ListDailySignups :many
+SELECT (created_at AT TIME ZONE 'UTC')::date AS day, COUNT(*) AS signups
+FROM account
+WHERE created_at >= sqlc.arg(since)
+GROUP BY day
+ORDER BY day;
+
+-- name: ListDailyUploads :many
+SELECT (created_at AT TIME ZONE 'UTC')::date AS day, COUNT(*) AS uploads
+FROM video
+WHERE created_at >= sqlc.arg(since)
+GROUP BY day
+ORDER BY day;
+
+-- name: ListDailyJobOutcomes :many
+SELECT (updated_at AT TIME ZONE 'UTC')::date AS day,
+    COUNT(*) FILTER (WHERE status = 'completed') AS completed,
+    COUNT(*) FILTER (WHERE status = 'failed') AS failed
+FROM job
+WHERE type = sqlc.arg(type) AND status IN ('completed', 'failed') AND updated_at >= sqlc.arg(since)
+GROUP BY day
+ORDER BY day;
+
+-- name: GetJobQueueDepth :one
+SELECT
+    COUNT(*) FILTER (WHERE status = 'pending') AS pending,
+    COUNT(*) FILTER (WHERE status = 'running') AS running,
+    COALESCE(EXTRACT(EPOCH FROM now() - MIN(run_at) FILTER (WHERE status = 'pending' AND run_at <= now())), 0)::float8
+        AS oldest_pending_age
+FROM job
+WHERE type = $1 AND status IN ('pending', 'running');
+
+-- name: ListDailyActiveUsers :many
+SELECT (created_at AT TIME ZONE 'UTC')::date AS day, COUNT(DISTINCT account_id) AS active_users
+FROM view_event
+WHERE account_id IS NOT NULL AND created_at >= sqlc.arg(since)
+GROUP BY day
+ORDER BY day;
+
+-- name: CountActiveUsers :one
+SELECT COUNT(*) FROM (
+    SELECT view_event.account_id FROM view_event
+    WHERE view_event.account_id IS NOT NULL AND view_event.created_at >= sqlc.arg(since)
+    UNION
+    SELECT session.account_id FROM session WHERE session.last_used_at >= sqlc.arg(since)
+) AS active;
+
+-- name: RecordStorageUsage :exec
+INSERT INTO storage_usage (day, used_bytes, cold_bytes, files, measured_at)
+VALUES ((sqlc.arg(measured_at)::timestamptz AT TIME ZONE 'UTC')::date, sqlc.arg(used_bytes), sqlc.arg(cold_bytes),
+    sqlc.arg(files), sqlc.arg(measured_at))
+ON CONFLICT (day) DO UPDATE
+SET used_bytes = EXCLUDED.used_bytes, cold_bytes = EXCLUDED.cold_bytes, files = EXCLUDED.files,
+    measured_at = EXCLUDED.measured_at;
+
+-- name: ListStorageUsage :many
+SELECT * FROM storage_usage
+WHERE day >= sqlc.arg(since)::date
+ORDER BY day;
@@ -0,0 +1,26 @@
+-- name: CreateStepUpChallenge :one
+INSERT INTO step_up_challenge (challenge_id, account_id, purpose, phone_number, code_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: CountRecentStepUpChallenges :one
+SELECT COUNT(*) FROM step_up_challenge
+WHERE account_id = sqlc.arg(account_id) AND created_at > sqlc.arg(since);
+
+-- name: GetStepUpChallenge :one
+SELECT * FROM step_up_challenge
+WHERE challenge_id = $1 AND account_id = $2;
+
+-- name: IncrementStepUpAttempts :execrows
+UPDATE step_up_challenge
+SET attempts = attempts + 1
+WHERE challenge_id = sqlc.arg(challenge_id) AND attempts < sqlc.arg(max_attempts)::int;
+
+-- name: ConsumeStepUpChallenge :execrows
+UPDATE step_up_challenge
+SET consumed_at = now()
+WHERE challenge_id = $1 AND consumed_at IS NULL;
+
+-- name: DeleteExpiredStepUpChallenges :execrows
+DELETE FROM step_up_challenge
+WHERE expires_at < sqlc.arg(before);
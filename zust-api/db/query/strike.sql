@@ -0,0 +1,18 @@
+-- name: CreateStrike :one
+INSERT INTO strike (account_id, decision_id, expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CountActiveStrikes :one
+SELECT COUNT(*) FROM strike
+WHERE account_id = sqlc.arg(account_id) AND expires_at > sqlc.arg(now);
+
+-- name: ListActiveStrikes :many
+SELECT s.strike_id, s.created_at, s.expires_at, d.target_type, d.target_id FROM strike s
+JOIN moderation_decision d ON d.decision_id = s.decision_id
+WHERE s.account_id = sqlc.arg(account_id) AND s.expires_at > sqlc.arg(now)
+ORDER BY s.created_at DESC, s.strike_id DESC;
+
+-- name: DeleteExpiredStrikes :execrows
+DELETE FROM strike
+WHERE expires_at <= sqlc.arg(before);
@@ -0,0 +1,11 @@
+-- name: UpsertAutoSubtitle :exec
+INSERT INTO subtitle (video_id, language, auto_generated)
+VALUES ($1, $2, TRUE)
+ON CONFLICT (video_id, language) DO UPDATE
+SET created_at = now()
+WHERE subtitle.auto_generated;
+
+-- name: ListSubtitles :many
+SELECT * FROM subtitle
+WHERE video_id = $1
+ORDER BY language;
@@ -0,0 +1,18 @@
+-- name: SetEmailVerificationCode :exec
+INSERT INTO email_verification_code (account_id, code_hash, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (account_id) DO UPDATE
+SET code_hash = EXCLUDED.code_hash, attempts = 0, expires_at = EXCLUDED.expires_at, created_at = now();
+
+-- name: GetEmailVerificationCode :one
+SELECT * FROM email_verification_code
+WHERE account_id = $1;
+
+-- name: IncrementEmailVerificationAttempts :execrows
+UPDATE email_verification_code
+SET attempts = attempts + 1
+WHERE account_id = sqlc.arg(account_id) AND attempts < sqlc.arg(max_attempts)::int;
+
+-- name: DeleteEmailVerificationCode :exec
+DELETE FROM email_verification_code
+WHERE account_id = $1;
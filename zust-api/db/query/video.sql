@@ -1,6 +1,6 @@
 -- name: CreateVideo :one
-INSERT INTO video (title, description, publisher_id)
-VALUES ($1, $2, $3)
+INSERT INTO video (title, description, publisher_id, license, attribution)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: UpdateVideoDuration :exec
@@ -10,17 +10,203 @@ WHERE video_id = $1;
 
 -- name: PublishVideo :one
 UPDATE video
-SET status = 'published'
-WHERE video_id = $1
+SET status = 'published', updated_at = now()
+WHERE video_id = $1 AND status = 'pending'
 RETURNING *;
 
 -- name: GetVideo :one
 SELECT 
-    v.video_id, v.title, v.duration, v.description, v.created_at, v.status,
-    a.account_id, a.username,
-    (SELECT COUNT(*) FROM subscribe s WHERE s.subscribe_to_id = v.publisher_id) AS total_subscriber,
-    (SELECT COUNT(*) FROM watch_video wv WHERE wv.video_id = v.video_id) AS total_view,
-    (SELECT COUNT(*) FROM like_video lv WHERE lv.video_id = v.video_id) AS total_like
+    v.video_id, v.title, v.duration, v.description, v.created_at, v.status, v.visibility, v.category,
+    v.original_removed_at, v.license, v.attribution, v.cold_at, v.version, v.age_restricted, a.account_id, a.username,
+    a.subscriber_count AS total_subscriber,
+    v.view_count AS total_view,
+    v.like_count AS total_like
 FROM video v 
 JOIN account a ON a.account_id = v.publisher_id
-WHERE v.video_id = $1;
+WHERE v.video_id = $1 AND v.deleted_at IS NULL AND a.deleted_at IS NULL;
+
+-- name: HoldVideo :exec
+UPDATE video
+SET status = 'held', updated_at = now()
+WHERE video_id = $1;
+
+-- name: UpdateVideoVisibility :execrows
+UPDATE video
+SET visibility = $3, updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND deleted_at IS NULL;
+
+-- name: UpdateVideoCategory :execrows
+UPDATE video
+SET category = $3, updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND deleted_at IS NULL;
+
+-- name: DeleteVideo :execrows
+UPDATE video
+SET deleted_at = now(), updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND deleted_at IS NULL;
+
+-- name: ListRetainedOriginals :many
+SELECT v.video_id, v.publisher_id FROM video v
+WHERE v.status = 'published' AND v.original_removed_at IS NULL
+    AND EXISTS (SELECT 1 FROM video_rendition r WHERE r.video_id = v.video_id)
+    AND NOT EXISTS (SELECT 1 FROM video_rendition r WHERE r.video_id = v.video_id AND r.status <> 'completed')
+ORDER BY v.publisher_id, v.created_at DESC;
+
+-- name: MarkOriginalRemoved :exec
+UPDATE video
+SET original_removed_at = now()
+WHERE video_id = $1;
+
+-- name: UpdateVideoLicense :execrows
+UPDATE video
+SET license = $3, attribution = $4, updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND deleted_at IS NULL;
+
+-- name: SearchVideos :many
+SELECT v.video_id, v.title, v.duration, v.created_at, v.license, v.attribution, a.account_id, a.username
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND (sqlc.arg(query)::text = '' OR v.search_vector @@ to_tsquery('simple', sqlc.arg(query)::text))
+    AND (sqlc.arg(license)::text = '' OR v.license::text = sqlc.arg(license)::text)
+    AND (NOT sqlc.arg(reusable)::boolean OR v.license <> 'standard')
+    AND a.tenant_id = sqlc.arg(tenant_id)
+    AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+        OR (v.created_at, v.video_id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: FailVideo :exec
+UPDATE video
+SET status = 'failed', updated_at = now()
+WHERE video_id = $1 AND status = 'pending';
+
+-- name: ResetFailedVideo :exec
+UPDATE video
+SET status = 'pending', updated_at = now()
+WHERE video_id = $1 AND status = 'failed';
+
+-- name: ListVideoFiles :many
+SELECT video_id, publisher_id, status, original_removed_at, cold_at FROM video;
+
+-- name: GetVideoAccess :one
+SELECT v.publisher_id, v.status, v.visibility, v.cold_at, v.age_restricted FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.video_id = $1 AND v.deleted_at IS NULL AND a.deleted_at IS NULL;
+
+-- name: ListStaleVideos :many
+SELECT v.video_id, v.publisher_id FROM video v
+WHERE v.status = 'published' AND v.cold_at IS NULL AND v.created_at < $1
+    AND NOT EXISTS (SELECT 1 FROM view_event e WHERE e.video_id = v.video_id AND e.created_at >= $1);
+
+-- name: MarkVideoCold :exec
+UPDATE video
+SET cold_at = now()
+WHERE video_id = $1;
+
+-- name: MarkVideoWarm :exec
+UPDATE video
+SET cold_at = NULL
+WHERE video_id = $1;
+
+-- name: ListFeedVideos :many
+SELECT v.video_id, v.title, v.duration, v.description, v.created_at, v.license, a.account_id, a.username,
+    v.view_count AS total_view,
+    v.like_count AS total_like
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND a.tenant_id = sqlc.arg(tenant_id)
+    AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+        OR (v.created_at, v.video_id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: ListChannelVideos :many
+SELECT v.video_id, v.title, v.duration, v.description, v.created_at, v.license, a.account_id, a.username,
+    v.view_count AS total_view,
+    v.like_count AS total_like
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND v.publisher_id = sqlc.arg(publisher_id)
+    AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+        OR (v.created_at, v.video_id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: ListSubscriptionVideos :many
+SELECT v.video_id, v.title, v.duration, v.description, v.created_at, v.license, a.account_id, a.username,
+    v.view_count AS total_view,
+    v.like_count AS total_like
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND v.publisher_id IN (SELECT subscribe_to_id FROM subscribe WHERE subscriber_id = sqlc.arg(subscriber_id))
+    AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+        OR (v.created_at, v.video_id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: EditVideo :one
+UPDATE video
+SET title = $4, description = $5, version = version + 1, updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND version = $3 AND deleted_at IS NULL
+RETURNING version;
+
+-- name: RestoreVideo :execrows
+UPDATE video
+SET deleted_at = NULL, updated_at = now()
+WHERE video_id = $1 AND deleted_at IS NOT NULL;
+
+-- name: ListDeletedVideos :many
+SELECT v.video_id, v.title, v.status, v.deleted_at, a.account_id, a.username FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.deleted_at IS NOT NULL
+    AND (sqlc.narg(after_deleted_at)::timestamptz IS NULL
+        OR (v.deleted_at, v.video_id) < (sqlc.narg(after_deleted_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY v.deleted_at DESC, v.video_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: SearchVideosRanked :many
+SELECT v.video_id, v.title, v.duration, v.created_at, v.license, v.attribution, a.account_id, a.username,
+    ts_rank(v.search_vector, to_tsquery('simple', sqlc.arg(query)::text)) AS rank
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND v.search_vector @@ to_tsquery('simple', sqlc.arg(query)::text)
+    AND (sqlc.arg(license)::text = '' OR v.license::text = sqlc.arg(license)::text)
+    AND (NOT sqlc.arg(reusable)::boolean OR v.license <> 'standard')
+    AND a.tenant_id = sqlc.arg(tenant_id)
+ORDER BY rank DESC, v.created_at DESC, v.video_id DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: ReconcileVideoCounters :many
+UPDATE video v
+SET like_count = c.likes, view_count = c.views
+FROM (
+    SELECT x.video_id, x.like_count AS old_likes, x.view_count AS old_views,
+        (SELECT COUNT(*) FROM like_video lv WHERE lv.video_id = x.video_id) AS likes,
+        x.pruned_view_count + (SELECT COUNT(*) FROM view_event ve WHERE ve.video_id = x.video_id) AS views
+    FROM video x
+) c
+WHERE v.video_id = c.video_id AND (v.like_count <> c.likes OR v.view_count <> c.views)
+RETURNING v.video_id, c.old_likes, c.likes, c.old_views, c.views;
+
+-- name: SetVideoAgeRestricted :execrows
+UPDATE video
+SET age_restricted = true, updated_at = now()
+WHERE video_id = $1 AND deleted_at IS NULL;
+
+-- name: RemoveVideo :execrows
+UPDATE video
+SET deleted_at = now(), updated_at = now()
+WHERE video_id = $1 AND deleted_at IS NULL;
+
+-- name: GetVideoPublisher :one
+SELECT publisher_id, title FROM video
+WHERE video_id = $1;
+
+-- name: ListPublisherVideoIDs :many
+SELECT video_id FROM video
+WHERE publisher_id = $1;
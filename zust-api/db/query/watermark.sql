@@ -0,0 +1,13 @@
+-- name: UpsertWatermark :exec
+INSERT INTO watermark (account_id, position, opacity)
+VALUES ($1, $2, $3)
+ON CONFLICT (account_id) DO UPDATE
+SET position = EXCLUDED.position, opacity = EXCLUDED.opacity, updated_at = now();
+
+-- name: GetWatermark :one
+SELECT * FROM watermark
+WHERE account_id = $1;
+
+-- name: DeleteWatermark :exec
+DELETE FROM watermark
+WHERE account_id = $1;
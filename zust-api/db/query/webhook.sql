@@ -0,0 +1,53 @@
+-- name: CreateWebhook :one
+INSERT INTO webhook (account_id, url, secret, events)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhook
+WHERE webhook_id = $1;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhook
+WHERE account_id = $1
+ORDER BY created_at DESC;
+
+-- name: CountWebhooks :one
+SELECT COUNT(*) FROM webhook
+WHERE account_id = $1;
+
+-- name: ListEventWebhooks :many
+SELECT * FROM webhook
+WHERE account_id = sqlc.arg(account_id) AND sqlc.arg(event)::text = ANY(events);
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhook
+WHERE webhook_id = $1 AND account_id = $2;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_delivery (webhook_id, event, payload)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_delivery
+SET status = $2, attempts = $3, status_code = $4, error = $5, last_attempt_at = now()
+WHERE delivery_id = $1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_delivery
+WHERE webhook_id = sqlc.arg(webhook_id)
+    AND (sqlc.narg(after_created_at)::timestamptz IS NULL
+        OR (created_at, delivery_id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid))
+ORDER BY created_at DESC, delivery_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: DeleteOldWebhookDeliveries :execrows
+DELETE FROM webhook_delivery
+WHERE created_at < sqlc.arg(before);
+
+-- name: GetWebhookDelivery :one
+SELECT d.delivery_id, d.event, d.payload, w.url, w.secret
+FROM webhook_delivery d
+JOIN webhook w ON w.webhook_id = d.webhook_id
+WHERE d.delivery_id = $1;
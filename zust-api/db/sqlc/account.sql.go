@@ -8,6 +8,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -23,13 +24,42 @@ func (q *Queries) ActivateAccount(ctx context.Context, accountID uuid.UUID) erro
 	return err
 }
 
+const addSubscribers = `-- name: AddSubscribers :exec
+UPDATE account
+SET subscriber_count = subscriber_count + $1
+WHERE account_id = $2
+`
+
+type AddSubscribersParams struct {
+	Delta     int64     `json:"delta"`
+	AccountID uuid.UUID `json:"account_id"`
+}
+
+func (q *Queries) AddSubscribers(ctx context.Context, arg AddSubscribersParams) error {
+	_, err := q.db.ExecContext(ctx, addSubscribers, arg.Delta, arg.AccountID)
+	return err
+}
+
+const countSubscribers = `-- name: CountSubscribers :one
+SELECT subscriber_count FROM account
+WHERE account_id = $1
+`
+
+func (q *Queries) CountSubscribers(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSubscribers, accountID)
+	var subscriber_count int64
+	err := row.Scan(&subscriber_count)
+	return subscriber_count, err
+}
+
 const createAccountWithOAuth = `-- name: CreateAccountWithOAuth :one
-INSERT INTO account (email, username, status, oauth_provider, oauth_provider_id)
-VALUES ($1, $2, 'active', $3, $4)
-RETURNING account_id, email, username, password, description, status, oauth_provider, oauth_provider_id, token_version
+INSERT INTO account (tenant_id, email, username, status, oauth_provider, oauth_provider_id)
+VALUES ($1, $2, $3, 'active', $4, $5)
+RETURNING account_id, email, username, password, description, status, oauth_provider, oauth_provider_id, token_version, processing_webhook_url, role, tenant_id, version, deleted_at, search_vector, subscriber_count, digest_frequency, timezone, digest_sent_at, phone_number, phone_verified_at, suspended_until, created_at
 `
 
 type CreateAccountWithOAuthParams struct {
+	TenantID        string         `json:"tenant_id"`
 	Email           string         `json:"email"`
 	Username        string         `json:"username"`
 	OauthProvider   sql.NullString `json:"oauth_provider"`
@@ -38,6 +68,7 @@ type CreateAccountWithOAuthParams struct {
 
 func (q *Queries) CreateAccountWithOAuth(ctx context.Context, arg CreateAccountWithOAuthParams) (Account, error) {
 	row := q.db.QueryRowContext(ctx, createAccountWithOAuth,
+		arg.TenantID,
 		arg.Email,
 		arg.Username,
 		arg.OauthProvider,
@@ -54,24 +85,44 @@ func (q *Queries) CreateAccountWithOAuth(ctx context.Context, arg CreateAccountW
 		&i.OauthProvider,
 		&i.OauthProviderID,
 		&i.TokenVersion,
+		&i.ProcessingWebhookUrl,
+		&i.Role,
+		&i.TenantID,
+		&i.Version,
+		&i.DeletedAt,
+		&i.SearchVector,
+		&i.SubscriberCount,
+		&i.DigestFrequency,
+		&i.Timezone,
+		&i.DigestSentAt,
+		&i.PhoneNumber,
+		&i.PhoneVerifiedAt,
+		&i.SuspendedUntil,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const createAccountWithPassword = `-- name: CreateAccountWithPassword :one
-INSERT INTO account (email, username, password)
-VALUES ($1, $2, $3)
-RETURNING account_id, email, username, password, description, status, oauth_provider, oauth_provider_id, token_version
+INSERT INTO account (tenant_id, email, username, password)
+VALUES ($1, $2, $3, $4)
+RETURNING account_id, email, username, password, description, status, oauth_provider, oauth_provider_id, token_version, processing_webhook_url, role, tenant_id, version, deleted_at, search_vector, subscriber_count, digest_frequency, timezone, digest_sent_at, phone_number, phone_verified_at, suspended_until, created_at
 `
 
 type CreateAccountWithPasswordParams struct {
+	TenantID string         `json:"tenant_id"`
 	Email    string         `json:"email"`
 	Username string         `json:"username"`
 	Password sql.NullString `json:"password"`
 }
 
 func (q *Queries) CreateAccountWithPassword(ctx context.Context, arg CreateAccountWithPasswordParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, createAccountWithPassword, arg.Email, arg.Username, arg.Password)
+	row := q.db.QueryRowContext(ctx, createAccountWithPassword,
+		arg.TenantID,
+		arg.Email,
+		arg.Username,
+		arg.Password,
+	)
 	var i Account
 	err := row.Scan(
 		&i.AccountID,
@@ -83,21 +134,50 @@ func (q *Queries) CreateAccountWithPassword(ctx context.Context, arg CreateAccou
 		&i.OauthProvider,
 		&i.OauthProviderID,
 		&i.TokenVersion,
+		&i.ProcessingWebhookUrl,
+		&i.Role,
+		&i.TenantID,
+		&i.Version,
+		&i.DeletedAt,
+		&i.SearchVector,
+		&i.SubscriberCount,
+		&i.DigestFrequency,
+		&i.Timezone,
+		&i.DigestSentAt,
+		&i.PhoneNumber,
+		&i.PhoneVerifiedAt,
+		&i.SuspendedUntil,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
+const deleteAccount = `-- name: DeleteAccount :execrows
+UPDATE account
+SET deleted_at = now(), token_version = token_version + 1
+WHERE account_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) DeleteAccount(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAccount, accountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const editProfile = `-- name: EditProfile :one
 UPDATE account
-SET username = $2, description = $3
-WHERE account_id = $1
-RETURNING account_id, email, username, description, status
+SET username = $2, description = $3, version = version + 1
+WHERE account_id = $1 AND version = $4
+RETURNING account_id, email, username, description, status, version
 `
 
 type EditProfileParams struct {
 	AccountID   uuid.UUID      `json:"account_id"`
 	Username    string         `json:"username"`
 	Description sql.NullString `json:"description"`
+	Version     int32          `json:"version"`
 }
 
 type EditProfileRow struct {
@@ -106,10 +186,16 @@ type EditProfileRow struct {
 	Username    string         `json:"username"`
 	Description sql.NullString `json:"description"`
 	Status      AccountStatus  `json:"status"`
+	Version     int32          `json:"version"`
 }
 
 func (q *Queries) EditProfile(ctx context.Context, arg EditProfileParams) (EditProfileRow, error) {
-	row := q.db.QueryRowContext(ctx, editProfile, arg.AccountID, arg.Username, arg.Description)
+	row := q.db.QueryRowContext(ctx, editProfile,
+		arg.AccountID,
+		arg.Username,
+		arg.Description,
+		arg.Version,
+	)
 	var i EditProfileRow
 	err := row.Scan(
 		&i.AccountID,
@@ -117,15 +203,21 @@ func (q *Queries) EditProfile(ctx context.Context, arg EditProfileParams) (EditP
 		&i.Username,
 		&i.Description,
 		&i.Status,
+		&i.Version,
 	)
 	return i, err
 }
 
 const getAccountByEmail = `-- name: GetAccountByEmail :one
 SELECT account_id, email, username, password, description, status, token_version FROM account
-WHERE email = $1
+WHERE tenant_id = $1 AND email = $2 AND deleted_at IS NULL
 `
 
+type GetAccountByEmailParams struct {
+	TenantID string `json:"tenant_id"`
+	Email    string `json:"email"`
+}
+
 type GetAccountByEmailRow struct {
 	AccountID    uuid.UUID      `json:"account_id"`
 	Email        string         `json:"email"`
@@ -136,8 +228,8 @@ type GetAccountByEmailRow struct {
 	TokenVersion int32          `json:"token_version"`
 }
 
-func (q *Queries) GetAccountByEmail(ctx context.Context, email string) (GetAccountByEmailRow, error) {
-	row := q.db.QueryRowContext(ctx, getAccountByEmail, email)
+func (q *Queries) GetAccountByEmail(ctx context.Context, arg GetAccountByEmailParams) (GetAccountByEmailRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByEmail, arg.TenantID, arg.Email)
 	var i GetAccountByEmailRow
 	err := row.Scan(
 		&i.AccountID,
@@ -153,9 +245,14 @@ func (q *Queries) GetAccountByEmail(ctx context.Context, email string) (GetAccou
 
 const getAccountByUsername = `-- name: GetAccountByUsername :one
 SELECT account_id, email, username, password, description, status, token_version FROM account
-WHERE username = $1
+WHERE tenant_id = $1 AND username = $2 AND deleted_at IS NULL
 `
 
+type GetAccountByUsernameParams struct {
+	TenantID string `json:"tenant_id"`
+	Username string `json:"username"`
+}
+
 type GetAccountByUsernameRow struct {
 	AccountID    uuid.UUID      `json:"account_id"`
 	Email        string         `json:"email"`
@@ -166,8 +263,8 @@ type GetAccountByUsernameRow struct {
 	TokenVersion int32          `json:"token_version"`
 }
 
-func (q *Queries) GetAccountByUsername(ctx context.Context, username string) (GetAccountByUsernameRow, error) {
-	row := q.db.QueryRowContext(ctx, getAccountByUsername, username)
+func (q *Queries) GetAccountByUsername(ctx context.Context, arg GetAccountByUsernameParams) (GetAccountByUsernameRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByUsername, arg.TenantID, arg.Username)
 	var i GetAccountByUsernameRow
 	err := row.Scan(
 		&i.AccountID,
@@ -181,17 +278,72 @@ func (q *Queries) GetAccountByUsername(ctx context.Context, username string) (Ge
 	return i, err
 }
 
-const getProfile = `-- name: GetProfile :one
-SELECT account_id, email, username, description, status FROM account
+const getAccountCredentials = `-- name: GetAccountCredentials :one
+SELECT password, phone_number, phone_verified_at FROM account
+WHERE account_id = $1 AND deleted_at IS NULL
+`
+
+type GetAccountCredentialsRow struct {
+	Password        sql.NullString `json:"password"`
+	PhoneNumber     sql.NullString `json:"phone_number"`
+	PhoneVerifiedAt sql.NullTime   `json:"phone_verified_at"`
+}
+
+func (q *Queries) GetAccountCredentials(ctx context.Context, accountID uuid.UUID) (GetAccountCredentialsRow, error) {
+	row := q.db.QueryRowContext(ctx, getAccountCredentials, accountID)
+	var i GetAccountCredentialsRow
+	err := row.Scan(&i.Password, &i.PhoneNumber, &i.PhoneVerifiedAt)
+	return i, err
+}
+
+const getAccountRole = `-- name: GetAccountRole :one
+SELECT role FROM account
 WHERE account_id = $1
 `
 
+func (q *Queries) GetAccountRole(ctx context.Context, accountID uuid.UUID) (AccountRole, error) {
+	row := q.db.QueryRowContext(ctx, getAccountRole, accountID)
+	var role AccountRole
+	err := row.Scan(&role)
+	return role, err
+}
+
+const getAccountTenant = `-- name: GetAccountTenant :one
+SELECT tenant_id FROM account
+WHERE account_id = $1
+`
+
+func (q *Queries) GetAccountTenant(ctx context.Context, accountID uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, getAccountTenant, accountID)
+	var tenant_id string
+	err := row.Scan(&tenant_id)
+	return tenant_id, err
+}
+
+const getProcessingWebhook = `-- name: GetProcessingWebhook :one
+SELECT processing_webhook_url FROM account
+WHERE account_id = $1
+`
+
+func (q *Queries) GetProcessingWebhook(ctx context.Context, accountID uuid.UUID) (sql.NullString, error) {
+	row := q.db.QueryRowContext(ctx, getProcessingWebhook, accountID)
+	var processing_webhook_url sql.NullString
+	err := row.Scan(&processing_webhook_url)
+	return processing_webhook_url, err
+}
+
+const getProfile = `-- name: GetProfile :one
+SELECT account_id, email, username, description, status, version FROM account
+WHERE account_id = $1 AND deleted_at IS NULL
+`
+
 type GetProfileRow struct {
 	AccountID   uuid.UUID      `json:"account_id"`
 	Email       string         `json:"email"`
 	Username    string         `json:"username"`
 	Description sql.NullString `json:"description"`
 	Status      AccountStatus  `json:"status"`
+	Version     int32          `json:"version"`
 }
 
 func (q *Queries) GetProfile(ctx context.Context, accountID uuid.UUID) (GetProfileRow, error) {
@@ -203,6 +355,7 @@ func (q *Queries) GetProfile(ctx context.Context, accountID uuid.UUID) (GetProfi
 		&i.Username,
 		&i.Description,
 		&i.Status,
+		&i.Version,
 	)
 	return i, err
 }
@@ -232,22 +385,223 @@ func (q *Queries) IncrementTokenVersion(ctx context.Context, accountID uuid.UUID
 
 const isAccountRegistered = `-- name: IsAccountRegistered :one
 SELECT EXISTS (
-    SELECT 1 FROM account WHERE oauth_provider = $1 AND oauth_provider_id = $2
+    SELECT 1 FROM account WHERE tenant_id = $1 AND oauth_provider = $2 AND oauth_provider_id = $3
 )
 `
 
 type IsAccountRegisteredParams struct {
+	TenantID        string         `json:"tenant_id"`
 	OauthProvider   sql.NullString `json:"oauth_provider"`
 	OauthProviderID sql.NullString `json:"oauth_provider_id"`
 }
 
 func (q *Queries) IsAccountRegistered(ctx context.Context, arg IsAccountRegisteredParams) (bool, error) {
-	row := q.db.QueryRowContext(ctx, isAccountRegistered, arg.OauthProvider, arg.OauthProviderID)
+	row := q.db.QueryRowContext(ctx, isAccountRegistered, arg.TenantID, arg.OauthProvider, arg.OauthProviderID)
 	var exists bool
 	err := row.Scan(&exists)
 	return exists, err
 }
 
+const listAccountIDs = `-- name: ListAccountIDs :many
+SELECT account_id FROM account
+`
+
+func (q *Queries) ListAccountIDs(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var account_id uuid.UUID
+		if err := rows.Scan(&account_id); err != nil {
+			return nil, err
+		}
+		items = append(items, account_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAdminEmails = `-- name: ListAdminEmails :many
+SELECT email FROM account
+WHERE role = 'admin' AND status = 'active' AND deleted_at IS NULL
+`
+
+func (q *Queries) ListAdminEmails(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listAdminEmails)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		items = append(items, email)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeletedAccounts = `-- name: ListDeletedAccounts :many
+SELECT account_id, tenant_id, email, username, deleted_at FROM account
+WHERE deleted_at IS NOT NULL
+    AND ($1::timestamptz IS NULL
+        OR (deleted_at, account_id) < ($1::timestamptz, $2::uuid))
+ORDER BY deleted_at DESC, account_id DESC
+LIMIT $3
+`
+
+type ListDeletedAccountsParams struct {
+	AfterDeletedAt sql.NullTime  `json:"after_deleted_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageSize       int32         `json:"page_size"`
+}
+
+type ListDeletedAccountsRow struct {
+	AccountID uuid.UUID    `json:"account_id"`
+	TenantID  string       `json:"tenant_id"`
+	Email     string       `json:"email"`
+	Username  string       `json:"username"`
+	DeletedAt sql.NullTime `json:"deleted_at"`
+}
+
+func (q *Queries) ListDeletedAccounts(ctx context.Context, arg ListDeletedAccountsParams) ([]ListDeletedAccountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDeletedAccounts, arg.AfterDeletedAt, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDeletedAccountsRow{}
+	for rows.Next() {
+		var i ListDeletedAccountsRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.TenantID,
+			&i.Email,
+			&i.Username,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscribers = `-- name: ListSubscribers :many
+SELECT a.account_id, a.username, a.description, s.subscribe_at FROM subscribe s
+JOIN account a ON a.account_id = s.subscriber_id
+WHERE s.subscribe_to_id = $1 AND a.status = 'active' AND a.deleted_at IS NULL
+    AND ($2::timestamptz IS NULL
+        OR (s.subscribe_at, s.subscriber_id) < ($2::timestamptz, $3::uuid))
+ORDER BY s.subscribe_at DESC, s.subscriber_id DESC
+LIMIT $4
+`
+
+type ListSubscribersParams struct {
+	SubscribeToID  uuid.UUID     `json:"subscribe_to_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageSize       int32         `json:"page_size"`
+}
+
+type ListSubscribersRow struct {
+	AccountID   uuid.UUID      `json:"account_id"`
+	Username    string         `json:"username"`
+	Description sql.NullString `json:"description"`
+	SubscribeAt time.Time      `json:"subscribe_at"`
+}
+
+func (q *Queries) ListSubscribers(ctx context.Context, arg ListSubscribersParams) ([]ListSubscribersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSubscribers,
+		arg.SubscribeToID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSubscribersRow{}
+	for rows.Next() {
+		var i ListSubscribersRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.Username,
+			&i.Description,
+			&i.SubscribeAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptions = `-- name: ListSubscriptions :many
+SELECT a.account_id, a.username, a.description FROM subscribe s
+JOIN account a ON a.account_id = s.subscribe_to_id
+WHERE s.subscriber_id = $1 AND a.status = 'active' AND a.deleted_at IS NULL
+ORDER BY s.subscribe_at DESC
+`
+
+type ListSubscriptionsRow struct {
+	AccountID   uuid.UUID      `json:"account_id"`
+	Username    string         `json:"username"`
+	Description sql.NullString `json:"description"`
+}
+
+func (q *Queries) ListSubscriptions(ctx context.Context, subscriberID uuid.UUID) ([]ListSubscriptionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSubscriptions, subscriberID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSubscriptionsRow{}
+	for rows.Next() {
+		var i ListSubscriptionsRow
+		if err := rows.Scan(&i.AccountID, &i.Username, &i.Description); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const lockAccount = `-- name: LockAccount :exec
 UPDATE account
 SET status = 'locked'
@@ -261,10 +615,11 @@ func (q *Queries) LockAccount(ctx context.Context, accountID uuid.UUID) error {
 
 const loginWithOAuth = `-- name: LoginWithOAuth :one
 SELECT account_id, email, username, description, status, token_version FROM account
-WHERE oauth_provider = $1 AND oauth_provider_id = $2
+WHERE tenant_id = $1 AND oauth_provider = $2 AND oauth_provider_id = $3 AND deleted_at IS NULL
 `
 
 type LoginWithOAuthParams struct {
+	TenantID        string         `json:"tenant_id"`
 	OauthProvider   sql.NullString `json:"oauth_provider"`
 	OauthProviderID sql.NullString `json:"oauth_provider_id"`
 }
@@ -279,7 +634,7 @@ type LoginWithOAuthRow struct {
 }
 
 func (q *Queries) LoginWithOAuth(ctx context.Context, arg LoginWithOAuthParams) (LoginWithOAuthRow, error) {
-	row := q.db.QueryRowContext(ctx, loginWithOAuth, arg.OauthProvider, arg.OauthProviderID)
+	row := q.db.QueryRowContext(ctx, loginWithOAuth, arg.TenantID, arg.OauthProvider, arg.OauthProviderID)
 	var i LoginWithOAuthRow
 	err := row.Scan(
 		&i.AccountID,
@@ -292,6 +647,239 @@ func (q *Queries) LoginWithOAuth(ctx context.Context, arg LoginWithOAuthParams)
 	return i, err
 }
 
+const reconcileSubscriberCounters = `-- name: ReconcileSubscriberCounters :many
+UPDATE account a
+SET subscriber_count = c.subscribers
+FROM (
+    SELECT x.account_id, x.subscriber_count AS old_subscribers,
+        (SELECT COUNT(*) FROM subscribe s WHERE s.subscribe_to_id = x.account_id) AS subscribers
+    FROM account x
+) c
+WHERE a.account_id = c.account_id AND a.subscriber_count <> c.subscribers
+RETURNING a.account_id, c.old_subscribers, c.subscribers
+`
+
+type ReconcileSubscriberCountersRow struct {
+	AccountID      uuid.UUID `json:"account_id"`
+	OldSubscribers int64     `json:"old_subscribers"`
+	Subscribers    int64     `json:"subscribers"`
+}
+
+func (q *Queries) ReconcileSubscriberCounters(ctx context.Context) ([]ReconcileSubscriberCountersRow, error) {
+	rows, err := q.db.QueryContext(ctx, reconcileSubscriberCounters)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconcileSubscriberCountersRow{}
+	for rows.Next() {
+		var i ReconcileSubscriberCountersRow
+		if err := rows.Scan(&i.AccountID, &i.OldSubscribers, &i.Subscribers); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reinstateSuspendedAccounts = `-- name: ReinstateSuspendedAccounts :many
+UPDATE account
+SET status = 'active', suspended_until = NULL
+WHERE status = 'banned' AND suspended_until <= $1::timestamptz
+RETURNING account_id
+`
+
+func (q *Queries) ReinstateSuspendedAccounts(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, reinstateSuspendedAccounts, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var account_id uuid.UUID
+		if err := rows.Scan(&account_id); err != nil {
+			return nil, err
+		}
+		items = append(items, account_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removePhoneNumber = `-- name: RemovePhoneNumber :exec
+UPDATE account
+SET phone_number = NULL, phone_verified_at = NULL
+WHERE account_id = $1
+`
+
+func (q *Queries) RemovePhoneNumber(ctx context.Context, accountID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, removePhoneNumber, accountID)
+	return err
+}
+
+const restoreAccount = `-- name: RestoreAccount :execrows
+UPDATE account
+SET deleted_at = NULL
+WHERE account_id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) RestoreAccount(ctx context.Context, accountID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreAccount, accountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchChannels = `-- name: SearchChannels :many
+SELECT account_id, username, description,
+    ts_rank(search_vector, to_tsquery('simple', $1::text)) AS rank
+FROM account
+WHERE tenant_id = $2 AND status = 'active' AND deleted_at IS NULL
+    AND search_vector @@ to_tsquery('simple', $1::text)
+ORDER BY rank DESC, account_id
+LIMIT $4 OFFSET $3
+`
+
+type SearchChannelsParams struct {
+	Query      string `json:"query"`
+	TenantID   string `json:"tenant_id"`
+	PageOffset int32  `json:"page_offset"`
+	PageSize   int32  `json:"page_size"`
+}
+
+type SearchChannelsRow struct {
+	AccountID   uuid.UUID      `json:"account_id"`
+	Username    string         `json:"username"`
+	Description sql.NullString `json:"description"`
+	Rank        float32        `json:"rank"`
+}
+
+func (q *Queries) SearchChannels(ctx context.Context, arg SearchChannelsParams) ([]SearchChannelsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChannels,
+		arg.Query,
+		arg.TenantID,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchChannelsRow{}
+	for rows.Next() {
+		var i SearchChannelsRow
+		if err := rows.Scan(
+			&i.AccountID,
+			&i.Username,
+			&i.Description,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setAccountRole = `-- name: SetAccountRole :exec
+UPDATE account
+SET role = $2
+WHERE account_id = $1
+`
+
+type SetAccountRoleParams struct {
+	AccountID uuid.UUID   `json:"account_id"`
+	Role      AccountRole `json:"role"`
+}
+
+func (q *Queries) SetAccountRole(ctx context.Context, arg SetAccountRoleParams) error {
+	_, err := q.db.ExecContext(ctx, setAccountRole, arg.AccountID, arg.Role)
+	return err
+}
+
+const setAccountStatus = `-- name: SetAccountStatus :exec
+UPDATE account
+SET status = $2, suspended_until = NULL
+WHERE account_id = $1
+`
+
+type SetAccountStatusParams struct {
+	AccountID uuid.UUID     `json:"account_id"`
+	Status    AccountStatus `json:"status"`
+}
+
+func (q *Queries) SetAccountStatus(ctx context.Context, arg SetAccountStatusParams) error {
+	_, err := q.db.ExecContext(ctx, setAccountStatus, arg.AccountID, arg.Status)
+	return err
+}
+
+const setPassword = `-- name: SetPassword :exec
+UPDATE account
+SET password = $2
+WHERE account_id = $1
+`
+
+type SetPasswordParams struct {
+	AccountID uuid.UUID      `json:"account_id"`
+	Password  sql.NullString `json:"password"`
+}
+
+func (q *Queries) SetPassword(ctx context.Context, arg SetPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setPassword, arg.AccountID, arg.Password)
+	return err
+}
+
+const setPhoneNumber = `-- name: SetPhoneNumber :exec
+UPDATE account
+SET phone_number = $2, phone_verified_at = now()
+WHERE account_id = $1
+`
+
+type SetPhoneNumberParams struct {
+	AccountID   uuid.UUID      `json:"account_id"`
+	PhoneNumber sql.NullString `json:"phone_number"`
+}
+
+func (q *Queries) SetPhoneNumber(ctx context.Context, arg SetPhoneNumberParams) error {
+	_, err := q.db.ExecContext(ctx, setPhoneNumber, arg.AccountID, arg.PhoneNumber)
+	return err
+}
+
+const setProcessingWebhook = `-- name: SetProcessingWebhook :exec
+UPDATE account
+SET processing_webhook_url = $2
+WHERE account_id = $1
+`
+
+type SetProcessingWebhookParams struct {
+	AccountID            uuid.UUID      `json:"account_id"`
+	ProcessingWebhookUrl sql.NullString `json:"processing_webhook_url"`
+}
+
+func (q *Queries) SetProcessingWebhook(ctx context.Context, arg SetProcessingWebhookParams) error {
+	_, err := q.db.ExecContext(ctx, setProcessingWebhook, arg.AccountID, arg.ProcessingWebhookUrl)
+	return err
+}
+
 const subscribe = `-- name: Subscribe :one
 INSERT INTO subscribe (subscriber_id, subscribe_to_id)
 VALUES ($1, $2)
@@ -310,6 +898,27 @@ func (q *Queries) Subscribe(ctx context.Context, arg SubscribeParams) (Subscribe
 	return i, err
 }
 
+const suspendAccount = `-- name: SuspendAccount :execrows
+UPDATE account
+SET status = 'banned', suspended_until = $1, token_version = token_version + 1
+WHERE account_id = $2 AND deleted_at IS NULL
+    AND (status <> 'banned' OR (suspended_until IS NOT NULL AND ($1::timestamptz IS NULL
+        OR suspended_until < $1::timestamptz)))
+`
+
+type SuspendAccountParams struct {
+	SuspendedUntil sql.NullTime `json:"suspended_until"`
+	AccountID      uuid.UUID    `json:"account_id"`
+}
+
+func (q *Queries) SuspendAccount(ctx context.Context, arg SuspendAccountParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, suspendAccount, arg.SuspendedUntil, arg.AccountID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const unlockAccount = `-- name: UnlockAccount :exec
 UPDATE account
 SET status = 'active'
@@ -321,7 +930,7 @@ func (q *Queries) UnlockAccount(ctx context.Context, accountID uuid.UUID) error
 	return err
 }
 
-const unsubscribe = `-- name: Unsubscribe :exec
+const unsubscribe = `-- name: Unsubscribe :execrows
 DELETE FROM subscribe
 WHERE subscriber_id = $1 AND subscribe_to_id = $2
 `
@@ -331,7 +940,10 @@ type UnsubscribeParams struct {
 	SubscribeToID uuid.UUID `json:"subscribe_to_id"`
 }
 
-func (q *Queries) Unsubscribe(ctx context.Context, arg UnsubscribeParams) error {
-	_, err := q.db.ExecContext(ctx, unsubscribe, arg.SubscriberID, arg.SubscribeToID)
-	return err
+func (q *Queries) Unsubscribe(ctx context.Context, arg UnsubscribeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unsubscribe, arg.SubscriberID, arg.SubscribeToID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
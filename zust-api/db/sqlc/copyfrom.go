@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// A view event to insert in bulk
+type CreateViewEventsParams struct {
+	VideoID       uuid.UUID     `json:"video_id"`
+	AccountID     uuid.NullUUID `json:"account_id"`
+	WatchDuration int32         `json:"watch_duration"`
+	Source        string        `json:"source"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// Method to insert view events in bulk with the COPY protocol, a batch is sent in one round trip instead of one
+// INSERT per event. It returns the number of inserted events
+func (store *Store) CreateViewEvents(ctx context.Context, events []CreateViewEventsParams) (int64, error) {
+	defer store.metrics.observeSince("CreateViewEvents", time.Now())
+	rows := make([][]any, len(events))
+	for i, event := range events {
+		var accountID any
+		if event.AccountID.Valid {
+			accountID = event.AccountID.UUID
+		}
+		rows[i] = []any{event.VideoID, accountID, event.WatchDuration, event.Source, event.CreatedAt}
+	}
+	return store.copyFrom(ctx, "view_event",
+		[]string{"video_id", "account_id", "watch_duration", "source", "created_at"}, rows)
+}
+
+// A playback event to insert in bulk
+type CreatePlaybackEventsParams struct {
+	VideoID    uuid.UUID       `json:"video_id"`
+	AccountID  uuid.NullUUID   `json:"account_id"`
+	PlaybackID uuid.UUID       `json:"playback_id"`
+	Type       string          `json:"type"`
+	Position   float64         `json:"position"`
+	SeekFrom   sql.NullFloat64 `json:"seek_from"`
+	Quality    sql.NullString  `json:"quality"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Method to insert playback events in bulk with the COPY protocol. It returns the number of inserted events
+func (store *Store) CreatePlaybackEvents(ctx context.Context, events []CreatePlaybackEventsParams) (int64, error) {
+	defer store.metrics.observeSince("CreatePlaybackEvents", time.Now())
+	rows := make([][]any, len(events))
+	for i, event := range events {
+		var accountID, seekFrom, quality any
+		if event.AccountID.Valid {
+			accountID = event.AccountID.UUID
+		}
+		if event.SeekFrom.Valid {
+			seekFrom = event.SeekFrom.Float64
+		}
+		if event.Quality.Valid {
+			quality = event.Quality.String
+		}
+		rows[i] = []any{event.VideoID, accountID, event.PlaybackID, event.Type, event.Position, seekFrom, quality,
+			event.OccurredAt, event.CreatedAt}
+	}
+	return store.copyFrom(ctx, "playback_event", []string{"video_id", "account_id", "playback_id", "type", "position",
+		"seek_from", "quality", "occurred_at", "created_at"}, rows)
+}
+
+// Helper method: copy rows into a table on the pgx connection under database/sql, COPY isn't available through
+// the database/sql API
+func (store *Store) copyFrom(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+	conn, err := store.db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var count int64
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("copy into %s: connection is not a pgx connection", table)
+		}
+		count, err = pgxConn.Conn().CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+		return err
+	})
+	return count, err
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Errors of the unique constraints of the database, returned by MapError
+var (
+	ErrDuplicateEmail    = errors.New("email is already taken")
+	ErrDuplicateUsername = errors.New("username is already taken")
+	ErrDuplicateTitle    = errors.New("title is already used")
+)
+
+// Typed error of each unique constraint (emails and usernames are unique per tenant)
+var uniqueViolations = map[string]error{
+	"account_email_key":    ErrDuplicateEmail,
+	"account_username_key": ErrDuplicateUsername,
+	"video_title_key":      ErrDuplicateTitle,
+}
+
+// Function to map an error of a query to its typed error: the violation of a known unique constraint is returned
+// as the error of the constraint, wrapping the Postgres error. Other errors are returned as is
+func MapError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" { // unique_violation
+		return err
+	}
+	if typed, ok := uniqueViolations[pgErr.ConstraintName]; ok {
+		return fmt.Errorf("%w: %w", typed, err)
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upper bounds (in seconds) of the buckets of the query latency histograms
+var queryBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// QueryMetrics records the latency of the queries by name (the name of the query in db/query), and logs the queries
+// slower than a threshold
+type QueryMetrics struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration // 0 to never log the slow queries
+
+	mu      sync.Mutex
+	queries map[string]*queryHistogram
+}
+
+// Latency histogram of a query
+type queryHistogram struct {
+	buckets []uint64 // number of queries in each bucket of queryBuckets, not cumulative
+	count   uint64
+	sum     float64 // in seconds
+}
+
+// Constructor method for the query metrics, the queries taking at least slowThreshold are logged
+func NewQueryMetrics(logger *slog.Logger, slowThreshold time.Duration) *QueryMetrics {
+	return &QueryMetrics{
+		logger:        logger,
+		slowThreshold: slowThreshold,
+		queries:       make(map[string]*queryHistogram),
+	}
+}
+
+// Method to record the duration of a query, and log it if it's slow
+func (metrics *QueryMetrics) observe(name string, duration time.Duration) {
+	if metrics == nil {
+		return
+	}
+
+	seconds := duration.Seconds()
+	metrics.mu.Lock()
+	histogram, ok := metrics.queries[name]
+	if !ok {
+		histogram = &queryHistogram{buckets: make([]uint64, len(queryBuckets))}
+		metrics.queries[name] = histogram
+	}
+	if i, _ := slices.BinarySearch(queryBuckets, seconds); i < len(queryBuckets) {
+		histogram.buckets[i]++
+	}
+	histogram.count++
+	histogram.sum += seconds
+	metrics.mu.Unlock()
+
+	if metrics.slowThreshold > 0 && duration >= metrics.slowThreshold {
+		metrics.logger.Warn("db: slow query", "query", name, "duration", duration)
+	}
+}
+
+// Method to record the duration of a query started at 'start'
+func (metrics *QueryMetrics) observeSince(name string, start time.Time) {
+	metrics.observe(name, time.Since(start))
+}
+
+// Method to write the latency histograms of the queries in the Prometheus text format
+func (metrics *QueryMetrics) WritePrometheus(w io.Writer) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	const name = "zust_db_query_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Latency of the database queries in seconds\n# TYPE %s histogram\n", name, name)
+	for _, query := range slices.Sorted(maps.Keys(metrics.queries)) {
+		histogram := metrics.queries[query]
+		var cumulative uint64
+		for i, bound := range queryBuckets {
+			cumulative += histogram.buckets[i]
+			fmt.Fprintf(w, "%s_bucket{query=%q,le=\"%g\"} %d\n", name, query, bound, cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{query=%q,le=\"+Inf\"} %d\n", name, query, histogram.count)
+		fmt.Fprintf(w, "%s_sum{query=%q} %g\n", name, query, histogram.sum)
+		fmt.Fprintf(w, "%s_count{query=%q} %d\n", name, query, histogram.count)
+	}
+}
+
+// Method to wrap a connection (or transaction) so the duration of its queries is recorded. The connection is
+// returned as is when the metrics are disabled
+func (metrics *QueryMetrics) instrument(conn DBTX) DBTX {
+	if metrics == nil {
+		return conn
+	}
+	return &instrumentedDB{conn: conn, metrics: metrics}
+}
+
+// Connection recording the duration of its queries. The duration of a query returning rows is the time until its
+// first rows are received, reading the rest of the rows is not included
+type instrumentedDB struct {
+	conn    DBTX
+	metrics *QueryMetrics
+}
+
+func (db *instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer db.metrics.observeSince(queryName(query), time.Now())
+	return db.conn.ExecContext(ctx, query, args...)
+}
+
+func (db *instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.conn.PrepareContext(ctx, query)
+}
+
+func (db *instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	defer db.metrics.observeSince(queryName(query), time.Now())
+	return db.conn.QueryContext(ctx, query, args...)
+}
+
+func (db *instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	defer db.metrics.observeSince(queryName(query), time.Now())
+	return db.conn.QueryRowContext(ctx, query, args...)
+}
+
+// Helper function: get the name of a query from its sqlc header ("-- name: GetVideo :one"), "other" for the queries
+// which are not generated by sqlc
+func queryName(query string) string {
+	header, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "other"
+	}
+	name, _, _ := strings.Cut(header, " ")
+	return name
+}
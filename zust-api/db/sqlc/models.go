@@ -7,12 +7,55 @@ package db
 import (
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AccountRole string
+
+const (
+	AccountRoleUser  AccountRole = "user"
+	AccountRoleAdmin AccountRole = "admin"
+)
+
+func (e *AccountRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AccountRole(s)
+	case string:
+		*e = AccountRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AccountRole: %T", src)
+	}
+	return nil
+}
+
+type NullAccountRole struct {
+	AccountRole AccountRole `json:"account_role"`
+	Valid       bool        `json:"valid"` // Valid is true if AccountRole is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAccountRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.AccountRole, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AccountRole.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAccountRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AccountRole), nil
+}
+
 type AccountStatus string
 
 const (
@@ -57,12 +100,457 @@ func (ns NullAccountStatus) Value() (driver.Value, error) {
 	return string(ns.AccountStatus), nil
 }
 
+type BlockedWordMode string
+
+const (
+	BlockedWordModeExact     BlockedWordMode = "exact"
+	BlockedWordModeSubstring BlockedWordMode = "substring"
+	BlockedWordModeRegex     BlockedWordMode = "regex"
+)
+
+func (e *BlockedWordMode) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = BlockedWordMode(s)
+	case string:
+		*e = BlockedWordMode(s)
+	default:
+		return fmt.Errorf("unsupported scan type for BlockedWordMode: %T", src)
+	}
+	return nil
+}
+
+type NullBlockedWordMode struct {
+	BlockedWordMode BlockedWordMode `json:"blocked_word_mode"`
+	Valid           bool            `json:"valid"` // Valid is true if BlockedWordMode is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullBlockedWordMode) Scan(value interface{}) error {
+	if value == nil {
+		ns.BlockedWordMode, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.BlockedWordMode.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullBlockedWordMode) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.BlockedWordMode), nil
+}
+
+type DigestFrequency string
+
+const (
+	DigestFrequencyOff    DigestFrequency = "off"
+	DigestFrequencyDaily  DigestFrequency = "daily"
+	DigestFrequencyWeekly DigestFrequency = "weekly"
+)
+
+func (e *DigestFrequency) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DigestFrequency(s)
+	case string:
+		*e = DigestFrequency(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DigestFrequency: %T", src)
+	}
+	return nil
+}
+
+type NullDigestFrequency struct {
+	DigestFrequency DigestFrequency `json:"digest_frequency"`
+	Valid           bool            `json:"valid"` // Valid is true if DigestFrequency is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullDigestFrequency) Scan(value interface{}) error {
+	if value == nil {
+		ns.DigestFrequency, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.DigestFrequency.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullDigestFrequency) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.DigestFrequency), nil
+}
+
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusCompleted JobStatus = "completed"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+func (e *JobStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = JobStatus(s)
+	case string:
+		*e = JobStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for JobStatus: %T", src)
+	}
+	return nil
+}
+
+type NullJobStatus struct {
+	JobStatus JobStatus `json:"job_status"`
+	Valid     bool      `json:"valid"` // Valid is true if JobStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullJobStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.JobStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.JobStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullJobStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.JobStatus), nil
+}
+
+type ModerationAction string
+
+const (
+	ModerationActionDismiss     ModerationAction = "dismiss"
+	ModerationActionAgeRestrict ModerationAction = "age_restrict"
+	ModerationActionRemove      ModerationAction = "remove"
+	ModerationActionStrike      ModerationAction = "strike"
+)
+
+func (e *ModerationAction) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ModerationAction(s)
+	case string:
+		*e = ModerationAction(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ModerationAction: %T", src)
+	}
+	return nil
+}
+
+type NullModerationAction struct {
+	ModerationAction ModerationAction `json:"moderation_action"`
+	Valid            bool             `json:"valid"` // Valid is true if ModerationAction is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullModerationAction) Scan(value interface{}) error {
+	if value == nil {
+		ns.ModerationAction, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ModerationAction.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullModerationAction) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ModerationAction), nil
+}
+
+type NotificationType string
+
+const (
+	NotificationTypeNewVideo      NotificationType = "new_video"
+	NotificationTypeNewSubscriber NotificationType = "new_subscriber"
+	NotificationTypeVideoReady    NotificationType = "video_ready"
+	NotificationTypeVideoFailed   NotificationType = "video_failed"
+)
+
+func (e *NotificationType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = NotificationType(s)
+	case string:
+		*e = NotificationType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for NotificationType: %T", src)
+	}
+	return nil
+}
+
+type NullNotificationType struct {
+	NotificationType NotificationType `json:"notification_type"`
+	Valid            bool             `json:"valid"` // Valid is true if NotificationType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullNotificationType) Scan(value interface{}) error {
+	if value == nil {
+		ns.NotificationType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.NotificationType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullNotificationType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.NotificationType), nil
+}
+
+type RenditionStatus string
+
+const (
+	RenditionStatusQueued     RenditionStatus = "queued"
+	RenditionStatusProcessing RenditionStatus = "processing"
+	RenditionStatusCompleted  RenditionStatus = "completed"
+	RenditionStatusFailed     RenditionStatus = "failed"
+)
+
+func (e *RenditionStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RenditionStatus(s)
+	case string:
+		*e = RenditionStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RenditionStatus: %T", src)
+	}
+	return nil
+}
+
+type NullRenditionStatus struct {
+	RenditionStatus RenditionStatus `json:"rendition_status"`
+	Valid           bool            `json:"valid"` // Valid is true if RenditionStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRenditionStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.RenditionStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RenditionStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRenditionStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RenditionStatus), nil
+}
+
+type ReportReason string
+
+const (
+	ReportReasonSpam       ReportReason = "spam"
+	ReportReasonHarassment ReportReason = "harassment"
+	ReportReasonHate       ReportReason = "hate"
+	ReportReasonViolence   ReportReason = "violence"
+	ReportReasonSexual     ReportReason = "sexual"
+	ReportReasonCopyright  ReportReason = "copyright"
+	ReportReasonOther      ReportReason = "other"
+)
+
+func (e *ReportReason) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReportReason(s)
+	case string:
+		*e = ReportReason(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReportReason: %T", src)
+	}
+	return nil
+}
+
+type NullReportReason struct {
+	ReportReason ReportReason `json:"report_reason"`
+	Valid        bool         `json:"valid"` // Valid is true if ReportReason is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReportReason) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReportReason, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReportReason.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReportReason) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReportReason), nil
+}
+
+type ReportTarget string
+
+const (
+	ReportTargetVideo   ReportTarget = "video"
+	ReportTargetAccount ReportTarget = "account"
+)
+
+func (e *ReportTarget) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReportTarget(s)
+	case string:
+		*e = ReportTarget(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReportTarget: %T", src)
+	}
+	return nil
+}
+
+type NullReportTarget struct {
+	ReportTarget ReportTarget `json:"report_target"`
+	Valid        bool         `json:"valid"` // Valid is true if ReportTarget is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReportTarget) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReportTarget, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReportTarget.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReportTarget) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReportTarget), nil
+}
+
+type StepUpPurpose string
+
+const (
+	StepUpPurposePhoneVerification StepUpPurpose = "phone_verification"
+	StepUpPurposePhoneChange       StepUpPurpose = "phone_change"
+	StepUpPurposePasswordChange    StepUpPurpose = "password_change"
+	StepUpPurposeAccountDeletion   StepUpPurpose = "account_deletion"
+)
+
+func (e *StepUpPurpose) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = StepUpPurpose(s)
+	case string:
+		*e = StepUpPurpose(s)
+	default:
+		return fmt.Errorf("unsupported scan type for StepUpPurpose: %T", src)
+	}
+	return nil
+}
+
+type NullStepUpPurpose struct {
+	StepUpPurpose StepUpPurpose `json:"step_up_purpose"`
+	Valid         bool          `json:"valid"` // Valid is true if StepUpPurpose is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullStepUpPurpose) Scan(value interface{}) error {
+	if value == nil {
+		ns.StepUpPurpose, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.StepUpPurpose.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullStepUpPurpose) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.StepUpPurpose), nil
+}
+
+type VideoLicense string
+
+const (
+	VideoLicenseStandard VideoLicense = "standard"
+	VideoLicenseCcBy     VideoLicense = "cc-by"
+	VideoLicenseCcBySa   VideoLicense = "cc-by-sa"
+	VideoLicenseCcByNd   VideoLicense = "cc-by-nd"
+	VideoLicenseCcByNc   VideoLicense = "cc-by-nc"
+	VideoLicenseCcByNcSa VideoLicense = "cc-by-nc-sa"
+	VideoLicenseCcByNcNd VideoLicense = "cc-by-nc-nd"
+	VideoLicenseCc0      VideoLicense = "cc0"
+)
+
+func (e *VideoLicense) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = VideoLicense(s)
+	case string:
+		*e = VideoLicense(s)
+	default:
+		return fmt.Errorf("unsupported scan type for VideoLicense: %T", src)
+	}
+	return nil
+}
+
+type NullVideoLicense struct {
+	VideoLicense VideoLicense `json:"video_license"`
+	Valid        bool         `json:"valid"` // Valid is true if VideoLicense is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullVideoLicense) Scan(value interface{}) error {
+	if value == nil {
+		ns.VideoLicense, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.VideoLicense.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullVideoLicense) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.VideoLicense), nil
+}
+
 type VideoStatus string
 
 const (
 	VideoStatusPending   VideoStatus = "pending"
 	VideoStatusPublished VideoStatus = "published"
+	VideoStatusHeld      VideoStatus = "held"
 	VideoStatusDeleted   VideoStatus = "deleted"
+	VideoStatusFailed    VideoStatus = "failed"
 )
 
 func (e *VideoStatus) Scan(src interface{}) error {
@@ -100,16 +588,176 @@ func (ns NullVideoStatus) Value() (driver.Value, error) {
 	return string(ns.VideoStatus), nil
 }
 
+type VideoVisibility string
+
+const (
+	VideoVisibilityPublic   VideoVisibility = "public"
+	VideoVisibilityUnlisted VideoVisibility = "unlisted"
+	VideoVisibilityPrivate  VideoVisibility = "private"
+)
+
+func (e *VideoVisibility) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = VideoVisibility(s)
+	case string:
+		*e = VideoVisibility(s)
+	default:
+		return fmt.Errorf("unsupported scan type for VideoVisibility: %T", src)
+	}
+	return nil
+}
+
+type NullVideoVisibility struct {
+	VideoVisibility VideoVisibility `json:"video_visibility"`
+	Valid           bool            `json:"valid"` // Valid is true if VideoVisibility is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullVideoVisibility) Scan(value interface{}) error {
+	if value == nil {
+		ns.VideoVisibility, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.VideoVisibility.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullVideoVisibility) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.VideoVisibility), nil
+}
+
+type WatermarkPosition string
+
+const (
+	WatermarkPositionTopLeft     WatermarkPosition = "top-left"
+	WatermarkPositionTopRight    WatermarkPosition = "top-right"
+	WatermarkPositionBottomLeft  WatermarkPosition = "bottom-left"
+	WatermarkPositionBottomRight WatermarkPosition = "bottom-right"
+)
+
+func (e *WatermarkPosition) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WatermarkPosition(s)
+	case string:
+		*e = WatermarkPosition(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WatermarkPosition: %T", src)
+	}
+	return nil
+}
+
+type NullWatermarkPosition struct {
+	WatermarkPosition WatermarkPosition `json:"watermark_position"`
+	Valid             bool              `json:"valid"` // Valid is true if WatermarkPosition is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWatermarkPosition) Scan(value interface{}) error {
+	if value == nil {
+		ns.WatermarkPosition, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WatermarkPosition.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWatermarkPosition) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WatermarkPosition), nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveryStatus struct {
+	WebhookDeliveryStatus WebhookDeliveryStatus `json:"webhook_delivery_status"`
+	Valid                 bool                  `json:"valid"` // Valid is true if WebhookDeliveryStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveryStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveryStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveryStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveryStatus), nil
+}
+
 type Account struct {
-	AccountID       uuid.UUID      `json:"account_id"`
-	Email           string         `json:"email"`
-	Username        string         `json:"username"`
-	Password        sql.NullString `json:"password"`
-	Description     sql.NullString `json:"description"`
-	Status          AccountStatus  `json:"status"`
-	OauthProvider   sql.NullString `json:"oauth_provider"`
-	OauthProviderID sql.NullString `json:"oauth_provider_id"`
-	TokenVersion    int32          `json:"token_version"`
+	AccountID            uuid.UUID       `json:"account_id"`
+	Email                string          `json:"email"`
+	Username             string          `json:"username"`
+	Password             sql.NullString  `json:"password"`
+	Description          sql.NullString  `json:"description"`
+	Status               AccountStatus   `json:"status"`
+	OauthProvider        sql.NullString  `json:"oauth_provider"`
+	OauthProviderID      sql.NullString  `json:"oauth_provider_id"`
+	TokenVersion         int32           `json:"token_version"`
+	ProcessingWebhookUrl sql.NullString  `json:"processing_webhook_url"`
+	Role                 AccountRole     `json:"role"`
+	TenantID             string          `json:"tenant_id"`
+	Version              int32           `json:"version"`
+	DeletedAt            sql.NullTime    `json:"deleted_at"`
+	SearchVector         interface{}     `json:"search_vector"`
+	SubscriberCount      int64           `json:"subscriber_count"`
+	DigestFrequency      DigestFrequency `json:"digest_frequency"`
+	Timezone             string          `json:"timezone"`
+	DigestSentAt         sql.NullTime    `json:"digest_sent_at"`
+	PhoneNumber          sql.NullString  `json:"phone_number"`
+	PhoneVerifiedAt      sql.NullTime    `json:"phone_verified_at"`
+	SuspendedUntil       sql.NullTime    `json:"suspended_until"`
+	CreatedAt            time.Time       `json:"created_at"`
+}
+
+type BlockedWord struct {
+	BlockedWordID uuid.UUID       `json:"blocked_word_id"`
+	AccountID     uuid.NullUUID   `json:"account_id"`
+	Pattern       string          `json:"pattern"`
+	Mode          BlockedWordMode `json:"mode"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type EmailVerificationCode struct {
+	AccountID uuid.UUID `json:"account_id"`
+	CodeHash  string    `json:"code_hash"`
+	Attempts  int32     `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Favorite struct {
@@ -118,27 +766,186 @@ type Favorite struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type Job struct {
+	JobID       int64           `json:"job_id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      JobStatus       `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	LastError   sql.NullString  `json:"last_error"`
+	RunAt       time.Time       `json:"run_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
 type LikeVideo struct {
 	VideoID   uuid.UUID `json:"video_id"`
 	AccountID uuid.UUID `json:"account_id"`
 	LikeAt    time.Time `json:"like_at"`
 }
 
+type ModerationDecision struct {
+	DecisionID  uuid.UUID        `json:"decision_id"`
+	TargetType  ReportTarget     `json:"target_type"`
+	TargetID    uuid.UUID        `json:"target_id"`
+	Action      ModerationAction `json:"action"`
+	ModeratorID uuid.UUID        `json:"moderator_id"`
+	Note        sql.NullString   `json:"note"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+type Notification struct {
+	NotificationID uuid.UUID        `json:"notification_id"`
+	RecipientID    uuid.UUID        `json:"recipient_id"`
+	Type           NotificationType `json:"type"`
+	ActorID        uuid.NullUUID    `json:"actor_id"`
+	VideoID        uuid.NullUUID    `json:"video_id"`
+	CreatedAt      time.Time        `json:"created_at"`
+	ReadAt         sql.NullTime     `json:"read_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+type PlaybackEvent struct {
+	EventID    int64           `json:"event_id"`
+	VideoID    uuid.UUID       `json:"video_id"`
+	AccountID  uuid.NullUUID   `json:"account_id"`
+	PlaybackID uuid.UUID       `json:"playback_id"`
+	Type       string          `json:"type"`
+	Position   float64         `json:"position"`
+	SeekFrom   sql.NullFloat64 `json:"seek_from"`
+	Quality    sql.NullString  `json:"quality"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type PushSubscription struct {
+	PushSubscriptionID uuid.UUID `json:"push_subscription_id"`
+	AccountID          uuid.UUID `json:"account_id"`
+	Endpoint           string    `json:"endpoint"`
+	P256dh             string    `json:"p256dh"`
+	Auth               string    `json:"auth"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type Report struct {
+	ReportID   uuid.UUID      `json:"report_id"`
+	ReporterID uuid.UUID      `json:"reporter_id"`
+	TargetType ReportTarget   `json:"target_type"`
+	TargetID   uuid.UUID      `json:"target_id"`
+	Reason     ReportReason   `json:"reason"`
+	Details    sql.NullString `json:"details"`
+	DecisionID uuid.NullUUID  `json:"decision_id"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+type Session struct {
+	SessionID  uuid.UUID    `json:"session_id"`
+	AccountID  uuid.UUID    `json:"account_id"`
+	TokenHash  string       `json:"token_hash"`
+	UserAgent  string       `json:"user_agent"`
+	Ip         string       `json:"ip"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastUsedAt time.Time    `json:"last_used_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+}
+
+type StepUpChallenge struct {
+	ChallengeID uuid.UUID     `json:"challenge_id"`
+	AccountID   uuid.UUID     `json:"account_id"`
+	Purpose     StepUpPurpose `json:"purpose"`
+	PhoneNumber string        `json:"phone_number"`
+	CodeHash    string        `json:"code_hash"`
+	Attempts    int32         `json:"attempts"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	ConsumedAt  sql.NullTime  `json:"consumed_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+type StorageUsage struct {
+	Day        time.Time `json:"day"`
+	UsedBytes  int64     `json:"used_bytes"`
+	ColdBytes  int64     `json:"cold_bytes"`
+	Files      int64     `json:"files"`
+	MeasuredAt time.Time `json:"measured_at"`
+}
+
+type Strike struct {
+	StrikeID   uuid.UUID `json:"strike_id"`
+	AccountID  uuid.UUID `json:"account_id"`
+	DecisionID uuid.UUID `json:"decision_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
 type Subscribe struct {
 	SubscriberID  uuid.UUID `json:"subscriber_id"`
 	SubscribeToID uuid.UUID `json:"subscribe_to_id"`
 	SubscribeAt   time.Time `json:"subscribe_at"`
 }
 
+type Subtitle struct {
+	VideoID       uuid.UUID `json:"video_id"`
+	Language      string    `json:"language"`
+	AutoGenerated bool      `json:"auto_generated"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
 type Video struct {
-	VideoID     uuid.UUID      `json:"video_id"`
-	Title       string         `json:"title"`
-	Duration    int32          `json:"duration"`
-	Description sql.NullString `json:"description"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	PublisherID uuid.UUID      `json:"publisher_id"`
-	Status      VideoStatus    `json:"status"`
+	VideoID           uuid.UUID       `json:"video_id"`
+	Title             string          `json:"title"`
+	Duration          int32           `json:"duration"`
+	Description       sql.NullString  `json:"description"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	PublisherID       uuid.UUID       `json:"publisher_id"`
+	Status            VideoStatus     `json:"status"`
+	Visibility        VideoVisibility `json:"visibility"`
+	Category          sql.NullString  `json:"category"`
+	OriginalRemovedAt sql.NullTime    `json:"original_removed_at"`
+	License           VideoLicense    `json:"license"`
+	Attribution       sql.NullString  `json:"attribution"`
+	ColdAt            sql.NullTime    `json:"cold_at"`
+	Version           int32           `json:"version"`
+	DeletedAt         sql.NullTime    `json:"deleted_at"`
+	SearchVector      interface{}     `json:"search_vector"`
+	LikeCount         int64           `json:"like_count"`
+	ViewCount         int64           `json:"view_count"`
+	PrunedViewCount   int64           `json:"pruned_view_count"`
+	AgeRestricted     bool            `json:"age_restricted"`
+}
+
+type VideoDailyWatchTime struct {
+	VideoID   uuid.UUID `json:"video_id"`
+	Day       time.Time `json:"day"`
+	WatchTime float64   `json:"watch_time"`
+	Playbacks int64     `json:"playbacks"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type VideoRendition struct {
+	VideoID    uuid.UUID       `json:"video_id"`
+	Resolution string          `json:"resolution"`
+	Status     RenditionStatus `json:"status"`
+	Progress   int32           `json:"progress"`
+	Error      sql.NullString  `json:"error"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+type VideoTrending struct {
+	VideoID     uuid.UUID `json:"video_id"`
+	RecentViews int64     `json:"recent_views"`
+	Score       float64   `json:"score"`
+}
+
+type ViewEvent struct {
+	EventID       int64         `json:"event_id"`
+	VideoID       uuid.UUID     `json:"video_id"`
+	AccountID     uuid.NullUUID `json:"account_id"`
+	WatchDuration int32         `json:"watch_duration"`
+	Source        string        `json:"source"`
+	CreatedAt     time.Time     `json:"created_at"`
 }
 
 type WatchVideo struct {
@@ -146,3 +953,32 @@ type WatchVideo struct {
 	AccountID uuid.UUID `json:"account_id"`
 	WatchAt   time.Time `json:"watch_at"`
 }
+
+type Watermark struct {
+	AccountID uuid.UUID         `json:"account_id"`
+	Position  WatermarkPosition `json:"position"`
+	Opacity   float32           `json:"opacity"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type Webhook struct {
+	WebhookID uuid.UUID `json:"webhook_id"`
+	AccountID uuid.UUID `json:"account_id"`
+	Url       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	DeliveryID    uuid.UUID             `json:"delivery_id"`
+	WebhookID     uuid.UUID             `json:"webhook_id"`
+	Event         string                `json:"event"`
+	Payload       json.RawMessage       `json:"payload"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int32                 `json:"attempts"`
+	StatusCode    sql.NullInt32         `json:"status_code"`
+	Error         sql.NullString        `json:"error"`
+	CreatedAt     time.Time             `json:"created_at"`
+	LastAttemptAt sql.NullTime          `json:"last_attempt_at"`
+}
@@ -0,0 +1,156 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Delays of the retries: the delay doubles after each retry up to retryMaxDelay, half of it is random so the
+// clients of a contended row don't retry in lockstep
+const (
+	retryBaseDelay = 20 * time.Millisecond
+	retryMaxDelay  = time.Second
+)
+
+// RetryError is returned when a query or a transaction still fails with a transient error once its retries are
+// exhausted. It wraps the error of the last attempt
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (err *RetryError) Error() string {
+	return fmt.Sprintf("query failed after %d attempts: %v", err.Attempts, err.Err)
+}
+
+func (err *RetryError) Unwrap() error {
+	return err.Err
+}
+
+// Connection running the statements failing with a transient error again. It's only used outside the transactions:
+// a failed statement aborts its transaction, so the whole transaction is retried by ExecTx instead
+type retryingDB struct {
+	conn       DBTX
+	maxRetries int
+}
+
+// Helper function: wrap a connection so its statements are retried at most maxRetries times, the connection is
+// returned as is when maxRetries is 0
+func retrying(conn DBTX, maxRetries int) DBTX {
+	if maxRetries == 0 {
+		return conn
+	}
+	return &retryingDB{conn: conn, maxRetries: maxRetries}
+}
+
+func (db *retryingDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := retry(ctx, db.maxRetries, retryableStatement(query), func() (err error) {
+		result, err = db.conn.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+func (db *retryingDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.conn.PrepareContext(ctx, query)
+}
+
+func (db *retryingDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := retry(ctx, db.maxRetries, retryableStatement(query), func() (err error) {
+		rows, err = db.conn.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// The error of the last attempt is returned by Scan, a sql.Row can't carry a RetryError
+func (db *retryingDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	var row *sql.Row
+	retry(ctx, db.maxRetries, retryableStatement(query), func() error {
+		row = db.conn.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+// Helper function: run an attempt until it succeeds, fails with an error which can't be retried, or maxRetries
+// retries are exhausted
+func retry(ctx context.Context, maxRetries int, retryable func(error) bool, attempt func() error) error {
+	for i := 0; ; i++ {
+		err := attempt()
+		if err == nil || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if i == maxRetries {
+			if maxRetries == 0 {
+				return err
+			}
+			return &RetryError{Attempts: i + 1, Err: err}
+		}
+
+		delay := min(retryBaseDelay<<i, retryMaxDelay)
+		timer := time.NewTimer(delay/2 + rand.N(delay/2))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// Helper function: get which errors of a statement can be retried. A statement rolled back by a serialization
+// failure or a deadlock had no effect, so it can always be run again. A statement failing with a connection error
+// may or may not have been run, so it's only run again if it's a read
+func retryableStatement(query string) func(error) bool {
+	idempotent := isRead(query)
+	return func(err error) bool {
+		return isSerializationFailure(err) || (idempotent && isConnectionError(err))
+	}
+}
+
+// Helper function: check if an error is a serialization failure (40001) or a deadlock (40P01)
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
+// Helper function: check if an error is a transient connection error: the connection was reset or closed, or the
+// server is restarting
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	// Class 08 (connection exception), admin_shutdown, crash_shutdown and cannot_connect_now
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P02" ||
+			pgErr.Code == "57P03"
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// Helper function: check if a statement only reads, after its sqlc header ("-- name: GetVideo :one")
+func isRead(query string) bool {
+	if strings.HasPrefix(query, "-- name: ") {
+		_, query, _ = strings.Cut(query, "\n")
+	}
+	keyword, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return strings.EqualFold(keyword, "SELECT")
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetryableStatement(t *testing.T) {
+	const (
+		read  = "-- name: GetVideo :one\nSELECT video_id FROM video WHERE video_id = $1"
+		write = "-- name: DeleteVideo :execrows\nUPDATE video SET deleted_at = now() WHERE video_id = $1"
+	)
+
+	tests := []struct {
+		name      string
+		err       error
+		readRetry bool // retried when the statement is a read
+		retry     bool // retried when the statement is a write
+	}{
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, readRetry: true, retry: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, readRetry: true, retry: true},
+		{name: "wrapped serialization failure", err: fmt.Errorf("tx: %w", &pgconn.PgError{Code: "40001"}),
+			readRetry: true, retry: true},
+		{name: "bad connection", err: driver.ErrBadConn, readRetry: true},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, readRetry: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), readRetry: true},
+		{name: "network error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("refused")}, readRetry: true},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, readRetry: true},
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}, readRetry: true},
+		{name: "cannot connect now", err: &pgconn.PgError{Code: "57P03"}, readRetry: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}},
+		{name: "query canceled", err: &pgconn.PgError{Code: "57014"}},
+		{name: "no rows", err: sql.ErrNoRows},
+		{name: "other error", err: errors.New("boom")},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := retryableStatement(read)(test.err); got != test.readRetry {
+				t.Errorf("read retryable(%v) = %v, want %v", test.err, got, test.readRetry)
+			}
+			if got := retryableStatement(write)(test.err); got != test.retry {
+				t.Errorf("write retryable(%v) = %v, want %v", test.err, got, test.retry)
+			}
+		})
+	}
+}
+
+func TestIsRead(t *testing.T) {
+	tests := []struct {
+		query string
+		read  bool
+	}{
+		{query: "-- name: GetVideo :one\nSELECT * FROM video", read: true},
+		{query: "-- name: ListVideos :many\n  select * FROM video", read: true},
+		{query: "SELECT 1", read: true},
+		{query: "-- name: CreateVideo :one\nINSERT INTO video DEFAULT VALUES RETURNING *"},
+		{query: "-- name: PublishVideo :one\nUPDATE video SET status = 'published' RETURNING *"},
+		{query: "-- name: DeleteVideo :execrows\nDELETE FROM video"},
+		{query: "-- name: ClaimJob :one\nWITH next AS (SELECT 1) UPDATE job SET status = 'running'"},
+		{query: "-- name: Broken :one"},
+	}
+
+	for _, test := range tests {
+		if got := isRead(test.query); got != test.read {
+			t.Errorf("isRead(%q) = %v, want %v", test.query, got, test.read)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	transient := &pgconn.PgError{Code: "40001"}
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		failures   int   // attempts failing with err before one succeeds
+		err        error // error of the failing attempts
+		attempts   int
+		wantErr    bool
+		retryErr   bool // the error is a RetryError
+	}{
+		{name: "success", maxRetries: 3, attempts: 1},
+		{name: "transient then success", maxRetries: 3, failures: 2, err: transient, attempts: 3},
+		{name: "retries exhausted", maxRetries: 2, failures: 10, err: transient, attempts: 3, wantErr: true,
+			retryErr: true},
+		{name: "not retryable", maxRetries: 3, failures: 10, err: sql.ErrNoRows, attempts: 1, wantErr: true},
+		{name: "no retry", maxRetries: 0, failures: 10, err: transient, attempts: 1, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			err := retry(context.Background(), test.maxRetries, isSerializationFailure, func() error {
+				attempts++
+				if attempts <= test.failures {
+					return test.err
+				}
+				return nil
+			})
+
+			if attempts != test.attempts {
+				t.Errorf("attempts = %d, want %d", attempts, test.attempts)
+			}
+			if (err != nil) != test.wantErr {
+				t.Fatalf("err = %v, want error: %v", err, test.wantErr)
+			}
+			var retryErr *RetryError
+			if errors.As(err, &retryErr) != test.retryErr {
+				t.Fatalf("err = %v, want RetryError: %v", err, test.retryErr)
+			}
+			if test.retryErr && (retryErr.Attempts != test.attempts || !errors.Is(err, test.err)) {
+				t.Errorf("RetryError = %+v, want %d attempts wrapping %v", retryErr, test.attempts, test.err)
+			}
+		})
+	}
+}
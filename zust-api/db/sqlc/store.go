@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+// Store provides all functions to execute queries and transactions
+type Store struct {
+	*Queries
+	db         *sql.DB
+	metrics    *QueryMetrics // nil if the queries are not instrumented
+	maxRetries int           // retries of the queries and transactions failing with a transient error
+}
+
+// Constructor method for store. The duration of the queries is recorded into 'metrics', unless it's nil, and the
+// queries failing with a transient error are retried at most maxRetries times
+func NewStore(db *sql.DB, metrics *QueryMetrics, maxRetries int) *Store {
+	return &Store{
+		Queries:    New(retrying(metrics.instrument(db), maxRetries)),
+		db:         db,
+		metrics:    metrics,
+		maxRetries: maxRetries,
+	}
+}
+
+// Method to execute a function within a database transaction.
+// The transaction is committed if fn returns nil, otherwise it's rolled back. A transaction rolled back by a
+// serialization failure or a deadlock is run again from the start, so fn must not keep state from a previous run
+func (store *Store) ExecTx(ctx context.Context, fn func(*Queries) error) error {
+	return retry(ctx, store.maxRetries, isSerializationFailure, func() error {
+		return store.execTx(ctx, fn)
+	})
+}
+
+// Helper method: run a function within a database transaction once
+func (store *Store) execTx(ctx context.Context, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(New(store.metrics.instrument(tx))); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx error: %w, rollback error: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Method to check that the database can be reached
+func (store *Store) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
+}
+
+// Method to write the latency metrics of the queries in the Prometheus text format, nothing if they are not recorded
+func (store *Store) WriteMetrics(w io.Writer) {
+	store.metrics.WritePrometheus(w)
+}
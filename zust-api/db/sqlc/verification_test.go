@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+	"zust/db/migration"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Helper function: open the test database given by TEST_DB_SOURCE and apply the migrations, the test is skipped
+// if it's not set. The queries of the test run in a transaction which is rolled back at the end
+func testQueries(t *testing.T) *Queries {
+	source := os.Getenv("TEST_DB_SOURCE")
+	if source == "" {
+		t.Skip("TEST_DB_SOURCE is not set")
+	}
+
+	conn, err := sql.Open("pgx", source)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := migration.Up(context.Background(), conn); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return New(tx)
+}
+
+func TestEmailVerificationAttempts(t *testing.T) {
+	const maxAttempts = 5
+	q := testQueries(t)
+	ctx := context.Background()
+
+	suffix := uuid.NewString()[:8]
+	account, err := q.CreateAccountWithPassword(ctx, CreateAccountWithPasswordParams{
+		TenantID: "default",
+		Email:    "verify-" + suffix + "@example.com",
+		Username: "verify_" + suffix,
+		Password: sql.NullString{String: "hash", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+
+	setCode := func() {
+		err := q.SetEmailVerificationCode(ctx, SetEmailVerificationCodeParams{
+			AccountID: account.AccountID,
+			CodeHash:  "hash-" + uuid.NewString(),
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to set verification code: %v", err)
+		}
+	}
+	try := func() int64 {
+		tried, err := q.IncrementEmailVerificationAttempts(ctx, IncrementEmailVerificationAttemptsParams{
+			AccountID:   account.AccountID,
+			MaxAttempts: maxAttempts,
+		})
+		if err != nil {
+			t.Fatalf("failed to count attempt: %v", err)
+		}
+		return tried
+	}
+
+	// Each try counts until the limit, the next ones are refused
+	setCode()
+	for i := 1; i <= maxAttempts+2; i++ {
+		want := int64(1)
+		if i > maxAttempts {
+			want = 0
+		}
+		if tried := try(); tried != want {
+			t.Fatalf("attempt %d: counted %d, want %d", i, tried, want)
+		}
+	}
+
+	verification, err := q.GetEmailVerificationCode(ctx, account.AccountID)
+	if err != nil {
+		t.Fatalf("failed to get verification code: %v", err)
+	}
+	if verification.Attempts != maxAttempts {
+		t.Errorf("attempts = %d, want %d", verification.Attempts, maxAttempts)
+	}
+
+	// A new code starts over
+	setCode()
+	if tried := try(); tried != 1 {
+		t.Errorf("attempt after a new code: counted %d, want 1", tried)
+	}
+
+	// The code is gone once used
+	if err := q.DeleteEmailVerificationCode(ctx, account.AccountID); err != nil {
+		t.Fatalf("failed to delete verification code: %v", err)
+	}
+	if tried := try(); tried != 0 {
+		t.Errorf("attempt after deletion: counted %d, want 0", tried)
+	}
+}
@@ -14,19 +14,27 @@ import (
 )
 
 const createVideo = `-- name: CreateVideo :one
-INSERT INTO video (title, description, publisher_id)
-VALUES ($1, $2, $3)
-RETURNING video_id, title, duration, description, created_at, updated_at, publisher_id, status
+INSERT INTO video (title, description, publisher_id, license, attribution)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING video_id, title, duration, description, created_at, updated_at, publisher_id, status, visibility, category, original_removed_at, license, attribution, cold_at, version, deleted_at, search_vector, like_count, view_count, pruned_view_count, age_restricted
 `
 
 type CreateVideoParams struct {
 	Title       string         `json:"title"`
 	Description sql.NullString `json:"description"`
 	PublisherID uuid.UUID      `json:"publisher_id"`
+	License     VideoLicense   `json:"license"`
+	Attribution sql.NullString `json:"attribution"`
 }
 
 func (q *Queries) CreateVideo(ctx context.Context, arg CreateVideoParams) (Video, error) {
-	row := q.db.QueryRowContext(ctx, createVideo, arg.Title, arg.Description, arg.PublisherID)
+	row := q.db.QueryRowContext(ctx, createVideo,
+		arg.Title,
+		arg.Description,
+		arg.PublisherID,
+		arg.License,
+		arg.Attribution,
+	)
 	var i Video
 	err := row.Scan(
 		&i.VideoID,
@@ -37,34 +45,113 @@ func (q *Queries) CreateVideo(ctx context.Context, arg CreateVideoParams) (Video
 		&i.UpdatedAt,
 		&i.PublisherID,
 		&i.Status,
+		&i.Visibility,
+		&i.Category,
+		&i.OriginalRemovedAt,
+		&i.License,
+		&i.Attribution,
+		&i.ColdAt,
+		&i.Version,
+		&i.DeletedAt,
+		&i.SearchVector,
+		&i.LikeCount,
+		&i.ViewCount,
+		&i.PrunedViewCount,
+		&i.AgeRestricted,
 	)
 	return i, err
 }
 
+const deleteVideo = `-- name: DeleteVideo :execrows
+UPDATE video
+SET deleted_at = now(), updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND deleted_at IS NULL
+`
+
+type DeleteVideoParams struct {
+	VideoID     uuid.UUID `json:"video_id"`
+	PublisherID uuid.UUID `json:"publisher_id"`
+}
+
+func (q *Queries) DeleteVideo(ctx context.Context, arg DeleteVideoParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteVideo, arg.VideoID, arg.PublisherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const editVideo = `-- name: EditVideo :one
+UPDATE video
+SET title = $4, description = $5, version = version + 1, updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND version = $3 AND deleted_at IS NULL
+RETURNING version
+`
+
+type EditVideoParams struct {
+	VideoID     uuid.UUID      `json:"video_id"`
+	PublisherID uuid.UUID      `json:"publisher_id"`
+	Version     int32          `json:"version"`
+	Title       string         `json:"title"`
+	Description sql.NullString `json:"description"`
+}
+
+func (q *Queries) EditVideo(ctx context.Context, arg EditVideoParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, editVideo,
+		arg.VideoID,
+		arg.PublisherID,
+		arg.Version,
+		arg.Title,
+		arg.Description,
+	)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const failVideo = `-- name: FailVideo :exec
+UPDATE video
+SET status = 'failed', updated_at = now()
+WHERE video_id = $1 AND status = 'pending'
+`
+
+func (q *Queries) FailVideo(ctx context.Context, videoID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, failVideo, videoID)
+	return err
+}
+
 const getVideo = `-- name: GetVideo :one
 SELECT 
-    v.video_id, v.title, v.duration, v.description, v.created_at, v.status,
-    a.account_id, a.username,
-    (SELECT COUNT(*) FROM subscribe s WHERE s.subscribe_to_id = v.publisher_id) AS total_subscriber,
-    (SELECT COUNT(*) FROM watch_video wv WHERE wv.video_id = v.video_id) AS total_view,
-    (SELECT COUNT(*) FROM like_video lv WHERE lv.video_id = v.video_id) AS total_like
+    v.video_id, v.title, v.duration, v.description, v.created_at, v.status, v.visibility, v.category,
+    v.original_removed_at, v.license, v.attribution, v.cold_at, v.version, v.age_restricted, a.account_id, a.username,
+    a.subscriber_count AS total_subscriber,
+    v.view_count AS total_view,
+    v.like_count AS total_like
 FROM video v 
 JOIN account a ON a.account_id = v.publisher_id
-WHERE v.video_id = $1
+WHERE v.video_id = $1 AND v.deleted_at IS NULL AND a.deleted_at IS NULL
 `
 
 type GetVideoRow struct {
-	VideoID         uuid.UUID      `json:"video_id"`
-	Title           string         `json:"title"`
-	Duration        int32          `json:"duration"`
-	Description     sql.NullString `json:"description"`
-	CreatedAt       time.Time      `json:"created_at"`
-	Status          VideoStatus    `json:"status"`
-	AccountID       uuid.UUID      `json:"account_id"`
-	Username        string         `json:"username"`
-	TotalSubscriber int64          `json:"total_subscriber"`
-	TotalView       int64          `json:"total_view"`
-	TotalLike       int64          `json:"total_like"`
+	VideoID           uuid.UUID       `json:"video_id"`
+	Title             string          `json:"title"`
+	Duration          int32           `json:"duration"`
+	Description       sql.NullString  `json:"description"`
+	CreatedAt         time.Time       `json:"created_at"`
+	Status            VideoStatus     `json:"status"`
+	Visibility        VideoVisibility `json:"visibility"`
+	Category          sql.NullString  `json:"category"`
+	OriginalRemovedAt sql.NullTime    `json:"original_removed_at"`
+	License           VideoLicense    `json:"license"`
+	Attribution       sql.NullString  `json:"attribution"`
+	ColdAt            sql.NullTime    `json:"cold_at"`
+	Version           int32           `json:"version"`
+	AgeRestricted     bool            `json:"age_restricted"`
+	AccountID         uuid.UUID       `json:"account_id"`
+	Username          string          `json:"username"`
+	TotalSubscriber   int64           `json:"total_subscriber"`
+	TotalView         int64           `json:"total_view"`
+	TotalLike         int64           `json:"total_like"`
 }
 
 func (q *Queries) GetVideo(ctx context.Context, videoID uuid.UUID) (GetVideoRow, error) {
@@ -77,6 +164,14 @@ func (q *Queries) GetVideo(ctx context.Context, videoID uuid.UUID) (GetVideoRow,
 		&i.Description,
 		&i.CreatedAt,
 		&i.Status,
+		&i.Visibility,
+		&i.Category,
+		&i.OriginalRemovedAt,
+		&i.License,
+		&i.Attribution,
+		&i.ColdAt,
+		&i.Version,
+		&i.AgeRestricted,
 		&i.AccountID,
 		&i.Username,
 		&i.TotalSubscriber,
@@ -86,11 +181,518 @@ func (q *Queries) GetVideo(ctx context.Context, videoID uuid.UUID) (GetVideoRow,
 	return i, err
 }
 
-const publishVideo = `-- name: PublishVideo :one
+const getVideoAccess = `-- name: GetVideoAccess :one
+SELECT v.publisher_id, v.status, v.visibility, v.cold_at, v.age_restricted FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.video_id = $1 AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+`
+
+type GetVideoAccessRow struct {
+	PublisherID   uuid.UUID       `json:"publisher_id"`
+	Status        VideoStatus     `json:"status"`
+	Visibility    VideoVisibility `json:"visibility"`
+	ColdAt        sql.NullTime    `json:"cold_at"`
+	AgeRestricted bool            `json:"age_restricted"`
+}
+
+func (q *Queries) GetVideoAccess(ctx context.Context, videoID uuid.UUID) (GetVideoAccessRow, error) {
+	row := q.db.QueryRowContext(ctx, getVideoAccess, videoID)
+	var i GetVideoAccessRow
+	err := row.Scan(
+		&i.PublisherID,
+		&i.Status,
+		&i.Visibility,
+		&i.ColdAt,
+		&i.AgeRestricted,
+	)
+	return i, err
+}
+
+const getVideoPublisher = `-- name: GetVideoPublisher :one
+SELECT publisher_id, title FROM video
+WHERE video_id = $1
+`
+
+type GetVideoPublisherRow struct {
+	PublisherID uuid.UUID `json:"publisher_id"`
+	Title       string    `json:"title"`
+}
+
+func (q *Queries) GetVideoPublisher(ctx context.Context, videoID uuid.UUID) (GetVideoPublisherRow, error) {
+	row := q.db.QueryRowContext(ctx, getVideoPublisher, videoID)
+	var i GetVideoPublisherRow
+	err := row.Scan(&i.PublisherID, &i.Title)
+	return i, err
+}
+
+const holdVideo = `-- name: HoldVideo :exec
 UPDATE video
-SET status = 'published'
+SET status = 'held', updated_at = now()
 WHERE video_id = $1
-RETURNING video_id, title, duration, description, created_at, updated_at, publisher_id, status
+`
+
+func (q *Queries) HoldVideo(ctx context.Context, videoID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, holdVideo, videoID)
+	return err
+}
+
+const listChannelVideos = `-- name: ListChannelVideos :many
+SELECT v.video_id, v.title, v.duration, v.description, v.created_at, v.license, a.account_id, a.username,
+    v.view_count AS total_view,
+    v.like_count AS total_like
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND v.publisher_id = $1
+    AND ($2::timestamptz IS NULL
+        OR (v.created_at, v.video_id) < ($2::timestamptz, $3::uuid))
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT $5 OFFSET $4
+`
+
+type ListChannelVideosParams struct {
+	PublisherID    uuid.UUID     `json:"publisher_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageOffset     int32         `json:"page_offset"`
+	PageSize       int32         `json:"page_size"`
+}
+
+type ListChannelVideosRow struct {
+	VideoID     uuid.UUID      `json:"video_id"`
+	Title       string         `json:"title"`
+	Duration    int32          `json:"duration"`
+	Description sql.NullString `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	License     VideoLicense   `json:"license"`
+	AccountID   uuid.UUID      `json:"account_id"`
+	Username    string         `json:"username"`
+	TotalView   int64          `json:"total_view"`
+	TotalLike   int64          `json:"total_like"`
+}
+
+func (q *Queries) ListChannelVideos(ctx context.Context, arg ListChannelVideosParams) ([]ListChannelVideosRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChannelVideos,
+		arg.PublisherID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListChannelVideosRow{}
+	for rows.Next() {
+		var i ListChannelVideosRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Duration,
+			&i.Description,
+			&i.CreatedAt,
+			&i.License,
+			&i.AccountID,
+			&i.Username,
+			&i.TotalView,
+			&i.TotalLike,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeletedVideos = `-- name: ListDeletedVideos :many
+SELECT v.video_id, v.title, v.status, v.deleted_at, a.account_id, a.username FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.deleted_at IS NOT NULL
+    AND ($1::timestamptz IS NULL
+        OR (v.deleted_at, v.video_id) < ($1::timestamptz, $2::uuid))
+ORDER BY v.deleted_at DESC, v.video_id DESC
+LIMIT $3
+`
+
+type ListDeletedVideosParams struct {
+	AfterDeletedAt sql.NullTime  `json:"after_deleted_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageSize       int32         `json:"page_size"`
+}
+
+type ListDeletedVideosRow struct {
+	VideoID   uuid.UUID    `json:"video_id"`
+	Title     string       `json:"title"`
+	Status    VideoStatus  `json:"status"`
+	DeletedAt sql.NullTime `json:"deleted_at"`
+	AccountID uuid.UUID    `json:"account_id"`
+	Username  string       `json:"username"`
+}
+
+func (q *Queries) ListDeletedVideos(ctx context.Context, arg ListDeletedVideosParams) ([]ListDeletedVideosRow, error) {
+	rows, err := q.db.QueryContext(ctx, listDeletedVideos, arg.AfterDeletedAt, arg.AfterID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListDeletedVideosRow{}
+	for rows.Next() {
+		var i ListDeletedVideosRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Status,
+			&i.DeletedAt,
+			&i.AccountID,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeedVideos = `-- name: ListFeedVideos :many
+SELECT v.video_id, v.title, v.duration, v.description, v.created_at, v.license, a.account_id, a.username,
+    v.view_count AS total_view,
+    v.like_count AS total_like
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND a.tenant_id = $1
+    AND ($2::timestamptz IS NULL
+        OR (v.created_at, v.video_id) < ($2::timestamptz, $3::uuid))
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT $5 OFFSET $4
+`
+
+type ListFeedVideosParams struct {
+	TenantID       string        `json:"tenant_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageOffset     int32         `json:"page_offset"`
+	PageSize       int32         `json:"page_size"`
+}
+
+type ListFeedVideosRow struct {
+	VideoID     uuid.UUID      `json:"video_id"`
+	Title       string         `json:"title"`
+	Duration    int32          `json:"duration"`
+	Description sql.NullString `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	License     VideoLicense   `json:"license"`
+	AccountID   uuid.UUID      `json:"account_id"`
+	Username    string         `json:"username"`
+	TotalView   int64          `json:"total_view"`
+	TotalLike   int64          `json:"total_like"`
+}
+
+func (q *Queries) ListFeedVideos(ctx context.Context, arg ListFeedVideosParams) ([]ListFeedVideosRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedVideos,
+		arg.TenantID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListFeedVideosRow{}
+	for rows.Next() {
+		var i ListFeedVideosRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Duration,
+			&i.Description,
+			&i.CreatedAt,
+			&i.License,
+			&i.AccountID,
+			&i.Username,
+			&i.TotalView,
+			&i.TotalLike,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPublisherVideoIDs = `-- name: ListPublisherVideoIDs :many
+SELECT video_id FROM video
+WHERE publisher_id = $1
+`
+
+func (q *Queries) ListPublisherVideoIDs(ctx context.Context, publisherID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, listPublisherVideoIDs, publisherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var video_id uuid.UUID
+		if err := rows.Scan(&video_id); err != nil {
+			return nil, err
+		}
+		items = append(items, video_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRetainedOriginals = `-- name: ListRetainedOriginals :many
+SELECT v.video_id, v.publisher_id FROM video v
+WHERE v.status = 'published' AND v.original_removed_at IS NULL
+    AND EXISTS (SELECT 1 FROM video_rendition r WHERE r.video_id = v.video_id)
+    AND NOT EXISTS (SELECT 1 FROM video_rendition r WHERE r.video_id = v.video_id AND r.status <> 'completed')
+ORDER BY v.publisher_id, v.created_at DESC
+`
+
+type ListRetainedOriginalsRow struct {
+	VideoID     uuid.UUID `json:"video_id"`
+	PublisherID uuid.UUID `json:"publisher_id"`
+}
+
+func (q *Queries) ListRetainedOriginals(ctx context.Context) ([]ListRetainedOriginalsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listRetainedOriginals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListRetainedOriginalsRow{}
+	for rows.Next() {
+		var i ListRetainedOriginalsRow
+		if err := rows.Scan(&i.VideoID, &i.PublisherID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listStaleVideos = `-- name: ListStaleVideos :many
+SELECT v.video_id, v.publisher_id FROM video v
+WHERE v.status = 'published' AND v.cold_at IS NULL AND v.created_at < $1
+    AND NOT EXISTS (SELECT 1 FROM view_event e WHERE e.video_id = v.video_id AND e.created_at >= $1)
+`
+
+type ListStaleVideosRow struct {
+	VideoID     uuid.UUID `json:"video_id"`
+	PublisherID uuid.UUID `json:"publisher_id"`
+}
+
+func (q *Queries) ListStaleVideos(ctx context.Context, createdAt time.Time) ([]ListStaleVideosRow, error) {
+	rows, err := q.db.QueryContext(ctx, listStaleVideos, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListStaleVideosRow{}
+	for rows.Next() {
+		var i ListStaleVideosRow
+		if err := rows.Scan(&i.VideoID, &i.PublisherID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSubscriptionVideos = `-- name: ListSubscriptionVideos :many
+SELECT v.video_id, v.title, v.duration, v.description, v.created_at, v.license, a.account_id, a.username,
+    v.view_count AS total_view,
+    v.like_count AS total_like
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND v.publisher_id IN (SELECT subscribe_to_id FROM subscribe WHERE subscriber_id = $1)
+    AND ($2::timestamptz IS NULL
+        OR (v.created_at, v.video_id) < ($2::timestamptz, $3::uuid))
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT $5 OFFSET $4
+`
+
+type ListSubscriptionVideosParams struct {
+	SubscriberID   uuid.UUID     `json:"subscriber_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageOffset     int32         `json:"page_offset"`
+	PageSize       int32         `json:"page_size"`
+}
+
+type ListSubscriptionVideosRow struct {
+	VideoID     uuid.UUID      `json:"video_id"`
+	Title       string         `json:"title"`
+	Duration    int32          `json:"duration"`
+	Description sql.NullString `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	License     VideoLicense   `json:"license"`
+	AccountID   uuid.UUID      `json:"account_id"`
+	Username    string         `json:"username"`
+	TotalView   int64          `json:"total_view"`
+	TotalLike   int64          `json:"total_like"`
+}
+
+func (q *Queries) ListSubscriptionVideos(ctx context.Context, arg ListSubscriptionVideosParams) ([]ListSubscriptionVideosRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSubscriptionVideos,
+		arg.SubscriberID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSubscriptionVideosRow{}
+	for rows.Next() {
+		var i ListSubscriptionVideosRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Duration,
+			&i.Description,
+			&i.CreatedAt,
+			&i.License,
+			&i.AccountID,
+			&i.Username,
+			&i.TotalView,
+			&i.TotalLike,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVideoFiles = `-- name: ListVideoFiles :many
+SELECT video_id, publisher_id, status, original_removed_at, cold_at FROM video
+`
+
+type ListVideoFilesRow struct {
+	VideoID           uuid.UUID    `json:"video_id"`
+	PublisherID       uuid.UUID    `json:"publisher_id"`
+	Status            VideoStatus  `json:"status"`
+	OriginalRemovedAt sql.NullTime `json:"original_removed_at"`
+	ColdAt            sql.NullTime `json:"cold_at"`
+}
+
+func (q *Queries) ListVideoFiles(ctx context.Context) ([]ListVideoFilesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listVideoFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListVideoFilesRow{}
+	for rows.Next() {
+		var i ListVideoFilesRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.PublisherID,
+			&i.Status,
+			&i.OriginalRemovedAt,
+			&i.ColdAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOriginalRemoved = `-- name: MarkOriginalRemoved :exec
+UPDATE video
+SET original_removed_at = now()
+WHERE video_id = $1
+`
+
+func (q *Queries) MarkOriginalRemoved(ctx context.Context, videoID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markOriginalRemoved, videoID)
+	return err
+}
+
+const markVideoCold = `-- name: MarkVideoCold :exec
+UPDATE video
+SET cold_at = now()
+WHERE video_id = $1
+`
+
+func (q *Queries) MarkVideoCold(ctx context.Context, videoID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markVideoCold, videoID)
+	return err
+}
+
+const markVideoWarm = `-- name: MarkVideoWarm :exec
+UPDATE video
+SET cold_at = NULL
+WHERE video_id = $1
+`
+
+func (q *Queries) MarkVideoWarm(ctx context.Context, videoID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markVideoWarm, videoID)
+	return err
+}
+
+const publishVideo = `-- name: PublishVideo :one
+UPDATE video
+SET status = 'published', updated_at = now()
+WHERE video_id = $1 AND status = 'pending'
+RETURNING video_id, title, duration, description, created_at, updated_at, publisher_id, status, visibility, category, original_removed_at, license, attribution, cold_at, version, deleted_at, search_vector, like_count, view_count, pruned_view_count, age_restricted
 `
 
 func (q *Queries) PublishVideo(ctx context.Context, videoID uuid.UUID) (Video, error) {
@@ -105,10 +707,299 @@ func (q *Queries) PublishVideo(ctx context.Context, videoID uuid.UUID) (Video, e
 		&i.UpdatedAt,
 		&i.PublisherID,
 		&i.Status,
+		&i.Visibility,
+		&i.Category,
+		&i.OriginalRemovedAt,
+		&i.License,
+		&i.Attribution,
+		&i.ColdAt,
+		&i.Version,
+		&i.DeletedAt,
+		&i.SearchVector,
+		&i.LikeCount,
+		&i.ViewCount,
+		&i.PrunedViewCount,
+		&i.AgeRestricted,
 	)
 	return i, err
 }
 
+const reconcileVideoCounters = `-- name: ReconcileVideoCounters :many
+UPDATE video v
+SET like_count = c.likes, view_count = c.views
+FROM (
+    SELECT x.video_id, x.like_count AS old_likes, x.view_count AS old_views,
+        (SELECT COUNT(*) FROM like_video lv WHERE lv.video_id = x.video_id) AS likes,
+        x.pruned_view_count + (SELECT COUNT(*) FROM view_event ve WHERE ve.video_id = x.video_id) AS views
+    FROM video x
+) c
+WHERE v.video_id = c.video_id AND (v.like_count <> c.likes OR v.view_count <> c.views)
+RETURNING v.video_id, c.old_likes, c.likes, c.old_views, c.views
+`
+
+type ReconcileVideoCountersRow struct {
+	VideoID  uuid.UUID `json:"video_id"`
+	OldLikes int64     `json:"old_likes"`
+	Likes    int64     `json:"likes"`
+	OldViews int64     `json:"old_views"`
+	Views    int32     `json:"views"`
+}
+
+func (q *Queries) ReconcileVideoCounters(ctx context.Context) ([]ReconcileVideoCountersRow, error) {
+	rows, err := q.db.QueryContext(ctx, reconcileVideoCounters)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconcileVideoCountersRow{}
+	for rows.Next() {
+		var i ReconcileVideoCountersRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.OldLikes,
+			&i.Likes,
+			&i.OldViews,
+			&i.Views,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeVideo = `-- name: RemoveVideo :execrows
+UPDATE video
+SET deleted_at = now(), updated_at = now()
+WHERE video_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) RemoveVideo(ctx context.Context, videoID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeVideo, videoID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resetFailedVideo = `-- name: ResetFailedVideo :exec
+UPDATE video
+SET status = 'pending', updated_at = now()
+WHERE video_id = $1 AND status = 'failed'
+`
+
+func (q *Queries) ResetFailedVideo(ctx context.Context, videoID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, resetFailedVideo, videoID)
+	return err
+}
+
+const restoreVideo = `-- name: RestoreVideo :execrows
+UPDATE video
+SET deleted_at = NULL, updated_at = now()
+WHERE video_id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) RestoreVideo(ctx context.Context, videoID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, restoreVideo, videoID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchVideos = `-- name: SearchVideos :many
+SELECT v.video_id, v.title, v.duration, v.created_at, v.license, v.attribution, a.account_id, a.username
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND ($1::text = '' OR v.search_vector @@ to_tsquery('simple', $1::text))
+    AND ($2::text = '' OR v.license::text = $2::text)
+    AND (NOT $3::boolean OR v.license <> 'standard')
+    AND a.tenant_id = $4
+    AND ($5::timestamptz IS NULL
+        OR (v.created_at, v.video_id) < ($5::timestamptz, $6::uuid))
+ORDER BY v.created_at DESC, v.video_id DESC
+LIMIT $8 OFFSET $7
+`
+
+type SearchVideosParams struct {
+	Query          string        `json:"query"`
+	License        string        `json:"license"`
+	Reusable       bool          `json:"reusable"`
+	TenantID       string        `json:"tenant_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	AfterID        uuid.NullUUID `json:"after_id"`
+	PageOffset     int32         `json:"page_offset"`
+	PageSize       int32         `json:"page_size"`
+}
+
+type SearchVideosRow struct {
+	VideoID     uuid.UUID      `json:"video_id"`
+	Title       string         `json:"title"`
+	Duration    int32          `json:"duration"`
+	CreatedAt   time.Time      `json:"created_at"`
+	License     VideoLicense   `json:"license"`
+	Attribution sql.NullString `json:"attribution"`
+	AccountID   uuid.UUID      `json:"account_id"`
+	Username    string         `json:"username"`
+}
+
+func (q *Queries) SearchVideos(ctx context.Context, arg SearchVideosParams) ([]SearchVideosRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchVideos,
+		arg.Query,
+		arg.License,
+		arg.Reusable,
+		arg.TenantID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchVideosRow{}
+	for rows.Next() {
+		var i SearchVideosRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Duration,
+			&i.CreatedAt,
+			&i.License,
+			&i.Attribution,
+			&i.AccountID,
+			&i.Username,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchVideosRanked = `-- name: SearchVideosRanked :many
+SELECT v.video_id, v.title, v.duration, v.created_at, v.license, v.attribution, a.account_id, a.username,
+    ts_rank(v.search_vector, to_tsquery('simple', $1::text)) AS rank
+FROM video v
+JOIN account a ON a.account_id = v.publisher_id
+WHERE v.status = 'published' AND v.visibility = 'public' AND v.deleted_at IS NULL AND a.deleted_at IS NULL
+    AND v.search_vector @@ to_tsquery('simple', $1::text)
+    AND ($2::text = '' OR v.license::text = $2::text)
+    AND (NOT $3::boolean OR v.license <> 'standard')
+    AND a.tenant_id = $4
+ORDER BY rank DESC, v.created_at DESC, v.video_id DESC
+LIMIT $6 OFFSET $5
+`
+
+type SearchVideosRankedParams struct {
+	Query      string `json:"query"`
+	License    string `json:"license"`
+	Reusable   bool   `json:"reusable"`
+	TenantID   string `json:"tenant_id"`
+	PageOffset int32  `json:"page_offset"`
+	PageSize   int32  `json:"page_size"`
+}
+
+type SearchVideosRankedRow struct {
+	VideoID     uuid.UUID      `json:"video_id"`
+	Title       string         `json:"title"`
+	Duration    int32          `json:"duration"`
+	CreatedAt   time.Time      `json:"created_at"`
+	License     VideoLicense   `json:"license"`
+	Attribution sql.NullString `json:"attribution"`
+	AccountID   uuid.UUID      `json:"account_id"`
+	Username    string         `json:"username"`
+	Rank        float32        `json:"rank"`
+}
+
+func (q *Queries) SearchVideosRanked(ctx context.Context, arg SearchVideosRankedParams) ([]SearchVideosRankedRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchVideosRanked,
+		arg.Query,
+		arg.License,
+		arg.Reusable,
+		arg.TenantID,
+		arg.PageOffset,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchVideosRankedRow{}
+	for rows.Next() {
+		var i SearchVideosRankedRow
+		if err := rows.Scan(
+			&i.VideoID,
+			&i.Title,
+			&i.Duration,
+			&i.CreatedAt,
+			&i.License,
+			&i.Attribution,
+			&i.AccountID,
+			&i.Username,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setVideoAgeRestricted = `-- name: SetVideoAgeRestricted :execrows
+UPDATE video
+SET age_restricted = true, updated_at = now()
+WHERE video_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) SetVideoAgeRestricted(ctx context.Context, videoID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setVideoAgeRestricted, videoID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateVideoCategory = `-- name: UpdateVideoCategory :execrows
+UPDATE video
+SET category = $3, updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND deleted_at IS NULL
+`
+
+type UpdateVideoCategoryParams struct {
+	VideoID     uuid.UUID      `json:"video_id"`
+	PublisherID uuid.UUID      `json:"publisher_id"`
+	Category    sql.NullString `json:"category"`
+}
+
+func (q *Queries) UpdateVideoCategory(ctx context.Context, arg UpdateVideoCategoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateVideoCategory, arg.VideoID, arg.PublisherID, arg.Category)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateVideoDuration = `-- name: UpdateVideoDuration :exec
 UPDATE video
 SET duration = $2
@@ -124,3 +1015,49 @@ func (q *Queries) UpdateVideoDuration(ctx context.Context, arg UpdateVideoDurati
 	_, err := q.db.ExecContext(ctx, updateVideoDuration, arg.VideoID, arg.Duration)
 	return err
 }
+
+const updateVideoLicense = `-- name: UpdateVideoLicense :execrows
+UPDATE video
+SET license = $3, attribution = $4, updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND deleted_at IS NULL
+`
+
+type UpdateVideoLicenseParams struct {
+	VideoID     uuid.UUID      `json:"video_id"`
+	PublisherID uuid.UUID      `json:"publisher_id"`
+	License     VideoLicense   `json:"license"`
+	Attribution sql.NullString `json:"attribution"`
+}
+
+func (q *Queries) UpdateVideoLicense(ctx context.Context, arg UpdateVideoLicenseParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateVideoLicense,
+		arg.VideoID,
+		arg.PublisherID,
+		arg.License,
+		arg.Attribution,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateVideoVisibility = `-- name: UpdateVideoVisibility :execrows
+UPDATE video
+SET visibility = $3, updated_at = now()
+WHERE video_id = $1 AND publisher_id = $2 AND deleted_at IS NULL
+`
+
+type UpdateVideoVisibilityParams struct {
+	VideoID     uuid.UUID       `json:"video_id"`
+	PublisherID uuid.UUID       `json:"publisher_id"`
+	Visibility  VideoVisibility `json:"visibility"`
+}
+
+func (q *Queries) UpdateVideoVisibility(ctx context.Context, arg UpdateVideoVisibilityParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateVideoVisibility, arg.VideoID, arg.PublisherID, arg.Visibility)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+	"zust/service/security"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache is the interface of the cache of the rows read on the hot paths (media serving, video pages). Values are
+// encoded in JSON, so they can be kept in memory or shared between the instances in Redis
+type Cache interface {
+	// Get returns the value of a key, false if the key is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool)
+
+	// Set stores the value of a key for ttl, or for the TTL of the cache if ttl is 0
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+
+	// Delete removes keys, it's called whenever the cached rows are written
+	Delete(ctx context.Context, keys ...string)
+
+	// Close releases the connections of the cache when the server shuts down
+	Close() error
+}
+
+// Constructor method for the cache of the configured driver
+func NewCache(config *security.Config) Cache {
+	switch config.CacheDriver {
+	case "redis":
+		return NewRedisCache(config)
+	case "none":
+		return noCache{}
+	default:
+		return NewLRUCache(config.CacheSize, config.CacheTTL)
+	}
+}
+
+// Function to get a value from the cache, or load and cache it if it's missing. Errors of 'load' (for example:
+// sql.ErrNoRows) are returned as is and never cached
+func Fetch[T any](ctx context.Context, cache Cache, key string, load func() (T, error)) (T, error) {
+	return FetchTTL(ctx, cache, key, 0, load)
+}
+
+// Function like Fetch, but the loaded value is cached for ttl instead of the TTL of the cache. It's used for the
+// values which must not stay stale for long when an invalidation is missed
+func FetchTTL[T any](ctx context.Context, cache Cache, key string, ttl time.Duration,
+	load func() (T, error)) (T, error) {
+	var value T
+	if data, ok := cache.Get(ctx, key); ok && json.Unmarshal(data, &value) == nil {
+		return value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	if data, err := json.Marshal(value); err == nil {
+		cache.Set(ctx, key, data, ttl)
+	}
+	return value, nil
+}
+
+// Cache which never keeps anything, used when caching is disabled
+type noCache struct{}
+
+func (noCache) Get(ctx context.Context, key string) ([]byte, bool)                   { return nil, false }
+func (noCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {}
+func (noCache) Delete(ctx context.Context, keys ...string)                           {}
+func (noCache) Close() error                                                         { return nil }
+
+// In-memory cache of a single instance, which evicts the least recently used entry when it's full
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+// Entry of the LRU cache
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// Constructor method for the LRU cache, holding at most 'size' entries for 'ttl'
+func NewLRUCache(size int, ttl time.Duration) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Method to get an entry of the LRU cache
+func (cache *LRUCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Method to set an entry of the LRU cache, the least recently used entry is evicted if the cache is full
+func (cache *LRUCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if ttl == 0 {
+		ttl = cache.ttl
+	}
+	expires := time.Now().Add(ttl)
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Method to remove entries of the LRU cache
+func (cache *LRUCache) Delete(ctx context.Context, keys ...string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := cache.entries[key]; ok {
+			cache.order.Remove(element)
+			delete(cache.entries, key)
+		}
+	}
+}
+
+// Method to close the LRU cache, nothing to release
+func (cache *LRUCache) Close() error {
+	return nil
+}
+
+// Prefix of the cache keys in Redis, so they never collide with the keys of the job queue
+const redisKeyPrefix = "zust:cache:"
+
+// Cache shared between the instances in Redis, so a write on an instance invalidates the rows cached by all of them.
+// Redis errors are treated as cache misses, the rows are then read from the database
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// Constructor method for the Redis cache
+func NewRedisCache(config *security.Config) *RedisCache {
+	return &RedisCache{
+		client: redis.NewClient(&redis.Options{
+			Addr:     config.RedisAddr,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+		}),
+		ttl: config.CacheTTL,
+	}
+}
+
+// Method to get an entry of the Redis cache
+func (cache *RedisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, err := cache.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Method to set an entry of the Redis cache
+func (cache *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if ttl == 0 {
+		ttl = cache.ttl
+	}
+	cache.client.Set(ctx, redisKeyPrefix+key, value, ttl)
+}
+
+// Method to remove entries of the Redis cache
+func (cache *RedisCache) Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = redisKeyPrefix + key
+	}
+	cache.client.Del(ctx, prefixed...)
+}
+
+// Method to close the connections of the Redis cache
+func (cache *RedisCache) Close() error {
+	return cache.client.Close()
+}
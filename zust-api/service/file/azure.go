@@ -0,0 +1,509 @@
+package file
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"zust/asset"
+	"zust/service/security"
+)
+
+// Version of the Azure Blob Storage REST API used by the requests and the SAS links
+const azureAPIVersion = "2021-08-06"
+
+// Size of the blocks a file is uploaded in. A blob holds at most 50000 blocks, so up to ~390 GiB per file
+const azureBlockSize = 8 << 20
+
+// Timeout of waiting for the response of a request. The transfer of the body is not bounded, the files can be large
+const azureResponseTimeout = 30 * time.Second
+
+// Azure Blob storage struct, which keeps the files as block blobs of a container. The keys are the blob names
+type AzureStorage struct {
+	Account   string
+	Key       []byte // decoded shared key of the account
+	Container string
+	Endpoint  string // for example: https://{account}.blob.core.windows.net
+	AssetPath string // directory overriding the embedded default assets, empty to use the embedded ones
+
+	client *http.Client
+}
+
+// Constructor method for Azure Blob storage struct
+func NewAzureStorage(config *security.Config) (*AzureStorage, error) {
+	key, err := base64.StdEncoding.DecodeString(config.AzureStorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure storage key: %w", err)
+	}
+
+	endpoint := config.AzureStorageEndpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", config.AzureStorageAccount)
+	}
+
+	return &AzureStorage{
+		Account:   config.AzureStorageAccount,
+		Key:       key,
+		Container: config.AzureStorageContainer,
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		AssetPath: config.AssetPath,
+		client:    &http.Client{Transport: &http.Transport{ResponseHeaderTimeout: azureResponseTimeout}},
+	}, nil
+}
+
+// Helper method: get the URL of a blob, or of the container if 'key' is empty
+func (storage *AzureStorage) blobURL(key string, query url.Values) *url.URL {
+	segments := []string{storage.Container}
+	for _, segment := range strings.Split(key, "/") {
+		if segment != "" {
+			segments = append(segments, url.PathEscape(segment))
+		}
+	}
+
+	u, _ := url.Parse(storage.Endpoint + "/" + strings.Join(segments, "/"))
+	u.RawQuery = query.Encode()
+	return u
+}
+
+// Helper method: send a request signed with the shared key of the account. A response with a status other than
+// 'expected' is returned as an error, wrapping fs.ErrNotExist for 404
+func (storage *AzureStorage) do(req *http.Request, expected ...int) (*http.Response, error) {
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", storage.Account, storage.sign(req)))
+
+	resp, err := storage.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(expected, resp.StatusCode) {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("azure: %s %s: %w", req.Method, req.URL.Path, fs.ErrNotExist)
+	}
+	return nil, fmt.Errorf("azure: %s %s: unexpected status %d: %s", req.Method, req.URL.Path, resp.StatusCode,
+		strings.TrimSpace(string(body)))
+}
+
+// Helper method: get the signature of a request for the SharedKey authorization: the base64 encoded HMAC-SHA256
+// of the verb, the standard headers, the x-ms-* headers and the resource of the request
+func (storage *AzureStorage) sign(req *http.Request) string {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	lines := []string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, x-ms-date is used instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}
+
+	// Canonicalized headers: the x-ms-* headers, lowercased and sorted
+	var headers []string
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			headers = append(headers, name+":"+strings.Join(values, ","))
+		}
+	}
+	slices.Sort(headers)
+	lines = append(lines, headers...)
+
+	// Canonicalized resource: the account, the path and the query parameters sorted by name
+	resource := "/" + storage.Account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		values := slices.Clone(query[name])
+		slices.Sort(values)
+		resource += fmt.Sprintf("\n%s:%s", strings.ToLower(name), strings.Join(values, ","))
+	}
+	lines = append(lines, resource)
+
+	mac := hmac.New(sha256.New, storage.Key)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Method to write a file, uploaded in blocks which replace the current blob only once they are all committed
+func (storage *AzureStorage) Put(key string, r io.Reader) error {
+	writer, err := storage.Create(key)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	if _, err := io.Copy(writer, r); err != nil {
+		return err
+	}
+	return writer.Commit()
+}
+
+// Method to open a writer of a file. The blocks written are only staged, the blob is replaced when the block list
+// is committed. Staged blocks which are never committed are discarded by Azure after a week
+func (storage *AzureStorage) Create(key string) (AtomicWriter, error) {
+	if key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("azure: invalid key %q", key)
+	}
+	return &azureWriter{storage: storage, key: key}, nil
+}
+
+// Atomic writer of a blob, uploading the written data in blocks
+type azureWriter struct {
+	storage *AzureStorage
+	key     string
+	buf     []byte
+	blocks  []string // IDs of the staged blocks, in order
+}
+
+// Method to buffer the written data, a block is staged each time the buffer is full
+func (writer *azureWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), azureBlockSize-len(writer.buf))
+		writer.buf = append(writer.buf, p[:n]...)
+		p = p[n:]
+		written += n
+
+		if len(writer.buf) == azureBlockSize {
+			if err := writer.stageBlock(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Helper method: stage the buffered data as a block of the blob
+func (writer *azureWriter) stageBlock() error {
+	// Block IDs must have the same length within a blob
+	id := base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "block-%06d", len(writer.blocks)))
+	query := url.Values{"comp": {"block"}, "blockid": {id}}
+
+	req, err := http.NewRequest(http.MethodPut, writer.storage.blobURL(writer.key, query).String(),
+		bytes.NewReader(writer.buf))
+	if err != nil {
+		return err
+	}
+	resp, err := writer.storage.do(req, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	writer.blocks = append(writer.blocks, id)
+	writer.buf = writer.buf[:0]
+	return nil
+}
+
+// XML body of the Put Block List request
+type azureBlockList struct {
+	XMLName xml.Name `xml:"BlockList"`
+	Latest  []string `xml:"Latest"`
+}
+
+// Method to stage the remaining data, then commit the block list so the blob is replaced
+func (writer *azureWriter) Commit() error {
+	if len(writer.buf) > 0 {
+		if err := writer.stageBlock(); err != nil {
+			return err
+		}
+	}
+
+	body, err := xml.Marshal(azureBlockList{Latest: writer.blocks})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut,
+		writer.storage.blobURL(writer.key, url.Values{"comp": {"blocklist"}}).String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	resp, err := writer.storage.do(req, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	writer.blocks = nil
+	return nil
+}
+
+// Method to discard the writer, the staged blocks are left to be discarded by Azure
+func (writer *azureWriter) Close() error {
+	writer.buf = nil
+	return nil
+}
+
+// Method to open a file for reading. The blob is read with ranged requests, so seeking doesn't download the part
+// skipped
+func (storage *AzureStorage) Get(key string) (io.ReadSeekCloser, *FileInfo, error) {
+	info, err := storage.Stat(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &azureBlob{storage: storage, key: key, size: info.Size}, info, nil
+}
+
+// Reader of a blob, the body of the current range request is opened on the first read after a seek
+type azureBlob struct {
+	storage *AzureStorage
+	key     string
+	size    int64
+	offset  int64
+	body    io.ReadCloser
+}
+
+// Method to read the blob from the current offset
+func (blob *azureBlob) Read(p []byte) (int, error) {
+	if blob.offset >= blob.size {
+		return 0, io.EOF
+	}
+
+	if blob.body == nil {
+		req, err := http.NewRequest(http.MethodGet, blob.storage.blobURL(blob.key, nil).String(), nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("x-ms-range", fmt.Sprintf("bytes=%d-", blob.offset))
+		resp, err := blob.storage.do(req, http.StatusOK, http.StatusPartialContent)
+		if err != nil {
+			return 0, err
+		}
+		blob.body = resp.Body
+	}
+
+	n, err := blob.body.Read(p)
+	blob.offset += int64(n)
+	if err == io.EOF && blob.offset < blob.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Method to move the offset, the current range request is dropped if the offset changes
+func (blob *azureBlob) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += blob.offset
+	case io.SeekEnd:
+		offset += blob.size
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("azure: negative offset %d", offset)
+	}
+
+	if offset != blob.offset && blob.body != nil {
+		blob.body.Close()
+		blob.body = nil
+	}
+	blob.offset = offset
+	return offset, nil
+}
+
+// Method to close the current range request
+func (blob *azureBlob) Close() error {
+	if blob.body == nil {
+		return nil
+	}
+	err := blob.body.Close()
+	blob.body = nil
+	return err
+}
+
+// Method to delete a file, deleting a blob which doesn't exist is not an error
+func (storage *AzureStorage) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, storage.blobURL(key, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := storage.do(req, http.StatusAccepted)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Method to get the metadata of a file from the properties of its blob
+func (storage *AzureStorage) Stat(key string) (*FileInfo, error) {
+	req, err := http.NewRequest(http.MethodHead, storage.blobURL(key, nil).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := storage.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return &FileInfo{Key: key, Name: path.Base(key), Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+// XML body of the List Blobs response
+type azureBlobList struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			LastModified  string `xml:"Last-Modified"`
+			ContentLength int64  `xml:"Content-Length"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+// Method to list the files whose key starts with 'prefix', following the pages of the listing
+func (storage *AzureStorage) List(prefix string) ([]FileInfo, error) {
+	var files []FileInfo
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		page, err := storage.listPage(query)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range page.Blobs {
+			modTime, _ := http.ParseTime(blob.Properties.LastModified)
+			files = append(files, FileInfo{
+				Key:     blob.Name,
+				Name:    path.Base(blob.Name),
+				Size:    blob.Properties.ContentLength,
+				ModTime: modTime,
+			})
+		}
+
+		if page.NextMarker == "" {
+			return files, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+// Helper method: get a page of the listing of the container
+func (storage *AzureStorage) listPage(query url.Values) (*azureBlobList, error) {
+	req, err := http.NewRequest(http.MethodGet, storage.blobURL("", query).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := storage.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var page azureBlobList
+	if err := xml.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Method to create the repository of a user. A container has no directories, so only the default avatar and cover
+// are stored, unless the user already has them
+func (storage *AzureStorage) EnsureUserRepo(accID string) error {
+	for name, key := range map[string]string{"avatar.png": AvatarKey(accID), "cover.png": CoverKey(accID)} {
+		if Exists(storage, key) {
+			continue
+		}
+
+		src, err := asset.Open(storage.AssetPath, name)
+		if err != nil {
+			return err
+		}
+		err = storage.Put(key, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Method to check that the container can be reached. The space of a container is not bounded, so it's reported
+// as 0 like the inodes
+func (storage *AzureStorage) Health() (*StorageHealth, error) {
+	req, err := http.NewRequest(http.MethodHead, storage.blobURL("", url.Values{"restype": {"container"}}).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := storage.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return &StorageHealth{}, nil
+}
+
+// Method to get a link to a file, signed with a service SAS granting read access to its blob until 'ttl' from now
+func (storage *AzureStorage) SignedURL(key string, ttl time.Duration) string {
+	expiry := time.Now().Add(ttl).UTC().Format(time.RFC3339)
+
+	// Fields of the string to sign, the optional ones are left empty
+	fields := []string{
+		"r",    // signed permissions
+		"",     // signed start
+		expiry, // signed expiry
+		fmt.Sprintf("/blob/%s/%s/%s", storage.Account, storage.Container, key),
+		"",                 // signed identifier
+		"",                 // signed IP
+		"",                 // signed protocol
+		azureAPIVersion,    // signed version
+		"b",                // signed resource: blob
+		"",                 // signed snapshot time
+		"",                 // signed encryption scope
+		"", "", "", "", "", // response headers overrides
+	}
+	mac := hmac.New(sha256.New, storage.Key)
+	mac.Write([]byte(strings.Join(fields, "\n")))
+
+	query := url.Values{
+		"sv":  {azureAPIVersion},
+		"sr":  {"b"},
+		"sp":  {"r"},
+		"se":  {expiry},
+		"sig": {base64.StdEncoding.EncodeToString(mac.Sum(nil))},
+	}
+	return storage.blobURL(key, query).String()
+}
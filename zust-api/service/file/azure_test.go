@@ -0,0 +1,239 @@
+package file
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// In-memory container of the Blob service, answering the requests of AzureStorage
+type fakeContainer struct {
+	mu     sync.Mutex
+	blobs  map[string][]byte
+	blocks map[string][]byte // staged blocks by blob name and block ID
+}
+
+func (container *fakeContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	container.mu.Lock()
+	defer container.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "SharedKey account:") || r.Header.Get("x-ms-date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/cold"), "/")
+	query := r.URL.Query()
+	switch {
+	case name == "" && query.Get("comp") == "list":
+		container.list(w, query.Get("prefix"), query.Get("marker"))
+	case name == "":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		body, _ := io.ReadAll(r.Body)
+		container.blocks[name+"/"+query.Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		var list azureBlockList
+		if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var blob []byte
+		for _, id := range list.Latest {
+			blob = append(blob, container.blocks[name+"/"+id]...)
+		}
+		container.blobs[name] = blob
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		if _, ok := container.blobs[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(container.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		blob, ok := container.blobs[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		status := http.StatusOK
+		if rng := r.Header.Get("x-ms-range"); rng != "" {
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			blob, status = blob[start:], http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(blob)
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// Helper method: list the blobs by pages of 2, the marker is the name of the first blob of the next page
+func (container *fakeContainer) list(w http.ResponseWriter, prefix, marker string) {
+	var names []string
+	for name := range container.blobs {
+		if strings.HasPrefix(name, prefix) && name >= marker {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var body strings.Builder
+	body.WriteString("<EnumerationResults><Blobs>")
+	for _, name := range names[:min(2, len(names))] {
+		fmt.Fprintf(&body, "<Blob><Name>%s</Name><Properties><Content-Length>%d</Content-Length></Properties></Blob>",
+			name, len(container.blobs[name]))
+	}
+	body.WriteString("</Blobs>")
+	if len(names) > 2 {
+		fmt.Fprintf(&body, "<NextMarker>%s</NextMarker>", names[2])
+	}
+	body.WriteString("</EnumerationResults>")
+	w.Write([]byte(body.String()))
+}
+
+func TestAzureStorage(t *testing.T) {
+	server := httptest.NewServer(&fakeContainer{blobs: map[string][]byte{}, blocks: map[string][]byte{}})
+	defer server.Close()
+	storage := &AzureStorage{Account: "account", Key: []byte("key"), Container: "cold", Endpoint: server.URL,
+		client: server.Client()}
+
+	// A file larger than a block is uploaded in several blocks
+	large := bytes.Repeat([]byte("0123456789"), azureBlockSize/10*2+1)
+	files := map[string][]byte{
+		"acc/resource/vid_720p.mp4":       large,
+		"acc/hls/vid/master.m3u8":         []byte("#EXTM3U"),
+		"acc/hls/vid/720p/index.m3u8":     []byte("#EXTM3U"),
+		"acc/hls/vid/720p/segment 0.m4s":  []byte("segment"),
+		"acc/resource/other_720p.mp4":     []byte("other"),
+		"acc/resource/vid_720p_empty.mp4": nil,
+	}
+	for key, content := range files {
+		if err := storage.Put(key, bytes.NewReader(content)); err != nil {
+			t.Fatalf("Put(%s) failed: %v", key, err)
+		}
+	}
+
+	// Listing follows the pages
+	infos, err := storage.List("acc/hls/vid/")
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	var keys []string
+	for _, info := range infos {
+		keys = append(keys, info.Key)
+	}
+	want := []string{"acc/hls/vid/720p/index.m3u8", "acc/hls/vid/720p/segment 0.m4s", "acc/hls/vid/master.m3u8"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("List() = %v, want %v", keys, want)
+	}
+
+	// Reading after a seek only fetches the rest of the blob
+	reader, info, err := storage.Get("acc/resource/vid_720p.mp4")
+	if err != nil {
+		t.Fatalf("Get() failed: %v", err)
+	}
+	defer reader.Close()
+	if info.Size != int64(len(large)) {
+		t.Errorf("size = %d, want %d", info.Size, len(large))
+	}
+	if _, err := reader.Seek(-15, io.SeekEnd); err != nil {
+		t.Fatalf("Seek() failed: %v", err)
+	}
+	if tail, err := io.ReadAll(reader); err != nil || string(tail) != "567890123456789" {
+		t.Errorf("read after seek = %q, %v, want %q", tail, err, "567890123456789")
+	}
+	if content, err := ReadFile(storage, "acc/resource/vid_720p.mp4"); err != nil || !bytes.Equal(content, large) {
+		t.Errorf("ReadFile() = %d bytes, %v, want %d bytes", len(content), err, len(large))
+	}
+	if content, err := ReadFile(storage, "acc/resource/vid_720p_empty.mp4"); err != nil || len(content) != 0 {
+		t.Errorf("ReadFile() of an empty file = %q, %v", content, err)
+	}
+
+	// Missing files wrap fs.ErrNotExist, deleting them is not an error
+	if err := storage.Delete("acc/hls/vid/master.m3u8"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := storage.Stat("acc/hls/vid/master.m3u8"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of a deleted file = %v, want fs.ErrNotExist", err)
+	}
+	if err := storage.Delete("acc/hls/vid/master.m3u8"); err != nil {
+		t.Errorf("Delete() of a missing file = %v, want nil", err)
+	}
+
+	if _, err := storage.Health(); err != nil {
+		t.Errorf("Health() failed: %v", err)
+	}
+}
+
+func TestAzureSign(t *testing.T) {
+	key := []byte("secret")
+	storage := &AzureStorage{Account: "account", Key: key, Container: "cold"}
+
+	req, _ := http.NewRequest(http.MethodPut,
+		storage.blobURL("acc/hls/a b.m4s", url.Values{"comp": {"block"}, "blockid": {"YQ=="}}).String(),
+		strings.NewReader("data"))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header.Set("x-ms-date", "Sun, 18 Oct 2026 10:00:00 GMT")
+
+	toSign := "PUT\n\n\n4\n\n\n\n\n\n\n\n\n" +
+		"x-ms-date:Sun, 18 Oct 2026 10:00:00 GMT\nx-ms-version:" + azureAPIVersion + "\n" +
+		"/account/cold/acc/hls/a%20b.m4s\nblockid:YQ==\ncomp:block"
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(toSign))
+	if got, want := storage.sign(req), base64.StdEncoding.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("sign() = %s, want %s", got, want)
+	}
+}
+
+func TestAzureSignedURL(t *testing.T) {
+	key := []byte("secret")
+	storage := &AzureStorage{Account: "account", Key: key, Container: "cold",
+		Endpoint: "https://account.blob.core.windows.net"}
+
+	link, err := url.Parse(storage.SignedURL("acc/resource/vid_720p.mp4", time.Hour))
+	if err != nil {
+		t.Fatalf("SignedURL() is not a URL: %v", err)
+	}
+	if link.Host != "account.blob.core.windows.net" || link.Path != "/cold/acc/resource/vid_720p.mp4" {
+		t.Errorf("SignedURL() = %s, want a link to the blob", link)
+	}
+
+	query := link.Query()
+	expiry, err := time.Parse(time.RFC3339, query.Get("se"))
+	if err != nil || time.Until(expiry) < 59*time.Minute || time.Until(expiry) > time.Hour {
+		t.Errorf("expiry = %s, want in an hour", query.Get("se"))
+	}
+	if query.Get("sp") != "r" || query.Get("sr") != "b" || query.Get("sv") != azureAPIVersion {
+		t.Errorf("SAS fields = %v, want read access to a blob", query)
+	}
+
+	toSign := "r\n\n" + query.Get("se") + "\n/blob/account/cold/acc/resource/vid_720p.mp4\n\n\n\n" +
+		azureAPIVersion + "\nb\n\n\n\n\n\n\n"
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(toSign))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); query.Get("sig") != want {
+		t.Errorf("signature = %s, want %s", query.Get("sig"), want)
+	}
+}
@@ -0,0 +1,85 @@
+package file
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// Function to write the stored files of 'keys' into a gzip compressed tar stream, each entry is named by its key
+func WriteBackup(storage Storage, keys []string, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	for _, key := range keys {
+		if err := writeBackupEntry(storage, archive, key); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Helper function: copy a stored file into the tar stream
+func writeBackupEntry(storage Storage, archive *tar.Writer, key string) error {
+	file, info, err := storage.Get(key)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = archive.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     key,
+		Size:     info.Size,
+		Mode:     0644,
+		ModTime:  info.ModTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(archive, file)
+	return err
+}
+
+// Function to restore the files of a backup written by WriteBackup into the storage. Files already in the storage
+// are kept, so a backup only brings back what is lost. It returns the number of files restored
+func RestoreBackup(storage Storage, r io.Reader) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+
+	restored := 0
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return restored, nil
+		}
+		if err != nil {
+			return restored, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Keys escaping the storage are rejected by the storage itself, a cleaned key never starts with '/'
+		key := strings.TrimPrefix(path.Clean(header.Name), "/")
+		if Exists(storage, key) {
+			continue
+		}
+		if err := storage.Put(key, archive); err != nil {
+			return restored, err
+		}
+		restored++
+	}
+}
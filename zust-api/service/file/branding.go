@@ -0,0 +1,128 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Branding clips of a channel, played before and after its videos
+const (
+	BrandingIntro = "intro"
+	BrandingOutro = "outro"
+)
+
+// Maximum duration of a branding clip, in seconds
+const MaxBrandingDuration = 30
+
+// Method to concatenate the intro and/or outro clips of a channel onto a video with the concat demuxer.
+// 'intro' and 'outro' expect full file paths, empty if the channel doesn't have it. 'info' is the probed input.
+// The concat demuxer requires all parts to share the same codecs and parameters, so each part is first normalized
+// to the resolution, frame rate and audio layout of the input (clips are letterboxed to keep their aspect ratio),
+// then the parts are joined without re-encoding
+func (service *MediaService) StitchBranding(ctx context.Context, input, intro, outro, output string,
+	info *MediaInfo) error {
+	// Normalized parts and the concat list are written next to the output, and removed when done
+	workDir, err := os.MkdirTemp(filepath.Dir(output), "branding-*"+TempMarker)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	var list strings.Builder
+	for i, part := range []string{intro, input, outro} {
+		if part == "" {
+			continue
+		}
+
+		normalized := filepath.Join(workDir, fmt.Sprintf("part_%d.mp4", i))
+		if err := service.normalizePart(ctx, part, normalized, info); err != nil {
+			return err
+		}
+		list.WriteString(fmt.Sprintf("file '%s'\n", normalized))
+	}
+
+	listFile := filepath.Join(workDir, "list.txt")
+	if err := os.WriteFile(listFile, []byte(list.String()), 0644); err != nil {
+		return err
+	}
+
+	/*
+	 * Command:
+	 * ffmpeg -f concat -safe 0 -i list.txt -c copy -movflags +faststart output.mp4
+	 */
+	cmd := service.ffmpeg(ctx,
+		"-f", "concat",
+		"-safe", "0", // the list contains absolute paths
+		"-i", listFile,
+		"-c", "copy",
+		"-movflags", "+faststart",
+		"-y",
+		output,
+	)
+	out, err := service.run(cmd)
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed for concatenating branding clips: %v\nOutput: %s", err, string(out))
+	}
+	return nil
+}
+
+// Helper method: re-encode a part of the stitched video with the parameters of the main video. A part without
+// audio gets a silent track, so every part has the same streams
+func (service *MediaService) normalizePart(ctx context.Context, input, output string, info *MediaInfo) error {
+	/*
+	 * Command:
+	 * ffmpeg -i part.mp4 -f lavfi -i anullsrc=r=48000:cl=stereo
+	 * -vf "scale=1920:1080:force_original_aspect_ratio=decrease,pad=1920:1080:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=30"
+	 * -map 0:v:0 -map 0:a:0 (or 1:a when the part has no audio) -shortest
+	 * -c:v libx264 -preset veryfast -crf 18 -pix_fmt yuv420p -c:a aac -b:a 192k -ar 48000 -ac 2 output.mp4
+	 */
+
+	partInfo, err := service.Probe(input)
+	if err != nil {
+		return err
+	}
+
+	// Dimensions must be even for yuv420p
+	width, height := info.Width/2*2, info.Height/2*2
+	fps := info.FPS
+	if fps <= 0 {
+		fps = 30
+	}
+	filter := fmt.Sprintf(
+		"scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s",
+		width, height, width, height, strconv.FormatFloat(fps, 'f', 3, 64),
+	)
+
+	audio := "0:a:0"
+	if !partInfo.HasAudio() {
+		audio = "1:a"
+	}
+
+	args := []string{
+		"-i", input,
+		"-f", "lavfi",
+		"-i", "anullsrc=r=48000:cl=stereo",
+		"-vf", filter,
+		"-map", "0:v:0",
+		"-map", audio,
+		"-shortest", // the silent track is infinite
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "18", // the stitched video is transcoded again, keep the quality loss low
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", "192k",
+		"-ar", "48000",
+		"-ac", "2",
+	}
+	args = append(args, service.threadArgs()...)
+	out, err := service.run(service.ffmpeg(ctx, append(args, "-y", output)...))
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed for normalizing branding part: %v\nOutput: %s", err, string(out))
+	}
+	return nil
+}
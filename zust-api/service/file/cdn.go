@@ -0,0 +1,39 @@
+package file
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Helper method: get the URL of a file under /media/{id} ('filename' can be empty for the media itself). If a CDN
+// is configured, the URL points at the CDN, with a token if links are signed
+func (service *MediaService) mediaURL(id, filename string) string {
+	prefix := fmt.Sprintf("/media/%s", id)
+	target := prefix
+	if filename != "" {
+		target = fmt.Sprintf("%s/%s", prefix, filename)
+	}
+
+	if service.CDNURL == "" {
+		return fmt.Sprintf("%s:%s%s", service.Domain, service.Port, target)
+	}
+	if service.CDNSigningKey == "" {
+		return service.CDNURL + target
+	}
+
+	expires := time.Now().Add(service.CDNTokenTTL).Unix()
+	return fmt.Sprintf("%s%s?expires=%d&token=%s", service.CDNURL, target, expires,
+		SignMediaPath(service.CDNSigningKey, prefix, expires))
+}
+
+// Function to sign the path prefix of a media link (/media/{id}) until 'expires' (Unix time). The token is the hex
+// encoded HMAC-SHA256 of '{prefix}:{expires}', it covers every file under the prefix (for example: the playlists
+// and segments of HLS), so the CDN edge can verify the token of any request under it with the same key
+func SignMediaPath(key, prefix string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s:%d", prefix, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
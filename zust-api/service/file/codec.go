@@ -0,0 +1,104 @@
+package file
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Video codec of a transcoded rendition
+type VideoCodec string
+
+const (
+	CodecH264 VideoCodec = "h264"
+	CodecVP9  VideoCodec = "vp9"
+	CodecAV1  VideoCodec = "av1"
+)
+
+// Codecs ordered from the most to the least efficient, which is the order of preference when serving a video
+var codecPreference = []VideoCodec{CodecAV1, CodecVP9, CodecH264}
+
+// AV1 encoders supported by the transcoder
+const (
+	EncoderSVTAV1 = "libsvtav1"
+	EncoderAOMAV1 = "libaom-av1"
+)
+
+// Helper function: parse a codec name, for example: vp9
+func ParseVideoCodec(name string) (VideoCodec, error) {
+	codec := VideoCodec(name)
+	if !slices.Contains(codecPreference, codec) {
+		return "", fmt.Errorf("unsupported video codec %q, expect one of: h264, vp9, av1", name)
+	}
+	return codec, nil
+}
+
+// Helper function: choose the best codec among 'available' that the client accepts.
+// If 'accepted' is empty, the client is assumed to only support H.264. It returns an empty string if none matches
+func BestCodec(accepted, available []VideoCodec) VideoCodec {
+	if len(accepted) == 0 {
+		accepted = []VideoCodec{CodecH264}
+	}
+	for _, codec := range codecPreference {
+		if slices.Contains(accepted, codec) && slices.Contains(available, codec) {
+			return codec
+		}
+	}
+	return ""
+}
+
+// Helper function: get the name of a rendition, which is the resolution with the codec appended for codecs
+// other than H.264, for example: 1080p, 1080p_vp9
+func RenditionName(resolution string, codec VideoCodec) string {
+	if codec == "" || codec == CodecH264 {
+		return resolution
+	}
+	return fmt.Sprintf("%s_%s", resolution, codec)
+}
+
+// CRF presets of each codec for a resolution. The CRF scales differ: libx264 uses [0, 51] while libvpx-vp9 and
+// the AV1 encoders use [0, 63]
+type CodecCRF struct {
+	H264 string
+	VP9  string
+	AV1  string
+}
+
+// Method to get the CRF preset of the codec
+func (presets CodecCRF) Get(codec VideoCodec) string {
+	switch codec {
+	case CodecVP9:
+		return presets.VP9
+	case CodecAV1:
+		return presets.AV1
+	default:
+		return presets.H264
+	}
+}
+
+// Sane CRF range of each codec, a per-title adjustment never goes out of it
+var crfRanges = map[VideoCodec][2]int{
+	CodecH264: {18, 35},
+	CodecVP9:  {15, 50},
+	CodecAV1:  {15, 50},
+}
+
+// Helper method: get the encoder arguments of a rung, based on its codec and CRF.
+// If 'hw' is true, H.264 is encoded by the detected hardware encoder
+func (service *MediaService) encoderArgs(res ResolutionConfig, hw bool) []string {
+	switch res.Codec {
+	case CodecVP9:
+		// Constant quality mode of libvpx-vp9 requires the bitrate to be 0
+		return []string{"-c:v", "libvpx-vp9", "-crf", res.CRF, "-b:v", "0",
+			"-deadline", "good", "-cpu-used", "2", "-row-mt", "1"}
+	case CodecAV1:
+		if service.AV1Encoder == EncoderAOMAV1 {
+			return []string{"-c:v", EncoderAOMAV1, "-crf", res.CRF, "-b:v", "0", "-cpu-used", "6", "-row-mt", "1"}
+		}
+		return []string{"-c:v", EncoderSVTAV1, "-crf", res.CRF, "-preset", "8"}
+	default:
+		if hw {
+			return service.hwEncoderArgs(res)
+		}
+		return []string{"-c:v", "libx264", "-preset", "fast", "-crf", res.CRF}
+	}
+}
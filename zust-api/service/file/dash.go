@@ -0,0 +1,168 @@
+package file
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DASH manifest filename, located next to the HLS master playlist
+const DASHManifest = "manifest.mpd"
+
+// MPD structure, only the elements we need for a static (VOD) presentation
+type mpd struct {
+	XMLName                   xml.Name  `xml:"MPD"`
+	XMLNS                     string    `xml:"xmlns,attr"`
+	Profiles                  string    `xml:"profiles,attr"`
+	Type                      string    `xml:"type,attr"`
+	MediaPresentationDuration string    `xml:"mediaPresentationDuration,attr"`
+	MinBufferTime             string    `xml:"minBufferTime,attr"`
+	Period                    mpdPeriod `xml:"Period"`
+}
+
+type mpdPeriod struct {
+	ID             string             `xml:"id,attr"`
+	Start          string             `xml:"start,attr"`
+	AdaptationSets []mpdAdaptationSet `xml:"AdaptationSet"`
+}
+
+type mpdAdaptationSet struct {
+	ID               int                 `xml:"id,attr"`
+	ContentType      string              `xml:"contentType,attr"`
+	MimeType         string              `xml:"mimeType,attr"`
+	SegmentAlignment bool                `xml:"segmentAlignment,attr"`
+	Representations  []mpdRepresentation `xml:"Representation"`
+}
+
+type mpdRepresentation struct {
+	ID              string             `xml:"id,attr"`
+	Bandwidth       int64              `xml:"bandwidth,attr"`
+	Width           int                `xml:"width,attr"`
+	Height          int                `xml:"height,attr"`
+	Codecs          string             `xml:"codecs,attr,omitempty"`
+	SegmentTemplate mpdSegmentTemplate `xml:"SegmentTemplate"`
+}
+
+type mpdSegmentTemplate struct {
+	Timescale      int             `xml:"timescale,attr"`
+	Initialization string          `xml:"initialization,attr"`
+	Media          string          `xml:"media,attr"`
+	StartNumber    int             `xml:"startNumber,attr"`
+	Timeline       []mpdTimelineEl `xml:"SegmentTimeline>S"`
+}
+
+type mpdTimelineEl struct {
+	Duration int64 `xml:"d,attr"`
+	Repeat   int   `xml:"r,attr,omitempty"`
+}
+
+// Method to write the DASH manifest referencing the CMAF segments produced by PackageHLS, so both formats share
+// the same segments on disk. 'dir' expects the HLS directory of the video. Segment durations are read from the
+// HLS media playlist of each variant. Representations of different codecs can't be switched between, so each codec
+// has its own adaptation set and the player picks the one it supports
+func (service *MediaService) WriteDASHManifest(dir string, variants []StreamVariant) error {
+	const timescale = 1000 // millisecond
+
+	var (
+		adaptations []mpdAdaptationSet
+		codecs      []VideoCodec // codec of each adaptation set
+		total       int64
+	)
+	for _, variant := range variants {
+		codec := variant.Codec
+		if codec == "" {
+			codec = CodecH264
+		}
+		index := slices.Index(codecs, codec)
+		if index < 0 {
+			index = len(adaptations)
+			codecs = append(codecs, codec)
+			adaptations = append(adaptations, mpdAdaptationSet{
+				ID:               index,
+				ContentType:      "video",
+				MimeType:         "video/mp4",
+				SegmentAlignment: true,
+			})
+		}
+
+		durations, err := readSegmentDurations(filepath.Join(dir, variant.Name, HLSPlaylist))
+		if err != nil {
+			return err
+		}
+
+		// Consecutive segments with the same duration are merged with the repeat attribute
+		var timeline []mpdTimelineEl
+		var sum int64
+		for _, duration := range durations {
+			d := int64(duration * timescale)
+			sum += d
+			if n := len(timeline); n > 0 && timeline[n-1].Duration == d {
+				timeline[n-1].Repeat++
+				continue
+			}
+			timeline = append(timeline, mpdTimelineEl{Duration: d})
+		}
+		total = max(total, sum)
+
+		adaptations[index].Representations = append(adaptations[index].Representations, mpdRepresentation{
+			ID:        variant.Name,
+			Bandwidth: variant.Bandwidth,
+			Width:     variant.Width,
+			Height:    variant.Height,
+			Codecs:    variant.Codecs,
+			SegmentTemplate: mpdSegmentTemplate{
+				Timescale:      timescale,
+				Initialization: fmt.Sprintf("%s/init.mp4", variant.Name),
+				Media:          fmt.Sprintf("%s/segment_$Number%%03d$.m4s", variant.Name),
+				StartNumber:    0,
+				Timeline:       timeline,
+			},
+		})
+	}
+
+	manifest := mpd{
+		XMLNS:                     "urn:mpeg:dash:schema:mpd:2011",
+		Profiles:                  "urn:mpeg:dash:profile:isoff-live:2011",
+		Type:                      "static",
+		MediaPresentationDuration: fmt.Sprintf("PT%.3fS", float64(total)/timescale),
+		MinBufferTime:             fmt.Sprintf("PT%dS", hlsSegmentTime),
+		Period:                    mpdPeriod{ID: "0", Start: "PT0S", AdaptationSets: adaptations},
+	}
+
+	out, err := xml.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(filepath.Join(dir, DASHManifest), append([]byte(xml.Header), out...))
+}
+
+// Helper function: read the duration (second) of each segment from an HLS media playlist
+func readSegmentDurations(playlist string) ([]float64, error) {
+	f, err := os.Open(playlist)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Each segment is described by a line like: #EXTINF:6.006000,
+	var durations []float64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "#EXTINF:")
+		if !found {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ",")
+		duration, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid segment duration %q in %s", value, playlist)
+		}
+		durations = append(durations, duration)
+	}
+	return durations, scanner.Err()
+}
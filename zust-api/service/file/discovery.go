@@ -0,0 +1,104 @@
+package file
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Method to discover ffmpeg and ffprobe and check their capabilities, so a broken installation fails at startup
+// instead of failing every upload: both binaries must be found and runnable, and ffmpeg must have the encoders
+// (and filters) the config relies on. The paths are resolved and the versions recorded on success
+func (service *MediaService) DetectFFmpeg() error {
+	for _, bin := range []*string{&service.FFmpegPath, &service.FFprobePath} {
+		path, err := exec.LookPath(*bin)
+		if err != nil {
+			return fmt.Errorf("%s not found, install it or set FFMPEG_PATH/FFPROBE_PATH: %w", *bin, err)
+		}
+		*bin = path
+	}
+
+	var err error
+	if service.FFmpegVersion, err = binaryVersion(service.FFmpegPath); err != nil {
+		return err
+	}
+	if service.FFprobeVersion, err = binaryVersion(service.FFprobePath); err != nil {
+		return err
+	}
+
+	// Encoders required by the transcoding codecs, H.264 (and AAC) is always required for the renditions
+	encoders, err := service.listCapabilities("-encoders")
+	if err != nil {
+		return err
+	}
+	required := []string{"libx264", "aac"}
+	for _, codec := range service.Codecs {
+		switch codec {
+		case CodecVP9:
+			required = append(required, "libvpx-vp9")
+		case CodecAV1:
+			required = append(required, service.AV1Encoder)
+		}
+	}
+
+	service.WebPSupported = encoders["libwebp"]
+
+	var missing []string
+	for _, encoder := range required {
+		if !encoders[encoder] {
+			missing = append(missing, encoder)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("ffmpeg at %s is missing required encoders: %s", service.FFmpegPath, strings.Join(missing, ", "))
+	}
+
+	// Loudness normalization relies on an optional filter
+	if service.Loudnorm != "" {
+		filters, err := service.listCapabilities("-filters")
+		if err != nil {
+			return err
+		}
+		if !filters["loudnorm"] {
+			return fmt.Errorf("ffmpeg at %s is missing the loudnorm filter required by LOUDNORM_ENABLED", service.FFmpegPath)
+		}
+	}
+	return nil
+}
+
+// Helper function: get the version of ffmpeg or ffprobe from the first line of '-version', for example:
+// ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers -> 6.1.1-3ubuntu5
+func binaryVersion(path string) (string, error) {
+	out, err := exec.Command(path, "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not runnable: %w", path, err)
+	}
+
+	line, _, _ := strings.Cut(string(out), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[1] != "version" {
+		return "", fmt.Errorf("unexpected version output of %s: %s", path, line)
+	}
+	return fields[2], nil
+}
+
+// Helper method: get the names of the encoders ('-encoders') or filters ('-filters') built into ffmpeg. Both are
+// listed as lines of flags, name and description after a header ending with a '------' line
+func (service *MediaService) listCapabilities(flag string) (map[string]bool, error) {
+	out, err := exec.Command(service.FFmpegPath, "-hide_banner", flag).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed for listing %s: %w", strings.TrimPrefix(flag, "-"), err)
+	}
+
+	names := make(map[string]bool)
+	_, list, found := strings.Cut(string(out), "------")
+	if !found {
+		list = string(out)
+	}
+	for _, line := range strings.Split(list, "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			names[fields[1]] = true
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package file
+
+import "errors"
+
+// Function to get the space and inodes of the file system containing 'path', not supported on this platform
+func StatDisk(path string) (*StorageHealth, error) {
+	return nil, errors.New("disk statistics are not supported on this platform")
+}
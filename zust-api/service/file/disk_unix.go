@@ -0,0 +1,21 @@
+//go:build unix
+
+package file
+
+import "syscall"
+
+// Function to get the space and inodes of the file system containing 'path'. Only the space available to
+// unprivileged users is counted as free
+func StatDisk(path string) (*StorageHealth, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+
+	return &StorageHealth{
+		TotalBytes:  uint64(stat.Blocks) * uint64(stat.Bsize),
+		FreeBytes:   uint64(stat.Bavail) * uint64(stat.Bsize),
+		TotalInodes: uint64(stat.Files),
+		FreeInodes:  uint64(stat.Ffree),
+	}, nil
+}
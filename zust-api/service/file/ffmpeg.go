@@ -0,0 +1,48 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Builder of an ffmpeg command line. Every argument is a separate element, so nothing is quoted: the arguments
+// are passed to exec.Command as is, without going through a shell
+type ffmpegArgs struct {
+	inputs  []string
+	count   int      // number of inputs, the index of the next input
+	filters []string // chains of the filter graph, joined with ';'
+	outputs []string
+}
+
+// Method to add an input, 'opts' are the input options placed before it. It returns the index of the input
+func (args *ffmpegArgs) Input(path string, opts ...string) int {
+	args.inputs = append(args.inputs, opts...)
+	args.inputs = append(args.inputs, "-i", path)
+	args.count++
+	return args.count - 1
+}
+
+// Method to add a chain to the filter graph, for example: [0:v]scale=-2:720[v0]
+func (args *ffmpegArgs) Filter(chain string) {
+	args.filters = append(args.filters, chain)
+}
+
+// Method to add an output, 'opts' are the output options (maps, codecs, ...) placed before it
+func (args *ffmpegArgs) Output(path string, opts ...string) {
+	args.outputs = append(args.outputs, opts...)
+	args.outputs = append(args.outputs, "-y", path)
+}
+
+// Method to get the arguments: inputs, filter graph and then outputs
+func (args *ffmpegArgs) Build() []string {
+	built := append([]string{}, args.inputs...)
+	if len(args.filters) > 0 {
+		built = append(built, "-filter_complex", strings.Join(args.filters, ";"))
+	}
+	return append(built, args.outputs...)
+}
+
+// Helper function: get the label of a filter pad, for example: [v0]
+func pad(name string, index int) string {
+	return fmt.Sprintf("[%s%d]", name, index)
+}
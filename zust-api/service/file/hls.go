@@ -0,0 +1,81 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HLS packaging settings
+const (
+	HLSMasterPlaylist = "master.m3u8"
+	HLSPlaylist       = "index.m3u8"
+	hlsSegmentTime    = 6 // second
+)
+
+// A variant stream of the HLS master playlist (or representation of the DASH manifest)
+type StreamVariant struct {
+	Name      string // rendition name, which is also the sub directory of the variant, for example: 720p
+	Width     int
+	Height    int
+	Bandwidth int64  // bit/s
+	Codecs    string // RFC 6381 codecs string, can be empty if unknown
+	Codec     VideoCodec
+}
+
+// Method to package a transcoded rendition into HLS: fMP4 (CMAF) segments and a VOD media playlist.
+// The same segments are referenced by the DASH manifest, see WriteDASHManifest.
+// 'input' expects the full file path of the rendition, 'outputDir' the directory of this variant.
+// The rendition is already H.264/AAC so the streams are copied without re-encoding
+func (service *MediaService) PackageHLS(ctx context.Context, input, outputDir string) error {
+	/*
+	 * Command:
+	 * ffmpeg -i input_720p.mp4 -c copy -f hls -hls_time 6 -hls_playlist_type vod -hls_segment_type fmp4
+	 * -hls_fmp4_init_filename init.mp4 -hls_segment_filename outputDir/segment_%03d.m4s outputDir/index.m3u8
+	 */
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+
+	cmd := service.ffmpeg(ctx,
+		"-i", input,
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(hlsSegmentTime),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_type", "fmp4",
+		"-hls_fmp4_init_filename", "init.mp4",
+		"-hls_segment_filename", filepath.Join(outputDir, "segment_%03d.m4s"),
+		"-y",
+		filepath.Join(outputDir, HLSPlaylist),
+	)
+	out, err := service.run(cmd)
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed for HLS packaging: %v\nOutput: %s", err, string(out))
+	}
+	return nil
+}
+
+// Method to write the HLS master playlist referencing the media playlist of each variant.
+// 'dir' expects the HLS directory of the video, variants are listed in the given order
+func (service *MediaService) WriteMasterPlaylist(dir string, variants []StreamVariant) error {
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:7\n")
+	playlist.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	for _, variant := range variants {
+		playlist.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d",
+			variant.Bandwidth, variant.Width, variant.Height))
+		if variant.Codecs != "" {
+			playlist.WriteString(fmt.Sprintf(",CODECS=\"%s\"", variant.Codecs))
+		}
+		playlist.WriteString("\n")
+		playlist.WriteString(fmt.Sprintf("%s/%s\n", variant.Name, HLSPlaylist))
+	}
+
+	return WriteFileAtomic(filepath.Join(dir, HLSMasterPlaylist), []byte(playlist.String()))
+}
@@ -0,0 +1,102 @@
+package file
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Hardware acceleration methods for H.264 encoding
+const (
+	HWAccelNone  = "none"
+	HWAccelNVENC = "nvenc"
+	HWAccelVAAPI = "vaapi"
+	HWAccelQSV   = "qsv"
+)
+
+// ffmpeg encoder of each hardware acceleration method
+var hwEncoders = map[string]string{
+	HWAccelNVENC: "h264_nvenc",
+	HWAccelVAAPI: "h264_vaapi",
+	HWAccelQSV:   "h264_qsv",
+}
+
+// Method to detect if the configured hardware encoder is usable: it must be built into ffmpeg, and a short test
+// encode must succeed (the encoder can be built in while the GPU or its driver is missing).
+// If it's not usable, H.264 is encoded with libx264 and the error explains why
+func (service *MediaService) DetectHWAccel() error {
+	service.hwEncoder = ""
+	encoder, ok := hwEncoders[service.HWAccel]
+	if !ok {
+		return fmt.Errorf("unknown hardware acceleration %q", service.HWAccel)
+	}
+
+	// List the encoders built into ffmpeg
+	encoders, err := service.listCapabilities("-encoders")
+	if err != nil {
+		return err
+	}
+	if !encoders[encoder] {
+		return fmt.Errorf("encoder %s is not built into ffmpeg", encoder)
+	}
+
+	/*
+	 * Test encode one second of a generated video
+	 * Command:
+	 * ffmpeg -f lavfi -i color=size=256x256:duration=1 [-vaapi_device device -vf format=nv12,hwupload]
+	 * -c:v h264_nvenc -f null -
+	 */
+	args := service.hwInputArgs(true)
+	args = append(args, "-f", "lavfi", "-i", "color=size=256x256:duration=1")
+	if filter := service.hwFilter(true); filter != "" {
+		args = append(args, "-vf", strings.TrimPrefix(filter, ","))
+	}
+	args = append(args, "-c:v", encoder, "-f", "null", "-")
+	if out, err := exec.Command(service.FFmpegPath, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("test encode with %s failed: %v\nOutput: %s", encoder, err, string(out))
+	}
+
+	service.hwEncoder = encoder
+	return nil
+}
+
+// Method to check if H.264 is encoded by the hardware encoder
+func (service *MediaService) HWAccelEnabled() bool {
+	return service.hwEncoder != ""
+}
+
+// Helper method: get the arguments placed before the input to initialize the hardware device, if any
+func (service *MediaService) hwInputArgs(hw bool) []string {
+	if hw && service.HWAccel == HWAccelVAAPI {
+		return []string{"-vaapi_device", service.VAAPIDevice}
+	}
+	return nil
+}
+
+// Helper method: get the filters appended after scaling to upload the frames to the hardware device, if any
+func (service *MediaService) hwFilter(hw bool) string {
+	if hw && service.HWAccel == HWAccelVAAPI {
+		return ",format=nv12,hwupload"
+	}
+	return ""
+}
+
+// Helper method: get the arguments of the hardware H.264 encoder. The CRF of the rung is mapped to the
+// constant quality option of each encoder
+func (service *MediaService) hwEncoderArgs(res ResolutionConfig) []string {
+	// Hardware encoders are less efficient than libx264 at the same quality value, bias it a little
+	quality := res.CRF
+	if crf, err := strconv.Atoi(res.CRF); err == nil {
+		quality = strconv.Itoa(max(crf-2, 0))
+	}
+
+	switch service.HWAccel {
+	case HWAccelNVENC:
+		return []string{"-c:v", service.hwEncoder, "-preset", "p4", "-rc", "vbr", "-cq", quality, "-b:v", "0"}
+	case HWAccelVAAPI:
+		return []string{"-c:v", service.hwEncoder, "-rc_mode", "CQP", "-qp", quality}
+	default:
+		return []string{"-c:v", service.hwEncoder, "-preset", "medium", "-global_quality", quality}
+	}
+}
@@ -0,0 +1,229 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Largest width or height of a transformed image
+const MaxImageDimension = 2048
+
+// Output formats of transformed images with their ffmpeg encoders
+var imageEncoders = map[string]string{
+	"png":  "png",
+	"jpeg": "mjpeg",
+	"webp": "libwebp",
+}
+
+// Transformation of an image: it's resized to fit in Width x Height (0 means unconstrained) keeping the aspect
+// ratio, then encoded in Format (png, jpeg or webp)
+type ImageTransform struct {
+	Width  int
+	Height int
+	Format string
+}
+
+// Method to check if an output format of transformed images is supported. WebP needs ffmpeg built with libwebp
+func (service *MediaService) IsSupportedImageFormat(format string) bool {
+	if format == "webp" {
+		return service.WebPSupported
+	}
+	_, ok := imageEncoders[format]
+	return ok
+}
+
+// Helper function: check if a file is an image which can be transformed (avatars, covers and thumbnails)
+func IsTransformableImage(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+// Method to transform the image 'input' into 'output'. The image is written into a unique temporary file first,
+// so concurrent requests of the same variant never read a half-written file. ffmpeg is killed if 'ctx' is done
+func (service *MediaService) TransformImage(ctx context.Context, input, output string, transform ImageTransform) error {
+	/*
+	 * Command:
+	 * ffmpeg -i avatar.png -vf "scale=320:180:force_original_aspect_ratio=decrease" -frames:v 1 -f image2 -c:v libwebp
+	 * output.webp
+	 */
+
+	encoder, ok := imageEncoders[transform.Format]
+	if !ok {
+		return fmt.Errorf("unsupported image format %q", transform.Format)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(output), filepath.Base(output)+"-*"+TempMarker)
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	args := []string{"-i", input}
+	switch {
+	case transform.Width > 0 && transform.Height > 0:
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease",
+			transform.Width, transform.Height))
+	case transform.Width > 0:
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:-1", transform.Width))
+	case transform.Height > 0:
+		args = append(args, "-vf", fmt.Sprintf("scale=-1:%d", transform.Height))
+	}
+	args = append(args,
+		"-frames:v", "1",
+		"-f", "image2", // the temporary file has no image extension
+		"-c:v", encoder,
+		"-y",
+		tmp.Name(),
+	)
+
+	out, err := service.run(service.ffmpeg(ctx, args...))
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed for transforming image: %v\nOutput: %s", err, string(out))
+	}
+	return os.Rename(tmp.Name(), output)
+}
+
+// Largest width or height of an uploaded image, larger images are rejected before being decoded
+const MaxUploadImageDimension = 8192
+
+// Error returned when an uploaded image is larger than MaxUploadImageDimension
+var ErrImageTooLarge = errors.New("image exceeds the maximum dimension")
+
+// Function to re-encode an uploaded PNG or JPEG image as PNG. Only the pixels are kept, so the metadata (EXIF
+// including the GPS location, XMP, text chunks, ...) is stripped. The EXIF orientation of JPEG photos is applied to
+// the pixels first, so the image is still displayed the right way up
+func NormalizeImage(src io.Reader, dst io.Writer) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if config.Width > MaxUploadImageDimension || config.Height > MaxUploadImageDimension {
+		return ErrImageTooLarge
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	return png.Encode(dst, img)
+}
+
+// Helper function: get the EXIF orientation (1 to 8) of a JPEG image, 1 (no transformation) if it has none. The
+// orientation is the tag 0x0112 of the first IFD of the EXIF (APP1) segment
+func jpegOrientation(data []byte) int {
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || i+2+length > len(data) {
+			break // start of scan, no more metadata
+		}
+
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// Helper function: get the orientation tag from the TIFF structure of an EXIF segment
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			if orientation := int(order.Uint16(tiff[entry+8:])); orientation >= 1 && orientation <= 8 {
+				return orientation
+			}
+			break
+		}
+	}
+	return 1
+}
+
+// Helper function: transform an image according to its EXIF orientation: 2 mirrors it horizontally, 3 rotates it
+// 180°, 4 mirrors it vertically, 5 transposes it, 6 rotates it 90° clockwise, 7 transverses it and 8 rotates it 90°
+// counterclockwise
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
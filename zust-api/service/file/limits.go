@@ -0,0 +1,47 @@
+package file
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+)
+
+// Helper method: create an ffmpeg command following the resource limits of the config: it runs with the
+// configured niceness (through nice, so the API server running alongside keeps the CPU priority) and the
+// filters use the configured number of threads. Encoders get their threads from threadArgs
+func (service *MediaService) ffmpeg(ctx context.Context, args ...string) *exec.Cmd {
+	if service.FFmpegThreads > 0 {
+		threads := strconv.Itoa(service.FFmpegThreads)
+		args = append([]string{"-filter_threads", threads, "-filter_complex_threads", threads}, args...)
+	}
+	if service.FFmpegNice > 0 {
+		args = append([]string{"-n", strconv.Itoa(service.FFmpegNice), service.FFmpegPath}, args...)
+		return exec.CommandContext(ctx, "nice", args...)
+	}
+	return exec.CommandContext(ctx, service.FFmpegPath, args...)
+}
+
+// Helper method: get the output option limiting the threads of an encoder, empty if not configured
+func (service *MediaService) threadArgs() []string {
+	if service.FFmpegThreads <= 0 {
+		return nil
+	}
+	return []string{"-threads", strconv.Itoa(service.FFmpegThreads)}
+}
+
+// Helper method: wait until a new ffmpeg process is allowed to start. It returns the function releasing the slot,
+// to be called when the process exits
+func (service *MediaService) acquire() func() {
+	if service.slots == nil {
+		return func() {}
+	}
+	service.slots <- struct{}{}
+	return func() { <-service.slots }
+}
+
+// Helper method: run an ffmpeg command once a slot is free, and return its combined output
+func (service *MediaService) run(cmd *exec.Cmd) ([]byte, error) {
+	release := service.acquire()
+	defer release()
+	return cmd.CombinedOutput()
+}
@@ -0,0 +1,340 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Low-latency HLS settings
+const (
+	llhlsPartTarget      = 1.0 // second
+	llhlsPartsPerSegment = 4
+	llhlsPartHoldBack    = 3 * llhlsPartTarget // must be at least 3 times the part target
+	llhlsPartsKept       = 2                   // number of completed segments whose parts are still listed
+)
+
+// A partial segment of a LL-HLS playlist
+type llhlsPart struct {
+	URI         string
+	Duration    float64
+	Independent bool
+}
+
+// A completed segment of a LL-HLS playlist
+type llhlsSegment struct {
+	URI      string
+	Duration float64
+	Parts    []llhlsPart
+}
+
+// LLHLSPlaylist is a live LL-HLS media playlist, which grows while the packager adds partial segments.
+// It supports blocking playlist reload: a client can wait until a given media sequence number (and part) exists
+type LLHLSPlaylist struct {
+	mu       sync.Mutex
+	segments []llhlsSegment
+	parts    []llhlsPart // parts of the segment being built
+	nextPart string      // URI of the next part, advertised as preload hint
+	ended    bool
+
+	// Closed (and replaced) each time the playlist changes, to wake up blocked requests
+	changed chan struct{}
+}
+
+// Constructor method for LL-HLS playlist
+func NewLLHLSPlaylist() *LLHLSPlaylist {
+	return &LLHLSPlaylist{changed: make(chan struct{})}
+}
+
+// Helper method: wake up all blocked requests, must be called with the lock held
+func (playlist *LLHLSPlaylist) notify() {
+	close(playlist.changed)
+	playlist.changed = make(chan struct{})
+}
+
+// Method to add a partial segment to the segment being built
+func (playlist *LLHLSPlaylist) AddPart(uri string, duration float64, independent bool, nextPart string) {
+	playlist.mu.Lock()
+	defer playlist.mu.Unlock()
+	playlist.parts = append(playlist.parts, llhlsPart{URI: uri, Duration: duration, Independent: independent})
+	playlist.nextPart = nextPart
+	playlist.notify()
+}
+
+// Method to complete the segment being built, 'uri' is the full segment made of all its parts
+func (playlist *LLHLSPlaylist) CloseSegment(uri string) {
+	playlist.mu.Lock()
+	defer playlist.mu.Unlock()
+
+	segment := llhlsSegment{URI: uri, Parts: playlist.parts}
+	for _, part := range playlist.parts {
+		segment.Duration += part.Duration
+	}
+	playlist.segments = append(playlist.segments, segment)
+	playlist.parts = nil
+	playlist.notify()
+}
+
+// Method to mark the end of the stream, no more segment will be added
+func (playlist *LLHLSPlaylist) End() {
+	playlist.mu.Lock()
+	defer playlist.mu.Unlock()
+	playlist.ended = true
+	playlist.nextPart = ""
+	playlist.notify()
+}
+
+// Method to get the media sequence number of the segment being built
+func (playlist *LLHLSPlaylist) NextSequence() int {
+	playlist.mu.Lock()
+	defer playlist.mu.Unlock()
+	return len(playlist.segments)
+}
+
+// Method to block until the playlist contains the segment 'msn', or its part 'part' if part >= 0 (the
+// _HLS_msn and _HLS_part directives). It returns early if the stream ended, or ctx error if ctx is done first
+func (playlist *LLHLSPlaylist) Wait(ctx context.Context, msn, part int) error {
+	for {
+		playlist.mu.Lock()
+		ready := playlist.ended || len(playlist.segments) > msn ||
+			(part >= 0 && len(playlist.segments) == msn && len(playlist.parts) > part)
+		changed := playlist.changed
+		playlist.mu.Unlock()
+
+		if ready {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Method to render the playlist
+func (playlist *LLHLSPlaylist) Render() string {
+	playlist.mu.Lock()
+	defer playlist.mu.Unlock()
+
+	// Target duration is the longest segment rounded up
+	target := llhlsPartTarget * llhlsPartsPerSegment
+	for _, segment := range playlist.segments {
+		target = max(target, segment.Duration)
+	}
+
+	var out strings.Builder
+	out.WriteString("#EXTM3U\n")
+	out.WriteString("#EXT-X-VERSION:9\n")
+	out.WriteString(fmt.Sprintf("#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target))))
+	out.WriteString(fmt.Sprintf("#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.3f\n", llhlsPartHoldBack))
+	out.WriteString(fmt.Sprintf("#EXT-X-PART-INF:PART-TARGET=%.3f\n", llhlsPartTarget))
+	out.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	out.WriteString("#EXT-X-MAP:URI=\"init.mp4\"\n")
+
+	// Parts are only listed for the most recent segments, older segments are listed as a whole
+	for i, segment := range playlist.segments {
+		if i >= len(playlist.segments)-llhlsPartsKept {
+			writeParts(&out, segment.Parts)
+		}
+		out.WriteString(fmt.Sprintf("#EXTINF:%.3f,\n%s\n", segment.Duration, segment.URI))
+	}
+	writeParts(&out, playlist.parts)
+
+	if playlist.ended {
+		out.WriteString("#EXT-X-ENDLIST\n")
+	} else if playlist.nextPart != "" {
+		out.WriteString(fmt.Sprintf("#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s\"\n", playlist.nextPart))
+	}
+	return out.String()
+}
+
+// Helper function: write the EXT-X-PART tags of the parts
+func writeParts(out *strings.Builder, parts []llhlsPart) {
+	for _, part := range parts {
+		out.WriteString(fmt.Sprintf("#EXT-X-PART:DURATION=%.3f,URI=\"%s\"", part.Duration, part.URI))
+		if part.Independent {
+			out.WriteString(",INDEPENDENT=YES")
+		}
+		out.WriteString("\n")
+	}
+}
+
+// Method to package a video into LL-HLS in real time (for premieres), until the whole input is streamed or ctx
+// is cancelled. ffmpeg produces one fMP4 fragment per part (each starting with a keyframe), which are then
+// concatenated into full segments. 'outputDir' expects the live directory of the video
+func (service *MediaService) PackageLLHLS(ctx context.Context, input, outputDir string, playlist *LLHLSPlaylist) error {
+	/*
+	 * Command:
+	 * ffmpeg -re -i input.mp4 -c:v libx264 -preset veryfast -tune zerolatency -force_key_frames expr:gte(t,n_forced*1)
+	 * -c:a aac -b:a 128k -f hls -hls_time 1 -hls_list_size 0 -hls_segment_type fmp4 -hls_fmp4_init_filename init.mp4
+	 * -hls_segment_filename outputDir/part_%05d.m4s outputDir/ffmpeg.m3u8
+	 */
+
+	defer playlist.End()
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+
+	partTarget := strconv.FormatFloat(llhlsPartTarget, 'f', -1, 64)
+	args := []string{
+		"-re",
+		"-i", input,
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-tune", "zerolatency",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%s)", partTarget),
+	}
+	args = append(args, service.threadArgs()...)
+	args = append(args,
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-f", "hls",
+		"-hls_time", partTarget,
+		"-hls_list_size", "0",
+		"-hls_segment_type", "fmp4",
+		"-hls_fmp4_init_filename", "init.mp4",
+		"-hls_segment_filename", filepath.Join(outputDir, "part_%05d.m4s"),
+		"-y",
+		filepath.Join(outputDir, "ffmpeg.m3u8"),
+	)
+	cmd := service.ffmpeg(ctx, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	release := service.acquire()
+	defer release()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	packager := &llhlsPackager{dir: outputDir, playlist: playlist}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			// Pick up the last parts, then complete the last segment
+			if syncErr := packager.sync(); syncErr != nil {
+				return syncErr
+			}
+			if flushErr := packager.flush(); flushErr != nil {
+				return flushErr
+			}
+			if err != nil {
+				return fmt.Errorf("ffmpeg failed for LL-HLS packaging: %v\nOutput: %s", err, stderr.String())
+			}
+			return nil
+		case <-ticker.C:
+			if err := packager.sync(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Helper struct: turn the fragments written by ffmpeg into parts and segments of the LL-HLS playlist
+type llhlsPackager struct {
+	dir      string
+	playlist *LLHLSPlaylist
+	seen     int      // number of fragments already added as parts
+	pending  []string // parts of the segment being built
+}
+
+// Method to add the new fragments listed in the ffmpeg playlist as parts, and complete a segment every
+// llhlsPartsPerSegment parts
+func (packager *llhlsPackager) sync() error {
+	fragments, durations, err := readFragments(filepath.Join(packager.dir, "ffmpeg.m3u8"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // ffmpeg has not written anything yet
+		}
+		return err
+	}
+
+	for ; packager.seen < len(fragments); packager.seen++ {
+		uri := fragments[packager.seen]
+		next := fmt.Sprintf("part_%05d.m4s", packager.seen+1)
+		packager.playlist.AddPart(uri, durations[packager.seen], true, next)
+		packager.pending = append(packager.pending, uri)
+
+		if len(packager.pending) == llhlsPartsPerSegment {
+			if err := packager.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Method to concatenate the pending parts into a segment file and complete the segment
+func (packager *llhlsPackager) flush() error {
+	if len(packager.pending) == 0 {
+		return nil
+	}
+
+	uri := fmt.Sprintf("segment_%05d.m4s", packager.playlist.NextSequence())
+	segment, err := os.Create(filepath.Join(packager.dir, uri))
+	if err != nil {
+		return err
+	}
+	defer segment.Close()
+
+	for _, part := range packager.pending {
+		src, err := os.Open(filepath.Join(packager.dir, part))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(segment, src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	packager.playlist.CloseSegment(uri)
+	packager.pending = nil
+	return nil
+}
+
+// Helper function: read the fragment URIs and durations listed in a HLS media playlist
+func readFragments(playlist string) ([]string, []float64, error) {
+	f, err := os.Open(playlist)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var (
+		uris      []string
+		durations []float64
+		duration  float64
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if value, found := strings.CutPrefix(line, "#EXTINF:"); found {
+			value, _, _ = strings.Cut(value, ",")
+			duration, _ = strconv.ParseFloat(value, 64)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		uris = append(uris, line)
+		durations = append(durations, duration)
+	}
+	return uris, durations, scanner.Err()
+}
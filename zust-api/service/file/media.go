@@ -1,11 +1,19 @@
 package file
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 	"zust/service/security"
 )
 
@@ -14,15 +22,124 @@ type MediaService struct {
 	Domain       string
 	Port         string
 	ResourcePath string
+
+	// Upload limits
+	MaxDuration        int // second, 0 means no limit
+	AllowedContainers  []string
+	AllowedVideoCodecs []string
+	AllowedAudioCodecs []string
+
+	// Transcoding codecs
+	Codecs     []VideoCodec
+	AV1Encoder string
+
+	// Hardware acceleration of H.264 encoding, hwEncoder is the ffmpeg encoder if it's detected as usable
+	HWAccel     string
+	VAAPIDevice string
+	hwEncoder   string
+
+	// Watermark overlaid on the videos of channels without their own watermark, nil if not configured
+	Watermark *Watermark
+
+	// EBU R128 loudness normalization, disabled if Loudnorm is empty. For example: I=-16:TP=-1.5:LRA=11
+	Loudnorm string
+
+	// Paths of ffmpeg and ffprobe, resolved with their versions by DetectFFmpeg
+	FFmpegPath     string
+	FFprobePath    string
+	FFmpegVersion  string
+	FFprobeVersion string
+
+	// Whether ffmpeg can encode WebP, for transformed images
+	WebPSupported bool
+
+	// Resource limits of ffmpeg: threads per process (0 means ffmpeg decides) and niceness (0 means unchanged).
+	// slots bounds the number of concurrent ffmpeg processes, nil means no limit
+	FFmpegThreads int
+	FFmpegNice    int
+	slots         chan struct{}
+
+	// Transcoding ladder, ordered from the highest to the lowest resolution
+	Ladder []ResolutionConfig
+
+	// CDN serving the media links, empty if media is served by the server itself. Links are signed with a token
+	// valid for CDNTokenTTL if CDNSigningKey is not empty
+	CDNURL        string
+	CDNSigningKey string
+	CDNTokenTTL   time.Duration
 }
 
 // Constructor method for media service struct
 func NewMediaService(config *security.Config) *MediaService {
+	var watermark *Watermark
+	if config.WatermarkImage != "" {
+		watermark = &Watermark{
+			Image:    config.WatermarkImage,
+			Position: config.WatermarkPosition,
+			Opacity:  config.WatermarkOpacity,
+		}
+	}
+
+	// Codecs are validated when the config is loaded
+	codecs := make([]VideoCodec, 0, len(config.TranscodeCodecs))
+	for _, name := range config.TranscodeCodecs {
+		codecs = append(codecs, VideoCodec(name))
+	}
+
+	var slots chan struct{}
+	if config.FFmpegMaxProcesses > 0 {
+		slots = make(chan struct{}, config.FFmpegMaxProcesses)
+	}
+
 	return &MediaService{
-		Domain:       config.Domain,
-		Port:         config.Port,
-		ResourcePath: config.ResourcePath,
+		Domain:             config.Domain,
+		Port:               config.Port,
+		ResourcePath:       config.ResourcePath,
+		MaxDuration:        config.MaxVideoDuration,
+		AllowedContainers:  config.AllowedContainers,
+		AllowedVideoCodecs: config.AllowedVideoCodecs,
+		AllowedAudioCodecs: config.AllowedAudioCodecs,
+		Codecs:             codecs,
+		AV1Encoder:         config.AV1Encoder,
+		HWAccel:            config.HWAccel,
+		VAAPIDevice:        config.VAAPIDevice,
+		Watermark:          watermark,
+		Loudnorm:           config.Loudnorm,
+		Ladder:             loadLadder(config.Ladder),
+		FFmpegPath:         config.FFmpegPath,
+		FFprobePath:        config.FFprobePath,
+		FFmpegThreads:      config.FFmpegThreads,
+		FFmpegNice:         config.FFmpegNice,
+		slots:              slots,
+		CDNURL:             config.CDNURL,
+		CDNSigningKey:      config.CDNSigningKey,
+		CDNTokenTTL:        config.CDNTokenTTL,
+	}
+}
+
+// Helper function: convert the ladder of the config into resolution configs, or use DefaultLadder if the config
+// doesn't define one. The rungs are validated and ordered when the config is loaded
+func loadLadder(rungs []security.LadderRung) []ResolutionConfig {
+	if len(rungs) == 0 {
+		return DefaultLadder
 	}
+
+	ladder := make([]ResolutionConfig, 0, len(rungs))
+	for _, rung := range rungs {
+		presets := CodecCRF{
+			H264: strconv.Itoa(rung.CRF.H264),
+			VP9:  strconv.Itoa(rung.CRF.VP9),
+			AV1:  strconv.Itoa(rung.CRF.AV1),
+		}
+		ladder = append(ladder, ResolutionConfig{
+			Resolution:   rung.Resolution,
+			CRF:          presets.H264,
+			Presets:      presets,
+			AudiobitRate: rung.AudioBitrate,
+			FPSCap:       rung.FPSCap,
+		})
+	}
+	return ladder
 }
 
 // File type for accssing media resource in user repository
@@ -33,6 +150,8 @@ var (
 	Cover     FileType = "cover"
 	Video     FileType = "resource"
 	Thumbnail FileType = "thumbnail"
+	HLS       FileType = "hls" // directory of the HLS packaging of a video, filename is the video ID
+	Subtitles FileType = "subtitles"
 )
 
 // Method to generate the URL for accessing media in user repository.
@@ -51,25 +170,44 @@ func (service *MediaService) GenerateMediaLink(accountID, filename string, fileT
 	}
 
 	id := security.Encode(fmt.Sprintf("%s:%s:%s", accountID, fileType, filename))
-	return fmt.Sprintf("%s:%s/media/%s", service.Domain, service.Port, id)
+	return service.mediaURL(id, "")
 }
 
-// Method to extract the full file path from ID generated from the GenerateMediaLink
-func (service *MediaService) ExtractFilePath(opaqueID string) string {
+// Method to generate the URL of the HLS master playlist of a video. Media playlists and segments are referenced
+// relatively, so they are served under the same prefix
+func (service *MediaService) GenerateHLSLink(accountID, videoID string) string {
+	id := security.Encode(fmt.Sprintf("%s:%s:%s", accountID, HLS, videoID))
+	return service.mediaURL(id, HLSMasterPlaylist)
+}
+
+// Method to generate the URL of the DASH manifest of a video, which shares the directory of HLS
+func (service *MediaService) GenerateDASHLink(accountID, videoID string) string {
+	id := security.Encode(fmt.Sprintf("%s:%s:%s", accountID, HLS, videoID))
+	return service.mediaURL(id, DASHManifest)
+}
+
+// Method to generate the URL of the LL-HLS playlist of a premiere
+func (service *MediaService) GenerateLiveLink(videoID string) string {
+	return fmt.Sprintf("%s:%s/live/%s/%s", service.Domain, service.Port, videoID, HLSPlaylist)
+}
+
+// Method to extract the storage key (the slash separated path relative to the resource path) from ID generated
+// from the GenerateMediaLink. It returns an empty string if the ID is malformed
+func (service *MediaService) ExtractFileKey(opaqueID string) string {
 	// Split the ID after decoding
 	paths := strings.Split(security.Decode(opaqueID), ":")
-
-	// base = resource path + account_id
-	base := filepath.Join(service.ResourcePath, paths[0])
+	if len(paths) != 3 {
+		return ""
+	}
 
 	// If this is avatar or cover, we skip the second element of paths, since avatar and cover are not located
 	// under sub dirirectory
 	if paths[1] == "avatar" || paths[1] == "cover" {
-		return filepath.Join(base, paths[2])
+		return path.Join(paths[0], paths[2])
 	}
 
-	// Otherwise, we use both elements in 'paths' to reconstruct the full file path
-	return filepath.Join(base, paths[1], paths[2])
+	// Otherwise, we use both elements in 'paths' to reconstruct the key
+	return path.Join(paths[0], paths[1], paths[2])
 }
 
 // Helper method: get video duration. 'input' expects a full path to where the video located
@@ -80,7 +218,7 @@ func (service *MediaService) GetVideoDuration(input string) (int32, error) {
 	 */
 
 	// Execute command
-	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration",
+	cmd := exec.Command(service.FFprobePath, "-v", "error", "-show_entries", "format=duration",
 		"-of", "default=noprint_wrappers=1:nokey=1", input)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
@@ -96,6 +234,67 @@ func (service *MediaService) GetVideoDuration(input string) (int32, error) {
 	return int32(duration), nil
 }
 
+// Helper method: sample 'count' frames evenly spread across the video and save them as PNG into 'outputDir'.
+// 'input' expects a full file path, 'duration' is the video duration in seconds.
+// It returns the full file paths of the extracted frames
+func (service *MediaService) ExtractFrames(ctx context.Context, input, outputDir string, count int,
+	duration int32) ([]string, error) {
+	/*
+	 * Command (for each frame):
+	 * ffmpeg -ss timestamp -i input.mp4 -frames:v 1 frame_1.png
+	 */
+
+	if count <= 0 {
+		return nil, fmt.Errorf("frame count must be positive")
+	}
+
+	frames := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		// Sample at i/(count+1) of the video so we never hit the very first or last frame
+		timestamp := float64(duration) * float64(i) / float64(count+1)
+		output := filepath.Join(outputDir, fmt.Sprintf("frame_%d.png", i))
+
+		cmd := service.ffmpeg(ctx,
+			"-ss", strconv.FormatFloat(timestamp, 'f', 2, 64),
+			"-i", input,
+			"-frames:v", "1",
+			"-y",
+			output,
+		)
+		out, err := service.run(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg failed for extracting frame: %v\nOutput: %s", err, string(out))
+		}
+		frames = append(frames, output)
+	}
+
+	return frames, nil
+}
+
+// Method to extract the audio of a video into a 16kHz mono WAV, the format expected by speech-to-text models.
+// Both 'input' and 'output' expect to be a full file path
+func (service *MediaService) ExtractAudio(ctx context.Context, input, output string) error {
+	/*
+	 * Command:
+	 * ffmpeg -i input.mp4 -vn -ac 1 -ar 16000 -c:a pcm_s16le output.wav
+	 */
+
+	cmd := service.ffmpeg(ctx,
+		"-i", input,
+		"-vn",
+		"-ac", "1",
+		"-ar", "16000",
+		"-c:a", "pcm_s16le",
+		"-y",
+		output,
+	)
+	out, err := service.run(cmd)
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed for extracting audio: %v\nOutput: %s", err, string(out))
+	}
+	return nil
+}
+
 // Helper method: transcode video into suitable for web progressive streaming.
 // Both 'input' and 'output' expect to be a full file path
 func TranscodeVideo(input, output string) error {
@@ -126,88 +325,379 @@ func TranscodeVideo(input, output string) error {
 // Video resolution config for transcoding
 type ResolutionConfig struct {
 	Resolution   string
-	CRF          string
+	Codec        VideoCodec // empty means H.264
+	CRF          string     // CRF of the codec, set from Presets by WithCodec
+	Presets      CodecCRF
 	AudiobitRate string
+	FPSCap       float64 // maximum frame rate of the rung, 0 means the frame rate of the source
 }
 
 var (
+	Resolution2160p = ResolutionConfig{
+		Resolution:   "3840:2160",
+		CRF:          "21",
+		Presets:      CodecCRF{H264: "21", VP9: "24", AV1: "24"},
+		AudiobitRate: "192k",
+	}
+
+	Resolution1440p = ResolutionConfig{
+		Resolution:   "2560:1440",
+		CRF:          "22",
+		Presets:      CodecCRF{H264: "22", VP9: "28", AV1: "27"},
+		AudiobitRate: "192k",
+	}
+
 	Resolution1080p = ResolutionConfig{
 		Resolution:   "1920:1080",
 		CRF:          "23",
+		Presets:      CodecCRF{H264: "23", VP9: "31", AV1: "30"},
 		AudiobitRate: "128k",
 	}
 
 	Resolution720p = ResolutionConfig{
 		Resolution:   "1280:720",
 		CRF:          "26",
+		Presets:      CodecCRF{H264: "26", VP9: "32", AV1: "32"},
 		AudiobitRate: "128k",
 	}
 
 	Resolution480p = ResolutionConfig{
 		Resolution:   "854:480",
 		CRF:          "28",
+		Presets:      CodecCRF{H264: "28", VP9: "34", AV1: "34"},
 		AudiobitRate: "96k",
 	}
+
+	Resolution360p = ResolutionConfig{
+		Resolution:   "640:360",
+		CRF:          "29",
+		Presets:      CodecCRF{H264: "29", VP9: "36", AV1: "36"},
+		AudiobitRate: "96k",
+	}
+
+	Resolution240p = ResolutionConfig{
+		Resolution:   "426:240",
+		CRF:          "30",
+		Presets:      CodecCRF{H264: "30", VP9: "37", AV1: "38"},
+		AudiobitRate: "64k",
+	}
+
+	Resolution144p = ResolutionConfig{
+		Resolution:   "256:144",
+		CRF:          "31",
+		Presets:      CodecCRF{H264: "31", VP9: "38", AV1: "40"},
+		AudiobitRate: "48k",
+	}
+
+	// All supported resolutions, from 144p to 4K, ordered from the highest to the lowest resolution
+	AllResolutions = []ResolutionConfig{
+		Resolution2160p, Resolution1440p, Resolution1080p, Resolution720p,
+		Resolution480p, Resolution360p, Resolution240p, Resolution144p,
+	}
+
+	// Default ladder, ordered from the highest to the lowest resolution
+	DefaultLadder = []ResolutionConfig{Resolution1080p, Resolution720p, Resolution480p}
+)
+
+// Method to get the height of the resolution config, for example: 1920:1080 -> 1080
+func (res ResolutionConfig) Height() int {
+	_, height, _ := strings.Cut(res.Resolution, ":")
+	h, _ := strconv.Atoi(height)
+	return h
+}
+
+// Method to get the name of the resolution config, for example: 1920:1080 -> 1080p.
+// The codec is appended for codecs other than H.264, for example: 1080p_vp9
+func (res ResolutionConfig) Name() string {
+	return RenditionName(fmt.Sprintf("%dp", res.Height()), res.Codec)
+}
+
+// Method to get a copy of the resolution config encoded with 'codec', using the CRF preset of the codec
+func (res ResolutionConfig) WithCodec(codec VideoCodec) ResolutionConfig {
+	res.Codec = codec
+	if crf := res.Presets.Get(codec); crf != "" {
+		res.CRF = crf
+	}
+	return res
+}
+
+// Helper function: expand the rungs of the ladder into one rung per codec, codecs are listed in the given order
+func WithCodecs(rungs []ResolutionConfig, codecs []VideoCodec) []ResolutionConfig {
+	if len(codecs) == 0 {
+		return rungs
+	}
+
+	expanded := make([]ResolutionConfig, 0, len(rungs)*len(codecs))
+	for _, codec := range codecs {
+		for _, res := range rungs {
+			expanded = append(expanded, res.WithCodec(codec))
+		}
+	}
+	return expanded
+}
+
+// Bits per pixel per frame thresholds to classify source complexity
+const (
+	lowComplexityBPP  = 0.05
+	highComplexityBPP = 0.15
 )
 
-// Helper method: transcode video into suitable web progressive streaming with multiple resolutions.
-// 'input' expects a full file path.
-// resolutions expects the key to be the ResolutionConfig constants, while the value to be the output full file path
-func (service *MediaService) MultiResolution(input string, resolutions map[ResolutionConfig]string) error {
+// Method to choose the rungs of the ladder and their CRF based on the source video:
+// rungs above the source resolution are skipped (upscaling only wastes storage), and the CRF is raised for
+// simple content (slides, animation) and lowered for complex content (sports, grain) so each title gets
+// roughly the same perceived quality. 'ladder' is ordered from the highest to the lowest resolution
+func (service *MediaService) PerTitleLadder(info *MediaInfo, ladder []ResolutionConfig) []ResolutionConfig {
+	// Estimate complexity as bits per pixel per frame of the source
+	crfDelta := 0
+	if info.Width > 0 && info.Height > 0 && info.FPS > 0 && info.Bitrate > 0 {
+		bpp := float64(info.Bitrate) / (float64(info.Width*info.Height) * info.FPS)
+		switch {
+		case bpp < lowComplexityBPP:
+			crfDelta = 2
+		case bpp > highComplexityBPP:
+			crfDelta = -2
+		}
+	}
+
+	rungs := make([]ResolutionConfig, 0, len(ladder))
+	for _, res := range ladder {
+		if info.Height > 0 && res.Height() > info.Height {
+			continue
+		}
+		rungs = append(rungs, res.adjust(crfDelta))
+	}
+
+	// Source is smaller than every rung, still produce the lowest one so the video is playable
+	if len(rungs) == 0 && len(ladder) > 0 {
+		rungs = append(rungs, ladder[len(ladder)-1].adjust(crfDelta))
+	}
+
+	return rungs
+}
+
+// Helper method: shift the CRF and the CRF presets of every codec by 'delta'
+func (res ResolutionConfig) adjust(delta int) ResolutionConfig {
+	codec := res.Codec
+	if codec == "" {
+		codec = CodecH264
+	}
+	res.CRF = adjustCRF(res.CRF, delta, codec)
+	res.Presets = CodecCRF{
+		H264: adjustCRF(res.Presets.H264, delta, CodecH264),
+		VP9:  adjustCRF(res.Presets.VP9, delta, CodecVP9),
+		AV1:  adjustCRF(res.Presets.AV1, delta, CodecAV1),
+	}
+	return res
+}
+
+// Helper function: shift the CRF value, clamped into the sane range of the codec, for example: [18, 35] for libx264
+func adjustCRF(crf string, delta int, codec VideoCodec) string {
+	value, err := strconv.Atoi(crf)
+	if err != nil {
+		return crf
+	}
+	bounds := crfRanges[codec]
+	value = min(max(value+delta, bounds[0]), bounds[1])
+	return strconv.Itoa(value)
+}
+
+// Helper method: transcode video into suitable web progressive streaming with multiple resolutions, in a single
+// ffmpeg run: the source is decoded once, then split and scaled for each rung.
+// 'input' expects a full file path, 'info' the probed input: rungs above the source resolution are skipped
+// (upscaling only wastes storage) and its duration is used to compute the percentage reported to 'progress',
+// which can be nil.
+// resolutions expects the key to be the rung, while the value to be the output full file path.
+// 'watermark' is overlaid on the video before scaling, nil means no watermark.
+// ffmpeg is killed when ctx is done (the transcode job is cancelled or timed out).
+// It returns the rungs transcoded, ordered from the highest to the lowest resolution
+func (service *MediaService) MultiResolution(ctx context.Context, input string,
+	resolutions map[ResolutionConfig]string, watermark *Watermark, info *MediaInfo,
+	progress ProgressFunc) ([]ResolutionConfig, error) {
 	/*
 	 * Multi-resolution with progressive streaming
 	 * Command:
 	 * ffmpeg -i filename.mp4
-	 * -filter_complex "[0:v]split=3[v1][v2][v3]; [v1]scale=854:480[v1out]; [v2]scale=1280:720[v2out]; [v3]scale=1920:1080[v3out]"
-	 * -map "[v1out]" -map 0:a -c:v libx264 -preset fast -crf 28 -c:a aac -b:a 96k -movflags +faststart filename_480p.mp4
-	 * -map "[v2out]" -map 0:a -c:v libx264 -preset fast -crf 26 -c:a aac -b:a 128k -movflags +faststart filename_720p.mp4
-	 * -map "[v3out]" -map 0:a -c:v libx264 -preset fast -crf 23 -c:a aac -b:a 128k -movflags +faststart filename_1080p.mp4
+	 * -filter_complex [0:v]split=3[v0][v1][v2];[v0]scale=-2:1080[out0];[v1]scale=-2:720[out1];[v2]scale=-2:480[out2]
+	 * -map [out0] -map 0:a? -c:v libx264 -preset fast -crf 23 -c:a aac -b:a 128k -movflags +faststart filename_1080p.mp4
+	 * -map [out1] -map 0:a? -c:v libx264 -preset fast -crf 26 -c:a aac -b:a 128k -movflags +faststart filename_720p.mp4
+	 * -map [out2] -map 0:a? -c:v libx264 -preset fast -crf 28 -c:a aac -b:a 96k -movflags +faststart filename_480p.mp4
 	 */
 
-	// Build the filter complex argument
-	var (
-		filter strings.Builder
-		i      = 1
-	)
+	rungs := sourceRungs(resolutions, info.Height)
+	if len(rungs) == 0 {
+		return nil, fmt.Errorf("no resolution to transcode")
+	}
+
+	// Transcode into temporary files, renamed once every rendition is complete, so a crashed transcode never leaves
+	// a truncated rendition to serve
+	outputs := make(map[ResolutionConfig]string, len(rungs))
+	for _, res := range rungs {
+		outputs[res] = TempPath(resolutions[res])
+		defer os.Remove(outputs[res])
+	}
 
-	filter.WriteString(fmt.Sprintf("\"[0:v]split=%d", len(resolutions)))
+	// Use the hardware encoder if it's detected, and fall back to libx264 if it fails in the middle
+	// (for example: the GPU runs out of memory or sessions)
+	hw := service.HWAccelEnabled()
+	err := service.runWithProgress(ctx, service.multiResolutionArgs(input, rungs, outputs, watermark, info.FPS, hw),
+		info.Duration, progress)
+	if err != nil && hw {
+		err = service.runWithProgress(ctx, service.multiResolutionArgs(input, rungs, outputs, watermark, info.FPS, false),
+			info.Duration, progress)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg failed for multi-resolution transcoding: %w", err)
+	}
 
-	for i < len(resolutions) {
-		filter.WriteString(fmt.Sprintf("[v%d]", i))
-		i++
+	for _, res := range rungs {
+		if err := os.Rename(outputs[res], resolutions[res]); err != nil {
+			return nil, err
+		}
 	}
-	i = 1
+	return rungs, nil
+}
 
+// Helper function: get the rungs at or below the source height (0 if unknown), ordered from the highest to the
+// lowest resolution then by codec, so the same rungs always produce the same command line.
+// If the source is smaller than every rung, the lowest rungs are still kept so the video is playable
+func sourceRungs(resolutions map[ResolutionConfig]string, sourceHeight int) []ResolutionConfig {
+	rungs := make([]ResolutionConfig, 0, len(resolutions))
+	lowest := 0
 	for res := range resolutions {
-		filter.WriteString(fmt.Sprintf("; [v%d]scale=%s[v%dout]", i, res.Resolution, i))
-		i++
-	}
-	i = 1
-	filter.WriteString("\"")
-
-	// Create command arguments and add initial value: input and filter_complex
-	args := []string{"-i", input, "-filter_complex", filter.String()}
-
-	// Build the rest of the arguments for each resolution
-	for res, output := range resolutions {
-		args = append(args,
-			"-map", fmt.Sprintf("\"[v%dout]\"", i),
-			"-map", "0;a",
-			"-c:v", "libx264",
-			"-preset", "fast",
-			"-crf", res.CRF,
-			"-c:a", "aac",
-			"-b:a", res.AudiobitRate,
-			"-movflags", "+faststart",
-			output,
-		)
+		if sourceHeight <= 0 || res.Height() <= sourceHeight {
+			rungs = append(rungs, res)
+		}
+		if lowest == 0 || res.Height() < lowest {
+			lowest = res.Height()
+		}
 	}
 
-	// Create command and execute it
-	cmd := exec.Command("ffmpeg", args...)
-	out, err := cmd.CombinedOutput()
+	if len(rungs) == 0 {
+		for res := range resolutions {
+			if res.Height() == lowest {
+				rungs = append(rungs, res)
+			}
+		}
+	}
+
+	slices.SortFunc(rungs, func(a, b ResolutionConfig) int {
+		if a.Height() != b.Height() {
+			return b.Height() - a.Height()
+		}
+		return strings.Compare(string(a.Codec), string(b.Codec))
+	})
+	return rungs
+}
+
+// Helper method: build the ffmpeg arguments of MultiResolution. 'rungs' are the rungs to transcode and 'outputs'
+// their output paths. 'sourceFPS' is the frame rate of the source (0 if unknown), rungs with a lower FPS cap are
+// resampled. If 'hw' is true, H.264 rungs are encoded by the hardware encoder
+func (service *MediaService) multiResolutionArgs(input string, rungs []ResolutionConfig,
+	outputs map[ResolutionConfig]string, watermark *Watermark, sourceFPS float64, hw bool) []string {
+	var args ffmpegArgs
+	src := args.Input(input, service.hwInputArgs(hw)...)
+
+	// The watermark is overlaid once on the source, so it's scaled down with the video for each resolution
+	source := fmt.Sprintf("[%d:v]", src)
+	if watermark != nil {
+		image := args.Input(watermark.Image)
+		args.Filter(watermark.filter(source, image, "[src]"))
+		source = "[src]"
+	}
+
+	// Decode once, then split the stream for each rung
+	var split strings.Builder
+	for i := range rungs {
+		split.WriteString(pad("v", i))
+	}
+	args.Filter(fmt.Sprintf("%ssplit=%d%s", source, len(rungs), split.String()))
+
+	for i, res := range rungs {
+		// Only H.264 rungs are hardware encoded, their frames are uploaded to the device after scaling
+		rungHW := hw && (res.Codec == "" || res.Codec == CodecH264)
+
+		// Scale by height and keep the aspect ratio of the source (the width must be even)
+		fps := ""
+		if res.FPSCap > 0 && (sourceFPS <= 0 || sourceFPS > res.FPSCap) {
+			fps = ",fps=" + strconv.FormatFloat(res.FPSCap, 'f', -1, 64)
+		}
+		args.Filter(fmt.Sprintf("%sscale=-2:%d%s%s%s",
+			pad("v", i), res.Height(), fps, service.hwFilter(rungHW), pad("out", i)))
+
+		opts := []string{
+			"-map", pad("out", i),
+			"-map", fmt.Sprintf("%d:a?", src), // audio is optional, the source may not have any audio stream
+		}
+		opts = append(opts, service.encoderArgs(res, rungHW)...)
+		opts = append(opts, service.threadArgs()...)
+		opts = append(opts, service.audioArgs(res)...)
+		opts = append(opts, "-movflags", "+faststart", "-f", "mp4") // the temporary file has no video extension
+		args.Output(outputs[res], opts...)
+	}
+
+	return args.Build()
+}
+
+// Helper method: get the audio arguments of a rung. If loudness normalization is enabled, the loudnorm filter is
+// applied in a single pass; it upsamples to 192kHz internally so the sample rate is set back to 48kHz
+func (service *MediaService) audioArgs(res ResolutionConfig) []string {
+	args := []string{"-c:a", "aac", "-b:a", res.AudiobitRate}
+	if service.Loudnorm != "" {
+		args = append(args, "-af", "loudnorm="+service.Loudnorm, "-ar", "48000")
+	}
+	return args
+}
+
+// Callback receiving the percentage (0-100) of an ffmpeg process
+type ProgressFunc func(percent int)
+
+// Helper method: run ffmpeg with '-progress pipe:1' and report the percentage to 'progress' each time it changes.
+// 'duration' (second) is the input duration, the progress is not reported if it's unknown.
+// ffmpeg is killed when ctx is done. The error returned includes the ffmpeg stderr
+func (service *MediaService) runWithProgress(ctx context.Context, args []string, duration float64,
+	progress ProgressFunc) error {
+	/*
+	 * ffmpeg writes blocks of key=value lines into stdout, for example:
+	 * out_time_us=12345678
+	 * ...
+	 * progress=continue (or progress=end for the last block)
+	 */
+	cmd := service.ffmpeg(ctx, append([]string{"-progress", "pipe:1", "-nostats"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("ffmpeg failed for multi-resolution transcoding: %v\nOutput: %s", err, string(out))
+		return err
+	}
+	release := service.acquire()
+	defer release()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	last := -1
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		if progress == nil || duration <= 0 || key != "out_time_us" {
+			continue
+		}
+
+		us, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue // ffmpeg reports N/A before the first frame
+		}
+		percent := min(int(float64(us)/1e6*100/duration), 100)
+		if percent > last {
+			last = percent
+			progress(percent)
+		}
+	}
+	// Drain the rest so ffmpeg is never blocked on a full pipe
+	io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%v\nOutput: %s", err, stderr.String())
 	}
 	return nil
 }
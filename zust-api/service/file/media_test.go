@@ -0,0 +1,141 @@
+package file
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSourceRungs(t *testing.T) {
+	vp9 := func(res ResolutionConfig) ResolutionConfig { return res.WithCodec(CodecVP9) }
+
+	tests := []struct {
+		name         string
+		rungs        []ResolutionConfig
+		sourceHeight int
+		want         []string
+	}{
+		{name: "above source dropped", rungs: []ResolutionConfig{Resolution1080p, Resolution720p, Resolution480p},
+			sourceHeight: 720, want: []string{"720p", "480p"}},
+		{name: "just below a rung", rungs: []ResolutionConfig{Resolution1080p, Resolution720p, Resolution480p},
+			sourceHeight: 719, want: []string{"480p"}},
+		{name: "sorted by height then codec",
+			rungs:        []ResolutionConfig{vp9(Resolution720p), Resolution720p, vp9(Resolution1080p), Resolution1080p},
+			sourceHeight: 1080, want: []string{"1080p", "1080p_vp9", "720p", "720p_vp9"}},
+		{name: "source below all rungs", rungs: []ResolutionConfig{Resolution480p, Resolution360p, vp9(Resolution360p)},
+			sourceHeight: 100, want: []string{"360p", "360p_vp9"}},
+		{name: "unknown source height", rungs: []ResolutionConfig{Resolution720p, Resolution2160p},
+			sourceHeight: 0, want: []string{"2160p", "720p"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolutions := make(map[ResolutionConfig]string, len(test.rungs))
+			for _, res := range test.rungs {
+				resolutions[res] = res.Name() + ".mp4"
+			}
+
+			var got []string
+			for _, res := range sourceRungs(resolutions, test.sourceHeight) {
+				got = append(got, res.Name())
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("sourceRungs() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMultiResolutionArgs(t *testing.T) {
+	capped := Resolution480p
+	capped.FPSCap = 30
+	vp9 := Resolution720p.WithCodec(CodecVP9)
+	outputs := map[ResolutionConfig]string{
+		Resolution720p: "out_720p",
+		Resolution480p: "out_480p",
+		capped:         "out_480p",
+		vp9:            "out_720p_vp9",
+	}
+	watermark := &Watermark{Image: "wm.png", Position: "top-left", Opacity: 0.5}
+
+	// Output options of a rung, after its maps
+	x264 := func(crf, audio string) string {
+		return "-c:v libx264 -preset fast -crf " + crf + " -c:a aac -b:a " + audio + " -movflags +faststart -f mp4"
+	}
+
+	tests := []struct {
+		name      string
+		service   *MediaService
+		rungs     []ResolutionConfig
+		watermark *Watermark
+		sourceFPS float64
+		hw        bool
+		want      string
+	}{
+		{
+			name:    "software",
+			service: &MediaService{},
+			rungs:   []ResolutionConfig{Resolution720p, Resolution480p},
+			want: "-i in.mp4 -filter_complex [0:v]split=2[v0][v1];[v0]scale=-2:720[out0];[v1]scale=-2:480[out1] " +
+				"-map [out0] -map 0:a? " + x264("26", "128k") + " -y out_720p " +
+				"-map [out1] -map 0:a? " + x264("28", "96k") + " -y out_480p",
+		},
+		{
+			name:      "watermark",
+			service:   &MediaService{},
+			rungs:     []ResolutionConfig{Resolution720p},
+			watermark: watermark,
+			want: "-i in.mp4 -i wm.png -filter_complex " +
+				"[1:v]format=rgba,colorchannelmixer=aa=0.50[wm];[0:v][wm]overlay=10:10[src];" +
+				"[src]split=1[v0];[v0]scale=-2:720[out0] " +
+				"-map [out0] -map 0:a? " + x264("26", "128k") + " -y out_720p",
+		},
+		{
+			name:      "fps cap",
+			service:   &MediaService{FFmpegThreads: 2},
+			rungs:     []ResolutionConfig{capped},
+			sourceFPS: 60,
+			want: "-i in.mp4 -filter_complex [0:v]split=1[v0];[v0]scale=-2:480,fps=30[out0] " +
+				"-map [out0] -map 0:a? -c:v libx264 -preset fast -crf 28 -threads 2 -c:a aac -b:a 96k " +
+				"-movflags +faststart -f mp4 -y out_480p",
+		},
+		{
+			name:    "vaapi",
+			service: &MediaService{HWAccel: HWAccelVAAPI, VAAPIDevice: "/dev/dri/renderD128", hwEncoder: "h264_vaapi"},
+			rungs:   []ResolutionConfig{Resolution720p, vp9},
+			hw:      true,
+			want: "-vaapi_device /dev/dri/renderD128 -i in.mp4 -filter_complex " +
+				"[0:v]split=2[v0][v1];[v0]scale=-2:720,format=nv12,hwupload[out0];[v1]scale=-2:720[out1] " +
+				"-map [out0] -map 0:a? -c:v h264_vaapi -rc_mode CQP -qp 24 -c:a aac -b:a 128k " +
+				"-movflags +faststart -f mp4 -y out_720p " +
+				"-map [out1] -map 0:a? -c:v libvpx-vp9 -crf 32 -b:v 0 -deadline good -cpu-used 2 -row-mt 1 " +
+				"-c:a aac -b:a 128k -movflags +faststart -f mp4 -y out_720p_vp9",
+		},
+		{
+			name:    "nvenc",
+			service: &MediaService{HWAccel: HWAccelNVENC, hwEncoder: "h264_nvenc"},
+			rungs:   []ResolutionConfig{Resolution720p},
+			hw:      true,
+			want: "-i in.mp4 -filter_complex [0:v]split=1[v0];[v0]scale=-2:720[out0] " +
+				"-map [out0] -map 0:a? -c:v h264_nvenc -preset p4 -rc vbr -cq 24 -b:v 0 -c:a aac -b:a 128k " +
+				"-movflags +faststart -f mp4 -y out_720p",
+		},
+		{
+			name:    "hardware fallback",
+			service: &MediaService{HWAccel: HWAccelVAAPI, VAAPIDevice: "/dev/dri/renderD128", hwEncoder: "h264_vaapi"},
+			rungs:   []ResolutionConfig{Resolution720p},
+			want: "-i in.mp4 -filter_complex [0:v]split=1[v0];[v0]scale=-2:720[out0] " +
+				"-map [out0] -map 0:a? " + x264("26", "128k") + " -y out_720p",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := test.service.multiResolutionArgs("in.mp4", test.rungs, outputs, test.watermark, test.sourceFPS,
+				test.hw)
+			if got := strings.Join(args, " "); got != test.want {
+				t.Errorf("multiResolutionArgs() =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Journal of a storage migration: the keys already copied with their checksum, so an interrupted migration resumes
+// where it stopped. It's persisted as lines of '{sha256} {key}' appended to a file
+type MigrationJournal struct {
+	file *os.File
+	done map[string]string
+}
+
+// Constructor method for migration journal, the keys recorded by a previous run of the migration are loaded
+func OpenMigrationJournal(path string) (*MigrationJournal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	journal := &MigrationJournal{file: file, done: make(map[string]string)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		checksum, key, found := strings.Cut(scanner.Text(), " ")
+		if found {
+			journal.done[key] = checksum
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return journal, nil
+}
+
+// Method to record a copied file
+func (journal *MigrationJournal) record(key, checksum string) error {
+	if _, err := fmt.Fprintf(journal.file, "%s %s\n", checksum, key); err != nil {
+		return err
+	}
+	journal.done[key] = checksum
+	return nil
+}
+
+// Method to close the journal file
+func (journal *MigrationJournal) Close() error {
+	return journal.file.Close()
+}
+
+// Progress of a storage migration
+type MigrationProgress struct {
+	Total   int   // files to migrate
+	Copied  int   // files copied by this run
+	Skipped int   // files already copied by a previous run
+	Bytes   int64 // bytes copied by this run
+}
+
+// Function to copy every file of 'src' into 'dst', for example: from the local storage to an object storage. Each
+// copy is verified by comparing the SHA-256 of the source with the one read back from the destination, then
+// recorded in the journal. Files recorded by a previous run and present in the destination with the same size are
+// skipped. Temporary files and the cache of transformed images are not migrated. 'progress' (can be nil) is called
+// after each file
+func MigrateStorage(ctx context.Context, src, dst Storage, journal *MigrationJournal,
+	progress func(MigrationProgress)) (MigrationProgress, error) {
+	files, err := src.List("")
+	if err != nil {
+		return MigrationProgress{}, err
+	}
+
+	var state MigrationProgress
+	for _, info := range files {
+		if strings.Contains(info.Key, TempMarker) || strings.HasPrefix(info.Key, ".cache/") {
+			continue
+		}
+		state.Total++
+	}
+
+	for _, info := range files {
+		if strings.Contains(info.Key, TempMarker) || strings.HasPrefix(info.Key, ".cache/") {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return state, err
+		}
+
+		if _, done := journal.done[info.Key]; done {
+			if copied, err := dst.Stat(info.Key); err == nil && copied.Size == info.Size {
+				state.Skipped++
+				if progress != nil {
+					progress(state)
+				}
+				continue
+			}
+		}
+
+		checksum, err := copyVerified(src, dst, info.Key)
+		if err != nil {
+			return state, fmt.Errorf("failed to migrate %s: %w", info.Key, err)
+		}
+		if err := journal.record(info.Key, checksum); err != nil {
+			return state, err
+		}
+
+		state.Copied++
+		state.Bytes += info.Size
+		if progress != nil {
+			progress(state)
+		}
+	}
+	return state, nil
+}
+
+// Helper function: copy a file from 'src' into 'dst', then read it back to verify its checksum. It returns the
+// hex encoded SHA-256 of the file
+func copyVerified(src, dst Storage, key string) (string, error) {
+	in, _, err := src.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	hasher := sha256.New()
+	if err := dst.Put(key, io.TeeReader(in, hasher)); err != nil {
+		return "", err
+	}
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+
+	copied, _, err := dst.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer copied.Close()
+
+	hasher.Reset()
+	if _, err := io.Copy(hasher, copied); err != nil {
+		return "", err
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != checksum {
+		return "", fmt.Errorf("checksum mismatch after copy")
+	}
+	return checksum, nil
+}
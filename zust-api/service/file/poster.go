@@ -0,0 +1,70 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Size of the standardized poster of a video
+const (
+	PosterWidth  = 1280
+	PosterHeight = 720
+)
+
+// Method to generate the standardized poster of a video: a PNG of PosterWidth x PosterHeight, letterboxed so the
+// aspect ratio of the source is kept. The source is the image at 'output' if the user supplied one (any format
+// ffmpeg can decode), otherwise, or if it cannot be decoded, the frame at the middle of the video 'input'.
+// 'duration' is the video duration in seconds. The poster replaces the file at 'output'
+func (service *MediaService) GeneratePoster(ctx context.Context, input, output string, duration float64) error {
+	/*
+	 * Command (from the supplied image):
+	 * ffmpeg -i thumbnail -vf "scale=1280:720:force_original_aspect_ratio=decrease,pad=1280:720:(ow-iw)/2:(oh-ih)/2,setsar=1"
+	 * -frames:v 1 -f image2 -c:v png poster.png
+	 * Command (from the video):
+	 * ffmpeg -ss timestamp -i input.mp4 -vf ... -frames:v 1 -f image2 -c:v png poster.png
+	 */
+
+	// Written next to the output first, since the supplied image is also the input
+	tmp := TempPath(output)
+	defer os.Remove(tmp)
+
+	var err error
+	if _, statErr := os.Stat(output); statErr == nil {
+		err = service.runPoster(ctx, tmp, "-i", output)
+	}
+	if _, statErr := os.Stat(output); statErr != nil || err != nil {
+		err = service.runPoster(ctx, tmp, "-ss", strconv.FormatFloat(duration/2, 'f', 2, 64), "-i", input)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, output)
+}
+
+// Helper method: run ffmpeg to write the first frame of the input into a standardized poster.
+// 'input' are the input arguments
+func (service *MediaService) runPoster(ctx context.Context, output string, input ...string) error {
+	filter := fmt.Sprintf(
+		"scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1",
+		PosterWidth, PosterHeight, PosterWidth, PosterHeight,
+	)
+
+	args := append([]string{}, input...)
+	args = append(args,
+		"-vf", filter,
+		"-map_metadata", "-1", // never carry the metadata of the source (EXIF, GPS location, ...) into the poster
+		"-frames:v", "1",
+		"-f", "image2", // the temporary file has no image extension
+		"-c:v", "png",
+		"-y",
+		output,
+	)
+	out, err := service.run(service.ffmpeg(ctx, args...))
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed for generating poster: %v\nOutput: %s", err, string(out))
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Extension of the metadata file kept next to a pre-warmed file
+const warmMetaExt = ".warm"
+
+// WarmCache keeps the first bytes of the hot files of a slow storage (network mount, object storage) on a fast
+// local disk, so the ranges requested when a video starts playing are served without waiting for the storage
+type WarmCache struct {
+	Path string
+}
+
+// Metadata of a pre-warmed file
+type warmMeta struct {
+	Size    int64     `json:"size"`   // size of the whole file
+	Length  int64     `json:"length"` // number of bytes kept in the cache
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Helper method: get the path of the cached bytes of a key
+func (cache *WarmCache) path(key string) string {
+	return filepath.Join(cache.Path, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+// Helper method: read the metadata of a pre-warmed file
+func (cache *WarmCache) meta(key string) (*warmMeta, error) {
+	data, err := os.ReadFile(cache.path(key) + warmMetaExt)
+	if err != nil {
+		return nil, err
+	}
+	var meta warmMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+// Method to copy the first 'length' bytes of a file from 'src' into the cache. A file already cached with at least
+// 'length' bytes is skipped, unless it was rewritten in 'src' since it was cached
+func (cache *WarmCache) Warm(src Storage, key string, length int64) error {
+	info, err := src.Stat(key)
+	if err != nil {
+		return err
+	}
+	length = min(length, info.Size)
+	if meta, err := cache.meta(key); err == nil && meta.Length >= length && meta.Size == info.Size &&
+		meta.ModTime.Equal(info.ModTime) {
+		return nil
+	}
+
+	in, _, err := src.Get(key)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	name := cache.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	out, err := CreateAtomic(name)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.CopyN(out, in, length); err != nil {
+		return err
+	}
+	if err := out.Commit(); err != nil {
+		return err
+	}
+
+	// The metadata is written last, a file is never read from the cache before all its bytes are there
+	data, err := json.Marshal(warmMeta{Size: info.Size, Length: length, ModTime: info.ModTime})
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(name+warmMetaExt, data)
+}
+
+// Method to open a file through the cache: the cached bytes are read from the cache, the rest of the file is read
+// from 'src' only when it's requested. It returns an error wrapping fs.ErrNotExist if the file is not cached
+func (cache *WarmCache) Open(src Storage, key string) (io.ReadSeekCloser, *FileInfo, error) {
+	meta, err := cache.meta(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	prefix, err := os.Open(cache.path(key))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := &warmReader{prefix: prefix, length: meta.Length, size: meta.Size, src: src, key: key}
+	return reader, &FileInfo{Key: key, Name: path.Base(key), Size: meta.Size, ModTime: meta.ModTime}, nil
+}
+
+// Method to remove the cached files whose key is not in 'keep'. It returns the number of removed files
+func (cache *WarmCache) Prune(keep map[string]bool) (int, error) {
+	removed := 0
+	err := filepath.WalkDir(cache.Path, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(name, warmMetaExt) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(cache.Path, strings.TrimSuffix(name, warmMetaExt))
+		if err != nil {
+			return err
+		}
+		if keep[filepath.ToSlash(rel)] {
+			return nil
+		}
+
+		// The metadata is removed first, so the file is no longer read from the cache
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := os.Remove(strings.TrimSuffix(name, warmMetaExt)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// Reader of a pre-warmed file: the first 'length' bytes are read from the cache, the rest from the storage
+type warmReader struct {
+	prefix *os.File
+	length int64
+	size   int64
+	offset int64
+
+	src        Storage
+	key        string
+	rest       io.ReadSeekCloser // opened on the first read past the cached bytes
+	restOffset int64
+}
+
+func (reader *warmReader) Read(p []byte) (int, error) {
+	if reader.offset >= reader.size {
+		return 0, io.EOF
+	}
+
+	if reader.offset < reader.length {
+		p = p[:min(int64(len(p)), reader.length-reader.offset)]
+		n, err := reader.prefix.ReadAt(p, reader.offset)
+		reader.offset += int64(n)
+		if err == io.EOF && n > 0 {
+			err = nil
+		}
+		return n, err
+	}
+
+	if reader.rest == nil {
+		rest, _, err := reader.src.Get(reader.key)
+		if err != nil {
+			return 0, err
+		}
+		reader.rest = rest
+		reader.restOffset = 0
+	}
+	if reader.restOffset != reader.offset {
+		if _, err := reader.rest.Seek(reader.offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+		reader.restOffset = reader.offset
+	}
+
+	n, err := reader.rest.Read(p)
+	reader.offset += int64(n)
+	reader.restOffset += int64(n)
+	return n, err
+}
+
+func (reader *warmReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += reader.offset
+	case io.SeekEnd:
+		offset += reader.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position %d", offset)
+	}
+	reader.offset = offset
+	return offset, nil
+}
+
+func (reader *warmReader) Close() error {
+	err := reader.prefix.Close()
+	if reader.rest != nil {
+		if restErr := reader.rest.Close(); err == nil {
+			err = restErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,273 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Media information of a file, parsed from ffprobe
+type MediaInfo struct {
+	Container     string  `json:"container"`             // ffprobe format name, for example: mov,mp4,m4a,3gp,3g2,mj2
+	MajorBrand    string  `json:"major_brand,omitempty"` // for example: isom, mp42, qt
+	Duration      float64 `json:"duration"`              // second
+	Bitrate       int64   `json:"bitrate"`               // bit/s of the video stream (or the whole file if unknown)
+	VideoCodec    string  `json:"video_codec"`
+	VideoProfile  string  `json:"video_profile,omitempty"` // for example: High, Main
+	VideoLevel    int     `json:"video_level,omitempty"`   // for example: 40 for level 4.0
+	Width         int     `json:"width"`
+	Height        int     `json:"height"`
+	FPS           float64 `json:"fps"`
+	AudioCodec    string  `json:"audio_codec,omitempty"`
+	AudioChannels int     `json:"audio_channels,omitempty"`
+}
+
+// Method to check if the media has an audio stream
+func (info *MediaInfo) HasAudio() bool {
+	return info.AudioCodec != ""
+}
+
+// H.264 profile_idc of each profile reported by ffprobe
+var avcProfiles = map[string]int{
+	"Constrained Baseline": 66,
+	"Baseline":             66,
+	"Main":                 77,
+	"High":                 100,
+}
+
+// Method to get the RFC 6381 codecs string of the media (used in HLS/DASH manifests), for example:
+// avc1.640028,mp4a.40.2. It returns an empty string if the video codec is not supported or its profile is unknown
+func (info *MediaInfo) Codecs() string {
+	var codecs string
+	switch info.VideoCodec {
+	case "h264":
+		profile, ok := avcProfiles[info.VideoProfile]
+		if !ok || info.VideoLevel <= 0 {
+			return ""
+		}
+		codecs = fmt.Sprintf("avc1.%02x00%02x", profile, info.VideoLevel)
+	case "vp9":
+		// Profile 0, 8 bit
+		codecs = fmt.Sprintf("vp09.00.%02d.08", levelForHeight(info.Height, vp9Levels))
+	case "av1":
+		// Main profile, main tier, 8 bit
+		codecs = fmt.Sprintf("av01.0.%02dM.08", levelForHeight(info.Height, av1Levels))
+	default:
+		return ""
+	}
+
+	if info.AudioCodec == "aac" {
+		codecs += ",mp4a.40.2"
+	}
+	return codecs
+}
+
+// Minimum level of VP9 (level x 10) and AV1 (seq_level_idx) for each height, ordered by height.
+// ffprobe doesn't report a usable level for them, so it's estimated from the resolution
+var (
+	vp9Levels = [][2]int{{480, 30}, {720, 31}, {1080, 40}, {1440, 50}, {2160, 51}}
+	av1Levels = [][2]int{{480, 4}, {720, 5}, {1080, 8}, {1440, 12}, {2160, 13}}
+)
+
+// Helper function: get the level of the lowest height that fits 'height'
+func levelForHeight(height int, levels [][2]int) int {
+	for _, level := range levels {
+		if height <= level[0] {
+			return level[1]
+		}
+	}
+	return levels[len(levels)-1][1]
+}
+
+// Helper method: probe the media file. 'input' expects a full file path
+func (service *MediaService) Probe(input string) (*MediaInfo, error) {
+	/*
+	 * Command:
+	 * ffprobe -v error -print_format json -show_format -show_streams input.mp4
+	 */
+
+	cmd := exec.Command(service.FFprobePath, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", input)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed for probing media: %w", err)
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecType    string `json:"codec_type"`
+			CodecName    string `json:"codec_name"`
+			Width        int    `json:"width"`
+			Height       int    `json:"height"`
+			AvgFrameRate string `json:"avg_frame_rate"`
+			BitRate      string `json:"bit_rate"`
+			Channels     int    `json:"channels"`
+			Profile      string `json:"profile"`
+			Level        int    `json:"level"`
+		} `json:"streams"`
+		Format struct {
+			FormatName string `json:"format_name"`
+			Duration   string `json:"duration"`
+			BitRate    string `json:"bit_rate"`
+			Tags       struct {
+				MajorBrand string `json:"major_brand"`
+			} `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, err
+	}
+
+	info := &MediaInfo{Container: probe.Format.FormatName, MajorBrand: strings.TrimSpace(probe.Format.Tags.MajorBrand)}
+	info.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	info.Bitrate, _ = strconv.ParseInt(probe.Format.BitRate, 10, 64)
+
+	// Only the first video and audio stream are considered
+	for _, stream := range probe.Streams {
+		switch stream.CodecType {
+		case "video":
+			if info.VideoCodec != "" {
+				continue
+			}
+			info.VideoCodec = stream.CodecName
+			info.VideoProfile = stream.Profile
+			info.VideoLevel = stream.Level
+			info.Width = stream.Width
+			info.Height = stream.Height
+			info.FPS = parseFrameRate(stream.AvgFrameRate)
+			if bitrate, err := strconv.ParseInt(stream.BitRate, 10, 64); err == nil {
+				info.Bitrate = bitrate
+			}
+		case "audio":
+			if info.AudioCodec != "" {
+				continue
+			}
+			info.AudioCodec = stream.CodecName
+			info.AudioChannels = stream.Channels
+		}
+	}
+
+	return info, nil
+}
+
+// Helper function: parse the ffprobe frame rate, which is a fraction like '30000/1001'
+func parseFrameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
+
+// Method to get the short container name of the media: mp4, mov, webm, mkv or the raw ffprobe format name.
+// ffprobe reports the same format name for mp4 and mov (and for webm and mkv), so we look at the brand/codecs
+func (info *MediaInfo) ContainerName() string {
+	formats := strings.Split(info.Container, ",")
+	switch {
+	case slices.Contains(formats, "mp4"):
+		if info.MajorBrand == "qt" {
+			return "mov"
+		}
+		return "mp4"
+	case slices.Contains(formats, "webm"):
+		// WebM only allows VP8/VP9/AV1 video and Vorbis/Opus audio, anything else is a generic Matroska file
+		webmVideo := slices.Contains([]string{"vp8", "vp9", "av1"}, info.VideoCodec)
+		webmAudio := !info.HasAudio() || slices.Contains([]string{"vorbis", "opus"}, info.AudioCodec)
+		if webmVideo && webmAudio {
+			return "webm"
+		}
+		return "mkv"
+	}
+	return info.Container
+}
+
+// Error returned when the media cannot be processed by the transcoding pipeline
+var ErrUnsupportedMedia = errors.New("unsupported media")
+
+// A single reason why a media is rejected
+type MediaError struct {
+	Field   string   `json:"field"` // container, video_codec, audio_codec or duration
+	Value   string   `json:"value"`
+	Allowed []string `json:"allowed,omitempty"`
+	Limit   int      `json:"limit,omitempty"`
+	Message string   `json:"message"`
+}
+
+// All reasons why a media is rejected
+type MediaErrors []MediaError
+
+func (errs MediaErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return fmt.Sprintf("%s: %s", ErrUnsupportedMedia, strings.Join(messages, "; "))
+}
+
+func (errs MediaErrors) Is(target error) bool {
+	return target == ErrUnsupportedMedia
+}
+
+// Method to validate the probed media against the upload limits (max duration, allowed containers and codecs).
+// It returns nil if the media is accepted, otherwise a MediaErrors listing every violation
+func (service *MediaService) ValidateMedia(info *MediaInfo) error {
+	var errs MediaErrors
+
+	if container := info.ContainerName(); !slices.Contains(service.AllowedContainers, container) {
+		errs = append(errs, MediaError{
+			Field:   "container",
+			Value:   container,
+			Allowed: service.AllowedContainers,
+			Message: fmt.Sprintf("container %q is not allowed", container),
+		})
+	}
+
+	if info.VideoCodec == "" {
+		errs = append(errs, MediaError{
+			Field:   "video_codec",
+			Allowed: service.AllowedVideoCodecs,
+			Message: "no video stream found",
+		})
+	} else if !slices.Contains(service.AllowedVideoCodecs, info.VideoCodec) {
+		errs = append(errs, MediaError{
+			Field:   "video_codec",
+			Value:   info.VideoCodec,
+			Allowed: service.AllowedVideoCodecs,
+			Message: fmt.Sprintf("video codec %q is not allowed", info.VideoCodec),
+		})
+	}
+
+	if info.HasAudio() && !slices.Contains(service.AllowedAudioCodecs, info.AudioCodec) {
+		errs = append(errs, MediaError{
+			Field:   "audio_codec",
+			Value:   info.AudioCodec,
+			Allowed: service.AllowedAudioCodecs,
+			Message: fmt.Sprintf("audio codec %q is not allowed", info.AudioCodec),
+		})
+	}
+
+	if service.MaxDuration > 0 && info.Duration > float64(service.MaxDuration) {
+		errs = append(errs, MediaError{
+			Field:   "duration",
+			Value:   strconv.FormatFloat(info.Duration, 'f', 0, 64),
+			Limit:   service.MaxDuration,
+			Message: fmt.Sprintf("video is longer than %d seconds", service.MaxDuration),
+		})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package file
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Number of bytes needed to sniff the type of a file, as used by http.DetectContentType
+const sniffLen = 512
+
+// Helper function: read the first bytes of an uploaded file, then rewind it so it can be copied from the start
+func readHead(file io.ReadSeeker) ([]byte, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// Helper function: check from its magic bytes that an uploaded file is a PNG or JPEG image, whatever its name or
+// the content type claimed by the client
+func IsImage(file io.ReadSeeker) (bool, error) {
+	head, err := readHead(file)
+	if err != nil {
+		return false, err
+	}
+
+	switch http.DetectContentType(head) {
+	case "image/png", "image/jpeg":
+		return true, nil
+	}
+	return false, nil
+}
+
+// Helper function: check from its magic bytes that an uploaded file is in a video container: ISO BMFF (MP4, MOV,
+// ...), Matroska/WebM, AVI, FLV or MPEG-TS. Whether the container and codecs are allowed is checked by probing
+func IsVideo(file io.ReadSeeker) (bool, error) {
+	head, err := readHead(file)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		return true, nil
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return true, nil
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "AVI ":
+		return true, nil
+	case bytes.HasPrefix(head, []byte("FLV")):
+		return true, nil
+	case len(head) > 188 && head[0] == 0x47 && head[188] == 0x47:
+		return true, nil
+	}
+	return false, nil
+}
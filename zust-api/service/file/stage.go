@@ -0,0 +1,81 @@
+package file
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Function to copy a stored file into the local file 'dest', for the tools which only work on local files (ffmpeg).
+// It returns an error wrapping fs.ErrNotExist if the key doesn't refer to a file
+func Fetch(storage Storage, key, dest string) error {
+	src, _, err := storage.Get(key)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := CreateAtomic(dest)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	return dst.Commit()
+}
+
+// Function to store the local file 'src' under a key, replacing the current file of the key if any
+func Store(storage Storage, key, src string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return storage.Put(key, file)
+}
+
+// Function to store the files of the local directory 'dir' under the key 'prefix', keeping their relative paths.
+// The files stored under the prefix by a previous run which are not in the directory are removed afterward, so the
+// current files are replaced without ever being missing
+func StoreDir(storage Storage, prefix, dir string) error {
+	stored := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		key := path.Join(prefix, filepath.ToSlash(rel))
+		if err := Store(storage, key, name); err != nil {
+			return err
+		}
+		stored[key] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	files, err := storage.List(prefix + "/")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if stored[file.Key] {
+			continue
+		}
+		if err := storage.Delete(file.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package file
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStoreDir(t *testing.T) {
+	storage := &LocalStorage{ResourcePath: t.TempDir()}
+	for _, key := range []string{"acc/hls/vid/master.m3u8", "acc/hls/vid/1080p/index.m3u8", "acc/hls/vid2/master.m3u8"} {
+		if err := storage.Put(key, strings.NewReader("old")); err != nil {
+			t.Fatalf("failed to put %s: %v", key, err)
+		}
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"master.m3u8", "720p/index.m3u8", "720p/segment0.m4s"} {
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte("new"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := StoreDir(storage, "acc/hls/vid", dir); err != nil {
+		t.Fatalf("StoreDir() failed: %v", err)
+	}
+
+	// The stale rendition is removed, the directory of the other video is kept
+	files, err := storage.List("acc/hls/")
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	var keys []string
+	for _, file := range files {
+		keys = append(keys, file.Key)
+	}
+	slices.Sort(keys)
+	want := []string{"acc/hls/vid/720p/index.m3u8", "acc/hls/vid/720p/segment0.m4s", "acc/hls/vid/master.m3u8",
+		"acc/hls/vid2/master.m3u8"}
+	if !slices.Equal(keys, want) {
+		t.Errorf("stored keys = %v, want %v", keys, want)
+	}
+
+	// Fetching gives back the stored content
+	dest := filepath.Join(t.TempDir(), "master.m3u8")
+	if err := Fetch(storage, "acc/hls/vid/master.m3u8", dest); err != nil {
+		t.Fatalf("Fetch() failed: %v", err)
+	}
+	if content, _ := os.ReadFile(dest); string(content) != "new" {
+		t.Errorf("fetched content = %q, want %q", content, "new")
+	}
+	if err := Fetch(storage, "acc/hls/vid/missing.m3u8", dest); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Fetch() of a missing key = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -1,55 +1,453 @@
 package file
 
 import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"zust/asset"
 	"zust/service/security"
+
+	"github.com/google/uuid"
 )
 
+// Storage is the interface of the backend keeping the media files, so the server doesn't depend on where they are
+// stored. Files are addressed by keys, which are slash separated paths relative to the root of the storage, for
+// example: {account_id}/hls/{video_id}/master.m3u8
+type Storage interface {
+	// Put writes a file from 'r', replacing the current file of the key if any. The file is written atomically:
+	// a failed write never leaves a partial file under the key
+	Put(key string, r io.Reader) error
+
+	// Create opens a writer of a file, for the files which are not written from a single reader. The file
+	// replaces the current file of the key only when the writer is committed
+	Create(key string) (AtomicWriter, error)
+
+	// Get opens a file for reading. The returned reader can seek, so a range of the file can be streamed without
+	// reading it from the start. It returns an error wrapping fs.ErrNotExist if the key doesn't refer to a file
+	Get(key string) (io.ReadSeekCloser, *FileInfo, error)
+
+	// Delete removes a file, removing a file which doesn't exist is not an error
+	Delete(key string) error
+
+	// Stat returns the metadata of a file, or an error wrapping fs.ErrNotExist if the key doesn't refer to a file
+	Stat(key string) (*FileInfo, error)
+
+	// List returns the files whose key starts with 'prefix'
+	List(prefix string) ([]FileInfo, error)
+
+	// EnsureUserRepo creates the repository of a user with the default avatar and cover, files already in the
+	// repository are kept
+	EnsureUserRepo(accID string) error
+
+	// Health returns the space left in the storage, or an error if the storage can't be reached
+	Health() (*StorageHealth, error)
+}
+
+// Linker is implemented by the storages which can give a direct link to a file, readable without credentials
+// until 'ttl' from now
+type Linker interface {
+	SignedURL(key string, ttl time.Duration) string
+}
+
+// Space and inodes of a storage. A storage without inodes reports them as 0
+type StorageHealth struct {
+	TotalBytes  uint64 `json:"total_bytes"`
+	FreeBytes   uint64 `json:"free_bytes"`
+	TotalInodes uint64 `json:"total_inodes"`
+	FreeInodes  uint64 `json:"free_inodes"`
+}
+
+// Metadata of a stored file
+type FileInfo struct {
+	Key     string // for example: {account_id}/hls/{video_id}/master.m3u8
+	Name    string // base name, for example: master.m3u8
+	Size    int64
+	ModTime time.Time
+}
+
 // Local storage struct, which hold configuration related to local storage
 type LocalStorage struct {
 	ResourcePath string
+	Sharded      bool   // user repositories are stored under {ab}/{cd}/{account_id} instead of {account_id}
+	AssetPath    string // directory overriding the embedded default assets, empty to use the embedded ones
+
+	// Directory of the repository of an account relative to the resource path (see TenantDir), nil when the
+	// deployment serves a single site
+	TenantDir func(accID string) (string, error)
 }
 
 // Constructor method for local storage struct
 func NewLocalStorage(config *security.Config) *LocalStorage {
 	return &LocalStorage{
 		ResourcePath: config.ResourcePath,
+		Sharded:      config.StorageLayout == "sharded",
+		AssetPath:    config.AssetPath,
+	}
+}
+
+// Timeout of a download, including the reading of the body: the imported videos can be large
+const downloadTimeout = 30 * time.Minute
+
+// Maximum number of redirects followed by a download
+const maxDownloadRedirects = 5
+
+// Client of the downloads. The URLs are given by the clients, so the client only connects to public addresses: the
+// check is done on each dialed address, including the ones of the redirects
+var downloadClient = &http.Client{
+	Timeout:   downloadTimeout,
+	Transport: &http.Transport{DialContext: (&net.Dialer{Control: security.PublicOnly}).DialContext},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= maxDownloadRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxDownloadRedirects)
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("unsupported redirect scheme %q", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+// Helper method: get the path of a key in the local file system. Keys escaping the resource path are rejected.
+// A user repository which is only found in the other layout is used as is, so the storage keeps working while
+// the repositories are being moved to the configured layout
+func (storage *LocalStorage) path(key string) (string, error) {
+	name := filepath.FromSlash(key)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid key %q: %w", key, fs.ErrNotExist)
+	}
+
+	accID, _, _ := strings.Cut(key, "/")
+	if uuid.Validate(accID) != nil {
+		return filepath.Join(storage.ResourcePath, name), nil
+	}
+
+	root := storage.ResourcePath
+	if storage.TenantDir != nil {
+		dir, err := storage.TenantDir(accID)
+		if err != nil {
+			return "", err
+		}
+		root = filepath.Join(root, filepath.FromSlash(dir))
+	}
+
+	flat := filepath.Join(root, name)
+	sharded := filepath.Join(root, filepath.FromSlash(ShardDir(accID)), name)
+	preferred, other := flat, sharded
+	if storage.Sharded {
+		preferred, other = sharded, flat
 	}
+	if !repoExists(preferred, name, accID) && repoExists(other, name, accID) {
+		return other, nil
+	}
+	return preferred, nil
+}
+
+// Helper function: check if the user repository of a file path exists
+func repoExists(path, name, accID string) bool {
+	repo := strings.TrimSuffix(path, strings.TrimPrefix(name, accID))
+	_, err := os.Stat(repo)
+	return err == nil
+}
+
+// Function to get the shard directory of a user repository in the sharded layout, for example: 3f/a2. It's taken
+// from the hash of the account ID, so the repositories are spread evenly whatever the version of the UUID
+func ShardDir(accID string) string {
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte(accID)))
+	return sum[0:2] + "/" + sum[2:4]
+}
+
+// Directory of the repositories of the tenants other than the default one, in the resource path
+const tenantsDir = "tenants"
+
+// Function to get the directory of the repositories of a tenant relative to the resource path, for example:
+// tenants/cooking-club. The repositories of the default tenant are at the root, so a single site deployment keeps its
+// layout
+func TenantDir(tenantID string) string {
+	if tenantID == "" || tenantID == security.DefaultTenant {
+		return ""
+	}
+	return tenantsDir + "/" + tenantID
+}
+
+// Helper function: get the key of a file from its path relative to the resource path, removing the tenant
+// directory and the shard directory of the sharded layout
+func keyFromRel(rel string) string {
+	if dir, rest, found := strings.Cut(rel, "/"); found && dir == tenantsDir {
+		if _, key, found := strings.Cut(rest, "/"); found {
+			rel = key
+		}
+	}
+
+	parts := strings.SplitN(rel, "/", 3)
+	if len(parts) == 3 {
+		accID, _, _ := strings.Cut(parts[2], "/")
+		if uuid.Validate(accID) == nil && parts[0]+"/"+parts[1] == ShardDir(accID) {
+			return parts[2]
+		}
+	}
+	return rel
+}
+
+// Function to move the user repositories of a local storage from the flat layout to the sharded layout. Each
+// repository is moved with a single rename, and the storage finds the repositories in both layouts, so it can run
+// while the server is using the sharded layout. It returns the number of repositories moved
+func ShardRepositories(root string) (int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || uuid.Validate(entry.Name()) != nil {
+			continue
+		}
+
+		dest := filepath.Join(root, filepath.FromSlash(ShardDir(entry.Name())), entry.Name())
+		if _, err := os.Stat(dest); err == nil {
+			return moved, fmt.Errorf("repository %s exists in both layouts", entry.Name())
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return moved, err
+		}
+		if err := os.Rename(filepath.Join(root, entry.Name()), dest); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// Method to get the path of a stored file in the local file system, for the tools which can't read from the
+// storage (ffmpeg). An invalid key gives an empty path
+func (storage *LocalStorage) LocalPath(key string) string {
+	name, _ := storage.path(key)
+	return name
+}
+
+// Method to write a file. It's written into a temporary file first, then renamed, so a failed write never leaves
+// a half-written file under the key
+func (storage *LocalStorage) Put(key string, r io.Reader) error {
+	file, err := storage.Create(key)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, r); err != nil {
+		return err
+	}
+	return file.Commit()
+}
+
+// Method to open an atomic writer of a stored file
+func (storage *LocalStorage) Create(key string) (AtomicWriter, error) {
+	name, err := storage.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	return CreateAtomic(name)
+}
+
+// Method to get the space left in the file system of the resource path
+func (storage *LocalStorage) Health() (*StorageHealth, error) {
+	return StatDisk(storage.ResourcePath)
+}
+
+// Method to open a stored file for reading
+func (storage *LocalStorage) Get(key string) (io.ReadSeekCloser, *FileInfo, error) {
+	name, err := storage.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if stat.IsDir() {
+		file.Close()
+		return nil, nil, fmt.Errorf("key %q is a directory: %w", key, fs.ErrNotExist)
+	}
+
+	return file, &FileInfo{Key: key, Name: stat.Name(), Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+// Method to remove a stored file
+func (storage *LocalStorage) Delete(key string) error {
+	name, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Method to get the metadata of a stored file
+func (storage *LocalStorage) Stat(key string) (*FileInfo, error) {
+	name, err := storage.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("key %q is a directory: %w", key, fs.ErrNotExist)
+	}
+	return &FileInfo{Key: key, Name: stat.Name(), Size: stat.Size(), ModTime: stat.ModTime()}, nil
+}
+
+// Method to list the stored files whose key starts with 'prefix'. Only the directory containing the prefix is
+// walked, for example: {account_id}/resource/{video_id}_ walks {account_id}/resource
+func (storage *LocalStorage) List(prefix string) ([]FileInfo, error) {
+	dir := prefix
+	if !strings.HasSuffix(prefix, "/") {
+		dir = path.Dir(prefix)
+	}
+	root, err := storage.path(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileInfo
+	err = filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may not exist (yet), or the entry may be removed while walking
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(storage.ResourcePath, name)
+		if err != nil {
+			return err
+		}
+		key := keyFromRel(filepath.ToSlash(rel))
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, FileInfo{Key: key, Name: info.Name(), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// Function to download media from a URL.
+// 'dest' expect only the full file path of the destination file
+func DownloadURL(ctx context.Context, url, dest string) error {
+	return DownloadURLWithProgress(ctx, url, dest, 0, nil)
 }
 
-// Method to download media from a URL.
-// 'path' expect only the full file path of the destination file
-func (storage *LocalStorage) DownloadURL(url, path string) error {
+// Function to download media from a URL while reporting progress, the download is cancelled with ctx.
+// 'dest' expect only the full file path of the destination file. 'limit' is the maximum number of bytes allowed
+// to download (0 means no limit). 'progress' (can be nil) is called with the number of bytes written so far and
+// the total size reported by the remote server (-1 if unknown)
+func DownloadURLWithProgress(ctx context.Context, url, dest string, limit int64,
+	progress func(written, total int64)) error {
 	// Create HTTP request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", req.URL.Scheme)
+	}
 
 	// Perform the request
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := downloadClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	// Create file in local storage
-	file, err := os.Create(path)
+	// Check for status code
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed with status code %d", resp.StatusCode)
+	}
+
+	// Reject early if the remote server already tells us the file is too large
+	if limit > 0 && resp.ContentLength > limit {
+		return ErrFileTooLarge
+	}
+
+	// Create a temporary file in local storage, renamed when the download completes
+	file, err := CreateAtomic(dest)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	// Write response body to file
-	_, err = io.Copy(file, resp.Body)
-	return err
+	// Write response body to file, read at most limit+1 bytes to detect oversized file
+	var body io.Reader = resp.Body
+	if limit > 0 {
+		body = io.LimitReader(resp.Body, limit+1)
+	}
+	written, err := io.Copy(file, &progressReader{reader: body, total: resp.ContentLength, progress: progress})
+	if err != nil {
+		return err
+	}
+	if limit > 0 && written > limit {
+		return ErrFileTooLarge
+	}
+
+	return file.Commit()
+}
+
+// Error returned when a downloaded file exceeds the size limit
+var ErrFileTooLarge = errors.New("file exceeds the size limit")
+
+// Reader wrapper which reports the number of bytes read so far
+type progressReader struct {
+	reader   io.Reader
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	pr.written += int64(n)
+	if pr.progress != nil && n > 0 {
+		pr.progress(pr.written, pr.total)
+	}
+	return n, err
 }
 
 // Method to create user repository in local storage with default avatar and cover
-func (storage *LocalStorage) CreateUserRepo(accID string) error {
+func (storage *LocalStorage) EnsureUserRepo(accID string) error {
 	/*
 	 * Directory structure example
 	 * storage
@@ -61,56 +459,294 @@ func (storage *LocalStorage) CreateUserRepo(accID string) error {
 	 * |______{video_id}_480p.mp4
 	 * |____thumbnail
 	 * |______{video_id}.png
+	 * |____subtitles
+	 * |______{video_id}_{language}.vtt
+	 * |____waveform
+	 * |______{video_id}.json (if the video has audio)
+	 * |____hls
+	 * |______{video_id}
+	 * |________master.m3u8
+	 * |________manifest.mpd (if DASH is enabled)
+	 * |________{resolution}/index.m3u8, init.mp4, segment_000.m4s, ...
+	 * |____live
+	 * |______{video_id}
+	 * |________init.mp4, part_00000.m4s, segment_00000.m4s, ... (LL-HLS of a premiere)
 	 * |____avatar.png
 	 * |____cover.png
+	 * |____watermark.png (if the channel has its own watermark)
+	 * |____branding
+	 * |______intro.mp4, outro.mp4 (if the channel has them)
 	 */
 
 	// Create user repository directory with their ID as name
-	userDir := filepath.Join(storage.ResourcePath, accID)
+	userDir, err := storage.path(accID)
+	if err != nil {
+		return err
+	}
 
 	// Create 'thumbnail' and 'resource' subdirectories
-	subDirs := []string{"resource", "thumbnail"}
+	subDirs := []string{"resource", "thumbnail", "hls", "live"}
 	for _, dir := range subDirs {
 		if err := os.MkdirAll(filepath.Join(userDir, dir), 0755); err != nil {
 			return err
 		}
 	}
 
-	// Create default avatar image
-	srcAvatar, err := os.Open("asset/avatar.png")
-	if err != nil {
+	// Create default avatar and cover images, unless the user already has them
+	if err := storage.putDefault("avatar.png", AvatarKey(accID)); err != nil {
 		return err
 	}
-	defer srcAvatar.Close()
+	return storage.putDefault("cover.png", CoverKey(accID))
+}
+
+// Helper method: copy a default asset into the storage if the key doesn't exist yet
+func (storage *LocalStorage) putDefault(name, key string) error {
+	if _, err := storage.Stat(key); err == nil {
+		return nil
+	}
 
-	destAvatar, err := os.Create(filepath.Join(userDir, "avatar.png"))
+	src, err := asset.Open(storage.AssetPath, name)
 	if err != nil {
 		return err
 	}
-	defer destAvatar.Close()
+	defer src.Close()
+
+	return storage.Put(key, src)
+}
+
+// Helper function: check if a stored file exists
+func Exists(storage Storage, key string) bool {
+	_, err := storage.Stat(key)
+	return err == nil
+}
+
+// Helper function: read a whole stored file
+func ReadFile(storage Storage, key string) ([]byte, error) {
+	file, _, err := storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// Helper function: get the filename of the H.264 rendition with the highest resolution available of a video, or an
+// empty string if there is none. The ladder is configurable, so the renditions are listed instead of guessed
+func BestRendition(storage Storage, accID, videoID string) string {
+	files, _ := storage.List(path.Join(accID, "resource", videoID+"_"))
+
+	best, bestHeight := "", 0
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name, ".mp4") {
+			continue
+		}
+		resolution := strings.TrimSuffix(strings.TrimPrefix(file.Name, videoID+"_"), ".mp4")
+		if height := ParseResolution(resolution); height > bestHeight {
+			best, bestHeight = file.Name, height
+		}
+	}
+	return best
+}
+
+// Helper function: get the height of a resolution name, for example: 1080p -> 1080. It returns 0 if the name is
+// not a resolution (renditions of other codecs, such as 1080p_vp9, are not)
+func ParseResolution(name string) int {
+	height, err := strconv.Atoi(strings.TrimSuffix(name, "p"))
+	if err != nil || !strings.HasSuffix(name, "p") || height <= 0 {
+		return 0
+	}
+	return height
+}
+
+// Helper function: remove the original uploaded file of a video. If 'archivePath' is not empty, the file is moved
+// into '{archivePath}/{account_id}/{video_id}.mp4' instead of being deleted. It returns the number of bytes reclaimed
+func RemoveOriginal(storage Storage, accID, videoID, archivePath string) (int64, error) {
+	key := OriginalKey(accID, videoID)
+	info, err := storage.Stat(key)
+	if err != nil {
+		return 0, err
+	}
+
+	// Archive the original file before deleting it
+	if archivePath != "" {
+		archiveDir := filepath.Join(archivePath, accID)
+		if err := os.MkdirAll(archiveDir, 0755); err != nil {
+			return 0, err
+		}
+		if err := archiveFile(storage, key, filepath.Join(archiveDir, info.Name)); err != nil {
+			return 0, err
+		}
+	}
+
+	return info.Size, storage.Delete(key)
+}
 
-	_, err = io.Copy(destAvatar, srcAvatar)
+// Helper function: copy a stored file into a local file
+func archiveFile(storage Storage, key, dest string) error {
+	in, _, err := storage.Get(key)
 	if err != nil {
 		return err
 	}
+	defer in.Close()
 
-	// Create default cover image
-	srcCover, err := os.Open("asset/cover.png")
+	out, err := CreateAtomic(dest)
 	if err != nil {
 		return err
 	}
-	defer srcCover.Close()
+	defer out.Close()
 
-	destCover, err := os.Create(filepath.Join(userDir, "cover.png"))
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Commit()
+}
+
+// Helper function: move a local file, falling back to copy and remove when source and destination are on different
+// devices
+func MoveFile(src, dest string) error {
+	if err := os.Rename(src, dest); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
-	defer destCover.Close()
+	defer in.Close()
 
-	_, err = io.Copy(destCover, srcCover)
+	out, err := CreateAtomic(dest)
 	if err != nil {
 		return err
 	}
+	defer out.Close()
 
-	return nil
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	if err := out.Commit(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// Helper function: get the codecs a rendition of a video is available in, for example: 720p -> [vp9 h264]
+func RenditionCodecs(storage Storage, accID, videoID, resolution string) []VideoCodec {
+	var codecs []VideoCodec
+	for _, codec := range codecPreference {
+		if Exists(storage, RenditionKey(accID, videoID, RenditionName(resolution, codec))) {
+			codecs = append(codecs, codec)
+		}
+	}
+	return codecs
+}
+
+// Helper function: get the key of the original uploaded file of a video
+func OriginalKey(accID, videoID string) string {
+	return path.Join(accID, "resource", videoID+".mp4")
+}
+
+// Helper function: get the key of a rendition of a video, for example: 720p_vp9
+func RenditionKey(accID, videoID, name string) string {
+	return path.Join(accID, "resource", fmt.Sprintf("%s_%s.mp4", videoID, name))
+}
+
+// Helper function: get the key of the avatar of a user
+func AvatarKey(accID string) string {
+	return path.Join(accID, "avatar.png")
+}
+
+// Helper function: get the key of the cover image of a user
+func CoverKey(accID string) string {
+	return path.Join(accID, "cover.png")
+}
+
+// Helper function: get the key of the thumbnail (poster) of a video
+func ThumbnailKey(accID, videoID string) string {
+	return path.Join(accID, "thumbnail", videoID+".png")
+}
+
+// Helper function: get the key of the watermark image of a channel
+func WatermarkKey(accID string) string {
+	return path.Join(accID, "watermark.png")
+}
+
+// Helper function: get the key of a branding clip (intro or outro) of a channel
+func BrandingKey(accID, kind string) string {
+	return path.Join(accID, "branding", kind+".mp4")
+}
+
+// Helper function: get the key of the WebVTT subtitles of a video in a language
+func SubtitleKey(accID, videoID, language string) string {
+	return path.Join(accID, "subtitles", fmt.Sprintf("%s_%s.vtt", videoID, language))
+}
+
+// Helper function: get the key of the peak waveform of a video
+func WaveformKey(accID, videoID string) string {
+	return path.Join(accID, "waveform", videoID+".json")
+}
+
+// Helper function: get the key of the HLS directory of a video
+func HLSKey(accID, videoID string) string {
+	return path.Join(accID, "hls", videoID)
+}
+
+// Helper function: get the key of the live (LL-HLS) directory of a video
+func LiveKey(accID, videoID string) string {
+	return path.Join(accID, "live", videoID)
+}
+
+// Helper function: get the account and the video a stored file belongs to. Files of a video are named by the video
+// ID, or are under a directory named by it. It returns false for the files of the account (avatar, cover, ...)
+func ParseVideoKey(key string) (accID, videoID uuid.UUID, ok bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) < 3 {
+		return uuid.Nil, uuid.Nil, false
+	}
+	accID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	switch parts[1] {
+	case "resource", "thumbnail", "subtitles", "waveform":
+	case "hls", "live":
+		if len(parts) < 4 {
+			return uuid.Nil, uuid.Nil, false
+		}
+	default:
+		return uuid.Nil, uuid.Nil, false
+	}
+	if len(parts[2]) < 36 {
+		return uuid.Nil, uuid.Nil, false
+	}
+	videoID, err = uuid.Parse(parts[2][:36])
+	if err != nil {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return accID, videoID, true
+}
+
+// Helper function: check if a stored file is a public file of an account (avatar or cover). Only the keys of
+// these files and the keys of the video files (see ParseVideoKey) are served
+func IsAccountKey(key string) bool {
+	accID, name, found := strings.Cut(key, "/")
+	if !found || uuid.Validate(accID) != nil {
+		return false
+	}
+	return name == "avatar.png" || name == "cover.png"
+}
+
+// Helper function: check if a video is packaged into HLS
+func HasHLS(storage Storage, accID, videoID string) bool {
+	return Exists(storage, path.Join(HLSKey(accID, videoID), HLSMasterPlaylist))
+}
+
+// Helper function: check if a video has a DASH manifest
+func HasDASH(storage Storage, accID, videoID string) bool {
+	return Exists(storage, path.Join(HLSKey(accID, videoID), DASHManifest))
+}
+
+// Helper function: check if a channel has a branding clip (intro or outro)
+func HasBranding(storage Storage, accID, kind string) bool {
+	return Exists(storage, BrandingKey(accID, kind))
 }
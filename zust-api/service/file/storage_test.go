@@ -0,0 +1,109 @@
+package file
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDownloadURL(t *testing.T) {
+	// The test server listens on the loopback, which the downloads must never reach
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer local.Close()
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "loopback", url: local.URL},
+		{name: "loopback name", url: "http://localhost:1/video.mp4"},
+		{name: "metadata", url: "http://169.254.169.254/latest/meta-data/"},
+		{name: "private", url: "http://10.0.0.1/video.mp4"},
+		{name: "file scheme", url: "file:///etc/passwd"},
+		{name: "ftp scheme", url: "ftp://example.com/video.mp4"},
+	}
+
+	dest := filepath.Join(t.TempDir(), "video.mp4")
+	for _, test := range tests {
+		if err := DownloadURL(context.Background(), test.url, dest); err == nil {
+			t.Errorf("%s: DownloadURL(%q) succeeded, want an error", test.name, test.url)
+		}
+	}
+}
+
+func TestParseVideoKey(t *testing.T) {
+	const (
+		accID   = "0b8f5a4e-7d3c-4a8e-9a43-2f1d6c5e8b71"
+		videoID = "5c2e7f10-3b9d-4e61-8f2a-9d4b1c7e6a05"
+	)
+
+	tests := []struct {
+		name string
+		key  string
+		ok   bool
+	}{
+		{name: "original", key: accID + "/resource/" + videoID + ".mp4", ok: true},
+		{name: "thumbnail", key: accID + "/thumbnail/" + videoID + ".png", ok: true},
+		{name: "subtitles", key: accID + "/subtitles/" + videoID + "_en.vtt", ok: true},
+		{name: "waveform", key: accID + "/waveform/" + videoID + ".json", ok: true},
+		{name: "hls segment", key: accID + "/hls/" + videoID + "/720p/segment_001.ts", ok: true},
+		{name: "hls playlist", key: accID + "/hls/" + videoID + "/master.m3u8", ok: true},
+		{name: "live segment", key: accID + "/live/" + videoID + "/index.m3u8", ok: true},
+		{name: "hls directory", key: accID + "/hls/" + videoID},
+		{name: "live directory", key: accID + "/live/" + videoID},
+		{name: "avatar", key: accID + "/avatar.png"},
+		{name: "unknown directory", key: accID + "/branding/" + videoID + ".mp4"},
+		{name: "invalid account", key: "assets/resource/" + videoID + ".mp4"},
+		{name: "invalid video", key: accID + "/resource/not-a-video-id-but-long-enough-to-slice.mp4"},
+		{name: "short video name", key: accID + "/resource/x.mp4"},
+		{name: "empty", key: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotAcc, gotVideo, ok := ParseVideoKey(test.key)
+			if ok != test.ok {
+				t.Fatalf("ParseVideoKey(%q) ok = %v, want %v", test.key, ok, test.ok)
+			}
+			if !ok {
+				if gotAcc != uuid.Nil || gotVideo != uuid.Nil {
+					t.Errorf("ParseVideoKey(%q) = %s, %s, want nil IDs", test.key, gotAcc, gotVideo)
+				}
+				return
+			}
+			if gotAcc.String() != accID || gotVideo.String() != videoID {
+				t.Errorf("ParseVideoKey(%q) = %s, %s, want %s, %s", test.key, gotAcc, gotVideo, accID, videoID)
+			}
+		})
+	}
+}
+
+func TestIsAccountKey(t *testing.T) {
+	const accID = "0b8f5a4e-7d3c-4a8e-9a43-2f1d6c5e8b71"
+
+	tests := []struct {
+		key     string
+		account bool
+	}{
+		{key: accID + "/avatar.png", account: true},
+		{key: accID + "/cover.png", account: true},
+		{key: accID + "/watermark.png"},
+		{key: accID + "/branding/intro.mp4"},
+		{key: accID + "/avatar.png/x"},
+		{key: "assets/avatar.png"},
+		{key: "avatar.png"},
+		{key: ""},
+	}
+
+	for _, test := range tests {
+		if got := IsAccountKey(test.key); got != test.account {
+			t.Errorf("IsAccountKey(%q) = %v, want %v", test.key, got, test.account)
+		}
+	}
+}
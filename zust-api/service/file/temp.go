@@ -0,0 +1,151 @@
+package file
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Marker of temporary files and directories: anything whose name contains it is partial work (an upload being
+// written, an intermediate file of transcoding, ...), which the janitor removes once it's old enough
+const TempMarker = ".tmp"
+
+// Helper function: get the temporary path of a file. The file is written there first, then renamed to 'path' when
+// complete, so a crash never leaves a half-written file under its final name
+func TempPath(path string) string {
+	return path + TempMarker
+}
+
+// Function to remove the temporary files and directories under 'root' (the local resource path, where uploads and
+// transcodes work) which are not modified for 'maxAge', left behind by crashed uploads and transcodes. It returns
+// the number of entries removed and the number of bytes reclaimed
+func CleanTemp(root string, maxAge time.Duration) (int, int64, error) {
+	var (
+		removed   int
+		reclaimed int64
+		cutoff    = time.Now().Add(-maxAge)
+	)
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// The entry may be removed while walking (for example: a temporary file being renamed)
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !strings.Contains(entry.Name(), TempMarker) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		size := info.Size()
+		if entry.IsDir() {
+			size = dirSize(path)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		removed++
+		reclaimed += size
+
+		if entry.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return removed, reclaimed, err
+}
+
+// Helper function: get the total size of the files in a directory
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// Writer of a file which only appears under its final name once it's committed. Closing the writer without
+// committing discards everything written
+type AtomicWriter interface {
+	io.Writer
+
+	// Commit flushes the file and moves it under its final name, replacing the current file if any
+	Commit() error
+
+	// Close discards the file if it's not committed, so it can always be deferred
+	Close() error
+}
+
+// Atomic writer of a local file, written into a temporary file in the same directory so the rename is atomic
+type atomicFile struct {
+	*os.File
+	path      string
+	committed bool
+}
+
+// Function to create a local file atomically: the content is written into a temporary file (with a unique name,
+// so concurrent writers of the same path never share it), then renamed to 'path' by Commit
+func CreateAtomic(path string) (AtomicWriter, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*"+TempMarker)
+	if err != nil {
+		return nil, err
+	}
+	// Temporary files are only readable by the owner, give the final file the usual permission
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &atomicFile{File: tmp, path: path}, nil
+}
+
+// Method to flush the temporary file and rename it to the final path
+func (file *atomicFile) Commit() error {
+	if err := file.File.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), file.path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	file.committed = true
+	return nil
+}
+
+// Method to close the writer, discarding the temporary file if it's not committed
+func (file *atomicFile) Close() error {
+	if file.committed {
+		return nil
+	}
+	file.File.Close()
+	return os.Remove(file.Name())
+}
+
+// Function to write a whole local file atomically
+func WriteFileAtomic(path string, data []byte) error {
+	file, err := CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Commit()
+}
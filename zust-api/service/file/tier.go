@@ -0,0 +1,43 @@
+package file
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+)
+
+// Function to list the files of a video which are moved to the cold storage when the video is stale: the
+// renditions and the HLS/DASH packaging. The original and the thumbnail always stay in the main storage
+func ColdKeys(storage Storage, accID, videoID string) ([]string, error) {
+	renditions, err := storage.List(path.Join(accID, "resource", videoID+"_"))
+	if err != nil {
+		return nil, err
+	}
+	packaging, err := storage.List(HLSKey(accID, videoID) + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, info := range append(renditions, packaging...) {
+		keys = append(keys, info.Key)
+	}
+	return keys, nil
+}
+
+// Function to move files from a storage to another. Each file is copied and verified before it's removed from
+// 'src', a file already moved (missing from 'src' but in 'dst') is skipped, so an interrupted move can be resumed
+func MoveFiles(src, dst Storage, keys []string) error {
+	for _, key := range keys {
+		if _, err := copyVerified(src, dst, key); err != nil {
+			if errors.Is(err, fs.ErrNotExist) && Exists(dst, key) {
+				continue
+			}
+			return err
+		}
+		if err := src.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package file
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Corners where the watermark can be placed
+var WatermarkPositions = []string{"top-left", "top-right", "bottom-left", "bottom-right"}
+
+// Distance between the watermark and the edges of the video, in pixels of the source video
+const watermarkMargin = 10
+
+// Watermark overlaid on the transcoded videos
+type Watermark struct {
+	Image    string  // full path of the PNG image
+	Position string  // one of WatermarkPositions, default to bottom-right
+	Opacity  float64 // [0, 1]
+}
+
+// Helper function: check if the watermark position is supported
+func IsValidWatermarkPosition(position string) bool {
+	return slices.Contains(WatermarkPositions, position)
+}
+
+// Method to get the filters overlaying the watermark (input 'imageInput' of ffmpeg) on the 'source' video pad.
+// The output pad of the filters is 'output', for example:
+// [1:v]format=rgba,colorchannelmixer=aa=0.5[wm];[0:v][wm]overlay=W-w-10:H-h-10[src]
+func (watermark *Watermark) filter(source string, imageInput int, output string) string {
+	var x, y string
+	switch watermark.Position {
+	case "top-left":
+		x, y = strconv.Itoa(watermarkMargin), strconv.Itoa(watermarkMargin)
+	case "top-right":
+		x, y = fmt.Sprintf("W-w-%d", watermarkMargin), strconv.Itoa(watermarkMargin)
+	case "bottom-left":
+		x, y = strconv.Itoa(watermarkMargin), fmt.Sprintf("H-h-%d", watermarkMargin)
+	default:
+		x, y = fmt.Sprintf("W-w-%d", watermarkMargin), fmt.Sprintf("H-h-%d", watermarkMargin)
+	}
+
+	opacity := strconv.FormatFloat(min(max(watermark.Opacity, 0), 1), 'f', 2, 64)
+	return fmt.Sprintf("[%d:v]format=rgba,colorchannelmixer=aa=%s[wm];%s[wm]overlay=%s:%s%s",
+		imageInput, opacity, source, x, y, output)
+}
@@ -0,0 +1,110 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Number of peaks of a waveform, whatever the duration of the video
+const WaveformPeaks = 1000
+
+// Sample rate the audio is decoded at, high enough for the peaks while keeping the decoding cheap
+const waveformSampleRate = 8000
+
+// Peak waveform of the audio of a video, for players to render a scrubber
+type Waveform struct {
+	Duration float64   `json:"duration"` // second
+	Peaks    []float64 `json:"peaks"`    // [0, 1], each peak covers duration / len(peaks) seconds
+}
+
+// Method to generate the peak waveform of the audio of a video and write it as JSON into 'output'.
+// The audio is decoded into mono 16-bit PCM and split into WaveformPeaks buckets, each keeping its highest
+// amplitude; the peaks are normalized so the loudest one is 1. 'info' is the probed input, which must have audio
+func (service *MediaService) GenerateWaveform(ctx context.Context, input, output string, info *MediaInfo) error {
+	/*
+	 * Command:
+	 * ffmpeg -i input.mp4 -vn -ac 1 -ar 8000 -f s16le -c:a pcm_s16le pipe:1
+	 */
+
+	if !info.HasAudio() {
+		return fmt.Errorf("video has no audio stream")
+	}
+
+	cmd := service.ffmpeg(ctx,
+		"-i", input,
+		"-vn",
+		"-ac", "1",
+		"-ar", fmt.Sprint(waveformSampleRate),
+		"-f", "s16le",
+		"-c:a", "pcm_s16le",
+		"pipe:1",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	release := service.acquire()
+	defer release()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// Bucket size from the probed duration, the decoded audio can be slightly longer so the last bucket takes the rest
+	total := int(math.Ceil(info.Duration * waveformSampleRate))
+	perPeak := max(total/WaveformPeaks, 1)
+	peaks := make([]float64, 0, WaveformPeaks)
+
+	reader := bufio.NewReader(stdout)
+	peak, count := 0.0, 0
+	for {
+		var sample int16
+		if err := binary.Read(reader, binary.LittleEndian, &sample); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				cmd.Wait()
+				return err
+			}
+			break
+		}
+
+		peak = max(peak, math.Abs(float64(sample))/math.MaxInt16)
+		count++
+		if count == perPeak && len(peaks) < WaveformPeaks-1 {
+			peaks = append(peaks, peak)
+			peak, count = 0, 0
+		}
+	}
+	if count > 0 {
+		peaks = append(peaks, peak)
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg failed for generating waveform: %v", err)
+	}
+
+	// Normalize, so quiet videos still have a readable waveform
+	loudest := 0.0
+	for _, p := range peaks {
+		loudest = max(loudest, p)
+	}
+	for i := range peaks {
+		if loudest > 0 {
+			peaks[i] = math.Round(peaks[i]/loudest*100) / 100
+		}
+	}
+
+	data, err := json.Marshal(Waveform{Duration: info.Duration, Peaks: peaks})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+	return WriteFileAtomic(output, data)
+}
@@ -0,0 +1,328 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+	"zust/service/security"
+
+	"github.com/hibiken/asynq"
+)
+
+// Maximum run time of a task, asynq cancels the context of the task past it and retries it. The transcodes and the
+// captions are well above the longest expected run (a long video in the whole ladder), the backups and restores
+// copy the whole storage, the thaws copy all renditions of a video back from the cold storage. The other types
+// get defaultTaskTimeout
+var taskTimeouts = map[string]time.Duration{
+	TypeTranscode: 12 * time.Hour,
+	TypeCaption:   4 * time.Hour,
+	TypeBackup:    6 * time.Hour,
+	TypeRestore:   6 * time.Hour,
+	TypeThaw:      time.Hour,
+}
+
+// Timeout of the types without their own
+const defaultTaskTimeout = 30 * time.Minute
+
+// AsynqQueue is the job queue backed by Redis through asynq, for multi-instance deployments.
+// asynq guarantees each task is processed by a single worker across all instances. Each job type has its own asynq
+// queue named after it, so an instance only pulls the tasks it has a handler for (the API server and the
+// transcoding workers share the same Redis) and the backlog can be counted per type
+type AsynqQueue struct {
+	client      *asynq.Client
+	inspector   *asynq.Inspector
+	redis       asynq.RedisClientOpt
+	logger      *slog.Logger
+	workers     int
+	maxAttempts int
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+
+	// Shut down the workers started by Start and close the clients, once
+	server   *asynq.Server
+	stopOnce sync.Once
+}
+
+// Constructor method for asynq queue
+func NewAsynqQueue(config *security.Config, logger *slog.Logger) *AsynqQueue {
+	redis := asynq.RedisClientOpt{
+		Addr:     config.RedisAddr,
+		Password: config.RedisPassword,
+		DB:       config.RedisDB,
+	}
+
+	return &AsynqQueue{
+		client:      asynq.NewClient(redis),
+		inspector:   asynq.NewInspector(redis),
+		redis:       redis,
+		logger:      logger,
+		workers:     config.JobWorkers,
+		maxAttempts: config.JobMaxAttempts,
+		handlers:    make(map[string]Handler),
+	}
+}
+
+// Method to put a job into the queue
+func (queue *AsynqQueue) Enqueue(ctx context.Context, jobType string, payload any, opts ...Option) error {
+	options := options{maxAttempts: queue.maxAttempts}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// asynq counts retries, not attempts
+	taskOpts := []asynq.Option{asynq.MaxRetry(max(options.maxAttempts-1, 0))}
+	if options.delay > 0 {
+		taskOpts = append(taskOpts, asynq.ProcessIn(options.delay))
+	}
+
+	timeout, ok := taskTimeouts[jobType]
+	if !ok {
+		timeout = defaultTaskTimeout
+	}
+	taskOpts = append(taskOpts, asynq.Timeout(timeout), asynq.Queue(jobType))
+
+	_, err = queue.client.EnqueueContext(ctx, asynq.NewTask(jobType, data), taskOpts...)
+	return err
+}
+
+// Method to set the handler of a job type
+func (queue *AsynqQueue) Register(jobType string, handler Handler) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	queue.handlers[jobType] = handler
+}
+
+// Method to start the workers in background, they are shut down when ctx is cancelled
+func (queue *AsynqQueue) Start(ctx context.Context) error {
+	mux := asynq.NewServeMux()
+	queues := make(map[string]int)
+	queue.mu.Lock()
+	for jobType, handler := range queue.handlers {
+		mux.HandleFunc(jobType, queue.wrap(handler))
+		queues[jobType] = 1
+	}
+	queue.mu.Unlock()
+
+	queue.server = asynq.NewServer(queue.redis, asynq.Config{
+		Concurrency: queue.workers,
+		Queues:      queues,
+		// Same exponential backoff as the DB queue. Tasks running out of retries are archived (dead-letter)
+		RetryDelayFunc: func(n int, err error, task *asynq.Task) time.Duration {
+			return backoff(n + 1)
+		},
+		Logger: newAsynqLogger(queue.logger),
+	})
+
+	if err := queue.server.Start(mux); err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		queue.Stop(context.Background())
+	}()
+	return nil
+}
+
+// Method to shut down the workers, asynq waits for the running tasks for a few seconds before putting them back
+// into the queue
+func (queue *AsynqQueue) Stop(ctx context.Context) error {
+	queue.stopOnce.Do(func() {
+		if queue.server != nil {
+			queue.server.Shutdown()
+		}
+		queue.client.Close()
+		queue.inspector.Close()
+	})
+	return nil
+}
+
+// Method to get the most recent failed (archived) tasks of all queues
+func (queue *AsynqQueue) Failed(ctx context.Context, limit int) ([]FailedJob, error) {
+	names, err := queue.inspector.Queues()
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make([]FailedJob, 0, limit)
+	for _, name := range names {
+		tasks, err := queue.inspector.ListArchivedTasks(name, asynq.PageSize(limit))
+		if err != nil {
+			if errors.Is(err, asynq.ErrQueueNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, task := range tasks {
+			failed = append(failed, FailedJob{
+				ID:        task.ID,
+				Type:      task.Type,
+				Payload:   task.Payload,
+				Attempts:  task.Retried + 1,
+				LastError: task.LastErr,
+				FailedAt:  task.LastFailedAt,
+			})
+		}
+	}
+
+	// Most recent first across all queues
+	slices.SortFunc(failed, func(a, b FailedJob) int {
+		return b.FailedAt.Compare(a.FailedAt)
+	})
+	return failed[:min(len(failed), limit)], nil
+}
+
+// Method to put an archived task back into its queue
+func (queue *AsynqQueue) Requeue(ctx context.Context, id string) error {
+	names, err := queue.inspector.Queues()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		err := queue.inspector.RunTask(name, id)
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			continue
+		}
+		return err
+	}
+	return ErrJobNotFound
+}
+
+// Method to get the number of tasks of a type waiting or running
+func (queue *AsynqQueue) Backlog(ctx context.Context, jobType string) (int, error) {
+	info, err := queue.inspector.GetQueueInfo(jobType)
+	if err != nil {
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return info.Pending + info.Active + info.Scheduled + info.Retry, nil
+}
+
+// Method to get the payloads of the tasks of a type waiting, scheduled, waiting for a retry or running. Each state
+// is listed page by page
+func (queue *AsynqQueue) Active(ctx context.Context, jobType string) ([]json.RawMessage, error) {
+	const pageSize = 100
+	lists := []func(string, ...asynq.ListOption) ([]*asynq.TaskInfo, error){
+		queue.inspector.ListPendingTasks,
+		queue.inspector.ListActiveTasks,
+		queue.inspector.ListScheduledTasks,
+		queue.inspector.ListRetryTasks,
+	}
+
+	var payloads []json.RawMessage
+	for _, list := range lists {
+		for page := 1; ; page++ {
+			tasks, err := list(jobType, asynq.Page(page), asynq.PageSize(pageSize))
+			if err != nil {
+				if errors.Is(err, asynq.ErrQueueNotFound) {
+					return payloads, nil
+				}
+				return nil, err
+			}
+			for _, task := range tasks {
+				payloads = append(payloads, task.Payload)
+			}
+			if len(tasks) < pageSize {
+				break
+			}
+		}
+	}
+	return payloads, nil
+}
+
+// Method to get the statistics of a job type from the queue of its type and its daily history in Redis
+func (queue *AsynqQueue) Stats(ctx context.Context, jobType string, since time.Time) (Stats, error) {
+	stats := Stats{Daily: []DailyStats{}}
+	info, err := queue.inspector.GetQueueInfo(jobType)
+	if err != nil {
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return stats, nil
+		}
+		return stats, err
+	}
+	stats.Pending = int64(info.Pending + info.Scheduled + info.Retry)
+	stats.Running = int64(info.Active)
+	stats.OldestPendingAge = info.Latency
+
+	// The history is listed from today backward, one entry per day even without any task. The date of an entry is
+	// the current time shifted by its number of days
+	since = since.UTC().Truncate(24 * time.Hour)
+	days := int(time.Since(since)/(24*time.Hour)) + 1
+	history, err := queue.inspector.History(jobType, days)
+	if err != nil {
+		return stats, err
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		day := history[i]
+		date := day.Date.UTC().Truncate(24 * time.Hour)
+		if date.Before(since) || day.Processed == 0 {
+			continue
+		}
+		stats.Daily = append(stats.Daily, DailyStats{
+			Day:       date,
+			Completed: int64(day.Processed - day.Failed),
+			Failed:    int64(day.Failed),
+		})
+	}
+	return stats, nil
+}
+
+// Helper method: adapt a job handler into an asynq handler
+func (queue *AsynqQueue) wrap(handler Handler) func(context.Context, *asynq.Task) error {
+	return func(ctx context.Context, task *asynq.Task) error {
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		taskID, _ := asynq.GetTaskID(ctx)
+
+		job := &Job{
+			Type:        task.Type(),
+			Payload:     task.Payload(),
+			Attempts:    retried + 1,
+			MaxAttempts: maxRetry + 1,
+		}
+
+		err := runHandler(ctx, handler, job)
+		if err != nil {
+			queue.logger.Error("job: failed to process job", "task_id", taskID, "type", job.Type,
+				"attempt", job.Attempts, "error", err)
+		}
+		return err
+	}
+}
+
+// asynq logger writing into the server logger
+type asynqLogger struct {
+	logger *slog.Logger
+}
+
+// Constructor method for asynq logger
+func newAsynqLogger(logger *slog.Logger) *asynqLogger {
+	return &asynqLogger{logger: logger.With("component", "asynq")}
+}
+
+func (l *asynqLogger) Debug(args ...any) { l.logger.Debug(fmt.Sprint(args...)) }
+func (l *asynqLogger) Info(args ...any)  { l.logger.Info(fmt.Sprint(args...)) }
+func (l *asynqLogger) Warn(args ...any)  { l.logger.Warn(fmt.Sprint(args...)) }
+func (l *asynqLogger) Error(args ...any) { l.logger.Error(fmt.Sprint(args...)) }
+
+// asynq expects the process to exit on fatal error
+func (l *asynqLogger) Fatal(args ...any) {
+	l.logger.Error(fmt.Sprint(args...))
+	os.Exit(1)
+}
@@ -0,0 +1,361 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+	db "zust/db/sqlc"
+	"zust/service/security"
+)
+
+const (
+	// Running jobs not updated for this long are considered abandoned (the instance crashed) and requeued
+	staleTimeout = 5 * time.Minute
+
+	// Interval of the heartbeat of a running job, updating it so it's never considered abandoned while the handler
+	// runs, however long it takes
+	heartbeatInterval = time.Minute
+)
+
+// DBQueue is the job queue backed by the 'job' table. Jobs are claimed with SELECT ... FOR UPDATE SKIP LOCKED,
+// so multiple workers (and server instances) never pick up the same job
+type DBQueue struct {
+	query        *db.Queries
+	logger       *slog.Logger
+	workers      int
+	pollInterval time.Duration
+	maxAttempts  int
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	// Wake up an idle worker when a job is enqueued by this instance
+	wake chan struct{}
+
+	// Stop the workers started by Start, and wait for them
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// Constructor method for DB queue
+func NewDBQueue(query *db.Queries, config *security.Config, logger *slog.Logger) *DBQueue {
+	return &DBQueue{
+		query:        query,
+		logger:       logger,
+		workers:      config.JobWorkers,
+		pollInterval: config.JobPollInterval,
+		maxAttempts:  config.JobMaxAttempts,
+		handlers:     make(map[string]Handler),
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Method to put a job into the queue
+func (queue *DBQueue) Enqueue(ctx context.Context, jobType string, payload any, opts ...Option) error {
+	options := options{maxAttempts: queue.maxAttempts}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = queue.query.EnqueueJob(ctx, db.EnqueueJobParams{
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: int32(options.maxAttempts),
+		RunAt:       time.Now().Add(options.delay),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Notify an idle worker, if none is idle it will pick the job up on the next poll
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Method to set the handler of a job type
+func (queue *DBQueue) Register(jobType string, handler Handler) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	queue.handlers[jobType] = handler
+}
+
+// Helper method: get the registered job types, a worker only claims the jobs it has a handler for, so instances
+// running different handlers (API server and transcoding workers) can share the same table
+func (queue *DBQueue) types() []string {
+	queue.mu.RLock()
+	defer queue.mu.RUnlock()
+	types := make([]string, 0, len(queue.handlers))
+	for jobType := range queue.handlers {
+		types = append(types, jobType)
+	}
+	return types
+}
+
+// Method to start the workers in background
+func (queue *DBQueue) Start(ctx context.Context) error {
+	// Requeue jobs abandoned by a crashed instance
+	if err := queue.requeueStale(ctx); err != nil {
+		return err
+	}
+
+	ctx, queue.cancel = context.WithCancel(ctx)
+	queue.running.Add(queue.workers + 1)
+	for i := 0; i < queue.workers; i++ {
+		go func() {
+			defer queue.running.Done()
+			queue.work(ctx)
+		}()
+	}
+
+	go func() {
+		defer queue.running.Done()
+		ticker := time.NewTicker(staleTimeout / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := queue.requeueStale(ctx); err != nil {
+					queue.logger.Error("job: failed to requeue stale jobs", "error", err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Method to stop the workers and wait for them. A job interrupted while running stays claimed, it's requeued as
+// stale later
+func (queue *DBQueue) Stop(ctx context.Context) error {
+	if queue.cancel == nil {
+		return nil
+	}
+	queue.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		queue.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Helper method: requeue running jobs that are not updated for too long
+func (queue *DBQueue) requeueStale(ctx context.Context) error {
+	count, err := queue.query.RequeueStaleJobs(ctx, time.Now().Add(-staleTimeout))
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		queue.logger.Info("job: requeued stale jobs", "count", count)
+	}
+	return nil
+}
+
+// Method to run a worker until ctx is cancelled: claim a job and process it, or wait if there is none
+func (queue *DBQueue) work(ctx context.Context) {
+	for {
+		claimed, err := queue.query.ClaimJob(ctx, queue.types())
+		if err == nil {
+			queue.process(ctx, claimed)
+			continue
+		}
+
+		if !errors.Is(err, sql.ErrNoRows) {
+			queue.logger.Error("job: failed to claim job", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-queue.wake:
+		case <-time.After(queue.pollInterval):
+		}
+	}
+}
+
+// Method to run the handler of a claimed job, then mark it as completed, retried or failed
+func (queue *DBQueue) process(ctx context.Context, claimed db.Job) {
+	job := &Job{
+		ID:          claimed.JobID,
+		Type:        claimed.Type,
+		Payload:     claimed.Payload,
+		Attempts:    int(claimed.Attempts),
+		MaxAttempts: int(claimed.MaxAttempts),
+	}
+
+	queue.mu.RLock()
+	handler, ok := queue.handlers[job.Type]
+	queue.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for job type %q", job.Type)
+	} else {
+		stopHeartbeat := queue.heartbeat(ctx, job.ID)
+		err = runHandler(ctx, handler, job)
+		stopHeartbeat()
+	}
+
+	if err == nil {
+		if err := queue.query.CompleteJob(ctx, job.ID); err != nil {
+			queue.logger.Error("job: failed to complete job", "job_id", job.ID, "error", err)
+		}
+		return
+	}
+
+	queue.logger.Error("job: failed to process job", "job_id", job.ID, "type", job.Type,
+		"attempt", job.Attempts, "error", err)
+	lastError := sql.NullString{String: err.Error(), Valid: true}
+
+	// Out of attempts, the job is failed for good
+	if !ok || job.LastAttempt() {
+		if err := queue.query.FailJob(ctx, db.FailJobParams{JobID: job.ID, LastError: lastError}); err != nil {
+			queue.logger.Error("job: failed to mark job as failed", "job_id", job.ID, "error", err)
+		}
+		return
+	}
+
+	// Otherwise retry later with exponential backoff
+	err = queue.query.RetryJob(ctx, db.RetryJobParams{
+		JobID:     job.ID,
+		RunAt:     time.Now().Add(backoff(job.Attempts)),
+		LastError: lastError,
+	})
+	if err != nil {
+		queue.logger.Error("job: failed to retry job", "job_id", job.ID, "error", err)
+	}
+}
+
+// Helper method: update a running job every heartbeat interval until the returned function is called, so the job
+// is only requeued once its worker is gone
+func (queue *DBQueue) heartbeat(ctx context.Context, jobID int64) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := queue.query.TouchJob(ctx, jobID); err != nil && ctx.Err() == nil {
+					queue.logger.Error("job: failed to update running job", "job_id", jobID, "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Method to get the most recent failed jobs
+func (queue *DBQueue) Failed(ctx context.Context, limit int) ([]FailedJob, error) {
+	jobs, err := queue.query.ListFailedJobs(ctx, int32(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make([]FailedJob, 0, len(jobs))
+	for _, job := range jobs {
+		failed = append(failed, FailedJob{
+			ID:        strconv.FormatInt(job.JobID, 10),
+			Type:      job.Type,
+			Payload:   job.Payload,
+			Attempts:  int(job.Attempts),
+			LastError: job.LastError.String,
+			FailedAt:  job.UpdatedAt,
+		})
+	}
+	return failed, nil
+}
+
+// Method to put a failed job back into the queue
+func (queue *DBQueue) Requeue(ctx context.Context, id string) error {
+	jobID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return ErrJobNotFound
+	}
+
+	count, err := queue.query.RequeueFailedJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrJobNotFound
+	}
+
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Method to get the number of pending and running jobs of a type
+func (queue *DBQueue) Backlog(ctx context.Context, jobType string) (int, error) {
+	count, err := queue.query.CountActiveJobs(ctx, jobType)
+	return int(count), err
+}
+
+// Method to get the payloads of the pending and running jobs of a type
+func (queue *DBQueue) Active(ctx context.Context, jobType string) ([]json.RawMessage, error) {
+	return queue.query.ListActiveJobPayloads(ctx, jobType)
+}
+
+// Method to get the statistics of a job type from the job table
+func (queue *DBQueue) Stats(ctx context.Context, jobType string, since time.Time) (Stats, error) {
+	var stats Stats
+	depth, err := queue.query.GetJobQueueDepth(ctx, jobType)
+	if err != nil {
+		return stats, err
+	}
+	stats.Pending, stats.Running = depth.Pending, depth.Running
+	stats.OldestPendingAge = time.Duration(depth.OldestPendingAge * float64(time.Second))
+
+	outcomes, err := queue.query.ListDailyJobOutcomes(ctx, db.ListDailyJobOutcomesParams{Type: jobType, Since: since})
+	if err != nil {
+		return stats, err
+	}
+	stats.Daily = make([]DailyStats, 0, len(outcomes))
+	for _, day := range outcomes {
+		stats.Daily = append(stats.Daily, DailyStats{Day: day.Day, Completed: day.Completed, Failed: day.Failed})
+	}
+	return stats, nil
+}
+
+// Helper function: run the handler, a panic is turned into an error so it won't kill the worker
+func runHandler(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
@@ -0,0 +1,144 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Job types
+const (
+	TypeTranscode      = "video.transcode"
+	TypeDownloadAvatar = "account.download_avatar"
+	TypeSendEmail      = "mail.send"
+	TypeCleanup        = "file.cleanup"
+	TypeCaption        = "video.caption"
+	TypeBackup         = "storage.backup"
+	TypeRestore        = "storage.restore"
+	TypeThaw           = "storage.thaw"
+	TypePush           = "notification.push"
+	TypeWebhook        = "webhook.deliver"
+)
+
+// Retry delay of failed jobs, doubled for each attempt: 30s, 1m, 2m, 4m, ... up to 1 hour
+const (
+	retryDelay    = 30 * time.Second
+	maxRetryDelay = time.Hour
+)
+
+// Error returned when requeuing a job that doesn't exist or is not failed
+var ErrJobNotFound = errors.New("job not found")
+
+// A job picked up by a worker
+type Job struct {
+	ID          int64
+	Type        string
+	Payload     json.RawMessage
+	Attempts    int // including the current attempt
+	MaxAttempts int
+}
+
+// Method to decode the job payload into v
+func (job *Job) Decode(v any) error {
+	return json.Unmarshal(job.Payload, v)
+}
+
+// Method to check if this is the last attempt of the job, so the handler can clean up before the job is failed
+func (job *Job) LastAttempt() bool {
+	return job.Attempts >= job.MaxAttempts
+}
+
+// A job that ran out of attempts (dead-letter)
+type FailedJob struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"` // for transcode jobs, this includes the ffmpeg stderr
+	FailedAt  time.Time       `json:"failed_at"`
+}
+
+// Helper function: get the delay before retrying a job that failed 'attempts' times
+func backoff(attempts int) time.Duration {
+	delay := retryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// Handler processes a job. Returning an error makes the job retried until it runs out of attempts
+type Handler func(ctx context.Context, job *Job) error
+
+// Queue is the interface of the job queue, so transcoding, avatar downloads, email sends and cleanups
+// share the same reliable queue instead of fire-and-forget goroutines
+type Queue interface {
+	// Enqueue puts a job into the queue, payload is encoded as JSON
+	Enqueue(ctx context.Context, jobType string, payload any, opts ...Option) error
+
+	// Register sets the handler of a job type, it must be called before Start
+	Register(jobType string, handler Handler)
+
+	// Start runs the workers in background until ctx is cancelled or Stop is called
+	Start(ctx context.Context) error
+
+	// Stop stops the workers, waiting for them to return until ctx is done. The jobs they were running are picked
+	// up again later
+	Stop(ctx context.Context) error
+
+	// Failed returns the most recent jobs that ran out of attempts
+	Failed(ctx context.Context, limit int) ([]FailedJob, error)
+
+	// Requeue puts a failed job back into the queue with its attempts reset.
+	// It returns ErrJobNotFound if there is no failed job with this ID
+	Requeue(ctx context.Context, id string) error
+
+	// Backlog returns the number of jobs of a type waiting or running, to apply backpressure on producers
+	Backlog(ctx context.Context, jobType string) (int, error)
+
+	// Active returns the payloads of the jobs of a type waiting (including for a retry) or running
+	Active(ctx context.Context, jobType string) ([]json.RawMessage, error)
+
+	// Stats returns the current queue of a job type, and its jobs finished per day (in UTC) since a time
+	Stats(ctx context.Context, jobType string, since time.Time) (Stats, error)
+}
+
+// Statistics of the jobs of a type. The DB queue counts a job as failed once it's out of attempts, asynq counts
+// each failed attempt and keeps the days of the last 90 days only
+type Stats struct {
+	Pending          int64 // waiting to run, including the jobs waiting for a retry
+	Running          int64
+	OldestPendingAge time.Duration // time the oldest due job has been waiting
+	Daily            []DailyStats
+}
+
+// Jobs of a type finished in a day (in UTC), the days without any are not listed
+type DailyStats struct {
+	Day       time.Time
+	Completed int64
+	Failed    int64
+}
+
+// Options of an enqueued job
+type options struct {
+	delay       time.Duration
+	maxAttempts int
+}
+
+// Option to customize an enqueued job
+type Option func(*options)
+
+// Option to run the job after a delay
+func WithDelay(delay time.Duration) Option {
+	return func(opts *options) {
+		opts.delay = delay
+	}
+}
+
+// Option to set the maximum number of attempts of the job
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(opts *options) {
+		opts.maxAttempts = maxAttempts
+	}
+}
@@ -0,0 +1,183 @@
+package mail
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Headers signed by DKIM when the message has them, From is always signed
+var dkimHeaders = []string{
+	"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// Length of the lines of the folded signature
+const dkimLineLength = 72
+
+// DKIMSigner signs the emails (RFC 6376) with the private key published in the DNS of the domain, at
+// {selector}._domainkey.{domain}. It uses the relaxed canonicalization, which survives the usual rewriting of the
+// headers and whitespace by the relays
+type DKIMSigner struct {
+	Domain    string
+	Selector  string
+	key       crypto.Signer
+	algorithm string // rsa-sha256 or ed25519-sha256 (RFC 8463)
+}
+
+// Constructor method for the DKIM signer, the key is a PEM encoded RSA (PKCS #1 or PKCS #8) or Ed25519 (PKCS #8)
+// private key
+func NewDKIMSigner(domain, selector string, keyPEM []byte) (*DKIMSigner, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("invalid DKIM private key: no PEM block")
+	}
+
+	var key any
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid DKIM private key: %w", err)
+	}
+
+	signer := &DKIMSigner{Domain: domain, Selector: selector}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		signer.key, signer.algorithm = key, "rsa-sha256"
+	case ed25519.PrivateKey:
+		signer.key, signer.algorithm = key, "ed25519-sha256"
+	default:
+		return nil, fmt.Errorf("unsupported DKIM private key type %T", key)
+	}
+	return signer, nil
+}
+
+// Method to sign a message built by Message.Build, it returns the message with its DKIM-Signature header
+func (signer *DKIMSigner) Sign(message []byte, timestamp time.Time) ([]byte, error) {
+	header, body, ok := bytes.Cut(message, []byte("\r\n\r\n"))
+	if !ok {
+		return nil, errors.New("invalid message: no end of headers")
+	}
+	fields := splitHeaderFields(string(header) + "\r\n")
+
+	// Hash of the body
+	bodyHash := sha256.Sum256(canonicalBody(body))
+
+	// Pick the signed headers, the last instance of a header is the one signed (RFC 6376 section 5.4.2)
+	var names []string
+	hash := sha256.New()
+	for _, name := range dkimHeaders {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if strings.EqualFold(fieldName(fields[i]), name) {
+				names = append(names, strings.ToLower(name))
+				hash.Write([]byte(canonicalHeader(fields[i]) + "\r\n"))
+				break
+			}
+		}
+	}
+	if len(names) == 0 || names[0] != "from" {
+		return nil, errors.New("invalid message: no From header")
+	}
+
+	// The signature header is signed too, with an empty b= tag and without its line break
+	value := fmt.Sprintf("v=1; a=%s; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=",
+		signer.algorithm, signer.Domain, signer.Selector, timestamp.Unix(), strings.Join(names, ":"),
+		base64.StdEncoding.EncodeToString(bodyHash[:]))
+	hash.Write([]byte(canonicalHeader("DKIM-Signature: " + value)))
+	digest := hash.Sum(nil)
+
+	var signature []byte
+	var err error
+	if signer.algorithm == "ed25519-sha256" {
+		// Ed25519 signs the SHA-256 hash itself (RFC 8463 section 3)
+		signature, err = signer.key.Sign(rand.Reader, digest, crypto.Hash(0))
+	} else {
+		signature, err = signer.key.Sign(rand.Reader, digest, crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Fold the signature, the whitespace in the b= tag is ignored by the verifiers
+	var signed bytes.Buffer
+	signed.WriteString("DKIM-Signature: " + value)
+	encoded := base64.StdEncoding.EncodeToString(signature)
+	for len(encoded) > dkimLineLength {
+		signed.WriteString(encoded[:dkimLineLength] + "\r\n ")
+		encoded = encoded[dkimLineLength:]
+	}
+	signed.WriteString(encoded + "\r\n")
+	signed.Write(message)
+	return signed.Bytes(), nil
+}
+
+// Helper function: split the header section into its fields, a folded field keeps its continuation lines
+func splitHeaderFields(header string) []string {
+	var fields []string
+	for _, line := range strings.SplitAfter(header, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSuffix(fields[i], "\r\n")
+	}
+	return fields
+}
+
+// Helper function: get the name of a header field
+func fieldName(field string) string {
+	name, _, _ := strings.Cut(field, ":")
+	return strings.TrimSpace(name)
+}
+
+// Helper function: canonicalize a header field with the relaxed algorithm (RFC 6376 section 3.4.2): lowercase name,
+// unfolded value with its whitespace reduced to single spaces and trimmed
+func canonicalHeader(field string) string {
+	name, value, _ := strings.Cut(field, ":")
+	value = strings.ReplaceAll(value, "\r\n", "")
+	return strings.ToLower(strings.TrimSpace(name)) + ":" + strings.Join(strings.FieldsFunc(value, isWSP), " ")
+}
+
+// Helper function: canonicalize the body with the relaxed algorithm (RFC 6376 section 3.4.4): whitespace reduced
+// to single spaces and removed at the end of the lines, and the empty lines at the end removed
+func canonicalBody(body []byte) []byte {
+	lines := strings.Split(string(body), "\r\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.FieldsFunc(line, isWSP), " ")
+		if len(line) > 0 && isWSP(rune(line[0])) && lines[i] != "" {
+			lines[i] = " " + lines[i]
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// Helper function: check if a character is whitespace in the sense of RFC 5234 (space or tab)
+func isWSP(r rune) bool {
+	return r == ' ' || r == '\t'
+}
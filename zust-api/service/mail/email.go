@@ -3,10 +3,13 @@ package mail
 import (
 	"fmt"
 	"net/smtp"
+	"os"
 	"strings"
 	"text/template"
+	"time"
 
 	"zust/service/security"
+	templates "zust/template"
 )
 
 // Email service struct, which holds configurations related to email sending
@@ -15,33 +18,90 @@ type EmailService struct {
 	Port  string
 	Email string
 	Auth  smtp.Auth
+	DKIM  *DKIMSigner // nil if DKIM signing is disabled
+
+	TemplatePath string // directory overriding the embedded templates, empty to use the embedded ones
 }
 
-// Constructing method for email service struct
-func NewEmailService(config *security.Config) *EmailService {
+// Constructing method for email service struct, it fails if the DKIM key can't be loaded
+func NewEmailService(config *security.Config) (*EmailService, error) {
 	// Try simple authentication
 	smtpAuth := smtp.PlainAuth("", config.Email, config.AppPassword, config.SMTPHost)
 
-	return &EmailService{
+	service := &EmailService{
 		Host:  config.SMTPHost,
 		Port:  config.SMTPPort,
 		Email: config.Email,
 		Auth:  smtpAuth,
+
+		TemplatePath: config.TemplatePath,
+	}
+
+	// Sign the emails when a DKIM key is configured
+	if config.DKIMDomain != "" {
+		key, err := os.ReadFile(config.DKIMKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DKIM key: %w", err)
+		}
+		service.DKIM, err = NewDKIMSigner(config.DKIMDomain, config.DKIMSelector, key)
+		if err != nil {
+			return nil, err
+		}
 	}
+	return service, nil
 }
 
 // Verification (account activation) email payload
 type VerificationEmailPayload struct {
 	Username string
 	Link     string
+	Code     string // numeric code, an alternative to the link
+}
+
+// Malware detection (sent to admins) email payload
+type MalwareEmailPayload struct {
+	VideoID     string
+	PublisherID string
+	Signature   string
+	Quarantine  string
+}
+
+// Strike (sent to the account given a strike) email payload
+type StrikeEmailPayload struct {
+	Username       string
+	Target         string // what the strike is for, for example: your video "Title"
+	ActiveStrikes  int
+	ExpiresAt      string
+	Suspended      bool
+	SuspendedUntil string // empty if the account is suspended until an admin reinstates it
+}
+
+// Digest (new videos of the subscriptions) email payload
+type DigestEmailPayload struct {
+	Username        string
+	Frequency       string // daily or weekly
+	Videos          []DigestVideo
+	UnsubscribeLink string
+}
+
+// A video in the digest email
+type DigestVideo struct {
+	Title    string
+	Channel  string
+	Duration string
+	Link     string
 }
 
 // Method to prepare email payload.
-// 'templ' is the path to where the HTML email located
+// 'templ' is the name of the email template (for example: verification.html)
 // Note that this method won't do any type checking whether templ and payload actually match before processing
 func (service *EmailService) PrepareEmail(templ string, payload any) (string, error) {
-	// Create buffer
-	tmpl, err := template.ParseFiles(templ)
+	// Parse the template, from the override directory or the embedded templates
+	content, err := templates.Read(service.TemplatePath, templ)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(templ).Parse(string(content))
 	if err != nil {
 		return "", err
 	}
@@ -56,30 +116,40 @@ func (service *EmailService) PrepareEmail(templ string, payload any) (string, er
 	return sb.String(), nil
 }
 
-// Method to send email
-func (service *EmailService) SendEmail(to, subject, body string) error {
-	// Set email headers with MIME version and content type
-	headers := make(map[string]string)
-	headers["From"] = service.Email
-	headers["To"] = to
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
-
-	// Build the message with headers
-	var message strings.Builder
-	for key, value := range headers {
-		message.WriteString(fmt.Sprintf("%s: %s\r\n", key, value))
+// Method to prepare the HTML and plain-text bodies of an email from the templates {name}.html and {name}.txt (for
+// example: verification), the email is sent with both
+func (service *EmailService) PrepareBodies(name string, payload any) (html, text string, err error) {
+	html, err = service.PrepareEmail(name+".html", payload)
+	if err != nil {
+		return "", "", err
+	}
+	text, err = service.PrepareEmail(name+".txt", payload)
+	if err != nil {
+		return "", "", err
+	}
+	return html, text, nil
+}
+
+// Method to send email, it's built as a MIME message then signed with DKIM if it's enabled
+func (service *EmailService) SendEmail(message *Message) error {
+	now := time.Now()
+	data, err := message.Build(service.Email, now)
+	if err != nil {
+		return err
+	}
+	if service.DKIM != nil {
+		data, err = service.DKIM.Sign(data, now)
+		if err != nil {
+			return err
+		}
 	}
-	message.WriteString("\r\n")
-	message.WriteString(body)
 
 	addr := fmt.Sprintf("%s:%s", service.Host, service.Port)
 	return smtp.SendMail(
 		addr,
 		service.Auth,
 		service.Email,
-		[]string{to},
-		[]byte(message.String()),
+		[]string{message.To},
+		data,
 	)
 }
@@ -0,0 +1,256 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Length of the lines of the Base64 encoded attachments (RFC 2045)
+const base64LineLength = 76
+
+// Error of an email which has neither a plain-text nor an HTML body
+var ErrEmptyBody = errors.New("email has no body")
+
+// An email: the plain-text and HTML bodies are sent as alternatives (the mail client shows the best one it
+// supports), at least one of them must be set
+type Message struct {
+	To          string
+	Subject     string
+	Text        string
+	HTML        string
+	Headers     map[string]string // added to the default headers, for example: List-Unsubscribe
+	Attachments []Attachment
+}
+
+// A file attached to an email
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"` // for example: application/pdf
+	Data        []byte `json:"data"`
+}
+
+// Method to build the email into its MIME form (RFC 5322 and RFC 2045), sent from 'from' at 'date'. The text is a
+// single part, or a multipart/alternative if both bodies are set; with attachments the whole is a multipart/mixed.
+// The non-ASCII header values are encoded (RFC 2047)
+func (message *Message) Build(from string, date time.Time) ([]byte, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	recipient, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient %q: %w", message.To, err)
+	}
+	messageID, err := newMessageID(sender.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the body first, its headers are part of the message headers
+	var body bytes.Buffer
+	contentHeader, err := message.writeContent(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the headers: the default ones, then the extra ones sorted by name so the message is deterministic
+	var out bytes.Buffer
+	defaults := [][2]string{
+		{"From", sender.String()},
+		{"To", recipient.String()},
+		{"Subject", mime.QEncoding.Encode("UTF-8", message.Subject)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+	}
+	for _, header := range defaults {
+		if err := writeHeader(&out, header[0], header[1]); err != nil {
+			return nil, err
+		}
+	}
+	extra := make([]string, 0, len(message.Headers))
+	for key := range message.Headers {
+		extra = append(extra, key)
+	}
+	slices.Sort(extra)
+	for _, key := range extra {
+		if err := writeHeader(&out, key, mime.QEncoding.Encode("UTF-8", message.Headers[key])); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if value := contentHeader.Get(key); value != "" {
+			if err := writeHeader(&out, key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+// Helper method: write the content of the email into w, and return the headers describing it
+func (message *Message) writeContent(w io.Writer) (textproto.MIMEHeader, error) {
+	if len(message.Attachments) == 0 {
+		return message.writeText(w)
+	}
+
+	// multipart/mixed: the text, then the attachments
+	writer := multipart.NewWriter(w)
+	var text bytes.Buffer
+	textHeader, err := message.writeText(&text)
+	if err != nil {
+		return nil, err
+	}
+	part, err := writer.CreatePart(textHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(text.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range message.Attachments {
+		if err := writeAttachment(writer, attachment); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return multipartHeader("multipart/mixed", writer.Boundary()), nil
+}
+
+// Helper method: write the text of the email into w, and return the headers describing it. The bodies are encoded
+// with quoted-printable, which keeps the lines short whatever the template
+func (message *Message) writeText(w io.Writer) (textproto.MIMEHeader, error) {
+	// The parts of a multipart/alternative go from the simplest to the richest
+	var bodies [][2]string
+	if message.Text != "" {
+		bodies = append(bodies, [2]string{"text/plain; charset=UTF-8", message.Text})
+	}
+	if message.HTML != "" {
+		bodies = append(bodies, [2]string{"text/html; charset=UTF-8", message.HTML})
+	}
+
+	switch len(bodies) {
+	case 0:
+		return nil, ErrEmptyBody
+	case 1:
+		return writeQuotedPrintable(w, bodies[0][0], bodies[0][1])
+	}
+
+	writer := multipart.NewWriter(w)
+	for _, body := range bodies {
+		var encoded bytes.Buffer
+		header, err := writeQuotedPrintable(&encoded, body[0], body[1])
+		if err != nil {
+			return nil, err
+		}
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(encoded.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return multipartHeader("multipart/alternative", writer.Boundary()), nil
+}
+
+// Helper function: write a text body with quoted-printable, and return the headers describing it
+func writeQuotedPrintable(w io.Writer, contentType, content string) (textproto.MIMEHeader, error) {
+	encoder := quotedprintable.NewWriter(w)
+	if _, err := encoder.Write([]byte(content)); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return header, nil
+}
+
+// Helper function: write an attachment as a part, Base64 encoded. A non-ASCII file name is encoded (RFC 2231)
+func writeAttachment(writer *multipart.Writer, attachment Attachment) error {
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type of attachment %q: %w", attachment.Filename, err)
+	}
+	params["name"] = attachment.Filename
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+	header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": attachment.Filename,
+	}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > base64LineLength {
+		if _, err := io.WriteString(part, encoded[:base64LineLength]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[base64LineLength:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+// Helper function: get the headers of a multipart entity
+func multipartHeader(mediaType, boundary string) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, map[string]string{"boundary": boundary}))
+	return header
+}
+
+// Helper function: write a header line, a value with a line break is refused since it would inject headers
+func writeHeader(w io.Writer, key, value string) error {
+	if strings.ContainsAny(key, "\r\n:") || strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("invalid email header %q", key)
+	}
+	_, err := fmt.Fprintf(w, "%s: %s\r\n", key, value)
+	return err
+}
+
+// Helper function: generate a unique Message-ID in the domain of the sender
+func newMessageID(sender string) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if _, after, ok := strings.Cut(sender, "@"); ok {
+		domain = after
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(id), domain), nil
+}
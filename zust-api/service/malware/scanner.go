@@ -0,0 +1,228 @@
+package malware
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"zust/service/security"
+)
+
+// Scanner is the hook called on the uploaded files before anything derived from them is published. It receives
+// the full path of a file and reports whether it's infected
+type Scanner interface {
+	Scan(ctx context.Context, path string) (*ScanResult, error)
+}
+
+// Scan result of a file
+type ScanResult struct {
+	Infected  bool
+	Signature string // name of the detected malware, if infected
+}
+
+// Constructor method for the scanner of the configured provider, it returns nil if scanning is disabled
+func NewScanner(config *security.Config) Scanner {
+	switch config.MalwareScanner {
+	case "clamav":
+		return &ClamAVScanner{Address: config.ClamAVAddress}
+	case "icap":
+		return &ICAPScanner{URL: config.ICAPURL}
+	default:
+		return nil
+	}
+}
+
+// Size of the chunks streamed to the scanners
+const chunkSize = 64 << 10
+
+// ClamAV scanner, which streams files to a clamd daemon with the INSTREAM command.
+// Note that clamd rejects streams larger than its StreamMaxLength (25M by default), which must be raised to the
+// maximum upload size
+type ClamAVScanner struct {
+	Address string // for example: localhost:3310
+}
+
+// Method to scan a file with clamd. The file is sent in chunks prefixed by their length (4 bytes, big endian),
+// terminated by a zero length chunk. clamd responds with 'stream: OK' or 'stream: {signature} FOUND'
+func (scanner *ClamAVScanner) Scan(ctx context.Context, path string) (*ScanResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", scanner.Address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	writer := bufio.NewWriter(conn)
+	if _, err := writer.WriteString("zINSTREAM\x00"); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if err := binary.Write(writer, binary.BigEndian, uint32(n)); err != nil {
+				return nil, err
+			}
+			if _, err := writer.Write(buf[:n]); err != nil {
+				return nil, err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := binary.Write(writer, binary.BigEndian, uint32(0)); err != nil {
+		return nil, err
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return &ScanResult{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return &ScanResult{Infected: true, Signature: signature}, nil
+	default:
+		return nil, fmt.Errorf("clamav scan failed: %s", reply)
+	}
+}
+
+// ICAP scanner, which sends files to an ICAP server (RFC 3507) with a RESPMOD request, as most antivirus gateways
+// support it
+type ICAPScanner struct {
+	URL string // for example: icap://localhost:1344/avscan
+}
+
+// Method to scan a file with the ICAP server. The file is encapsulated as the body of an HTTP response, the server
+// responds with 204 if it's clean, or with the modified response and an X-Infection-Found (or X-Virus-ID) header
+// if it's infected
+func (scanner *ICAPScanner) Scan(ctx context.Context, path string) (*ScanResult, error) {
+	target, err := url.Parse(scanner.URL)
+	if err != nil {
+		return nil, err
+	}
+	host := target.Host
+	if target.Port() == "" {
+		host = net.JoinHostPort(target.Hostname(), "1344")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Encapsulated HTTP response headers, the body follows them in chunked encoding
+	resHeader := fmt.Sprintf("HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n",
+		stat.Size())
+
+	writer := bufio.NewWriter(conn)
+	fmt.Fprintf(writer, "RESPMOD %s ICAP/1.0\r\n", scanner.URL)
+	fmt.Fprintf(writer, "Host: %s\r\n", target.Host)
+	fmt.Fprintf(writer, "Allow: 204\r\n")
+	fmt.Fprintf(writer, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(resHeader))
+	writer.WriteString(resHeader)
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(writer, "%x\r\n", n)
+			writer.Write(buf[:n])
+			writer.WriteString("\r\n")
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	writer.WriteString("0\r\n\r\n")
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	// Read the status line and the ICAP headers
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	status, err := reader.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	header, err := reader.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	fields := strings.Fields(status)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "ICAP/") {
+		return nil, fmt.Errorf("invalid ICAP response: %s", status)
+	}
+	switch fields[1] {
+	case "204":
+		return &ScanResult{}, nil
+	case "200":
+		signature := header.Get("X-Virus-ID")
+		if infection := header.Get("X-Infection-Found"); infection != "" {
+			signature = infectionThreat(infection)
+		}
+		if signature == "" {
+			// The response is modified without telling why, the file is not trusted
+			signature = "unknown"
+		}
+		return &ScanResult{Infected: true, Signature: signature}, nil
+	default:
+		return nil, fmt.Errorf("icap scan failed: %s", status)
+	}
+}
+
+// Helper function: get the threat name from an X-Infection-Found header, for example:
+// Type=0; Resolution=2; Threat=Eicar-Test-Signature; -> Eicar-Test-Signature
+func infectionThreat(header string) string {
+	for _, field := range strings.Split(header, ";") {
+		if name, value, found := strings.Cut(strings.TrimSpace(field), "="); found && name == "Threat" {
+			return value
+		}
+	}
+	return header
+}
@@ -0,0 +1,116 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"zust/service/security"
+)
+
+// ModerationScanner is the hook called after a video is uploaded/transcoded. It receives the sampled frames
+// (full file paths) of a video and returns the score of each category the detection service supports
+type ModerationScanner interface {
+	Scan(ctx context.Context, frames []string) (*ScanResult, error)
+}
+
+// Scan result returned by the detection service. Each score is expected to be in range [0, 1]
+type ScanResult struct {
+	Scores map[string]float64 `json:"scores"`
+}
+
+// Method to check the scan result against the configured thresholds.
+// It returns the first category whose score reaches its threshold, and whether such category exists
+func (result *ScanResult) Exceed(thresholds map[string]float64) (string, bool) {
+	for category, threshold := range thresholds {
+		if score, ok := result.Scores[category]; ok && score >= threshold {
+			return category, true
+		}
+	}
+	return "", false
+}
+
+// HTTP scanner, which sends frames to an external NSFW/violence detection service
+type HTTPScanner struct {
+	URL    string
+	APIKey string
+	client *http.Client
+}
+
+// Constructor method for HTTP scanner
+func NewHTTPScanner(config *security.Config) *HTTPScanner {
+	return &HTTPScanner{
+		URL:    config.ModerationURL,
+		APIKey: config.ModerationAPIKey,
+		client: &http.Client{},
+	}
+}
+
+// Method to send all frames to the detection service in a single multipart request.
+// The service is expected to response with JSON: {"scores": {"nsfw": 0.12, "violence": 0.8}}
+func (scanner *HTTPScanner) Scan(ctx context.Context, frames []string) (*ScanResult, error) {
+	// Build the multipart body, each frame is a 'frames' file part
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, frame := range frames {
+		if err := writeFramePart(writer, frame); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "POST", scanner.URL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	if scanner.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", scanner.APIKey))
+	}
+
+	// Perform the request
+	resp, err := scanner.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check for status code
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("moderation scan failed: %s", string(data))
+	}
+
+	// Parse response body
+	var result ScanResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Helper function: copy a frame file into a multipart file part
+func writeFramePart(writer *multipart.Writer, frame string) error {
+	src, err := os.Open(frame)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	part, err := writer.CreateFormFile("frames", filepath.Base(frame))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, src)
+	return err
+}
@@ -0,0 +1,254 @@
+// Package push sends Web Push notifications to the browsers: the payloads are encrypted for the browser (RFC 8291)
+// and the requests are signed with the VAPID key of the deployment (RFC 8292), so the push services of the browsers
+// accept them without any account
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"zust/service/security"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Record size of the encrypted payloads, a payload is sent in a single record so it's at most recordSize minus the
+// padding delimiter and the authentication tag. The VAPID tokens are valid for vapidExpiration (at most 24 hours)
+const (
+	recordSize      = 4096
+	authSecretSize  = 16
+	MaxPayloadSize  = recordSize - 17
+	vapidExpiration = 12 * time.Hour
+	requestTimeout  = 10 * time.Second
+)
+
+// Error of a subscription which is expired or unsubscribed by the browser, it should be deleted
+var ErrSubscriptionGone = errors.New("push subscription is gone")
+
+// Service sends the Web Push notifications, signed with the VAPID key pair of the deployment
+type Service struct {
+	publicKey  []byte // uncompressed P-256 point, the applicationServerKey of the browsers
+	privateKey *ecdsa.PrivateKey
+	subject    string // contact of the deployment (mailto: or https: URL) given to the push services
+	client     *http.Client
+}
+
+// A browser subscribed to the notifications, from PushSubscription.toJSON() of the browser
+type Subscription struct {
+	Endpoint string
+	P256dh   string // public key of the browser, Base64 URL encoded
+	Auth     string // authentication secret of the browser, Base64 URL encoded
+}
+
+// Method to check the keys of a subscription: the browser key must be a P-256 public key, and the authentication
+// secret 16 bytes
+func (subscription Subscription) Validate() error {
+	browserKey, err := base64.RawURLEncoding.DecodeString(subscription.P256dh)
+	if err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	if _, err := ecdh.P256().NewPublicKey(browserKey); err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(subscription.Auth)
+	if err != nil {
+		return fmt.Errorf("invalid auth secret: %w", err)
+	}
+	if len(authSecret) != authSecretSize {
+		return fmt.Errorf("invalid auth secret: %d bytes, expected %d", len(authSecret), authSecretSize)
+	}
+	return nil
+}
+
+// Constructor method for the push service. It returns nil if Web Push is disabled (no VAPID key), and an error if the
+// VAPID keys are invalid or don't match
+func NewService(config *security.Config) (*Service, error) {
+	if config.VAPIDPrivateKey == "" {
+		return nil, nil
+	}
+
+	// The keys are Base64 URL encoded, as generated by the web-push tools: the private key is the raw scalar
+	rawPrivate, err := base64.RawURLEncoding.DecodeString(config.VAPIDPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+	ecdhKey, err := ecdh.P256().NewPrivateKey(rawPrivate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+	publicKey := ecdhKey.PublicKey().Bytes()
+	if config.VAPIDPublicKey != base64.RawURLEncoding.EncodeToString(publicKey) {
+		return nil, fmt.Errorf("VAPID_PUBLIC_KEY doesn't match VAPID_PRIVATE_KEY")
+	}
+
+	privateKey := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(publicKey[1:33]),
+			Y:     new(big.Int).SetBytes(publicKey[33:]),
+		},
+		D: new(big.Int).SetBytes(rawPrivate),
+	}
+
+	return &Service{
+		publicKey:  publicKey,
+		privateKey: privateKey,
+		subject:    config.VAPIDSubject,
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: &http.Transport{DialContext: (&net.Dialer{Control: security.PublicOnly}).DialContext},
+		},
+	}, nil
+}
+
+// Method to get the public VAPID key, Base64 URL encoded, given to the browsers to subscribe
+func (service *Service) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(service.publicKey)
+}
+
+// Method to send a notification to a browser. The push service keeps it for ttl if the browser is offline. It
+// returns ErrSubscriptionGone if the subscription is expired or unsubscribed
+func (service *Service) Send(ctx context.Context, subscription Subscription, payload []byte, ttl time.Duration) error {
+	body, err := encrypt(subscription, payload)
+	if err != nil {
+		return err
+	}
+	authorization, err := service.vapid(subscription.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("push service responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Helper method: get the VAPID Authorization header of a request to a push service: a JWT signed with the private
+// key, for the origin of the endpoint
+func (service *Service) vapid(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidExpiration).Unix(),
+		"sub": service.subject,
+	})
+	signed, err := token.SignedString(service.privateKey)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, service.PublicKey()), nil
+}
+
+// Helper function: encrypt a payload for a browser with the aes128gcm content coding (RFC 8188), the keys are
+// derived from an ephemeral key pair and the keys of the browser (RFC 8291)
+func encrypt(subscription Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, fmt.Errorf("push payload is too large: %d bytes, at most %d", len(payload), MaxPayloadSize)
+	}
+
+	browserKey, err := base64.RawURLEncoding.DecodeString(subscription.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	browserPublic, err := ecdh.P256().NewPublicKey(browserKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(subscription.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+
+	// Shared secret of an ephemeral key pair and the browser
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret, err := ephemeral.ECDH(browserPublic)
+	if err != nil {
+		return nil, err
+	}
+	serverKey := ephemeral.PublicKey().Bytes()
+
+	// Input keying material from the shared secret and the auth secret, then the content key and nonce
+	keyInfo := append(append([]byte("WebPush: info\x00"), browserKey...), serverKey...)
+	ikm, err := hkdf.Key(sha256.New, sharedSecret, authSecret, string(keyInfo), 32)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	contentKey, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header (salt, record size, key ID = ephemeral public key) then the single record, ended by the 0x02 delimiter
+	body := make([]byte, 0, 16+4+1+len(serverKey)+len(payload)+1+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(serverKey)))
+	body = append(body, serverKey...)
+	plaintext := make([]byte, len(payload)+1)
+	copy(plaintext, payload)
+	plaintext[len(payload)] = 0x02
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"zust/service/security"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Attempts is the interface of the store of the failed attempts of a key (for example: the logins of a username)
+// in a window, kept in memory or shared between the instances in Redis
+type Attempts interface {
+	// Get returns the failed attempts of a key in the current window, and the time until the window ends
+	Get(ctx context.Context, key string) (int, time.Duration, error)
+
+	// Fail records a failed attempt of a key, the window starts with the first failed attempt
+	Fail(ctx context.Context, key string, window time.Duration) (int, error)
+
+	// Reset forgets the failed attempts of a key
+	Reset(ctx context.Context, key string) error
+}
+
+// Constructor method for the attempts store of the configured rate limit driver, nil if rate limiting is disabled
+func NewAttempts(config *security.Config) Attempts {
+	switch config.RateLimitDriver {
+	case "redis":
+		return NewRedisAttempts(config)
+	case "none":
+		return nil
+	default:
+		return NewMemoryAttempts()
+	}
+}
+
+// Failed attempts of a key in the memory store
+type attemptWindow struct {
+	count   int
+	expires time.Time
+}
+
+// Attempts store keeping the failed attempts in memory, each instance counts the attempts it receives
+type MemoryAttempts struct {
+	mu      sync.Mutex
+	windows map[string]*attemptWindow
+	swept   time.Time
+}
+
+// Constructor method for the memory attempts store
+func NewMemoryAttempts() *MemoryAttempts {
+	return &MemoryAttempts{windows: make(map[string]*attemptWindow), swept: time.Now()}
+}
+
+// Method to get the failed attempts of a key kept in memory
+func (attempts *MemoryAttempts) Get(ctx context.Context, key string) (int, time.Duration, error) {
+	attempts.mu.Lock()
+	defer attempts.mu.Unlock()
+
+	window, ok := attempts.windows[key]
+	if !ok {
+		return 0, 0, nil
+	}
+	left := time.Until(window.expires)
+	if left <= 0 {
+		delete(attempts.windows, key)
+		return 0, 0, nil
+	}
+	return window.count, left, nil
+}
+
+// Method to record a failed attempt of a key kept in memory
+func (attempts *MemoryAttempts) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	attempts.mu.Lock()
+	defer attempts.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(attempts.swept) >= sweepInterval {
+		attempts.sweep(now)
+	}
+
+	current, ok := attempts.windows[key]
+	if !ok || now.After(current.expires) {
+		current = &attemptWindow{expires: now.Add(window)}
+		attempts.windows[key] = current
+	}
+	current.count++
+	return current.count, nil
+}
+
+// Method to forget the failed attempts of a key kept in memory
+func (attempts *MemoryAttempts) Reset(ctx context.Context, key string) error {
+	attempts.mu.Lock()
+	defer attempts.mu.Unlock()
+	delete(attempts.windows, key)
+	return nil
+}
+
+// Helper method: remove the ended windows, so the memory doesn't grow with the number of keys
+func (attempts *MemoryAttempts) sweep(now time.Time) {
+	for key, window := range attempts.windows {
+		if now.After(window.expires) {
+			delete(attempts.windows, key)
+		}
+	}
+	attempts.swept = now
+}
+
+// Prefix of the attempts keys in Redis, so they never collide with the token buckets
+const redisAttemptsPrefix = "zust:attempts:"
+
+// Counter of the failed attempts in Redis, the window starts with the counter
+var redisFailScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// Attempts store keeping the failed attempts in Redis, shared between the instances so a key is locked out on all
+// of them
+type RedisAttempts struct {
+	client *redis.Client
+}
+
+// Constructor method for the Redis attempts store
+func NewRedisAttempts(config *security.Config) *RedisAttempts {
+	return &RedisAttempts{
+		client: redis.NewClient(&redis.Options{
+			Addr:     config.RedisAddr,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+		}),
+	}
+}
+
+// Method to get the failed attempts of a key kept in Redis
+func (attempts *RedisAttempts) Get(ctx context.Context, key string) (int, time.Duration, error) {
+	pipe := attempts.client.Pipeline()
+	count := pipe.Get(ctx, redisAttemptsPrefix+key)
+	left := pipe.PTTL(ctx, redisAttemptsPrefix+key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+
+	value, err := count.Int()
+	if err != nil {
+		return 0, 0, err
+	}
+	return value, max(left.Val(), 0), nil
+}
+
+// Method to record a failed attempt of a key kept in Redis
+func (attempts *RedisAttempts) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	count, err := redisFailScript.Run(ctx, attempts.client, []string{redisAttemptsPrefix + key},
+		window.Milliseconds()).Int()
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Method to forget the failed attempts of a key kept in Redis
+func (attempts *RedisAttempts) Reset(ctx context.Context, key string) error {
+	return attempts.client.Del(ctx, redisAttemptsPrefix+key).Err()
+}
@@ -0,0 +1,170 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+	"zust/service/security"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Policy of a token bucket: a client can send Burst requests at once, then Rate requests per second
+type Policy struct {
+	Rate  float64
+	Burst int
+}
+
+// Result of a request against its bucket
+type Result struct {
+	Allowed    bool
+	Remaining  int           // tokens left in the bucket
+	RetryAfter time.Duration // time until a token is available, when the request is not allowed
+}
+
+// Limiter is the interface of the store of the token buckets, kept in memory or shared between the instances in
+// Redis
+type Limiter interface {
+	// Allow takes a token from the bucket of a key, the bucket is created full on the first request
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// Constructor method for the limiter of the configured driver, nil if rate limiting is disabled
+func NewLimiter(config *security.Config) Limiter {
+	switch config.RateLimitDriver {
+	case "redis":
+		return NewRedisLimiter(config)
+	case "none":
+		return nil
+	default:
+		return NewMemoryLimiter()
+	}
+}
+
+// Helper function: compute the result of a request against a bucket holding 'tokens' tokens (already refilled)
+func take(tokens float64, policy Policy) (float64, Result) {
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := time.Duration((1 - tokens) / policy.Rate * float64(time.Second))
+	return tokens, Result{Allowed: false, Remaining: 0, RetryAfter: wait}
+}
+
+// Bucket of the memory limiter
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Interval between two sweeps of the full buckets of the memory limiter
+const sweepInterval = time.Minute
+
+// Limiter keeping the buckets in memory, each instance limits the requests it receives
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// Constructor method for the memory limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*bucket), swept: time.Now()}
+}
+
+// Method to take a token from a bucket kept in memory
+func (limiter *MemoryLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(limiter.swept) >= sweepInterval {
+		limiter.sweep(now)
+	}
+
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(policy.Burst), updated: now}
+		limiter.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(policy.Burst), b.tokens+now.Sub(b.updated).Seconds()*policy.Rate)
+	b.updated = now
+
+	var result Result
+	b.tokens, result = take(b.tokens, policy)
+	return result, nil
+}
+
+// Helper method: remove the buckets not used for a sweep interval, so the memory doesn't grow with the number of
+// clients. A removed bucket is created full again, which is what it would have refilled to with the configured
+// policies
+func (limiter *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range limiter.buckets {
+		if now.Sub(b.updated) >= sweepInterval {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.swept = now
+}
+
+// Prefix of the rate limit keys in Redis, so they never collide with the keys of the cache and the job queue
+const redisKeyPrefix = "zust:ratelimit:"
+
+// Token bucket in Redis, refilled and taken atomically. It returns the tokens left (as a string, to keep the
+// fraction) and whether the request is allowed
+var redisTakeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {tostring(tokens), allowed}
+`)
+
+// Limiter keeping the buckets in Redis, shared between the instances so a client is limited across all of them
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// Constructor method for the Redis limiter
+func NewRedisLimiter(config *security.Config) *RedisLimiter {
+	return &RedisLimiter{
+		client: redis.NewClient(&redis.Options{
+			Addr:     config.RedisAddr,
+			Password: config.RedisPassword,
+			DB:       config.RedisDB,
+		}),
+	}
+}
+
+// Method to take a token from a bucket kept in Redis
+func (limiter *RedisLimiter) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	now := float64(time.Now().UnixMicro()) / 1e6
+	values, err := redisTakeScript.Run(ctx, limiter.client, []string{redisKeyPrefix + key},
+		policy.Rate, policy.Burst, now).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	tokensStr, _ := values[0].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	if allowed, _ := values[1].(int64); allowed == 1 {
+		return Result{Allowed: true, Remaining: int(tokens)}, nil
+	}
+	_, result := take(tokens, policy)
+	return result, nil
+}
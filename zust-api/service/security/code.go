@@ -0,0 +1,35 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// Function to generate a random numeric code of the given number of digits, for example a code sent by SMS. The
+// leading zeros are kept
+func GenerateCode(digits int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+// Function to hash a numeric code, hex encoded. A code has too few digits for a plain hash (all of them can be
+// tried from a leaked database), so it's the HMAC-SHA256 of '{scope}:{code}' with the secret key. The scope (for
+// example the ID of the challenge) makes the hash of the same code differ between challenges
+func HashCode(key, scope, code string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "code:%s:%s", scope, code)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Function to check a numeric code against its hash, in constant time
+func CompareCode(key, scope, code, hash string) bool {
+	return hmac.Equal([]byte(HashCode(key, scope, code)), []byte(hash))
+}
@@ -0,0 +1,45 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateCode(t *testing.T) {
+	for _, digits := range []int{1, 6, 8} {
+		for range 50 {
+			code, err := GenerateCode(digits)
+			if err != nil {
+				t.Fatalf("GenerateCode(%d) failed: %v", digits, err)
+			}
+			if len(code) != digits || strings.Trim(code, "0123456789") != "" {
+				t.Fatalf("GenerateCode(%d) = %q, want %d digits", digits, code, digits)
+			}
+		}
+	}
+}
+
+func TestCompareCode(t *testing.T) {
+	hash := HashCode("secret", "challenge-1", "012345")
+
+	tests := []struct {
+		name  string
+		key   string
+		scope string
+		code  string
+		match bool
+	}{
+		{name: "right code", key: "secret", scope: "challenge-1", code: "012345", match: true},
+		{name: "wrong code", key: "secret", scope: "challenge-1", code: "012346"},
+		{name: "leading zero dropped", key: "secret", scope: "challenge-1", code: "12345"},
+		{name: "other challenge", key: "secret", scope: "challenge-2", code: "012345"},
+		{name: "other key", key: "other", scope: "challenge-1", code: "012345"},
+		{name: "empty code", key: "secret", scope: "challenge-1", code: ""},
+	}
+
+	for _, test := range tests {
+		if got := CompareCode(test.key, test.scope, test.code, hash); got != test.match {
+			t.Errorf("%s: CompareCode() = %v, want %v", test.name, got, test.match)
+		}
+	}
+}
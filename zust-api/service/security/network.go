@@ -0,0 +1,23 @@
+package security
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// Function to refuse the connections to the loopback, private and link-local addresses, it's the Control of the
+// dialers calling the URLs given by the clients, so they can't reach the internal network.
+// It's checked on the resolved address, so a public name resolving to an internal address is refused too
+func PublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() {
+		return fmt.Errorf("address %s is not public", host)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package security
+
+import "testing"
+
+func TestPublicOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{address: "93.184.216.34:443", public: true},
+		{address: "8.8.8.8:53", public: true},
+		{address: "[2606:4700:4700::1111]:443", public: true},
+		{address: "127.0.0.1:80"},
+		{address: "127.1.2.3:8080"},
+		{address: "[::1]:80"},
+		{address: "10.0.0.1:80"},
+		{address: "172.16.5.4:80"},
+		{address: "192.168.1.1:80"},
+		{address: "[fd00::1]:80"},
+		{address: "169.254.169.254:80"}, // cloud metadata
+		{address: "[fe80::1]:80"},
+		{address: "224.0.0.251:5353"},
+		{address: "0.0.0.0:80"},
+		{address: "[::]:80"},
+		{address: "[::ffff:127.0.0.1]:80"},
+		{address: "[::ffff:10.0.0.1]:80"},
+		{address: "localhost:80"}, // only resolved addresses are dialed
+		{address: "8.8.8.8"},      // no port
+	}
+
+	for _, test := range tests {
+		err := PublicOnly("tcp", test.address, nil)
+		if (err == nil) != test.public {
+			t.Errorf("PublicOnly(%q) = %v, want public: %v", test.address, err, test.public)
+		}
+	}
+}
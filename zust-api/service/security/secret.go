@@ -4,12 +4,23 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/netip"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
 )
 
 // Config struct to hold environment variables
@@ -18,9 +29,84 @@ type Config struct {
 	Domain string
 	Port   string
 
-	// Database config
-	DbDriver string
-	DbSource string
+	// TLS of the listener, served over HTTPS with HTTP/2 when both files are set. H2CEnabled accepts HTTP/2 without
+	// TLS (h2c), for deployments behind a TCP load balancer terminating TLS
+	TLSCertFile string
+	TLSKeyFile  string
+	H2CEnabled  bool
+
+	// Deadline of the requests, over it the request is answered with 504. Uploads, media streaming and other long
+	// running routes have no deadline. 0 disables the deadline
+	RequestTimeout time.Duration
+
+	// Time given to the server to stop on SIGTERM: finish the requests in flight, then stop the job workers and the
+	// other subsystems
+	ShutdownTimeout time.Duration
+
+	// Rate limiting of the requests: RateLimitDriver is 'memory' (default, per instance), 'redis' (shared between
+	// instances) or 'none'. The limits are in requests per minute: RateLimitAuth per IP on /auth/*, RateLimitMedia
+	// per IP on /media/* and /live/*, RateLimitAPI per account (or per IP if anonymous) on the other routes.
+	// TrustedProxies are the addresses (or CIDR ranges) of the proxies in front of the server: the IP of the client
+	// is the rightmost address of X-Forwarded-For which isn't a trusted proxy. X-Forwarded-For is ignored unless the
+	// request comes from a trusted proxy
+	RateLimitDriver string
+	RateLimitAuth   int
+	RateLimitMedia  int
+	RateLimitAPI    int
+	TrustedProxies  []netip.Prefix
+
+	// Lockout of the logins of a username after LoginMaxAttempts wrong passwords in LoginLockout, counted in the store
+	// of RateLimitDriver (so shared between instances with 'redis'). 0 disables the lockout
+	LoginMaxAttempts int
+	LoginLockout     time.Duration
+
+	// Serve the GraphQL endpoint (POST /graphql) along with the REST API
+	GraphQLEnabled bool
+
+	// pprof endpoints under /debug/pprof/: 'off' (default), 'admin' (admin accounts only) or 'local' (loopback
+	// only)
+	PprofMode string
+
+	// Database config. AutoMigrate applies the pending migrations when the server starts. On boot, the connection
+	// is retried with backoff for DbConnectTimeout, so the server can start before the database is up
+	DbSource         string
+	AutoMigrate      bool
+	DbConnectTimeout time.Duration
+
+	// Connection pool of the database (pgxpool). DbMaxConns is the size of the pool (0 to use the default of pgxpool:
+	// the number of CPUs, at least 4), DbMinConns connections are kept open even when idle. Connections are closed
+	// after DbMaxConnLifetime, or DbMaxConnIdleTime without use, and idle connections are checked every
+	// DbHealthCheckPeriod
+	DbMaxConns          int
+	DbMinConns          int
+	DbMaxConnLifetime   time.Duration
+	DbMaxConnIdleTime   time.Duration
+	DbHealthCheckPeriod time.Duration
+
+	// The queries taking at least DbSlowQueryThreshold are logged with their name and duration (0 means disabled).
+	// The queries and transactions failing with a transient error (serialization failure, deadlock, connection
+	// reset) are retried at most DbMaxRetries times (0 means disabled)
+	DbSlowQueryThreshold time.Duration
+	DbMaxRetries         int
+
+	// Batching of the view events (playback pings) and the playback events (player events): they are inserted in
+	// bulk once EventBatchSize events are buffered, or every EventFlushInterval
+	EventBatchSize     int
+	EventFlushInterval time.Duration
+
+	// The view and playback events are partitioned by month, the partitions older than EventRetention months are
+	// dropped (0 means the events are kept forever)
+	EventRetention int
+
+	// The trending videos (computed from the view events of the last 48 hours) are refreshed every
+	// TrendingRefreshInterval
+	TrendingRefreshInterval time.Duration
+
+	// The daily watch time of the videos is rolled up from the playback events every WatchTimeRollupInterval
+	WatchTimeRollupInterval time.Duration
+
+	// The like, view and subscriber counters are recomputed from their source tables every CounterReconcileInterval
+	CounterReconcileInterval time.Duration
 
 	// OAuth config
 	GithubClientID     string
@@ -28,10 +114,13 @@ type Config struct {
 	GoogleClientID     string
 	GoogleClientSecret string
 
-	// JWT config
+	// JWT config, the expirations are durations (TOKEN_EXPIRATION accepts a unit, like 15m, or minutes). Each login
+	// opens a session expiring RefreshTokenExpirationTime after its last refresh, the expired and revoked sessions
+	// are deleted every SessionCleanupInterval
 	SecretKey                  string
 	TokenExpirationTime        time.Duration
 	RefreshTokenExpirationTime time.Duration
+	SessionCleanupInterval     time.Duration
 
 	// Email config
 	SMTPHost    string
@@ -39,71 +128,1446 @@ type Config struct {
 	Email       string
 	AppPassword string
 
-	// Resource path
-	ResourcePath string
+	// DKIM signing of the emails, disabled if DKIMDomain is empty. DKIMKeyFile is the PEM encoded private key
+	// (RSA or Ed25519), its public key is published at {DKIMSelector}._domainkey.{DKIMDomain}
+	DKIMDomain   string
+	DKIMSelector string
+	DKIMKeyFile  string
+
+	// VAPID key pair (Base64 URL encoded, the private key is the raw P-256 scalar) signing the Web Push
+	// notifications, and the contact of the deployment (mailto: or https: URL) given to the push services. Web Push
+	// is disabled without key
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	VAPIDSubject    string
+
+	// The digests of the new videos of the subscriptions are sent after DigestHour (0-23) in the timezone of each
+	// account, the accounts due are checked every DigestCheckInterval
+	DigestHour          int
+	DigestCheckInterval time.Duration
+
+	// A webhook delivery is attempted at most WebhookMaxAttempts times (with the backoff of the jobs), and the
+	// deliveries are kept in the delivery log for WebhookDeliveryRetention
+	WebhookMaxAttempts       int
+	WebhookDeliveryRetention time.Duration
+
+	// Sites served by the deployment, loaded from TENANTS_FILE. The tenant of a request is taken from its host,
+	// requests to a host of no tenant are served by the default tenant. Empty means a single site
+	Tenants []Tenant
+
+	// Resource path, and the layout of the user repositories in it: 'flat' ({account_id}) or 'sharded'
+	// ({ab}/{cd}/{account_id}, from the hash of the account ID)
+	ResourcePath  string
+	StorageLayout string
+
+	// Directories overriding the default assets (avatar.png, cover.png) and the email templates embedded in the
+	// binary, a file missing from them falls back to the embedded one. Empty to only use the embedded files
+	AssetPath    string
+	TemplatePath string
+
+	// File upload constraint. Request bodies are limited to ImageSize on the image uploads, VideoSize on the video
+	// uploads and JSONBodySize on the other routes
+	ImageSize          int64
+	VideoSize          int64
+	JSONBodySize       int64
+	MaxVideoDuration   int // second, 0 means no limit
+	AllowedContainers  []string
+	AllowedVideoCodecs []string
+	AllowedAudioCodecs []string
+
+	// Content moderation config, moderation is disabled if ModerationURL is empty
+	ModerationURL        string
+	ModerationAPIKey     string
+	ModerationThresholds map[string]float64
+	ModerationFrames     int
+
+	// Enforcement of the strikes given by the moderators: a strike counts for StrikeExpiry, and an account reaching
+	// the number of active strikes of a rule of StrikePolicy is suspended
+	StrikeExpiry time.Duration
+	StrikePolicy []StrikeRule
+
+	// Malware scanning of uploads, disabled if MalwareScanner is 'none'. Infected files are moved into
+	// QuarantinePath
+	MalwareScanner string
+	ClamAVAddress  string
+	ICAPURL        string
+	QuarantinePath string
+
+	// Directory of the backup archives of the user repositories
+	BackupPath string
+
+	// Automatic captions config, transcription is disabled if TranscriptionProvider is 'none'.
+	// 'whisper' runs whisper.cpp locally, 'http' sends the audio to a speech-to-text service
+	TranscriptionProvider string
+	TranscriptionLanguage string
+	WhisperPath           string
+	WhisperModel          string
+	TranscriptionURL      string
+	TranscriptionAPIKey   string
+
+	// SMS provider of the step-up codes, disabled if SMSProvider is 'none'. 'twilio' sends the messages from SMSFrom
+	// with the Twilio API, or a compatible API at TwilioAPIURL
+	SMSProvider      string
+	SMSFrom          string
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioAPIURL     string
+
+	// Original file retention policy: 'keep', 'delete' or 'archive'.
+	// OriginalQuota (bytes) is the space of originals each user can keep, 0 means no original is kept
+	OriginalPolicy      string
+	OriginalArchivePath string
+	OriginalQuota       int64
+	RetentionInterval   time.Duration
+
+	// Cache-Control of media responses: MediaCacheMaxAge applies to files which can change under the same URL
+	// (avatars, covers, thumbnails, playlists, ...), MediaCacheImmutable marks video files and segments immutable
+	MediaCacheMaxAge    time.Duration
+	MediaCacheImmutable bool
 
-	// File upload constraint
-	ImageSize int64
-	VideoSize int64
+	// Per-connection bandwidth throttling of media responses (bytes per second, 0 means unlimited).
+	// MediaRateLimits caps the files of a resolution (for example: '720p'), others use MediaRateLimit
+	MediaRateLimit  int
+	MediaRateLimits map[string]int
+
+	// CDN integration: media links point at CDNURL instead of the server, signed with a token valid for
+	// CDNTokenTTL if CDNSigningKey is set. If CDNOriginSecret is set, media requests must carry it in the
+	// X-Origin-Auth header (added by the CDN when fetching from the origin)
+	CDNURL          string
+	CDNSigningKey   string
+	CDNTokenTTL     time.Duration
+	CDNOriginSecret string
+
+	// Cold storage tiering. Every ColdCheckInterval, the renditions of the videos not watched for ColdAfter are
+	// moved into the cold storage, and moved back when the video is requested. ColdStorageDriver is 'local' (default,
+	// disabled if ColdStoragePath is empty) or 'azure' (a container of Azure Blob Storage)
+	ColdStorageDriver string
+	ColdStoragePath   string
+	ColdAfter         time.Duration
+	ColdCheckInterval time.Duration
+
+	// Azure Blob Storage account of the 'azure' cold storage. AzureStorageEndpoint overrides the endpoint of the
+	// account (for example: Azurite), AzureSASTTL is the lifetime of the SAS links to its files
+	AzureStorageAccount   string
+	AzureStorageKey       string
+	AzureStorageContainer string
+	AzureStorageEndpoint  string
+	AzureSASTTL           time.Duration
+
+	// Range-aware pre-warm, disabled if PrewarmPath is empty. The renditions requested at least PrewarmMinHits
+	// times during a PrewarmInterval are copied into PrewarmPath (a fast local disk), up to the end of their most
+	// requested ranges and at most PrewarmMaxSize bytes per file
+	PrewarmPath     string
+	PrewarmMinHits  int
+	PrewarmMaxSize  int64
+	PrewarmInterval time.Duration
+
+	// Orphan file garbage collection: the storage is reconciled with the database every OrphanGCInterval
+	// (0 means disabled), files without owning row and not modified for OrphanMinAge are deleted
+	OrphanGCInterval time.Duration
+	OrphanMinAge     time.Duration
+
+	// Temporary file janitor: partial files not modified for TempMaxAge are removed every TempCleanupInterval
+	TempMaxAge          time.Duration
+	TempCleanupInterval time.Duration
+
+	// Storage health check, run every StorageCheckInterval. Uploads are rejected while the free space (in bytes)
+	// or the free inodes of the storage are below the thresholds
+	StorageCheckInterval time.Duration
+	StorageMinFree       uint64
+	StorageMinFreeInodes uint64
+
+	// Background job queue config. JobDriver is 'db' (default) or 'asynq' (Redis, for multi-instance deployments)
+	JobDriver       string
+	JobWorkers      int
+	JobPollInterval time.Duration
+	JobMaxAttempts  int
+
+	// Number of times a failed transcode is retried
+	TranscodeRetries int
+
+	// Where media jobs (transcoding, captions) run: 'local' in the API server, or 'remote' in the transcoding
+	// workers (cmd/worker) sharing the job queue, the database and the storage
+	TranscodeWorkers string
+
+	// Paths of ffmpeg and ffprobe, looked up in PATH if not absolute
+	FFmpegPath  string
+	FFprobePath string
+
+	// Resource limits of transcoding, so a big upload can't starve the API server the workers run inside.
+	// FFmpegMaxProcesses bounds the concurrent ffmpeg processes, FFmpegThreads the threads of each process
+	// (0 means ffmpeg decides), FFmpegNice is the niceness ffmpeg runs with (0-19) and TranscodeQueueLimit the
+	// number of transcode jobs waiting or running above which uploads are rejected (0 means no limit)
+	FFmpegMaxProcesses  int
+	FFmpegThreads       int
+	FFmpegNice          int
+	TranscodeQueueLimit int
+
+	// Transcoding ladder loaded from LADDER_FILE, empty means the default ladder
+	Ladder []LadderRung
+
+	// Codecs of the transcoding ladder: 'h264', 'vp9' and/or 'av1'. AV1Encoder is 'libsvtav1' (default) or 'libaom-av1'
+	TranscodeCodecs []string
+	AV1Encoder      string
+
+	// Hardware accelerated H.264 encoding: 'none' (default), 'nvenc', 'vaapi' or 'qsv'
+	HWAccel     string
+	VAAPIDevice string
+
+	// Default watermark overlaid on transcoded videos, disabled if the image is empty.
+	// Position is 'top-left', 'top-right', 'bottom-left' or 'bottom-right' (default), opacity is in [0, 1]
+	WatermarkImage    string
+	WatermarkPosition string
+	WatermarkOpacity  float64
+
+	// EBU R128 loudness normalization of the transcoded audio, empty if disabled. For example: I=-16:TP=-1.5:LRA=11
+	Loudnorm string
+
+	// Generate MPEG-DASH manifest alongside HLS
+	DASHEnabled bool
+
+	// Redis config
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+
+	// Cache of the rows read on the hot paths. CacheDriver is 'memory' (default, an LRU of CacheSize entries),
+	// 'redis' (shared between instances) or 'none'. Entries expire after CacheTTL
+	CacheDriver string
+	CacheSize   int
+	CacheTTL    time.Duration
+
+	// The token versions and sessions, checked on every authenticated request, are cached for TokenCacheTTL only:
+	// a logout missed by the cache of an instance (memory cache with several instances) is effective shortly after
+	TokenCacheTTL time.Duration
 }
 
 var config Config
 
+// ID of the tenant serving the hosts of no other tenant, and holding the accounts created before multi-tenancy
+const DefaultTenant = "default"
+
+// A site served by the deployment, with its own accounts and storage prefix. The zero values of the overrides keep
+// the global configuration
+type Tenant struct {
+	ID        string   `json:"id" yaml:"id"`       // lowercase letters, digits and dashes, for example: cooking-club
+	Hosts     []string `json:"hosts" yaml:"hosts"` // for example: cooking.example.com
+	Domain    string   `json:"domain" yaml:"domain"`
+	ImageSize int64    `json:"max_image_size" yaml:"max_image_size"`     // MB
+	VideoSize int64    `json:"max_video_upload" yaml:"max_video_upload"` // MB
+}
+
+// Method to get the configuration of a tenant: the global configuration with the overrides of the tenant
+func (config *Config) ForTenant(id string) *Config {
+	for _, tenant := range config.Tenants {
+		if tenant.ID != id {
+			continue
+		}
+
+		override := *config
+		if tenant.Domain != "" {
+			override.Domain = tenant.Domain
+		}
+		if tenant.ImageSize > 0 {
+			override.ImageSize = tenant.ImageSize << 20
+		}
+		if tenant.VideoSize > 0 {
+			override.VideoSize = tenant.VideoSize << 20
+		}
+		return &override
+	}
+	return config
+}
+
+// A rung of the transcoding ladder
+type LadderRung struct {
+	Resolution   string    `json:"resolution" yaml:"resolution"` // width:height, for example: 1920:1080
+	CRF          LadderCRF `json:"crf" yaml:"crf"`
+	AudioBitrate string    `json:"audio_bitrate" yaml:"audio_bitrate"` // for example: 128k
+	FPSCap       float64   `json:"fps_cap" yaml:"fps_cap"`             // maximum frame rate, 0 means no cap
+}
+
+// CRF of each codec for a rung of the transcoding ladder, the codecs not used by TRANSCODE_CODECS can be omitted
+type LadderCRF struct {
+	H264 int `json:"h264" yaml:"h264"`
+	VP9  int `json:"vp9" yaml:"vp9"`
+	AV1  int `json:"av1" yaml:"av1"`
+}
+
 // Load global variable to hold the configuration
 func LoadConfig(path string) error {
-	// Load .env file
-	err := godotenv.Load(path)
-	if err != nil {
+	// Load .env file if any, the variables already set in the environment take precedence. Without the file, the
+	// configuration is only read from the environment (for example: in a container)
+	if path != "" {
+		if err := godotenv.Load(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	// Fail fast with all the missing variables instead of the first one failing to parse
+	if err := requireEnv("DOMAIN", "PORT", "DB_SOURCE", "SECRET_KEY", "RESOURCE_PATH",
+		"TOKEN_EXPIRATION", "REFRESH_TOKEN_EXPIRATION", "MAX_IMAGE_SIZE", "MAX_VIDEO_UPLOAD"); err != nil {
 		return err
 	}
 
-	// Try parse environment variables to its correct type
-	tokenExpiration, err := strconv.Atoi(os.Getenv("TOKEN_EXPIRATION"))
+	// Parse token expirations, with a unit (for example: 15m, 168h) or in minutes
+	tokenExpiration, err := getEnvDuration("TOKEN_EXPIRATION", time.Minute)
 	if err != nil {
 		return err
 	}
-	refreshTokenExpiration, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_EXPIRATION"))
+	refreshTokenExpiration, err := getEnvDuration("REFRESH_TOKEN_EXPIRATION", time.Minute)
 	if err != nil {
 		return err
 	}
+	if tokenExpiration <= 0 || refreshTokenExpiration <= 0 {
+		return fmt.Errorf("TOKEN_EXPIRATION and REFRESH_TOKEN_EXPIRATION must be positive")
+	}
+
+	// Parse session cleanup interval (in hours)
+	sessionCleanupInterval, err := getEnvInt("SESSION_CLEANUP_INTERVAL", 24)
+	if err != nil {
+		return err
+	}
+	if sessionCleanupInterval < 1 {
+		return fmt.Errorf("SESSION_CLEANUP_INTERVAL must be at least 1")
+	}
 
 	// Parse image size constraint from string to int
 	imageSize, err := strconv.ParseInt(os.Getenv("MAX_IMAGE_SIZE"), 10, 64)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid MAX_IMAGE_SIZE, expect a number of MB: %w", err)
 	}
 	imageSize <<= 20 // Stored as byte
 
 	// Parse video size constraint from string to int
 	videoSize, err := strconv.ParseInt(os.Getenv("MAX_VIDEO_UPLOAD"), 10, 64)
 	if err != nil {
-		return err
+		return fmt.Errorf("invalid MAX_VIDEO_UPLOAD, expect a number of MB: %w", err)
 	}
 	videoSize <<= 20
 
+	// Parse JSON body size constraint (in KB)
+	jsonBodySize, err := getEnvInt("MAX_JSON_BODY", 1024)
+	if err != nil {
+		return err
+	}
+	if jsonBodySize < 1 {
+		return fmt.Errorf("MAX_JSON_BODY must be at least 1")
+	}
+
+	// Parse video duration constraint (in seconds)
+	maxVideoDuration, err := getEnvInt("MAX_VIDEO_DURATION", 0)
+	if err != nil {
+		return err
+	}
+
+	// Parse moderation thresholds, for example: nsfw=0.8,violence=0.9
+	thresholds, err := parseThresholds(os.Getenv("MODERATION_THRESHOLDS"))
+	if err != nil {
+		return err
+	}
+
+	// Number of frames sampled from each video for moderation, default to 5
+	moderationFrames, err := getEnvInt("MODERATION_FRAMES", 5)
+	if err != nil {
+		return err
+	}
+	if moderationFrames < 1 {
+		return fmt.Errorf("MODERATION_FRAMES must be at least 1")
+	}
+
+	// Parse strike policy, for example: 2=168h,3=indefinite suspends an account for a week at 2 active strikes and
+	// until an admin reinstates it at 3
+	strikeExpiry, err := getEnvInt("STRIKE_EXPIRY", 90)
+	if err != nil {
+		return err
+	}
+	if strikeExpiry < 1 {
+		return fmt.Errorf("STRIKE_EXPIRY must be at least 1")
+	}
+	strikePolicy, err := parseStrikePolicy(getEnv("STRIKE_POLICY", "3=indefinite"))
+	if err != nil {
+		return err
+	}
+
+	// Parse storage layout
+	storageLayout := getEnv("STORAGE_LAYOUT", "flat")
+	if storageLayout != "flat" && storageLayout != "sharded" {
+		return fmt.Errorf("invalid STORAGE_LAYOUT %q, only accept flat or sharded", storageLayout)
+	}
+
+	// Parse original file retention policy
+	originalPolicy := getEnv("ORIGINAL_POLICY", "keep")
+	if originalPolicy != "keep" && originalPolicy != "delete" && originalPolicy != "archive" {
+		return fmt.Errorf("invalid ORIGINAL_POLICY %q, only accept keep, delete or archive", originalPolicy)
+	}
+	if originalPolicy == "archive" && os.Getenv("ORIGINAL_ARCHIVE_PATH") == "" {
+		return fmt.Errorf("ORIGINAL_ARCHIVE_PATH is required when ORIGINAL_POLICY is archive")
+	}
+	originalQuota, err := getEnvInt("ORIGINAL_QUOTA", 0)
+	if err != nil {
+		return err
+	}
+	retentionInterval, err := getEnvInt("RETENTION_INTERVAL", 60)
+	if err != nil {
+		return err
+	}
+	if retentionInterval < 1 {
+		return fmt.Errorf("RETENTION_INTERVAL must be at least 1")
+	}
+
+	mediaCacheMaxAge, err := getEnvInt("MEDIA_CACHE_MAX_AGE", 300)
+	if err != nil {
+		return err
+	}
+	if mediaCacheMaxAge < 0 {
+		return fmt.Errorf("MEDIA_CACHE_MAX_AGE cannot be negative")
+	}
+
+	// Rate limits are configured in kilobits per second
+	mediaRateLimit, err := getEnvInt("MEDIA_RATE_LIMIT", 0)
+	if err != nil {
+		return err
+	}
+	if mediaRateLimit < 0 {
+		return fmt.Errorf("MEDIA_RATE_LIMIT cannot be negative")
+	}
+	mediaRateLimits, err := parseRateLimits(os.Getenv("MEDIA_RATE_LIMITS"))
+	if err != nil {
+		return err
+	}
+
+	cdnTokenTTL, err := getEnvInt("CDN_TOKEN_TTL", 60)
+	if err != nil {
+		return err
+	}
+	if cdnTokenTTL <= 0 {
+		return fmt.Errorf("CDN_TOKEN_TTL must be positive")
+	}
+
+	// Parse cold storage tiering config: stale age (in months) and interval (in hours)
+	coldAfter, err := getEnvInt("COLD_AFTER", 6)
+	if err != nil {
+		return err
+	}
+	coldCheckInterval, err := getEnvInt("COLD_CHECK_INTERVAL", 24)
+	if err != nil {
+		return err
+	}
+	if coldAfter < 1 || coldCheckInterval < 1 {
+		return fmt.Errorf("COLD_AFTER and COLD_CHECK_INTERVAL must be at least 1")
+	}
+	coldStorageDriver := getEnv("COLD_STORAGE_DRIVER", "local")
+	if coldStorageDriver != "local" && coldStorageDriver != "azure" {
+		return fmt.Errorf("invalid COLD_STORAGE_DRIVER %q, only accept local or azure", coldStorageDriver)
+	}
+
+	// Parse Azure Blob Storage config: account, key, container and SAS links lifetime (in seconds)
+	if coldStorageDriver == "azure" {
+		for _, name := range []string{"AZURE_STORAGE_ACCOUNT", "AZURE_STORAGE_KEY", "AZURE_STORAGE_CONTAINER"} {
+			if os.Getenv(name) == "" {
+				return fmt.Errorf("%s is required when COLD_STORAGE_DRIVER is azure", name)
+			}
+		}
+	}
+	azureSASTTL, err := getEnvInt("AZURE_SAS_TTL", 3600)
+	if err != nil {
+		return err
+	}
+	if azureSASTTL < 1 {
+		return fmt.Errorf("AZURE_SAS_TTL must be at least 1")
+	}
+
+	// Parse pre-warm config: min hits, max size (in MB) and interval (in seconds)
+	prewarmMinHits, err := getEnvInt("PREWARM_MIN_HITS", 20)
+	if err != nil {
+		return err
+	}
+	prewarmMaxSize, err := getEnvInt("PREWARM_MAX_SIZE", 8)
+	if err != nil {
+		return err
+	}
+	prewarmInterval, err := getEnvInt("PREWARM_INTERVAL", 300)
+	if err != nil {
+		return err
+	}
+	if prewarmMinHits < 1 || prewarmMaxSize < 1 || prewarmInterval < 1 {
+		return fmt.Errorf("PREWARM_MIN_HITS, PREWARM_MAX_SIZE and PREWARM_INTERVAL must be at least 1")
+	}
+
+	orphanGCInterval, err := getEnvInt("ORPHAN_GC_INTERVAL", 0)
+	if err != nil {
+		return err
+	}
+	orphanMinAge, err := getEnvInt("ORPHAN_MIN_AGE", 24)
+	if err != nil {
+		return err
+	}
+	if orphanGCInterval < 0 || orphanMinAge < 0 {
+		return fmt.Errorf("ORPHAN_GC_INTERVAL and ORPHAN_MIN_AGE cannot be negative")
+	}
+
+	tempMaxAge, err := getEnvInt("TEMP_MAX_AGE", 24)
+	if err != nil {
+		return err
+	}
+	tempCleanupInterval, err := getEnvInt("TEMP_CLEANUP_INTERVAL", 60)
+	if err != nil {
+		return err
+	}
+	if tempMaxAge < 1 || tempCleanupInterval < 1 {
+		return fmt.Errorf("TEMP_MAX_AGE and TEMP_CLEANUP_INTERVAL must be at least 1")
+	}
+
+	// Parse storage health check config: interval (in seconds), minimum free space (in MB) and inodes
+	storageCheckInterval, err := getEnvInt("STORAGE_CHECK_INTERVAL", 60)
+	if err != nil {
+		return err
+	}
+	if storageCheckInterval < 1 {
+		return fmt.Errorf("STORAGE_CHECK_INTERVAL must be at least 1")
+	}
+	storageMinFree, err := getEnvInt("STORAGE_MIN_FREE", 1024)
+	if err != nil {
+		return err
+	}
+	storageMinFreeInodes, err := getEnvInt("STORAGE_MIN_FREE_INODES", 10000)
+	if err != nil {
+		return err
+	}
+	if storageMinFree < 0 || storageMinFreeInodes < 0 {
+		return fmt.Errorf("STORAGE_MIN_FREE and STORAGE_MIN_FREE_INODES cannot be negative")
+	}
+
+	// Parse job queue config: number of workers, poll interval (in seconds) and default max attempts
+	jobWorkers, err := getEnvInt("JOB_WORKERS", 4)
+	if err != nil {
+		return err
+	}
+	if jobWorkers < 1 {
+		return fmt.Errorf("JOB_WORKERS must be at least 1")
+	}
+	jobPollInterval, err := getEnvInt("JOB_POLL_INTERVAL", 5)
+	if err != nil {
+		return err
+	}
+	if jobPollInterval < 1 {
+		return fmt.Errorf("JOB_POLL_INTERVAL must be at least 1")
+	}
+	jobMaxAttempts, err := getEnvInt("JOB_MAX_ATTEMPTS", 3)
+	if err != nil {
+		return err
+	}
+	if jobMaxAttempts < 1 {
+		return fmt.Errorf("JOB_MAX_ATTEMPTS must be at least 1")
+	}
+
+	jobDriver := getEnv("JOB_DRIVER", "db")
+	if jobDriver != "db" && jobDriver != "asynq" {
+		return fmt.Errorf("invalid JOB_DRIVER %q, only accept db or asynq", jobDriver)
+	}
+	if jobDriver == "asynq" && os.Getenv("REDIS_ADDR") == "" {
+		return fmt.Errorf("REDIS_ADDR is required when JOB_DRIVER is asynq")
+	}
+	redisDB, err := getEnvInt("REDIS_DB", 0)
+	if err != nil {
+		return err
+	}
+
+	// Parse cache config: driver, size (in entries) and TTL (in seconds)
+	cacheDriver := getEnv("CACHE_DRIVER", "memory")
+	if cacheDriver != "memory" && cacheDriver != "redis" && cacheDriver != "none" {
+		return fmt.Errorf("invalid CACHE_DRIVER %q, only accept memory, redis or none", cacheDriver)
+	}
+	if cacheDriver == "redis" && os.Getenv("REDIS_ADDR") == "" {
+		return fmt.Errorf("REDIS_ADDR is required when CACHE_DRIVER is redis")
+	}
+	cacheSize, err := getEnvInt("CACHE_SIZE", 10000)
+	if err != nil {
+		return err
+	}
+	cacheTTL, err := getEnvInt("CACHE_TTL", 60)
+	if err != nil {
+		return err
+	}
+	if cacheSize < 1 || cacheTTL < 1 {
+		return fmt.Errorf("CACHE_SIZE and CACHE_TTL must be at least 1")
+	}
+
+	// Parse token cache TTL (in seconds)
+	tokenCacheTTL, err := getEnvInt("TOKEN_CACHE_TTL", 10)
+	if err != nil {
+		return err
+	}
+	if tokenCacheTTL < 1 {
+		return fmt.Errorf("TOKEN_CACHE_TTL must be at least 1")
+	}
+
+	// Number of times a failed transcode is retried
+	transcodeRetries, err := getEnvInt("TRANSCODE_RETRIES", 3)
+	if err != nil {
+		return err
+	}
+	if transcodeRetries < 0 {
+		return fmt.Errorf("TRANSCODE_RETRIES must not be negative")
+	}
+
+	transcodeWorkers := getEnv("TRANSCODE_WORKERS", "local")
+	if transcodeWorkers != "local" && transcodeWorkers != "remote" {
+		return fmt.Errorf("invalid TRANSCODE_WORKERS %q, only accept local or remote", transcodeWorkers)
+	}
+
+	// Parse transcoding resource limits
+	ffmpegMaxProcesses, err := getEnvInt("FFMPEG_MAX_PROCESSES", 2)
+	if err != nil {
+		return err
+	}
+	ffmpegThreads, err := getEnvInt("FFMPEG_THREADS", 0)
+	if err != nil {
+		return err
+	}
+	ffmpegNice, err := getEnvInt("FFMPEG_NICE", 10)
+	if err != nil {
+		return err
+	}
+	if ffmpegNice < 0 || ffmpegNice > 19 {
+		return fmt.Errorf("FFMPEG_NICE must be in range [0, 19]")
+	}
+	transcodeQueueLimit, err := getEnvInt("TRANSCODE_QUEUE_LIMIT", 0)
+	if err != nil {
+		return err
+	}
+	if ffmpegMaxProcesses < 0 || ffmpegThreads < 0 || transcodeQueueLimit < 0 {
+		return fmt.Errorf("FFMPEG_MAX_PROCESSES, FFMPEG_THREADS and TRANSCODE_QUEUE_LIMIT cannot be negative")
+	}
+
+	// Parse transcoding codecs
+	transcodeCodecs := getEnvList("TRANSCODE_CODECS", []string{"h264"})
+	for _, codec := range transcodeCodecs {
+		if codec != "h264" && codec != "vp9" && codec != "av1" {
+			return fmt.Errorf("invalid TRANSCODE_CODECS %q, only accept h264, vp9 or av1", codec)
+		}
+	}
+	av1Encoder := getEnv("AV1_ENCODER", "libsvtav1")
+	if av1Encoder != "libsvtav1" && av1Encoder != "libaom-av1" {
+		return fmt.Errorf("invalid AV1_ENCODER %q, only accept libsvtav1 or libaom-av1", av1Encoder)
+	}
+
+	// Load the transcoding ladder if configured
+	var ladder []LadderRung
+	if path := os.Getenv("LADDER_FILE"); path != "" {
+		if ladder, err = loadLadder(path, transcodeCodecs); err != nil {
+			return err
+		}
+	}
+
+	// Load the tenants if configured
+	var tenants []Tenant
+	if path := os.Getenv("TENANTS_FILE"); path != "" {
+		if tenants, err = loadTenants(path); err != nil {
+			return err
+		}
+	}
+
+	// Parse transcription provider
+	transcriptionProvider := getEnv("TRANSCRIPTION_PROVIDER", "none")
+	switch transcriptionProvider {
+	case "none":
+	case "whisper":
+		if os.Getenv("WHISPER_MODEL") == "" {
+			return fmt.Errorf("WHISPER_MODEL is required when TRANSCRIPTION_PROVIDER is whisper")
+		}
+	case "http":
+		if os.Getenv("TRANSCRIPTION_URL") == "" {
+			return fmt.Errorf("TRANSCRIPTION_URL is required when TRANSCRIPTION_PROVIDER is http")
+		}
+	default:
+		return fmt.Errorf("invalid TRANSCRIPTION_PROVIDER %q, only accept none, whisper or http", transcriptionProvider)
+	}
+
+	malwareScanner := getEnv("MALWARE_SCANNER", "none")
+	switch malwareScanner {
+	case "none", "clamav":
+	case "icap":
+		if os.Getenv("ICAP_URL") == "" {
+			return fmt.Errorf("ICAP_URL is required when MALWARE_SCANNER is icap")
+		}
+	default:
+		return fmt.Errorf("invalid MALWARE_SCANNER %q, only accept none, clamav or icap", malwareScanner)
+	}
+
+	smsProvider := getEnv("SMS_PROVIDER", "none")
+	switch smsProvider {
+	case "none":
+	case "twilio":
+		if os.Getenv("TWILIO_ACCOUNT_SID") == "" || os.Getenv("TWILIO_AUTH_TOKEN") == "" || os.Getenv("SMS_FROM") == "" {
+			return fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and SMS_FROM are required when SMS_PROVIDER is twilio")
+		}
+	default:
+		return fmt.Errorf("invalid SMS_PROVIDER %q, only accept none or twilio", smsProvider)
+	}
+
+	// Parse hardware acceleration
+	hwAccel := getEnv("HW_ACCEL", "none")
+	if hwAccel != "none" && hwAccel != "nvenc" && hwAccel != "vaapi" && hwAccel != "qsv" {
+		return fmt.Errorf("invalid HW_ACCEL %q, only accept none, nvenc, vaapi or qsv", hwAccel)
+	}
+
+	// Parse loudness normalization targets: integrated loudness (LUFS), true peak (dBTP) and loudness range (LU)
+	var loudnorm string
+	if getEnv("LOUDNORM_ENABLED", "false") == "true" {
+		targets := []struct {
+			key, name, fallback string
+		}{
+			{"LOUDNORM_I", "I", "-16"},
+			{"LOUDNORM_TP", "TP", "-1.5"},
+			{"LOUDNORM_LRA", "LRA", "11"},
+		}
+		params := make([]string, 0, len(targets))
+		for _, target := range targets {
+			value := getEnv(target.key, target.fallback)
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return fmt.Errorf("invalid %s %q, expect a number", target.key, value)
+			}
+			params = append(params, fmt.Sprintf("%s=%s", target.name, value))
+		}
+		loudnorm = strings.Join(params, ":")
+	}
+
+	// Parse default watermark
+	watermarkPosition := getEnv("WATERMARK_POSITION", "bottom-right")
+	switch watermarkPosition {
+	case "top-left", "top-right", "bottom-left", "bottom-right":
+	default:
+		return fmt.Errorf("invalid WATERMARK_POSITION %q, only accept top-left, top-right, bottom-left or bottom-right",
+			watermarkPosition)
+	}
+	watermarkOpacity, err := strconv.ParseFloat(getEnv("WATERMARK_OPACITY", "0.5"), 64)
+	if err != nil || watermarkOpacity < 0 || watermarkOpacity > 1 {
+		return fmt.Errorf("invalid WATERMARK_OPACITY, expect a number between 0 and 1")
+	}
+
+	// Parse DKIM config, the key itself is checked by the email service
+	dkimDomain, dkimSelector, dkimKeyFile := os.Getenv("DKIM_DOMAIN"), os.Getenv("DKIM_SELECTOR"),
+		os.Getenv("DKIM_KEY_FILE")
+	if (dkimDomain == "") != (dkimSelector == "") || (dkimDomain == "") != (dkimKeyFile == "") {
+		return fmt.Errorf("DKIM_DOMAIN, DKIM_SELECTOR and DKIM_KEY_FILE must be set together")
+	}
+
+	// Parse TLS config: the certificate and its key are set together
+	if (os.Getenv("TLS_CERT_FILE") == "") != (os.Getenv("TLS_KEY_FILE") == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	// Parse rate limit config: driver and limits (in requests per minute)
+	rateLimitDriver := getEnv("RATE_LIMIT_DRIVER", "memory")
+	if rateLimitDriver != "memory" && rateLimitDriver != "redis" && rateLimitDriver != "none" {
+		return fmt.Errorf("invalid RATE_LIMIT_DRIVER %q, only accept memory, redis or none", rateLimitDriver)
+	}
+	if rateLimitDriver == "redis" && os.Getenv("REDIS_ADDR") == "" {
+		return fmt.Errorf("REDIS_ADDR is required when RATE_LIMIT_DRIVER is redis")
+	}
+	rateLimitAuth, err := getEnvInt("RATE_LIMIT_AUTH", 20)
+	if err != nil {
+		return err
+	}
+	rateLimitMedia, err := getEnvInt("RATE_LIMIT_MEDIA", 3000)
+	if err != nil {
+		return err
+	}
+	rateLimitAPI, err := getEnvInt("RATE_LIMIT_API", 300)
+	if err != nil {
+		return err
+	}
+	if rateLimitAuth < 1 || rateLimitMedia < 1 || rateLimitAPI < 1 {
+		return fmt.Errorf("RATE_LIMIT_AUTH, RATE_LIMIT_MEDIA and RATE_LIMIT_API must be at least 1")
+	}
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return err
+	}
+
+	// Parse login lockout config: attempts and lockout (in minutes)
+	loginMaxAttempts, err := getEnvInt("LOGIN_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return err
+	}
+	loginLockout, err := getEnvInt("LOGIN_LOCKOUT", 15)
+	if err != nil {
+		return err
+	}
+	if loginMaxAttempts < 0 || loginLockout < 1 {
+		return fmt.Errorf("LOGIN_MAX_ATTEMPTS cannot be negative and LOGIN_LOCKOUT must be at least 1")
+	}
+
+	// Parse request and shutdown timeouts (in seconds)
+	requestTimeout, err := getEnvInt("REQUEST_TIMEOUT", 30)
+	if err != nil {
+		return err
+	}
+	if requestTimeout < 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT must not be negative")
+	}
+	shutdownTimeout, err := getEnvInt("SHUTDOWN_TIMEOUT", 30)
+	if err != nil {
+		return err
+	}
+	if shutdownTimeout < 1 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must be at least 1")
+	}
+
+	// Parse database connection config
+	dbConnectTimeout, err := getEnvInt("DB_CONNECT_TIMEOUT", 60)
+	if err != nil {
+		return err
+	}
+	if dbConnectTimeout < 0 {
+		return fmt.Errorf("DB_CONNECT_TIMEOUT must not be negative")
+	}
+
+	// Parse database pool config: size, lifetimes (in minutes) and health check period (in seconds)
+	dbMaxConns, err := getEnvInt("DB_MAX_CONNS", 0)
+	if err != nil {
+		return err
+	}
+	dbMinConns, err := getEnvInt("DB_MIN_CONNS", 0)
+	if err != nil {
+		return err
+	}
+	if dbMaxConns < 0 || dbMinConns < 0 {
+		return fmt.Errorf("DB_MAX_CONNS and DB_MIN_CONNS must not be negative")
+	}
+	if dbMaxConns > 0 && dbMinConns > dbMaxConns {
+		return fmt.Errorf("DB_MIN_CONNS must not be over DB_MAX_CONNS")
+	}
+	dbMaxConnLifetime, err := getEnvInt("DB_MAX_CONN_LIFETIME", 60)
+	if err != nil {
+		return err
+	}
+	dbMaxConnIdleTime, err := getEnvInt("DB_MAX_CONN_IDLE_TIME", 30)
+	if err != nil {
+		return err
+	}
+	dbHealthCheckPeriod, err := getEnvInt("DB_HEALTH_CHECK_PERIOD", 60)
+	if err != nil {
+		return err
+	}
+	if dbMaxConnLifetime < 1 || dbMaxConnIdleTime < 1 || dbHealthCheckPeriod < 1 {
+		return fmt.Errorf("DB_MAX_CONN_LIFETIME, DB_MAX_CONN_IDLE_TIME and DB_HEALTH_CHECK_PERIOD must be at least 1")
+	}
+
+	// Parse slow query threshold (in milliseconds)
+	dbSlowQueryThreshold, err := getEnvInt("DB_SLOW_QUERY_THRESHOLD", 500)
+	if err != nil {
+		return err
+	}
+	if dbSlowQueryThreshold < 0 {
+		return fmt.Errorf("DB_SLOW_QUERY_THRESHOLD cannot be negative")
+	}
+
+	// Parse max retries of the transient database errors
+	dbMaxRetries, err := getEnvInt("DB_MAX_RETRIES", 3)
+	if err != nil {
+		return err
+	}
+	if dbMaxRetries < 0 {
+		return fmt.Errorf("DB_MAX_RETRIES cannot be negative")
+	}
+
+	// Parse view events batching config (flush interval in seconds)
+	eventBatchSize, err := getEnvInt("EVENT_BATCH_SIZE", 500)
+	if err != nil {
+		return err
+	}
+	eventFlushInterval, err := getEnvInt("EVENT_FLUSH_INTERVAL", 5)
+	if err != nil {
+		return err
+	}
+	if eventBatchSize < 1 || eventFlushInterval < 1 {
+		return fmt.Errorf("EVENT_BATCH_SIZE and EVENT_FLUSH_INTERVAL must be at least 1")
+	}
+
+	// Parse view events retention (in months)
+	eventRetention, err := getEnvInt("EVENT_RETENTION", 0)
+	if err != nil {
+		return err
+	}
+	if eventRetention < 0 {
+		return fmt.Errorf("EVENT_RETENTION cannot be negative")
+	}
+
+	// Parse trending refresh interval (in minutes)
+	trendingRefreshInterval, err := getEnvInt("TRENDING_REFRESH_INTERVAL", 5)
+	if err != nil {
+		return err
+	}
+	if trendingRefreshInterval < 1 {
+		return fmt.Errorf("TRENDING_REFRESH_INTERVAL must be at least 1")
+	}
+
+	// Parse watch time rollup interval (in minutes)
+	watchTimeRollupInterval, err := getEnvInt("WATCH_TIME_ROLLUP_INTERVAL", 60)
+	if err != nil {
+		return err
+	}
+	if watchTimeRollupInterval < 1 {
+		return fmt.Errorf("WATCH_TIME_ROLLUP_INTERVAL must be at least 1")
+	}
+
+	// Parse counter reconciliation interval (in hours)
+	counterReconcileInterval, err := getEnvInt("COUNTER_RECONCILE_INTERVAL", 24)
+	if err != nil {
+		return err
+	}
+	if counterReconcileInterval < 1 {
+		return fmt.Errorf("COUNTER_RECONCILE_INTERVAL must be at least 1")
+	}
+
+	// Parse Web Push config, the keys themselves are checked by the push service
+	vapidPublicKey, vapidPrivateKey, vapidSubject := os.Getenv("VAPID_PUBLIC_KEY"), os.Getenv("VAPID_PRIVATE_KEY"),
+		os.Getenv("VAPID_SUBJECT")
+	if (vapidPublicKey == "") != (vapidPrivateKey == "") {
+		return fmt.Errorf("VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY must be set together")
+	}
+	if vapidPrivateKey != "" && !strings.HasPrefix(vapidSubject, "mailto:") && !strings.HasPrefix(vapidSubject, "https://") {
+		return fmt.Errorf("VAPID_SUBJECT must be a mailto: or https: URL")
+	}
+
+	// Parse digest config (the check interval is in minutes)
+	digestHour, err := getEnvInt("DIGEST_HOUR", 8)
+	if err != nil {
+		return err
+	}
+	if digestHour < 0 || digestHour > 23 {
+		return fmt.Errorf("DIGEST_HOUR must be between 0 and 23")
+	}
+	digestCheckInterval, err := getEnvInt("DIGEST_CHECK_INTERVAL", 15)
+	if err != nil {
+		return err
+	}
+	if digestCheckInterval < 1 {
+		return fmt.Errorf("DIGEST_CHECK_INTERVAL must be at least 1")
+	}
+
+	// Parse webhook config (the retention is in days)
+	webhookMaxAttempts, err := getEnvInt("WEBHOOK_MAX_ATTEMPTS", 6)
+	if err != nil {
+		return err
+	}
+	if webhookMaxAttempts < 1 {
+		return fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+	webhookDeliveryRetention, err := getEnvInt("WEBHOOK_DELIVERY_RETENTION", 30)
+	if err != nil {
+		return err
+	}
+	if webhookDeliveryRetention < 1 {
+		return fmt.Errorf("WEBHOOK_DELIVERY_RETENTION must be at least 1")
+	}
+
+	// Parse pprof config
+	pprofMode := getEnv("PPROF_MODE", "off")
+	if pprofMode != "off" && pprofMode != "admin" && pprofMode != "local" {
+		return fmt.Errorf("invalid PPROF_MODE %q, only accept off, admin or local", pprofMode)
+	}
+
 	config = Config{
 		Domain:                     os.Getenv("DOMAIN"),
 		Port:                       os.Getenv("PORT"),
-		DbDriver:                   os.Getenv("DB_DRIVER"),
+		TLSCertFile:                os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:                 os.Getenv("TLS_KEY_FILE"),
+		H2CEnabled:                 getEnv("H2C_ENABLED", "false") == "true",
+		RequestTimeout:             time.Duration(requestTimeout) * time.Second,
+		ShutdownTimeout:            time.Duration(shutdownTimeout) * time.Second,
+		PprofMode:                  pprofMode,
+		GraphQLEnabled:             getEnv("GRAPHQL_ENABLED", "false") == "true",
+		RateLimitDriver:            rateLimitDriver,
+		RateLimitAuth:              rateLimitAuth,
+		RateLimitMedia:             rateLimitMedia,
+		RateLimitAPI:               rateLimitAPI,
+		TrustedProxies:             trustedProxies,
+		LoginMaxAttempts:           loginMaxAttempts,
+		LoginLockout:               time.Duration(loginLockout) * time.Minute,
 		DbSource:                   os.Getenv("DB_SOURCE"),
+		AutoMigrate:                getEnv("AUTO_MIGRATE", "false") == "true",
+		DbConnectTimeout:           time.Duration(dbConnectTimeout) * time.Second,
+		DbMaxConns:                 dbMaxConns,
+		DbMinConns:                 dbMinConns,
+		DbMaxConnLifetime:          time.Duration(dbMaxConnLifetime) * time.Minute,
+		DbMaxConnIdleTime:          time.Duration(dbMaxConnIdleTime) * time.Minute,
+		DbHealthCheckPeriod:        time.Duration(dbHealthCheckPeriod) * time.Second,
+		DbSlowQueryThreshold:       time.Duration(dbSlowQueryThreshold) * time.Millisecond,
+		DbMaxRetries:               dbMaxRetries,
+		EventBatchSize:             eventBatchSize,
+		EventFlushInterval:         time.Duration(eventFlushInterval) * time.Second,
+		EventRetention:             eventRetention,
+		TrendingRefreshInterval:    time.Duration(trendingRefreshInterval) * time.Minute,
+		WatchTimeRollupInterval:    time.Duration(watchTimeRollupInterval) * time.Minute,
+		CounterReconcileInterval:   time.Duration(counterReconcileInterval) * time.Hour,
 		GithubClientID:             os.Getenv("GITHUB_CLIENT_ID"),
 		GithubClientSecret:         os.Getenv("GITHUB_CLIENT_SECRET"),
 		GoogleClientID:             os.Getenv("GOOGLE_CLIENT_ID"),
 		GoogleClientSecret:         os.Getenv("GOOGLE_CLIENT_SECRET"),
 		SecretKey:                  os.Getenv("SECRET_KEY"),
-		TokenExpirationTime:        time.Duration(tokenExpiration),
-		RefreshTokenExpirationTime: time.Duration(refreshTokenExpiration),
+		TokenExpirationTime:        tokenExpiration,
+		RefreshTokenExpirationTime: refreshTokenExpiration,
+		SessionCleanupInterval:     time.Duration(sessionCleanupInterval) * time.Hour,
 		SMTPHost:                   os.Getenv("SMTP_HOST"),
 		SMTPPort:                   os.Getenv("SMTP_PORT"),
 		Email:                      os.Getenv("EMAIL"),
 		AppPassword:                os.Getenv("APP_PASSWORD"),
+		DKIMDomain:                 dkimDomain,
+		DKIMSelector:               dkimSelector,
+		DKIMKeyFile:                dkimKeyFile,
+		VAPIDPublicKey:             vapidPublicKey,
+		VAPIDPrivateKey:            vapidPrivateKey,
+		VAPIDSubject:               vapidSubject,
+		DigestHour:                 digestHour,
+		DigestCheckInterval:        time.Duration(digestCheckInterval) * time.Minute,
+		WebhookMaxAttempts:         webhookMaxAttempts,
+		WebhookDeliveryRetention:   time.Duration(webhookDeliveryRetention) * 24 * time.Hour,
 		ResourcePath:               os.Getenv("RESOURCE_PATH"),
+		StorageLayout:              storageLayout,
+		AssetPath:                  os.Getenv("ASSET_PATH"),
+		TemplatePath:               os.Getenv("TEMPLATE_PATH"),
 		ImageSize:                  imageSize,
 		VideoSize:                  videoSize,
+		JSONBodySize:               int64(jsonBodySize) << 10,
+		MaxVideoDuration:           maxVideoDuration,
+		AllowedContainers:          getEnvList("ALLOWED_CONTAINERS", []string{"mp4", "mov", "webm"}),
+		AllowedVideoCodecs:         getEnvList("ALLOWED_VIDEO_CODECS", []string{"h264", "hevc", "vp9", "av1"}),
+		AllowedAudioCodecs:         getEnvList("ALLOWED_AUDIO_CODECS", []string{"aac", "mp3", "opus", "vorbis"}),
+		ModerationURL:              os.Getenv("MODERATION_URL"),
+		ModerationAPIKey:           os.Getenv("MODERATION_API_KEY"),
+		ModerationThresholds:       thresholds,
+		ModerationFrames:           moderationFrames,
+		StrikeExpiry:               time.Duration(strikeExpiry) * 24 * time.Hour,
+		StrikePolicy:               strikePolicy,
+		MalwareScanner:             malwareScanner,
+		ClamAVAddress:              getEnv("CLAMAV_ADDRESS", "localhost:3310"),
+		ICAPURL:                    os.Getenv("ICAP_URL"),
+		QuarantinePath:             getEnv("QUARANTINE_PATH", "quarantine"),
+		BackupPath:                 getEnv("BACKUP_PATH", "backup"),
+		TranscriptionProvider:      transcriptionProvider,
+		TranscriptionLanguage:      getEnv("TRANSCRIPTION_LANGUAGE", "auto"),
+		WhisperPath:                getEnv("WHISPER_PATH", "whisper-cli"),
+		WhisperModel:               os.Getenv("WHISPER_MODEL"),
+		TranscriptionURL:           os.Getenv("TRANSCRIPTION_URL"),
+		TranscriptionAPIKey:        os.Getenv("TRANSCRIPTION_API_KEY"),
+		SMSProvider:                smsProvider,
+		SMSFrom:                    os.Getenv("SMS_FROM"),
+		TwilioAccountSID:           os.Getenv("TWILIO_ACCOUNT_SID"),
+		TwilioAuthToken:            os.Getenv("TWILIO_AUTH_TOKEN"),
+		TwilioAPIURL:               getEnv("TWILIO_API_URL", "https://api.twilio.com"),
+		OriginalPolicy:             originalPolicy,
+		OriginalArchivePath:        os.Getenv("ORIGINAL_ARCHIVE_PATH"),
+		OriginalQuota:              int64(originalQuota) << 20, // Stored as byte
+		RetentionInterval:          time.Duration(retentionInterval) * time.Minute,
+		MediaCacheMaxAge:           time.Duration(mediaCacheMaxAge) * time.Second,
+		MediaCacheImmutable:        getEnv("MEDIA_CACHE_IMMUTABLE", "true") == "true",
+		MediaRateLimit:             mediaRateLimit * 1000 / 8, // Stored as byte per second
+		MediaRateLimits:            mediaRateLimits,
+		CDNURL:                     strings.TrimSuffix(os.Getenv("CDN_URL"), "/"),
+		CDNSigningKey:              os.Getenv("CDN_SIGNING_KEY"),
+		CDNTokenTTL:                time.Duration(cdnTokenTTL) * time.Minute,
+		CDNOriginSecret:            os.Getenv("CDN_ORIGIN_SECRET"),
+		ColdStorageDriver:          coldStorageDriver,
+		ColdStoragePath:            os.Getenv("COLD_STORAGE_PATH"),
+		AzureStorageAccount:        os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		AzureStorageKey:            os.Getenv("AZURE_STORAGE_KEY"),
+		AzureStorageContainer:      os.Getenv("AZURE_STORAGE_CONTAINER"),
+		AzureStorageEndpoint:       os.Getenv("AZURE_STORAGE_ENDPOINT"),
+		AzureSASTTL:                time.Duration(azureSASTTL) * time.Second,
+		ColdAfter:                  time.Duration(coldAfter) * 30 * 24 * time.Hour,
+		ColdCheckInterval:          time.Duration(coldCheckInterval) * time.Hour,
+		PrewarmPath:                os.Getenv("PREWARM_PATH"),
+		PrewarmMinHits:             prewarmMinHits,
+		PrewarmMaxSize:             int64(prewarmMaxSize) << 20,
+		PrewarmInterval:            time.Duration(prewarmInterval) * time.Second,
+		OrphanGCInterval:           time.Duration(orphanGCInterval) * time.Hour,
+		OrphanMinAge:               time.Duration(orphanMinAge) * time.Hour,
+		TempMaxAge:                 time.Duration(tempMaxAge) * time.Hour,
+		TempCleanupInterval:        time.Duration(tempCleanupInterval) * time.Minute,
+		StorageCheckInterval:       time.Duration(storageCheckInterval) * time.Second,
+		StorageMinFree:             uint64(storageMinFree) << 20,
+		StorageMinFreeInodes:       uint64(storageMinFreeInodes),
+		JobDriver:                  jobDriver,
+		JobWorkers:                 jobWorkers,
+		JobPollInterval:            time.Duration(jobPollInterval) * time.Second,
+		JobMaxAttempts:             jobMaxAttempts,
+		TranscodeRetries:           transcodeRetries,
+		TranscodeWorkers:           transcodeWorkers,
+		FFmpegPath:                 getEnv("FFMPEG_PATH", "ffmpeg"),
+		FFprobePath:                getEnv("FFPROBE_PATH", "ffprobe"),
+		FFmpegMaxProcesses:         ffmpegMaxProcesses,
+		FFmpegThreads:              ffmpegThreads,
+		FFmpegNice:                 ffmpegNice,
+		TranscodeQueueLimit:        transcodeQueueLimit,
+		Ladder:                     ladder,
+		Tenants:                    tenants,
+		TranscodeCodecs:            transcodeCodecs,
+		AV1Encoder:                 av1Encoder,
+		HWAccel:                    hwAccel,
+		VAAPIDevice:                getEnv("VAAPI_DEVICE", "/dev/dri/renderD128"),
+		WatermarkImage:             os.Getenv("WATERMARK_IMAGE"),
+		WatermarkPosition:          watermarkPosition,
+		WatermarkOpacity:           watermarkOpacity,
+		Loudnorm:                   loudnorm,
+		DASHEnabled:                getEnv("DASH_ENABLED", "false") == "true",
+		RedisAddr:                  os.Getenv("REDIS_ADDR"),
+		RedisPassword:              os.Getenv("REDIS_PASSWORD"),
+		RedisDB:                    redisDB,
+		CacheDriver:                cacheDriver,
+		CacheSize:                  cacheSize,
+		CacheTTL:                   time.Duration(cacheTTL) * time.Second,
+		TokenCacheTTL:              time.Duration(tokenCacheTTL) * time.Second,
 	}
 	return err
 }
 
+// Helper function: get an environment variable, or the fallback value if it's not set
+func getEnv(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Helper function: get a comma separated list environment variable, or the fallback value if it's not set
+func getEnvList(key string, fallback []string) []string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Environment variables which can be given as command line flags instead, the flag is named after the variable in
+// lower case with dashes (for example: -db-source for DB_SOURCE)
+var flagEnv = []string{"DOMAIN", "PORT", "DB_SOURCE", "SECRET_KEY", "RESOURCE_PATH", "TOKEN_EXPIRATION",
+	"REFRESH_TOKEN_EXPIRATION", "MAX_IMAGE_SIZE", "MAX_VIDEO_UPLOAD"}
+
+// Function to define the flags of the required environment variables on 'flags'. Once the flags are parsed, the
+// returned function sets the variables of the flags given on the command line, so they take precedence over both
+// the environment and the .env file when the configuration is loaded
+func EnvFlags(flags *flag.FlagSet) func() error {
+	keys := make(map[string]string, len(flagEnv))
+	for _, key := range flagEnv {
+		name := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+		flags.String(name, "", fmt.Sprintf("overrides the %s environment variable", key))
+		keys[name] = key
+	}
+
+	return func() error {
+		var err error
+		flags.Visit(func(f *flag.Flag) {
+			if key, ok := keys[f.Name]; ok && err == nil {
+				err = os.Setenv(key, f.Value.String())
+			}
+		})
+		return err
+	}
+}
+
+// Helper function: check that the required environment variables are set, the error lists all the missing ones
+func requireEnv(keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		if strings.TrimSpace(os.Getenv(key)) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required environment variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Helper function: parse a duration environment variable, either with a unit (for example: 90s, 15m, 168h) or as
+// a number of 'unit'
+func getEnvDuration(key string, unit time.Duration) (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(key))
+	if number, err := strconv.Atoi(value); err == nil {
+		return time.Duration(number) * unit, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q, expect a duration like 15m or a number", key, value)
+	}
+	return duration, nil
+}
+
+// Helper function: get an integer environment variable, or the fallback value if it's not set
+func getEnvInt(key string, fallback int) (int, error) {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q, expect an integer", key, value)
+	}
+	return number, nil
+}
+
+// Format of a tenant ID, it's used as a directory name in the storage
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,29}$`)
+
+// Helper function: load the tenants from a JSON (.json) or YAML (.yaml, .yml) file, which is a list of
+//
+//   - id: cooking-club
+//     hosts: [cooking.example.com]
+//     domain: cooking.example.com
+//     max_video_upload: 500
+//
+// Each tenant needs at least one host, and a host belongs to one tenant only
+func loadTenants(path string) ([]Tenant, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []Tenant
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &tenants)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tenants)
+	default:
+		return nil, fmt.Errorf("invalid TENANTS_FILE %q, only accept .json, .yaml or .yml file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TENANTS_FILE: %w", err)
+	}
+
+	ids := make(map[string]bool, len(tenants))
+	hosts := make(map[string]string)
+	for i := range tenants {
+		tenant := &tenants[i]
+		if !tenantIDPattern.MatchString(tenant.ID) {
+			return nil, fmt.Errorf("invalid tenant ID %q, only accept lowercase letters, digits and dashes", tenant.ID)
+		}
+		if ids[tenant.ID] {
+			return nil, fmt.Errorf("duplicated tenant ID %q", tenant.ID)
+		}
+		ids[tenant.ID] = true
+
+		if len(tenant.Hosts) == 0 {
+			return nil, fmt.Errorf("tenant %q must have at least one host", tenant.ID)
+		}
+		for j, host := range tenant.Hosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if other, ok := hosts[host]; ok {
+				return nil, fmt.Errorf("host %q belongs to both tenant %q and %q", host, other, tenant.ID)
+			}
+			hosts[host] = tenant.ID
+			tenant.Hosts[j] = host
+		}
+
+		if tenant.ImageSize < 0 || tenant.VideoSize < 0 {
+			return nil, fmt.Errorf("upload limits of tenant %q must not be negative", tenant.ID)
+		}
+	}
+	return tenants, nil
+}
+
+// Helper function: load the transcoding ladder from a JSON (.json) or YAML (.yaml, .yml) file, which is a list of
+// rungs, for example in YAML:
+//
+//   - resolution: 1920:1080
+//     crf: {h264: 23, vp9: 31, av1: 30}
+//     audio_bitrate: 128k
+//     fps_cap: 60
+//
+// Rungs are validated against the enabled codecs and sorted from the highest to the lowest resolution
+func loadLadder(path string, codecs []string) ([]LadderRung, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ladder []LadderRung
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &ladder)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &ladder)
+	default:
+		return nil, fmt.Errorf("invalid LADDER_FILE %q, only accept .json, .yaml or .yml file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse LADDER_FILE: %w", err)
+	}
+	if len(ladder) == 0 {
+		return nil, fmt.Errorf("LADDER_FILE must define at least one rung")
+	}
+
+	heights := make(map[int]bool, len(ladder))
+	for i := range ladder {
+		rung := &ladder[i]
+
+		width, height, found := strings.Cut(rung.Resolution, ":")
+		w, wErr := strconv.Atoi(width)
+		h, hErr := strconv.Atoi(height)
+		if !found || wErr != nil || hErr != nil || w <= 0 || h <= 0 {
+			return nil, fmt.Errorf("invalid ladder resolution %q, expect format width:height", rung.Resolution)
+		}
+		if heights[h] {
+			return nil, fmt.Errorf("duplicated ladder resolution %dp", h)
+		}
+		heights[h] = true
+
+		crfs := map[string]int{"h264": rung.CRF.H264, "vp9": rung.CRF.VP9, "av1": rung.CRF.AV1}
+		for _, codec := range codecs {
+			if crfs[codec] <= 0 || crfs[codec] > 63 {
+				return nil, fmt.Errorf("invalid %s CRF of ladder resolution %dp", codec, h)
+			}
+		}
+
+		if rung.AudioBitrate == "" {
+			rung.AudioBitrate = "128k"
+		}
+		if rung.FPSCap < 0 {
+			return nil, fmt.Errorf("invalid fps cap of ladder resolution %dp", h)
+		}
+	}
+
+	slices.SortFunc(ladder, func(a, b LadderRung) int {
+		_, ha, _ := strings.Cut(a.Resolution, ":")
+		_, hb, _ := strings.Cut(b.Resolution, ":")
+		x, _ := strconv.Atoi(ha)
+		y, _ := strconv.Atoi(hb)
+		return y - x
+	})
+	return ladder, nil
+}
+
+// Helper function: parse a comma separated list of category=threshold pairs
+func parseThresholds(str string) (map[string]float64, error) {
+	thresholds := make(map[string]float64)
+	for _, pair := range strings.Split(str, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		category, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid threshold %q, expect format category=value", pair)
+		}
+
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, err
+		}
+		thresholds[strings.TrimSpace(category)] = threshold
+	}
+	return thresholds, nil
+}
+
+// A rule of the strike policy: an account reaching Strikes active strikes is suspended for Suspension, or until an
+// admin reinstates it if Suspension is 0
+type StrikeRule struct {
+	Strikes    int
+	Suspension time.Duration
+}
+
+// Helper function: parse a comma separated list of strikes=suspension pairs, the suspension is a duration (for
+// example: 168h) or 'indefinite'. The rules are sorted by number of strikes
+func parseStrikePolicy(str string) ([]StrikeRule, error) {
+	var rules []StrikeRule
+	for _, pair := range strings.Split(str, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		count, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid strike rule %q, expect format strikes=suspension", pair)
+		}
+
+		strikes, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || strikes < 1 {
+			return nil, fmt.Errorf("invalid number of strikes %q in strike rule, expect a positive integer", count)
+		}
+		if slices.ContainsFunc(rules, func(rule StrikeRule) bool { return rule.Strikes == strikes }) {
+			return nil, fmt.Errorf("duplicate strike rule for %d strikes", strikes)
+		}
+
+		rule := StrikeRule{Strikes: strikes}
+		if value = strings.TrimSpace(value); value != "indefinite" {
+			if rule.Suspension, err = time.ParseDuration(value); err != nil || rule.Suspension <= 0 {
+				return nil, fmt.Errorf("invalid suspension %q in strike rule, expect a duration like 168h or indefinite",
+					value)
+			}
+		}
+		rules = append(rules, rule)
+	}
+
+	slices.SortFunc(rules, func(x, y StrikeRule) int {
+		return x.Strikes - y.Strikes
+	})
+	return rules, nil
+}
+
+// Helper function: parse a comma separated list of IP addresses and CIDR ranges, an address is a range of itself
+func parseTrustedProxies(str string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, item := range strings.Split(str, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		if addr, err := netip.ParseAddr(item); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q, expect an IP address or a CIDR range", item)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// Helper function: parse a comma separated list of resolution=kbps pairs into rates in bytes per second
+func parseRateLimits(str string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(str, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		resolution, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid rate limit %q, expect format resolution=kbps", pair)
+		}
+
+		resolution = strings.TrimSpace(resolution)
+		height, err := strconv.Atoi(strings.TrimSuffix(resolution, "p"))
+		if err != nil || !strings.HasSuffix(resolution, "p") || height <= 0 {
+			return nil, fmt.Errorf("invalid resolution %q in rate limit, expect format like 720p", resolution)
+		}
+
+		kbps, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		if kbps < 0 {
+			return nil, fmt.Errorf("rate limit of %s cannot be negative", resolution)
+		}
+		limits[resolution] = kbps * 1000 / 8
+	}
+	return limits, nil
+}
+
 // Method to get the configuration
 func GetConfig() Config {
 	return config
@@ -0,0 +1,30 @@
+package security
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+func TestEnvFlags(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DOMAIN", "http://localhost")
+	t.Setenv("DB_SOURCE", "")
+
+	flags := flag.NewFlagSet("zust", flag.ContinueOnError)
+	applyFlags := EnvFlags(flags)
+	if err := flags.Parse([]string{"-port", "9090", "-db-source", "postgres://zust@db/zust"}); err != nil {
+		t.Fatalf("failed to parse flags: %v", err)
+	}
+	if err := applyFlags(); err != nil {
+		t.Fatalf("failed to apply flags: %v", err)
+	}
+
+	// The flags given override the environment, the others keep it
+	want := map[string]string{"PORT": "9090", "DB_SOURCE": "postgres://zust@db/zust", "DOMAIN": "http://localhost"}
+	for key, value := range want {
+		if got := os.Getenv(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
@@ -2,6 +2,10 @@ package security
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -26,6 +30,7 @@ type CustomClaims struct {
 	Role                 string `json:"role"`
 	TokenType            string `json:"token_type"`
 	Version              int    `json:"version"`
+	SessionID            string `json:"sid,omitempty"` // Session of the login, empty for the tokens issued before sessions
 	jwt.RegisteredClaims        // Embed the JWT Registered claims
 }
 
@@ -33,15 +38,15 @@ type CustomClaims struct {
 func NewJWTService(config *Config) *JWTService {
 	return &JWTService{
 		SecretKey:                  []byte(config.SecretKey),
-		TokenExpirationTime:        config.TokenExpirationTime * time.Minute,
-		RefreshTokenExpirationTime: config.RefreshTokenExpirationTime * time.Minute,
+		TokenExpirationTime:        config.TokenExpirationTime,
+		RefreshTokenExpirationTime: config.RefreshTokenExpirationTime,
 	}
 }
 
-// Method to create a new JWT token. It receive account ID, username, avatar, role, token type (access or refresh),
-// version and expiration time then return the signed token (string) or error
+// Method to create a new JWT token. It receive account ID, token type (access or refresh), session ID, version and
+// expiration time then return the signed token (string) or error
 func (service *JWTService) CreateToken(
-	accID, tokenType string, version int, expiration time.Duration) (string, error) {
+	accID, tokenType, sessionID string, version int, expiration time.Duration) (string, error) {
 	// Check for token type value
 	if tokenType = strings.TrimSpace(tokenType); tokenType != "refresh-token" && tokenType != "access-token" {
 		return "", fmt.Errorf("invalid token type, only accept refresh-token or access-token")
@@ -52,7 +57,9 @@ func (service *JWTService) CreateToken(
 		ID:        accID,
 		TokenType: tokenType,
 		Version:   version,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),                               // Unique, so each rotated token has its own hash
 			Issuer:    "Zust",                                         // Who issue this token
 			Subject:   accID,                                          // Whom the token is about
 			IssuedAt:  jwt.NewNumericDate(time.Now()),                 // When the token is created
@@ -72,8 +79,63 @@ func (service *JWTService) CreateToken(
 	return tokenStr, nil
 }
 
+// TokenVersionStore is the source of the token versions checked by VerifyToken. *db.Queries reads them from the
+// database, the server reads them through its cache since they are checked on every authenticated request
+type TokenVersionStore interface {
+	// GetTokenVersion returns the token version of an account
+	GetTokenVersion(ctx context.Context, accountID uuid.UUID) (int32, error)
+
+	// GetSessionTokenVersion returns the token version of the account of a session, sql.ErrNoRows if the session
+	// is revoked or expired
+	GetSessionTokenVersion(ctx context.Context, arg db.GetSessionTokenVersionParams) (int32, error)
+}
+
 // Method to verify the token. It receive the signed token (string) and return the custom claims or error
-func (service *JWTService) VerifyToken(signedToken string, query *db.Queries) (*CustomClaims, error) {
+func (service *JWTService) VerifyToken(ctx context.Context, signedToken string,
+	versions TokenVersionStore) (*CustomClaims, error) {
+	claims, err := service.ParseToken(signedToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if token version is correct with database
+	var accountID uuid.UUID
+	err = accountID.Scan(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account ID in token")
+	}
+
+	// The tokens of a session are only valid while the session is neither revoked nor expired
+	var version int32
+	if claims.SessionID != "" {
+		var sessionID uuid.UUID
+		if err := sessionID.Scan(claims.SessionID); err != nil {
+			return nil, fmt.Errorf("invalid session ID in token")
+		}
+		version, err = versions.GetSessionTokenVersion(ctx, db.GetSessionTokenVersionParams{
+			SessionID: sessionID,
+			AccountID: accountID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("session is revoked or expired")
+		}
+	} else {
+		version, err = versions.GetTokenVersion(ctx, accountID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get token version from database: %v", err)
+	}
+	if int(version) != claims.Version {
+		return nil, fmt.Errorf("token version is not valid")
+	}
+
+	return claims, nil
+}
+
+// Method to check the signature, issuer and type of a token without the database, so a revoked token (with an
+// outdated version) is still accepted. Only use it where the account doesn't need to be authenticated, for example
+// to pick the rate limit bucket of a request
+func (service *JWTService) ParseToken(signedToken string) (*CustomClaims, error) {
 	// Use custom parser with deley to 30 secs
 	parser := jwt.NewParser(jwt.WithLeeway(30 * time.Second))
 
@@ -107,19 +169,11 @@ func (service *JWTService) VerifyToken(signedToken string, query *db.Queries) (*
 		return nil, fmt.Errorf("invalid token type")
 	}
 
-	// Check if token version is correct with database
-	var uuid uuid.UUID
-	err = uuid.Scan(claims.ID)
-	if err != nil {
-		return nil, fmt.Errorf("invalid account ID in token")
-	}
-	version, err := query.GetTokenVersion(context.Background(), uuid)
-	if err != nil {
-		return nil, fmt.Errorf("cannot get token version from database: %v", err)
-	}
-	if int(version) != claims.Version {
-		return nil, fmt.Errorf("token version is not valid")
-	}
-
 	return claims, nil
 }
+
+// Function to hash a refresh token, only the hash of the current refresh token of a session is stored
+func HashToken(signedToken string) string {
+	sum := sha256.Sum256([]byte(signedToken))
+	return hex.EncodeToString(sum[:])
+}
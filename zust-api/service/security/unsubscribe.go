@@ -0,0 +1,53 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of non-essential emails an account can unsubscribe from
+const (
+	UnsubscribeDigest = "digest"
+)
+
+// Error of an unsubscribe token which is malformed or not signed with the secret key
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+
+// Function to sign the unsubscribe token of an account for a kind of email. The token is '{payload}.{signature}':
+// the payload is the Base64 URL encoded '{account_id}:{kind}', the signature is the hex encoded HMAC-SHA256 of the
+// payload. It doesn't expire, so the link of an old email still works
+func SignUnsubscribeToken(key, accountID, kind string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(accountID + ":" + kind))
+	return payload + "." + signUnsubscribePayload(key, payload)
+}
+
+// Function to verify an unsubscribe token, and return the account and the kind of email it unsubscribes from
+func VerifyUnsubscribeToken(key, token string) (accountID, kind string, err error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signUnsubscribePayload(key, payload))) {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	accountID, kind, ok = strings.Cut(string(data), ":")
+	if !ok {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return accountID, kind, nil
+}
+
+// Helper function: sign the payload of an unsubscribe token, the purpose is part of the signed data so the
+// signature can't be reused by another feature signing with the same key
+func signUnsubscribePayload(key, payload string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "unsubscribe:%s", payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
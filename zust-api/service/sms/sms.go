@@ -0,0 +1,94 @@
+// Package sms sends the text messages of the server, for example the codes of the step-up verification of the
+// sensitive actions
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"zust/service/security"
+)
+
+// Timeout of a request to the SMS provider
+const requestTimeout = 10 * time.Second
+
+// Sender sends a text message to a phone number (E.164, for example: +14155550100)
+type Sender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// Constructor method for the sender selected by the config, it returns nil if SMS is disabled
+func NewSender(config *security.Config) Sender {
+	switch config.SMSProvider {
+	case "twilio":
+		return &TwilioSender{
+			URL:        strings.TrimSuffix(config.TwilioAPIURL, "/"),
+			AccountSID: config.TwilioAccountSID,
+			AuthToken:  config.TwilioAuthToken,
+			From:       config.SMSFrom,
+			client:     &http.Client{Timeout: requestTimeout},
+		}
+	default:
+		return nil
+	}
+}
+
+// Sender using the Messages API of Twilio, or of a provider with a compatible API
+type TwilioSender struct {
+	URL        string // base URL of the API, for example: https://api.twilio.com
+	AccountSID string
+	AuthToken  string
+	From       string // phone number or messaging service the messages are sent from
+	client     *http.Client
+}
+
+// Error body of the Twilio API
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Method to send a text message with the Twilio API
+func (twilio *TwilioSender) Send(ctx context.Context, to, body string) error {
+	/*
+	 * Request:
+	 * POST {url}/2010-04-01/Accounts/{account_sid}/Messages.json
+	 * Authorization: Basic {account_sid}:{auth_token}
+	 * To={to}&From={from}&Body={body}
+	 */
+
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("From", twilio.From)
+	form.Set("Body", body)
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", twilio.URL, url.PathEscape(twilio.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(twilio.AccountSID, twilio.AuthToken)
+
+	resp, err := twilio.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr twilioError
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&apiErr); err == nil && apiErr.Message != "" {
+			return fmt.Errorf("sms provider responded with status %d: %s (code %d)", resp.StatusCode, apiErr.Message,
+				apiErr.Code)
+		}
+		return fmt.Errorf("sms provider responded with status %d", resp.StatusCode)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	return nil
+}
@@ -0,0 +1,180 @@
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"zust/service/security"
+)
+
+// Transcriber is the speech-to-text step run after a video is transcoded. It receives the audio of a video
+// (full file path of a 16kHz mono WAV) and returns its captions
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio string) (*Transcript, error)
+}
+
+// Captions produced by a transcriber
+type Transcript struct {
+	Language string `json:"language"` // BCP 47 tag, for example: en
+	VTT      string `json:"vtt"`      // WebVTT document
+}
+
+// BCP 47 language tag, restricted to a primary language and an optional region (fits VARCHAR(10))
+var languageTag = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,4})?$`)
+
+// Helper function: check if the language of a transcript is a valid tag, it's also used in file names
+func IsValidLanguage(language string) bool {
+	return languageTag.MatchString(language)
+}
+
+// Constructor method for the transcriber selected by the config, it returns nil if transcription is disabled
+func NewTranscriber(config *security.Config) Transcriber {
+	switch config.TranscriptionProvider {
+	case "whisper":
+		return &WhisperTranscriber{
+			Path:     config.WhisperPath,
+			Model:    config.WhisperModel,
+			Language: config.TranscriptionLanguage,
+		}
+	case "http":
+		return &HTTPTranscriber{
+			URL:    config.TranscriptionURL,
+			APIKey: config.TranscriptionAPIKey,
+			client: &http.Client{},
+		}
+	default:
+		return nil
+	}
+}
+
+// Local transcriber, which runs whisper.cpp on the machine
+type WhisperTranscriber struct {
+	Path     string // whisper.cpp CLI, for example: whisper-cli
+	Model    string // full path of the ggml model
+	Language string // spoken language, 'auto' to detect it
+}
+
+// Language detected by whisper.cpp, printed as: auto-detected language: en (p = 0.98)
+var detectedLanguage = regexp.MustCompile(`auto-detected language: ([a-z-]+)`)
+
+// Method to transcribe the audio with whisper.cpp, the WebVTT is written next to the audio then read back
+func (whisper *WhisperTranscriber) Transcribe(ctx context.Context, audio string) (*Transcript, error) {
+	/*
+	 * Command:
+	 * whisper-cli -m model.bin -f audio.wav -l auto -ovtt -of audio
+	 */
+
+	prefix := strings.TrimSuffix(audio, filepath.Ext(audio))
+	cmd := exec.CommandContext(ctx,
+		whisper.Path,
+		"-m", whisper.Model,
+		"-f", audio,
+		"-l", whisper.Language,
+		"-ovtt",
+		"-of", prefix,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("whisper failed for transcribing audio: %v\nOutput: %s", err, string(out))
+	}
+
+	vtt, err := os.ReadFile(prefix + ".vtt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(prefix + ".vtt")
+
+	language := whisper.Language
+	if match := detectedLanguage.FindSubmatch(out); match != nil {
+		language = string(match[1])
+	}
+	if language == "auto" {
+		language = "und" // undetermined
+	}
+
+	return &Transcript{Language: language, VTT: string(vtt)}, nil
+}
+
+// HTTP transcriber, which sends the audio to an external speech-to-text service
+type HTTPTranscriber struct {
+	URL    string
+	APIKey string
+	client *http.Client
+}
+
+// Method to send the audio to the speech-to-text service as the 'audio' file part of a multipart request.
+// The service is expected to response with JSON: {"language": "en", "vtt": "WEBVTT\n\n00:00.000 --> ..."}
+func (transcriber *HTTPTranscriber) Transcribe(ctx context.Context, audio string) (*Transcript, error) {
+	// Build the multipart body
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writeAudioPart(writer, audio); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, "POST", transcriber.URL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	if transcriber.APIKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", transcriber.APIKey))
+	}
+
+	// Perform the request
+	resp, err := transcriber.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Check for status code
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("transcription failed: %s", string(data))
+	}
+
+	// Parse response body
+	var transcript Transcript
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(transcript.VTT, "WEBVTT") {
+		return nil, fmt.Errorf("transcription service returned an invalid WebVTT document")
+	}
+	if transcript.Language == "" {
+		transcript.Language = "und"
+	}
+	return &transcript, nil
+}
+
+// Helper function: copy the audio file into a multipart file part
+func writeAudioPart(writer *multipart.Writer, audio string) error {
+	src, err := os.Open(audio)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	part, err := writer.CreateFormFile("audio", filepath.Base(audio))
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, src)
+	return err
+}
@@ -0,0 +1,105 @@
+// Package webhook delivers the events of the creators to their webhooks. Each request is signed with the secret of
+// the webhook, so the receiver can check it comes from the server and isn't replayed
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+	"zust/service/security"
+)
+
+// Timeout of a delivery, and size of the generated secrets (in bytes, before hex encoding)
+const (
+	requestTimeout = 10 * time.Second
+	secretSize     = 32
+)
+
+// Headers of the deliveries
+const (
+	HeaderEvent     = "X-Zust-Event"
+	HeaderDelivery  = "X-Zust-Delivery"
+	HeaderSignature = "X-Zust-Signature"
+)
+
+// A request to a webhook, the ID is the same for all the attempts of the delivery so the receiver can deduplicate
+type Delivery struct {
+	ID     string
+	URL    string
+	Secret string
+	Event  string
+	Body   []byte
+}
+
+// Client delivers the events to the webhooks, only to the public addresses
+type Client struct {
+	client *http.Client
+}
+
+// Constructor method for the webhook client
+func NewClient() *Client {
+	return &Client{
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: &http.Transport{DialContext: (&net.Dialer{Control: security.PublicOnly}).DialContext},
+			// A redirect would be followed without the checks of the webhook URL, it's a failed delivery instead
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Function to generate the secret of a new webhook, hex encoded
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Function to sign the body of a delivery. The signature is 't={timestamp},v1={signature}': the signature is the hex
+// encoded HMAC-SHA256 of '{timestamp}.{body}' with the secret of the webhook, the timestamp is in Unix seconds so
+// the receiver can refuse the old requests
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Method to deliver an event to a webhook. It returns the status code of the response (0 if there is none), and an
+// error if the request failed or the status code isn't 2xx
+func (client *Client) Deliver(ctx context.Context, delivery Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, time.Now(), delivery.Body))
+
+	resp, err := client.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
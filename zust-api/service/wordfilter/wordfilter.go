@@ -0,0 +1,92 @@
+// Package wordfilter matches texts against blocked-word lists, for example the words the titles of the videos can't
+// contain. The matching ignores the case
+package wordfilter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// How the pattern of a rule is matched
+type Mode string
+
+const (
+	ModeExact     Mode = "exact"     // whole words, for example 'spam' matches 'Spam!' but not 'spammer'
+	ModeSubstring Mode = "substring" // anywhere in the text, for example 'spam' matches 'spammer'
+	ModeRegex     Mode = "regex"     // regular expression, in the RE2 syntax
+)
+
+// A blocked word (or phrase, or pattern) of a list
+type Rule struct {
+	ID      string
+	Pattern string
+	Mode    Mode
+	Scope   string // list of the rule, for example: site or channel
+}
+
+// A rule matching a text
+type Match struct {
+	Rule Rule
+	Text string // part of the text matched
+}
+
+// Filter matching texts against compiled rules
+type Filter struct {
+	rules   []Rule
+	regexps []*regexp.Regexp
+}
+
+// Function to compile the pattern of a rule into a case-insensitive regular expression. RE2 runs in linear time, so
+// a pattern set by a user can't make the matching hang. An exact pattern is matched as whole words: its words are
+// surrounded by non-word characters, the submatch is the matched text
+func Compile(mode Mode, pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, errors.New("pattern is empty")
+	}
+
+	switch mode {
+	case ModeExact:
+		words := strings.Fields(pattern)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		return regexp.Compile(`(?i)(?:^|[^\p{L}\p{N}_])(` + strings.Join(words, `\s+`) + `)(?:$|[^\p{L}\p{N}_])`)
+	case ModeSubstring:
+		return regexp.Compile("(?i)(" + regexp.QuoteMeta(pattern) + ")")
+	case ModeRegex:
+		// The pattern is checked alone first, so it can't close the group it's wrapped into
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return regexp.Compile("(?i)(" + pattern + ")")
+	default:
+		return nil, fmt.Errorf("unsupported match mode %q", mode)
+	}
+}
+
+// Constructor method for the filter of the rules
+func New(rules []Rule) (*Filter, error) {
+	filter := &Filter{rules: rules, regexps: make([]*regexp.Regexp, len(rules))}
+	for i, rule := range rules {
+		re, err := Compile(rule.Mode, rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.ID, err)
+		}
+		filter.regexps[i] = re
+	}
+	return filter, nil
+}
+
+// Method to get the rules matching a text, with the first part of the text each of them matches
+func (filter *Filter) Match(text string) []Match {
+	var matches []Match
+	for i, re := range filter.regexps {
+		if found := re.FindStringSubmatch(text); found != nil {
+			matches = append(matches, Match{Rule: filter.rules[i], Text: strings.TrimSpace(found[1])})
+		}
+	}
+	return matches
+}
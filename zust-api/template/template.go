@@ -0,0 +1,26 @@
+// Package template holds the email templates, embedded into the binary so the server doesn't depend on its working
+// directory
+package template
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed *.html *.txt
+var files embed.FS
+
+// Function to read a template, from the 'override' directory if it's set and has the file, otherwise from the
+// embedded templates
+func Read(override, name string) ([]byte, error) {
+	if override != "" {
+		data, err := os.ReadFile(filepath.Join(override, name))
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+	return files.ReadFile(name)
+}